@@ -71,11 +71,11 @@ describe('API Integration', () => {
       json: async () => mockWeather,
     })
     
-    const response = await mockFetch('/api/weather?lat=40.7128&lon=-74.0060')
+    const response = await mockFetch('/api/v1/weather?lat=40.7128&lon=-74.0060')
     const data = await response.json()
     
     expect(data).toEqual(mockWeather)
-    expect(mockFetch).toHaveBeenCalledWith('/api/weather?lat=40.7128&lon=-74.0060')
+    expect(mockFetch).toHaveBeenCalledWith('/api/v1/weather?lat=40.7128&lon=-74.0060')
   })
 
   it('handles API errors', async () => {
@@ -86,7 +86,7 @@ describe('API Integration', () => {
       json: async () => ({ error: 'Invalid coordinates' }),
     })
     
-    const response = await mockFetch('/api/weather?lat=invalid&lon=invalid')
+    const response = await mockFetch('/api/v1/weather?lat=invalid&lon=invalid')
     
     expect(response.ok).toBe(false)
     expect(response.status).toBe(400)
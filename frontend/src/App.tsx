@@ -78,7 +78,7 @@ function WeatherDisplay({ lat, lng, unit, onUnitChange }: {
   const { data, isLoading, error } = useQuery<WeatherData>({
     queryKey: ['weather', lat, lng],
     queryFn: async () => {
-      const response = await fetch(`/api/weather?lat=${lat}&lon=${lng}`)
+      const response = await fetch(`/api/v1/weather?lat=${lat}&lon=${lng}`)
       if (!response.ok) throw new Error('Failed to fetch weather')
       return response.json()
     },
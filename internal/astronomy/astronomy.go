@@ -0,0 +1,227 @@
+package astronomy
+
+import (
+	"math"
+	"time"
+)
+
+// Reasons a day has no sunrise and sunset
+const (
+	// PolarNight marks a day the sun stays below the horizon
+	PolarNight = "polar_night"
+	// MidnightSun marks a day the sun stays above the horizon
+	MidnightSun = "midnight_sun"
+)
+
+// Moon phase names, in the order the moon passes through them
+const (
+	NewMoon        = "new_moon"
+	WaxingCrescent = "waxing_crescent"
+	FirstQuarter   = "first_quarter"
+	WaxingGibbous  = "waxing_gibbous"
+	FullMoon       = "full_moon"
+	WaningGibbous  = "waning_gibbous"
+	LastQuarter    = "last_quarter"
+	WaningCrescent = "waning_crescent"
+)
+
+// principalPhases and intermediatePhases list the phases by quarter of the
+// moon's elongation from the sun
+var (
+	principalPhases    = []string{NewMoon, FirstQuarter, FullMoon, LastQuarter}
+	intermediatePhases = []string{WaxingCrescent, WaxingGibbous, WaningGibbous, WaningCrescent}
+)
+
+const (
+	// j2000 is the Julian date of 2000-01-01 12:00 TT, the epoch the orbital
+	// elements are given from
+	j2000 = 2451545.0
+	// unixEpochJD is the Julian date of 1970-01-01 00:00 UTC
+	unixEpochJD = 2440587.5
+	// horizonAltitude is the sun's center altitude at sunrise and sunset,
+	// allowing for refraction and the sun's radius
+	horizonAltitude = -0.833
+	// obliquity is the tilt of the Earth's axis to the ecliptic
+	obliquity = 23.4397
+	// synodicMonth is the mean length of the moon's cycle of phases in days
+	synodicMonth = 29.530588853
+	// principalPhaseWidth is how far in elongation, half a day's travel, the
+	// moon may be from a principal phase to be named after it
+	principalPhaseWidth = 360 / synodicMonth / 2
+	// riseSetIterations refines sunrise and sunset with the sun's position
+	// at the previous estimate, converging well inside a minute
+	riseSetIterations = 3
+)
+
+// SunDay is a day's solar events
+type SunDay struct {
+	// Sunrise and Sunset are nil on days the sun doesn't cross the horizon,
+	// with Polar saying why
+	Sunrise, Sunset *time.Time
+	// SolarNoon is when the sun crosses the meridian, highest in the sky
+	SolarNoon time.Time
+	// DayLength is the time between sunrise and sunset: zero in polar night
+	// and 24 hours under the midnight sun
+	DayLength time.Duration
+	// Polar is PolarNight or MidnightSun when there is no sunrise or sunset
+	Polar string
+}
+
+// Sun computes the solar events of a civil day at a coordinate (longitude
+// east positive), with the sunrise equation the NOAA solar calculator uses.
+// The day is date's calendar day in date's location, and the times are
+// returned in that location. Results are within a minute or so of published
+// almanacs outside the polar regions.
+func Sun(date time.Time, lat, lon float64) SunDay {
+	loc := date.Location()
+	year, month, day := date.Date()
+	// Whole days from J2000 to the date's noon in UTC, then on to local mean noon
+	n := julianDate(time.Date(year, month, day, 12, 0, 0, 0, time.UTC)) - j2000
+	meanNoon := n - lon/360
+
+	noon, declination := solarTransit(meanNoon, meanNoon)
+	result := SunDay{SolarNoon: fromJulianDate(noon).In(loc)}
+	switch cosHourAngle := hourAngleCosine(lat, declination); {
+	case cosHourAngle > 1:
+		result.Polar = PolarNight
+		return result
+	case cosHourAngle < -1:
+		result.Polar = MidnightSun
+		result.DayLength = 24 * time.Hour
+		return result
+	}
+
+	rise, riseOK := riseOrSet(meanNoon, lat, -1)
+	set, setOK := riseOrSet(meanNoon, lat, 1)
+	if !riseOK || !setOK {
+		// The sun only just reaches the horizon; call the day by the transit
+		if lat*declination > 0 {
+			result.Polar, result.DayLength = MidnightSun, 24*time.Hour
+		} else {
+			result.Polar = PolarNight
+		}
+		return result
+	}
+	sunrise, sunset := fromJulianDate(rise).In(loc), fromJulianDate(set).In(loc)
+	result.Sunrise, result.Sunset = &sunrise, &sunset
+	result.DayLength = sunset.Sub(sunrise).Round(time.Second)
+	return result
+}
+
+// riseOrSet returns the Julian date of sunrise (sign -1) or sunset (sign 1)
+// on the day with the given mean solar noon, refining the estimate with the
+// sun's declination at the previous one
+func riseOrSet(meanNoon, lat float64, sign float64) (float64, bool) {
+	at := meanNoon
+	var event float64
+	for range riseSetIterations {
+		transit, declination := solarTransit(meanNoon, at)
+		cosHourAngle := hourAngleCosine(lat, declination)
+		if cosHourAngle < -1 || cosHourAngle > 1 {
+			return 0, false
+		}
+		event = transit + sign*degrees(math.Acos(cosHourAngle))/360
+		at = event - j2000
+	}
+	return event, true
+}
+
+// solarTransit returns the Julian date of solar noon on the day with the
+// given mean solar noon (days from J2000), and the sun's declination in
+// degrees at the instant at
+func solarTransit(meanNoon, at float64) (transit, declination float64) {
+	anomaly := meanAnomaly(at)
+	longitude := eclipticLongitude(anomaly)
+	transit = j2000 + meanNoon + 0.0053*sin(anomaly) - 0.0069*sin(2*longitude)
+	declination = degrees(math.Asin(sin(longitude) * sin(obliquity)))
+	return transit, declination
+}
+
+// meanAnomaly returns the sun's mean anomaly in degrees, days from J2000
+func meanAnomaly(days float64) float64 {
+	return normalize(357.5291 + 0.98560028*days)
+}
+
+// eclipticLongitude returns the sun's ecliptic longitude in degrees for its
+// mean anomaly, with the equation of the center
+func eclipticLongitude(anomaly float64) float64 {
+	center := 1.9148*sin(anomaly) + 0.02*sin(2*anomaly) + 0.0003*sin(3*anomaly)
+	return normalize(anomaly + center + 180 + 102.9372)
+}
+
+// hourAngleCosine returns the cosine of the sun's hour angle at sunrise and
+// sunset; beyond [-1, 1] the sun doesn't reach the horizon
+func hourAngleCosine(lat, declination float64) float64 {
+	return (sin(horizonAltitude) - sin(lat)*sin(declination)) / (cos(lat) * cos(declination))
+}
+
+// MoonPhase is the moon's phase at an instant
+type MoonPhase struct {
+	// Phase is one of the eight phase names, such as WaxingGibbous
+	Phase string
+	// Illumination is the fraction of the moon's disk lit, from 0 to 1
+	Illumination float64
+	// AgeDays is the time since the last new moon, estimated from the
+	// moon's elongation
+	AgeDays float64
+}
+
+// Moon computes the moon's phase at an instant from the principal terms of
+// the lunar and solar longitudes in Meeus's Astronomical Algorithms, good to
+// a few hours of the published phase times
+func Moon(t time.Time) MoonPhase {
+	centuries := (julianDate(t) - j2000) / 36525
+
+	elongation := 297.8501921 + 445267.1114034*centuries
+	sunAnomaly := 357.5291092 + 35999.0502909*centuries
+	moonAnomaly := 134.9633964 + 477198.8675055*centuries
+	latitudeArgument := 93.2720950 + 483202.0175233*centuries
+
+	moonLongitude := 218.3164477 + 481267.88123421*centuries +
+		6.289*sin(moonAnomaly) + 1.274*sin(2*elongation-moonAnomaly) + 0.658*sin(2*elongation) +
+		0.214*sin(2*moonAnomaly) - 0.186*sin(sunAnomaly) - 0.114*sin(2*latitudeArgument)
+	sunLongitude := 280.46646 + 36000.76983*centuries +
+		1.914602*sin(sunAnomaly) + 0.019993*sin(2*sunAnomaly)
+
+	angle := normalize(moonLongitude - sunLongitude)
+	return MoonPhase{
+		Phase:        phaseName(angle),
+		Illumination: (1 - cos(angle)) / 2,
+		AgeDays:      angle / 360 * synodicMonth,
+	}
+}
+
+// phaseName names the phase at an elongation in degrees. The principal phases
+// are instants, so they only name the half day either side of one.
+func phaseName(elongation float64) string {
+	nearest := math.Round(elongation / 90)
+	if math.Abs(elongation-nearest*90) <= principalPhaseWidth {
+		return principalPhases[int(nearest)%len(principalPhases)]
+	}
+	return intermediatePhases[int(elongation/90)]
+}
+
+// julianDate converts a time to a Julian date
+func julianDate(t time.Time) float64 {
+	return unixEpochJD + float64(t.UnixNano())/float64(24*time.Hour)
+}
+
+// fromJulianDate converts a Julian date to a UTC time, to the second
+func fromJulianDate(jd float64) time.Time {
+	return time.Unix(0, int64((jd-unixEpochJD)*float64(24*time.Hour))).UTC().Round(time.Second)
+}
+
+// normalize reduces an angle in degrees to [0, 360)
+func normalize(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return deg
+}
+
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
+
+func sin(deg float64) float64 { return math.Sin(deg * math.Pi / 180) }
+
+func cos(deg float64) float64 { return math.Cos(deg * math.Pi / 180) }
@@ -0,0 +1,131 @@
+package astronomy
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// tolerance is how far computed times may stray from almanac times, which
+// are published to the minute
+const tolerance = 2 * time.Minute
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestSun(t *testing.T) {
+	// Almanac times from the USNO and timeanddate.com
+	tests := []struct {
+		name             string
+		zone             string
+		date             string
+		lat, lon         float64
+		sunrise, sunset  string
+		noon             string
+		dayLengthMinutes int
+	}{
+		{"New York, summer solstice", "America/New_York", "2024-06-20", 40.7128, -74.006, "05:25", "20:31", "12:58", 905},
+		{"New York, first day of DST", "America/New_York", "2024-03-10", 40.7128, -74.006, "07:16", "18:58", "13:07", 701},
+		{"London, winter solstice", "Europe/London", "2024-12-21", 51.5074, -0.1278, "08:04", "15:53", "11:59", 469},
+		{"Sydney, southern summer", "Australia/Sydney", "2024-01-15", -33.8688, 151.2093, "06:00", "20:09", "13:04", 849},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := mustLoad(t, tt.zone)
+			day, _ := time.ParseInLocation(time.DateOnly, tt.date, loc)
+			got := Sun(day.Add(12*time.Hour), tt.lat, tt.lon)
+			if got.Sunrise == nil || got.Sunset == nil || got.Polar != "" {
+				t.Fatalf("Sun = %+v; want a sunrise and sunset", got)
+			}
+			at := func(clock string) time.Time {
+				tm, _ := time.ParseInLocation(time.DateOnly+" 15:04", tt.date+" "+clock, loc)
+				return tm
+			}
+			for _, c := range []struct {
+				event     string
+				got, want time.Time
+			}{
+				{"sunrise", *got.Sunrise, at(tt.sunrise)},
+				{"sunset", *got.Sunset, at(tt.sunset)},
+				{"solar noon", got.SolarNoon, at(tt.noon)},
+			} {
+				if diff := c.got.Sub(c.want); diff < -tolerance || diff > tolerance {
+					t.Errorf("%s = %s; want %s within %s", c.event, c.got, c.want, tolerance)
+				}
+				if c.got.Location() != loc {
+					t.Errorf("%s is in %s; want %s", c.event, c.got.Location(), loc)
+				}
+			}
+			if minutes := int(got.DayLength.Minutes()); math.Abs(float64(minutes-tt.dayLengthMinutes)) > 2 {
+				t.Errorf("day length = %s; want about %d minutes", got.DayLength, tt.dayLengthMinutes)
+			}
+		})
+	}
+}
+
+func TestSunPolar(t *testing.T) {
+	oslo := mustLoad(t, "Europe/Oslo")
+	tests := []struct {
+		name      string
+		date      time.Time
+		lat, lon  float64
+		want      string
+		dayLength time.Duration
+	}{
+		// Tromsø, well inside the Arctic Circle
+		{"arctic winter", time.Date(2024, 12, 21, 12, 0, 0, 0, oslo), 69.6492, 18.9553, PolarNight, 0},
+		{"arctic summer", time.Date(2024, 6, 21, 12, 0, 0, 0, oslo), 69.6492, 18.9553, MidnightSun, 24 * time.Hour},
+		// McMurdo Station, where the seasons are reversed
+		{"antarctic winter", time.Date(2024, 6, 21, 12, 0, 0, 0, time.UTC), -77.846, 166.676, PolarNight, 0},
+		{"antarctic summer", time.Date(2024, 12, 21, 12, 0, 0, 0, time.UTC), -77.846, 166.676, MidnightSun, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		got := Sun(tt.date, tt.lat, tt.lon)
+		if got.Polar != tt.want || got.Sunrise != nil || got.Sunset != nil || got.DayLength != tt.dayLength {
+			t.Errorf("%s: Sun = %+v; want %s with no sunrise or sunset and a %s day", tt.name, got, tt.want, tt.dayLength)
+		}
+		if got.SolarNoon.IsZero() {
+			t.Errorf("%s: solar noon unset", tt.name)
+		}
+	}
+
+	// Tromsø sees the sun again by late January
+	if got := Sun(time.Date(2025, 1, 21, 12, 0, 0, 0, oslo), 69.6492, 18.9553); got.Polar != "" || got.Sunrise == nil {
+		t.Errorf("Tromsø on 2025-01-21 = %+v; want a sunrise", got)
+	}
+}
+
+func TestMoon(t *testing.T) {
+	// Principal phases of April 2024 from the USNO
+	tests := []struct {
+		at           string
+		phase        string
+		illumination float64
+	}{
+		{"2024-04-08T18:21:00Z", NewMoon, 0},
+		{"2024-04-12T12:00:00Z", WaxingCrescent, 0.18},
+		{"2024-04-15T19:13:00Z", FirstQuarter, 0.5},
+		{"2024-04-23T23:49:00Z", FullMoon, 1},
+		{"2024-05-01T11:27:00Z", LastQuarter, 0.5},
+		{"2024-05-05T00:00:00Z", WaningCrescent, 0.13},
+	}
+	for _, tt := range tests {
+		at, err := time.Parse(time.RFC3339, tt.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := Moon(at)
+		if got.Phase != tt.phase || math.Abs(got.Illumination-tt.illumination) > 0.03 {
+			t.Errorf("Moon(%s) = %+v; want %s about %.2f lit", tt.at, got, tt.phase, tt.illumination)
+		}
+		if got.AgeDays < 0 || got.AgeDays >= synodicMonth {
+			t.Errorf("Moon(%s) age = %v days; want within a synodic month", tt.at, got.AgeDays)
+		}
+	}
+}
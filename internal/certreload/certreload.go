@@ -0,0 +1,140 @@
+// Package certreload serves a TLS certificate pair from disk and can reload it
+// in place, so renewed certificates are picked up without a restart
+package certreload
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval is how often Watch checks the files for changes
+const DefaultWatchInterval = time.Minute
+
+// Reloader holds the current certificate loaded from a cert and key file
+type Reloader struct {
+	certFile, keyFile string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	// modTimes are the files' modification times when they were last loaded
+	modTimes [2]time.Time
+}
+
+// New loads the certificate pair, failing with an error naming the files if
+// they can't be read or don't form a valid pair
+func New(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the certificate pair again. On failure the previous
+// certificate stays in use.
+func (r *Reloader) Reload() error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate %s with key %s: %w", r.certFile, r.keyFile, err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTimes = modTimes
+	r.mu.Unlock()
+	return nil
+}
+
+// stat returns the modification times of the cert and key files
+func (r *Reloader) stat() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return modTimes, fmt.Errorf("can't read TLS file: %w", err)
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// TLSConfig returns a server TLS configuration serving the current certificate
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+}
+
+// Listener wraps ln so connections accepted from it speak TLS with the current certificate
+func (r *Reloader) Listener(ln net.Listener) net.Listener {
+	return tls.NewListener(ln, r.TLSConfig())
+}
+
+// Watch reloads the certificate whenever either file's modification time
+// changes, checking every interval until ctx is done. Failed reloads are
+// logged and retried on the next change.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if r.changed() {
+				r.reloadAndLog()
+			}
+		}
+	}
+}
+
+// changed reports whether either file was modified since it was last loaded
+func (r *Reloader) changed() bool {
+	modTimes, err := r.stat()
+	if err != nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return modTimes != r.modTimes
+}
+
+// ReloadOn reloads the certificate each time a value arrives on signals, such
+// as SIGHUP from signal.Notify, until ctx is done
+func (r *Reloader) ReloadOn(ctx context.Context, signals <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			r.reloadAndLog()
+		}
+	}
+}
+
+// reloadAndLog reloads the certificate, logging the outcome
+func (r *Reloader) reloadAndLog() {
+	if err := r.Reload(); err != nil {
+		log.Printf("TLS certificate reload failed, keeping the previous certificate: %v", err)
+		return
+	}
+	log.Printf("Reloaded TLS certificate from %s", r.certFile)
+}
@@ -0,0 +1,183 @@
+package certreload
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// writeSelfSigned writes a self-signed certificate for 127.0.0.1 and its key
+// to certFile and keyFile, returning the certificate
+func writeSelfSigned(t *testing.T, certFile, keyFile, name string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestServeHTTPS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	original := writeSelfSigned(t, certFile, keyFile, "original")
+
+	r, err := New(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/ping", func(c *fiber.Ctx) error { return c.SendString(c.Protocol()) })
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = app.Listener(r.Listener(ln)) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	roots := x509.NewCertPool()
+	roots.AddCert(original)
+	// Each request opens a new connection, so it sees the certificate served at that moment
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		DisableKeepAlives: true,
+	}}
+	get := func() (*http.Response, error) {
+		return client.Get("https://" + ln.Addr().String() + "/ping")
+	}
+
+	resp, err := get()
+	if err != nil {
+		t.Fatalf("GET over https: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "https" {
+		t.Errorf("GET = %d %q; want 200 over https", resp.StatusCode, body)
+	}
+	if got := resp.TLS.PeerCertificates[0].SerialNumber; got.Cmp(original.SerialNumber) != 0 {
+		t.Errorf("served certificate serial = %v; want %v", got, original.SerialNumber)
+	}
+
+	// A renewed pair is picked up once its modification time changes
+	renewed := writeSelfSigned(t, certFile, keyFile, "renewed")
+	later := time.Now().Add(time.Minute)
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	roots.AddCert(renewed)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, 10*time.Millisecond)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := get()
+		if err != nil {
+			t.Fatalf("GET after renewal: %v", err)
+		}
+		resp.Body.Close()
+		if resp.TLS.PeerCertificates[0].SerialNumber.Cmp(renewed.SerialNumber) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("renewed certificate was not picked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReloadKeepsCertificateOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeSelfSigned(t, certFile, keyFile, "original")
+
+	r, err := New(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	before, _ := r.GetCertificate(nil)
+
+	// A half-written renewal fails to load
+	if err := os.WriteFile(certFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err == nil {
+		t.Fatal("Reload of an invalid certificate succeeded")
+	}
+	if after, _ := r.GetCertificate(nil); after != before {
+		t.Error("failed reload replaced the certificate; want the previous one kept")
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeSelfSigned(t, certFile, keyFile, "first")
+	otherCert, otherKey := filepath.Join(dir, "other.pem"), filepath.Join(dir, "other-key.pem")
+	writeSelfSigned(t, otherCert, otherKey, "second")
+
+	tests := []struct {
+		name              string
+		certFile, keyFile string
+	}{
+		{"missing cert", filepath.Join(dir, "missing.pem"), keyFile},
+		{"missing key", certFile, filepath.Join(dir, "missing.pem")},
+		{"mismatched pair", certFile, otherKey},
+	}
+
+	for _, tt := range tests {
+		if _, err := New(tt.certFile, tt.keyFile); err == nil {
+			t.Errorf("%s: New succeeded; want an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2/middleware/compress"
+)
+
+// DefaultCompressionMinSize is the smallest response body compressed when no
+// minimum is configured; smaller bodies gain too little to be worth the CPU
+const DefaultCompressionMinSize = 1024
+
+// Compression configures response compression
+type Compression struct {
+	// Level trades CPU for size; compress.LevelDisabled turns compression off
+	Level compress.Level
+	// MinSize is the smallest response body, in bytes, that is compressed
+	MinSize int
+}
+
+// compressionLevels maps COMPRESSION_LEVEL values to compression levels
+var compressionLevels = map[string]compress.Level{
+	"off":     compress.LevelDisabled,
+	"speed":   compress.LevelBestSpeed,
+	"default": compress.LevelDefault,
+	"best":    compress.LevelBestCompression,
+}
+
+// LoadCompression reads COMPRESSION_LEVEL (off, speed, default, or best) and
+// COMPRESSION_MIN_SIZE (bytes) using getenv, defaulting to the default level
+// and DefaultCompressionMinSize
+func LoadCompression(getenv func(string) string) (Compression, error) {
+	c := Compression{Level: compress.LevelDefault, MinSize: DefaultCompressionMinSize}
+
+	if raw := strings.ToLower(strings.TrimSpace(getenv("COMPRESSION_LEVEL"))); raw != "" {
+		level, ok := compressionLevels[raw]
+		if !ok {
+			return Compression{}, fmt.Errorf("invalid COMPRESSION_LEVEL %q: must be off, speed, default, or best", raw)
+		}
+		c.Level = level
+	}
+	if raw := strings.TrimSpace(getenv("COMPRESSION_MIN_SIZE")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return Compression{}, fmt.Errorf("invalid COMPRESSION_MIN_SIZE %q: must be a number of bytes", raw)
+		}
+		c.MinSize = n
+	}
+	return c, nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2/middleware/compress"
+)
+
+func TestLoadCompression(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    Compression
+		wantErr bool
+	}{
+		{"default", nil, Compression{Level: compress.LevelDefault, MinSize: DefaultCompressionMinSize}, false},
+		{"off", map[string]string{"COMPRESSION_LEVEL": "off"}, Compression{Level: compress.LevelDisabled, MinSize: DefaultCompressionMinSize}, false},
+		{"speed", map[string]string{"COMPRESSION_LEVEL": "Speed"}, Compression{Level: compress.LevelBestSpeed, MinSize: DefaultCompressionMinSize}, false},
+		{"best", map[string]string{"COMPRESSION_LEVEL": "best"}, Compression{Level: compress.LevelBestCompression, MinSize: DefaultCompressionMinSize}, false},
+		{"min size", map[string]string{"COMPRESSION_MIN_SIZE": "4096"}, Compression{Level: compress.LevelDefault, MinSize: 4096}, false},
+		{"zero min size", map[string]string{"COMPRESSION_MIN_SIZE": "0"}, Compression{Level: compress.LevelDefault}, false},
+		{"unknown level", map[string]string{"COMPRESSION_LEVEL": "9"}, Compression{}, true},
+		{"negative min size", map[string]string{"COMPRESSION_MIN_SIZE": "-1"}, Compression{}, true},
+		{"non-numeric min size", map[string]string{"COMPRESSION_MIN_SIZE": "1kb"}, Compression{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadCompression(func(key string) string { return tt.env[key] })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadCompression error = %v; wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("LoadCompression = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
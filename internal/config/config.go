@@ -0,0 +1,69 @@
+// Package config reads and validates the service's settings from the environment
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DefaultPort is the port the server listens on when none is configured
+const DefaultPort = 3000
+
+// Listen is the address the HTTP server listens on
+type Listen struct {
+	// Host is the interface to bind; empty binds every interface
+	Host string
+	// Port is the TCP port; 0 picks a free ephemeral port
+	Port int
+}
+
+// Addr returns the address in host:port form, as accepted by net.Listen
+func (l Listen) Addr() string {
+	return net.JoinHostPort(l.Host, strconv.Itoa(l.Port))
+}
+
+// URL returns the base URL a local client reaches the server at, given the
+// scheme it is served over and the port actually bound. Wildcard hosts are
+// reported as localhost.
+func (l Listen) URL(scheme string, port int) string {
+	host := l.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// LoadListen reads the listen address from ADDR (host:port, e.g. :8080 or
+// 127.0.0.1:0), or from HOST and PORT, using getenv (typically os.Getenv).
+// ADDR can't be combined with HOST or PORT. Unset parts default to every
+// interface and DefaultPort.
+func LoadListen(getenv func(string) string) (Listen, error) {
+	addr, host, port := strings.TrimSpace(getenv("ADDR")), strings.TrimSpace(getenv("HOST")), strings.TrimSpace(getenv("PORT"))
+
+	if addr != "" {
+		if host != "" || port != "" {
+			return Listen{}, fmt.Errorf("ADDR %q can't be combined with HOST or PORT", addr)
+		}
+		var err error
+		host, port, err = net.SplitHostPort(addr)
+		if err != nil {
+			return Listen{}, fmt.Errorf("invalid ADDR %q: want host:port, e.g. :3000: %v", addr, err)
+		}
+	}
+
+	// A bracketed IPv6 HOST is accepted as well as a bare one
+	l := Listen{Host: strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"), Port: DefaultPort}
+	if port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 0 || p > 65535 {
+			return Listen{}, fmt.Errorf("invalid port %q: must be a number from 0 to 65535", port)
+		}
+		l.Port = p
+	}
+	if strings.ContainsAny(l.Host, " /") {
+		return Listen{}, fmt.Errorf("invalid host %q", l.Host)
+	}
+	return l, nil
+}
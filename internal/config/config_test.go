@@ -0,0 +1,64 @@
+package config
+
+import "testing"
+
+func TestLoadListen(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		wantAddr string
+		wantErr  bool
+	}{
+		{"defaults", nil, ":3000", false},
+		{"port", map[string]string{"PORT": "8080"}, ":8080", false},
+		{"host and port", map[string]string{"HOST": "127.0.0.1", "PORT": "8080"}, "127.0.0.1:8080", false},
+		{"host only", map[string]string{"HOST": "127.0.0.1"}, "127.0.0.1:3000", false},
+		{"ipv6 host", map[string]string{"HOST": "::1"}, "[::1]:3000", false},
+		{"bracketed ipv6 host", map[string]string{"HOST": "[::1]"}, "[::1]:3000", false},
+		{"addr", map[string]string{"ADDR": "0.0.0.0:9000"}, "0.0.0.0:9000", false},
+		{"ephemeral addr", map[string]string{"ADDR": ":0"}, ":0", false},
+		{"ipv6 addr", map[string]string{"ADDR": "[::1]:9000"}, "[::1]:9000", false},
+		{"addr without port", map[string]string{"ADDR": "localhost"}, "", true},
+		{"addr with host", map[string]string{"ADDR": ":9000", "HOST": "127.0.0.1"}, "", true},
+		{"addr with port", map[string]string{"ADDR": ":9000", "PORT": "8080"}, "", true},
+		{"non-numeric port", map[string]string{"PORT": "http"}, "", true},
+		{"negative port", map[string]string{"PORT": "-1"}, "", true},
+		{"port out of range", map[string]string{"PORT": "65536"}, "", true},
+		{"addr port out of range", map[string]string{"ADDR": ":70000"}, "", true},
+		{"invalid host", map[string]string{"HOST": "http://example.com"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := LoadListen(func(key string) string { return tt.env[key] })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadListen error = %v; wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && l.Addr() != tt.wantAddr {
+				t.Errorf("Addr() = %q; want %q", l.Addr(), tt.wantAddr)
+			}
+		})
+	}
+}
+
+func TestListenURL(t *testing.T) {
+	tests := []struct {
+		listen Listen
+		scheme string
+		port   int
+		want   string
+	}{
+		{Listen{Port: 3000}, "http", 3000, "http://localhost:3000"},
+		{Listen{Host: "0.0.0.0", Port: 0}, "http", 54321, "http://localhost:54321"},
+		{Listen{Host: "::", Port: 8080}, "http", 8080, "http://localhost:8080"},
+		{Listen{Host: "127.0.0.1", Port: 8080}, "http", 8080, "http://127.0.0.1:8080"},
+		{Listen{Host: "::1", Port: 8080}, "http", 8080, "http://[::1]:8080"},
+		{Listen{Port: 443}, "https", 443, "https://localhost:443"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.listen.URL(tt.scheme, tt.port); got != tt.want {
+			t.Errorf("%+v.URL(%q, %d) = %q; want %q", tt.listen, tt.scheme, tt.port, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"strings"
+)
+
+// DefaultSQLitePath is the cache database file used when none is configured
+const DefaultSQLitePath = "./weather_cache.db"
+
+// Database is where the server keeps its persistent cache
+type Database struct {
+	// SQLitePath is the cache database file. It always holds grid mappings,
+	// request stats, and the other local tables, and holds forecasts too
+	// unless PostgresURL is set.
+	SQLitePath string
+	// PostgresURL, when set, keeps per-coordinate forecasts and their history
+	// in Postgres so replicas share them
+	PostgresURL string
+}
+
+// LoadDatabase reads DATABASE_URL and SQLITE_PATH using getenv. A postgres://
+// or postgresql:// DATABASE_URL selects Postgres for forecasts, with the
+// local tables at SQLITE_PATH; any other DATABASE_URL is the SQLite file.
+func LoadDatabase(getenv func(string) string) Database {
+	d := Database{SQLitePath: strings.TrimSpace(getenv("SQLITE_PATH"))}
+	if d.SQLitePath == "" {
+		d.SQLitePath = DefaultSQLitePath
+	}
+	url := strings.TrimSpace(getenv("DATABASE_URL"))
+	switch {
+	case strings.HasPrefix(url, "postgres://"), strings.HasPrefix(url, "postgresql://"):
+		d.PostgresURL = url
+	case url != "":
+		d.SQLitePath = url
+	}
+	return d
+}
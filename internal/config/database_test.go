@@ -0,0 +1,33 @@
+package config
+
+import "testing"
+
+func TestLoadDatabase(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want Database
+	}{
+		{"unset", nil, Database{SQLitePath: DefaultSQLitePath}},
+		{"sqlite file", map[string]string{"DATABASE_URL": "/data/cache.db"}, Database{SQLitePath: "/data/cache.db"}},
+		{"sqlite path", map[string]string{"SQLITE_PATH": "/data/local.db"}, Database{SQLitePath: "/data/local.db"}},
+		{
+			"postgres",
+			map[string]string{"DATABASE_URL": "postgres://weather:secret@db:5432/weather?sslmode=require"},
+			Database{SQLitePath: DefaultSQLitePath, PostgresURL: "postgres://weather:secret@db:5432/weather?sslmode=require"},
+		},
+		{
+			"postgresql with local path",
+			map[string]string{"DATABASE_URL": "postgresql://db/weather", "SQLITE_PATH": "/data/local.db"},
+			Database{SQLitePath: "/data/local.db", PostgresURL: "postgresql://db/weather"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LoadDatabase(func(key string) string { return tt.env[key] }); got != tt.want {
+				t.Errorf("LoadDatabase = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
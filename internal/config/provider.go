@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Forecast providers accepted by WEATHER_PROVIDER and FALLBACK_PROVIDER
+const (
+	ProviderNWS       = "nws"
+	ProviderOpenMeteo = "open-meteo"
+	ProviderOWM       = "owm"
+	// ProviderNone turns the fallback off
+	ProviderNone = "none"
+)
+
+// Providers selects the forecast backends
+type Providers struct {
+	// Primary is the provider serving forecasts
+	Primary string
+	// Fallback is the provider serving coordinates outside the primary's
+	// coverage: ProviderOpenMeteo, or empty for none
+	Fallback string
+	// OpenMeteoURL is the Open-Meteo host; empty uses the public API
+	OpenMeteoURL string
+	// OWMAPIKey is the OpenWeatherMap API key, required when it is the primary
+	OWMAPIKey string
+	// OWMURL is the OpenWeatherMap host; empty uses the public API
+	OWMURL string
+}
+
+// LoadProviders reads WEATHER_PROVIDER, FALLBACK_PROVIDER, OPEN_METEO_URL,
+// OWM_API_KEY, and OWM_URL using getenv. The primary defaults to the NWS and
+// the fallback to Open-Meteo; "none" turns the fallback off, so coordinates
+// outside NWS coverage are rejected. Selecting OpenWeatherMap without an API
+// key is an error.
+func LoadProviders(getenv func(string) string) (Providers, error) {
+	p := Providers{
+		OpenMeteoURL: strings.TrimSpace(getenv("OPEN_METEO_URL")),
+		OWMAPIKey:    strings.TrimSpace(getenv("OWM_API_KEY")),
+		OWMURL:       strings.TrimSpace(getenv("OWM_URL")),
+	}
+
+	switch primary := strings.ToLower(strings.TrimSpace(getenv("WEATHER_PROVIDER"))); primary {
+	case "", ProviderNWS:
+		p.Primary = ProviderNWS
+	case ProviderOpenMeteo:
+		p.Primary = ProviderOpenMeteo
+	case ProviderOWM:
+		if p.OWMAPIKey == "" {
+			return Providers{}, fmt.Errorf("WEATHER_PROVIDER=owm requires OWM_API_KEY")
+		}
+		p.Primary = ProviderOWM
+	default:
+		return Providers{}, fmt.Errorf("invalid WEATHER_PROVIDER %q: must be %s, %s, or %s", primary, ProviderNWS, ProviderOpenMeteo, ProviderOWM)
+	}
+
+	switch fallback := strings.ToLower(strings.TrimSpace(getenv("FALLBACK_PROVIDER"))); fallback {
+	case "", ProviderOpenMeteo:
+		p.Fallback = ProviderOpenMeteo
+	case ProviderNone:
+	default:
+		return Providers{}, fmt.Errorf("invalid FALLBACK_PROVIDER %q: must be %s or %s", fallback, ProviderOpenMeteo, ProviderNone)
+	}
+	return p, nil
+}
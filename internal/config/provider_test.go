@@ -0,0 +1,36 @@
+package config
+
+import "testing"
+
+func TestLoadProviders(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    Providers
+		wantErr bool
+	}{
+		{"default", nil, Providers{Primary: ProviderNWS, Fallback: ProviderOpenMeteo}, false},
+		{"open-meteo fallback", map[string]string{"FALLBACK_PROVIDER": "Open-Meteo"}, Providers{Primary: ProviderNWS, Fallback: ProviderOpenMeteo}, false},
+		{"no fallback", map[string]string{"FALLBACK_PROVIDER": "none"}, Providers{Primary: ProviderNWS}, false},
+		{"open-meteo host", map[string]string{"OPEN_METEO_URL": "http://meteo.internal:8080"}, Providers{Primary: ProviderNWS, Fallback: ProviderOpenMeteo, OpenMeteoURL: "http://meteo.internal:8080"}, false},
+		{"unknown fallback", map[string]string{"FALLBACK_PROVIDER": "metoffice"}, Providers{}, true},
+		{"open-meteo primary", map[string]string{"WEATHER_PROVIDER": "open-meteo"}, Providers{Primary: ProviderOpenMeteo, Fallback: ProviderOpenMeteo}, false},
+		{"owm", map[string]string{"WEATHER_PROVIDER": "owm", "OWM_API_KEY": "k3y"}, Providers{Primary: ProviderOWM, Fallback: ProviderOpenMeteo, OWMAPIKey: "k3y"}, false},
+		// Selecting OpenWeatherMap without a key fails at startup, not on the first request
+		{"owm without key", map[string]string{"WEATHER_PROVIDER": "owm"}, Providers{}, true},
+		{"owm with blank key", map[string]string{"WEATHER_PROVIDER": "owm", "OWM_API_KEY": "  "}, Providers{}, true},
+		{"unknown primary", map[string]string{"WEATHER_PROVIDER": "darksky"}, Providers{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadProviders(func(key string) string { return tt.env[key] })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadProviders error = %v; wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("LoadProviders = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
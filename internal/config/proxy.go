@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Proxy is how far the reverse proxies in front of the server are trusted to
+// report the client's address
+type Proxy struct {
+	// TrustedProxies are the proxy addresses from TRUSTED_PROXIES. When set,
+	// only requests from them have their forwarded headers honored.
+	TrustedProxies []netip.Prefix
+	// TrustForwardedFor takes the client address from X-Forwarded-For
+	TrustForwardedFor bool
+}
+
+// LoadProxy reads TRUSTED_PROXIES (comma-separated IPs or CIDRs) and
+// TRUST_PROXY using getenv
+func LoadProxy(getenv func(string) string) (Proxy, error) {
+	var p Proxy
+	for _, raw := range strings.Split(getenv("TRUSTED_PROXIES"), ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(raw)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(raw)
+			if addrErr != nil {
+				return Proxy{}, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: must be an IP address or CIDR", raw)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		p.TrustedProxies = append(p.TrustedProxies, prefix.Masked())
+	}
+
+	if raw := strings.TrimSpace(getenv("TRUST_PROXY")); raw != "" {
+		trust, err := strconv.ParseBool(raw)
+		if err != nil {
+			return Proxy{}, fmt.Errorf("invalid TRUST_PROXY %q: must be true or false", raw)
+		}
+		p.TrustForwardedFor = trust
+	}
+	return p, nil
+}
+
+// ClientIP returns the address a request came from, given the peer that sent
+// it and its X-Forwarded-For header values. Unless TrustForwardedFor is set,
+// and the peer is a trusted proxy when any are configured, that is the peer
+// itself, so clients can't choose the address by sending the header. Otherwise
+// it is the rightmost forwarded address that isn't a trusted proxy: each proxy
+// appends the address it received the request from, and everything left of
+// the first untrusted hop could have been written by the client.
+func (p Proxy) ClientIP(peer netip.Addr, forwardedFor []string) netip.Addr {
+	peer = peer.Unmap()
+	if !p.TrustForwardedFor || (len(p.TrustedProxies) > 0 && !p.trusted(peer)) {
+		return peer
+	}
+	var hops []string
+	for _, header := range forwardedFor {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = addr.Unmap()
+		if !p.trusted(client) {
+			break
+		}
+	}
+	return client
+}
+
+// trusted reports whether addr is one of the trusted proxies
+func (p Proxy) trusted(addr netip.Addr) bool {
+	for _, prefix := range p.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestLoadProxy(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    Proxy
+		wantErr bool
+	}{
+		{"unset", nil, Proxy{}, false},
+		{"trust proxy", map[string]string{"TRUST_PROXY": "true"}, Proxy{TrustForwardedFor: true}, false},
+		{
+			"trusted proxies", map[string]string{"TRUSTED_PROXIES": "10.0.0.1, 192.168.0.0/16,fd00::/8"},
+			Proxy{TrustedProxies: []netip.Prefix{
+				netip.MustParsePrefix("10.0.0.1/32"), netip.MustParsePrefix("192.168.0.0/16"), netip.MustParsePrefix("fd00::/8"),
+			}},
+			false,
+		},
+		{"invalid trusted proxy", map[string]string{"TRUSTED_PROXIES": "10.0.0.1,proxy.internal"}, Proxy{}, true},
+		{"invalid trust proxy", map[string]string{"TRUST_PROXY": "sometimes"}, Proxy{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadProxy(func(key string) string { return tt.env[key] })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadProxy error = %v; wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadProxy = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	tests := []struct {
+		name         string
+		proxy        Proxy
+		peer         string
+		forwardedFor []string
+		want         string
+	}{
+		{"header ignored without TRUST_PROXY", Proxy{}, "203.0.113.9", []string{"81.2.69.142"}, "203.0.113.9"},
+		{"header ignored from an untrusted peer", Proxy{TrustForwardedFor: true, TrustedProxies: trusted}, "203.0.113.9", []string{"81.2.69.142"}, "203.0.113.9"},
+		{"no header", Proxy{TrustForwardedFor: true}, "10.0.0.1", nil, "10.0.0.1"},
+		{"single proxy", Proxy{TrustForwardedFor: true}, "10.0.0.1", []string{"81.2.69.142"}, "81.2.69.142"},
+		{"client-supplied hops are skipped", Proxy{TrustForwardedFor: true}, "10.0.0.1", []string{"1.1.1.1, 81.2.69.142"}, "81.2.69.142"},
+		{"trusted hops are skipped", Proxy{TrustForwardedFor: true, TrustedProxies: trusted}, "10.0.0.1", []string{"1.1.1.1, 81.2.69.142, 10.0.0.2"}, "81.2.69.142"},
+		{"repeated headers", Proxy{TrustForwardedFor: true, TrustedProxies: trusted}, "10.0.0.1", []string{"1.1.1.1", "81.2.69.142", "10.0.0.2"}, "81.2.69.142"},
+		{"garbage stops the walk", Proxy{TrustForwardedFor: true, TrustedProxies: trusted}, "10.0.0.1", []string{"81.2.69.142, unknown, 10.0.0.2"}, "10.0.0.2"},
+		{"IPv4-mapped peer", Proxy{}, "::ffff:203.0.113.9", nil, "203.0.113.9"},
+	}
+
+	for _, tt := range tests {
+		got := tt.proxy.ClientIP(netip.MustParseAddr(tt.peer), tt.forwardedFor)
+		if got != netip.MustParseAddr(tt.want) {
+			t.Errorf("%s: ClientIP = %s; want %s", tt.name, got, tt.want)
+		}
+	}
+}
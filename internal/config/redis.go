@@ -0,0 +1,115 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisAddr is the Redis server used when REDIS_URL is unset
+const DefaultRedisAddr = "localhost:6379"
+
+// Redis is how the server connects to Redis
+type Redis struct {
+	Options *redis.Options
+	// Required makes startup fail when Redis can't be reached, instead of
+	// carrying on with SQLite only
+	Required bool
+}
+
+// LoadRedis reads the Redis connection settings using getenv. REDIS_URL is
+// either host:port or a redis:// or rediss:// URL, which may carry credentials
+// and a database number. REDIS_PASSWORD, REDIS_DB, REDIS_TLS, REDIS_POOL_SIZE,
+// and REDIS_MIN_IDLE_CONNS override what the URL sets; REDIS_REQUIRED makes a
+// failed connection fatal.
+func LoadRedis(getenv func(string) string) (Redis, error) {
+	opts, err := redisURLOptions(strings.TrimSpace(getenv("REDIS_URL")))
+	if err != nil {
+		return Redis{}, err
+	}
+	// Callers bound Redis round trips with their context's deadline, which
+	// go-redis ignores in favour of its socket timeouts unless told otherwise
+	opts.ContextTimeoutEnabled = true
+	r := Redis{Options: opts}
+
+	if password := getenv("REDIS_PASSWORD"); password != "" {
+		opts.Password = password
+	}
+	if raw := strings.TrimSpace(getenv("REDIS_DB")); raw != "" {
+		db, err := strconv.Atoi(raw)
+		if err != nil || db < 0 {
+			return Redis{}, fmt.Errorf("invalid REDIS_DB %q: must be a non-negative database number", raw)
+		}
+		opts.DB = db
+	}
+	if raw := strings.TrimSpace(getenv("REDIS_TLS")); raw != "" {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return Redis{}, fmt.Errorf("invalid REDIS_TLS %q: must be true or false", raw)
+		}
+		switch {
+		case !enabled:
+			opts.TLSConfig = nil
+		case opts.TLSConfig == nil:
+			host, _, _ := net.SplitHostPort(opts.Addr)
+			opts.TLSConfig = &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+		}
+	}
+	if raw := strings.TrimSpace(getenv("REDIS_POOL_SIZE")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return Redis{}, fmt.Errorf("invalid REDIS_POOL_SIZE %q: must be a positive number of connections", raw)
+		}
+		opts.PoolSize = n
+	}
+	if raw := strings.TrimSpace(getenv("REDIS_MIN_IDLE_CONNS")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return Redis{}, fmt.Errorf("invalid REDIS_MIN_IDLE_CONNS %q: must be a non-negative number of connections", raw)
+		}
+		opts.MinIdleConns = n
+	}
+	if raw := strings.TrimSpace(getenv("REDIS_REQUIRED")); raw != "" {
+		required, err := strconv.ParseBool(raw)
+		if err != nil {
+			return Redis{}, fmt.Errorf("invalid REDIS_REQUIRED %q: must be true or false", raw)
+		}
+		r.Required = required
+	}
+	return r, nil
+}
+
+// redisURLOptions parses REDIS_URL, which is a plain host:port unless it has a
+// scheme. Errors never quote the URL, since it may hold a password.
+func redisURLOptions(raw string) (*redis.Options, error) {
+	if raw == "" {
+		return &redis.Options{Addr: DefaultRedisAddr}, nil
+	}
+	if !strings.Contains(raw, "://") {
+		if _, port, err := net.SplitHostPort(raw); err != nil || port == "" {
+			return nil, fmt.Errorf("invalid REDIS_URL %q: must be host:port or a redis:// or rediss:// URL", raw)
+		}
+		return &redis.Options{Addr: raw}, nil
+	}
+
+	scheme, _, _ := strings.Cut(raw, "://")
+	if scheme != "redis" && scheme != "rediss" {
+		return nil, fmt.Errorf("invalid REDIS_URL: scheme %q must be redis or rediss", scheme)
+	}
+	opts, err := redis.ParseURL(raw)
+	if err != nil {
+		// url.Error repeats the whole URL
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+	return opts, nil
+}
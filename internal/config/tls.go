@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// TLS is the certificate pair the server terminates HTTPS with
+type TLS struct {
+	CertFile string
+	KeyFile  string
+	// AutoReload picks up renewed certificates without a restart
+	AutoReload bool
+}
+
+// Enabled reports whether a certificate pair is configured
+func (t TLS) Enabled() bool {
+	return t.CertFile != ""
+}
+
+// LoadTLS reads TLS_CERT_FILE, TLS_KEY_FILE, and TLS_AUTO_RELOAD using getenv.
+// The files must be set together and readable; with neither set the server
+// speaks plain HTTP.
+func LoadTLS(getenv func(string) string) (TLS, error) {
+	t := TLS{CertFile: strings.TrimSpace(getenv("TLS_CERT_FILE")), KeyFile: strings.TrimSpace(getenv("TLS_KEY_FILE"))}
+	switch {
+	case t.CertFile == "" && t.KeyFile == "":
+		return TLS{}, nil
+	case t.CertFile == "":
+		return TLS{}, fmt.Errorf("TLS_KEY_FILE is set but TLS_CERT_FILE is not; set both to serve HTTPS")
+	case t.KeyFile == "":
+		return TLS{}, fmt.Errorf("TLS_CERT_FILE is set but TLS_KEY_FILE is not; set both to serve HTTPS")
+	}
+	for _, file := range []string{t.CertFile, t.KeyFile} {
+		f, err := os.Open(file)
+		if err != nil {
+			return TLS{}, fmt.Errorf("can't read TLS file: %w", err)
+		}
+		f.Close()
+	}
+
+	if raw := strings.TrimSpace(getenv("TLS_AUTO_RELOAD")); raw != "" {
+		reload, err := strconv.ParseBool(raw)
+		if err != nil {
+			return TLS{}, fmt.Errorf("invalid TLS_AUTO_RELOAD %q: must be true or false", raw)
+		}
+		t.AutoReload = reload
+	}
+	return t, nil
+}
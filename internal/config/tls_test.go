@@ -0,0 +1,46 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadTLS(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	for _, f := range []string{cert, key} {
+		if err := os.WriteFile(f, []byte("pem"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing := filepath.Join(dir, "missing.pem")
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    TLS
+		wantErr bool
+	}{
+		{"unset", nil, TLS{}, false},
+		{"pair", map[string]string{"TLS_CERT_FILE": cert, "TLS_KEY_FILE": key}, TLS{CertFile: cert, KeyFile: key}, false},
+		{"auto reload", map[string]string{"TLS_CERT_FILE": cert, "TLS_KEY_FILE": key, "TLS_AUTO_RELOAD": "true"}, TLS{CertFile: cert, KeyFile: key, AutoReload: true}, false},
+		{"cert only", map[string]string{"TLS_CERT_FILE": cert}, TLS{}, true},
+		{"key only", map[string]string{"TLS_KEY_FILE": key}, TLS{}, true},
+		{"unreadable cert", map[string]string{"TLS_CERT_FILE": missing, "TLS_KEY_FILE": key}, TLS{}, true},
+		{"unreadable key", map[string]string{"TLS_CERT_FILE": cert, "TLS_KEY_FILE": missing}, TLS{}, true},
+		{"invalid auto reload", map[string]string{"TLS_CERT_FILE": cert, "TLS_KEY_FILE": key, "TLS_AUTO_RELOAD": "sometimes"}, TLS{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadTLS(func(key string) string { return tt.env[key] })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadTLS error = %v; wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("LoadTLS = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"weather-api-go/internal/models"
+)
+
+// LoadWarmLocations reads WARM_LOCATIONS using getenv: semicolon-separated
+// lat,lon pairs such as "40.7128,-74.0060;47.6062,-122.3321". Unset means no
+// configured locations.
+func LoadWarmLocations(getenv func(string) string) ([]models.Coordinates, error) {
+	raw := strings.TrimSpace(getenv("WARM_LOCATIONS"))
+	if raw == "" {
+		return nil, nil
+	}
+
+	var locations []models.Coordinates
+	for _, pair := range strings.Split(raw, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		latStr, lonStr, ok := strings.Cut(pair, ",")
+		if !ok {
+			return nil, fmt.Errorf("invalid WARM_LOCATIONS entry %q: must be lat,lon", pair)
+		}
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+		lon, lonErr := strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+		if latErr != nil || lonErr != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			return nil, fmt.Errorf("invalid WARM_LOCATIONS entry %q: must be a latitude (-90 to 90) and longitude (-180 to 180)", pair)
+		}
+		locations = append(locations, models.Coordinates{Latitude: lat, Longitude: lon})
+	}
+	return locations, nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"weather-api-go/internal/models"
+)
+
+func TestLoadWarmLocations(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []models.Coordinates
+		wantErr bool
+	}{
+		{"unset", "", nil, false},
+		{"one", "40.7128,-74.0060", []models.Coordinates{{Latitude: 40.7128, Longitude: -74.006}}, false},
+		{"several with spaces", " 40.7128, -74.0060 ; 47.6062,-122.3321; ", []models.Coordinates{
+			{Latitude: 40.7128, Longitude: -74.006}, {Latitude: 47.6062, Longitude: -122.3321},
+		}, false},
+		{"missing longitude", "40.7128", nil, true},
+		{"not a number", "north,-74.0060", nil, true},
+		{"out of range", "95,-74.0060", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadWarmLocations(func(key string) string {
+				if key == "WARM_LOCATIONS" {
+					return tt.value
+				}
+				return ""
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadWarmLocations error = %v; wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadWarmLocations = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
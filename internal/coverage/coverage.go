@@ -0,0 +1,104 @@
+// Package coverage tells whether a coordinate falls inside the area the NWS
+// forecasts for, so requests that the points API would reject with a 404 can be
+// turned away without an upstream call.
+package coverage
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+)
+
+// coverageGeoJSON holds simplified outlines of the NWS forecast areas: CONUS,
+// Alaska (split at the antimeridian), Hawaii, Puerto Rico and the U.S. Virgin
+// Islands, and Guam. Outlines follow land borders and run a little offshore
+// along coasts, so near-shore points are never rejected.
+//
+//go:embed coverage.geojson
+var coverageGeoJSON []byte
+
+// point is a [longitude, latitude] pair, in GeoJSON order
+type point [2]float64
+
+// ring is a closed polygon outline
+type ring []point
+
+// Region is a named forecast area made of one or more polygons
+type Region struct {
+	Name     string
+	polygons []ring
+}
+
+// regions are the embedded forecast areas
+var regions = mustLoadRegions(coverageGeoJSON)
+
+func mustLoadRegions(data []byte) []Region {
+	var fc struct {
+		Features []struct {
+			Properties struct {
+				Name string `json:"name"`
+			} `json:"properties"`
+			Geometry struct {
+				Type        string   `json:"type"`
+				Coordinates [][]ring `json:"coordinates"`
+			} `json:"geometry"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &fc); err != nil {
+		panic(fmt.Sprintf("coverage: invalid geometry: %v", err))
+	}
+
+	out := make([]Region, 0, len(fc.Features))
+	for _, f := range fc.Features {
+		if f.Geometry.Type != "MultiPolygon" {
+			panic(fmt.Sprintf("coverage: %s is a %s; want MultiPolygon", f.Properties.Name, f.Geometry.Type))
+		}
+		region := Region{Name: f.Properties.Name}
+		for _, polygon := range f.Geometry.Coordinates {
+			// Only outer rings: the simplified outlines have no holes
+			region.polygons = append(region.polygons, polygon[0])
+		}
+		out = append(out, region)
+	}
+	return out
+}
+
+// Locate returns the name of the forecast area containing a coordinate
+func Locate(lat, lon float64) (string, bool) {
+	for _, r := range regions {
+		if r.Contains(lat, lon) {
+			return r.Name, true
+		}
+	}
+	return "", false
+}
+
+// Covered reports whether a coordinate lies inside any NWS forecast area
+func Covered(lat, lon float64) bool {
+	_, ok := Locate(lat, lon)
+	return ok
+}
+
+// Contains reports whether a coordinate lies inside the region
+func (r Region) Contains(lat, lon float64) bool {
+	for _, polygon := range r.polygons {
+		if polygon.contains(lon, lat) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether (x, y) lies inside the ring, by counting how many
+// edges a ray cast from the point towards +x crosses (even-odd rule)
+func (rg ring) contains(x, y float64) bool {
+	inside := false
+	for i, j := 0, len(rg)-1; i < len(rg); j, i = i, i+1 {
+		xi, yi := rg[i][0], rg[i][1]
+		xj, yj := rg[j][0], rg[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
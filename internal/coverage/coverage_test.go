@@ -0,0 +1,81 @@
+package coverage
+
+import "testing"
+
+func TestRingContains(t *testing.T) {
+	// A concave "U": the notch between the arms is outside
+	u := ring{{0, 0}, {3, 0}, {3, 3}, {2, 3}, {2, 1}, {1, 1}, {1, 3}, {0, 3}, {0, 0}}
+	tests := []struct {
+		x, y float64
+		want bool
+	}{
+		{0.5, 0.5, true},
+		{0.5, 2.5, true},
+		{2.5, 2.5, true},
+		{1.5, 2, false},
+		{1.5, 0.5, true},
+		{-1, 1, false},
+		{4, 1, false},
+		{1.5, 4, false},
+	}
+	for _, tt := range tests {
+		if got := u.contains(tt.x, tt.y); got != tt.want {
+			t.Errorf("contains(%v, %v) = %v; want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestLocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     string
+	}{
+		{"New York", 40.7128, -74.0060, "CONUS"},
+		{"Seattle", 47.6062, -122.3321, "CONUS"},
+		{"San Diego", 32.7157, -117.1611, "CONUS"},
+		{"El Paso", 31.7619, -106.4850, "CONUS"},
+		{"Duluth", 46.7867, -92.1005, "CONUS"},
+		{"Buffalo", 42.8864, -78.8784, "CONUS"},
+		{"Bar Harbor", 44.3876, -68.2039, "CONUS"},
+		{"Key West", 24.5551, -81.7800, "CONUS"},
+		{"New Orleans", 29.9511, -90.0715, "CONUS"},
+		{"Brownsville", 25.9017, -97.4975, "CONUS"},
+		{"Farallon Islands", 37.6989, -123.0034, "CONUS"},
+		{"45th parallel border", 45.0, -74.0, "CONUS"},
+		{"49th parallel border", 49.0, -100.0, "CONUS"},
+		{"Anchorage", 61.2181, -149.9003, "Alaska"},
+		{"Utqiagvik", 71.2906, -156.7886, "Alaska"},
+		{"Juneau", 58.3019, -134.4197, "Alaska"},
+		{"Unalaska", 53.8739, -166.5367, "Alaska"},
+		{"St. Paul Island", 57.1253, -170.2856, "Alaska"},
+		{"Attu", 52.8400, 173.1800, "Alaska"},
+		{"Honolulu", 21.3069, -157.8583, "Hawaii"},
+		{"Hilo", 19.7241, -155.0868, "Hawaii"},
+		{"Lihue", 21.9811, -159.3711, "Hawaii"},
+		{"San Juan", 18.4655, -66.1057, "Puerto Rico and U.S. Virgin Islands"},
+		{"Christiansted", 17.7466, -64.7032, "Puerto Rico and U.S. Virgin Islands"},
+		{"Hagatna", 13.4757, 144.7489, "Guam"},
+
+		{"Vancouver", 49.2827, -123.1207, ""},
+		{"Toronto", 43.6532, -79.3832, ""},
+		{"Montreal", 45.5017, -73.5673, ""},
+		{"Ensenada", 31.8667, -116.5964, ""},
+		{"Monterrey", 25.6866, -100.3161, ""},
+		{"Havana", 23.1136, -82.3666, ""},
+		{"Nassau", 25.0443, -77.3504, ""},
+		{"Road Town", 18.4286, -64.6185, ""},
+		{"Whitehorse", 60.7212, -135.0568, ""},
+		{"Provideniya", 64.4233, -173.2286, ""},
+		{"Mid-Atlantic", 35.0, -60.0, ""},
+		{"Gulf of Mexico", 25.0, -90.0, ""},
+		{"North Pacific", 40.0, -135.0, ""},
+		{"Null Island", 0, 0, ""},
+	}
+	for _, tt := range tests {
+		got, ok := Locate(tt.lat, tt.lon)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("%s (%v, %v): Locate = %q, %v; want %q", tt.name, tt.lat, tt.lon, got, ok, tt.want)
+		}
+	}
+}
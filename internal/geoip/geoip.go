@@ -0,0 +1,84 @@
+// Package geoip estimates locations from IP addresses with a MaxMind GeoLite2
+// or GeoIP2 City database.
+package geoip
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+
+	"weather-api-go/internal/models"
+)
+
+// DB is an opened City database. It is safe for concurrent use.
+type DB struct {
+	db *mmdb
+}
+
+// Open reads the MaxMind DB file at path into memory. Databases without
+// coordinates, such as GeoLite2 Country, are rejected.
+func Open(path string) (*DB, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	db, err := parseMMDB(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if dbType, _ := db.metadata["database_type"].(string); !strings.Contains(dbType, "City") {
+		return nil, fmt.Errorf("%s: %q is not a City database", path, dbType)
+	}
+	return &DB{db: db}, nil
+}
+
+// LocateIP returns the approximate location of an address, or nil when the
+// database has no coordinates for it. Private, loopback, and other
+// non-routable addresses are never located.
+func (g *DB) LocateIP(addr netip.Addr) (*models.IPLocation, error) {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return nil, nil
+	}
+	v, err := g.db.lookup(addr)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	record, _ := v.(map[string]any)
+
+	location, _ := record["location"].(map[string]any)
+	lat, latOK := location["latitude"].(float64)
+	lon, lonOK := location["longitude"].(float64)
+	if !latOK || !lonOK {
+		return nil, nil
+	}
+	accuracy, _ := location["accuracy_radius"].(uint64)
+
+	loc := &models.IPLocation{
+		Latitude:   lat,
+		Longitude:  lon,
+		AccuracyKm: int(accuracy),
+		City:       englishName(record["city"]),
+		Country:    isoCode(record["country"]),
+	}
+	if subdivisions, _ := record["subdivisions"].([]any); len(subdivisions) > 0 {
+		loc.Region = isoCode(subdivisions[0])
+	}
+	return loc, nil
+}
+
+// englishName returns the English name of a city, country, or subdivision record
+func englishName(v any) string {
+	record, _ := v.(map[string]any)
+	names, _ := record["names"].(map[string]any)
+	name, _ := names["en"].(string)
+	return name
+}
+
+// isoCode returns the ISO code of a country or subdivision record
+func isoCode(v any) string {
+	record, _ := v.(map[string]any)
+	code, _ := record["iso_code"].(string)
+	return code
+}
@@ -0,0 +1,82 @@
+package geoip
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"weather-api-go/internal/models"
+)
+
+// writeTestDB writes a database of the given type to a temporary file
+func writeTestDB(t *testing.T, dbType string, networks map[string]map[string]any) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(path, buildMMDB(28, 6, dbType, networks), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLocateIP(t *testing.T) {
+	path := writeTestDB(t, "GeoLite2-City", map[string]map[string]any{
+		// Shaped like a GeoLite2 City record
+		"81.2.69.0/24": {
+			"city":         map[string]any{"geoname_id": uint32(2643743), "names": map[string]any{"en": "London", "de": "London"}},
+			"country":      map[string]any{"geoname_id": uint32(2635167), "iso_code": "GB", "names": map[string]any{"en": "United Kingdom"}},
+			"location":     map[string]any{"accuracy_radius": uint16(10), "latitude": 51.5142, "longitude": -0.0931, "time_zone": "Europe/London"},
+			"subdivisions": []any{map[string]any{"iso_code": "ENG", "names": map[string]any{"en": "England"}}},
+		},
+		// Country-level matches carry no coordinates
+		"2.125.160.0/19": {
+			"country": map[string]any{"iso_code": "GB"},
+		},
+		// So do private networks in a database that lists them
+		"10.0.0.0/8": {
+			"location": map[string]any{"latitude": 1.0, "longitude": 1.0},
+		},
+	})
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		addr string
+		want *models.IPLocation
+	}{
+		{"81.2.69.142", &models.IPLocation{Latitude: 51.5142, Longitude: -0.0931, AccuracyKm: 10, City: "London", Region: "ENG", Country: "GB"}},
+		{"2.125.160.216", nil},
+		{"8.8.8.8", nil},
+		{"10.1.2.3", nil},
+		{"127.0.0.1", nil},
+		{"::1", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := db.LocateIP(netip.MustParseAddr(tt.addr))
+		if err != nil {
+			t.Fatalf("LocateIP(%s): %v", tt.addr, err)
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("LocateIP(%s) = %+v; want %+v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if _, err := Open(writeTestDB(t, "GeoLite2-Country", nil)); err == nil {
+		t.Error("Open of a Country database succeeded; want an error")
+	}
+	garbage := filepath.Join(t.TempDir(), "garbage.mmdb")
+	if err := os.WriteFile(garbage, []byte("not a database"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(garbage); err == nil {
+		t.Error("Open of a non-database file succeeded; want an error")
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.mmdb")); err == nil {
+		t.Error("Open of a missing file succeeded; want an error")
+	}
+}
@@ -0,0 +1,329 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+)
+
+// metadataMarker precedes the metadata map at the end of a MaxMind DB file
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// dataSectionSeparator is the run of zero bytes between the search tree and
+// the data section
+const dataSectionSeparator = 16
+
+// maxDecodeDepth bounds nesting in the data section, so a corrupt file can't
+// recurse without limit
+const maxDecodeDepth = 32
+
+// errCorrupt reports a file that isn't a well-formed MaxMind DB
+var errCorrupt = errors.New("malformed MaxMind DB")
+
+// mmdb reads records from a MaxMind DB (.mmdb) file held in memory. See
+// https://maxmind.github.io/MaxMind-DB/ for the format.
+type mmdb struct {
+	buf        []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	// dataStart is the offset of the data section in buf
+	dataStart uint
+	// ipv4Start is the node IPv4 lookups start from in an IPv6 tree
+	ipv4Start uint
+	// metadata is the decoded metadata map
+	metadata map[string]any
+}
+
+// parseMMDB validates a MaxMind DB file and prepares it for lookups
+func parseMMDB(buf []byte) (*mmdb, error) {
+	i := bytes.LastIndex(buf, metadataMarker)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: no metadata section", errCorrupt)
+	}
+	metaStart := uint(i + len(metadataMarker))
+	meta := decoder{buf: buf[metaStart:]}
+	v, _, err := meta.decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: metadata: %v", errCorrupt, err)
+	}
+	metadata, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", errCorrupt)
+	}
+
+	db := &mmdb{buf: buf, metadata: metadata}
+	db.nodeCount = metadataUint(metadata, "node_count")
+	db.recordSize = metadataUint(metadata, "record_size")
+	db.ipVersion = metadataUint(metadata, "ip_version")
+	if major := metadataUint(metadata, "binary_format_major_version"); major != 2 {
+		return nil, fmt.Errorf("%w: unsupported format version %d", errCorrupt, major)
+	}
+	if db.recordSize != 24 && db.recordSize != 28 && db.recordSize != 32 {
+		return nil, fmt.Errorf("%w: unsupported record size %d", errCorrupt, db.recordSize)
+	}
+	if db.ipVersion != 4 && db.ipVersion != 6 {
+		return nil, fmt.Errorf("%w: unsupported IP version %d", errCorrupt, db.ipVersion)
+	}
+	treeSize := db.nodeCount * db.recordSize / 4
+	db.dataStart = treeSize + dataSectionSeparator
+	if db.dataStart > uint(i) {
+		return nil, fmt.Errorf("%w: search tree overruns the file", errCorrupt)
+	}
+
+	// IPv4 addresses live under ::/96 in an IPv6 tree
+	if db.ipVersion == 6 {
+		for depth := 0; depth < 96 && db.ipv4Start < db.nodeCount; depth++ {
+			db.ipv4Start = db.record(db.ipv4Start, 0)
+		}
+	}
+	return db, nil
+}
+
+// metadataUint reads an unsigned metadata field, or 0 when it is missing
+func metadataUint(metadata map[string]any, key string) uint {
+	v, _ := metadata[key].(uint64)
+	return uint(v)
+}
+
+// record reads one of a search tree node's two records: 0 for the left
+// (zero bit) branch, 1 for the right
+func (db *mmdb) record(node uint, bit uint) uint {
+	b := db.buf[node*db.recordSize/4:]
+	switch db.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// lookup returns the record for an address, or nil when the file has none
+func (db *mmdb) lookup(addr netip.Addr) (any, error) {
+	addr = addr.Unmap()
+	node, bits := uint(0), addr.AsSlice()
+	switch {
+	case addr.Is4() && db.ipVersion == 6:
+		node = db.ipv4Start
+	case addr.Is6() && db.ipVersion == 4:
+		return nil, nil
+	}
+
+	for i := 0; i < len(bits)*8 && node < db.nodeCount; i++ {
+		bit := uint(bits[i/8]>>(7-i%8)) & 1
+		node = db.record(node, bit)
+	}
+	switch {
+	case node == db.nodeCount:
+		return nil, nil
+	case node < db.nodeCount:
+		return nil, fmt.Errorf("%w: search tree is deeper than the address", errCorrupt)
+	}
+
+	offset := node - db.nodeCount - dataSectionSeparator
+	data := decoder{buf: db.buf[db.dataStart:]}
+	v, _, err := data.decode(offset, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errCorrupt, err)
+	}
+	return v, nil
+}
+
+// Data section field types
+const (
+	typeExtended  = 0
+	typePointer   = 1
+	typeString    = 2
+	typeDouble    = 3
+	typeBytes     = 4
+	typeUint16    = 5
+	typeUint32    = 6
+	typeMap       = 7
+	typeInt32     = 8
+	typeUint64    = 9
+	typeUint128   = 10
+	typeArray     = 11
+	typeContainer = 12
+	typeEndMarker = 13
+	typeBool      = 14
+	typeFloat     = 15
+)
+
+// decoder decodes values from a data section. Maps decode to map[string]any,
+// arrays to []any, unsigned integers to uint64, int32 to int64, doubles and
+// floats to float64, and uint128 to its big-endian bytes.
+type decoder struct {
+	buf []byte
+}
+
+// decode decodes the value at offset, returning it and the offset just past it
+func (d decoder) decode(offset uint, depth int) (any, uint, error) {
+	if depth > maxDecodeDepth {
+		return nil, 0, errors.New("data nested too deeply")
+	}
+	typ, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typ == typePointer {
+		// A pointer's target is decoded in its place, and is never itself a pointer
+		target, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		if t, _, _, err := d.control(target); err != nil || t == typePointer {
+			return nil, 0, errors.New("pointer to a pointer")
+		}
+		v, _, err := d.decode(target, depth+1)
+		return v, next, err
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]any, size)
+		for range size {
+			var key, value any
+			if key, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is not a string")
+			}
+			if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			m[k] = value
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, size)
+		for range size {
+			var value any
+			if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	end := offset + size
+	if end > uint(len(d.buf)) || end < offset {
+		return nil, 0, errors.New("value overruns the data section")
+	}
+	b := d.buf[offset:end]
+	switch typ {
+	case typeString:
+		return string(b), end, nil
+	case typeBytes, typeUint128:
+		return append([]byte(nil), b...), end, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("double of %d bytes", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), end, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("float of %d bytes", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), end, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, fmt.Errorf("integer of %d bytes", size)
+		}
+		return uintFrom(b), end, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("int32 of %d bytes", size)
+		}
+		return int64(int32(uintFrom(b))), end, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported data type %d", typ)
+}
+
+// control reads the control byte (and any extended type and size bytes) of the
+// field at offset, returning its type, payload size, and payload offset. For
+// pointers the size is the control byte's low five bits, which pointer reads.
+func (d decoder) control(offset uint) (typ, size, next uint, err error) {
+	if offset >= uint(len(d.buf)) {
+		return 0, 0, 0, errors.New("offset outside the data section")
+	}
+	ctrl := d.buf[offset]
+	offset++
+	typ = uint(ctrl >> 5)
+	if typ == typePointer {
+		return typ, uint(ctrl & 0x1f), offset, nil
+	}
+	if typ == typeExtended {
+		if offset >= uint(len(d.buf)) {
+			return 0, 0, 0, errors.New("truncated extended type")
+		}
+		typ = 7 + uint(d.buf[offset])
+		offset++
+		if typ == typeContainer || typ == typeEndMarker || typ < 8 {
+			return 0, 0, 0, fmt.Errorf("unsupported data type %d", typ)
+		}
+	}
+
+	size = uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d.buf)) {
+			return 0, 0, 0, errors.New("truncated size")
+		}
+		extra := uint(uintFrom(d.buf[offset : offset+n]))
+		offset += n
+		switch n {
+		case 1:
+			size = 29 + extra
+		case 2:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+	return typ, size, offset, nil
+}
+
+// pointer reads a pointer's target offset from the bits of its control byte
+// and the bytes that follow it
+func (d decoder) pointer(ctrl, offset uint) (target, next uint, err error) {
+	n := ctrl>>3 + 1
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, errors.New("truncated pointer")
+	}
+	v := uint(uintFrom(d.buf[offset : offset+n]))
+	switch n {
+	case 1:
+		target = (ctrl&0x7)<<8 | v
+	case 2:
+		target = ((ctrl&0x7)<<16 | v) + 2048
+	case 3:
+		target = ((ctrl&0x7)<<24 | v) + 526336
+	default:
+		target = v
+	}
+	return target, offset + n, nil
+}
+
+// uintFrom decodes a big-endian unsigned integer of up to eight bytes
+func uintFrom(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
@@ -0,0 +1,278 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// encodeValue appends a value to a data section in the MaxMind DB encoding.
+// It supports the Go types decode produces, plus uint16 and uint32.
+func encodeValue(buf *bytes.Buffer, v any) {
+	control := func(typ, size int) {
+		ctrl := byte(0)
+		if typ <= 7 {
+			ctrl = byte(typ << 5)
+		}
+		switch {
+		case size < 29:
+			ctrl |= byte(size)
+		case size < 285:
+			ctrl |= 29
+		case size < 65821:
+			ctrl |= 30
+		default:
+			ctrl |= 31
+		}
+		buf.WriteByte(ctrl)
+		if typ > 7 {
+			buf.WriteByte(byte(typ - 7))
+		}
+		switch {
+		case size < 29:
+		case size < 285:
+			buf.WriteByte(byte(size - 29))
+		case size < 65821:
+			buf.Write(binary.BigEndian.AppendUint16(nil, uint16(size-285)))
+		default:
+			buf.Write(binary.BigEndian.AppendUint32(nil, uint32(size-65821))[1:])
+		}
+	}
+	unsigned := func(typ int, v uint64) {
+		b := binary.BigEndian.AppendUint64(nil, v)
+		b = bytes.TrimLeft(b, "\x00")
+		control(typ, len(b))
+		buf.Write(b)
+	}
+
+	switch v := v.(type) {
+	case string:
+		control(typeString, len(v))
+		buf.WriteString(v)
+	case float64:
+		control(typeDouble, 8)
+		buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(v)))
+	case uint16:
+		unsigned(typeUint16, uint64(v))
+	case uint32:
+		unsigned(typeUint32, uint64(v))
+	case uint64:
+		unsigned(typeUint64, v)
+	case bool:
+		size := 0
+		if v {
+			size = 1
+		}
+		control(typeBool, size)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		control(typeMap, len(v))
+		for _, k := range keys {
+			encodeValue(buf, k)
+			encodeValue(buf, v[k])
+		}
+	case []any:
+		control(typeArray, len(v))
+		for _, e := range v {
+			encodeValue(buf, e)
+		}
+	default:
+		panic("unsupported test value")
+	}
+}
+
+// buildMMDB writes a MaxMind DB holding a record for each network. IPv4
+// networks in an IPv6 database are placed under ::/96.
+func buildMMDB(recordSize, ipVersion int, dbType string, networks map[string]map[string]any) []byte {
+	type node struct {
+		child [2]*node
+		leaf  bool
+		data  int
+		index int
+	}
+	root := &node{}
+	var data bytes.Buffer
+	for cidr, record := range networks {
+		prefix := netip.MustParsePrefix(cidr)
+		offset := data.Len()
+		encodeValue(&data, record)
+
+		addr, bits := prefix.Addr().AsSlice(), prefix.Bits()
+		if ipVersion == 6 && prefix.Addr().Is4() {
+			addr = append(make([]byte, 12), addr...)
+			bits += 96
+		}
+		n := root
+		for i := range bits {
+			bit := addr[i/8] >> (7 - i%8) & 1
+			if i == bits-1 {
+				n.child[bit] = &node{leaf: true, data: offset}
+				break
+			}
+			if n.child[bit] == nil {
+				n.child[bit] = &node{}
+			}
+			n = n.child[bit]
+		}
+	}
+
+	var internal []*node
+	var number func(n *node)
+	number = func(n *node) {
+		if n == nil || n.leaf {
+			return
+		}
+		n.index = len(internal)
+		internal = append(internal, n)
+		number(n.child[0])
+		number(n.child[1])
+	}
+	number(root)
+	nodeCount := len(internal)
+	value := func(n *node) int {
+		switch {
+		case n == nil:
+			return nodeCount
+		case n.leaf:
+			return nodeCount + dataSectionSeparator + n.data
+		}
+		return n.index
+	}
+
+	var buf bytes.Buffer
+	for _, n := range internal {
+		left, right := value(n.child[0]), value(n.child[1])
+		switch recordSize {
+		case 24:
+			buf.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left), byte(right >> 16), byte(right >> 8), byte(right)})
+		case 28:
+			buf.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left), byte(left>>24)<<4 | byte(right>>24)&0x0f,
+				byte(right >> 16), byte(right >> 8), byte(right)})
+		case 32:
+			buf.Write(binary.BigEndian.AppendUint32(nil, uint32(left)))
+			buf.Write(binary.BigEndian.AppendUint32(nil, uint32(right)))
+		}
+	}
+	buf.Write(make([]byte, dataSectionSeparator))
+	buf.Write(data.Bytes())
+	buf.Write(metadataMarker)
+	encodeValue(&buf, map[string]any{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"database_type":               dbType,
+		"ip_version":                  uint16(ipVersion),
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(recordSize),
+	})
+	return buf.Bytes()
+}
+
+func TestMMDBLookup(t *testing.T) {
+	london := map[string]any{"city": map[string]any{"names": map[string]any{"en": "London"}}}
+	tokyo := map[string]any{"city": map[string]any{"names": map[string]any{"en": "Tokyo"}}}
+	networks := map[string]map[string]any{"81.2.69.0/24": london, "2001:218::/32": tokyo}
+
+	tests := []struct {
+		addr string
+		want map[string]any
+		v6   bool // only an IPv6 database holds the record
+	}{
+		{"81.2.69.142", london, false},
+		{"::ffff:81.2.69.142", london, false},
+		{"81.2.70.1", nil, false},
+		{"2001:218:1::1", tokyo, true},
+		{"2001:219::1", nil, true},
+	}
+
+	for _, ipVersion := range []int{4, 6} {
+		for _, recordSize := range []int{24, 28, 32} {
+			nets := map[string]map[string]any{"81.2.69.0/24": london}
+			if ipVersion == 6 {
+				nets = networks
+			}
+			db, err := parseMMDB(buildMMDB(recordSize, ipVersion, "GeoLite2-City", nets))
+			if err != nil {
+				t.Fatalf("IPv%d/%d-bit: %v", ipVersion, recordSize, err)
+			}
+			for _, tt := range tests {
+				want := tt.want
+				if tt.v6 && ipVersion == 4 {
+					want = nil
+				}
+				got, err := db.lookup(netip.MustParseAddr(tt.addr))
+				if err != nil {
+					t.Fatalf("IPv%d/%d-bit lookup(%s): %v", ipVersion, recordSize, tt.addr, err)
+				}
+				if (want == nil) != (got == nil) || (want != nil && !reflect.DeepEqual(got, any(want))) {
+					t.Errorf("IPv%d/%d-bit lookup(%s) = %v; want %v", ipVersion, recordSize, tt.addr, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	var buf bytes.Buffer
+	encodeValue(&buf, map[string]any{
+		"long":  long,
+		"flags": []any{true, false},
+		"big":   uint64(1) << 40,
+		"small": uint16(7),
+	})
+	v, next, err := decoder{buf: buf.Bytes()}.decode(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"long": long, "flags": []any{true, false}, "big": uint64(1) << 40, "small": uint64(7)}
+	if !reflect.DeepEqual(v, any(want)) || next != uint(buf.Len()) {
+		t.Errorf("decode = %v, next %d; want %v, next %d", v, next, want, buf.Len())
+	}
+
+	// A map whose key points back at an earlier string: {"en": "x"}
+	pointed := []byte{0x42, 'e', 'n', 0xe1, 0x20, 0x00, 0x41, 'x'}
+	v, next, err = decoder{buf: pointed}.decode(3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, any(map[string]any{"en": "x"})) || next != uint(len(pointed)) {
+		t.Errorf("decode with a pointer = %v, next %d; want map[en:x], next %d", v, next, len(pointed))
+	}
+
+	malformed := map[string][]byte{
+		"pointer to a pointer": {0x20, 0x00},
+		"truncated string":     {0x45, 'a', 'b'},
+		"truncated size":       {0x5d},
+		"odd-sized double":     {0x64, 0, 0, 0, 0},
+		"offset past the end":  {},
+	}
+	for name, b := range malformed {
+		if _, _, err := (decoder{buf: b}).decode(0, 0); err == nil {
+			t.Errorf("decode of %s succeeded; want an error", name)
+		}
+	}
+}
+
+func TestParseMMDBRejectsMalformedFiles(t *testing.T) {
+	valid := buildMMDB(24, 6, "GeoLite2-City", map[string]map[string]any{"81.2.69.0/24": {"x": "y"}})
+	tests := map[string][]byte{
+		"no metadata":        []byte("not a database"),
+		"truncated tree":     valid[len(valid)/2:],
+		"bad record size":    bytes.Replace(valid, []byte("record_size\xa1\x18"), []byte("record_size\xa1\x10"), 1),
+		"metadata not a map": append([]byte(nil), append(metadataMarker, 0x41, 'x')...),
+	}
+	for name, buf := range tests {
+		if _, err := parseMMDB(buf); err == nil {
+			t.Errorf("parseMMDB of %s succeeded; want an error", name)
+		}
+	}
+}
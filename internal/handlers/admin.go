@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/jsoncase"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/services"
+)
+
+// InvalidateCache handles DELETE /admin/cache requests
+// @Summary Invalidate the cached forecast for a coordinate
+// @Description Removes the coordinate's cached forecast, and its grid cell's, from Redis and SQLite so the next request refetches from the NWS. Requires the admin token.
+// @Tags debug
+// @Produce json
+// @Param lat query number true "Latitude coordinate (-90 to 90)" example(40.7128)
+// @Param lon query number true "Longitude coordinate (-180 to 180)" example(-74.0060)
+// @Success 200 {object} models.CacheInvalidationResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/cache [delete]
+func (h *WeatherHandler) InvalidateCache(c *fiber.Ctx) error {
+	lat, lon, code := parseCoordinates(c)
+	if code != "" {
+		return sendError(c, fiber.StatusBadRequest, code)
+	}
+
+	removed, err := h.service.InvalidateWeather(c.UserContext(), lat, lon)
+	if err != nil {
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeCacheInvalidation, "cause", err.Error())
+	}
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.JSON(jsoncase.For(c, removed))
+}
+
+// InvalidateAllCache handles DELETE /admin/cache/all requests
+// @Summary Invalidate every cached forecast
+// @Description Removes all cached forecasts from Redis and SQLite. Forecast history and grid mappings are kept. Requires the admin token.
+// @Tags debug
+// @Produce json
+// @Success 200 {object} models.CacheInvalidationResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/cache/all [delete]
+func (h *WeatherHandler) InvalidateAllCache(c *fiber.Ctx) error {
+	removed, err := h.service.InvalidateAllWeather(c.UserContext())
+	if err != nil {
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeCacheInvalidation, "cause", err.Error())
+	}
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.JSON(jsoncase.For(c, removed))
+}
+
+// ListCachedLocations handles GET /admin/cache/locations requests
+// @Summary List cached locations
+// @Description Returns the coordinates with a cached forecast, most recently refreshed first, with each entry's refresh time and whether it is still fresh. Requires the admin token.
+// @Tags debug
+// @Produce json
+// @Param limit query int false "Page size (1 to 1000)" default(100)
+// @Param offset query int false "Number of locations to skip" default(0)
+// @Success 200 {object} models.CachedLocationsResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/cache/locations [get]
+func (h *WeatherHandler) ListCachedLocations(c *fiber.Ctx) error {
+	limit, err := queryInt(c, "limit", services.DefaultCachedLocationsLimit)
+	if err != nil || limit < 1 || limit > services.MaxCachedLocationsLimit {
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidPagination,
+			"max", strconv.Itoa(services.MaxCachedLocationsLimit))
+	}
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil || offset < 0 {
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidPagination,
+			"max", strconv.Itoa(services.MaxCachedLocationsLimit))
+	}
+
+	locations, err := h.service.ListCachedLocations(c.UserContext(), limit, offset)
+	if err != nil {
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeCacheStatsUnavailable, "cause", err.Error())
+	}
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.JSON(jsoncase.For(c, locations))
+}
+
+// queryInt parses an integer query parameter, returning def when it is absent
+func queryInt(c *fiber.Ctx, key string, def int) (int, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return def, nil
+	}
+	return strconv.Atoi(raw)
+}
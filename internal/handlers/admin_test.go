@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+)
+
+func TestInvalidateCache(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	getWeather := func() string {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Header.Get(CacheStatusHeader)
+	}
+	invalidate := func(target string) models.CacheInvalidationResponse {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("DELETE", target, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("DELETE %s: status = %d; want 200", target, resp.StatusCode)
+		}
+		var removed models.CacheInvalidationResponse
+		if err := json.NewDecoder(resp.Body).Decode(&removed); err != nil {
+			t.Fatal(err)
+		}
+		return removed
+	}
+
+	getWeather()
+	if got := getWeather(); got != "HIT" {
+		t.Fatalf("second request %s = %q; want HIT", CacheStatusHeader, got)
+	}
+
+	// The coordinate's entry plus its grid cell's forecast, day/night periods,
+	// and raw document
+	if removed := invalidate("/api/admin/cache?lat=40.7128&lon=-74.0060"); removed.SQLiteRows != 4 || removed.RedisKeys != 0 {
+		t.Errorf("invalidation removed %+v; want 4 SQLite rows and no Redis keys", removed)
+	}
+	if got := getWeather(); got != "MISS" {
+		t.Errorf("request after invalidation %s = %q; want MISS", CacheStatusHeader, got)
+	}
+
+	if removed := invalidate("/api/admin/cache/all"); removed.SQLiteRows != 4 {
+		t.Errorf("invalidating everything removed %+v; want 4 SQLite rows", removed)
+	}
+	if removed := invalidate("/api/admin/cache/all"); removed != (models.CacheInvalidationResponse{}) {
+		t.Errorf("invalidating an empty cache removed %+v; want nothing", removed)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/api/admin/cache?lat=abc&lon=-74", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("invalid coordinates: status = %d; want 400", resp.StatusCode)
+	}
+}
+
+func TestListCachedLocations(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+	if _, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/admin/cache/locations", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d; want 200", resp.StatusCode)
+	}
+	var page models.CachedLocationsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || len(page.Locations) != 1 || page.Limit != 100 || page.Offset != 0 {
+		t.Fatalf("page = %+v; want the one cached location with default paging", page)
+	}
+	if loc := page.Locations[0]; loc.Latitude != 40.713 || loc.Longitude != -74.006 || !loc.IsFresh {
+		t.Errorf("location = %+v; want a fresh entry at the normalized coordinate", loc)
+	}
+
+	for _, query := range []string{"limit=0", "limit=1001", "limit=abc", "offset=-1"} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/admin/cache/locations?"+query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("?%s: status = %d; want 400", query, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"weather-api-go/internal/jsoncase"
+	"weather-api-go/internal/metrics"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/services"
+)
+
+// GetAirQuality handles GET /airquality requests
+// @Summary Get current air quality
+// @Description Returns the current AirNow air quality near the specified latitude and longitude: the highest AQI among the reported pollutants, its category and pollutant, and the reporting area, with every pollutant listed worst first. Observations are cached for an hour. Returns 501 when no AirNow API key is configured and 404 when no monitors report nearby.
+// @Tags weather
+// @Produce json
+// @Param lat query number true "Latitude coordinate (-90 to 90)" example(40.7128)
+// @Param lon query number true "Longitude coordinate (-180 to 180)" example(-74.0060)
+// @Success 200 {object} models.AirQualityResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Router /airquality [get]
+func (h *WeatherHandler) GetAirQuality(c *fiber.Ctx) error {
+	lat, lon, code := parseCoordinates(c)
+	if code != "" {
+		return sendError(c, fiber.StatusBadRequest, code)
+	}
+
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+	airQuality, err := h.service.GetAirQuality(ctx, lat, lon)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAirQualityDisabled):
+			return sendError(c, fiber.StatusNotImplemented, models.ErrorCodeAirQualityDisabled)
+		case errors.Is(err, services.ErrNoAirQuality):
+			return sendError(c, fiber.StatusNotFound, models.ErrorCodeNoAirQuality)
+		}
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeAirQualityUnavailable, "cause", err.Error())
+	}
+
+	metrics.MarkCacheHit(c, airQuality.CacheHit)
+	setCacheControl(c, airQuality.FreshUntil)
+	return c.JSON(jsoncase.For(c, airQuality))
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"weather-api-go/internal/models"
+)
+
+func TestGetAirQualityNotConfigured(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	for _, tt := range []struct {
+		target string
+		status int
+		code   string
+	}{
+		// Without an AirNow key the endpoint says so rather than failing
+		{"/api/airquality?lat=40.7128&lon=-74.0060", fiber.StatusNotImplemented, models.ErrorCodeAirQualityDisabled},
+		{"/api/airquality?lon=-74.0060", fiber.StatusBadRequest, models.ErrorCodeMissingLatitude},
+		{"/api/airquality?lat=91&lon=-74.0060", fiber.StatusBadRequest, models.ErrorCodeCoordinatesOutOfRange},
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", tt.target, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.status || body.Code != tt.code {
+			t.Errorf("%s: %d %s; want %d %s", tt.target, resp.StatusCode, body.Code, tt.status, tt.code)
+		}
+	}
+}
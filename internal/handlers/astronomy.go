@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"weather-api-go/internal/jsoncase"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/services"
+)
+
+// GetAstronomy handles GET /astronomy requests
+// @Summary Get sunrise, sunset, and moon phase
+// @Description Computes sunrise, sunset, solar noon, day length, and the moon's phase for the specified latitude and longitude on a day, locally and without any upstream request. Times are in the location's time zone when its NWS grid mapping is cached, otherwise UTC. Sunrise and sunset are null with a polar_condition on days the sun doesn't cross the horizon.
+// @Tags weather
+// @Produce json
+// @Param lat query number true "Latitude coordinate (-90 to 90)" example(40.7128)
+// @Param lon query number true "Longitude coordinate (-180 to 180)" example(-74.0060)
+// @Param date query string false "Local day (YYYY-MM-DD), defaults to today" example(2024-06-20)
+// @Param tz query string false "IANA time zone to give times in and to take the day in" example(America/Chicago)
+// @Success 200 {object} models.AstronomyResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /astronomy [get]
+func (h *WeatherHandler) GetAstronomy(c *fiber.Ctx) error {
+	lat, lon, code := parseCoordinates(c)
+	if code != "" {
+		return sendError(c, fiber.StatusBadRequest, code)
+	}
+	tz, err := services.ParseTimeZone(c.Query("tz"))
+	if err != nil {
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidTimeZone)
+	}
+
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+	astronomy, err := h.service.GetAstronomy(ctx, lat, lon, c.Query("date"), tz)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDate) {
+			return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidDate)
+		}
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeWeatherUnavailable, "cause", err.Error())
+	}
+	return c.JSON(jsoncase.For(c, astronomy))
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"weather-api-go/internal/models"
+)
+
+func TestGetAstronomy(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	get := func(target string) (int, map[string]interface{}) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("GET", target, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, body
+	}
+
+	// Without a cached grid mapping the times are in UTC
+	status, body := get("/api/astronomy?lat=40.7128&lon=-74.0060&date=2024-06-20")
+	if status != fiber.StatusOK || body["time_zone"] != "UTC" || body["sunrise"] != "2024-06-20T09:24:48Z" {
+		t.Errorf("uncached: %d %v; want UTC with sunrise 09:24:48Z", status, body)
+	}
+
+	// A forecast lookup caches the mapping and its time zone
+	if _, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060", nil)); err != nil {
+		t.Fatal(err)
+	}
+	status, body = get("/api/astronomy?lat=40.7128&lon=-74.0060&date=2024-06-20")
+	if status != fiber.StatusOK || body["time_zone"] != "America/New_York" ||
+		body["sunrise"] != "2024-06-20T05:24:48-04:00" || body["sunset"] != "2024-06-20T20:30:33-04:00" {
+		t.Errorf("cached: %d %v; want New York times", status, body)
+	}
+	if moon, _ := body["moon"].(map[string]interface{}); moon["phase"] != "waxing_gibbous" {
+		t.Errorf("moon = %v; want waxing gibbous", body["moon"])
+	}
+
+	// Polar days keep sunrise and sunset as explicit nulls
+	status, body = get("/api/astronomy?lat=69.6492&lon=18.9553&date=2024-12-21&tz=Europe/Oslo")
+	sunrise, hasSunrise := body["sunrise"]
+	if status != fiber.StatusOK || !hasSunrise || sunrise != nil || body["polar_condition"] != "polar_night" ||
+		body["day_length_seconds"] != 0.0 {
+		t.Errorf("polar night: %d %v; want null sunrise and sunset with polar_night", status, body)
+	}
+
+	for target, code := range map[string]string{
+		"/api/astronomy?lat=40.7128&lon=-74.0060&date=2024-02-30": models.ErrorCodeInvalidDate,
+		"/api/astronomy?lat=40.7128&lon=-74.0060&tz=Nowhere":      models.ErrorCodeInvalidTimeZone,
+		"/api/astronomy?lon=-74.0060":                             models.ErrorCodeMissingLatitude,
+	} {
+		if status, body := get(target); status != fiber.StatusBadRequest || body["code"] != code {
+			t.Errorf("%s: %d %v; want 400 %s", target, status, body, code)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/jsoncase"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+	"weather-api-go/internal/services"
+)
+
+// CacheHandler serves statistics about the persistent cache and manages the
+// locations the cache warmer keeps fresh
+type CacheHandler struct {
+	maintenance *services.Maintenance
+	// warmer reports and manages warm locations; nil when there is no warmer
+	warmer *services.CacheWarmer
+}
+
+// CacheHandlerOption configures optional cache handler behavior
+type CacheHandlerOption func(*CacheHandler)
+
+// WithCacheWarmer reports the warmer's locations in the cache stats and
+// serves the warm location admin endpoints
+func WithCacheWarmer(warmer *services.CacheWarmer) CacheHandlerOption {
+	return func(h *CacheHandler) {
+		h.warmer = warmer
+	}
+}
+
+// NewCacheHandler creates a cache handler
+func NewCacheHandler(maintenance *services.Maintenance, opts ...CacheHandlerOption) *CacheHandler {
+	h := &CacheHandler{maintenance: maintenance}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// GetCacheStats handles GET /cache/stats requests
+// @Summary Cache statistics
+// @Description Returns the cache database size against its cap, cached rows per table, how many rows size-based pruning has removed, and when the cache warmer last refreshed each of its locations
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.CacheStatsResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /cache/stats [get]
+func (h *CacheHandler) GetCacheStats(c *fiber.Ctx) error {
+	stats, err := h.maintenance.CacheStats(c.UserContext())
+	if err != nil {
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeCacheStatsUnavailable, "cause", err.Error())
+	}
+	if h.warmer != nil {
+		if stats.Warming, err = h.warmer.Locations(c.UserContext()); err != nil {
+			return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeCacheStatsUnavailable, "cause", err.Error())
+		}
+	}
+	return c.JSON(jsoncase.For(c, stats))
+}
+
+// ListWarmLocations handles GET /admin/warm-locations requests
+// @Summary List warm locations
+// @Description Returns the locations the cache warmer keeps fresh, from WARM_LOCATIONS and added through this endpoint, with when each was last refreshed. Requires the admin token.
+// @Tags debug
+// @Produce json
+// @Success 200 {object} models.WarmLocationsResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/warm-locations [get]
+func (h *CacheHandler) ListWarmLocations(c *fiber.Ctx) error {
+	locations, err := h.warmer.Locations(c.UserContext())
+	if err != nil {
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeWarmLocationsFailed, "cause", err.Error())
+	}
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.JSON(jsoncase.For(c, models.WarmLocationsResponse{Locations: locations}))
+}
+
+// AddWarmLocation handles POST /admin/warm-locations requests
+// @Summary Add a warm location
+// @Description Adds a coordinate for the cache warmer to keep fresh, starting with its next pass. Coordinates are stored at cache precision, so adding one twice is harmless. Requires the admin token.
+// @Tags debug
+// @Produce json
+// @Param lat query number true "Latitude coordinate (-90 to 90)" example(40.7128)
+// @Param lon query number true "Longitude coordinate (-180 to 180)" example(-74.0060)
+// @Success 201 {object} models.WarmLocationsResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/warm-locations [post]
+func (h *CacheHandler) AddWarmLocation(c *fiber.Ctx) error {
+	lat, lon, code := parseCoordinates(c)
+	if code != "" {
+		return sendError(c, fiber.StatusBadRequest, code)
+	}
+	if err := h.warmer.AddLocation(c.UserContext(), lat, lon); err != nil {
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeWarmLocationsFailed, "cause", err.Error())
+	}
+	locations, err := h.warmer.Locations(c.UserContext())
+	if err != nil {
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeWarmLocationsFailed, "cause", err.Error())
+	}
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.Status(fiber.StatusCreated).JSON(jsoncase.For(c, models.WarmLocationsResponse{Locations: locations}))
+}
+
+// RemoveWarmLocation handles DELETE /admin/warm-locations requests
+// @Summary Remove a warm location
+// @Description Stops the cache warmer refreshing a coordinate added through POST /admin/warm-locations. Locations from WARM_LOCATIONS can't be removed. Requires the admin token.
+// @Tags debug
+// @Param lat query number true "Latitude coordinate (-90 to 90)" example(40.7128)
+// @Param lon query number true "Longitude coordinate (-180 to 180)" example(-74.0060)
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/warm-locations [delete]
+func (h *CacheHandler) RemoveWarmLocation(c *fiber.Ctx) error {
+	lat, lon, code := parseCoordinates(c)
+	if code != "" {
+		return sendError(c, fiber.StatusBadRequest, code)
+	}
+	err := h.warmer.RemoveLocation(c.UserContext(), lat, lon)
+	if errors.Is(err, repository.ErrWarmLocationNotFound) {
+		return sendError(c, fiber.StatusNotFound, models.ErrorCodeWarmLocationNotFound,
+			"lat", strconv.FormatFloat(lat, 'f', -1, 64), "lon", strconv.FormatFloat(lon, 'f', -1, 64))
+	}
+	if err != nil {
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeWarmLocationsFailed, "cause", err.Error())
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+	"weather-api-go/internal/services"
+)
+
+func TestDatabaseSizeCap(t *testing.T) {
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	repo := repository.NewWeatherRepository(db, nil)
+
+	// Documents large enough that the empty schema's pages stay well below
+	// the low-water mark pruning has to reach
+	body := make([]byte, 32<<10)
+	for d := 0; d < 20; d++ {
+		err := repo.SaveRawDocument(context.Background(), repository.RawPoints, fmt.Sprintf("doc-%02d", d), &models.RawDocument{
+			ContentType: "application/geo+json", Body: body, Timestamp: time.Now().AddDate(0, 0, -d),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	size, err := repo.DatabaseSize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	health := func(maxBytes int64) models.HealthResponse {
+		m := services.NewMaintenance(repo, services.MaintenanceConfig{MaxDatabaseBytes: maxBytes})
+		return NewWeatherHandler(nil, WithDatabaseUsage(m.DatabaseUsage)).Health(context.Background())
+	}
+	if got := health(0); got.Status != "healthy" || got.Database == nil || got.Database.UsageRatio != nil {
+		t.Errorf("uncapped health = %+v; want healthy with no usage ratio", got)
+	}
+	if got := health(size * 2); got.Status != "healthy" {
+		t.Errorf("health at 50%% of the cap = %q; want healthy", got.Status)
+	}
+	if got := health(size * 100 / 95); got.Status != "degraded" {
+		t.Errorf("health at 95%% of the cap = %q; want degraded", got.Status)
+	}
+
+	// A cap well below the current size prunes down to the low-water mark
+	maxBytes := size / 2
+	m := services.NewMaintenance(repo, services.MaintenanceConfig{MaxDatabaseBytes: maxBytes})
+	if err := m.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Get("/api/cache/stats", NewCacheHandler(m).GetCacheStats)
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/cache/stats", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d; want 200", resp.StatusCode)
+	}
+	var stats models.CacheStatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+
+	lowWater := int64(float64(maxBytes) * services.DefaultPruneLowWater)
+	if stats.Database.SizeBytes > lowWater {
+		t.Errorf("size after pruning = %d; want at most %d", stats.Database.SizeBytes, lowWater)
+	}
+	if stats.Database.MaxBytes != maxBytes || stats.Database.UsageRatio == nil {
+		t.Errorf("database = %+v; want max_bytes %d with a usage ratio", stats.Database, maxBytes)
+	}
+	if stats.PrunedRows == 0 || stats.LastPrunedAt == nil {
+		t.Errorf("pruned_rows = %d, last_pruned_at = %v; want pruning recorded", stats.PrunedRows, stats.LastPrunedAt)
+	}
+	if n := stats.Tables["raw_documents"]; n == 0 || n+stats.PrunedRows != 20 {
+		t.Errorf("raw_documents = %d with %d pruned; want the 20 documents split between them", n, stats.PrunedRows)
+	}
+}
+
+func TestCacheRetentionPurge(t *testing.T) {
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	repo := repository.NewWeatherRepository(db, nil)
+
+	for i, age := range []time.Duration{time.Hour, 80 * time.Hour, 100 * time.Hour} {
+		err := repo.SaveToCache(context.Background(), &models.WeatherCache{
+			Latitude: 40 + float64(i), Longitude: -74, Forecast: "Sunny", Timestamp: time.Now().Add(-age),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := services.NewMaintenance(repo, services.MaintenanceConfig{CacheRetention: 72 * time.Hour})
+	if err := m.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Get("/api/cache/stats", NewCacheHandler(m).GetCacheStats)
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/cache/stats", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stats models.CacheStatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.PurgedRows != 2 || stats.LastPurgeAt == nil {
+		t.Errorf("purged_rows = %d, last_purge_at = %v; want 2 rows purged", stats.PurgedRows, stats.LastPurgeAt)
+	}
+	if stats.Tables["weather_cache"] != 1 || stats.Tables["weather_history"] != 3 {
+		t.Errorf("tables = %v; want 1 cached forecast left and the history untouched", stats.Tables)
+	}
+	if _, err := repo.GetFromCache(context.Background(), 40, -74); err != nil {
+		t.Errorf("recent forecast was purged: %v", err)
+	}
+}
+
+func TestWarmLocations(t *testing.T) {
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	repo := repository.NewWeatherRepository(db, nil)
+	warmer := services.NewCacheWarmer(repo, services.NewWeatherService(repo, nil), services.WarmerConfig{
+		Locations: []models.Coordinates{{Latitude: 47.6062, Longitude: -122.3321}},
+	})
+	handler := NewCacheHandler(services.NewMaintenance(repo, services.MaintenanceConfig{}), WithCacheWarmer(warmer))
+
+	app := fiber.New()
+	app.Get("/api/cache/stats", handler.GetCacheStats)
+	app.Get("/api/admin/warm-locations", handler.ListWarmLocations)
+	app.Post("/api/admin/warm-locations", handler.AddWarmLocation)
+	app.Delete("/api/admin/warm-locations", handler.RemoveWarmLocation)
+
+	tests := []struct {
+		method, query string
+		wantCode      int
+	}{
+		{"POST", "?lat=40.7128&lon=-74.0060", fiber.StatusCreated},
+		{"POST", "?lat=95&lon=-74.0060", fiber.StatusBadRequest},
+		{"DELETE", "?lat=47.6062&lon=-122.3321", fiber.StatusNotFound},
+		{"POST", "?lat=41.8781&lon=-87.6298", fiber.StatusCreated},
+		{"DELETE", "?lat=41.8781&lon=-87.6298", fiber.StatusNoContent},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(tt.method, "/api/admin/warm-locations"+tt.query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.wantCode {
+			t.Errorf("%s %s: status = %d; want %d", tt.method, tt.query, resp.StatusCode, tt.wantCode)
+		}
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/admin/warm-locations", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list models.WarmLocationsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Locations) != 2 || list.Locations[0].Source != models.WarmSourceConfig ||
+		list.Locations[1].Source != models.WarmSourceAdmin || list.Locations[1].Latitude != 40.713 {
+		t.Errorf("locations = %+v; want the configured one, then 40.713,-74.006 as added", list.Locations)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/cache/stats", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stats models.CacheStatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Warming) != 2 {
+		t.Errorf("warming = %+v; want both locations", stats.Warming)
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/services"
+)
+
+// csvHeader is the header row of every CSV export
+var csvHeader = []string{
+	"timestamp", "latitude", "longitude", "period", "short_forecast",
+	"temp_c", "temp_f", "wind", "precipitation_probability",
+}
+
+// csvChunkRows is how many rows are buffered before a chunk is sent
+const csvChunkRows = 64
+
+// sendCSV streams rows after the header row as a CSV attachment, written in
+// chunks as they are produced. The rows are consumed after the handler
+// returns, so they must not reference the request context.
+func sendCSV(c *fiber.Ctx, filename string, rows iter.Seq[[]string]) error {
+	c.Attachment(filename)
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return
+		}
+		n := 0
+		for row := range rows {
+			if err := cw.Write(row); err != nil {
+				return
+			}
+			if n++; n%csvChunkRows == 0 {
+				cw.Flush()
+				// Fails once the client has gone away
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		}
+		cw.Flush()
+	})
+	return nil
+}
+
+// csvFilename names an export after its kind, coordinates, and date(s), such
+// as forecast_40.7128_-74.006_2024-01-15.csv
+func csvFilename(kind string, lat, lon float64, dates ...string) string {
+	parts := append([]string{kind, services.FormatPointCoordinate(lat), services.FormatPointCoordinate(lon)}, dates...)
+	return strings.Join(parts, "_") + ".csv"
+}
+
+// forecastRows yields one CSV row per forecast period, timestamped at its
+// local start time when the forecast has a time zone
+func forecastRows(forecast *models.ForecastResponse) iter.Seq[[]string] {
+	lat, lon := formatCSVFloat(forecast.Latitude), formatCSVFloat(forecast.Longitude)
+	return func(yield func([]string) bool) {
+		for _, p := range forecast.Periods {
+			precipitation := ""
+			if p.PrecipitationProbability != nil {
+				precipitation = formatCSVFloat(*p.PrecipitationProbability)
+			}
+			start := p.StartTime
+			if p.StartTimeLocal != nil {
+				start = *p.StartTimeLocal
+			}
+			row := []string{
+				start.Format(time.RFC3339), lat, lon, p.Name, p.ShortForecast,
+				formatCSVFloat(p.TempC), formatCSVFloat(p.TempF),
+				strings.TrimSpace(p.WindDirection + " " + p.WindSpeed), precipitation,
+			}
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// historyRows yields one CSV row per day, timestamped at UTC midnight, with
+// the day's mean temperatures and dominant forecast. Days have no period
+// name, wind, or precipitation, so those columns are empty.
+func historyRows(history *models.WeatherHistoryResponse) iter.Seq[[]string] {
+	lat, lon := formatCSVFloat(history.Latitude), formatCSVFloat(history.Longitude)
+	return func(yield func([]string) bool) {
+		for _, d := range history.Days {
+			row := []string{
+				d.Day + "T00:00:00Z", lat, lon, "", d.Forecast,
+				formatCSVFloat(d.MeanTempC), formatCSVFloat(d.MeanTempF), "", "",
+			}
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// formatCSVFloat writes a number in its shortest exact form
+func formatCSVFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
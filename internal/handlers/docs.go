@@ -1,20 +1,118 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/metrics"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/units"
 )
 
-// getOpenAPISpec returns the OpenAPI specification
-func getOpenAPISpec() map[string]interface{} {
+// APIBasePath is the prefix the API routes are mounted under
+const APIBasePath = "/api"
+
+// DocsHandler serves the API documentation and OpenAPI documents
+type DocsHandler struct {
+	externalBaseURL string
+	routes          *Routes
+	health          func(context.Context) models.HealthResponse
+	metrics         *metrics.Recorder
+}
+
+// DocsHandlerOption configures optional live data on the docs page
+type DocsHandlerOption func(*DocsHandler)
+
+// WithRoutes documents the routes registered through routes. Without it every
+// route in the registry is documented.
+func WithRoutes(routes *Routes) DocsHandlerOption {
+	return func(h *DocsHandler) {
+		h.routes = routes
+	}
+}
+
+// WithHealthCheck shows the result of the given health check in the docs page header
+func WithHealthCheck(check func(context.Context) models.HealthResponse) DocsHandlerOption {
+	return func(h *DocsHandler) {
+		h.health = check
+	}
+}
+
+// WithMetrics shows uptime and recent request latency in the docs page header
+func WithMetrics(recorder *metrics.Recorder) DocsHandlerOption {
+	return func(h *DocsHandler) {
+		h.metrics = recorder
+	}
+}
+
+// NewDocsHandler creates a docs handler. When externalBaseURL is set (e.g.
+// https://weather.example.com) it is advertised as the server URL; otherwise
+// the URL is derived from each request's scheme and host.
+func NewDocsHandler(externalBaseURL string, opts ...DocsHandlerOption) *DocsHandler {
+	h := &DocsHandler{externalBaseURL: strings.TrimSuffix(externalBaseURL, "/")}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// serverURL returns the URL of the current API version as seen by the client
+// making the request. Forwarded scheme and host headers are only honored from
+// trusted proxies.
+func (h *DocsHandler) serverURL(c *fiber.Ctx) string {
+	base := h.externalBaseURL
+	if base == "" {
+		base = c.Protocol() + "://" + c.Hostname()
+	}
+	return base + APIBasePath + "/" + APIVersion
+}
+
+// spec returns the OpenAPI specification of the documented routes as seen by
+// the client making the request
+func (h *DocsHandler) spec(c *fiber.Ctx) map[string]interface{} {
+	routes := apiRoutes
+	if h.routes != nil {
+		routes = h.routes.List()
+	}
+	return getOpenAPISpec(h.serverURL(c), routes)
+}
+
+// getOpenAPISpec returns the OpenAPI specification of the given routes,
+// advertising the given server URL. Each route's operation is its entry in
+// documentedPaths, completed by routeOperation.
+func getOpenAPISpec(serverURL string, routes []Route) map[string]interface{} {
+	documented := documentedPaths()
+	paths := map[string]interface{}{}
+	for _, route := range routes {
+		path := openAPIPath(route.Path)
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+		docs, _ := documented[path].(map[string]interface{})
+		method := strings.ToLower(route.Method)
+		item[method] = routeOperation(route, docs[method])
+		// Fiber answers HEAD for every GET route
+		if head, ok := docs["head"]; ok && route.Method == fiber.MethodGet {
+			item["head"] = routeOperation(route, head)
+		}
+	}
+
 	return map[string]interface{}{
 		"openapi": "3.0.0",
 		"info": map[string]interface{}{
 			"title":       "Weather API",
 			"version":     "1.0.0",
-			"description": "A modern weather service providing forecast data with dual-layer caching",
+			"description": "A modern weather service providing forecast data with dual-layer caching. Every path is also served without the version (e.g. /api/weather) until its Sunset date, as a deprecated alias.",
 			"contact": map[string]interface{}{
 				"name":  "API Support",
 				"email": "support@weather-api.example.com",
@@ -26,120 +124,2341 @@ func getOpenAPISpec() map[string]interface{} {
 		},
 		"servers": []map[string]interface{}{
 			{
-				"url":         "http://localhost:3000/api",
-				"description": "Local development server",
-			},
-		},
-		"paths": map[string]interface{}{
-			"/weather": map[string]interface{}{
-				"get": map[string]interface{}{
-					"summary":     "Get weather forecast",
-					"description": "Returns current weather forecast for given coordinates",
-					"tags":        []string{"Weather"},
-					"parameters": []map[string]interface{}{
-						{
-							"name":        "lat",
-							"in":          "query",
-							"required":    true,
-							"schema":      map[string]interface{}{"type": "number"},
-							"description": "Latitude (-90 to 90)",
-							"example":     40.7128,
+				"url":         serverURL,
+				"description": "This deployment",
+			},
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"adminToken": map[string]interface{}{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "The ADMIN_TOKEN the server was started with",
+				},
+				"apiKey": map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "X-API-Key",
+					"description": "A client key from API_KEYS or the api_keys table",
+				},
+			},
+		},
+		"tags": []map[string]interface{}{
+			{"name": "Weather", "description": "Weather forecast operations"},
+			{"name": "Observations", "description": "Measured station observations"},
+			{"name": "Alerts", "description": "Active NWS weather alerts"},
+			{"name": "System", "description": "System health and status"},
+			{"name": "Admin", "description": "Cache administration and debugging, enabled by ADMIN_TOKEN"},
+		},
+	}
+}
+
+// documentedPaths returns the hand-written operations of the API routes, keyed
+// by OpenAPI path and then method. Schemas of request and response bodies with
+// a model in the route registry are reconciled with the model when the spec
+// is built, so only their descriptions, formats, and examples are authoritative.
+func documentedPaths() map[string]interface{} {
+	paths := map[string]interface{}{
+		"/weather": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Get weather forecast",
+				"description": "Returns current weather forecast for given coordinates, or for a place looked up by zip, city, or q. A place lookup that matches several locations returns 300 with the candidates instead of picking one. When none of lat, lon, city, or q is given and a GeoIP database is configured, the caller's approximate location is estimated from their IP address and reported in approximate_location.",
+				"tags":        []string{"Weather"},
+				"parameters": []map[string]interface{}{
+					{
+						"name":        "lat",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Latitude (-90 to 90); required unless zip, city, or q is given, or the caller is located by IP",
+						"example":     40.7128,
+					},
+					{
+						"name":        "lon",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Longitude (-180 to 180); required unless zip, city, or q is given, or the caller is located by IP",
+						"example":     -74.0060,
+					},
+					{
+						"name":        "zip",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string", "pattern": "^[0-9]{5}(-[0-9]{4})?$"},
+						"description": "US ZIP or ZIP+4 code to look up instead of coordinates; the forecast is for the ZIP code's centroid. Combining it with lat, lon, city, or q is rejected with CONFLICTING_LOCATION.",
+						"example":     "10001",
+					},
+					{
+						"name":        "city",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string", "maxLength": 200},
+						"description": "City to look up instead of coordinates, optionally followed by a comma and state (Portland,OR). Ignored when lat or lon is given.",
+						"example":     "Portland,OR",
+					},
+					{
+						"name":        "q",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string", "maxLength": 200},
+						"description": "Free-text place to look up instead of coordinates. Ignored when lat, lon, or city is given.",
+						"example":     "Mount Rainier",
+					},
+					{
+						"name":        "include",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string"},
+						"description": "Comma-separated optional sections: advisories, detailed for the NWS's narrative forecast in detailed_forecast, and uv for uv_index and uv_category",
+						"example":     "advisories,detailed",
+					},
+					{
+						"name":        "units",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string", "enum": []string{"metric", "imperial", "both"}, "default": "both"},
+						"description": "Unit system for values: metric keeps only temperature_c, imperial only temperature_f, both (default) keeps both",
+					},
+					iconSizeParam("Size of the icon URL; the NWS's own size when omitted"),
+					windUnitParam("Unit to report the wind speed in, as wind_speed and wind_unit instead of wind_speed_kmh and wind_speed_mph"),
+					timeZoneParam("IANA time zone to give cached_at_local in; the location's own when omitted"),
+					{
+						"name":        "at",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string"},
+						"description": "Future time to forecast for, as RFC 3339 or a local date-time without offset (YYYY-MM-DDTHH:MM[:SS]) in the location's time zone. Within the hourly forecast values are interpolated between hours; beyond it the covering day or night period is returned.",
+						"example":     "2024-06-01T18:00:00Z",
+					},
+					{
+						"name":        "If-None-Match",
+						"in":          "header",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string"},
+						"description": "ETag from an earlier response; 304 is returned while it still matches",
+					},
+					{
+						"name":        "format",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string", "enum": []string{"json", "xml"}, "default": "json"},
+						"description": "Response format; overrides the Accept header, where application/xml or text/xml also selects XML",
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": withXML(map[string]interface{}{
+						"description": "Weather data retrieved successfully",
+						"headers": map[string]interface{}{
+							"X-Cache": map[string]interface{}{
+								"description": "HIT when served from a fresh cache, MISS after a live NWS fetch, STALE when expired data is served while it is refreshed or because the fetch failed",
+								"schema":      map[string]interface{}{"type": "string", "enum": []string{"HIT", "MISS", "STALE"}},
+							},
+							"ETag": map[string]interface{}{
+								"description": "Weak validator of the cached forecast behind the response; it changes whenever the forecast is refetched, even if it reads the same",
+								"schema":      map[string]interface{}{"type": "string"},
+							},
+							"Last-Modified": map[string]interface{}{
+								"description": "When the forecast was fetched from the provider (cached_at)",
+								"schema":      map[string]interface{}{"type": "string"},
+							},
+						},
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": weatherResponseSpec(),
+							},
+						},
+					}),
+					"300": map[string]interface{}{
+						"description": "The place lookup matched several locations (AMBIGUOUS_LOCATION); retry with one candidate's coordinates",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": ambiguousLocationSpec(),
+							},
+						},
+					},
+					"304": map[string]interface{}{
+						"description": "The forecast is unchanged since the If-None-Match ETag; the body is empty",
+					},
+					"400": errorResponseSpec("Invalid parameters, including a malformed ZIP code (INVALID_ZIP) or zip combined with other location parameters (CONFLICTING_LOCATION), or no location was given and the caller's IP address couldn't be located (IP_NOT_LOCATED)"),
+					"404": errorResponseSpec("No place matches the lookup (LOCATION_NOT_FOUND), the ZIP code doesn't exist (ZIP_NOT_FOUND), the coordinate is outside NWS coverage (OUT_OF_COVERAGE), or ?at= was given but the NWS publishes no hourly forecast for the location (NO_HOURLY_FORECAST)"),
+					"422": errorResponseSpec("The requested time is in the past or beyond the forecast horizon"),
+					"500": errorResponseSpec("Weather data or the place lookup failed inside the service (WEATHER_UNAVAILABLE), or the cache could not be read to stand in for a failed fetch (CACHE_UNAVAILABLE)"),
+					"502": errorResponseSpec("The forecast provider could not be reached or answered with a 5xx (UPSTREAM_UNAVAILABLE), or its response could not be used (UPSTREAM_BAD_RESPONSE); details carry the provider's status"),
+					"503": weatherUnavailableSpec(),
+					"504": errorResponseSpec("The forecast provider did not answer in time (UPSTREAM_TIMEOUT)"),
+				},
+			},
+		},
+		"/weather/batch": map[string]interface{}{
+			"post": map[string]interface{}{
+				"summary":     "Get weather for many coordinates",
+				"description": "Looks up the /weather forecast for each coordinate concurrently and returns one result per coordinate, in request order. A coordinate that fails carries its own error (the code /weather would have returned) instead of failing the request, and repeated coordinates are looked up once. At most 100 coordinates may be sent by default (BATCH_MAX_SIZE).",
+				"tags":        []string{"Weather"},
+				"requestBody": map[string]interface{}{
+					"required": true,
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": map[string]interface{}{
+								"type":     "array",
+								"minItems": 1,
+								"items": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"lat", "lon"},
+									"properties": map[string]interface{}{
+										"lat": map[string]interface{}{"type": "number", "example": 40.7128},
+										"lon": map[string]interface{}{"type": "number", "example": -74.0060},
+									},
+								},
+							},
+						},
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "One result per requested coordinate, in request order",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": batchWeatherResponseSpec(),
+							},
+						},
+					},
+					"400": errorResponseSpec("The body is not a non-empty array of coordinates (INVALID_BATCH), or has too many (BATCH_TOO_LARGE)"),
+				},
+			},
+		},
+		"/graphql": map[string]interface{}{
+			"post": map[string]interface{}{
+				"summary":     "Query weather, forecast, and alerts with GraphQL",
+				"description": "Executes a GraphQL query over weather(lat, lon), forecast(lat, lon, days), and alerts(lat, lon), which share the caches of /weather, /forecast, and /alerts, so one request can replace several. Field names are camelCase. Queries nesting deeper than GRAPHQL_MAX_DEPTH (6) or costing more than GRAPHQL_MAX_COMPLEXITY (100), where each lookup costs 10 and other fields 1, are rejected with 400 before anything is fetched, as are malformed and invalid queries. A failed lookup is null in data, with an error whose extensions carry the code its REST endpoint would return.",
+				"tags":        []string{"Weather"},
+				"requestBody": map[string]interface{}{
+					"required": true,
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": map[string]interface{}{
+								"type":     "object",
+								"required": []string{"query"},
+								"properties": map[string]interface{}{
+									"query":         map[string]interface{}{"type": "string", "example": "{ weather(lat: 40.7128, lon: -74.006) { forecast temperatureF } alerts(lat: 40.7128, lon: -74.006) { alerts { event } } }"},
+									"variables":     map[string]interface{}{"type": "object"},
+									"operationName": map[string]interface{}{"type": "string"},
+								},
+							},
+						},
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": graphQLResponseSpec("Query executed; lookups that failed are null in data and listed in errors", true),
+					"400": graphQLResponseSpec("Malformed, invalid, too deep (QUERY_TOO_DEEP), or too complex (QUERY_TOO_COMPLEX) query", false),
+				},
+			},
+		},
+		"/ws": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Subscribe to weather updates over a WebSocket",
+				"description": "Upgrades to a WebSocket. Send {\"type\": \"subscribe\", \"coordinates\": [{\"lat\": 40.7128, \"lon\": -74.006}]} to receive {\"type\": \"weather\", \"lat\", \"lon\", \"weather\"} with each coordinate's current /weather response at once, then again whenever its cache entry is refreshed; {\"type\": \"unsubscribe\", ...} stops them. Errors arrive as {\"type\": \"error\", \"error\"}, echoing lat and lon when they concern one coordinate. A connection may subscribe to as many coordinates as a batch request may carry (TOO_MANY_SUBSCRIPTIONS). A slow client only receives the latest weather for each coordinate.",
+				"tags":        []string{"Weather"},
+				"responses": map[string]interface{}{
+					"101": map[string]interface{}{"description": "Switched to the WebSocket protocol"},
+					"426": errorResponseSpec("The request is not a WebSocket upgrade (WEBSOCKET_REQUIRED)"),
+				},
+			},
+		},
+		"/weather/cached": map[string]interface{}{
+			"head": cachedWeatherOperation(),
+			"get":  cachedWeatherOperation(),
+		},
+		"/weather/stream": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Stream weather updates as server-sent events",
+				"description": "A text/event-stream of the coordinate's /weather response: a weather event at once, then another whenever its cache entry is refreshed or interval seconds pass without one. Events are numbered from 1 and the first sets retry to 5000 ms. A lookup that fails mid-stream sends an error event whose data is an error response. A keep-alive comment is sent after 15 seconds without an event.",
+				"tags":        []string{"Weather"},
+				"parameters": []map[string]interface{}{
+					{
+						"name":        "lat",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Latitude (-90 to 90)",
+						"example":     40.7128,
+					},
+					{
+						"name":        "lon",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Longitude (-180 to 180)",
+						"example":     -74.0060,
+					},
+					{
+						"name":        "interval",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 3600, "default": 300},
+						"description": "Seconds between events when the weather hasn't changed",
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Event stream of weather and error events",
+						"content": map[string]interface{}{
+							"text/event-stream": map[string]interface{}{
+								"schema": map[string]interface{}{"type": "string", "example": "retry: 5000\nid: 1\nevent: weather\ndata: {\"forecast\":\"Sunny\",...}\n\n"},
+							},
+						},
+					},
+					"400": errorResponseSpec("Missing or invalid coordinates, or an interval outside 1-3600 (INVALID_INTERVAL)"),
+					"404": errorResponseSpec("Coordinate is outside NWS coverage (OUT_OF_COVERAGE)"),
+					"500": errorResponseSpec("The initial weather lookup failed inside the service"),
+					"502": errorResponseSpec("The forecast provider could not be reached or answered with a 5xx (UPSTREAM_UNAVAILABLE), or its response could not be used (UPSTREAM_BAD_RESPONSE); details carry the provider's status"),
+					"504": errorResponseSpec("The forecast provider did not answer in time (UPSTREAM_TIMEOUT)"),
+					"503": errorResponseSpec("Upstream capacity is saturated (SHED) or the provider quota is exhausted"),
+				},
+			},
+		},
+		"/weather/history": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Get daily forecast history",
+				"description": "Per-day min/max/mean temperatures and the dominant forecast recorded for a coordinate, oldest first. Days are UTC; older days come from downsampled daily summaries, so the series is continuous.",
+				"tags":        []string{"Weather"},
+				"parameters": []map[string]interface{}{
+					{
+						"name":        "lat",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Latitude (-90 to 90)",
+						"example":     40.7128,
+					},
+					{
+						"name":        "lon",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Longitude (-180 to 180)",
+						"example":     -74.0060,
+					},
+					{
+						"name":        "from",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string", "format": "date"},
+						"description": "First UTC day to include; defaults to 30 days before to",
+					},
+					{
+						"name":        "to",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string", "format": "date"},
+						"description": "Last UTC day to include; defaults to today. At most 366 days may be requested.",
+					},
+					{
+						"name":        "format",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string", "enum": []string{"json", "csv"}, "default": "json"},
+						"description": "Response format; csv streams one row per day, with mean temperatures, as an attachment",
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": withCSV(map[string]interface{}{
+						"description": "Daily history, oldest first; days without cached forecasts are omitted",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"latitude", "longitude", "from", "to", "days"},
+									"properties": map[string]interface{}{
+										"latitude":  map[string]interface{}{"type": "number"},
+										"longitude": map[string]interface{}{"type": "number"},
+										"from":      map[string]interface{}{"type": "string", "format": "date"},
+										"to":        map[string]interface{}{"type": "string", "format": "date"},
+										"days": map[string]interface{}{
+											"type": "array",
+											"items": map[string]interface{}{
+												"type": "object",
+												"required": []string{
+													"day", "min_temp_c", "max_temp_c", "mean_temp_c",
+													"min_temp_f", "max_temp_f", "mean_temp_f", "forecast", "samples",
+												},
+												"properties": map[string]interface{}{
+													"day":         map[string]interface{}{"type": "string", "format": "date"},
+													"min_temp_c":  map[string]interface{}{"type": "number"},
+													"max_temp_c":  map[string]interface{}{"type": "number"},
+													"mean_temp_c": map[string]interface{}{"type": "number"},
+													"min_temp_f":  map[string]interface{}{"type": "number"},
+													"max_temp_f":  map[string]interface{}{"type": "number"},
+													"mean_temp_f": map[string]interface{}{"type": "number"},
+													"forecast":    map[string]interface{}{"type": "string", "description": "Most frequent short forecast of the day"},
+													"samples":     map[string]interface{}{"type": "integer", "description": "Cached forecasts summarized"},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					}),
+					"400": errorResponseSpec("Invalid coordinates or date range"),
+					"500": errorResponseSpec("History could not be read"),
+				},
+			},
+		},
+		"/weather/hourly": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Get the hourly forecast",
+				"description": "The remaining hours of the NWS hourly forecast for the coordinate, oldest first. Hourly forecasts are cached per grid cell for 30 minutes.",
+				"tags":        []string{"Weather"},
+				"parameters": []map[string]interface{}{
+					{
+						"name":        "lat",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Latitude (-90 to 90)",
+						"example":     40.7128,
+					},
+					{
+						"name":        "lon",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Longitude (-180 to 180)",
+						"example":     -74.0060,
+					},
+					timeZoneParam("IANA time zone to give local hours in; the location's own when omitted"),
+					windUnitParam("Unit to give each hour's wind_speed in, such as \"8 to 16.1 km/h\"; the NWS's own (mph) when omitted"),
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Hourly forecast retrieved successfully",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"latitude", "longitude", "hours"},
+									"properties": map[string]interface{}{
+										"latitude":  map[string]interface{}{"type": "number"},
+										"longitude": map[string]interface{}{"type": "number"},
+										"time_zone": timeZoneSpec(),
+										"hours": map[string]interface{}{
+											"type": "array",
+											"items": map[string]interface{}{
+												"type":     "object",
+												"required": []string{"time", "temp_c", "temp_f", "short_forecast"},
+												"properties": map[string]interface{}{
+													"time":                      map[string]interface{}{"type": "string", "format": "date-time", "description": "Start of the hour, in UTC"},
+													"time_local":                map[string]interface{}{"type": "string", "format": "date-time", "description": "Start of the hour in time_zone, when known"},
+													"temp_c":                    map[string]interface{}{"type": "number"},
+													"temp_f":                    map[string]interface{}{"type": "number"},
+													"short_forecast":            map[string]interface{}{"type": "string", "example": "Mostly Clear"},
+													"wind_speed":                map[string]interface{}{"type": "string", "example": "9 mph"},
+													"wind_direction":            map[string]interface{}{"type": "string", "example": "NW"},
+													"precipitation_probability": map[string]interface{}{"type": "number", "description": "Chance of precipitation in percent, when forecast"},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					"400": errorResponseSpec("Invalid coordinates or time zone (INVALID_TIME_ZONE)"),
+					"404": errorResponseSpec("Coordinate is outside NWS coverage (OUT_OF_COVERAGE), or the NWS publishes no hourly forecast for the location (NO_HOURLY_FORECAST)"),
+					"500": errorResponseSpec("Forecast data could not be retrieved"),
+					"502": errorResponseSpec("The forecast provider could not be reached or answered with a 5xx (UPSTREAM_UNAVAILABLE), or its response could not be used (UPSTREAM_BAD_RESPONSE); details carry the provider's status"),
+					"503": shedResponseSpec(),
+					"504": errorResponseSpec("The forecast provider did not answer in time (UPSTREAM_TIMEOUT)"),
+				},
+			},
+		},
+		"/forecast": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Get the multi-day forecast",
+				"description": "Every NWS day/night forecast period for the coordinate, typically a week ahead, in NWS order. Periods are cached per grid cell for the forecast TTL.",
+				"tags":        []string{"Weather"},
+				"parameters": []map[string]interface{}{
+					{
+						"name":        "lat",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Latitude (-90 to 90)",
+						"example":     40.7128,
+					},
+					{
+						"name":        "lon",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Longitude (-180 to 180)",
+						"example":     -74.0060,
+					},
+					iconSizeParam("Size of each period's icon URL; the NWS's own size when omitted"),
+					windUnitParam("Unit to give each period's wind_speed in, such as \"8 to 16.1 km/h\"; the NWS's own (mph) when omitted"),
+					timeZoneParam("IANA time zone to give local period times in; the location's own when omitted"),
+					{
+						"name":        "format",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string", "enum": []string{"json", "xml", "csv"}, "default": "json"},
+						"description": "Response format; overrides the Accept header, where application/xml or text/xml also selects XML. csv streams one row per period as an attachment.",
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": withCSV(withXML(map[string]interface{}{
+						"description": "Forecast periods retrieved successfully",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"latitude", "longitude", "periods"},
+									"properties": map[string]interface{}{
+										"latitude":  map[string]interface{}{"type": "number"},
+										"longitude": map[string]interface{}{"type": "number"},
+										"time_zone": timeZoneSpec(),
+										"periods": map[string]interface{}{
+											"type": "array",
+											"items": map[string]interface{}{
+												"type": "object",
+												"required": []string{
+													"start_time", "end_time", "is_daytime", "short_forecast", "temp_c", "temp_f",
+												},
+												"properties": map[string]interface{}{
+													"name":                      map[string]interface{}{"type": "string", "example": "Tonight"},
+													"start_time":                map[string]interface{}{"type": "string", "format": "date-time", "description": "In UTC"},
+													"end_time":                  map[string]interface{}{"type": "string", "format": "date-time", "description": "In UTC"},
+													"start_time_local":          map[string]interface{}{"type": "string", "format": "date-time", "description": "start_time in time_zone, when known"},
+													"end_time_local":            map[string]interface{}{"type": "string", "format": "date-time", "description": "end_time in time_zone, when known"},
+													"is_daytime":                map[string]interface{}{"type": "boolean"},
+													"short_forecast":            map[string]interface{}{"type": "string", "example": "Mostly Clear"},
+													"detailed_forecast":         map[string]interface{}{"type": "string"},
+													"temp_c":                    map[string]interface{}{"type": "number"},
+													"temp_f":                    map[string]interface{}{"type": "number"},
+													"precipitation_probability": map[string]interface{}{"type": "number", "description": "Chance of precipitation in percent, when forecast"},
+													"humidity_percent":          map[string]interface{}{"type": "number", "description": "Relative humidity in percent, when forecast"},
+													"wind_speed":                map[string]interface{}{"type": "string", "example": "5 to 10 mph"},
+													"wind_direction":            map[string]interface{}{"type": "string", "example": "NW"},
+													"icon":                      map[string]interface{}{"type": "string", "format": "uri", "description": "NWS icon URL for the period, when given"},
+												},
+											},
+										},
+									},
+								},
+							},
 						},
-						{
-							"name":        "lon",
-							"in":          "query",
-							"required":    true,
-							"schema":      map[string]interface{}{"type": "number"},
-							"description": "Longitude (-180 to 180)",
-							"example":     -74.0060,
+					})),
+					"400": errorResponseSpec("Invalid coordinates, icon_size, or time zone (INVALID_TIME_ZONE)"),
+					"404": errorResponseSpec("Coordinate is outside NWS coverage (OUT_OF_COVERAGE)"),
+					"500": errorResponseSpec("Forecast data could not be retrieved"),
+					"502": errorResponseSpec("The forecast provider could not be reached or answered with a 5xx (UPSTREAM_UNAVAILABLE), or its response could not be used (UPSTREAM_BAD_RESPONSE); details carry the provider's status"),
+					"503": shedResponseSpec(),
+					"504": errorResponseSpec("The forecast provider did not answer in time (UPSTREAM_TIMEOUT)"),
+				},
+			},
+		},
+		"/alerts": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Get active weather alerts",
+				"description": "NWS alerts active at the coordinate, including storm-based warnings drawn as polygons, optionally filtered. No active alerts yields an empty list, not an error. Alerts are cached for at most 3 minutes, and never past an alert's expiry; filters apply to the cached list, so they share one upstream fetch.",
+				"tags":        []string{"Alerts"},
+				"parameters": []map[string]interface{}{
+					{
+						"name":        "lat",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Latitude (-90 to 90)",
+						"example":     40.7128,
+					},
+					{
+						"name":        "lon",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Longitude (-180 to 180)",
+						"example":     -74.0060,
+					},
+					{
+						"name":        "severity",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string"},
+						"description": "Comma-separated severities to keep, in any case: Extreme, Severe, Moderate, Minor, or Unknown",
+						"example":     "Severe,Extreme",
+					},
+					{
+						"name":        "urgency",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string"},
+						"description": "Comma-separated urgencies to keep, in any case: Immediate, Expected, Future, Past, or Unknown",
+						"example":     "Immediate,Expected",
+					},
+					{
+						"name":        "event",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string"},
+						"description": "Keep alerts whose event contains this text, ignoring case",
+						"example":     "Tornado",
+					},
+					{
+						"name":        "active_only",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "boolean"},
+						"description": "Keep only alerts in effect now, whose onset has passed; expired alerts are always left out",
+					},
+					{
+						"name":        "geometry",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "boolean"},
+						"description": "Include each alert's GeoJSON geometry, which is large and so left out by default",
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Active alerts retrieved successfully",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"latitude", "longitude", "alerts"},
+									"properties": map[string]interface{}{
+										"latitude":  map[string]interface{}{"type": "number"},
+										"longitude": map[string]interface{}{"type": "number"},
+										"alerts": map[string]interface{}{
+											"type": "array",
+											"items": map[string]interface{}{
+												"type": "object",
+												"required": []string{
+													"id", "event", "severity", "urgency", "headline", "message_type", "sent", "expires", "affected_zones",
+												},
+												"properties": map[string]interface{}{
+													"id":             map[string]interface{}{"type": "string"},
+													"event":          map[string]interface{}{"type": "string", "example": "Winter Storm Warning"},
+													"severity":       map[string]interface{}{"type": "string", "example": "Severe"},
+													"urgency":        map[string]interface{}{"type": "string", "example": "Expected"},
+													"headline":       map[string]interface{}{"type": "string"},
+													"message_type":   map[string]interface{}{"type": "string", "example": "Alert"},
+													"sent":           map[string]interface{}{"type": "string", "format": "date-time"},
+													"onset":          map[string]interface{}{"type": "string", "format": "date-time"},
+													"expires":        map[string]interface{}{"type": "string", "format": "date-time"},
+													"affected_zones": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "example": []string{"NYZ072"}},
+													"references":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "IDs of the alerts this one updates"},
+													"geometry": map[string]interface{}{
+														"type":        "object",
+														"description": "GeoJSON geometry of the area the alert covers, present only with geometry=true: the polygon of a storm-based warning, or the shapes of the affected zones (merged into a MultiPolygon when there are several). Omitted when no shape could be found.",
+														"properties": map[string]interface{}{
+															"type":        map[string]interface{}{"type": "string", "example": "Polygon"},
+															"coordinates": map[string]interface{}{"type": "array", "items": map[string]interface{}{}},
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
 						},
 					},
-					"responses": map[string]interface{}{
-						"200": map[string]interface{}{
-							"description": "Weather data retrieved successfully",
-							"content": map[string]interface{}{
-								"application/json": map[string]interface{}{
-									"schema": map[string]interface{}{
-										"type": "object",
-										"properties": map[string]interface{}{
-											"forecast": map[string]interface{}{
-												"type":        "string",
-												"example":     "Partly Cloudy",
-												"description": "Short weather forecast",
+					"400": errorResponseSpec("Invalid coordinates, severity (INVALID_ALERT_SEVERITY, listing the accepted values), urgency (INVALID_ALERT_URGENCY), active_only (INVALID_ACTIVE_ONLY), or geometry (INVALID_GEOMETRY)"),
+					"404": errorResponseSpec("Coordinate is outside NWS coverage (OUT_OF_COVERAGE)"),
+					"500": errorResponseSpec("Alerts could not be retrieved"),
+					"503": shedResponseSpec(),
+				},
+			},
+		},
+		"/astronomy": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Get sunrise, sunset, and moon phase",
+				"description": "Sunrise, sunset, solar noon, day length, and the moon's phase for the coordinate on a day, computed locally with the NOAA sunrise equation and Meeus's lunar terms, without any upstream request. Times are in the location's time zone when its NWS grid mapping is cached (after any forecast lookup for it), otherwise UTC. On days the sun doesn't cross the horizon, sunrise and sunset are null and polar_condition says why.",
+				"tags":        []string{"Weather"},
+				"parameters": []map[string]interface{}{
+					{
+						"name":        "lat",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Latitude (-90 to 90)",
+						"example":     40.7128,
+					},
+					{
+						"name":        "lon",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Longitude (-180 to 180)",
+						"example":     -74.0060,
+					},
+					{
+						"name":        "date",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string", "format": "date"},
+						"description": "Local day (YYYY-MM-DD); defaults to today",
+						"example":     "2024-06-20",
+					},
+					timeZoneParam("IANA time zone to give times in and take the day in; the location's own when omitted"),
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Sun and moon computed successfully",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"required": []string{
+										"latitude", "longitude", "date", "time_zone", "sunrise", "sunset", "solar_noon", "day_length_seconds", "moon",
+									},
+									"properties": map[string]interface{}{
+										"latitude":  map[string]interface{}{"type": "number"},
+										"longitude": map[string]interface{}{"type": "number"},
+										"date":      map[string]interface{}{"type": "string", "format": "date", "example": "2024-06-20"},
+										"time_zone": map[string]interface{}{"type": "string", "example": "America/New_York", "description": "IANA time zone the times are given in; UTC when the location's is unknown"},
+										"sunrise":   map[string]interface{}{"type": "string", "format": "date-time", "nullable": true, "description": "Null when the sun doesn't rise or set that day"},
+										"sunset":    map[string]interface{}{"type": "string", "format": "date-time", "nullable": true, "description": "Null when the sun doesn't rise or set that day"},
+										"polar_condition": map[string]interface{}{
+											"type":        "string",
+											"enum":        []string{"polar_night", "midnight_sun"},
+											"description": "Why sunrise and sunset are null: the sun stays below (polar_night) or above (midnight_sun) the horizon all day; omitted otherwise",
+										},
+										"solar_noon":         map[string]interface{}{"type": "string", "format": "date-time"},
+										"day_length_seconds": map[string]interface{}{"type": "integer", "example": 54360, "description": "0 in polar night, 86400 under the midnight sun"},
+										"moon": map[string]interface{}{
+											"type":        "object",
+											"description": "The moon's phase at local noon",
+											"required":    []string{"phase", "illumination", "age_days"},
+											"properties": map[string]interface{}{
+												"phase": map[string]interface{}{
+													"type": "string",
+													"enum": []string{
+														"new_moon", "waxing_crescent", "first_quarter", "waxing_gibbous",
+														"full_moon", "waning_gibbous", "last_quarter", "waning_crescent",
+													},
+												},
+												"illumination": map[string]interface{}{"type": "number", "example": 0.97, "description": "Fraction of the disk lit, from 0 to 1"},
+												"age_days":     map[string]interface{}{"type": "number", "example": 13.6, "description": "Days since the last new moon"},
 											},
-											"temperature": map[string]interface{}{
-												"type":        "string",
-												"enum":        []string{"hot", "cold", "moderate"},
-												"description": "Temperature classification",
+										},
+									},
+								},
+							},
+						},
+					},
+					"400": errorResponseSpec("Invalid coordinates, date (INVALID_DATE), or time zone (INVALID_TIME_ZONE)"),
+				},
+			},
+		},
+		"/metadata": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Get NWS point metadata",
+				"description": "The NWS forecast office, grid cell, forecast and county zones, radar station, and forecast URLs the coordinate resolves to, all from the NWS points document. Useful for debugging and for linking to the official forecast. Cached for 30 days with the coordinate's grid mapping, which forecast lookups share, so it rarely costs an upstream request.",
+				"tags":        []string{"Weather"},
+				"parameters": []map[string]interface{}{
+					{
+						"name":        "lat",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Latitude (-90 to 90)",
+						"example":     40.7128,
+					},
+					{
+						"name":        "lon",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Longitude (-180 to 180)",
+						"example":     -74.0060,
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Metadata resolved successfully",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"required": []string{
+										"latitude", "longitude", "office", "grid_x", "grid_y", "forecast_zone", "county", "radar_station",
+										"forecast_url", "city", "state", "time_zone",
+									},
+									"properties": map[string]interface{}{
+										"latitude":            map[string]interface{}{"type": "number"},
+										"longitude":           map[string]interface{}{"type": "number"},
+										"office":              map[string]interface{}{"type": "string", "example": "OKX", "description": "NWS forecast office whose grid contains the point (gridId)"},
+										"grid_x":              map[string]interface{}{"type": "integer", "example": 33},
+										"grid_y":              map[string]interface{}{"type": "integer", "example": 35},
+										"forecast_zone":       map[string]interface{}{"type": "string", "example": "NYZ072"},
+										"county":              map[string]interface{}{"type": "string", "example": "NYC061", "description": "County zone ID"},
+										"radar_station":       map[string]interface{}{"type": "string", "example": "KOKX", "description": "Nearest NEXRAD radar"},
+										"forecast_url":        map[string]interface{}{"type": "string", "format": "uri", "example": "https://api.weather.gov/gridpoints/OKX/33,35/forecast"},
+										"forecast_hourly_url": map[string]interface{}{"type": "string", "format": "uri", "description": "Omitted when the NWS publishes no hourly forecast for the cell"},
+										"city":                map[string]interface{}{"type": "string", "example": "New York", "description": "Nearest city the NWS reports for the point"},
+										"state":               map[string]interface{}{"type": "string", "example": "NY"},
+										"time_zone":           map[string]interface{}{"type": "string", "example": "America/New_York"},
+									},
+								},
+							},
+						},
+					},
+					"400": errorResponseSpec("Invalid coordinates"),
+					"404": errorResponseSpec("Coordinate is outside NWS coverage (OUT_OF_COVERAGE)"),
+					"500": errorResponseSpec("Metadata could not be resolved"),
+					"503": shedResponseSpec(),
+				},
+			},
+		},
+		"/airquality": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Get current air quality",
+				"description": "The current AirNow air quality near the coordinate, described by its worst pollutant: the highest AQI among the reported pollutants, that pollutant's EPA category, and the reporting area whose monitors measured it. Every reported pollutant is listed worst first. Observations are cached for an hour, AirNow's reporting interval. Requires AIRNOW_API_KEY.",
+				"tags":        []string{"Weather"},
+				"parameters": []map[string]interface{}{
+					{
+						"name":        "lat",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Latitude (-90 to 90)",
+						"example":     40.7128,
+					},
+					{
+						"name":        "lon",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Longitude (-180 to 180)",
+						"example":     -74.0060,
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Air quality retrieved successfully",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"required": []string{
+										"latitude", "longitude", "aqi", "category", "primary_pollutant", "reporting_area", "pollutants",
+									},
+									"properties": map[string]interface{}{
+										"latitude":          map[string]interface{}{"type": "number"},
+										"longitude":         map[string]interface{}{"type": "number"},
+										"aqi":               map[string]interface{}{"type": "integer", "example": 152, "description": "Highest AQI among the reported pollutants"},
+										"category":          map[string]interface{}{"type": "string", "example": "Unhealthy", "description": "EPA AQI category of the primary pollutant"},
+										"primary_pollutant": map[string]interface{}{"type": "string", "example": "PM2.5"},
+										"reporting_area":    map[string]interface{}{"type": "string", "example": "Northeast Urban NJ"},
+										"state_code":        map[string]interface{}{"type": "string", "example": "NJ"},
+										"observed_at":       map[string]interface{}{"type": "string", "format": "date-time", "description": "Start of the observed hour; omitted when AirNow's time zone isn't recognized"},
+										"pollutants": map[string]interface{}{
+											"type":        "array",
+											"description": "Every reported pollutant, worst first",
+											"items": map[string]interface{}{
+												"type":     "object",
+												"required": []string{"pollutant", "aqi", "category", "reporting_area"},
+												"properties": map[string]interface{}{
+													"pollutant":      map[string]interface{}{"type": "string", "example": "PM2.5"},
+													"aqi":            map[string]interface{}{"type": "integer", "example": 152},
+													"category":       map[string]interface{}{"type": "string", "example": "Unhealthy"},
+													"reporting_area": map[string]interface{}{"type": "string", "example": "Northeast Urban NJ"},
+													"state_code":     map[string]interface{}{"type": "string", "example": "NJ"},
+													"observed_at":    map[string]interface{}{"type": "string", "format": "date-time"},
+												},
 											},
-											"temperature_c": map[string]interface{}{
-												"type":        "number",
-												"example":     22.5,
-												"description": "Temperature in Celsius",
+										},
+									},
+								},
+							},
+						},
+					},
+					"400": errorResponseSpec("Invalid coordinates"),
+					"404": errorResponseSpec("No air quality monitors report near the coordinate (NO_AIR_QUALITY)"),
+					"500": errorResponseSpec("Air quality could not be retrieved"),
+					"501": errorResponseSpec("No AirNow API key is configured (AIR_QUALITY_DISABLED)"),
+				},
+			},
+		},
+		"/subscriptions": map[string]interface{}{
+			"post": map[string]interface{}{
+				"summary":     "Subscribe a webhook to alerts",
+				"description": "Registers a callback URL that is POSTed {subscription_id, latitude, longitude, alert} for each new NWS alert active at the coordinate and at least as severe as min_severity. Each alert is delivered once per subscription. Deliveries carry X-Webhook-Delivery with the delivery ID and X-Webhook-Signature with sha256= and the hex HMAC-SHA256 of the body keyed by the secret, which is only returned here. A delivery the callback doesn't accept with a 2xx response is retried with backoff, then marked dead. The callback host must resolve to a public address. The subscription belongs to the caller's API key, and only that key can list or delete it.",
+				"tags":        []string{"Alerts"},
+				"requestBody": map[string]interface{}{
+					"required": true,
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": map[string]interface{}{
+								"type":     "object",
+								"required": []string{"callback_url", "lat", "lon"},
+								"properties": map[string]interface{}{
+									"callback_url": map[string]interface{}{"type": "string", "format": "uri", "example": "https://example.com/hooks/weather"},
+									"lat":          map[string]interface{}{"type": "number", "example": 40.7128},
+									"lon":          map[string]interface{}{"type": "number", "example": -74.0060},
+									"min_severity": alertSeveritySpec(),
+								},
+							},
+						},
+					},
+				},
+				"responses": map[string]interface{}{
+					"201": map[string]interface{}{
+						"description": "Subscription created, with its signing secret",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{"schema": webhookSubscriptionSpec(true)},
+						},
+					},
+					"400": errorResponseSpec("Malformed body, invalid coordinates, callback URL (INVALID_CALLBACK_URL), callback host that doesn't resolve to a public address (CALLBACK_URL_NOT_ALLOWED), or severity (INVALID_SEVERITY)"),
+					"500": errorResponseSpec("Subscription could not be saved"),
+				},
+			},
+			"get": map[string]interface{}{
+				"summary":     "List webhook subscriptions",
+				"description": "The caller's webhook subscriptions, oldest first, without their secrets",
+				"tags":        []string{"Alerts"},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Webhook subscriptions",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"subscriptions"},
+									"properties": map[string]interface{}{
+										"subscriptions": map[string]interface{}{"type": "array", "items": webhookSubscriptionSpec(false)},
+									},
+								},
+							},
+						},
+					},
+					"500": errorResponseSpec("Subscriptions could not be read"),
+				},
+			},
+		},
+		"/subscriptions/{id}": map[string]interface{}{
+			"delete": map[string]interface{}{
+				"summary":     "Delete a webhook subscription",
+				"description": "Stops deliveries to the subscription and deletes its delivery log",
+				"tags":        []string{"Alerts"},
+				"parameters":  []map[string]interface{}{subscriptionIDParameter()},
+				"responses": map[string]interface{}{
+					"204": map[string]interface{}{"description": "Subscription deleted"},
+					"404": errorResponseSpec("The caller has no such subscription (SUBSCRIPTION_NOT_FOUND)"),
+					"500": errorResponseSpec("Subscription could not be deleted"),
+				},
+			},
+		},
+		"/subscriptions/{id}/deliveries": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "List a webhook subscription's deliveries",
+				"description": "The subscription's 100 most recent deliveries, newest first. Pending deliveries are waiting for their first attempt or a retry; dead ones failed every attempt and won't be retried.",
+				"tags":        []string{"Alerts"},
+				"parameters":  []map[string]interface{}{subscriptionIDParameter()},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Deliveries, newest first",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"deliveries"},
+									"properties": map[string]interface{}{
+										"deliveries": map[string]interface{}{"type": "array", "items": webhookDeliverySpec()},
+									},
+								},
+							},
+						},
+					},
+					"404": errorResponseSpec("The caller has no such subscription (SUBSCRIPTION_NOT_FOUND)"),
+					"500": errorResponseSpec("Deliveries could not be read"),
+				},
+			},
+		},
+		"/stations/{stationId}/observations": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Get station observation history",
+				"description": "Returns recent measured observations for an NWS station, oldest first, suitable for charting",
+				"tags":        []string{"Observations"},
+				"parameters": []map[string]interface{}{
+					{
+						"name":        "stationId",
+						"in":          "path",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "string"},
+						"description": "NWS station identifier (3-5 letters or digits)",
+						"example":     "KNYC",
+					},
+					{
+						"name":        "hours",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "integer", "minimum": 1, "default": 24},
+						"description": "History window in hours, capped at 72",
+						"example":     24,
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Observation series retrieved successfully",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"station_id": map[string]interface{}{"type": "string", "example": "KNYC"},
+										"hours":      map[string]interface{}{"type": "integer", "example": 24},
+										"observations": map[string]interface{}{
+											"type":  "array",
+											"items": observationSpec(),
+										},
+									},
+								},
+							},
+						},
+					},
+					"400": errorResponseSpec("Invalid station ID or hours"),
+					"404": errorResponseSpec("Unknown station"),
+					"500": errorResponseSpec("Observation data could not be retrieved"),
+				},
+			},
+		},
+		"/observations": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Get current observed conditions",
+				"description": "The latest observation from the NWS station nearest the coordinate. When the latest observation has no temperature, the most recent one from the past 3 hours that does is returned. The station is resolved once per coordinate and remembered for 7 days; observations are cached for 5 minutes.",
+				"tags":        []string{"Observations"},
+				"parameters": []map[string]interface{}{
+					{
+						"name":        "lat",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Latitude (-90 to 90)",
+						"example":     40.7128,
+					},
+					{
+						"name":        "lon",
+						"in":          "query",
+						"required":    true,
+						"schema":      map[string]interface{}{"type": "number"},
+						"description": "Longitude (-180 to 180)",
+						"example":     -74.0060,
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Current conditions retrieved successfully",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"latitude", "longitude", "station_id", "observation"},
+									"properties": map[string]interface{}{
+										"latitude":    map[string]interface{}{"type": "number"},
+										"longitude":   map[string]interface{}{"type": "number"},
+										"station_id":  map[string]interface{}{"type": "string", "example": "KNYC"},
+										"observation": observationSpec(),
+									},
+								},
+							},
+						},
+					},
+					"400": errorResponseSpec("Invalid coordinates"),
+					"404": errorResponseSpec("Coordinate is outside NWS coverage (OUT_OF_COVERAGE), or no observation station is near it (NO_OBSERVATION_STATION)"),
+					"500": errorResponseSpec("Observation data could not be retrieved"),
+					"503": shedResponseSpec(),
+				},
+			},
+		},
+		"/metrics": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Service metrics",
+				"description": "Uptime, recent request latency, and service counters such as requests_shed",
+				"tags":        []string{"System"},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Metrics snapshot",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"uptime_seconds", "counters"},
+									"properties": map[string]interface{}{
+										"uptime_seconds":    map[string]interface{}{"type": "integer", "example": 3600},
+										"recent_latency_ms": map[string]interface{}{"type": "number", "example": 1.2},
+										"counters": map[string]interface{}{
+											"type":                 "object",
+											"additionalProperties": map[string]interface{}{"type": "integer"},
+											"example":              map[string]interface{}{"requests_shed": 0},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"/stats/daily": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Daily request stats",
+				"description": "Per-day request totals, route counts, error counts, cache hit ratio, distinct coordinates, and latency percentiles, rolled up shortly after each UTC midnight",
+				"tags":        []string{"System"},
+				"parameters": []map[string]interface{}{
+					{
+						"name":        "from",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string", "format": "date"},
+						"description": "First UTC day to include; defaults to 30 days before to",
+						"example":     "2024-01-01",
+					},
+					{
+						"name":        "to",
+						"in":          "query",
+						"required":    false,
+						"schema":      map[string]interface{}{"type": "string", "format": "date"},
+						"description": "Last UTC day to include; defaults to today. At most 366 days may be requested.",
+						"example":     "2024-01-31",
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Daily stats series, oldest first; days without traffic are omitted",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"from", "to", "days"},
+									"properties": map[string]interface{}{
+										"from": map[string]interface{}{"type": "string", "format": "date"},
+										"to":   map[string]interface{}{"type": "string", "format": "date"},
+										"days": map[string]interface{}{
+											"type": "array",
+											"items": map[string]interface{}{
+												"type": "object",
+												"required": []string{
+													"day", "total_requests", "route_counts", "client_error_count", "error_count",
+													"distinct_coordinates", "p50_latency_ms", "p95_latency_ms",
+												},
+												"properties": map[string]interface{}{
+													"day":            map[string]interface{}{"type": "string", "format": "date"},
+													"total_requests": map[string]interface{}{"type": "integer", "example": 1250},
+													"route_counts": map[string]interface{}{
+														"type":                 "object",
+														"additionalProperties": map[string]interface{}{"type": "integer"},
+														"example":              map[string]interface{}{"/api/v1/weather": 1200},
+													},
+													"client_error_count": map[string]interface{}{"type": "integer", "description": "Responses with a 4xx status"},
+													"error_count":        map[string]interface{}{"type": "integer", "description": "Responses with a 5xx status"},
+													"cache_hit_ratio": map[string]interface{}{
+														"type":        "number",
+														"description": "Share of cache-backed requests answered from a fresh cache; omitted when none were made",
+														"example":     0.92,
+													},
+													"distinct_coordinates": map[string]interface{}{"type": "integer", "example": 87},
+													"p50_latency_ms":       map[string]interface{}{"type": "number", "example": 1.4},
+													"p95_latency_ms":       map[string]interface{}{"type": "number", "example": 210.5},
+												},
 											},
-											"temperature_f": map[string]interface{}{
-												"type":        "number",
-												"example":     72.5,
-												"description": "Temperature in Fahrenheit",
+										},
+									},
+								},
+							},
+						},
+					},
+					"400": errorResponseSpec("Invalid date range"),
+					"500": errorResponseSpec("Stats could not be read"),
+				},
+			},
+		},
+		"/cache/stats": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Cache statistics",
+				"description": "Cache database size against its cap, cached rows per table, rows removed by retention purging and size-based pruning, and the cache warmer's locations",
+				"tags":        []string{"System"},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Cache statistics",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"database", "tables", "pruned_rows", "purged_rows"},
+									"properties": map[string]interface{}{
+										"database": databaseUsageSpec(),
+										"tables": map[string]interface{}{
+											"type":                 "object",
+											"additionalProperties": map[string]interface{}{"type": "integer"},
+											"example":              map[string]interface{}{"weather_cache": 350, "weather_history": 4200},
+										},
+										"pruned_rows":    map[string]interface{}{"type": "integer", "example": 1200},
+										"last_pruned_at": map[string]interface{}{"type": "string", "format": "date-time"},
+										"purged_rows":    map[string]interface{}{"type": "integer", "example": 340},
+										"last_purge_at":  map[string]interface{}{"type": "string", "format": "date-time"},
+										"warming": map[string]interface{}{
+											"type":        "array",
+											"description": "Locations the cache warmer keeps fresh, with when each was last refreshed",
+											"items": map[string]interface{}{
+												"type":     "object",
+												"required": []string{"latitude", "longitude", "source"},
+												"properties": map[string]interface{}{
+													"latitude":       map[string]interface{}{"type": "number"},
+													"longitude":      map[string]interface{}{"type": "number"},
+													"source":         map[string]interface{}{"type": "string", "enum": []string{"config", "admin"}},
+													"last_warmed_at": map[string]interface{}{"type": "string", "format": "date-time"},
+													"last_error":     map[string]interface{}{"type": "string"},
+												},
 											},
 										},
 									},
 								},
 							},
 						},
-						"400": map[string]interface{}{
-							"description": "Invalid parameters",
-							"content": map[string]interface{}{
-								"application/json": map[string]interface{}{
-									"schema": map[string]interface{}{
-										"type": "object",
-										"properties": map[string]interface{}{
-											"error":   map[string]interface{}{"type": "string"},
-											"details": map[string]interface{}{"type": "string"},
+					},
+					"500": errorResponseSpec("Cache statistics could not be read"),
+				},
+			},
+		},
+		"/me/usage": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Your own API key usage",
+				"description": "The calling key's label, daily quota with today's usage and reset time, request counts for the last 7 days, and rate limit state. These are the counters and bucket the quota and rate limiter enforce, as sent in the X-Quota-* and X-RateLimit-* headers of this response. Checking usage counts against neither, so it still answers once the quota is used up. Only the caller's own key is reported.",
+				"tags":        []string{"System"},
+				"security":    apiKeySecurity(),
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "The key's usage",
+						"headers": map[string]interface{}{
+							"X-Quota-Limit": map[string]interface{}{
+								"description": "Requests allowed a UTC day, as quota.limit; sent when the key has a quota",
+								"schema":      map[string]interface{}{"type": "integer"},
+							},
+							"X-Quota-Remaining": map[string]interface{}{
+								"description": "Requests left today, as quota.remaining",
+								"schema":      map[string]interface{}{"type": "integer"},
+							},
+							"X-Quota-Reset": map[string]interface{}{
+								"description": "Seconds until the quota resets at midnight UTC, as quota.reset_seconds",
+								"schema":      map[string]interface{}{"type": "integer"},
+							},
+							"X-RateLimit-Limit": map[string]interface{}{
+								"description": "Requests allowed a minute, as rate_limit.limit",
+								"schema":      map[string]interface{}{"type": "integer"},
+							},
+							"X-RateLimit-Remaining": map[string]interface{}{
+								"description": "Requests that may be sent now, as rate_limit.remaining",
+								"schema":      map[string]interface{}{"type": "integer"},
+							},
+							"X-RateLimit-Reset": map[string]interface{}{
+								"description": "Seconds until the bucket is full again, as rate_limit.reset_seconds",
+								"schema":      map[string]interface{}{"type": "integer"},
+							},
+						},
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"key_id", "label", "quota", "history"},
+									"properties": map[string]interface{}{
+										"key_id": map[string]interface{}{"type": "string", "example": "mobile-app"},
+										"label":  map[string]interface{}{"type": "string", "description": "The key's name, or its ID when it has none", "example": "Mobile app"},
+										"quota": map[string]interface{}{
+											"type":        "object",
+											"description": "Today's quota period, a UTC day",
+											"required":    []string{"limit", "used", "period_start", "reset_seconds", "reset_at"},
+											"properties": map[string]interface{}{
+												"limit":         map[string]interface{}{"type": "integer", "description": "Requests allowed a day; 0 means unlimited", "example": 10000},
+												"used":          map[string]interface{}{"type": "integer", "description": "Requests counted today", "example": 1234},
+												"remaining":     map[string]interface{}{"type": "integer", "description": "Omitted when the key is unlimited", "example": 8766},
+												"period_start":  map[string]interface{}{"type": "string", "format": "date-time"},
+												"reset_seconds": map[string]interface{}{"type": "integer", "description": "Seconds until the quota resets", "example": 48600},
+												"reset_at":      map[string]interface{}{"type": "string", "format": "date-time"},
+											},
+										},
+										"history": map[string]interface{}{
+											"type":        "array",
+											"description": "Requests counted on each of the last 7 UTC days, oldest first, ending today",
+											"items": map[string]interface{}{
+												"type":     "object",
+												"required": []string{"date", "requests"},
+												"properties": map[string]interface{}{
+													"date":     map[string]interface{}{"type": "string", "format": "date", "example": "2024-01-15"},
+													"requests": map[string]interface{}{"type": "integer", "example": 1234},
+												},
+											},
+										},
+										"rate_limit": map[string]interface{}{
+											"type":        "object",
+											"description": "Omitted when clients aren't rate limited",
+											"required":    []string{"limit", "burst", "remaining", "reset_seconds", "reset_at"},
+											"properties": map[string]interface{}{
+												"limit":         map[string]interface{}{"type": "integer", "description": "Requests allowed a minute", "example": 60},
+												"burst":         map[string]interface{}{"type": "integer", "description": "Requests that may be sent at once when the bucket is full", "example": 60},
+												"remaining":     map[string]interface{}{"type": "integer", "example": 42},
+												"reset_seconds": map[string]interface{}{"type": "integer", "description": "Seconds until the bucket is full again", "example": 18},
+												"reset_at":      map[string]interface{}{"type": "string", "format": "date-time"},
+											},
 										},
 									},
 								},
 							},
 						},
 					},
+					"401": errorResponseSpec("No API key was presented (API_KEY_REQUIRED), or API keys aren't enabled"),
+					"403": errorResponseSpec("The API key matches no client (API_KEY_INVALID)"),
+					"503": errorResponseSpec("The key's request counts could not be read (USAGE_UNAVAILABLE)"),
+				},
+			},
+		},
+		"/health": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Health check",
+				"description": "Check API health status, including SQLite, Redis, and the NWS. Returns 503 only when neither SQLite nor Redis is usable.",
+				"tags":        []string{"System"},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Service is healthy or degraded",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{"schema": healthResponseSpec()},
+						},
+					},
+					"503": map[string]interface{}{
+						"description": "Service is unhealthy: SQLite is unusable and Redis is down or not configured",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{"schema": healthResponseSpec()},
+						},
+					},
+				},
+			},
+		},
+		"/raw/points": map[string]interface{}{
+			"get": rawDocumentOperation("Get the raw NWS points document", "Returns the untouched NWS points response for a coordinate, as last parsed when fresh."),
+		},
+		"/raw/forecast": map[string]interface{}{
+			"get": rawDocumentOperation("Get the raw NWS forecast document", "Returns the untouched NWS forecast response for the grid cell containing a coordinate, as last parsed when fresh."),
+		},
+		"/admin/cache": map[string]interface{}{
+			"delete": map[string]interface{}{
+				"summary":     "Invalidate the cached forecast for a coordinate",
+				"description": "Removes the coordinate's cached forecast, and its grid cell's, from Redis and SQLite so the next request refetches from the NWS. Requires the admin token.",
+				"tags":        []string{"Admin"},
+				"security":    adminSecurity(),
+				"parameters":  coordinateParameters(),
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Cache entries removed",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{}},
+					},
+					"400": errorResponseSpec("Missing or invalid coordinates"),
+					"401": errorResponseSpec("Missing or wrong admin token (UNAUTHORIZED)"),
+					"500": errorResponseSpec("The cache could not be cleared"),
+				},
+			},
+		},
+		"/admin/cache/all": map[string]interface{}{
+			"delete": map[string]interface{}{
+				"summary":     "Invalidate every cached forecast",
+				"description": "Removes all cached forecasts from Redis and SQLite. Forecast history and grid mappings are kept. Requires the admin token.",
+				"tags":        []string{"Admin"},
+				"security":    adminSecurity(),
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Cache entries removed",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{}},
+					},
+					"401": errorResponseSpec("Missing or wrong admin token (UNAUTHORIZED)"),
+					"500": errorResponseSpec("The cache could not be cleared"),
+				},
+			},
+		},
+		"/admin/cache/locations": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "List cached locations",
+				"description": "Returns the coordinates with a cached forecast, most recently refreshed first, with each entry's refresh time and whether it is still fresh. Requires the admin token.",
+				"tags":        []string{"Admin"},
+				"security":    adminSecurity(),
+				"parameters": []map[string]interface{}{
+					{
+						"name":        "limit",
+						"in":          "query",
+						"schema":      map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 1000, "default": 100},
+						"description": "Page size",
+					},
+					{
+						"name":        "offset",
+						"in":          "query",
+						"schema":      map[string]interface{}{"type": "integer", "minimum": 0, "default": 0},
+						"description": "Number of locations to skip",
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "A page of cached locations",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{}},
+					},
+					"400": errorResponseSpec("Invalid limit or offset"),
+					"401": errorResponseSpec("Missing or wrong admin token (UNAUTHORIZED)"),
+					"500": errorResponseSpec("Cached locations could not be read"),
+				},
+			},
+		},
+		"/admin/warm-locations": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "List warm locations",
+				"description": "Returns the locations the cache warmer keeps fresh, from WARM_LOCATIONS and added through this endpoint, with when each was last refreshed. Requires the admin token.",
+				"tags":        []string{"Admin"},
+				"security":    adminSecurity(),
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Warm locations",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{}},
+					},
+					"401": errorResponseSpec("Missing or wrong admin token (UNAUTHORIZED)"),
+					"500": errorResponseSpec("Warm locations could not be read"),
+				},
+			},
+			"post": map[string]interface{}{
+				"summary":     "Add a warm location",
+				"description": "Adds a coordinate for the cache warmer to keep fresh, starting with its next pass. Coordinates are stored at cache precision, so adding one twice is harmless. Requires the admin token.",
+				"tags":        []string{"Admin"},
+				"security":    adminSecurity(),
+				"parameters":  coordinateParameters(),
+				"responses": map[string]interface{}{
+					"201": map[string]interface{}{
+						"description": "Location added; the warm locations after the change",
+						"content":     map[string]interface{}{"application/json": map[string]interface{}{}},
+					},
+					"400": errorResponseSpec("Missing or invalid coordinates"),
+					"401": errorResponseSpec("Missing or wrong admin token (UNAUTHORIZED)"),
+					"500": errorResponseSpec("The location could not be saved"),
+				},
+			},
+			"delete": map[string]interface{}{
+				"summary":     "Remove a warm location",
+				"description": "Stops the cache warmer refreshing a coordinate added through POST /admin/warm-locations. Locations from WARM_LOCATIONS can't be removed. Requires the admin token.",
+				"tags":        []string{"Admin"},
+				"security":    adminSecurity(),
+				"parameters":  coordinateParameters(),
+				"responses": map[string]interface{}{
+					"204": map[string]interface{}{"description": "Location removed"},
+					"400": errorResponseSpec("Missing or invalid coordinates"),
+					"401": errorResponseSpec("Missing or wrong admin token (UNAUTHORIZED)"),
+					"404": errorResponseSpec("The coordinate isn't a location added through this endpoint"),
+					"500": errorResponseSpec("The location could not be removed"),
+				},
+			},
+		},
+		"/admin/subscriptions/unowned": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "List unowned webhook subscriptions",
+				"description": "Returns the webhook subscriptions created while API keys weren't required, oldest first, without their secrets. They still receive alerts but belong to no key, so only the admin can manage them. Requires the admin token.",
+				"tags":        []string{"Admin"},
+				"security":    adminSecurity(),
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Unowned webhook subscriptions",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"subscriptions"},
+									"properties": map[string]interface{}{
+										"subscriptions": map[string]interface{}{"type": "array", "items": webhookSubscriptionSpec(false)},
+									},
+								},
+							},
+						},
+					},
+					"401": errorResponseSpec("Missing or wrong admin token (UNAUTHORIZED)"),
+					"500": errorResponseSpec("Subscriptions could not be read"),
 				},
 			},
-			"/health": map[string]interface{}{
-				"get": map[string]interface{}{
-					"summary":     "Health check",
-					"description": "Check API health status",
-					"tags":        []string{"System"},
-					"responses": map[string]interface{}{
-						"200": map[string]interface{}{
-							"description": "Service is healthy",
-							"content": map[string]interface{}{
-								"application/json": map[string]interface{}{
-									"schema": map[string]interface{}{
-										"type": "object",
-										"properties": map[string]interface{}{
-											"status":    map[string]interface{}{"type": "string", "example": "healthy"},
-											"timestamp": map[string]interface{}{"type": "string"},
-										},
+		},
+		"/admin/subscriptions/unowned/{id}": map[string]interface{}{
+			"delete": map[string]interface{}{
+				"summary":     "Delete an unowned webhook subscription",
+				"description": "Stops deliveries to a subscription created while API keys weren't required and deletes its delivery log. Requires the admin token.",
+				"tags":        []string{"Admin"},
+				"security":    adminSecurity(),
+				"parameters":  []map[string]interface{}{subscriptionIDParameter()},
+				"responses": map[string]interface{}{
+					"204": map[string]interface{}{"description": "Subscription deleted"},
+					"401": errorResponseSpec("Missing or wrong admin token (UNAUTHORIZED)"),
+					"404": errorResponseSpec("No unowned subscription has this ID (SUBSCRIPTION_NOT_FOUND)"),
+					"500": errorResponseSpec("Subscription could not be deleted"),
+				},
+			},
+		},
+		"/admin/subscriptions/unowned/{id}/deliveries": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "List an unowned webhook subscription's deliveries",
+				"description": "The 100 most recent deliveries, newest first, of a subscription created while API keys weren't required. Requires the admin token.",
+				"tags":        []string{"Admin"},
+				"security":    adminSecurity(),
+				"parameters":  []map[string]interface{}{subscriptionIDParameter()},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Deliveries, newest first",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"deliveries"},
+									"properties": map[string]interface{}{
+										"deliveries": map[string]interface{}{"type": "array", "items": webhookDeliverySpec()},
 									},
 								},
 							},
 						},
 					},
+					"401": errorResponseSpec("Missing or wrong admin token (UNAUTHORIZED)"),
+					"404": errorResponseSpec("No unowned subscription has this ID (SUBSCRIPTION_NOT_FOUND)"),
+					"500": errorResponseSpec("Deliveries could not be read"),
 				},
 			},
 		},
-		"tags": []map[string]interface{}{
-			{"name": "Weather", "description": "Weather forecast operations"},
-			{"name": "System", "description": "System health and status"},
+	}
+
+	// The coordinates-in-the-path variants are documented like their query forms
+	for _, path := range []string{"/weather", "/forecast"} {
+		query := paths[path].(map[string]interface{})["get"].(map[string]interface{})
+		paths[path+"/{lat}/{lon}"] = map[string]interface{}{"get": withPathCoordinates(query, path)}
+	}
+	weather := paths["/weather"].(map[string]interface{})
+	weather["post"] = withBodyCoordinates(weather["get"].(map[string]interface{}))
+	withPoints(weather["get"].(map[string]interface{}))
+	return paths
+}
+
+// batchWeatherResponseSpec describes the per-coordinate results returned by
+// /weather/batch and /weather?points=
+func batchWeatherResponseSpec() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "object",
+		"required": []string{"results"},
+		"properties": map[string]interface{}{
+			"results": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type":        "object",
+					"description": "Exactly one of weather and error is present",
+					"properties": map[string]interface{}{
+						"lat":     map[string]interface{}{"type": "number", "description": "Requested latitude, omitted when the request left it out or it couldn't be read"},
+						"lon":     map[string]interface{}{"type": "number", "description": "Requested longitude, omitted when the request left it out or it couldn't be read"},
+						"weather": weatherResponseSpec(),
+						"error":   errorSpec(),
+					},
+				},
+			},
+		},
+	}
+}
+
+// weatherResponseSpec describes the WeatherResponse body returned by /weather
+func weatherResponseSpec() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"forecast": map[string]interface{}{
+				"type":        "string",
+				"example":     "Partly Cloudy",
+				"description": "Short weather forecast",
+			},
+			"period_name": map[string]interface{}{
+				"type":        "string",
+				"example":     "Tonight",
+				"description": "Name of the forecast period, when the provider names it",
+			},
+			"icon": map[string]interface{}{
+				"type":        "string",
+				"format":      "uri",
+				"example":     "https://api.weather.gov/icons/land/night/few?size=medium",
+				"description": "NWS icon URL for the forecast period, sized by icon_size; omitted when the provider gives none",
+			},
+			"is_daytime": map[string]interface{}{
+				"type":        "boolean",
+				"example":     false,
+				"description": "Whether the forecast period is daytime, estimated from the local solar time when the provider doesn't say",
+			},
+			"detailed_forecast": map[string]interface{}{
+				"type":        "string",
+				"example":     "Partly cloudy, with a low around 48. West wind 5 to 10 mph.",
+				"description": "The NWS's narrative forecast for the period, present only with include=detailed and when the provider gives one",
+			},
+			"temperature": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"hot", "cold", "moderate"},
+				"description": "Temperature classification",
+			},
+			"temperature_c": map[string]interface{}{
+				"type":        "number",
+				"example":     22.5,
+				"description": "Temperature in Celsius",
+			},
+			"temperature_f": map[string]interface{}{
+				"type":        "number",
+				"example":     72.5,
+				"description": "Temperature in Fahrenheit",
+			},
+			"temperature_trend": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"rising", "falling"},
+				"example":     "rising",
+				"description": "NWS temperature trend when the temperature moves against the period's usual course, such as rising overnight; omitted when the NWS gives none",
+			},
+			"temperature_change_c": map[string]interface{}{
+				"type":        "number",
+				"example":     -1.7,
+				"description": "Change in temperature in Celsius since the location's previous refresh, omitted for its first",
+			},
+			"wind_speed_kmh": speedRangeSpec("Forecast wind speed range in km/h, omitted with units=imperial or when no wind is forecast"),
+			"wind_speed_mph": speedRangeSpec("Forecast wind speed range in mph, omitted with units=metric or when no wind is forecast"),
+			"wind_speed":     speedRangeSpec("Forecast wind speed range in the unit wind_unit asks for, replacing wind_speed_kmh and wind_speed_mph; omitted without wind_unit or when no wind is forecast"),
+			"wind_unit": map[string]interface{}{
+				"type":        "string",
+				"enum":        units.WindUnits,
+				"example":     "kn",
+				"description": "Unit of wind_speed, present with it",
+			},
+			"wind_direction": map[string]interface{}{
+				"type":        "string",
+				"example":     "NW",
+				"description": "Compass point the wind blows from, when forecast",
+			},
+			"precipitation_probability": map[string]interface{}{
+				"type":        "number",
+				"example":     30,
+				"description": "Chance of precipitation in percent, omitted when the provider doesn't forecast it rather than reported as 0",
+			},
+			"humidity_percent": map[string]interface{}{
+				"type":        "number",
+				"example":     48,
+				"description": "Relative humidity in percent, when forecast",
+			},
+			"dewpoint_c": map[string]interface{}{
+				"type":        "number",
+				"example":     22.2,
+				"description": "Dewpoint in Celsius, omitted with units=imperial or when not forecast",
+			},
+			"dewpoint_f": map[string]interface{}{
+				"type":        "number",
+				"example":     72,
+				"description": "Dewpoint in Fahrenheit, omitted with units=metric or when not forecast",
+			},
+			"uv_index": map[string]interface{}{
+				"type":        "number",
+				"example":     7,
+				"description": "UV index forecast for the hour the data was fetched, present only with include=uv and when the EPA forecasts for the location's city. A failed lookup omits it rather than failing the response.",
+			},
+			"uv_category": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"low", "moderate", "high", "very high", "extreme"},
+				"example":     "high",
+				"description": "WHO exposure category of uv_index: low (0-2), moderate (3-5), high (6-7), very high (8-10), or extreme (11+)",
+			},
+			"valid_at": map[string]interface{}{
+				"type":        "string",
+				"format":      "date-time",
+				"description": "Instant the values describe, present only with at: the requested time, or the start of the covering period beyond the hourly forecast",
+			},
+			"interpolated": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Present only with at: true when values were interpolated between hourly entries, false when taken from a day or night period",
+			},
+			"location": map[string]interface{}{
+				"type":        "string",
+				"example":     "Newark, NJ",
+				"description": "Nearest city to the coordinate as reported by the NWS, when known",
+			},
+			"place": placeSpec("Place a zip, city, or q lookup resolved to, present only for lookups"),
+			"approximate_location": map[string]interface{}{
+				"type":        "object",
+				"description": "Caller's location estimated from their IP address, present only when the request gave no location; the forecast is for these coordinates, and the caller is likely within accuracy_km of them",
+				"required":    []string{"latitude", "longitude"},
+				"properties": map[string]interface{}{
+					"latitude":    map[string]interface{}{"type": "number", "example": 51.5142},
+					"longitude":   map[string]interface{}{"type": "number", "example": -0.0931},
+					"accuracy_km": map[string]interface{}{"type": "integer", "example": 20},
+					"city":        map[string]interface{}{"type": "string", "example": "London"},
+					"region":      map[string]interface{}{"type": "string", "example": "ENG", "description": "ISO 3166-2 subdivision code without the country prefix"},
+					"country":     map[string]interface{}{"type": "string", "example": "GB", "description": "ISO 3166-1 alpha-2 country code"},
+				},
+			},
+			"cached_point": map[string]interface{}{
+				"type":        "object",
+				"description": "Coordinate the forecast was cached for, present only when it was served from a nearby point's fresh cache entry, within NEARBY_CACHE_RADIUS_KM, rather than the requested coordinate's own",
+				"required":    []string{"latitude", "longitude", "distance_km"},
+				"properties": map[string]interface{}{
+					"latitude":    map[string]interface{}{"type": "number", "example": 40.72},
+					"longitude":   map[string]interface{}{"type": "number", "example": -74.006},
+					"distance_km": map[string]interface{}{"type": "number", "example": 0.78, "description": "Distance from the requested coordinate, to the nearest 10 m"},
+				},
+			},
+			"provider": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"nws", "open-meteo", "owm"},
+				"example":     "nws",
+				"description": "Forecast provider the data came from: the NWS, Open-Meteo (configured, or as the fallback outside NWS coverage), or OpenWeatherMap",
+			},
+			"source": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"live", "redis", "memory", "sqlite", "postgres", "stale"},
+				"example":     "redis",
+				"description": "Where the data came from: live from the provider, the redis, memory, sqlite, or postgres cache, or stale cached data served while it is refreshed in the background or because the upstream fetch failed",
+			},
+			"cached_at": map[string]interface{}{
+				"type":        "string",
+				"format":      "date-time",
+				"description": "When the data was fetched from the provider, in UTC",
+			},
+			"time_zone": timeZoneSpec(),
+			"cached_at_local": map[string]interface{}{
+				"type":        "string",
+				"format":      "date-time",
+				"description": "cached_at in time_zone, when known",
+			},
+			"advisories": map[string]interface{}{
+				"type":        "object",
+				"description": "Derived frost and heat risk flags, present only with include=advisories. Heuristics computed locally from the current forecast and the day/night periods in the next 24 hours, so a daytime request flags overnight frost; they may precede official NWS advisories.",
+				"properties": map[string]interface{}{
+					"frost_risk": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Temperature at or below the hard freeze threshold (default -2°C), or at or below the frost threshold (default 2°C) with clear skies and light wind (default ≤10 km/h). Missing wind data counts as light wind.",
+					},
+					"heat_risk": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Heat index at or above the heat threshold (default 32°C). The NWS Rothfusz regression is used; the air temperature stands in when humidity is unavailable.",
+					},
+					"heat_index_c": map[string]interface{}{
+						"type":        "number",
+						"description": "Highest heat index across the evaluated forecast samples, in Celsius",
+					},
+					"severity": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"none", "low", "moderate", "high"},
+						"description": "Worst of the frost and heat levels. Frost is high at or below the hard freeze threshold and moderate at or below 0°C; heat is high at or above the danger threshold (default 39°C).",
+					},
+				},
+			},
+		},
+	}
+}
+
+// withPathCoordinates returns a copy of an operation taking lat and lon as
+// query parameters, for its variant at path/{lat}/{lon}. Place lookups, which
+// the path form has no room for, are left out.
+func withPathCoordinates(query map[string]interface{}, path string) map[string]interface{} {
+	op := copySpec(query).(map[string]interface{})
+	op["summary"] = fmt.Sprintf("%s (coordinates in the path)", op["summary"])
+	op["description"] = fmt.Sprintf("Same as GET %s?lat=&lon=, with the coordinates as path segments for SDK generators and CDN caches that handle them better. A trailing slash is allowed, and a URL-encoded minus sign (%%2D) is decoded.", path)
+
+	op["parameters"] = append([]map[string]interface{}{
+		{"name": "lat", "in": "path", "required": true, "schema": map[string]interface{}{"type": "number", "minimum": -90, "maximum": 90}, "description": "Latitude", "example": 40.7128},
+		{"name": "lon", "in": "path", "required": true, "schema": map[string]interface{}{"type": "number", "minimum": -180, "maximum": 180}, "description": "Longitude", "example": -74.0060},
+	}, optionParameters(op)...)
+	delete(op["responses"].(map[string]interface{}), "300")
+	return op
+}
+
+// withBodyCoordinates returns a copy of the GET /weather operation for POST
+// /weather, which takes the coordinates as a JSON body
+func withBodyCoordinates(query map[string]interface{}) map[string]interface{} {
+	op := copySpec(query).(map[string]interface{})
+	op["summary"] = "Get weather forecast for posted coordinates"
+	op["description"] = "Same as GET /weather?lat=&lon=, with the coordinates in a JSON body for clients whose gateways strip long query strings. Unknown fields in the body are ignored, and the other parameters stay in the query."
+	op["parameters"] = optionParameters(op)
+	op["requestBody"] = map[string]interface{}{
+		"required": true,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{
+					"type":     "object",
+					"required": []string{"latitude", "longitude"},
+					"properties": map[string]interface{}{
+						"latitude":  map[string]interface{}{"type": "number", "minimum": -90, "maximum": 90, "example": 40.7128},
+						"longitude": map[string]interface{}{"type": "number", "minimum": -180, "maximum": 180, "example": -74.0060},
+					},
+				},
+			},
+		},
+	}
+	responses := op["responses"].(map[string]interface{})
+	delete(responses, "300")
+	responses["400"] = errorResponseSpec("The body isn't a JSON object of coordinates, with the decoding error in details (INVALID_COORDINATES_BODY), or a coordinate is missing or out of range")
+	responses["404"] = errorResponseSpec("?at= was given but the NWS publishes no hourly forecast for the location (NO_HOURLY_FORECAST)")
+	responses["415"] = errorResponseSpec("The body isn't sent as application/json (UNSUPPORTED_MEDIA_TYPE)")
+	return op
+}
+
+// withPoints documents ?points= on GET /weather, which answers with the
+// per-point results of /weather/batch instead of a single forecast. It is
+// added after the operation's variants are copied, since they don't take it.
+func withPoints(op map[string]interface{}) {
+	op["description"] = fmt.Sprintf("%s With points, up to %d lat,lon pairs separated by semicolons are looked up at once and answered like POST /weather/batch, for clients that can only send GET requests.", op["description"], MaxWeatherPoints)
+	op["parameters"] = append(op["parameters"].([]map[string]interface{}), map[string]interface{}{
+		"name":        "points",
+		"in":          "query",
+		"required":    false,
+		"schema":      map[string]interface{}{"type": "string"},
+		"description": fmt.Sprintf("Semicolon-separated lat,lon pairs to look up instead of one location, at most %d. Whitespace and empty pairs, such as after a trailing semicolon, are ignored. Each pair is checked like lat and lon, and an invalid one gets its own error in the results. Can't be combined with other location parameters (INVALID_POINTS).", MaxWeatherPoints),
+		"example":     "40.71,-74.00;34.05,-118.24",
+	})
+
+	responses := op["responses"].(map[string]interface{})
+	ok := responses["200"].(map[string]interface{})
+	content := ok["content"].(map[string]interface{})
+	weather := content["application/json"].(map[string]interface{})["schema"]
+	content["application/json"] = map[string]interface{}{
+		"schema": map[string]interface{}{"oneOf": []interface{}{weather, batchWeatherResponseSpec()}},
+	}
+	ok["description"] = "Weather data retrieved successfully, or with points, one result per point in request order"
+	responses["400"] = errorResponseSpec(fmt.Sprintf("%s; with points, points was empty or combined with another location parameter (INVALID_POINTS), or had more than %d pairs (BATCH_TOO_LARGE)", responses["400"].(map[string]interface{})["description"], MaxWeatherPoints))
+}
+
+// optionParameters returns the parameters of a /weather or /forecast operation
+// other than its location, which its variants take elsewhere
+func optionParameters(op map[string]interface{}) []map[string]interface{} {
+	var params []map[string]interface{}
+	for _, param := range op["parameters"].([]map[string]interface{}) {
+		if !slices.Contains([]string{"lat", "lon", "zip", "city", "q"}, param["name"].(string)) {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// copySpec deep-copies part of the spec so a copy can be changed on its own
+func copySpec(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			c[k] = copySpec(item)
+		}
+		return c
+	case []map[string]interface{}:
+		c := make([]map[string]interface{}, len(v))
+		for i, item := range v {
+			c[i] = copySpec(item).(map[string]interface{})
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, item := range v {
+			c[i] = copySpec(item)
+		}
+		return c
+	}
+	return v
+}
+
+// coordinateParameters describes the required lat and lon query parameters
+func coordinateParameters() []map[string]interface{} {
+	return []map[string]interface{}{
+		{
+			"name":        "lat",
+			"in":          "query",
+			"required":    true,
+			"schema":      map[string]interface{}{"type": "number", "minimum": -90, "maximum": 90},
+			"description": "Latitude",
+			"example":     40.7128,
+		},
+		{
+			"name":        "lon",
+			"in":          "query",
+			"required":    true,
+			"schema":      map[string]interface{}{"type": "number", "minimum": -180, "maximum": 180},
+			"description": "Longitude",
+			"example":     -74.0060,
+		},
+	}
+}
+
+// adminSecurity requires the admin token for an operation
+func adminSecurity() []map[string]interface{} {
+	return []map[string]interface{}{{"adminToken": []string{}}}
+}
+
+// apiKeySecurity is the security requirement of endpoints that need a client's API key
+func apiKeySecurity() []map[string]interface{} {
+	return []map[string]interface{}{{"apiKey": []string{}}}
+}
+
+// rawDocumentOperation describes an admin endpoint returning an untouched NWS document
+func rawDocumentOperation(summary, description string) map[string]interface{} {
+	return map[string]interface{}{
+		"summary":     summary,
+		"description": description + " Requires the admin token or an API key.",
+		"tags":        []string{"Admin"},
+		"security":    append(adminSecurity(), apiKeySecurity()...),
+		"parameters":  coordinateParameters(),
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "The NWS document",
+				"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": map[string]interface{}{"type": "object"}}},
+			},
+			"400": errorResponseSpec("Missing or invalid coordinates"),
+			"401": errorResponseSpec("Neither an API key nor the admin token was presented (UNAUTHORIZED)"),
+			"404": errorResponseSpec("Coordinate is outside NWS coverage (OUT_OF_COVERAGE)"),
+			"502": errorResponseSpec("The NWS could not be reached, or answered with an error"),
+			"503": shedResponseSpec(),
+		},
+	}
+}
+
+// placeSpec describes a geocoded place
+func placeSpec(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "object",
+		"description": description,
+		"required":    []string{"name", "latitude", "longitude"},
+		"properties": map[string]interface{}{
+			"name":      map[string]interface{}{"type": "string", "example": "Portland, Multnomah County, Oregon, United States"},
+			"latitude":  map[string]interface{}{"type": "number", "example": 45.5202},
+			"longitude": map[string]interface{}{"type": "number", "example": -122.6742},
+		},
+	}
+}
+
+// speedRangeSpec describes a wind speed range
+func speedRangeSpec(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "object",
+		"description": description,
+		"required":    []string{"min", "max"},
+		"properties": map[string]interface{}{
+			"min": map[string]interface{}{"type": "number", "example": 5},
+			"max": map[string]interface{}{"type": "number", "example": 10, "description": "Equal to min for a single forecast speed"},
+		},
+	}
+}
+
+// iconSizeParam describes the icon_size query parameter
+func iconSizeParam(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        "icon_size",
+		"in":          "query",
+		"required":    false,
+		"schema":      map[string]interface{}{"type": "string", "enum": []string{"small", "medium", "large"}},
+		"description": description,
+	}
+}
+
+// windUnitParam describes the wind_unit query parameter
+func windUnitParam(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        "wind_unit",
+		"in":          "query",
+		"required":    false,
+		"schema":      map[string]interface{}{"type": "string", "enum": units.WindUnits},
+		"description": description + ". Other values return 400 INVALID_WIND_UNIT listing the accepted units.",
+	}
+}
+
+// timeZoneParam describes the tz query parameter
+func timeZoneParam(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        "tz",
+		"in":          "query",
+		"required":    false,
+		"schema":      map[string]interface{}{"type": "string"},
+		"description": description + ". Unknown zones are rejected with INVALID_TIME_ZONE.",
+		"example":     "America/Chicago",
+	}
+}
+
+// timeZoneSpec describes the time_zone a response's local times are given in
+func timeZoneSpec() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "string",
+		"example":     "America/New_York",
+		"description": "IANA time zone of the local times: the tz override, or the location's own as the NWS reports it; omitted when unknown",
+	}
+}
+
+// ambiguousLocationSpec describes the ErrorResponse body plus the candidate
+// places returned when a place lookup is ambiguous
+func ambiguousLocationSpec() map[string]interface{} {
+	spec := errorSpec()
+	spec["required"] = []string{"error", "code", "candidates"}
+	spec["properties"].(map[string]interface{})["candidates"] = map[string]interface{}{
+		"type":  "array",
+		"items": placeSpec("A matching place, best match first"),
+	}
+	return spec
+}
+
+// observationSpec describes one normalized station observation
+func observationSpec() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"timestamp":          map[string]interface{}{"type": "string", "format": "date-time"},
+			"description":        map[string]interface{}{"type": "string"},
+			"temperature_c":      map[string]interface{}{"type": "number"},
+			"temperature_f":      map[string]interface{}{"type": "number"},
+			"dewpoint_c":         map[string]interface{}{"type": "number"},
+			"dewpoint_f":         map[string]interface{}{"type": "number"},
+			"wind_speed_kmh":     map[string]interface{}{"type": "number"},
+			"wind_speed_mph":     map[string]interface{}{"type": "number"},
+			"wind_direction_deg": map[string]interface{}{"type": "number"},
+			"pressure_hpa":       map[string]interface{}{"type": "number"},
+		},
+	}
+}
+
+// alertSeveritySpec describes the minimum NWS alert severity a webhook
+// subscription is sent
+func alertSeveritySpec() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "string",
+		"enum":        []string{"Minor", "Moderate", "Severe", "Extreme"},
+		"default":     "Severe",
+		"description": "Least severe alert delivered; matched case-insensitively",
+	}
+}
+
+// webhookSubscriptionSpec describes a webhook subscription, with its secret
+// when it has just been created
+func webhookSubscriptionSpec(withSecret bool) map[string]interface{} {
+	required := []string{"id", "callback_url", "latitude", "longitude", "min_severity", "created_at"}
+	properties := map[string]interface{}{
+		"id":           map[string]interface{}{"type": "string", "example": "3f2b9c6d1e8a4f70"},
+		"callback_url": map[string]interface{}{"type": "string", "format": "uri"},
+		"latitude":     map[string]interface{}{"type": "number"},
+		"longitude":    map[string]interface{}{"type": "number"},
+		"min_severity": alertSeveritySpec(),
+		"created_at":   map[string]interface{}{"type": "string", "format": "date-time"},
+	}
+	if withSecret {
+		required = append(required, "secret")
+		properties["secret"] = map[string]interface{}{
+			"type":        "string",
+			"description": "Key of the HMAC-SHA256 signature sent with each delivery; not shown again",
+		}
+	}
+	return map[string]interface{}{"type": "object", "required": required, "properties": properties}
+}
+
+// webhookDeliverySpec describes one alert delivery to a webhook subscription
+func webhookDeliverySpec() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "object",
+		"required": []string{"id", "subscription_id", "alert_id", "status", "attempts", "created_at"},
+		"properties": map[string]interface{}{
+			"id":              map[string]interface{}{"type": "integer"},
+			"subscription_id": map[string]interface{}{"type": "string"},
+			"alert_id":        map[string]interface{}{"type": "string"},
+			"status":          map[string]interface{}{"type": "string", "enum": []string{"pending", "delivered", "dead"}},
+			"attempts":        map[string]interface{}{"type": "integer"},
+			"last_error":      map[string]interface{}{"type": "string", "example": "callback returned status 503"},
+			"next_attempt_at": map[string]interface{}{"type": "string", "format": "date-time"},
+			"created_at":      map[string]interface{}{"type": "string", "format": "date-time"},
+			"delivered_at":    map[string]interface{}{"type": "string", "format": "date-time"},
+		},
+	}
+}
+
+// subscriptionIDParameter describes the {id} path parameter of the
+// subscription endpoints
+func subscriptionIDParameter() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        "id",
+		"in":          "path",
+		"required":    true,
+		"schema":      map[string]interface{}{"type": "string"},
+		"description": "Subscription ID",
+	}
+}
+
+// errorSpec describes the standard ErrorResponse body
+func errorSpec() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "object",
+		"required": []string{"error", "code"},
+		"properties": map[string]interface{}{
+			"error": map[string]interface{}{
+				"type":        "string",
+				"description": "Human-readable summary in the language chosen by ?lang= or Accept-Language (en, es; English when untranslated)",
+			},
+			"details": map[string]interface{}{"type": "string"},
+			"code": map[string]interface{}{
+				"type":        "string",
+				"description": "Stable machine-readable error code, never localized",
+			},
+		},
+	}
+}
+
+// errorResponseSpec describes a response carrying the standard ErrorResponse body
+func errorResponseSpec(description string) map[string]interface{} {
+	return withXML(map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": errorSpec(),
+			},
+		},
+	})
+}
+
+// withXML adds an application/xml body with the JSON body's schema to a
+// response, for endpoints whose format is negotiated
+func withXML(response map[string]interface{}) map[string]interface{} {
+	content := response["content"].(map[string]interface{})
+	content["application/xml"] = content["application/json"]
+	return response
+}
+
+// withCSV adds the text/csv export offered by ?format=csv to a response
+func withCSV(response map[string]interface{}) map[string]interface{} {
+	content := response["content"].(map[string]interface{})
+	content["text/csv"] = map[string]interface{}{
+		"schema": map[string]interface{}{
+			"type":        "string",
+			"description": "Header row timestamp,latitude,longitude,period,short_forecast,temp_c,temp_f,wind,precipitation_probability, then one row per entry. Sent with a Content-Disposition filename naming the coordinates and date.",
+		},
+	}
+	return response
+}
+
+// graphQLResponseSpec describes a GraphQL response: data when the query was
+// executed, and errors with the REST error code in extensions
+func graphQLResponseSpec(description string, executed bool) map[string]interface{} {
+	properties := map[string]interface{}{
+		"errors": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type":     "object",
+				"required": []string{"message"},
+				"properties": map[string]interface{}{
+					"message":   map[string]interface{}{"type": "string"},
+					"locations": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}},
+					"path":      map[string]interface{}{"type": "array", "items": map[string]interface{}{}},
+					"extensions": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"code":    map[string]interface{}{"type": "string", "example": "OUT_OF_COVERAGE"},
+							"details": map[string]interface{}{"type": "string"},
+						},
+					},
+				},
+			},
+		},
+	}
+	if executed {
+		properties["data"] = map[string]interface{}{"type": "object", "nullable": true}
+	}
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{"type": "object", "properties": properties},
+			},
+		},
+	}
+}
+
+// cachedWeatherOperation describes the cache presence check, served for both HEAD and GET
+func cachedWeatherOperation() map[string]interface{} {
+	return map[string]interface{}{
+		"summary":     "Check for a cached forecast",
+		"description": "Reports whether a fresh cached forecast exists for a coordinate, so a /weather request would be answered without an NWS fetch. Never calls the NWS and returns no body.",
+		"tags":        []string{"Weather"},
+		"parameters": []map[string]interface{}{
+			{
+				"name":        "lat",
+				"in":          "query",
+				"required":    true,
+				"schema":      map[string]interface{}{"type": "number"},
+				"description": "Latitude (-90 to 90)",
+				"example":     40.7128,
+			},
+			{
+				"name":        "lon",
+				"in":          "query",
+				"required":    true,
+				"schema":      map[string]interface{}{"type": "number"},
+				"description": "Longitude (-180 to 180)",
+				"example":     -74.0060,
+			},
+		},
+		"responses": map[string]interface{}{
+			"204": map[string]interface{}{
+				"description": "A fresh forecast is cached",
+				"headers": map[string]interface{}{
+					"Age": map[string]interface{}{
+						"description": "Seconds since the cached forecast was fetched",
+						"schema":      map[string]interface{}{"type": "integer"},
+					},
+					"X-Data-Source": map[string]interface{}{
+						"description": "Cache tier holding the forecast: the coordinate's own entry or its grid cell's",
+						"schema": map[string]interface{}{
+							"type": "string",
+							"enum": []string{"redis", "memory", "sqlite", "postgres", "nearby:sqlite", "nearby:postgres", "grid:redis", "grid:sqlite"},
+						},
+					},
+				},
+			},
+			"400": errorResponseSpec("Invalid parameters"),
+			"404": map[string]interface{}{"description": "No fresh forecast is cached"},
+		},
+	}
+}
+
+// databaseUsageSpec is the schema of the cache database size report
+func databaseUsageSpec() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "object",
+		"required": []string{"size_bytes"},
+		"properties": map[string]interface{}{
+			"size_bytes":  map[string]interface{}{"type": "integer", "example": 52428800},
+			"max_bytes":   map[string]interface{}{"type": "integer", "description": "Size cap; omitted when unlimited", "example": 104857600},
+			"usage_ratio": map[string]interface{}{"type": "number", "description": "size_bytes / max_bytes; omitted when unlimited", "example": 0.5},
+		},
+	}
+}
+
+// healthResponseSpec describes the HealthResponse body returned by /health
+func healthResponseSpec() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"status": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"healthy", "degraded", "unhealthy"},
+				"description": "degraded while a dependency is down or the cache database is above 90% of its size cap; unhealthy when neither SQLite nor Redis is usable",
+				"example":     "healthy",
+			},
+			"timestamp": map[string]interface{}{"type": "string"},
+			"database":  databaseUsageSpec(),
+			"temperature_thresholds": map[string]interface{}{
+				"type":        "object",
+				"description": "Effective thresholds for the hot/cold/moderate classification",
+				"required":    []string{"hot_c", "cold_c"},
+				"properties": map[string]interface{}{
+					"hot_c":  map[string]interface{}{"type": "number", "description": "Readings at or above this are hot", "example": 30},
+					"cold_c": map[string]interface{}{"type": "number", "description": "Readings at or below this are cold", "example": 10},
+				},
+			},
+			"dependencies": map[string]interface{}{
+				"type":     "object",
+				"required": []string{"sqlite", "redis", "nws"},
+				"properties": map[string]interface{}{
+					"sqlite":   dependencyHealthSpec("A query against the cache database, with a 1s timeout"),
+					"redis":    dependencyHealthSpec("A Redis PING with a 1s timeout; disabled when Redis is not configured"),
+					"nws":      dependencyHealthSpec("The outcome of the most recent NWS request; unknown before the first"),
+					"postgres": dependencyHealthSpec("A ping of the Postgres forecast store with a 1s timeout; present only when DATABASE_URL selects Postgres"),
+				},
+			},
+		},
+	}
+}
+
+// dependencyHealthSpec describes one dependency's section of the health response
+func dependencyHealthSpec(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "object",
+		"description": description,
+		"required":    []string{"status", "latency_ms"},
+		"properties": map[string]interface{}{
+			"status":        map[string]interface{}{"type": "string", "enum": []string{"up", "down", "disabled", "unknown"}, "example": "up"},
+			"latency_ms":    map[string]interface{}{"type": "number", "example": 0.42},
+			"error":         map[string]interface{}{"type": "string", "description": "Why the dependency is down"},
+			"circuit":       map[string]interface{}{"type": "string", "enum": []string{"closed", "open", "half-open"}, "description": "State of the NWS circuit breaker; present only for the NWS when the breaker is enabled"},
+			"backoff_until": map[string]interface{}{"type": "string", "format": "date-time", "description": "When the Retry-After the NWS last sent runs out; present only while NWS requests are held back at its request"},
+		},
+	}
+}
+
+// shedResponseSpec describes the 503 returned when a request is shed under load
+func shedResponseSpec() map[string]interface{} {
+	spec := errorResponseSpec("Upstream capacity is saturated (SHED), NWS requests are paused by the circuit breaker (NWS_CIRCUIT_OPEN) or at the NWS's request (NWS_BACKOFF), and no cached data exists")
+	spec["headers"] = map[string]interface{}{
+		"Retry-After": map[string]interface{}{
+			"description": "Seconds to wait before retrying",
+			"schema":      map[string]interface{}{"type": "integer"},
 		},
 	}
+	return spec
+}
+
+// weatherUnavailableSpec describes the 503 returned by /weather when a request
+// is shed or the forecast provider's quota is used up
+func weatherUnavailableSpec() map[string]interface{} {
+	spec := shedResponseSpec()
+	spec["description"] = "Upstream capacity is saturated (SHED), NWS requests are paused by the circuit breaker (NWS_CIRCUIT_OPEN) or at the NWS's request (NWS_BACKOFF), or the forecast provider's quota is used up (PROVIDER_QUOTA_EXCEEDED), and no cached data exists. Retry-After is set for SHED, NWS_CIRCUIT_OPEN, and NWS_BACKOFF."
+	return spec
+}
+
+// OpenAPISpecJSON returns the OpenAPI specification of every route in the
+// registry serialized as JSON, with a host-relative server URL
+func OpenAPISpecJSON() ([]byte, error) {
+	return json.Marshal(getOpenAPISpec(APIBasePath+"/"+APIVersion, apiRoutes))
+}
+
+// docsPageData is the live data rendered into the documentation page. Optional
+// fields are left empty when their source is unavailable.
+type docsPageData struct {
+	ServerURL string
+	// SpecURL is where the page loads the OpenAPI document from
+	SpecURL       string
+	Health        *models.HealthResponse
+	Uptime        string
+	RecentLatency string
+}
+
+var docsTemplate = template.Must(template.New("docs").Parse(docsHTML))
+
+// renderAPIDocs renders the documentation page with the given live data
+func renderAPIDocs(w io.Writer, data docsPageData) error {
+	return docsTemplate.Execute(w, data)
+}
+
+// pageData collects the live data for a docs page request
+func (h *DocsHandler) pageData(c *fiber.Ctx) docsPageData {
+	data := docsPageData{ServerURL: h.serverURL(c), SpecURL: h.externalBaseURL + "/openapi.json"}
+	if h.health != nil {
+		health := h.health(c.UserContext())
+		data.Health = &health
+	}
+	if h.metrics != nil {
+		data.Uptime = formatDuration(h.metrics.Uptime().Truncate(time.Second))
+		if latency, ok := h.metrics.RecentLatency(); ok {
+			data.RecentLatency = formatDuration(latency.Round(100 * time.Microsecond))
+		}
+	}
+	return data
+}
+
+// formatDuration renders a duration compactly, e.g. 3h12m or 1.2ms
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	return s
 }
 
-// GetAPIDocsHTML returns the futuristic API documentation HTML
-func GetAPIDocsHTML() string {
-	apiSpec, _ := json.Marshal(getOpenAPISpec())
+// ServeAPIDocs serves the futuristic API documentation page for Fiber
+func (h *DocsHandler) ServeAPIDocs(c *fiber.Ctx) error {
+	var buf bytes.Buffer
+	if err := renderAPIDocs(&buf, h.pageData(c)); err != nil {
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeDocsUnavailable, "cause", err.Error())
+	}
+
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.Send(buf.Bytes())
+}
 
-	return fmt.Sprintf(`<!DOCTYPE html>
+// docsHTML is the documentation page template
+const docsHTML = `<!DOCTYPE html>
 <html lang="en">
 <head>
     <meta charset="UTF-8">
@@ -158,7 +2477,7 @@ func GetAPIDocsHTML() string {
         
         body {
             font-family: 'Inter', -apple-system, BlinkMacSystemFont, sans-serif;
-            background: linear-gradient(135deg, #0f0f23 0%%, #1a1a2e 50%%, #16213e 100%%);
+            background: linear-gradient(135deg, #0f0f23 0%, #1a1a2e 50%, #16213e 100%);
             min-height: 100vh;
             color: #e4e4e7;
         }
@@ -190,7 +2509,7 @@ func GetAPIDocsHTML() string {
         .logo-icon {
             width: 40px;
             height: 40px;
-            background: linear-gradient(135deg, #667eea 0%%, #764ba2 100%%);
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
             border-radius: 12px;
             display: flex;
             align-items: center;
@@ -201,7 +2520,7 @@ func GetAPIDocsHTML() string {
         .logo-text {
             font-size: 1.25rem;
             font-weight: 600;
-            background: linear-gradient(135deg, #667eea 0%%, #764ba2 100%%);
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
             -webkit-background-clip: text;
             -webkit-text-fill-color: transparent;
             background-clip: text;
@@ -217,6 +2536,21 @@ func GetAPIDocsHTML() string {
             letter-spacing: 0.05em;
         }
         
+        .badge-healthy {
+            background: rgba(34, 197, 94, 0.15);
+            color: #4ade80;
+        }
+        
+        .badge-unhealthy {
+            background: rgba(239, 68, 68, 0.15);
+            color: #f87171;
+        }
+        
+        .badge-muted {
+            background: rgba(255, 255, 255, 0.05);
+            color: #a1a1aa;
+        }
+        
         .header-actions {
             display: flex;
             gap: 1rem;
@@ -235,7 +2569,7 @@ func GetAPIDocsHTML() string {
         }
         
         .btn-primary {
-            background: linear-gradient(135deg, #667eea 0%%, #764ba2 100%%);
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
             color: white;
             border: none;
         }
@@ -256,6 +2590,25 @@ func GetAPIDocsHTML() string {
             border-color: rgba(255, 255, 255, 0.2);
         }
         
+        .examples {
+            max-width: 1400px;
+            margin: 0 auto;
+            padding: 1.5rem 2rem 0;
+            display: flex;
+            flex-wrap: wrap;
+            gap: 0.75rem;
+        }
+        
+        .examples code {
+            font-family: 'JetBrains Mono', monospace;
+            font-size: 0.8rem;
+            background: rgba(255, 255, 255, 0.05);
+            border: 1px solid rgba(255, 255, 255, 0.1);
+            border-radius: 8px;
+            padding: 0.5rem 0.75rem;
+            color: #c4b5fd;
+        }
+        
         .main-content {
             max-width: 1400px;
             margin: 0 auto;
@@ -267,7 +2620,7 @@ func GetAPIDocsHTML() string {
             background: rgba(255, 255, 255, 0.03);
             border: 1px solid rgba(255, 255, 255, 0.1);
             border-radius: 16px;
-            height: 100%%;
+            height: 100%;
             overflow: hidden;
             box-shadow: 0 8px 32px rgba(0, 0, 0, 0.4);
         }
@@ -276,7 +2629,7 @@ func GetAPIDocsHTML() string {
             position: fixed;
             width: 600px;
             height: 600px;
-            background: radial-gradient(circle, rgba(102, 126, 234, 0.15) 0%%, transparent 70%%);
+            background: radial-gradient(circle, rgba(102, 126, 234, 0.15) 0%, transparent 70%);
             top: -300px;
             right: -300px;
             pointer-events: none;
@@ -287,7 +2640,7 @@ func GetAPIDocsHTML() string {
             position: fixed;
             width: 400px;
             height: 400px;
-            background: radial-gradient(circle, rgba(118, 75, 162, 0.1) 0%%, transparent 70%%);
+            background: radial-gradient(circle, rgba(118, 75, 162, 0.1) 0%, transparent 70%);
             bottom: -200px;
             left: -200px;
             pointer-events: none;
@@ -305,6 +2658,17 @@ func GetAPIDocsHTML() string {
                 <div class="logo-icon">🌤️</div>
                 <span class="logo-text">Weather API</span>
                 <span class="badge">v1.0.0</span>
+                {{- with .Health}}
+                <span class="badge {{if eq .Status "healthy"}}badge-healthy{{else}}badge-unhealthy{{end}}">{{.Status}}</span>
+                {{- else}}
+                <span class="badge badge-muted">status unknown</span>
+                {{- end}}
+                {{- if .Uptime}}
+                <span class="badge badge-muted">up {{.Uptime}}</span>
+                {{- end}}
+                {{- if .RecentLatency}}
+                <span class="badge badge-muted">p50 {{.RecentLatency}}</span>
+                {{- end}}
             </div>
             <div class="header-actions">
                 <a href="/" class="btn btn-secondary">Back to App</a>
@@ -313,10 +2677,16 @@ func GetAPIDocsHTML() string {
         </div>
     </header>
     
+    <section class="examples">
+        <code>curl "{{.ServerURL}}/weather?lat=40.7128&amp;lon=-74.0060"</code>
+        <code>curl "{{.ServerURL}}/stations/KNYC/observations?hours=12"</code>
+        <code>curl "{{.ServerURL}}/health"</code>
+    </section>
+    
     <main class="main-content">
         <div class="elements-container">
             <elements-api
-                apiDescriptionDocument='%s'
+                apiDescriptionUrl="{{.SpecURL}}"
                 router="hash"
                 layout="sidebar"
                 hideSchemas="false"
@@ -325,11 +2695,16 @@ func GetAPIDocsHTML() string {
         </div>
     </main>
 </body>
-</html>`, string(apiSpec))
-}
+</html>`
 
-// ServeAPIDocs serves the futuristic API documentation page for Fiber
-func ServeAPIDocs(c *fiber.Ctx) error {
-	c.Set("Content-Type", "text/html; charset=utf-8")
-	return c.SendString(GetAPIDocsHTML())
+// ServeOpenAPIJSON handles GET /openapi.json requests, and GET /swagger/doc.json
+// where Swagger UI looks for it
+// @Summary Get the OpenAPI specification
+// @Description Returns the OpenAPI document of the routes this deployment serves, with its server URL
+// @Tags docs
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /openapi.json [get]
+func (h *DocsHandler) ServeOpenAPIJSON(c *fiber.Ctx) error {
+	return c.JSON(h.spec(c))
 }
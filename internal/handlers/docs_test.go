@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/jsonschema"
+	"weather-api-go/internal/metrics"
+	"weather-api-go/internal/models"
+)
+
+// specServerURL fetches /swagger/doc.json and returns the first server URL
+func specServerURL(t *testing.T, app *fiber.App, headers map[string]string) string {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/swagger/doc.json", nil)
+	req.Host = "api.example.com"
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&spec); err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Servers) != 1 {
+		t.Fatalf("servers = %v; want exactly one", spec.Servers)
+	}
+	return spec.Servers[0].URL
+}
+
+func TestOpenAPIServerURL(t *testing.T) {
+	forwarded := map[string]string{
+		"X-Forwarded-Proto": "https",
+		"X-Forwarded-Host":  "weather.example.com",
+	}
+
+	tests := []struct {
+		name    string
+		baseURL string
+		trusted []string
+		headers map[string]string
+		want    string
+	}{
+		{"direct request", "", nil, nil, "http://api.example.com/api/v1"},
+		{"trusted proxy", "", []string{"0.0.0.0"}, forwarded, "https://weather.example.com/api/v1"},
+		{"untrusted proxy headers ignored", "", []string{"10.0.0.1"}, forwarded, "http://api.example.com/api/v1"},
+		{"configured base URL", "https://public.example.com/", nil, forwarded, "https://public.example.com/api/v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{EnableTrustedProxyCheck: true, TrustedProxies: tt.trusted})
+			app.Get("/swagger/doc.json", NewDocsHandler(tt.baseURL).ServeOpenAPIJSON)
+
+			if got := specServerURL(t, app, tt.headers); got != tt.want {
+				t.Errorf("server URL = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIDocsPageUsesRequestHost(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	req := httptest.NewRequest("GET", "/docs", nil)
+	req.Host = "docs.example.com:8080"
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "http://docs.example.com:8080/api") {
+		t.Error("docs page does not advertise the request host as the server URL")
+	}
+	if strings.Contains(string(body), "localhost:3000") {
+		t.Error("docs page still references localhost:3000")
+	}
+}
+
+func TestRenderAPIDocs(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    docsPageData
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "live data",
+			data: docsPageData{
+				ServerURL:     "https://weather.example.com/api",
+				SpecURL:       "https://weather.example.com/openapi.json",
+				Health:        &models.HealthResponse{Status: "healthy"},
+				Uptime:        "3h12m",
+				RecentLatency: "1.2ms",
+			},
+			want: []string{
+				`<span class="badge badge-healthy">healthy</span>`,
+				"up 3h12m",
+				"p50 1.2ms",
+				`curl "https://weather.example.com/api/weather?lat=40.7128&amp;lon=-74.0060"`,
+				`apiDescriptionUrl="https://weather.example.com/openapi.json"`,
+			},
+		},
+		{
+			name:    "health and metrics unavailable",
+			data:    docsPageData{ServerURL: "http://localhost:3000/api", SpecURL: "/openapi.json"},
+			want:    []string{"status unknown", `curl "http://localhost:3000/api/health"`},
+			notWant: []string{`class="badge badge-healthy"`, ">up ", ">p50 "},
+		},
+		{
+			name: "unhealthy",
+			data: docsPageData{ServerURL: "/api", SpecURL: "/openapi.json", Health: &models.HealthResponse{Status: "degraded"}},
+			want: []string{`<span class="badge badge-unhealthy">degraded</span>`},
+		},
+		{
+			name: "values are escaped",
+			data: docsPageData{
+				ServerURL: `http://evil.example.com/"><script>alert(1)</script>`,
+				SpecURL:   `/openapi.json"><b>it's bold</b>`,
+				Health:    &models.HealthResponse{Status: "<script>"},
+			},
+			notWant: []string{"<script>", `it's`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := renderAPIDocs(&buf, tt.data); err != nil {
+				t.Fatalf("renderAPIDocs: %v", err)
+			}
+			page := buf.String()
+			for _, s := range tt.want {
+				if !strings.Contains(page, s) {
+					t.Errorf("page is missing %q", s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(page, s) {
+					t.Errorf("page unexpectedly contains %q", s)
+				}
+			}
+		})
+	}
+}
+
+func TestServeAPIDocsWithLiveData(t *testing.T) {
+	recorder := metrics.NewRecorder(10)
+	recorder.Observe(3 * time.Millisecond)
+
+	health := func(context.Context) models.HealthResponse { return models.HealthResponse{Status: "healthy"} }
+	app := fiber.New()
+	app.Get("/docs", NewDocsHandler("", WithHealthCheck(health), WithMetrics(recorder)).ServeAPIDocs)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/docs", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q; want text/html", ct)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	for _, s := range []string{`class="badge badge-healthy"`, ">up 0s<", ">p50 3ms<"} {
+		if !strings.Contains(string(body), s) {
+			t.Errorf("docs page is missing %q", s)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{3*time.Hour + 12*time.Minute, "3h12m"},
+		{time.Minute, "1m"},
+		{45 * time.Second, "45s"},
+		{1200 * time.Microsecond, "1.2ms"},
+	}
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%v) = %q; want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestOpenAPISpecDocumentsRegisteredRoutes(t *testing.T) {
+	app := fiber.New()
+	routes := NewRoutes(app.Group(APIBasePath), APIVersion)
+	noop := func(c *fiber.Ctx) error { return nil }
+	for _, route := range apiRoutes {
+		routes.add(route.Method, route.Path, []fiber.Handler{noop})
+	}
+	app.Get("/openapi.json", NewDocsHandler("", WithRoutes(routes)).ServeOpenAPIJSON)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/openapi.json", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Paths map[string]map[string]struct {
+			Summary   string `json:"summary"`
+			Responses map[string]struct {
+				Content map[string]struct {
+					Schema struct {
+						Properties map[string]json.RawMessage `json:"properties"`
+						OneOf      []struct {
+							Properties map[string]json.RawMessage `json:"properties"`
+						} `json:"oneOf"`
+					} `json:"schema"`
+				} `json:"content"`
+			} `json:"responses"`
+		} `json:"paths"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&spec); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every route Fiber serves under the API is in the spec, with its
+	// hand-written documentation
+	documented := documentedPaths()
+	for _, route := range app.GetRoutes(true) {
+		path, ok := strings.CutPrefix(route.Path, APIBasePath+"/"+APIVersion)
+		if !ok || route.Method == fiber.MethodHead {
+			continue
+		}
+		method := strings.ToLower(route.Method)
+		if _, ok := spec.Paths[openAPIPath(path)][method]; !ok {
+			t.Errorf("%s %s is served but missing from the spec", route.Method, route.Path)
+		}
+		if _, ok := documented[openAPIPath(path)].(map[string]interface{})[method]; !ok {
+			t.Errorf("%s %s has no documented operation", route.Method, route.Path)
+		}
+	}
+	// and no documentation is left for a route that doesn't exist
+	for path, methods := range documented {
+		for method := range methods.(map[string]interface{}) {
+			if _, ok := spec.Paths[path][method]; !ok {
+				t.Errorf("%s %s is documented but not in the route registry", strings.ToUpper(method), path)
+			}
+		}
+	}
+
+	// WeatherResponse is documented with exactly the struct's JSON properties,
+	// as the first of the shapes /weather answers with
+	var want []string
+	weather := reflect.TypeOf(models.WeatherResponse{})
+	for i := 0; i < weather.NumField(); i++ {
+		name, _, _ := strings.Cut(weather.Field(i).Tag.Get("json"), ",")
+		if name != "-" && weather.Field(i).IsExported() {
+			want = append(want, name)
+		}
+	}
+	var got []string
+	for name := range spec.Paths["/weather"]["get"].Responses["200"].Content["application/json"].Schema.OneOf[0].Properties {
+		got = append(got, name)
+	}
+	slices.Sort(want)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("/weather properties = %v; want the WeatherResponse fields %v", got, want)
+	}
+}
+
+func TestOpenAPISpecOnlyDocumentsRegisteredRoutes(t *testing.T) {
+	app := fiber.New()
+	routes := NewRoutes(app.Group(APIBasePath), APIVersion)
+	routes.Get("/health", func(c *fiber.Ctx) error { return nil })
+	spec := getOpenAPISpec(APIBasePath, routes.List())
+	paths := spec["paths"].(map[string]interface{})
+	if len(paths) != 1 || paths["/health"] == nil {
+		t.Errorf("paths = %v; want only /health", paths)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a route missing from the registry didn't panic")
+		}
+	}()
+	routes.Get("/undocumented", func(c *fiber.Ctx) error { return nil })
+}
+
+func TestMergeSchema(t *testing.T) {
+	type model struct {
+		Name  string   `json:"name" example:"Portland"`
+		Count *int     `json:"count,omitempty"`
+		Tags  []string `json:"tags"`
+	}
+	documented := map[string]interface{}{
+		"type":        "object",
+		"description": "A model",
+		"required":    []string{"name", "removed"},
+		"properties": map[string]interface{}{
+			"name":    map[string]interface{}{"type": "string", "description": "The name"},
+			"count":   map[string]interface{}{"type": "string", "description": "Marshaled as a string"},
+			"removed": map[string]interface{}{"type": "string"},
+		},
+	}
+	got := mergeSchema(jsonschema.Schema(model{}), documented)
+
+	if got["description"] != "A model" {
+		t.Errorf("description = %v; want the documented one", got["description"])
+	}
+	properties := got["properties"].(map[string]interface{})
+	if _, ok := properties["removed"]; ok {
+		t.Error("a property the struct lacks is still documented")
+	}
+	name := properties["name"].(map[string]interface{})
+	if name["description"] != "The name" || name["example"] != "Portland" || name["examples"] != nil {
+		t.Errorf("name = %v; want its documented description and the struct's example", name)
+	}
+	if count := properties["count"].(map[string]interface{}); count["type"] != "string" {
+		t.Errorf("count = %v; want the documented type where the two disagree", count)
+	}
+	if tags := properties["tags"].(map[string]interface{}); tags["type"] != "array" {
+		t.Errorf("tags = %v; want the undocumented field generated", tags)
+	}
+	if required := got["required"].([]string); !slices.Equal(required, []string{"name", "tags"}) {
+		t.Errorf("required = %v; want the struct's", required)
+	}
+}
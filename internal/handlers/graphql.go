@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/location"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"weather-api-go/internal/i18n"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/services"
+)
+
+// DefaultMaxQueryDepth is how deeply a GraphQL query may nest fields when no
+// limit is configured
+const DefaultMaxQueryDepth = 6
+
+// DefaultMaxQueryComplexity is the most a GraphQL query may cost when no limit
+// is configured; see graphQLLookupCost
+const DefaultMaxQueryComplexity = 100
+
+// graphQLLookupCost is the complexity of a root field, each of which is a
+// service lookup that may reach upstream. Every other field costs 1, so
+// aliasing a lookup many times exhausts the budget quickly.
+const graphQLLookupCost = 10
+
+// MaxForecastDays is the most days the GraphQL forecast may be limited to; the
+// NWS forecasts about a week ahead
+const MaxForecastDays = 7
+
+// graphQLRequest is a GraphQL-over-HTTP POST body
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphQLScope is what resolvers need from the request: the service, the
+// context its lookups run under, and the request itself to localize errors.
+// The lookups' context carries the request timeout, which the query's own
+// context doesn't, as graphql-go would abandon the whole query at it rather
+// than let each field report its timeout. Resolvers run before the handler
+// returns, so holding the fiber context is safe.
+type graphQLScope struct {
+	service *services.WeatherService
+	ctx     context.Context
+	c       *fiber.Ctx
+}
+
+type graphQLScopeKey struct{}
+
+// scopeOf returns the scope a resolver runs in
+func scopeOf(p graphql.ResolveParams) graphQLScope {
+	return p.Context.Value(graphQLScopeKey{}).(graphQLScope)
+}
+
+// graphQLError is a resolver error carrying the REST error code and details
+// as extensions
+type graphQLError struct {
+	resp models.ErrorResponse
+}
+
+func (e graphQLError) Error() string {
+	return e.resp.Error
+}
+
+func (e graphQLError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.resp.Code}
+	if e.resp.Details != "" {
+		ext["details"] = e.resp.Details
+	}
+	return ext
+}
+
+// formatted is the error as sent in a rejected request's errors list
+func (e graphQLError) formatted() []gqlerrors.FormattedError {
+	return []gqlerrors.FormattedError{{Message: e.Error(), Locations: []location.SourceLocation{}, Extensions: e.Extensions()}}
+}
+
+// newGraphQLError builds the error for a code in the request's language
+func newGraphQLError(c *fiber.Ctx, code string, params ...string) graphQLError {
+	return graphQLError{i18n.Error(c, code, params...)}
+}
+
+// serviceError maps a service failure to the code its REST endpoint would
+// answer with, or unavailable for anything else
+func serviceError(c *fiber.Ctx, err error, unavailable string) graphQLError {
+	var shed *services.ShedError
+	switch {
+	case errors.As(err, &shed):
+		return newGraphQLError(c, models.ErrorCodeShed)
+	case errors.Is(err, services.ErrOutsideCoverage):
+		return newGraphQLError(c, models.ErrorCodeOutOfCoverage)
+	case errors.Is(err, services.ErrQuotaExceeded):
+		return newGraphQLError(c, models.ErrorCodeQuotaExceeded)
+	}
+	if _, code, ok := failureCode(err); ok {
+		return newGraphQLError(c, code, "cause", err.Error())
+	}
+	return newGraphQLError(c, unavailable, "cause", err.Error())
+}
+
+// coordinateArgs reads and range-checks the lat and lon arguments
+func coordinateArgs(p graphql.ResolveParams) (float64, float64, error) {
+	lat, _ := p.Args["lat"].(float64)
+	lon, _ := p.Args["lon"].(float64)
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return 0, 0, newGraphQLError(scopeOf(p).c, models.ErrorCodeCoordinatesOutOfRange)
+	}
+	return lat, lon, nil
+}
+
+var coordinateArgsConfig = graphql.FieldConfigArgument{
+	"lat": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Float), Description: "Latitude (-90 to 90)"},
+	"lon": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Float), Description: "Longitude (-180 to 180)"},
+}
+
+var weatherType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Weather",
+	Description: "Short forecast and temperature characterization, as served by /weather",
+	Fields: graphql.Fields{
+		"forecast":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"temperature":  &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "hot, cold, or moderate"},
+		"temperatureC": &graphql.Field{Type: graphql.Float},
+		"temperatureF": &graphql.Field{Type: graphql.Float},
+		"location":     &graphql.Field{Type: graphql.String, Description: "Nearest city, as reported by the NWS"},
+		"provider":     &graphql.Field{Type: graphql.String},
+		"source":       &graphql.Field{Type: graphql.String, Description: "live, redis, memory, sqlite, postgres, or stale"},
+		"cachedAt":     &graphql.Field{Type: graphql.DateTime},
+	},
+})
+
+var forecastPeriodType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ForecastPeriod",
+	Fields: graphql.Fields{
+		"name":                     &graphql.Field{Type: graphql.String},
+		"startTime":                &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"endTime":                  &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"isDaytime":                &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"shortForecast":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"detailedForecast":         &graphql.Field{Type: graphql.String},
+		"tempC":                    &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"tempF":                    &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"precipitationProbability": &graphql.Field{Type: graphql.Float},
+		"windSpeed":                &graphql.Field{Type: graphql.String},
+		"windDirection":            &graphql.Field{Type: graphql.String},
+	},
+})
+
+var forecastType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Forecast",
+	Fields: graphql.Fields{
+		"latitude":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"longitude": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"periods":   &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(forecastPeriodType)))},
+	},
+})
+
+var alertType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Alert",
+	Fields: graphql.Fields{
+		"id":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"event":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"severity":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"urgency":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"headline":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"messageType":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"sent":          &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"onset":         &graphql.Field{Type: graphql.DateTime},
+		"expires":       &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"affectedZones": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+	},
+})
+
+var alertsType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Alerts",
+	Fields: graphql.Fields{
+		"latitude":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"longitude": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"alerts":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(alertType)))},
+	},
+})
+
+// graphQLSchema exposes the weather, forecast, and alerts lookups. The
+// resolvers go through the same WeatherService methods as the REST endpoints,
+// so they share their caches.
+var graphQLSchema = mustGraphQLSchema()
+
+func mustGraphQLSchema() graphql.Schema {
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"weather": &graphql.Field{
+					Type:        weatherType,
+					Description: "Current forecast for a coordinate, as served by /weather",
+					Args:        coordinateArgsConfig,
+					Resolve:     resolveWeather,
+				},
+				"forecast": &graphql.Field{
+					Type:        forecastType,
+					Description: "Day/night forecast periods for a coordinate, as served by /forecast",
+					Args: graphql.FieldConfigArgument{
+						"lat":  coordinateArgsConfig["lat"],
+						"lon":  coordinateArgsConfig["lon"],
+						"days": &graphql.ArgumentConfig{Type: graphql.Int, Description: "Limit the periods to the first 1-7 days"},
+					},
+					Resolve: resolveForecast,
+				},
+				"alerts": &graphql.Field{
+					Type:        alertsType,
+					Description: "Active NWS alerts for a coordinate, as served by /alerts",
+					Args:        coordinateArgsConfig,
+					Resolve:     resolveAlerts,
+				},
+			},
+		}),
+	})
+	if err != nil {
+		panic("graphql schema: " + err.Error())
+	}
+	return schema
+}
+
+func resolveWeather(p graphql.ResolveParams) (interface{}, error) {
+	lat, lon, err := coordinateArgs(p)
+	if err != nil {
+		return nil, err
+	}
+	scope := scopeOf(p)
+	weather, err := scope.service.GetWeather(scope.ctx, lat, lon)
+	if err != nil {
+		return nil, serviceError(scope.c, err, models.ErrorCodeWeatherUnavailable)
+	}
+	return weather, nil
+}
+
+func resolveForecast(p graphql.ResolveParams) (interface{}, error) {
+	lat, lon, err := coordinateArgs(p)
+	if err != nil {
+		return nil, err
+	}
+	scope := scopeOf(p)
+	days, limited := p.Args["days"].(int)
+	if limited && (days < 1 || days > MaxForecastDays) {
+		return nil, newGraphQLError(scope.c, models.ErrorCodeInvalidDays, "max", strconv.Itoa(MaxForecastDays))
+	}
+	forecast, err := scope.service.GetForecast(scope.ctx, lat, lon)
+	if err != nil {
+		return nil, serviceError(scope.c, err, models.ErrorCodeWeatherUnavailable)
+	}
+	if limited {
+		limitedForecast := *forecast
+		limitedForecast.Periods = firstDays(forecast.Periods, days)
+		return &limitedForecast, nil
+	}
+	return forecast, nil
+}
+
+// firstDays returns the periods starting on the first n local dates
+func firstDays(periods []models.ForecastPeriod, n int) []models.ForecastPeriod {
+	var seen int
+	var last string
+	for i, p := range periods {
+		if date := p.StartTime.Format(time.DateOnly); date != last {
+			if seen == n {
+				return periods[:i]
+			}
+			seen, last = seen+1, date
+		}
+	}
+	return periods
+}
+
+func resolveAlerts(p graphql.ResolveParams) (interface{}, error) {
+	lat, lon, err := coordinateArgs(p)
+	if err != nil {
+		return nil, err
+	}
+	scope := scopeOf(p)
+	alerts, err := scope.service.GetPointAlerts(scope.ctx, lat, lon)
+	if err != nil {
+		return nil, serviceError(scope.c, err, models.ErrorCodeAlertsUnavailable)
+	}
+	return alerts, nil
+}
+
+// queryCost measures a selection set: how deeply it nests fields, and its
+// complexity, where root fields cost graphQLLookupCost and any other field 1.
+// Fragment cycles are rejected by validation before this runs.
+func queryCost(set *ast.SelectionSet, fragments map[string]*ast.FragmentDefinition, root bool) (depth, complexity int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var d, cost int
+		switch s := selection.(type) {
+		case *ast.Field:
+			d, cost = queryCost(s.SelectionSet, fragments, false)
+			d++
+			if root {
+				cost += graphQLLookupCost
+			} else {
+				cost++
+			}
+		case *ast.InlineFragment:
+			d, cost = queryCost(s.SelectionSet, fragments, root)
+		case *ast.FragmentSpread:
+			if fragment := fragments[s.Name.Value]; fragment != nil {
+				d, cost = queryCost(fragment.SelectionSet, fragments, root)
+			}
+		}
+		depth = max(depth, d)
+		complexity += cost
+	}
+	return depth, complexity
+}
+
+// checkQueryLimits reports the error code and parameters for the first
+// operation over the depth or complexity limit, or an empty code
+func (h *WeatherHandler) checkQueryLimits(doc *ast.Document) (string, []string) {
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+	for _, def := range doc.Definitions {
+		operation, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		depth, complexity := queryCost(operation.SelectionSet, fragments, true)
+		if depth > h.maxQueryDepth {
+			return models.ErrorCodeQueryTooDeep, []string{"max", strconv.Itoa(h.maxQueryDepth)}
+		}
+		if complexity > h.maxQueryComplexity {
+			return models.ErrorCodeQueryTooComplex, []string{
+				"cost", strconv.Itoa(complexity),
+				"max", strconv.Itoa(h.maxQueryComplexity),
+				"lookup", strconv.Itoa(graphQLLookupCost),
+			}
+		}
+	}
+	return "", nil
+}
+
+// sendGraphQLErrors rejects a request before execution with 400 and errors
+// in the GraphQL response shape, without data since nothing was executed
+func sendGraphQLErrors(c *fiber.Ctx, errs []gqlerrors.FormattedError) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errs})
+}
+
+// GraphQL handles POST /graphql requests
+// @Summary Query weather, forecast, and alerts with GraphQL
+// @Description Executes a GraphQL query against the weather, forecast(days), and alerts lookups, which share the REST endpoints' caches. Queries over the depth or complexity limit, and malformed or invalid queries, are rejected with 400 before anything is fetched; lookup failures are reported per field in errors, with the REST error code in extensions.
+// @Tags weather
+// @Accept json
+// @Produce json
+// @Param request body object true "GraphQL request with query, and optional variables and operationName"
+// @Success 200 {object} object "GraphQL response with data and errors"
+// @Failure 400 {object} object "GraphQL response with errors"
+// @Router /graphql [post]
+func (h *WeatherHandler) GraphQL(c *fiber.Ctx) error {
+	var req graphQLRequest
+	if err := c.App().Config().JSONDecoder(c.Body(), &req); err != nil || req.Query == "" {
+		return sendGraphQLErrors(c, newGraphQLError(c, models.ErrorCodeInvalidGraphQL).formatted())
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		return sendGraphQLErrors(c, gqlerrors.FormatErrors(err))
+	}
+	if result := graphql.ValidateDocument(&graphQLSchema, doc, nil); !result.IsValid {
+		return sendGraphQLErrors(c, result.Errors)
+	}
+	if code, params := h.checkQueryLimits(doc); code != "" {
+		return sendGraphQLErrors(c, newGraphQLError(c, code, params...).formatted())
+	}
+
+	lookups, cancel := h.requestContext(c)
+	defer cancel()
+	ctx := context.WithValue(c.UserContext(), graphQLScopeKey{}, graphQLScope{service: h.service, ctx: lookups, c: c})
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        graphQLSchema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+	return c.JSON(result)
+}
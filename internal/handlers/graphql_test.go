@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+	"weather-api-go/internal/services"
+)
+
+// graphQLResult is a GraphQL response as clients decode it
+type graphQLResult struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+// postGraphQL sends a GraphQL request body and decodes the response
+func postGraphQL(t *testing.T, app *fiber.App, body string) (int, graphQLResult) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/api/graphql", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req, 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result graphQLResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return resp.StatusCode, result
+}
+
+// graphQLBody encodes a query and its variables as a request body
+func graphQLBody(t *testing.T, query string, variables map[string]any) string {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+// countingNWS serves points, a three-period forecast spanning two days, and
+// one active alert, counting the requests for each
+func countingNWS(t *testing.T) (*httptest.Server, map[string]*int32) {
+	t.Helper()
+	counts := map[string]*int32{"points": new(int32), "forecast": new(int32), "alerts": new(int32)}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/points/"):
+			atomic.AddInt32(counts["points"], 1)
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35, "forecast": "%s/gridpoints/OKX/33,35/forecast"}}`, server.URL)
+		case strings.HasSuffix(r.URL.Path, "/forecast"):
+			atomic.AddInt32(counts["forecast"], 1)
+			fmt.Fprint(w, `{"properties": {"periods": [
+				{"name": "Tonight", "startTime": "2099-01-15T18:00:00-05:00", "endTime": "2099-01-16T06:00:00-05:00",
+				 "shortForecast": "Light Snow", "temperature": 28, "temperatureUnit": "F"},
+				{"name": "Friday", "startTime": "2099-01-16T06:00:00-05:00", "endTime": "2099-01-16T18:00:00-05:00",
+				 "isDaytime": true, "shortForecast": "Sunny", "temperature": 41, "temperatureUnit": "F"},
+				{"name": "Friday Night", "startTime": "2099-01-16T18:00:00-05:00", "endTime": "2099-01-17T06:00:00-05:00",
+				 "shortForecast": "Clear", "temperature": 30, "temperatureUnit": "F"}
+			]}}`)
+		case r.URL.Path == "/alerts/active":
+			atomic.AddInt32(counts["alerts"], 1)
+			fmt.Fprint(w, `{"features": [
+				{"properties": {"id": "a1", "event": "Winter Storm Warning", "severity": "Severe", "urgency": "Expected",
+				 "sent": "2099-01-15T03:00:00-05:00", "expires": "2099-01-16T06:00:00-05:00",
+				 "affectedZones": ["https://api.weather.gov/zones/forecast/NYZ072"]}}
+			]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, counts
+}
+
+const cardQuery = `query Card($lat: Float!, $lon: Float!) {
+	weather(lat: $lat, lon: $lon) { forecast temperatureF source }
+	forecast(lat: $lat, lon: $lon, days: 1) { periods { name shortForecast startTime } }
+	alerts(lat: $lat, lon: $lon) { alerts { event affectedZones } }
+}`
+
+func TestGraphQLCombinedQuery(t *testing.T) {
+	nws, counts := countingNWS(t)
+	app := newTestApp(t, nws)
+	body := graphQLBody(t, cardQuery, map[string]any{"lat": 40.7128, "lon": -74.006})
+
+	// Like /weather and /forecast, the lookups share the grid point. The
+	// weather lookup caches the forecast document's periods, which spares the
+	// forecast lookup its fetch when graphql-go, which resolves fields in no
+	// fixed order, runs the weather lookup first.
+	wantCounts := map[string]int32{"points": 1, "forecast": 2, "alerts": 1}
+	var fetched int32
+	checkCounts := func(t *testing.T, when string) {
+		t.Helper()
+		for kind, count := range counts {
+			got := atomic.LoadInt32(count)
+			if got != wantCounts[kind] && !(kind == "forecast" && got == 1) {
+				t.Errorf("%s%s fetched %d times; want %d", when, kind, got, wantCounts[kind])
+			}
+		}
+	}
+	tests := []struct {
+		name       string
+		wantSource string
+	}{
+		{"first", services.SourceLive},
+		// Served entirely from cache
+		{"repeated", "sqlite"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, result := postGraphQL(t, app, body)
+			if status != fiber.StatusOK || len(result.Errors) > 0 {
+				t.Fatalf("status = %d, errors = %+v; want 200 without errors", status, result.Errors)
+			}
+
+			var weather struct {
+				Forecast     string  `json:"forecast"`
+				TemperatureF float64 `json:"temperatureF"`
+				Source       string  `json:"source"`
+			}
+			var forecast struct {
+				Periods []struct {
+					Name          string `json:"name"`
+					ShortForecast string `json:"shortForecast"`
+					StartTime     string `json:"startTime"`
+				} `json:"periods"`
+			}
+			var alerts struct {
+				Alerts []struct {
+					Event         string   `json:"event"`
+					AffectedZones []string `json:"affectedZones"`
+				} `json:"alerts"`
+			}
+			for field, v := range map[string]any{"weather": &weather, "forecast": &forecast, "alerts": &alerts} {
+				if err := json.Unmarshal(result.Data[field], v); err != nil {
+					t.Fatalf("decoding %s %s: %v", field, result.Data[field], err)
+				}
+			}
+
+			if weather.Forecast != "Light Snow" || weather.TemperatureF != 28 || weather.Source != tt.wantSource {
+				t.Errorf("weather = %+v; want Light Snow at 28F from %s", weather, tt.wantSource)
+			}
+			// days: 1 keeps only the periods starting on the first date
+			if len(forecast.Periods) != 1 || forecast.Periods[0].Name != "Tonight" || forecast.Periods[0].StartTime != "2099-01-15T18:00:00-05:00" {
+				t.Errorf("forecast periods = %+v; want only Tonight", forecast.Periods)
+			}
+			if len(alerts.Alerts) != 1 || alerts.Alerts[0].Event != "Winter Storm Warning" || len(alerts.Alerts[0].AffectedZones) != 1 {
+				t.Errorf("alerts = %+v; want the winter storm warning", alerts.Alerts)
+			}
+
+			checkCounts(t, "")
+			if got := atomic.LoadInt32(counts["forecast"]); fetched == 0 {
+				fetched = got
+			} else if got != fetched {
+				t.Errorf("forecast fetched %d more times; want none", got-fetched)
+			}
+		})
+	}
+
+	// REST requests share the caches the resolvers filled
+	for _, target := range []string{"/api/weather?lat=40.7128&lon=-74.006", "/api/forecast?lat=40.7128&lon=-74.006", "/api/alerts?lat=40.7128&lon=-74.006"} {
+		resp, err := app.Test(httptest.NewRequest("GET", target, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Errorf("%s: status = %d; want 200", target, resp.StatusCode)
+		}
+	}
+	checkCounts(t, "after REST requests ")
+	if got := atomic.LoadInt32(counts["forecast"]); got != fetched {
+		t.Errorf("REST requests fetched the forecast %d more times; want none", got-fetched)
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	nws, counts := countingNWS(t)
+	app := newTestApp(t, nws)
+
+	var aliases strings.Builder
+	for i := 0; i <= DefaultMaxQueryComplexity/graphQLLookupCost; i++ {
+		fmt.Fprintf(&aliases, "w%d: weather(lat: 40.7128, lon: %d) { forecast } ", i, -70-i)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string // empty for GraphQL's own syntax and validation errors
+		wantData   bool
+	}{
+		{"not JSON", "query { weather }", fiber.StatusBadRequest, models.ErrorCodeInvalidGraphQL, false},
+		{"no query", `{"variables": {}}`, fiber.StatusBadRequest, models.ErrorCodeInvalidGraphQL, false},
+		{"syntax error", graphQLBody(t, "{ weather(lat: 40", nil), fiber.StatusBadRequest, "", false},
+		{"unknown field", graphQLBody(t, "{ weather(lat: 40.7128, lon: -74.006) { humidity } }", nil), fiber.StatusBadRequest, "", false},
+		{"missing argument", graphQLBody(t, "{ alerts(lat: 40.7128) { latitude } }", nil), fiber.StatusBadRequest, "", false},
+		{
+			"too deep", graphQLBody(t, "{ __schema { types { fields { type { ofType { ofType { name } } } } } } }", nil),
+			fiber.StatusBadRequest, models.ErrorCodeQueryTooDeep, false,
+		},
+		{"too complex", graphQLBody(t, "{ "+aliases.String()+"}", nil), fiber.StatusBadRequest, models.ErrorCodeQueryTooComplex, false},
+		{
+			"too complex through fragments", graphQLBody(t, "fragment Lookups on Query { "+aliases.String()+"} { ... on Query { ...Lookups } }", nil),
+			fiber.StatusBadRequest, models.ErrorCodeQueryTooComplex, false,
+		},
+		// Field errors are reported alongside null data, with the REST code
+		{"coordinates out of range", graphQLBody(t, "{ weather(lat: 91, lon: -74.006) { forecast } }", nil), fiber.StatusOK, models.ErrorCodeCoordinatesOutOfRange, true},
+		{"invalid days", graphQLBody(t, "{ forecast(lat: 40.7128, lon: -74.006, days: 8) { latitude } }", nil), fiber.StatusOK, models.ErrorCodeInvalidDays, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, result := postGraphQL(t, app, tt.body)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d; want %d", status, tt.wantStatus)
+			}
+			if len(result.Errors) == 0 || result.Errors[0].Message == "" {
+				t.Fatalf("errors = %+v; want an error", result.Errors)
+			}
+			if tt.wantCode != "" && result.Errors[0].Extensions["code"] != tt.wantCode {
+				t.Errorf("error = %+v; want code %s", result.Errors[0], tt.wantCode)
+			}
+			if (result.Data != nil) != tt.wantData {
+				t.Errorf("data = %v; want data %v", result.Data, tt.wantData)
+			}
+		})
+	}
+
+	for kind, count := range counts {
+		if got := atomic.LoadInt32(count); got != 0 {
+			t.Errorf("rejected queries fetched %s %d times; want none", kind, got)
+		}
+	}
+}
+
+func TestGraphQLRequestTimeout(t *testing.T) {
+	nws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer nws.Close()
+
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	service := services.NewWeatherService(repository.NewWeatherRepository(db, nil),
+		services.NewNWSAPIClient(services.WithBaseURL(nws.URL), services.WithHTTPClient(nws.Client())))
+
+	app := fiber.New()
+	app.Post("/api/graphql", NewWeatherHandler(service, WithRequestTimeout(50*time.Millisecond)).GraphQL)
+
+	start := time.Now()
+	status, result := postGraphQL(t, app, graphQLBody(t, cardQuery, map[string]any{"lat": 40.7128, "lon": -74.006}))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("response took %v; want the lookups to share the request timeout", elapsed)
+	}
+	if status != fiber.StatusOK || len(result.Errors) != 3 {
+		t.Fatalf("status = %d, errors = %+v; want 200 with an error per lookup", status, result.Errors)
+	}
+	for _, e := range result.Errors {
+		if code := e.Extensions["code"]; code != models.ErrorCodeUpstreamTimeout && code != models.ErrorCodeAlertsUnavailable {
+			t.Errorf("error = %+v; want an upstream timeout or alerts unavailable", e)
+		}
+	}
+}
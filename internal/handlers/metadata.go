@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"weather-api-go/internal/jsoncase"
+	"weather-api-go/internal/metrics"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/services"
+)
+
+// GetMetadata handles GET /metadata requests
+// @Summary Get NWS point metadata
+// @Description Returns the NWS forecast office, grid cell, forecast and county zones, radar station, and forecast URLs the specified latitude and longitude resolve to, from the NWS points document. The metadata is cached for 30 days with the coordinate's grid mapping, which forecast lookups share.
+// @Tags weather
+// @Produce json
+// @Param lat query number true "Latitude coordinate (-90 to 90)" example(40.7128)
+// @Param lon query number true "Longitude coordinate (-180 to 180)" example(-74.0060)
+// @Success 200 {object} models.MetadataResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /metadata [get]
+func (h *WeatherHandler) GetMetadata(c *fiber.Ctx) error {
+	lat, lon, code := parseCoordinates(c)
+	if code != "" {
+		return sendError(c, fiber.StatusBadRequest, code)
+	}
+
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+	metadata, err := h.service.GetMetadata(ctx, lat, lon)
+	if err != nil {
+		var shed *services.ShedError
+		if errors.As(err, &shed) {
+			return sendShed(c, shed)
+		}
+		if errors.Is(err, services.ErrOutsideCoverage) {
+			return sendError(c, fiber.StatusNotFound, models.ErrorCodeOutOfCoverage)
+		}
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeMetadataUnavailable, "cause", err.Error())
+	}
+
+	metrics.MarkCacheHit(c, metadata.CacheHit)
+	setCacheControl(c, metadata.FreshUntil)
+	return c.JSON(jsoncase.For(c, metadata))
+}
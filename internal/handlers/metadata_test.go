@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"weather-api-go/internal/models"
+)
+
+func TestGetMetadata(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/metadata?lat=40.7128&lon=-74.0060", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	forecastURL, _ := body["forecast_url"].(string)
+	if resp.StatusCode != fiber.StatusOK || !strings.HasSuffix(forecastURL, "/gridpoints/OKX/33,35/forecast") ||
+		body["time_zone"] != "America/New_York" {
+		t.Errorf("metadata: %d %v; want the resolved grid mapping", resp.StatusCode, body)
+	}
+	// The mapping is cached for 30 days
+	if cc := resp.Header.Get(fiber.HeaderCacheControl); !strings.HasPrefix(cc, "public, max-age=259") {
+		t.Errorf("Cache-Control = %q; want about 30 days", cc)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/metadata?lon=-74.0060", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errBody models.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&errBody); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest || errBody.Code != models.ErrorCodeMissingLatitude {
+		t.Errorf("missing latitude: %d %s; want 400 %s", resp.StatusCode, errBody.Code, models.ErrorCodeMissingLatitude)
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/jsoncase"
+	"weather-api-go/internal/metrics"
+	"weather-api-go/internal/models"
+)
+
+// MetricsHandler serves the in-process service metrics
+type MetricsHandler struct {
+	recorder *metrics.Recorder
+}
+
+// NewMetricsHandler creates a metrics handler reading from the given recorder
+func NewMetricsHandler(recorder *metrics.Recorder) *MetricsHandler {
+	return &MetricsHandler{recorder: recorder}
+}
+
+// GetMetrics handles GET /metrics requests
+// @Summary Service metrics
+// @Description Returns uptime, recent request latency, and service counters such as shed requests
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.MetricsResponse
+// @Router /metrics [get]
+func (h *MetricsHandler) GetMetrics(c *fiber.Ctx) error {
+	resp := models.MetricsResponse{
+		UptimeSeconds: int64(h.recorder.Uptime().Seconds()),
+		Counters:      h.recorder.Counters(),
+	}
+	if latency, ok := h.recorder.RecentLatency(); ok {
+		ms := float64(latency.Microseconds()) / 1000
+		resp.RecentLatencyMs = &ms
+	}
+	return c.JSON(jsoncase.For(c, resp))
+}
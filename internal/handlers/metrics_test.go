@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/metrics"
+	"weather-api-go/internal/models"
+)
+
+func TestGetMetrics(t *testing.T) {
+	recorder := metrics.NewRecorder(10)
+	app := fiber.New()
+	app.Get("/api/metrics", NewMetricsHandler(recorder).GetMetrics)
+
+	get := func() models.MetricsResponse {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/metrics", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body models.MetricsResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+
+	if body := get(); body.RecentLatencyMs != nil || len(body.Counters) != 0 {
+		t.Errorf("fresh recorder metrics = %+v; want no latency or counters", body)
+	}
+
+	recorder.Observe(1500 * time.Microsecond)
+	recorder.Inc(metrics.RequestsShed)
+	recorder.Inc(metrics.RequestsShed)
+
+	body := get()
+	if body.RecentLatencyMs == nil || *body.RecentLatencyMs != 1.5 {
+		t.Errorf("recent_latency_ms = %v; want 1.5", body.RecentLatencyMs)
+	}
+	if body.Counters[metrics.RequestsShed] != 2 {
+		t.Errorf("counters = %v; want %s=2", body.Counters, metrics.RequestsShed)
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/jsoncase"
+	"weather-api-go/internal/models"
+)
+
+// MaxWeatherPoints caps how many coordinates a /weather?points= request may
+// carry; larger lookups belong in POST /weather/batch
+const MaxWeatherPoints = 10
+
+// getWeatherPoints answers GET /weather?points=40.71,-74.00;34.05,-118.24, a
+// batch lookup for clients that can only send GET requests. Each pair is
+// checked like ?lat=&lon=, and results and errors are reported per point as
+// /weather/batch reports them.
+func (h *WeatherHandler) getWeatherPoints(c *fiber.Ctx) error {
+	if hasLocationQuery(c) || c.Query("zip") != "" {
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidPoints)
+	}
+	points := parsePoints(c.Query("points"))
+	if len(points) == 0 {
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidPoints)
+	}
+	if len(points) > MaxWeatherPoints {
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeBatchTooLarge, "max", strconv.Itoa(MaxWeatherPoints))
+	}
+
+	// As in GetWeatherBatch, valid[j] is the request index of coords[j]
+	resp := models.BatchWeatherResponse{Results: make([]models.BatchWeatherResult, len(points))}
+	coords := make([]models.Coordinates, 0, len(points))
+	valid := make([]int, 0, len(points))
+	for i, point := range points {
+		latStr, lonStr, _ := strings.Cut(point, ",")
+		lat, lon, code := checkCoordinates(strings.TrimSpace(latStr), strings.TrimSpace(lonStr))
+		if code != "" {
+			resp.Results[i].Error = batchError(c, code)
+			continue
+		}
+		resp.Results[i] = models.BatchWeatherResult{Lat: &lat, Lon: &lon}
+		coords = append(coords, models.Coordinates{Latitude: lat, Longitude: lon})
+		valid = append(valid, i)
+	}
+
+	h.lookupBatch(c, resp.Results, coords, valid)
+	return c.JSON(jsoncase.For(c, resp))
+}
+
+// parsePoints splits a ?points= list into its lat,lon pairs. Whitespace around
+// pairs and empty pairs, such as from a trailing semicolon, are dropped.
+func parsePoints(s string) []string {
+	var points []string
+	for _, point := range strings.Split(s, ";") {
+		if point = strings.TrimSpace(point); point != "" {
+			points = append(points, point)
+		}
+	}
+	return points
+}
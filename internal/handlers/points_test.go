@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+)
+
+func TestParsePoints(t *testing.T) {
+	tests := []struct {
+		points string
+		want   []string
+	}{
+		{"40.71,-74.00;34.05,-118.24", []string{"40.71,-74.00", "34.05,-118.24"}},
+		{"40.71,-74.00;34.05,-118.24;", []string{"40.71,-74.00", "34.05,-118.24"}},
+		{"40.71,-74.00;;;", []string{"40.71,-74.00"}},
+		{" 40.71, -74.00 ;\t34.05,-118.24 ", []string{"40.71, -74.00", "34.05,-118.24"}},
+		{";40.71,-74.00", []string{"40.71,-74.00"}},
+		{"40.71", []string{"40.71"}},
+		{"", nil},
+		{" ; ;", nil},
+	}
+	for _, tt := range tests {
+		if got := parsePoints(tt.points); !slices.Equal(got, tt.want) {
+			t.Errorf("parsePoints(%q) = %q; want %q", tt.points, got, tt.want)
+		}
+	}
+}
+
+func TestGetWeatherPoints(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	get := func(query string) (int, []byte) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?"+query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body json.RawMessage
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	// Whitespace and a trailing semicolon are tolerated, and each invalid
+	// pair gets its own error without failing the others
+	status, body := get("points=" + url.QueryEscape(" 40.7128, -74.0060 ;north,-74;40.7,;95,0;1,2,3;34.0522,-118.2437;"))
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d; want 200: %s", status, body)
+	}
+	var resp models.BatchWeatherResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		lat, lon float64
+		code     string
+	}{
+		{40.7128, -74.0060, ""},
+		{0, 0, models.ErrorCodeInvalidLatitude},
+		{0, 0, models.ErrorCodeMissingLongitude},
+		{0, 0, models.ErrorCodeCoordinatesOutOfRange},
+		{0, 0, models.ErrorCodeInvalidLongitude},
+		{34.0522, -118.2437, ""},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("%d results; want %d: %s", len(resp.Results), len(want), body)
+	}
+	for i, w := range want {
+		result := resp.Results[i]
+		if w.code != "" {
+			if result.Error == nil || result.Error.Code != w.code || result.Weather != nil {
+				t.Errorf("result %d = %+v; want error %s", i, result, w.code)
+			}
+			continue
+		}
+		if result.Error != nil || result.Weather == nil || result.Weather.Forecast != "Partly Cloudy" {
+			t.Errorf("result %d = %+v; want the forecast", i, result)
+		}
+		if result.Lat == nil || *result.Lat != w.lat || result.Lon == nil || *result.Lon != w.lon {
+			t.Errorf("result %d echoes %v,%v; want %v,%v", i, result.Lat, result.Lon, w.lat, w.lon)
+		}
+	}
+
+	// The cap counts pairs, not the empty entries around them
+	ten := strings.Repeat("40.7128,-74.0060;", MaxWeatherPoints)
+	if status, body := get("points=" + url.QueryEscape(ten+";;")); status != fiber.StatusOK {
+		t.Errorf("%d points: status = %d; want 200: %s", MaxWeatherPoints, status, body)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		code  string
+	}{
+		{"over the cap", "points=" + url.QueryEscape(ten+"34.05,-118.24"), models.ErrorCodeBatchTooLarge},
+		{"empty", "points=", models.ErrorCodeInvalidPoints},
+		{"only separators", "points=" + url.QueryEscape(" ; ;"), models.ErrorCodeInvalidPoints},
+		{"with lat and lon", "points=40.71,-74.00&lat=40.71&lon=-74.00", models.ErrorCodeInvalidPoints},
+		{"with zip", "points=40.71,-74.00&zip=10001", models.ErrorCodeInvalidPoints},
+	}
+	for _, tt := range tests {
+		status, body := get(tt.query)
+		var errResp models.ErrorResponse
+		json.Unmarshal(body, &errResp)
+		if status != fiber.StatusBadRequest || errResp.Code != tt.code {
+			t.Errorf("%s: %d %s; want 400 %s", tt.name, status, body, tt.code)
+		}
+	}
+}
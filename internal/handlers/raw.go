@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/services"
+)
+
+// GetRawPoints handles GET /raw/points requests
+// @Summary Get the raw NWS points document
+// @Description Returns the untouched NWS points response for a coordinate, as last parsed when fresh. Requires the admin token or an API key.
+// @Tags debug
+// @Produce json
+// @Param lat query number true "Latitude coordinate (-90 to 90)" example(40.7128)
+// @Param lon query number true "Longitude coordinate (-180 to 180)" example(-74.0060)
+// @Success 200 {object} object
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /raw/points [get]
+func (h *WeatherHandler) GetRawPoints(c *fiber.Ctx) error {
+	return h.sendRawDocument(c, (*services.WeatherService).GetRawPoints)
+}
+
+// GetRawForecast handles GET /raw/forecast requests
+// @Summary Get the raw NWS forecast document
+// @Description Returns the untouched NWS forecast response for the grid cell containing a coordinate, as last parsed when fresh. Requires the admin token or an API key.
+// @Tags debug
+// @Produce json
+// @Param lat query number true "Latitude coordinate (-90 to 90)" example(40.7128)
+// @Param lon query number true "Longitude coordinate (-180 to 180)" example(-74.0060)
+// @Success 200 {object} object
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /raw/forecast [get]
+func (h *WeatherHandler) GetRawForecast(c *fiber.Ctx) error {
+	return h.sendRawDocument(c, (*services.WeatherService).GetRawForecast)
+}
+
+// sendRawDocument writes an upstream document verbatim with its original content type
+func (h *WeatherHandler) sendRawDocument(c *fiber.Ctx, fetch func(s *services.WeatherService, ctx context.Context, lat, lon float64) (*models.RawDocument, error)) error {
+	lat, lon, code := parseCoordinates(c)
+	if code != "" {
+		return sendError(c, fiber.StatusBadRequest, code)
+	}
+
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+	doc, err := fetch(h.service, ctx, lat, lon)
+	if err != nil {
+		var shed *services.ShedError
+		if errors.As(err, &shed) {
+			return sendShed(c, shed)
+		}
+		if errors.Is(err, services.ErrOutsideCoverage) {
+			return sendError(c, fiber.StatusNotFound, models.ErrorCodeOutOfCoverage)
+		}
+		if errors.Is(err, services.ErrDocumentTooLarge) {
+			return sendError(c, fiber.StatusBadGateway, models.ErrorCodeDocumentTooLarge)
+		}
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeDocumentUnavailable, "cause", err.Error())
+	}
+
+	contentType := doc.ContentType
+	if contentType == "" {
+		contentType = fiber.MIMEApplicationJSON
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.Send(doc.Body)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+)
+
+func TestGetRawForecast(t *testing.T) {
+	const forecast = `{"properties":{"periods":[{"shortForecast":"Sunny","temperature":72,"temperatureUnit":"F"}]}}`
+	var nws *httptest.Server
+	nws = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/geo+json")
+		if strings.HasPrefix(r.URL.Path, "/points/") {
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 1, "gridY": 1, "forecast": "%s/forecast"}}`, nws.URL)
+			return
+		}
+		fmt.Fprint(w, forecast)
+	}))
+	defer nws.Close()
+
+	app := newTestApp(t, nws)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/raw/forecast?lat=40.7128&lon=-74.0060", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d; want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get(fiber.HeaderContentType); ct != "application/geo+json" {
+		t.Errorf("Content-Type = %q; want the upstream application/geo+json", ct)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != forecast {
+		t.Errorf("body = %s; want the untouched upstream document", body)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/raw/points?lat=abc&lon=-74", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("invalid coordinates: status = %d; want 400", resp.StatusCode)
+	}
+}
+
+func TestGetRawPointsOutsideCoverage(t *testing.T) {
+	var requests int32
+	nws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"title": "Data Unavailable For Requested Point", "status": 404, "detail": "Unable to provide data for requested point 40.5,-73.5"}`)
+	}))
+	defer nws.Close()
+	app := newTestApp(t, nws)
+
+	tests := []struct {
+		name         string
+		target       string
+		wantRequests int32
+	}{
+		// London is outside the embedded outlines, so the NWS is never asked
+		{"pre-check", "/api/raw/points?lat=51.5074&lon=-0.1278", 0},
+		// Open water inside them is only known once the points API says so
+		{"points 404", "/api/raw/points?lat=40.5&lon=-73.5", 1},
+		{"remembered", "/api/raw/points?lat=40.5&lon=-73.5", 1},
+		{"metadata", "/api/metadata?lat=40.5&lon=-73.5", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tt.target, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body models.ErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != fiber.StatusNotFound || body.Code != models.ErrorCodeOutOfCoverage {
+				t.Errorf("%d %s; want 404 %s", resp.StatusCode, body.Code, models.ErrorCodeOutOfCoverage)
+			}
+			if got := atomic.LoadInt32(&requests); got != tt.wantRequests {
+				t.Errorf("%d NWS requests; want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
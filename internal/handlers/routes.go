@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/jsonschema"
+	"weather-api-go/internal/models"
+)
+
+// APIVersion is the version of the API the routes in the registry implement,
+// and the path segment they are served under, e.g. /api/v1/weather. A breaking
+// change to a response belongs in a new version, whose handlers can adapt
+// this version's responses.
+const APIVersion = "v1"
+
+// UnversionedDeprecated is when the unversioned API paths, e.g. /api/weather,
+// were deprecated in favor of the versioned ones
+var UnversionedDeprecated = time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+
+// DefaultUnversionedSunset is when the unversioned API paths are planned to
+// stop being served
+var DefaultUnversionedSunset = time.Date(2027, time.April, 16, 0, 0, 0, 0, time.UTC)
+
+// Route is one API endpoint: its method and path, and the JSON models it reads
+// and answers with
+type Route struct {
+	Method string
+	// Path is relative to the API version, in Fiber syntax (/subscriptions/:id)
+	Path string
+	// Request is the JSON request body model, nil for requests without one
+	Request interface{}
+	// Response is the JSON success response model, nil when it isn't JSON
+	Response interface{}
+}
+
+// apiRoutes is the registry of API endpoints. Only routes listed here can be
+// registered, and the OpenAPI spec documents the ones that were, so it can
+// neither miss a route nor describe one that isn't served.
+var apiRoutes = []Route{
+	{Method: fiber.MethodGet, Path: "/weather", Response: models.WeatherResponse{}},
+	{Method: fiber.MethodGet, Path: "/weather/:lat/:lon", Response: models.WeatherResponse{}},
+	{Method: fiber.MethodPost, Path: "/weather", Request: models.Coordinates{}, Response: models.WeatherResponse{}},
+	{Method: fiber.MethodPost, Path: "/weather/batch", Request: []models.BatchCoordinate{}, Response: models.BatchWeatherResponse{}},
+	{Method: fiber.MethodPost, Path: "/graphql"},
+	{Method: fiber.MethodGet, Path: "/ws"},
+	{Method: fiber.MethodGet, Path: "/weather/cached"},
+	{Method: fiber.MethodGet, Path: "/weather/history", Response: models.WeatherHistoryResponse{}},
+	{Method: fiber.MethodGet, Path: "/weather/stream"},
+	{Method: fiber.MethodGet, Path: "/weather/hourly", Response: models.HourlyForecastResponse{}},
+	{Method: fiber.MethodGet, Path: "/forecast", Response: models.ForecastResponse{}},
+	{Method: fiber.MethodGet, Path: "/forecast/:lat/:lon", Response: models.ForecastResponse{}},
+	{Method: fiber.MethodGet, Path: "/alerts", Response: models.AlertsResponse{}},
+	{Method: fiber.MethodGet, Path: "/astronomy", Response: models.AstronomyResponse{}},
+	{Method: fiber.MethodGet, Path: "/airquality", Response: models.AirQualityResponse{}},
+	{Method: fiber.MethodGet, Path: "/metadata", Response: models.MetadataResponse{}},
+	{Method: fiber.MethodPost, Path: "/subscriptions", Request: models.CreateSubscriptionRequest{}, Response: models.WebhookSubscription{}},
+	{Method: fiber.MethodGet, Path: "/subscriptions", Response: models.WebhookSubscriptionsResponse{}},
+	{Method: fiber.MethodDelete, Path: "/subscriptions/:id"},
+	{Method: fiber.MethodGet, Path: "/subscriptions/:id/deliveries", Response: models.WebhookDeliveriesResponse{}},
+	{Method: fiber.MethodGet, Path: "/stations/:stationId/observations", Response: models.ObservationHistoryResponse{}},
+	{Method: fiber.MethodGet, Path: "/observations", Response: models.CurrentConditionsResponse{}},
+	{Method: fiber.MethodGet, Path: "/health", Response: models.HealthResponse{}},
+	{Method: fiber.MethodGet, Path: "/metrics", Response: models.MetricsResponse{}},
+	{Method: fiber.MethodGet, Path: "/stats/daily", Response: models.DailyStatsResponse{}},
+	{Method: fiber.MethodGet, Path: "/cache/stats", Response: models.CacheStatsResponse{}},
+	{Method: fiber.MethodGet, Path: "/me/usage", Response: models.UsageResponse{}},
+	{Method: fiber.MethodGet, Path: "/raw/points"},
+	{Method: fiber.MethodGet, Path: "/raw/forecast"},
+	{Method: fiber.MethodDelete, Path: "/admin/cache", Response: models.CacheInvalidationResponse{}},
+	{Method: fiber.MethodDelete, Path: "/admin/cache/all", Response: models.CacheInvalidationResponse{}},
+	{Method: fiber.MethodGet, Path: "/admin/cache/locations", Response: models.CachedLocationsResponse{}},
+	{Method: fiber.MethodGet, Path: "/admin/warm-locations", Response: models.WarmLocationsResponse{}},
+	{Method: fiber.MethodPost, Path: "/admin/warm-locations", Response: models.WarmLocationsResponse{}},
+	{Method: fiber.MethodDelete, Path: "/admin/warm-locations"},
+	{Method: fiber.MethodGet, Path: "/admin/subscriptions/unowned", Response: models.WebhookSubscriptionsResponse{}},
+	{Method: fiber.MethodDelete, Path: "/admin/subscriptions/unowned/:id"},
+	{Method: fiber.MethodGet, Path: "/admin/subscriptions/unowned/:id/deliveries", Response: models.WebhookDeliveriesResponse{}},
+}
+
+// Routes registers API routes with Fiber under a version, and remembers them
+// for the OpenAPI spec
+type Routes struct {
+	router     fiber.Router
+	version    string
+	registered []Route
+
+	// aliased serves routes at their unversioned paths too, marked with
+	// deprecated and sunset
+	aliased            bool
+	deprecated, sunset time.Time
+}
+
+// RoutesOption configures optional Routes behavior
+type RoutesOption func(*Routes)
+
+// WithUnversionedAliases also serves every route at its path without the
+// version, as it was served before versioning. Responses there carry the
+// Deprecation (RFC 9745) and Sunset (RFC 8594) headers, and a Link to the
+// versioned route; the aliases aren't documented.
+func WithUnversionedAliases(deprecated, sunset time.Time) RoutesOption {
+	return func(r *Routes) {
+		r.aliased, r.deprecated, r.sunset = true, deprecated, sunset
+	}
+}
+
+// NewRoutes registers routes under version, e.g. APIVersion, on router, which
+// is expected to be mounted at APIBasePath
+func NewRoutes(router fiber.Router, version string, opts ...RoutesOption) *Routes {
+	r := &Routes{router: router, version: version}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Get registers handlers for a GET route, which Fiber also answers for HEAD
+func (r *Routes) Get(path string, handlers ...fiber.Handler) {
+	r.add(fiber.MethodGet, path, handlers)
+}
+
+// Post registers handlers for a POST route
+func (r *Routes) Post(path string, handlers ...fiber.Handler) {
+	r.add(fiber.MethodPost, path, handlers)
+}
+
+// Delete registers handlers for a DELETE route
+func (r *Routes) Delete(path string, handlers ...fiber.Handler) {
+	r.add(fiber.MethodDelete, path, handlers)
+}
+
+// add registers a route from the registry. It panics for routes missing from
+// apiRoutes, so an undocumented route fails at startup instead of going unnoticed.
+func (r *Routes) add(method, path string, handlers []fiber.Handler) {
+	i := slices.IndexFunc(apiRoutes, func(route Route) bool { return route.Method == method && route.Path == path })
+	if i < 0 {
+		panic(fmt.Sprintf("handlers: %s %s is not in the route registry", method, path))
+	}
+	r.router.Add(method, "/"+r.version+path, handlers...)
+	if r.aliased {
+		r.router.Add(method, path, append([]fiber.Handler{r.deprecatedAlias}, handlers...)...)
+	}
+	r.registered = append(r.registered, apiRoutes[i])
+}
+
+// deprecatedAlias marks a response from an unversioned alias as deprecated,
+// linking to the versioned route
+func (r *Routes) deprecatedAlias(c *fiber.Ctx) error {
+	c.Set("Deprecation", "@"+strconv.FormatInt(r.deprecated.Unix(), 10))
+	c.Set("Sunset", r.sunset.UTC().Format(http.TimeFormat))
+	successor := APIBasePath + "/" + r.version + strings.TrimPrefix(c.Path(), APIBasePath)
+	c.Set(fiber.HeaderLink, "<"+successor+`>; rel="successor-version"`)
+	return c.Next()
+}
+
+// List returns the routes registered so far, in registration order
+func (r *Routes) List() []Route {
+	return slices.Clone(r.registered)
+}
+
+// openAPIPath converts a Fiber route path to OpenAPI's template syntax,
+// e.g. /subscriptions/:id to /subscriptions/{id}
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// pathParams returns the names of a Fiber route path's parameters
+func pathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// routeOperation completes a route's documented operation from the route
+// itself: it declares every path parameter, and reconciles the request and
+// success response schemas with the route's models. A route without
+// documentation gets a bare operation.
+func routeOperation(route Route, documented interface{}) map[string]interface{} {
+	op, ok := documented.(map[string]interface{})
+	if !ok {
+		op = map[string]interface{}{
+			"summary":   route.Method + " " + route.Path,
+			"responses": map[string]interface{}{"200": map[string]interface{}{"description": "Success"}},
+		}
+	}
+
+	params, _ := op["parameters"].([]map[string]interface{})
+	for _, name := range pathParams(route.Path) {
+		declared := slices.ContainsFunc(params, func(p map[string]interface{}) bool { return p["in"] == "path" && p["name"] == name })
+		if !declared {
+			params = append(params, map[string]interface{}{"name": name, "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"}})
+		}
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if route.Request != nil {
+		body, ok := op["requestBody"].(map[string]interface{})
+		if !ok {
+			body = map[string]interface{}{"required": true, "content": map[string]interface{}{"application/json": map[string]interface{}{}}}
+			op["requestBody"] = body
+		}
+		reconcileContent(body, route.Request)
+	}
+	if route.Response != nil {
+		responses, _ := op["responses"].(map[string]interface{})
+		for _, status := range []string{"200", "201"} {
+			if response, ok := responses[status].(map[string]interface{}); ok {
+				reconcileContent(response, route.Response)
+				break
+			}
+		}
+	}
+	return op
+}
+
+// reconcileContent replaces the JSON and XML schemas of a request body or
+// response with ones reconciled with model
+func reconcileContent(body map[string]interface{}, model interface{}) {
+	content, _ := body["content"].(map[string]interface{})
+	for _, mediaType := range []string{"application/json", "application/xml"} {
+		media, ok := content[mediaType].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		documented, _ := media["schema"].(map[string]interface{})
+		// An operation answering in more than one shape lists the model's first
+		if alternatives, ok := documented["oneOf"].([]interface{}); ok {
+			first, _ := alternatives[0].(map[string]interface{})
+			alternatives[0] = mergeSchema(jsonschema.Schema(model), first)
+			continue
+		}
+		media["schema"] = mergeSchema(jsonschema.Schema(model), documented)
+	}
+}
+
+// mergeSchema reconciles a schema generated from a Go type with its
+// documented counterpart: properties and required fields come from the type,
+// so the spec can't describe fields the type lacks, while descriptions,
+// formats, enums, and examples come from the documentation. Where the two
+// disagree on a type, the documented schema stands, since a type may marshal
+// itself differently than its fields suggest.
+func mergeSchema(generated, documented map[string]interface{}) map[string]interface{} {
+	if documented != nil && documented["type"] != generated["type"] {
+		return documented
+	}
+
+	merged := map[string]interface{}{}
+	for k, v := range documented {
+		if k != "properties" && k != "required" && k != "items" {
+			merged[k] = v
+		}
+	}
+	for k, v := range generated {
+		switch k {
+		case "properties", "required", "items":
+		case "examples":
+			// OpenAPI 3.0 schemas take a single example
+			if examples, ok := v.([]interface{}); ok && len(examples) > 0 && merged["example"] == nil {
+				merged["example"] = examples[0]
+			}
+		default:
+			if _, ok := merged[k]; !ok {
+				merged[k] = v
+			}
+		}
+	}
+
+	if properties, ok := generated["properties"].(map[string]interface{}); ok {
+		documentedProperties, _ := documented["properties"].(map[string]interface{})
+		mergedProperties := make(map[string]interface{}, len(properties))
+		for name, property := range properties {
+			documentedProperty, _ := documentedProperties[name].(map[string]interface{})
+			mergedProperties[name] = mergeSchema(property.(map[string]interface{}), documentedProperty)
+		}
+		merged["properties"] = mergedProperties
+		if required, ok := generated["required"]; ok {
+			merged["required"] = required
+		}
+	}
+	if items, ok := generated["items"].(map[string]interface{}); ok {
+		documentedItems, _ := documented["items"].(map[string]interface{})
+		merged["items"] = mergeSchema(items, documentedItems)
+	}
+	return merged
+}
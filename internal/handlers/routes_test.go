@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/repository"
+	"weather-api-go/internal/services"
+)
+
+func TestUnversionedAliases(t *testing.T) {
+	nws := fakeNWS(t)
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	client := services.NewNWSAPIClient(services.WithBaseURL(nws.URL), services.WithHTTPClient(nws.Client()))
+	handler := NewWeatherHandler(services.NewWeatherService(repository.NewWeatherRepository(db, nil), client))
+
+	deprecated := time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, time.April, 16, 0, 0, 0, 0, time.UTC)
+	app := fiber.New()
+	routes := NewRoutes(app.Group(APIBasePath), APIVersion, WithUnversionedAliases(deprecated, sunset))
+	routes.Get("/weather", handler.GetWeather)
+	routes.Get("/forecast", handler.GetForecast)
+	routes.Get("/subscriptions/:id/deliveries", func(c *fiber.Ctx) error { return c.SendString(c.Params("id")) })
+
+	get := func(path string) (string, map[string]string) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		headers := map[string]string{}
+		for _, name := range []string{"Deprecation", "Sunset", "Link"} {
+			headers[name] = resp.Header.Get(name)
+		}
+		headers["status"] = resp.Status
+		return string(body), headers
+	}
+
+	tests := []struct {
+		path      string
+		successor string
+	}{
+		{"/weather?lat=40.7128&lon=-74.0060", "/api/v1/weather"},
+		{"/forecast?lat=40.7128&lon=-74.0060", "/api/v1/forecast"},
+		{"/weather?lat=91&lon=0", "/api/v1/weather"},
+		{"/subscriptions/42/deliveries", "/api/v1/subscriptions/42/deliveries"},
+	}
+	for _, tt := range tests {
+		// The first request caches the forecast, so both are answered from the cache
+		get(APIBasePath + "/" + APIVersion + tt.path)
+		versionedBody, versioned := get(APIBasePath + "/" + APIVersion + tt.path)
+		aliasBody, alias := get(APIBasePath + tt.path)
+
+		if aliasBody != versionedBody || alias["status"] != versioned["status"] {
+			t.Errorf("%s: alias answered %s %s; want the versioned route's %s %s", tt.path, alias["status"], aliasBody, versioned["status"], versionedBody)
+		}
+		if versioned["Deprecation"] != "" || versioned["Sunset"] != "" {
+			t.Errorf("%s: versioned route marked deprecated: %v", tt.path, versioned)
+		}
+		if alias["Deprecation"] != "@1792108800" {
+			t.Errorf("%s: Deprecation = %q; want @1792108800", tt.path, alias["Deprecation"])
+		}
+		if alias["Sunset"] != "Fri, 16 Apr 2027 00:00:00 GMT" {
+			t.Errorf("%s: Sunset = %q; want Fri, 16 Apr 2027 00:00:00 GMT", tt.path, alias["Sunset"])
+		}
+		if want := "<" + tt.successor + `>; rel="successor-version"`; alias["Link"] != want {
+			t.Errorf("%s: Link = %q; want %q", tt.path, alias["Link"], want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"gopkg.in/yaml.v3"
+	"weather-api-go/internal/jsonschema"
+	"weather-api-go/internal/models"
+)
+
+// schemaModels lists the models published at /schemas/{model}.json. Schemas are
+// generated from the structs on each request, so they cannot drift from the code.
+// Every response model belongs here: the JSON case round-trip test covers this list.
+var schemaModels = map[string]interface{}{
+	"WeatherResponse":            models.WeatherResponse{},
+	"ErrorResponse":              models.ErrorResponse{},
+	"HealthResponse":             models.HealthResponse{},
+	"Advisories":                 models.Advisories{},
+	"Observation":                models.Observation{},
+	"ObservationHistoryResponse": models.ObservationHistoryResponse{},
+	"Alert":                      models.Alert{},
+	"MetricsResponse":            models.MetricsResponse{},
+	"DailyStatsResponse":         models.DailyStatsResponse{},
+	"WeatherHistoryResponse":     models.WeatherHistoryResponse{},
+	"CacheStatsResponse":         models.CacheStatsResponse{},
+	"ForecastResponse":           models.ForecastResponse{},
+	"ForecastPeriod":             models.ForecastPeriod{},
+	"HourlyForecastResponse":     models.HourlyForecastResponse{},
+	"AlertsResponse":             models.AlertsResponse{},
+	"CurrentConditionsResponse":  models.CurrentConditionsResponse{},
+	"BatchWeatherResponse":       models.BatchWeatherResponse{},
+	"AmbiguousLocationResponse":  models.AmbiguousLocationResponse{},
+	"AstronomyResponse":          models.AstronomyResponse{},
+	"AirQualityResponse":         models.AirQualityResponse{},
+	"MetadataResponse":           models.MetadataResponse{},
+	"UsageResponse":              models.UsageResponse{},
+}
+
+// ServeModelSchema handles GET /schemas/:model.json requests
+// @Summary Get a model JSON Schema
+// @Description Returns the JSON Schema (draft 2020-12) for a response model, generated from the Go struct
+// @Tags docs
+// @Produce json
+// @Param model path string true "Model name" example(WeatherResponse)
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /schemas/{model}.json [get]
+func ServeModelSchema(c *fiber.Ctx) error {
+	model, ok := schemaModels[c.Params("model")]
+	if !ok {
+		return sendError(c, fiber.StatusNotFound, models.ErrorCodeUnknownSchema, "model", c.Params("model"))
+	}
+
+	return c.JSON(jsonschema.Generate(model), "application/schema+json")
+}
+
+// ServeOpenAPIYAML handles GET /openapi.yaml requests
+// @Summary Get the OpenAPI specification as YAML
+// @Description Returns the same OpenAPI document rendered by the docs page, serialized as YAML
+// @Tags docs
+// @Produce application/yaml
+// @Success 200 {string} string
+// @Router /openapi.yaml [get]
+func (h *DocsHandler) ServeOpenAPIYAML(c *fiber.Ctx) error {
+	data, err := yaml.Marshal(h.spec(c))
+	if err != nil {
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeDocsUnavailable, "cause", err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, "application/yaml; charset=utf-8")
+	return c.Send(data)
+}
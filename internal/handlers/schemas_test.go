@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"weather-api-go/internal/jsoncase"
+	"weather-api-go/internal/jsonschema"
+)
+
+func TestWeatherResponseMatchesServedSchema(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/schemas/WeatherResponse.json", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("schema status = %d; want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/schema+json") {
+		t.Errorf("schema Content-Type = %q; want application/schema+json", ct)
+	}
+
+	var schema map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&schema); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{"", "&include=advisories", "&include=advisories,detailed"} {
+		resp, err = app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060"+query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != 200 {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("weather status = %d: %s", resp.StatusCode, body)
+		}
+
+		var doc interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+			t.Fatal(err)
+		}
+		if err := jsonschema.Validate(schema, doc); err != nil {
+			t.Errorf("live /weather%s response does not match schema: %v", query, err)
+		}
+	}
+}
+
+func TestServeModelSchemaUnknownModel(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/schemas/Nope.json", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 404 {
+		t.Errorf("status = %d; want 404", resp.StatusCode)
+	}
+}
+
+func TestServeOpenAPIYAML(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/openapi.yaml", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/yaml") {
+		t.Errorf("Content-Type = %q; want application/yaml", ct)
+	}
+
+	var spec map[string]interface{}
+	if err := yaml.NewDecoder(resp.Body).Decode(&spec); err != nil {
+		t.Fatalf("response is not valid YAML: %v", err)
+	}
+	if spec["openapi"] != "3.0.0" {
+		t.Errorf("openapi = %v; want 3.0.0", spec["openapi"])
+	}
+	if _, ok := spec["paths"].(map[string]interface{})["/weather"]; !ok {
+		t.Error("YAML spec is missing /weather")
+	}
+}
+
+// fill builds a value of type t with every field, element, and pointer populated,
+// so that no property is dropped by omitempty or nil. Map keys contain an
+// underscore to show they are left alone.
+func fill(t reflect.Type) reflect.Value {
+	v := reflect.New(t).Elem()
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		v.Set(reflect.ValueOf(time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)))
+	case t == reflect.TypeOf(json.RawMessage{}):
+		v.SetBytes([]byte(`[[-74.02,40.7],[-73.97,40.71]]`))
+	case t.Kind() == reflect.Ptr:
+		v.Set(fill(t.Elem()).Addr())
+	case t.Kind() == reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				v.Field(i).Set(fill(t.Field(i).Type))
+			}
+		}
+	case t.Kind() == reflect.Slice:
+		v.Set(reflect.Append(reflect.MakeSlice(t, 0, 1), fill(t.Elem())))
+	case t.Kind() == reflect.Map:
+		v.Set(reflect.MakeMap(t))
+		v.SetMapIndex(reflect.ValueOf("some_key").Convert(t.Key()), fill(t.Elem()))
+	case t.Kind() == reflect.String:
+		v.SetString("some_text")
+	case t.Kind() == reflect.Bool:
+		v.SetBool(true)
+	case v.CanInt():
+		v.SetInt(7)
+	case v.CanUint():
+		v.SetUint(7)
+	case v.CanFloat():
+		v.SetFloat(1.5)
+	}
+	return v
+}
+
+// snakeKeys renames a decoded document's struct properties from camelCase back
+// to their json tags, guided by the Go type. Properties no field maps to are errors.
+func snakeKeys(t reflect.Type, doc interface{}) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == reflect.TypeOf(time.Time{}) || doc == nil:
+		return doc, nil
+	case t.Kind() == reflect.Struct:
+		names := map[string]reflect.StructField{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			names[jsoncase.Camel.Name(name)] = f
+		}
+		out := map[string]interface{}{}
+		for key, value := range doc.(map[string]interface{}) {
+			f, ok := names[key]
+			if !ok {
+				return nil, fmt.Errorf("%s: unexpected property %q", t.Name(), key)
+			}
+			renamed, err := snakeKeys(f.Type, value)
+			if err != nil {
+				return nil, err
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "" {
+				name = f.Name
+			}
+			out[name] = renamed
+		}
+		return out, nil
+	case t.Kind() == reflect.Map:
+		out := map[string]interface{}{}
+		for key, value := range doc.(map[string]interface{}) {
+			renamed, err := snakeKeys(t.Elem(), value)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = renamed
+		}
+		return out, nil
+	case t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8:
+		out := []interface{}{}
+		for _, value := range doc.([]interface{}) {
+			renamed, err := snakeKeys(t.Elem(), value)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, renamed)
+		}
+		return out, nil
+	}
+	return doc, nil
+}
+
+func TestResponseModelsRoundTripCamelCase(t *testing.T) {
+	for name, model := range schemaModels {
+		t.Run(name, func(t *testing.T) {
+			typ := reflect.TypeOf(model)
+			v := fill(typ).Interface()
+
+			snake, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			camel, err := jsoncase.Marshal(v, jsoncase.Camel, json.Marshal)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var camelDoc, snakeDoc interface{}
+			if err := json.Unmarshal(camel, &camelDoc); err != nil {
+				t.Fatal(err)
+			}
+			json.Unmarshal(snake, &snakeDoc)
+			restored, err := snakeKeys(typ, camelDoc)
+			if err != nil {
+				t.Fatalf("property missed the camelCase mapping: %v", err)
+			}
+			if !reflect.DeepEqual(restored, snakeDoc) {
+				t.Fatalf("camelCase output does not map back to snake_case\n got: %s\nwant: %s", camel, snake)
+			}
+
+			// The restored document decodes to the same value as the snake_case one
+			data, _ := json.Marshal(restored)
+			got, want := reflect.New(typ), reflect.New(typ)
+			if err := json.Unmarshal(data, got.Interface()); err != nil {
+				t.Fatal(err)
+			}
+			json.Unmarshal(snake, want.Interface())
+			if !reflect.DeepEqual(got.Interface(), want.Interface()) {
+				t.Errorf("round trip changed the value\n got: %+v\nwant: %+v", got.Elem(), want.Elem())
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/jsoncase"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/services"
+)
+
+// StatsHandler serves the daily request stats rolled up from the request log
+type StatsHandler struct {
+	service *services.StatsService
+}
+
+// NewStatsHandler creates a stats handler
+func NewStatsHandler(service *services.StatsService) *StatsHandler {
+	return &StatsHandler{service: service}
+}
+
+// GetDailyStats handles GET /stats/daily requests
+// @Summary Daily request stats
+// @Description Returns per-day request totals, route and error counts, cache hit ratio, distinct coordinates, and p50/p95 latency for a range of UTC days
+// @Tags health
+// @Produce json
+// @Param from query string false "First UTC day (YYYY-MM-DD), defaults to 30 days before to" example(2024-01-01)
+// @Param to query string false "Last UTC day (YYYY-MM-DD), defaults to today" example(2024-01-31)
+// @Success 200 {object} models.DailyStatsResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /stats/daily [get]
+func (h *StatsHandler) GetDailyStats(c *fiber.Ctx) error {
+	stats, err := h.service.GetDailyStats(c.UserContext(), c.Query("from"), c.Query("to"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDayRange) {
+			return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidDateRange,
+				"max", strconv.Itoa(services.MaxStatsRangeDays))
+		}
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeStatsUnavailable, "cause", err.Error())
+	}
+	return c.JSON(jsoncase.For(c, stats))
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+	"weather-api-go/internal/services"
+)
+
+func TestGetDailyStats(t *testing.T) {
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	repo := repository.NewWeatherRepository(db, nil)
+
+	err = repo.SaveRequestLog(context.Background(), []models.RequestLogEntry{
+		{Timestamp: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC), Route: "/api/weather", Status: 200, LatencyMs: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.RollupDailyStats(context.Background(), "2024-01-15"); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Get("/api/stats/daily", NewStatsHandler(services.NewStatsService(repo, 0)).GetDailyStats)
+
+	tests := []struct {
+		query    string
+		wantCode int
+		wantDays int
+	}{
+		{"?from=2024-01-01&to=2024-01-31", fiber.StatusOK, 1},
+		{"?from=2024-01-16&to=2024-01-31", fiber.StatusOK, 0},
+		{"?to=2024-01-20", fiber.StatusOK, 1},
+		{"?from=2024-1-1&to=2024-01-31", fiber.StatusBadRequest, 0},
+		{"?from=2024-02-01&to=2024-01-31", fiber.StatusBadRequest, 0},
+		{"?from=2023-01-01&to=2024-01-31", fiber.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/stats/daily"+tt.query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.wantCode {
+			t.Errorf("%s: status = %d; want %d", tt.query, resp.StatusCode, tt.wantCode)
+			continue
+		}
+		if tt.wantCode != fiber.StatusOK {
+			continue
+		}
+		var body models.DailyStatsResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.Days) != tt.wantDays {
+			t.Errorf("%s: got %d days; want %d", tt.query, len(body.Days), tt.wantDays)
+		}
+	}
+}
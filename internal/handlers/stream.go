@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/i18n"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/services"
+)
+
+// DefaultStreamInterval is how often a weather stream resends the weather
+// when it hasn't changed
+const DefaultStreamInterval = 5 * time.Minute
+
+// MaxStreamInterval is the longest interval a weather stream may request
+const MaxStreamInterval = time.Hour
+
+// streamKeepAlive is how long a weather stream may go quiet before a comment
+// is sent, so proxies keep the connection open and disconnects are noticed
+const streamKeepAlive = 15 * time.Second
+
+// streamRetry is how long EventSource clients wait before reconnecting
+const streamRetry = 5 * time.Second
+
+// GetWeatherStream handles GET /weather/stream requests
+// @Summary Stream weather updates
+// @Description Server-sent events with the coordinate's /weather response: a weather event at once, then another whenever its cache entry is refreshed or the interval passes without one. Lookups that fail mid-stream send an error event. Comments are sent every 15 seconds the stream is otherwise quiet.
+// @Tags weather
+// @Produce text/event-stream
+// @Param lat query number true "Latitude coordinate (-90 to 90)" example(40.7128)
+// @Param lon query number true "Longitude coordinate (-180 to 180)" example(-74.0060)
+// @Param interval query int false "Seconds between events when the weather hasn't changed (1-3600, default 300)" example(300)
+// @Success 200 {string} string "weather and error events"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 504 {object} models.ErrorResponse
+// @Router /weather/stream [get]
+func (h *WeatherHandler) GetWeatherStream(c *fiber.Ctx) error {
+	lat, lon, code := parseCoordinates(c)
+	if code != "" {
+		return sendError(c, fiber.StatusBadRequest, code)
+	}
+	interval := DefaultStreamInterval
+	if raw := c.Query("interval"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 1 || time.Duration(seconds)*time.Second > MaxStreamInterval {
+			return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidInterval,
+				"max", strconv.Itoa(int(MaxStreamInterval/time.Second)))
+		}
+		interval = time.Duration(seconds) * time.Second
+	}
+
+	// Subscribe before looking the weather up, so a refresh in between isn't missed
+	sub := h.service.SubscribeWeather(lat, lon)
+	ctx, cancel := h.requestContext(c)
+	weather, err := h.service.GetWeather(ctx, lat, lon)
+	cancel()
+	if err != nil {
+		sub.Close()
+		return sendWeatherError(c, err)
+	}
+
+	// The stream is written after the handler returns, once the request's
+	// context is gone; fasthttp's Done only closes on shutdown
+	encode, lang, shutdown := messageEncoder(c), i18n.Language(c), c.Context().Done()
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	// Keeps nginx from buffering events
+	c.Set("X-Accel-Buffering", "no")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+		events := &eventWriter{w: w, encode: encode}
+		last := fetchedAt(weather)
+		w.WriteString("retry: " + strconv.FormatInt(streamRetry.Milliseconds(), 10) + "\n")
+		if !events.send("weather", weather) {
+			return
+		}
+
+		resend := time.NewTicker(interval)
+		defer resend.Stop()
+		keepAlive := time.NewTicker(streamKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			// A failed write means the client has gone away
+			var ok bool
+			select {
+			case <-shutdown:
+				return
+			case entry := <-sub.Updates():
+				// The lookups below may have sent this refresh already
+				if !entry.Timestamp.After(last) {
+					continue
+				}
+				last = entry.Timestamp
+				ctx, cancel := context.WithTimeout(context.Background(), h.requestTimeout)
+				ok = events.send("weather", h.service.WeatherUpdate(ctx, entry, services.WeatherOptions{}))
+				cancel()
+				resend.Reset(interval)
+			case <-resend.C:
+				// Lookups don't hold a database connection between events
+				ctx, cancel := context.WithTimeout(context.Background(), h.requestTimeout)
+				weather, err := h.service.GetWeather(ctx, lat, lon)
+				cancel()
+				if err != nil {
+					code, params := batchErrorCode(err)
+					ok = events.send("error", i18n.ErrorIn(lang, code, params...))
+					break
+				}
+				if t := fetchedAt(weather); t.After(last) {
+					last = t
+				}
+				ok = events.send("weather", weather)
+			case <-keepAlive.C:
+				ok = events.comment("keep-alive")
+			}
+			if !ok {
+				return
+			}
+			keepAlive.Reset(streamKeepAlive)
+		}
+	})
+	return nil
+}
+
+// fetchedAt returns when a response's data was fetched, or the zero time when
+// it doesn't say
+func fetchedAt(weather *models.WeatherResponse) time.Time {
+	if weather.CachedAt == nil {
+		return time.Time{}
+	}
+	return *weather.CachedAt
+}
+
+// eventWriter writes server-sent events, numbering them from 1
+type eventWriter struct {
+	w      *bufio.Writer
+	encode func(v interface{}) ([]byte, error)
+	id     int
+}
+
+// send writes an event with v as its JSON data and flushes it, reporting
+// whether the write succeeded
+func (e *eventWriter) send(event string, v interface{}) bool {
+	data, err := e.encode(v)
+	if err != nil {
+		return false
+	}
+	e.id++
+	e.w.WriteString("id: " + strconv.Itoa(e.id) + "\nevent: " + event + "\ndata: ")
+	e.w.Write(data)
+	e.w.WriteString("\n\n")
+	return e.w.Flush() == nil
+}
+
+// comment writes and flushes a comment line, which clients ignore
+func (e *eventWriter) comment(text string) bool {
+	e.w.WriteString(": " + text + "\n\n")
+	return e.w.Flush() == nil
+}
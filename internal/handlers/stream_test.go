@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+// sseEvent is one server-sent event
+type sseEvent struct {
+	retry, id, event, data string
+}
+
+// openWeatherStream starts a weather stream, returning a reader over its events
+func openWeatherStream(t *testing.T, addr, query string) (*bufio.Reader, *http.Response) {
+	t.Helper()
+	resp, err := http.Get("http://" + addr + APIBasePath + "/weather/stream?" + query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d; want 200", resp.StatusCode)
+	}
+	return bufio.NewReader(resp.Body), resp
+}
+
+// readEvent reads the next event, skipping comments, failing the test if none
+// arrives in time
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	type result struct {
+		event sseEvent
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var e sseEvent
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				done <- result{err: err}
+				return
+			}
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" && e.event != "" {
+				done <- result{event: e}
+				return
+			}
+			field, value, _ := strings.Cut(line, ": ")
+			switch field {
+			case "retry":
+				e.retry = value
+			case "id":
+				e.id = value
+			case "event":
+				e.event = value
+			case "data":
+				e.data = value
+			}
+		}
+	}()
+	select {
+	case res := <-done:
+		if res.err != nil {
+			t.Fatalf("reading event: %v", res.err)
+		}
+		return res.event
+	case <-time.After(3 * time.Second):
+		t.Fatal("no event within 3s")
+	}
+	return sseEvent{}
+}
+
+// eventWeather decodes a weather event's data
+func eventWeather(t *testing.T, e sseEvent) models.WeatherResponse {
+	t.Helper()
+	if e.event != "weather" {
+		t.Fatalf("event = %+v; want a weather event", e)
+	}
+	var weather models.WeatherResponse
+	if err := json.Unmarshal([]byte(e.data), &weather); err != nil {
+		t.Fatalf("decoding %q: %v", e.data, err)
+	}
+	return weather
+}
+
+func TestGetWeatherStream(t *testing.T) {
+	addr, repo := listenTestApp(t)
+	events, resp := openWeatherStream(t, addr, "lat=40.7128&lon=-74.0060")
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q; want text/event-stream", ct)
+	}
+	if cc := resp.Header.Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("Cache-Control = %q; want no-cache", cc)
+	}
+
+	first := readEvent(t, events)
+	if first.retry != "5000" || first.id != "1" {
+		t.Errorf("first event retry/id = %q/%q; want 5000/1", first.retry, first.id)
+	}
+	if w := eventWeather(t, first); w.Forecast != "Partly Cloudy" {
+		t.Errorf("first forecast = %q; want the current Partly Cloudy", w.Forecast)
+	}
+
+	// A refresh of the coordinate's entry is sent without waiting for the interval
+	refreshed := time.Now().Add(time.Second)
+	err := repo.SaveToCache(context.Background(), &models.WeatherCache{
+		Latitude: 40.7128, Longitude: -74.006, Forecast: "Thunderstorms", TempC: 30, TempF: 86, Timestamp: refreshed,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := readEvent(t, events)
+	if second.id != "2" {
+		t.Errorf("second event id = %q; want 2", second.id)
+	}
+	if w := eventWeather(t, second); w.Forecast != "Thunderstorms" || w.CachedAt == nil || !w.CachedAt.Equal(refreshed) {
+		t.Errorf("second event = %+v; want the refresh at %v", w, refreshed)
+	}
+}
+
+func TestGetWeatherStreamInterval(t *testing.T) {
+	addr, repo := listenTestApp(t)
+	events, resp := openWeatherStream(t, addr, "lat=40.7128&lon=-74.0060&interval=1")
+
+	start := time.Now()
+	readEvent(t, events)
+	if w := eventWeather(t, readEvent(t, events)); w.Forecast != "Partly Cloudy" {
+		t.Errorf("resent forecast = %q; want Partly Cloudy", w.Forecast)
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("second event after %v; want it after the 1s interval", elapsed)
+	}
+
+	// The stream notices the disconnect at its next write and unsubscribes
+	resp.Body.Close()
+	waitForNoSubscribers(t, repo, 40.7128, -74.006)
+}
+
+// waitForNoSubscribers fails the test if a coordinate still has cache
+// subscribers a few seconds from now
+func waitForNoSubscribers(t *testing.T, repo *repository.WeatherRepository, lat, lon float64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for repo.CacheSubscribers(lat, lon) != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := repo.CacheSubscribers(lat, lon); n != 0 {
+		t.Errorf("subscribers to %v,%v = %d; want 0", lat, lon, n)
+	}
+}
+
+func TestGetWeatherStreamValidation(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"lon=-74.0060", models.ErrorCodeMissingLatitude},
+		{"lat=40.7128&lon=-74.0060&interval=0", models.ErrorCodeInvalidInterval},
+		{"lat=40.7128&lon=-74.0060&interval=soon", models.ErrorCodeInvalidInterval},
+		{"lat=40.7128&lon=-74.0060&interval=3601", models.ErrorCodeInvalidInterval},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/weather/stream?"+tt.query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if resp.StatusCode != http.StatusBadRequest || body.Code != tt.want {
+			t.Errorf("%s: %d %s; want 400 %s", tt.query, resp.StatusCode, body.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/jsoncase"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+	"weather-api-go/internal/services"
+)
+
+// SubscriptionHandler manages webhook subscriptions for severe-weather alerts
+type SubscriptionHandler struct {
+	webhooks *services.Webhooks
+	// owner identifies the caller a subscription belongs to
+	owner func(c *fiber.Ctx) string
+}
+
+// SubscriptionHandlerOption configures a SubscriptionHandler
+type SubscriptionHandlerOption func(*SubscriptionHandler)
+
+// WithSubscriptionOwner scopes subscriptions to the caller owner returns,
+// such as the ID of the API key the request authenticated with. Callers only
+// see and delete their own subscriptions. Without it every subscription
+// belongs to the same anonymous owner.
+func WithSubscriptionOwner(owner func(c *fiber.Ctx) string) SubscriptionHandlerOption {
+	return func(h *SubscriptionHandler) {
+		h.owner = owner
+	}
+}
+
+// NewSubscriptionHandler creates a subscription handler
+func NewSubscriptionHandler(webhooks *services.Webhooks, opts ...SubscriptionHandlerOption) *SubscriptionHandler {
+	h := &SubscriptionHandler{
+		webhooks: webhooks,
+		owner:    func(*fiber.Ctx) string { return "" },
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// CreateSubscription handles POST /subscriptions requests
+// @Summary Subscribe a webhook to alerts
+// @Description Registers a callback URL that is POSTed each new NWS alert at the coordinate at least as severe as min_severity (Minor, Moderate, Severe, or Extreme; default Severe). Each alert is delivered once per subscription, signed with an X-Webhook-Signature header of "sha256=" and the hex HMAC-SHA256 of the body keyed by the returned secret. Failed deliveries are retried with backoff before being marked dead. Callback hosts must resolve to public addresses. The subscription belongs to the caller's API key.
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param subscription body models.CreateSubscriptionRequest true "Callback URL, coordinate, and minimum severity"
+// @Success 201 {object} models.WebhookSubscription
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /subscriptions [post]
+func (h *SubscriptionHandler) CreateSubscription(c *fiber.Ctx) error {
+	var req models.CreateSubscriptionRequest
+	if err := c.App().Config().JSONDecoder(c.Body(), &req); err != nil || req.CallbackURL == "" {
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidWebhookRequest)
+	}
+	if code := checkBatchCoordinate(models.BatchCoordinate{Lat: req.Lat, Lon: req.Lon}); code != "" {
+		return sendError(c, fiber.StatusBadRequest, code)
+	}
+
+	sub, err := h.webhooks.Subscribe(c.UserContext(), h.owner(c), req.CallbackURL, *req.Lat, *req.Lon, req.MinSeverity)
+	switch {
+	case errors.Is(err, services.ErrInvalidCallbackURL):
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidCallbackURL)
+	case errors.Is(err, services.ErrCallbackNotAllowed):
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeCallbackNotAllowed)
+	case errors.Is(err, services.ErrInvalidSeverity):
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidSeverity)
+	case err != nil:
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeWebhooksUnavailable, "cause", err.Error())
+	}
+	return c.Status(fiber.StatusCreated).JSON(jsoncase.For(c, sub))
+}
+
+// ListSubscriptions handles GET /subscriptions requests
+// @Summary List webhook subscriptions
+// @Description Returns the caller's webhook subscriptions, oldest first, without their secrets
+// @Tags alerts
+// @Produce json
+// @Success 200 {object} models.WebhookSubscriptionsResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /subscriptions [get]
+func (h *SubscriptionHandler) ListSubscriptions(c *fiber.Ctx) error {
+	subs, err := h.webhooks.ListSubscriptions(c.UserContext(), h.owner(c))
+	if err != nil {
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeWebhooksUnavailable, "cause", err.Error())
+	}
+	return c.JSON(jsoncase.For(c, subs))
+}
+
+// DeleteSubscription handles DELETE /subscriptions/:id requests
+// @Summary Delete a webhook subscription
+// @Description Stops deliveries to the subscription and deletes its delivery log
+// @Tags alerts
+// @Param id path string true "Subscription ID"
+// @Success 204
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /subscriptions/{id} [delete]
+func (h *SubscriptionHandler) DeleteSubscription(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.webhooks.Unsubscribe(c.UserContext(), h.owner(c), id); err != nil {
+		return sendSubscriptionError(c, id, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetDeliveries handles GET /subscriptions/:id/deliveries requests
+// @Summary List a webhook subscription's deliveries
+// @Description Returns the subscription's 100 most recent deliveries, newest first. Pending deliveries are waiting for an attempt, and dead ones failed every attempt.
+// @Tags alerts
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} models.WebhookDeliveriesResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /subscriptions/{id}/deliveries [get]
+func (h *SubscriptionHandler) GetDeliveries(c *fiber.Ctx) error {
+	id := c.Params("id")
+	deliveries, err := h.webhooks.Deliveries(c.UserContext(), h.owner(c), id)
+	if err != nil {
+		return sendSubscriptionError(c, id, err)
+	}
+	return c.JSON(jsoncase.For(c, deliveries))
+}
+
+// sendSubscriptionError responds to a failed lookup of a subscription
+func sendSubscriptionError(c *fiber.Ctx, id string, err error) error {
+	if errors.Is(err, repository.ErrSubscriptionNotFound) {
+		return sendError(c, fiber.StatusNotFound, models.ErrorCodeSubscriptionNotFound, "id", id)
+	}
+	return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeWebhooksUnavailable, "cause", err.Error())
+}
+
+// ListUnownedSubscriptions handles GET /admin/subscriptions/unowned requests
+// @Summary List unowned webhook subscriptions
+// @Description Returns the webhook subscriptions created while API keys weren't required, oldest first, without their secrets. They still receive alerts but belong to no key, so only the admin can manage them. Requires the admin token.
+// @Tags debug
+// @Produce json
+// @Success 200 {object} models.WebhookSubscriptionsResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/subscriptions/unowned [get]
+func (h *SubscriptionHandler) ListUnownedSubscriptions(c *fiber.Ctx) error {
+	subs, err := h.webhooks.ListSubscriptions(c.UserContext(), "")
+	if err != nil {
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeWebhooksUnavailable, "cause", err.Error())
+	}
+	return c.JSON(jsoncase.For(c, subs))
+}
+
+// DeleteUnownedSubscription handles DELETE /admin/subscriptions/unowned/:id requests
+// @Summary Delete an unowned webhook subscription
+// @Description Stops deliveries to a subscription created while API keys weren't required and deletes its delivery log. Requires the admin token.
+// @Tags debug
+// @Param id path string true "Subscription ID"
+// @Success 204
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/subscriptions/unowned/{id} [delete]
+func (h *SubscriptionHandler) DeleteUnownedSubscription(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.webhooks.Unsubscribe(c.UserContext(), "", id); err != nil {
+		return sendSubscriptionError(c, id, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetUnownedDeliveries handles GET /admin/subscriptions/unowned/:id/deliveries requests
+// @Summary List an unowned webhook subscription's deliveries
+// @Description Returns the 100 most recent deliveries, newest first, of a subscription created while API keys weren't required. Requires the admin token.
+// @Tags debug
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} models.WebhookDeliveriesResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/subscriptions/unowned/{id}/deliveries [get]
+func (h *SubscriptionHandler) GetUnownedDeliveries(c *fiber.Ctx) error {
+	id := c.Params("id")
+	deliveries, err := h.webhooks.Deliveries(c.UserContext(), "", id)
+	if err != nil {
+		return sendSubscriptionError(c, id, err)
+	}
+	return c.JSON(jsoncase.For(c, deliveries))
+}
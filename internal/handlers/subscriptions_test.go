@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+	"weather-api-go/internal/services"
+)
+
+// callbackResolver resolves example.com publicly and intranet.example.com
+// privately, without DNS
+type callbackResolver struct{}
+
+func (callbackResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	switch host {
+	case "example.com":
+		return []netip.Addr{netip.MustParseAddr("93.184.215.14")}, nil
+	case "intranet.example.com":
+		return []netip.Addr{netip.MustParseAddr("10.0.0.5")}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// subscriptionOwnerHeader names the caller in tests, in place of an API key
+const subscriptionOwnerHeader = "X-Test-Owner"
+
+func newSubscriptionApp(t *testing.T) *fiber.App {
+	t.Helper()
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	repo := repository.NewWeatherRepository(db, nil)
+	webhooks := services.NewWebhooks(repo, services.NewWeatherService(repo, nil), services.WebhookConfig{Resolver: callbackResolver{}})
+	handler := NewSubscriptionHandler(webhooks, WithSubscriptionOwner(func(c *fiber.Ctx) string {
+		return c.Get(subscriptionOwnerHeader)
+	}))
+
+	app := fiber.New()
+	app.Post("/api/subscriptions", handler.CreateSubscription)
+	app.Get("/api/subscriptions", handler.ListSubscriptions)
+	app.Delete("/api/subscriptions/:id", handler.DeleteSubscription)
+	app.Get("/api/subscriptions/:id/deliveries", handler.GetDeliveries)
+	app.Get("/api/admin/subscriptions/unowned", handler.ListUnownedSubscriptions)
+	app.Delete("/api/admin/subscriptions/unowned/:id", handler.DeleteUnownedSubscription)
+	app.Get("/api/admin/subscriptions/unowned/:id/deliveries", handler.GetUnownedDeliveries)
+	return app
+}
+
+func TestSubscriptionLifecycle(t *testing.T) {
+	app := newSubscriptionApp(t)
+
+	req := httptest.NewRequest("POST", "/api/subscriptions",
+		strings.NewReader(`{"callback_url": "https://example.com/hook", "lat": 40.7128, "lon": -74.006, "min_severity": "extreme"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("create status = %d; want 201", resp.StatusCode)
+	}
+	var created models.WebhookSubscription
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == "" || created.Secret == "" || created.MinSeverity != "Extreme" {
+		t.Errorf("created = %+v; want an ID, a secret, and severity Extreme", created)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/subscriptions", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list models.WebhookSubscriptionsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Subscriptions) != 1 || list.Subscriptions[0].ID != created.ID || list.Subscriptions[0].Secret != "" {
+		t.Errorf("list = %+v; want the subscription without its secret", list)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/subscriptions/"+created.ID+"/deliveries", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deliveries models.WebhookDeliveriesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&deliveries); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK || deliveries.Deliveries == nil || len(deliveries.Deliveries) != 0 {
+		t.Errorf("deliveries = %d %+v; want 200 with an empty list", resp.StatusCode, deliveries)
+	}
+
+	for i, want := range []int{fiber.StatusNoContent, fiber.StatusNotFound} {
+		resp, err := app.Test(httptest.NewRequest("DELETE", "/api/subscriptions/"+created.ID, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != want {
+			t.Errorf("delete %d: status = %d; want %d", i+1, resp.StatusCode, want)
+		}
+	}
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/subscriptions/"+created.ID+"/deliveries", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("deliveries after delete status = %d; want 404", resp.StatusCode)
+	}
+}
+
+func TestSubscriptionsScopedToOwner(t *testing.T) {
+	app := newSubscriptionApp(t)
+	request := func(method, target, owner, body string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(subscriptionOwnerHeader, owner)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := request("POST", "/api/subscriptions", "mobile-app", `{"callback_url": "https://example.com/hook", "lat": 40.7128, "lon": -74.006}`)
+	var created models.WebhookSubscription
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil || resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("create = %d, %v; want 201", resp.StatusCode, err)
+	}
+
+	for _, tt := range []struct {
+		owner string
+		count int
+		found bool
+	}{
+		{"mobile-app", 1, true},
+		{"dashboard", 0, false},
+		{"", 0, false},
+	} {
+		var list models.WebhookSubscriptionsResponse
+		if err := json.NewDecoder(request("GET", "/api/subscriptions", tt.owner, "").Body).Decode(&list); err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Subscriptions) != tt.count {
+			t.Errorf("%q listed %d subscriptions; want %d", tt.owner, len(list.Subscriptions), tt.count)
+		}
+		want := fiber.StatusNotFound
+		if tt.found {
+			want = fiber.StatusOK
+		}
+		if resp := request("GET", "/api/subscriptions/"+created.ID+"/deliveries", tt.owner, ""); resp.StatusCode != want {
+			t.Errorf("%q deliveries status = %d; want %d", tt.owner, resp.StatusCode, want)
+		}
+	}
+
+	if resp := request("DELETE", "/api/subscriptions/"+created.ID, "dashboard", ""); resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("delete by another owner status = %d; want 404", resp.StatusCode)
+	}
+	if resp := request("DELETE", "/api/subscriptions/"+created.ID, "mobile-app", ""); resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("delete by its owner status = %d; want 204", resp.StatusCode)
+	}
+}
+
+func TestAdminManagesUnownedSubscriptions(t *testing.T) {
+	app := newSubscriptionApp(t)
+	create := func(owner string) models.WebhookSubscription {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/subscriptions", strings.NewReader(`{"callback_url": "https://example.com/hook", "lat": 40.7128, "lon": -74.006}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(subscriptionOwnerHeader, owner)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sub models.WebhookSubscription
+		if err := json.NewDecoder(resp.Body).Decode(&sub); err != nil || resp.StatusCode != fiber.StatusCreated {
+			t.Fatalf("create = %d, %v; want 201", resp.StatusCode, err)
+		}
+		return sub
+	}
+	// Made before API keys were required, so it belongs to no key
+	unowned := create("")
+	owned := create("mobile-app")
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/admin/subscriptions/unowned", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list models.WebhookSubscriptionsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Subscriptions) != 1 || list.Subscriptions[0].ID != unowned.ID {
+		t.Errorf("unowned = %+v; want only %s", list.Subscriptions, unowned.ID)
+	}
+
+	for _, tt := range []struct {
+		method, target string
+		want           int
+	}{
+		{"GET", "/api/admin/subscriptions/unowned/" + unowned.ID + "/deliveries", fiber.StatusOK},
+		{"GET", "/api/admin/subscriptions/unowned/" + owned.ID + "/deliveries", fiber.StatusNotFound},
+		{"DELETE", "/api/admin/subscriptions/unowned/" + owned.ID, fiber.StatusNotFound},
+		{"DELETE", "/api/admin/subscriptions/unowned/" + unowned.ID, fiber.StatusNoContent},
+		{"DELETE", "/api/admin/subscriptions/unowned/" + unowned.ID, fiber.StatusNotFound},
+	} {
+		resp, err := app.Test(httptest.NewRequest(tt.method, tt.target, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s status = %d; want %d", tt.method, tt.target, resp.StatusCode, tt.want)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/subscriptions", nil)
+	req.Header.Set(subscriptionOwnerHeader, "mobile-app")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list = models.WebhookSubscriptionsResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Subscriptions) != 1 || list.Subscriptions[0].ID != owned.ID {
+		t.Errorf("owner's subscriptions = %+v; want %s untouched", list.Subscriptions, owned.ID)
+	}
+}
+
+func TestCreateSubscriptionValidation(t *testing.T) {
+	app := newSubscriptionApp(t)
+
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`not json`, models.ErrorCodeInvalidWebhookRequest},
+		{`{"lat": 40.7128, "lon": -74.006}`, models.ErrorCodeInvalidWebhookRequest},
+		{`{"callback_url": "https://example.com/hook", "lon": -74.006}`, models.ErrorCodeMissingLatitude},
+		{`{"callback_url": "https://example.com/hook", "lat": 95, "lon": -74.006}`, models.ErrorCodeCoordinatesOutOfRange},
+		{`{"callback_url": "mailto:ops@example.com", "lat": 40.7128, "lon": -74.006}`, models.ErrorCodeInvalidCallbackURL},
+		{`{"callback_url": "http://169.254.169.254/latest/meta-data/", "lat": 40.7128, "lon": -74.006}`, models.ErrorCodeCallbackNotAllowed},
+		{`{"callback_url": "https://intranet.example.com/hook", "lat": 40.7128, "lon": -74.006}`, models.ErrorCodeCallbackNotAllowed},
+		{`{"callback_url": "https://example.com/hook", "lat": 40.7128, "lon": -74.006, "min_severity": "Bad"}`, models.ErrorCodeInvalidSeverity},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/subscriptions", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: %v", tt.body, err)
+		}
+		if resp.StatusCode != fiber.StatusBadRequest || body.Code != tt.want {
+			t.Errorf("%s: %d %s; want 400 %s", tt.body, resp.StatusCode, body.Code, tt.want)
+		}
+	}
+}
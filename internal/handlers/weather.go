@@ -1,22 +1,131 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/config"
+	"weather-api-go/internal/i18n"
+	"weather-api-go/internal/jsoncase"
+	"weather-api-go/internal/metrics"
 	"weather-api-go/internal/models"
+	"weather-api-go/internal/negotiate"
+	"weather-api-go/internal/repository"
 	"weather-api-go/internal/services"
+	"weather-api-go/internal/units"
 )
 
+// DegradedDatabaseUsage is the share of the database size cap above which health reports degraded
+const DegradedDatabaseUsage = 0.9
+
+// DefaultMaxBatchSize is the most coordinates a batch weather request may carry
+// when no limit is configured
+const DefaultMaxBatchSize = 100
+
+// DefaultRequestTimeout bounds the work behind a request, including its
+// upstream fetches, when no timeout is configured
+const DefaultRequestTimeout = 8 * time.Second
+
+// MaxLocationQueryLength caps the length of a ?city= or ?q= place lookup
+const MaxLocationQueryLength = 200
+
 // WeatherHandler handles weather-related HTTP requests
 type WeatherHandler struct {
-	service *services.WeatherService
+	service        *services.WeatherService
+	databaseUsage  func(context.Context) (models.DatabaseUsage, error)
+	maxBatchSize   int
+	requestTimeout time.Duration
+	// maxQueryDepth and maxQueryComplexity bound GraphQL queries
+	maxQueryDepth      int
+	maxQueryComplexity int
+	// proxy decides whether the client address is taken from X-Forwarded-For
+	proxy config.Proxy
+}
+
+// WeatherHandlerOption configures optional weather handler behavior
+type WeatherHandlerOption func(*WeatherHandler)
+
+// WithDatabaseUsage reports the cache database size in health checks, which
+// are degraded once it nears its cap
+func WithDatabaseUsage(usage func(context.Context) (models.DatabaseUsage, error)) WeatherHandlerOption {
+	return func(h *WeatherHandler) {
+		h.databaseUsage = usage
+	}
+}
+
+// WithProxy sets how far X-Forwarded-For is trusted when locating callers by
+// IP address; by default the header is ignored
+func WithProxy(p config.Proxy) WeatherHandlerOption {
+	return func(h *WeatherHandler) {
+		h.proxy = p
+	}
+}
+
+// WithMaxBatchSize caps how many coordinates a batch weather request may carry
+func WithMaxBatchSize(n int) WeatherHandlerOption {
+	return func(h *WeatherHandler) {
+		if n > 0 {
+			h.maxBatchSize = n
+		}
+	}
+}
+
+// WithRequestTimeout bounds the work behind each request. A request whose
+// upstream fetch outlasts it is answered from stale cache when there is any.
+func WithRequestTimeout(d time.Duration) WeatherHandlerOption {
+	return func(h *WeatherHandler) {
+		if d > 0 {
+			h.requestTimeout = d
+		}
+	}
+}
+
+// WithQueryLimits caps how deeply GraphQL queries may nest fields and how
+// much they may cost; zero keeps a default
+func WithQueryLimits(depth, complexity int) WeatherHandlerOption {
+	return func(h *WeatherHandler) {
+		if depth > 0 {
+			h.maxQueryDepth = depth
+		}
+		if complexity > 0 {
+			h.maxQueryComplexity = complexity
+		}
+	}
 }
 
 // NewWeatherHandler creates a new weather handler
-func NewWeatherHandler(service *services.WeatherService) *WeatherHandler {
-	return &WeatherHandler{service: service}
+func NewWeatherHandler(service *services.WeatherService, opts ...WeatherHandlerOption) *WeatherHandler {
+	h := &WeatherHandler{
+		service:            service,
+		maxBatchSize:       DefaultMaxBatchSize,
+		requestTimeout:     DefaultRequestTimeout,
+		maxQueryDepth:      DefaultMaxQueryDepth,
+		maxQueryComplexity: DefaultMaxQueryComplexity,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// requestContext returns the context a request's service calls run under: the
+// request's own, so their cache and NWS spans join its trace, ending at the
+// request timeout. The returned cancel must be called once the request is
+// answered.
+func (h *WeatherHandler) requestContext(c *fiber.Ctx) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(c.UserContext(), h.requestTimeout)
 }
 
 // GetWeather handles GET /weather requests
@@ -24,75 +133,992 @@ func NewWeatherHandler(service *services.WeatherService) *WeatherHandler {
 // @Description Returns the short forecast and temperature characterization for the specified latitude and longitude
 // @Tags weather
 // @Accept json
-// @Produce json
-// @Param lat query number true "Latitude coordinate (-90 to 90)" example(40.7128)
-// @Param lon query number true "Longitude coordinate (-180 to 180)" example(-74.0060)
+// @Produce json,xml
+// @Param lat query number false "Latitude coordinate (-90 to 90); required unless zip, city, or q is given, or the caller is located by IP" example(40.7128)
+// @Param lon query number false "Longitude coordinate (-180 to 180); required unless zip, city, or q is given, or the caller is located by IP" example(-74.0060)
+// @Param zip query string false "US ZIP or ZIP+4 code to look up instead of coordinates; can't be combined with other location parameters" example(10001)
+// @Param city query string false "City to look up instead of coordinates, with an optional state (City,ST)" example(Portland,OR)
+// @Param q query string false "Free-text place to look up instead of coordinates" example(Mount Rainier)
+// @Param include query string false "Comma-separated optional sections (advisories, detailed, uv)" example(advisories)
+// @Param units query string false "Unit system for values: metric, imperial, or both (default)" Enums(metric, imperial, both)
+// @Param icon_size query string false "Size of the NWS icon URL; the NWS's size when omitted" Enums(small, medium, large)
+// @Param wind_unit query string false "Unit to give a single wind_speed in, replacing wind_speed_kmh and wind_speed_mph" Enums(mph, kmh, ms, kn)
+// @Param tz query string false "IANA time zone to give local times in; the location's own when omitted" example(America/Chicago)
+// @Param points query string false "Semicolon-separated lat,lon pairs to look up at once instead of one location, answered like /weather/batch (at most 10)" example(40.71,-74.00;34.05,-118.24)
+// @Param at query string false "Future time to forecast for: RFC 3339, or local YYYY-MM-DDTHH:MM[:SS] in the location's time zone" example(2024-06-01T18:00:00Z)
+// @Param If-None-Match header string false "ETag from an earlier response; 304 is returned while it still matches"
+// @Param format query string false "Response format; overrides the Accept header" Enums(json, xml)
 // @Success 200 {object} models.WeatherResponse
+// @Success 304 "The forecast is unchanged since the If-None-Match ETag"
+// @Success 300 {object} models.AmbiguousLocationResponse
+// @Success 200 {object} models.BatchWeatherResponse "With ?points="
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Failure 504 {object} models.ErrorResponse
 // @Router /weather [get]
 func (h *WeatherHandler) GetWeather(c *fiber.Ctx) error {
-	latStr := c.Query("lat")
-	if latStr == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error:   "Missing latitude parameter",
-			Details: "Latitude is required (e.g., lat=40.7128)",
-		})
+	if c.Context().QueryArgs().Has("points") {
+		return h.getWeatherPoints(c)
 	}
 
-	lat, err := strconv.ParseFloat(latStr, 64)
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	var place *models.Place
+	var located *models.IPLocation
+	var lat, lon float64
+	if zip := strings.TrimSpace(c.Query("zip")); zip != "" {
+		if hasLocationQuery(c) {
+			return sendError(c, fiber.StatusBadRequest, models.ErrorCodeConflictingLocation)
+		}
+		var err error
+		if place, err = h.service.ResolveZIP(ctx, zip); err != nil {
+			return sendZIPError(c, zip, err)
+		}
+		lat, lon = place.Latitude, place.Longitude
+		metrics.MarkCoordinates(c, models.Coordinates{Latitude: lat, Longitude: lon})
+	} else if query, ok := placeQuery(c); ok {
+		if len(query.String()) > MaxLocationQueryLength {
+			return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidLocation,
+				"max", strconv.Itoa(MaxLocationQueryLength))
+		}
+		var err error
+		if place, err = h.service.ResolvePlace(ctx, query); err != nil {
+			return sendPlaceError(c, query, err)
+		}
+		lat, lon = place.Latitude, place.Longitude
+		metrics.MarkCoordinates(c, models.Coordinates{Latitude: lat, Longitude: lon})
+	} else if !hasLocationQuery(c) {
+		var err error
+		if located, err = h.service.LocateIP(h.clientIP(c)); err != nil {
+			return sendLocateError(c, err)
+		}
+		lat, lon = located.Latitude, located.Longitude
+		metrics.MarkCoordinates(c, models.Coordinates{Latitude: lat, Longitude: lon})
+	} else {
+		var code string
+		if lat, lon, code = parseCoordinates(c); code != "" {
+			return sendError(c, fiber.StatusBadRequest, code)
+		}
+	}
+	return h.sendWeather(ctx, c, lat, lon, place, located)
+}
+
+// PostWeather handles POST /weather requests, for clients that can't send
+// long query strings
+// @Summary Get weather forecast for posted coordinates
+// @Description Same as GET /weather?lat=&lon=, with the coordinates in a JSON body. Unknown fields are ignored, and the other parameters stay in the query.
+// @Tags weather
+// @Accept json
+// @Produce json,xml
+// @Param coordinates body models.Coordinates true "Coordinates to forecast for"
+// @Param units query string false "Unit system for values: metric, imperial, or both (default)" Enums(metric, imperial, both)
+// @Success 200 {object} models.WeatherResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Failure 504 {object} models.ErrorResponse
+// @Router /weather [post]
+func (h *WeatherHandler) PostWeather(c *fiber.Ctx) error {
+	if !c.Is("json") {
+		return sendError(c, fiber.StatusUnsupportedMediaType, models.ErrorCodeUnsupportedMediaType)
+	}
+	// Coordinates left out of the body keep their NaN, which no JSON number decodes to
+	coords := models.Coordinates{Latitude: math.NaN(), Longitude: math.NaN()}
+	if err := c.App().Config().JSONDecoder(c.Body(), &coords); err != nil {
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidCoordinatesBody, "cause", err.Error())
+	}
+	lat, lon, code := checkCoordinates(bodyCoordinate(coords.Latitude), bodyCoordinate(coords.Longitude))
+	if code != "" {
+		return sendError(c, fiber.StatusBadRequest, code)
+	}
+	metrics.MarkCoordinates(c, models.Coordinates{Latitude: lat, Longitude: lon})
+
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+	return h.sendWeather(ctx, c, lat, lon, nil, nil)
+}
+
+// bodyCoordinate formats a posted latitude or longitude for checkCoordinates,
+// so the body is held to the same checks as the query; NaN, for a coordinate
+// left out, is reported missing
+func bodyCoordinate(v float64) string {
+	if math.IsNaN(v) {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// sendWeather answers a weather request for resolved coordinates, reading the
+// response options from the query. place and located are set when the
+// coordinates were looked up from a place name or the caller's address.
+func (h *WeatherHandler) sendWeather(ctx context.Context, c *fiber.Ctx, lat, lon float64, place *models.Place, located *models.IPLocation) error {
+	system, err := units.ParseSystem(c.Query("units"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error:   "Invalid latitude parameter",
-			Details: "Latitude must be a valid float number",
-		})
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidUnits)
+	}
+	iconSize, err := services.ParseIconSize(c.Query("icon_size"))
+	if err != nil {
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidIconSize)
+	}
+	windUnit, ok := parseWindUnit(c)
+	if !ok {
+		return sendInvalidWindUnit(c)
+	}
+	tz, err := services.ParseTimeZone(c.Query("tz"))
+	if err != nil {
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidTimeZone)
+	}
+	opts := services.WeatherOptions{
+		IncludeAdvisories: hasInclude(c, "advisories"),
+		IncludeDetailed:   hasInclude(c, "detailed"),
+		IncludeUV:         hasInclude(c, "uv"),
+		Units:             system,
+		IconSize:          iconSize,
+		WindUnit:          windUnit,
+		TimeZone:          tz,
+	}
+	if atStr := c.Query("at"); atStr != "" {
+		at, err := services.ParseForecastTime(atStr)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidForecastTime)
+		}
+		opts.At = &at
 	}
 
-	lonStr := c.Query("lon")
-	if lonStr == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error:   "Missing longitude parameter",
-			Details: "Longitude is required (e.g., lon=-74.0060)",
+	coords := repository.NewCoordinates(lat, lon)
+	weather, err := h.service.GetWeatherFor(ctx, coords, opts)
+	if err != nil {
+		return sendWeatherError(c, err)
+	}
+	weather.Place = place
+	weather.ApproximateLocation = located
+
+	metrics.MarkCacheHit(c, weather.CacheHit)
+	c.Set(CacheStatusHeader, cacheStatus(weather.Source))
+	setCacheControl(c, weather.FreshUntil)
+	if located != nil {
+		// The response depends on the caller's address, so shared caches must not reuse it
+		c.Set(fiber.HeaderCacheControl, strings.Replace(c.GetRespHeader(fiber.HeaderCacheControl), "public", "private", 1))
+	}
+	if weather.CachedAt != nil {
+		etag := weatherETag(c, coords, weather, opts)
+		c.Set(fiber.HeaderETag, etag)
+		c.Set(fiber.HeaderLastModified, weather.CachedAt.UTC().Format(http.TimeFormat))
+		if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+			c.Status(fiber.StatusNotModified)
+			return nil
+		}
+	}
+	return negotiate.Send(c, weather)
+}
+
+// sendWeatherError answers a request whose weather lookup failed
+func sendWeatherError(c *fiber.Ctx, err error) error {
+	var shed *services.ShedError
+	if errors.As(err, &shed) {
+		return sendShed(c, shed)
+	}
+	if errors.Is(err, services.ErrForecastTimeInPast) {
+		return sendError(c, fiber.StatusUnprocessableEntity, models.ErrorCodeForecastTimeInPast)
+	}
+	if errors.Is(err, services.ErrBeyondForecastHorizon) {
+		return sendError(c, fiber.StatusUnprocessableEntity, models.ErrorCodeBeyondForecastHorizon)
+	}
+	if errors.Is(err, services.ErrNoHourlyForecast) {
+		return sendError(c, fiber.StatusNotFound, models.ErrorCodeNoHourlyForecast)
+	}
+	if errors.Is(err, services.ErrOutsideCoverage) {
+		return sendError(c, fiber.StatusNotFound, models.ErrorCodeOutOfCoverage)
+	}
+	if errors.Is(err, services.ErrQuotaExceeded) {
+		return sendError(c, fiber.StatusServiceUnavailable, models.ErrorCodeQuotaExceeded)
+	}
+	if status, code, ok := failureCode(err); ok {
+		return sendError(c, status, code, "cause", err.Error())
+	}
+	return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeWeatherUnavailable, "cause", err.Error())
+}
+
+// failureCode returns the status and error code for a weather fetch that
+// failed because of the provider, or because the cache couldn't stand in for
+// it; ok is false for a failure of this service's own
+func failureCode(err error) (status int, code string, ok bool) {
+	switch {
+	case errors.Is(err, services.ErrUpstreamTimeout):
+		return fiber.StatusGatewayTimeout, models.ErrorCodeUpstreamTimeout, true
+	case errors.Is(err, services.ErrUpstreamUnavailable):
+		return fiber.StatusBadGateway, models.ErrorCodeUpstreamUnavailable, true
+	case errors.Is(err, services.ErrUpstreamBadResponse):
+		return fiber.StatusBadGateway, models.ErrorCodeUpstreamBadResponse, true
+	case errors.Is(err, services.ErrCacheUnavailable):
+		return fiber.StatusInternalServerError, models.ErrorCodeCacheUnavailable, true
+	}
+	return 0, "", false
+}
+
+// placeQuery reads a ?city= or ?q= place lookup. Coordinates take precedence,
+// so it reports false whenever lat or lon is given.
+func placeQuery(c *fiber.Ctx) (services.PlaceQuery, bool) {
+	if lat, lon := coordinateParams(c); lat != "" || lon != "" {
+		return services.PlaceQuery{}, false
+	}
+	if city := strings.TrimSpace(c.Query("city")); city != "" {
+		return services.ParseCityQuery(city), true
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		return services.PlaceQuery{Text: q}, true
+	}
+	return services.PlaceQuery{}, false
+}
+
+// hasLocationQuery reports whether the request names a location by
+// coordinates or place name
+func hasLocationQuery(c *fiber.Ctx) bool {
+	if lat, lon := coordinateParams(c); lat != "" || lon != "" {
+		return true
+	}
+	for _, param := range []string{"city", "q"} {
+		if c.Query(param) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the caller, behind any trusted proxies
+func (h *WeatherHandler) clientIP(c *fiber.Ctx) netip.Addr {
+	peer, _ := netip.AddrFromSlice(c.Context().RemoteIP())
+	return h.proxy.ClientIP(peer, c.GetReqHeaders()[fiber.HeaderXForwardedFor])
+}
+
+// sendLocateError answers a request naming no location whose caller couldn't
+// be located by IP. Without an IP locator, the coordinates are reported missing
+// as before.
+func sendLocateError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrIPLocationDisabled) {
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeMissingLatitude)
+	}
+	return sendError(c, fiber.StatusBadRequest, models.ErrorCodeIPNotLocated)
+}
+
+// sendZIPError reports a failed ZIP code lookup
+func sendZIPError(c *fiber.Ctx, zip string, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidZIP):
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidZIP)
+	case errors.Is(err, services.ErrZIPNotFound):
+		return sendError(c, fiber.StatusNotFound, models.ErrorCodeZIPNotFound, "zip", zip)
+	}
+	return sendPlaceError(c, services.PlaceQuery{Text: zip}, err)
+}
+
+// sendPlaceError reports a failed place lookup. Several matches are answered
+// with 300 Multiple Choices listing the candidates.
+func sendPlaceError(c *fiber.Ctx, query services.PlaceQuery, err error) error {
+	var ambiguous *services.AmbiguousPlaceError
+	switch {
+	case errors.As(err, &ambiguous):
+		e := i18n.Error(c, models.ErrorCodeAmbiguousLocation, "query", query.String())
+		return negotiate.Send(c.Status(fiber.StatusMultipleChoices), models.AmbiguousLocationResponse{
+			Error:      e.Error,
+			Details:    e.Details,
+			Code:       e.Code,
+			Candidates: ambiguous.Candidates,
 		})
+	case errors.Is(err, services.ErrPlaceNotFound):
+		return sendError(c, fiber.StatusNotFound, models.ErrorCodeLocationNotFound, "query", query.String())
+	case errors.Is(err, services.ErrGeocodingDisabled):
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeGeocodingDisabled)
 	}
+	return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeGeocodingUnavailable, "cause", err.Error())
+}
 
-	lon, err := strconv.ParseFloat(lonStr, 64)
+// GetWeatherBatch handles POST /weather/batch requests
+// @Summary Get weather for many coordinates
+// @Description Looks up the /weather forecast for each coordinate in a JSON array, concurrently, and returns one result per coordinate in request order. A coordinate that fails carries its own error instead of failing the request; repeated coordinates are looked up once.
+// @Tags weather
+// @Accept json
+// @Produce json
+// @Param coordinates body []models.BatchCoordinate true "Coordinates to look up"
+// @Success 200 {object} models.BatchWeatherResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /weather/batch [post]
+func (h *WeatherHandler) GetWeatherBatch(c *fiber.Ctx) error {
+	var items []models.BatchCoordinate
+	if err := c.App().Config().JSONDecoder(c.Body(), &items); err != nil || len(items) == 0 {
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidBatch)
+	}
+	if len(items) > h.maxBatchSize {
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeBatchTooLarge, "max", strconv.Itoa(h.maxBatchSize))
+	}
+
+	// Only coordinates that pass validation are looked up; valid[i] is the
+	// request index of coords[i]
+	resp := models.BatchWeatherResponse{Results: make([]models.BatchWeatherResult, len(items))}
+	coords := make([]models.Coordinates, 0, len(items))
+	valid := make([]int, 0, len(items))
+	for i, item := range items {
+		resp.Results[i] = models.BatchWeatherResult{Lat: item.Lat, Lon: item.Lon}
+		if code := checkBatchCoordinate(item); code != "" {
+			resp.Results[i].Error = batchError(c, code)
+			continue
+		}
+		coords = append(coords, models.Coordinates{Latitude: *item.Lat, Longitude: *item.Lon})
+		valid = append(valid, i)
+	}
+
+	h.lookupBatch(c, resp.Results, coords, valid)
+	return c.JSON(jsoncase.For(c, resp))
+}
+
+// lookupBatch looks up the weather for coords concurrently, filling in the
+// result at request index valid[j] for coords[j]
+func (h *WeatherHandler) lookupBatch(c *fiber.Ctx, results []models.BatchWeatherResult, coords []models.Coordinates, valid []int) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+	for j, result := range h.service.GetWeatherBatch(ctx, coords) {
+		i := valid[j]
+		if result.Err != nil {
+			code, params := batchErrorCode(result.Err)
+			results[i].Error = batchError(c, code, params...)
+			continue
+		}
+		results[i].Weather = result.Weather
+	}
+}
+
+// checkBatchCoordinate returns the error code for a missing or out-of-range
+// batch coordinate, matching the checks checkCoordinates applies to requests
+func checkBatchCoordinate(item models.BatchCoordinate) string {
+	switch {
+	case item.Lat == nil:
+		return models.ErrorCodeMissingLatitude
+	case item.Lon == nil:
+		return models.ErrorCodeMissingLongitude
+	case !coordinatesInRange(*item.Lat, *item.Lon):
+		return models.ErrorCodeCoordinatesOutOfRange
+	}
+	return ""
+}
+
+// batchErrorCode returns the error code, and its message parameters, for a
+// coordinate whose batch weather lookup failed
+func batchErrorCode(err error) (string, []string) {
+	switch {
+	case errors.Is(err, services.ErrUpstreamSaturated):
+		return models.ErrorCodeShed, nil
+	case errors.Is(err, services.ErrOutsideCoverage):
+		return models.ErrorCodeOutOfCoverage, nil
+	case errors.Is(err, services.ErrQuotaExceeded):
+		return models.ErrorCodeQuotaExceeded, nil
+	}
+	if _, code, ok := failureCode(err); ok {
+		return code, []string{"cause", err.Error()}
+	}
+	return models.ErrorCodeWeatherUnavailable, []string{"cause", err.Error()}
+}
+
+// batchError builds the localized error for one failed batch coordinate
+func batchError(c *fiber.Ctx, code string, params ...string) *models.ErrorResponse {
+	e := i18n.Error(c, code, params...)
+	return &e
+}
+
+// GetCachedWeather handles HEAD and GET /weather/cached requests
+// @Summary Check for a cached forecast
+// @Description Reports whether a fresh cached forecast exists for the coordinate, so a /weather request would be answered without an NWS fetch. Never calls the NWS and returns no body.
+// @Tags weather
+// @Param lat query number true "Latitude coordinate (-90 to 90)" example(40.7128)
+// @Param lon query number true "Longitude coordinate (-180 to 180)" example(-74.0060)
+// @Success 204 "A fresh forecast is cached; Age and X-Data-Source describe it"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 "No fresh forecast is cached"
+// @Router /weather/cached [head]
+// @Router /weather/cached [get]
+func (h *WeatherHandler) GetCachedWeather(c *fiber.Ctx) error {
+	lat, lon, code := parseCoordinates(c)
+	if code != "" {
+		return sendError(c, fiber.StatusBadRequest, code)
+	}
+
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+	cached, ok := h.service.CachedWeather(ctx, lat, lon)
+	if !ok {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	age := int(time.Since(cached.Timestamp).Seconds())
+	if age < 0 {
+		age = 0
+	}
+	c.Set(fiber.HeaderAge, strconv.Itoa(age))
+	c.Set("X-Data-Source", cached.Source)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetForecast handles GET /forecast requests
+// @Summary Get the multi-day forecast
+// @Description Returns every NWS day/night forecast period for the specified latitude and longitude, typically a week ahead, in NWS order
+// @Tags weather
+// @Produce json,xml,text/csv
+// @Param lat query number true "Latitude coordinate (-90 to 90)" example(40.7128)
+// @Param lon query number true "Longitude coordinate (-180 to 180)" example(-74.0060)
+// @Param icon_size query string false "Size of each period's NWS icon URL; the NWS's size when omitted" Enums(small, medium, large)
+// @Param wind_unit query string false "Unit to give each period's wind speed in; the NWS's when omitted" Enums(mph, kmh, ms, kn)
+// @Param tz query string false "IANA time zone to give local period times in; the location's own when omitted" example(America/Chicago)
+// @Param format query string false "Response format; overrides the Accept header. csv downloads one row per period." Enums(json, xml, csv)
+// @Success 200 {object} models.ForecastResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Failure 504 {object} models.ErrorResponse
+// @Router /forecast [get]
+func (h *WeatherHandler) GetForecast(c *fiber.Ctx) error {
+	lat, lon, code := parseCoordinates(c)
+	if code != "" {
+		return sendError(c, fiber.StatusBadRequest, code)
+	}
+	iconSize, err := services.ParseIconSize(c.Query("icon_size"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error:   "Invalid longitude parameter",
-			Details: "Longitude must be a valid float number",
-		})
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidIconSize)
+	}
+	windUnit, ok := parseWindUnit(c)
+	if !ok {
+		return sendInvalidWindUnit(c)
+	}
+	tz, err := services.ParseTimeZone(c.Query("tz"))
+	if err != nil {
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidTimeZone)
 	}
 
-	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error:   "Invalid coordinates",
-			Details: "Latitude must be between -90 and 90, Longitude between -180 and 180",
-		})
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+	forecast, err := h.service.GetForecast(ctx, lat, lon)
+	if err != nil {
+		var shed *services.ShedError
+		if errors.As(err, &shed) {
+			return sendShed(c, shed)
+		}
+		if errors.Is(err, services.ErrOutsideCoverage) {
+			return sendError(c, fiber.StatusNotFound, models.ErrorCodeOutOfCoverage)
+		}
+		if status, code, ok := failureCode(err); ok {
+			return sendError(c, status, code, "cause", err.Error())
+		}
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeWeatherUnavailable, "cause", err.Error())
 	}
 
-	weather, err := h.service.GetWeather(lat, lon)
+	forecast = services.LocalizeForecast(forecast, tz)
+	forecast.Periods = services.ResizeIcons(forecast.Periods, iconSize)
+	forecast.Periods = services.ConvertPeriodWinds(forecast.Periods, windUnit)
+
+	metrics.MarkCacheHit(c, forecast.CacheHit)
+	setCacheControl(c, forecast.FreshUntil)
+	if negotiate.Format(c) == negotiate.CSV {
+		return sendCSV(c, csvFilename("forecast", lat, lon, time.Now().UTC().Format(time.DateOnly)), forecastRows(forecast))
+	}
+	return negotiate.Send(c, forecast)
+}
+
+// GetHourlyForecast handles GET /weather/hourly requests
+// @Summary Get the hourly forecast
+// @Description Returns the remaining hours of the NWS hourly forecast for the specified latitude and longitude, oldest first. Hourly forecasts are cached for 30 minutes.
+// @Tags weather
+// @Produce json
+// @Param lat query number true "Latitude coordinate (-90 to 90)" example(40.7128)
+// @Param lon query number true "Longitude coordinate (-180 to 180)" example(-74.0060)
+// @Param wind_unit query string false "Unit to give each hour's wind speed in; the NWS's when omitted" Enums(mph, kmh, ms, kn)
+// @Param tz query string false "IANA time zone to give local hours in; the location's own when omitted" example(America/Chicago)
+// @Success 200 {object} models.HourlyForecastResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Failure 504 {object} models.ErrorResponse
+// @Router /weather/hourly [get]
+func (h *WeatherHandler) GetHourlyForecast(c *fiber.Ctx) error {
+	lat, lon, code := parseCoordinates(c)
+	if code != "" {
+		return sendError(c, fiber.StatusBadRequest, code)
+	}
+	windUnit, ok := parseWindUnit(c)
+	if !ok {
+		return sendInvalidWindUnit(c)
+	}
+	tz, err := services.ParseTimeZone(c.Query("tz"))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error:   "Failed to get weather data",
-			Details: err.Error(),
-		})
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidTimeZone)
+	}
+
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+	forecast, err := h.service.GetHourlyForecast(ctx, lat, lon)
+	if err != nil {
+		var shed *services.ShedError
+		if errors.As(err, &shed) {
+			return sendShed(c, shed)
+		}
+		if errors.Is(err, services.ErrNoHourlyForecast) {
+			return sendError(c, fiber.StatusNotFound, models.ErrorCodeNoHourlyForecast)
+		}
+		if errors.Is(err, services.ErrOutsideCoverage) {
+			return sendError(c, fiber.StatusNotFound, models.ErrorCodeOutOfCoverage)
+		}
+		if status, code, ok := failureCode(err); ok {
+			return sendError(c, status, code, "cause", err.Error())
+		}
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeWeatherUnavailable, "cause", err.Error())
+	}
+
+	forecast = services.LocalizeHourly(forecast, tz)
+	forecast = services.ConvertHourlyWinds(forecast, windUnit)
+
+	metrics.MarkCacheHit(c, forecast.CacheHit)
+	setCacheControl(c, forecast.FreshUntil)
+	return c.JSON(jsoncase.For(c, forecast))
+}
+
+// GetAlerts handles GET /alerts requests
+// @Summary Get active weather alerts
+// @Description Returns the NWS alerts active at the specified latitude and longitude, including storm-based warnings, optionally filtered by severity, urgency, event, and whether they are in effect yet, and with their GeoJSON geometry on request. No active alerts yields an empty list. Alerts are cached for at most 3 minutes, before filtering, so every filter shares one upstream fetch.
+// @Tags alerts
+// @Produce json
+// @Param lat query number true "Latitude coordinate (-90 to 90)" example(40.7128)
+// @Param lon query number true "Longitude coordinate (-180 to 180)" example(-74.0060)
+// @Param severity query string false "Comma-separated severities to keep (Extreme, Severe, Moderate, Minor, Unknown)" example(Severe,Extreme)
+// @Param urgency query string false "Comma-separated urgencies to keep (Immediate, Expected, Future, Past, Unknown)" example(Immediate)
+// @Param event query string false "Keep alerts whose event contains this text, ignoring case" example(Tornado)
+// @Param active_only query bool false "Keep only alerts whose onset has passed"
+// @Param geometry query bool false "Include each alert's GeoJSON geometry: its polygon, or the shapes of its affected zones"
+// @Success 200 {object} models.AlertsResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /alerts [get]
+func (h *WeatherHandler) GetAlerts(c *fiber.Ctx) error {
+	lat, lon, code := parseCoordinates(c)
+	if code != "" {
+		return sendError(c, fiber.StatusBadRequest, code)
+	}
+	activeOnly := false
+	if v := c.Query("active_only"); v != "" {
+		var err error
+		if activeOnly, err = strconv.ParseBool(v); err != nil {
+			return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidActiveOnly)
+		}
+	}
+	includeGeometry := false
+	if v := c.Query("geometry"); v != "" {
+		var err error
+		if includeGeometry, err = strconv.ParseBool(v); err != nil {
+			return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidGeometry)
+		}
+	}
+	filter, err := services.ParseAlertFilter(c.Query("severity"), c.Query("urgency"), c.Query("event"), activeOnly)
+	switch {
+	case errors.Is(err, services.ErrInvalidAlertSeverity):
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidAlertSeverity, "accepted", strings.Join(services.AlertSeverities, ", "))
+	case errors.Is(err, services.ErrInvalidAlertUrgency):
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidAlertUrgency, "accepted", strings.Join(services.AlertUrgencies, ", "))
+	}
+
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+	alerts, err := h.service.GetPointAlerts(ctx, lat, lon)
+	if err != nil {
+		var shed *services.ShedError
+		if errors.As(err, &shed) {
+			return sendShed(c, shed)
+		}
+		if errors.Is(err, services.ErrOutsideCoverage) {
+			return sendError(c, fiber.StatusNotFound, models.ErrorCodeOutOfCoverage)
+		}
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeAlertsUnavailable, "cause", err.Error())
+	}
+	alerts.Alerts = filter.Apply(alerts.Alerts, time.Now())
+	if includeGeometry {
+		alerts.Alerts = h.service.WithAlertGeometry(ctx, alerts.Alerts)
+	} else {
+		// Polygons are large, so they are left out unless asked for
+		for i := range alerts.Alerts {
+			alerts.Alerts[i].Geometry = nil
+		}
+	}
+
+	metrics.MarkCacheHit(c, alerts.CacheHit)
+	setCacheControl(c, alerts.FreshUntil)
+	return c.JSON(jsoncase.For(c, alerts))
+}
+
+// GetStationObservations handles GET /stations/:stationId/observations requests
+// @Summary Get station observation history
+// @Description Returns the recent measured observations for an NWS station, oldest first, with temperatures, wind, and pressure normalized into API units
+// @Tags observations
+// @Accept json
+// @Produce json
+// @Param stationId path string true "NWS station identifier" example(KNYC)
+// @Param hours query int false "History window in hours (default 24, capped at 72)" example(24)
+// @Success 200 {object} models.ObservationHistoryResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /stations/{stationId}/observations [get]
+func (h *WeatherHandler) GetStationObservations(c *fiber.Ctx) error {
+	stationID, err := services.NormalizeStationID(c.Params("stationId"))
+	if err != nil {
+		return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidStationID)
+	}
+
+	hours := services.DefaultObservationHours
+	if hoursStr := c.Query("hours"); hoursStr != "" {
+		hours, err = strconv.Atoi(hoursStr)
+		if err != nil || hours < 1 {
+			return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidHours,
+				"max", strconv.Itoa(services.MaxObservationHours))
+		}
 	}
 
-	return c.JSON(weather)
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+	history, err := h.service.GetStationObservations(ctx, stationID, hours)
+	if err != nil {
+		if errors.Is(err, services.ErrStationNotFound) {
+			return sendError(c, fiber.StatusNotFound, models.ErrorCodeStationNotFound, "station", stationID)
+		}
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeObservationUnavailable, "cause", err.Error())
+	}
+
+	metrics.MarkCacheHit(c, history.CacheHit)
+	setCacheControl(c, history.FreshUntil)
+	return c.JSON(jsoncase.For(c, history))
+}
+
+// GetCurrentConditions handles GET /observations requests
+// @Summary Get current observed conditions
+// @Description Returns the latest observation from the NWS station nearest the specified latitude and longitude. When the latest observation has no temperature, the most recent one from the past 3 hours that does is returned.
+// @Tags observations
+// @Produce json
+// @Param lat query number true "Latitude coordinate (-90 to 90)" example(40.7128)
+// @Param lon query number true "Longitude coordinate (-180 to 180)" example(-74.0060)
+// @Success 200 {object} models.CurrentConditionsResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /observations [get]
+func (h *WeatherHandler) GetCurrentConditions(c *fiber.Ctx) error {
+	lat, lon, code := parseCoordinates(c)
+	if code != "" {
+		return sendError(c, fiber.StatusBadRequest, code)
+	}
+
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+	conditions, err := h.service.GetCurrentConditions(ctx, lat, lon)
+	if err != nil {
+		var shed *services.ShedError
+		if errors.As(err, &shed) {
+			return sendShed(c, shed)
+		}
+		if errors.Is(err, services.ErrNoObservationStation) || errors.Is(err, services.ErrStationNotFound) {
+			return sendError(c, fiber.StatusNotFound, models.ErrorCodeNoObservationStation)
+		}
+		if errors.Is(err, services.ErrOutsideCoverage) {
+			return sendError(c, fiber.StatusNotFound, models.ErrorCodeOutOfCoverage)
+		}
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeObservationUnavailable, "cause", err.Error())
+	}
+
+	metrics.MarkCacheHit(c, conditions.CacheHit)
+	setCacheControl(c, conditions.FreshUntil)
+	return c.JSON(jsoncase.For(c, conditions))
+}
+
+// GetWeatherHistory handles GET /weather/history requests
+// @Summary Get daily forecast history
+// @Description Returns per-day min/max/mean temperatures and the dominant forecast recorded for a coordinate, oldest first. Days are UTC; days without cached forecasts are omitted.
+// @Tags weather
+// @Produce json,text/csv
+// @Param lat query number true "Latitude coordinate (-90 to 90)" example(40.7128)
+// @Param lon query number true "Longitude coordinate (-180 to 180)" example(-74.0060)
+// @Param from query string false "First UTC day (YYYY-MM-DD), defaults to 30 days before to" example(2024-01-01)
+// @Param to query string false "Last UTC day (YYYY-MM-DD), defaults to today" example(2024-01-31)
+// @Param format query string false "Response format; csv downloads one row per day with mean temperatures" Enums(json, csv)
+// @Success 200 {object} models.WeatherHistoryResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /weather/history [get]
+func (h *WeatherHandler) GetWeatherHistory(c *fiber.Ctx) error {
+	lat, lon, code := parseCoordinates(c)
+	if code != "" {
+		return sendError(c, fiber.StatusBadRequest, code)
+	}
+
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+	history, err := h.service.GetWeatherHistory(ctx, lat, lon, c.Query("from"), c.Query("to"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDayRange) {
+			return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidDateRange,
+				"max", strconv.Itoa(services.MaxHistoryDays))
+		}
+		return sendError(c, fiber.StatusInternalServerError, models.ErrorCodeHistoryUnavailable, "cause", err.Error())
+	}
+	if negotiate.Format(c) == negotiate.CSV {
+		return sendCSV(c, csvFilename("history", lat, lon, history.From, history.To), historyRows(history))
+	}
+	return c.JSON(jsoncase.For(c, history))
 }
 
 // GetHealth handles GET /health requests
 // @Summary Health check
-// @Description Check if the weather service is running
+// @Description Check if the weather service is running and report its dependencies and the effective temperature thresholds. Status is degraded while a dependency is down or the cache database is above 90% of its size cap, and unhealthy (503) when neither SQLite nor Redis is usable.
 // @Tags health
 // @Accept json
 // @Produce json
 // @Success 200 {object} models.HealthResponse
+// @Failure 503 {object} models.HealthResponse
 // @Router /health [get]
 func (h *WeatherHandler) GetHealth(c *fiber.Ctx) error {
-	return c.JSON(models.HealthResponse{
+	health := h.Health(c.UserContext())
+	if health.Status == "unhealthy" {
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+	return c.JSON(jsoncase.For(c, health))
+}
+
+// Health reports the current service health. The service is unhealthy when it
+// has no usable cache at all, SQLite being down with no Redis to fall back on,
+// and degraded when any dependency is down or the database nears its cap.
+func (h *WeatherHandler) Health(ctx context.Context) models.HealthResponse {
+	health := models.HealthResponse{
 		Status:    "healthy",
 		Timestamp: time.Now().Format(time.RFC3339),
-	})
+	}
+	degrade := func() {
+		if health.Status == "healthy" {
+			health.Status = "degraded"
+		}
+	}
+	if h.service != nil {
+		t := h.service.TemperatureThresholds()
+		health.TemperatureThresholds = &models.TemperatureThresholds{HotC: t.HotC, ColdC: t.ColdC}
+
+		deps := h.service.CheckDependencies(ctx)
+		health.Dependencies = &deps
+		switch {
+		case deps.SQLite.Status == services.DependencyDown && deps.Redis.Status != services.DependencyUp:
+			health.Status = "unhealthy"
+		case deps.SQLite.Status == services.DependencyDown, deps.Redis.Status == services.DependencyDown,
+			deps.NWS.Status == services.DependencyDown, deps.NWS.Circuit == services.CircuitOpen,
+			deps.NWS.BackoffUntil != nil,
+			deps.Postgres != nil && deps.Postgres.Status == services.DependencyDown:
+			degrade()
+		}
+	}
+	if h.databaseUsage == nil {
+		return health
+	}
+
+	usage, err := h.databaseUsage(ctx)
+	if err != nil {
+		degrade()
+		return health
+	}
+	health.Database = &usage
+	if usage.UsageRatio != nil && *usage.UsageRatio > DegradedDatabaseUsage {
+		degrade()
+	}
+	return health
+}
+
+// parseCoordinates reads and range-checks the request's coordinates,
+// returning the error code to send when they are missing or invalid
+func parseCoordinates(c *fiber.Ctx) (float64, float64, string) {
+	latStr, lonStr := coordinateParams(c)
+	lat, lon, code := checkCoordinates(latStr, lonStr)
+	if code != "" {
+		return 0, 0, code
+	}
+
+	metrics.MarkCoordinates(c, models.Coordinates{Latitude: lat, Longitude: lon})
+	return lat, lon, ""
+}
+
+// coordinateParams returns the raw lat and lon of a request: the :lat and
+// :lon path segments on routes that have them, e.g. /weather/40.7/-74.0, and
+// the query parameters otherwise
+func coordinateParams(c *fiber.Ctx) (string, string) {
+	if !slices.Contains(c.Route().Params, "lat") {
+		return c.Query("lat"), c.Query("lon")
+	}
+	// Path segments arrive escaped, e.g. %2D74.0 for -74.0
+	lat, err := url.PathUnescape(c.Params("lat"))
+	if err != nil {
+		lat = c.Params("lat")
+	}
+	lon, err := url.PathUnescape(c.Params("lon"))
+	if err != nil {
+		lon = c.Params("lon")
+	}
+	return lat, lon
+}
+
+// checkCoordinates parses and range-checks a latitude and longitude,
+// returning the error code for ones that are missing or invalid
+func checkCoordinates(latStr, lonStr string) (float64, float64, string) {
+	if latStr == "" {
+		return 0, 0, models.ErrorCodeMissingLatitude
+	}
+	lat, err := strconv.ParseFloat(latStr, 64)
+	if err != nil {
+		return 0, 0, models.ErrorCodeInvalidLatitude
+	}
+
+	if lonStr == "" {
+		return 0, 0, models.ErrorCodeMissingLongitude
+	}
+	lon, err := strconv.ParseFloat(lonStr, 64)
+	if err != nil {
+		return 0, 0, models.ErrorCodeInvalidLongitude
+	}
+
+	if !coordinatesInRange(lat, lon) {
+		return 0, 0, models.ErrorCodeCoordinatesOutOfRange
+	}
+	return lat, lon, ""
+}
+
+// coordinatesInRange reports whether a latitude and longitude are on the globe
+func coordinatesInRange(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+// parseWindUnit reads ?wind_unit=, empty when it isn't given; ok is false
+// for a unit that isn't accepted
+func parseWindUnit(c *fiber.Ctx) (string, bool) {
+	raw := c.Query("wind_unit")
+	if raw == "" {
+		return "", true
+	}
+	unit, err := units.ParseWindUnit(raw)
+	return unit, err == nil
+}
+
+// sendInvalidWindUnit rejects a ?wind_unit= that isn't accepted, listing
+// those that are
+func sendInvalidWindUnit(c *fiber.Ctx) error {
+	return sendError(c, fiber.StatusBadRequest, models.ErrorCodeInvalidWindUnit, "accepted", strings.Join(units.WindUnits, ", "))
+}
+
+// sendError sends the error response for a code, localized to the request's language
+func sendError(c *fiber.Ctx, status int, code string, params ...string) error {
+	return negotiate.Send(c.Status(status), i18n.Error(c, code, params...))
+}
+
+// sendShed rejects a request that needed upstream capacity with 503 and a Retry-After hint
+func sendShed(c *fiber.Ctx, shed *services.ShedError) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(shed.RetryAfter.Seconds()))))
+	switch {
+	case errors.Is(shed, services.ErrCircuitOpen):
+		return sendError(c, fiber.StatusServiceUnavailable, models.ErrorCodeCircuitOpen)
+	case errors.Is(shed, services.ErrUpstreamBackoff):
+		return sendError(c, fiber.StatusServiceUnavailable, models.ErrorCodeUpstreamBackoff)
+	}
+	return sendError(c, fiber.StatusServiceUnavailable, models.ErrorCodeShed)
+}
+
+// CacheStatusHeader reports whether /weather data came from the cache: HIT for
+// a fresh cached forecast, MISS for a live NWS fetch, or STALE for expired data
+// served while it is refreshed or because the fetch failed
+const CacheStatusHeader = "X-Cache"
+
+// cacheStatus maps a weather response's source to its CacheStatusHeader value;
+// any cache tier is a hit
+func cacheStatus(source string) string {
+	switch source {
+	case services.SourceLive, "":
+		return "MISS"
+	case services.SourceStale:
+		return "STALE"
+	}
+	return "HIT"
+}
+
+// setCacheControl advertises how long a response stays fresh; stale data is marked no-cache
+func setCacheControl(c *fiber.Ctx, freshUntil time.Time) {
+	maxAge := int(time.Until(freshUntil).Seconds())
+	if maxAge <= 0 {
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		return
+	}
+	c.Set(fiber.HeaderCacheControl, "public, max-age="+strconv.Itoa(maxAge))
+}
+
+// weatherETag returns the weak ETag of a /weather response. It is derived from
+// the cache entry behind the response rather than the body: the normalized
+// coordinate, the time the entry was fetched, and its forecast, plus the query
+// options that shape the representation and the advisories, which move with
+// the clock. A refreshed entry gets a new ETag even when its forecast reads
+// the same, while one entry keeps its ETag whether it is served fresh or stale.
+func weatherETag(c *fiber.Ctx, coords repository.Coordinates, weather *models.WeatherResponse, opts services.WeatherOptions) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%d|%s|%s|%s|%s|%s|%s|%s|%s|%s|%s", coords.Key,
+		weather.CachedAt.UnixNano(), weather.Provider, weather.Forecast,
+		opts.Units, opts.WindUnit, opts.IconSize, weather.TimeZone, c.Query("include"), c.Query("at"), c.Query("case"), negotiate.Format(c))
+	if weather.Place != nil {
+		fmt.Fprintf(h, "|%s", weather.Place.Name)
+	}
+	// The UV index can be added to an entry after it was first served
+	if weather.UVIndex != nil {
+		fmt.Fprintf(h, "|%g", *weather.UVIndex)
+	}
+	// Advisories look at the periods ahead of now, so one entry's change as
+	// periods fall out of the window and into it
+	if adv := weather.Advisories; adv != nil {
+		fmt.Fprintf(h, "|%t|%t|%s", adv.FrostRisk, adv.HeatRisk, adv.Severity)
+		if adv.HeatIndexC != nil {
+			fmt.Fprintf(h, "|%g", *adv.HeatIndexC)
+		}
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header lists the ETag, using
+// the weak comparison RFC 9110 prescribes for If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// hasInclude reports whether the comma-separated include query parameter lists the given section
+func hasInclude(c *fiber.Ctx, section string) bool {
+	for _, v := range strings.Split(c.Query("include"), ",") {
+		if strings.EqualFold(strings.TrimSpace(v), section) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,2387 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"weather-api-go/internal/config"
+	"weather-api-go/internal/metrics"
+	"weather-api-go/internal/middleware"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+	"weather-api-go/internal/services"
+	"weather-api-go/internal/tracing"
+)
+
+// fakeNWS serves a minimal points/forecast exchange for handler tests
+func fakeNWS(t testing.TB) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/points/"):
+			fmt.Fprintf(w, `{"properties": {"forecast": "%s/gridpoints/OKX/33,35/forecast", "timeZone": "America/New_York"}}`, server.URL)
+		case strings.HasSuffix(r.URL.Path, "/forecast"):
+			fmt.Fprint(w, `{"properties": {"periods": [
+				{"shortForecast": "Partly Cloudy", "detailedForecast": "Partly cloudy, with a high near 72. West wind 5 to 10 mph.",
+					"temperature": 72, "temperatureUnit": "F", "windSpeed": "5 to 10 mph", "windDirection": "W",
+					"dewpoint": {"unitCode": "wmoUnit:degC", "value": 10}, "relativeHumidity": {"unitCode": "wmoUnit:percent", "value": 45},
+					"icon": "https://api.weather.gov/icons/land/day/sct?size=medium"}
+			]}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestApp wires the real handler, service, and repository against a fake NWS
+// server, with any middleware mounted on the API group
+func newTestApp(t testing.TB, nws *httptest.Server, mw ...fiber.Handler) *fiber.App {
+	t.Helper()
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	repo := repository.NewWeatherRepository(db, nil)
+	client := services.NewNWSAPIClient(services.WithBaseURL(nws.URL), services.WithHTTPClient(nws.Client()))
+	handler := NewWeatherHandler(services.NewWeatherService(repo, client))
+
+	docs := NewDocsHandler("")
+
+	app := fiber.New()
+	api := app.Group(APIBasePath, mw...)
+	api.Get("/weather", handler.GetWeather)
+	api.Get("/weather/:lat/:lon", handler.GetWeather)
+	api.Post("/weather", handler.PostWeather)
+	api.Post("/weather/batch", handler.GetWeatherBatch)
+	api.Post("/graphql", handler.GraphQL)
+	api.Get("/ws", handler.WeatherUpdates)
+	api.Get("/weather/stream", handler.GetWeatherStream)
+	api.Get("/weather/history", handler.GetWeatherHistory)
+	api.Get("/weather/hourly", handler.GetHourlyForecast)
+	api.Get("/forecast", handler.GetForecast)
+	api.Get("/forecast/:lat/:lon", handler.GetForecast)
+	api.Get("/alerts", handler.GetAlerts)
+	api.Get("/astronomy", handler.GetAstronomy)
+	api.Get("/airquality", handler.GetAirQuality)
+	api.Get("/metadata", handler.GetMetadata)
+	api.Get("/observations", handler.GetCurrentConditions)
+	api.Get("/raw/points", handler.GetRawPoints)
+	api.Get("/raw/forecast", handler.GetRawForecast)
+	api.Delete("/admin/cache", handler.InvalidateCache)
+	api.Delete("/admin/cache/all", handler.InvalidateAllCache)
+	api.Get("/admin/cache/locations", handler.ListCachedLocations)
+	api.Get("/health", handler.GetHealth)
+	app.Get("/docs", docs.ServeAPIDocs)
+	app.Get("/openapi.yaml", docs.ServeOpenAPIYAML)
+	app.Get("/swagger/doc.json", docs.ServeOpenAPIJSON)
+	app.Get("/schemas/:model.json", ServeModelSchema)
+	return app
+}
+
+// listenTestApp serves the streaming routes on a local listener against a
+// fake NWS server, returning its address and repository
+func listenTestApp(t *testing.T, opts ...WeatherHandlerOption) (string, *repository.WeatherRepository) {
+	t.Helper()
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	nws := fakeNWS(t)
+	repo := repository.NewWeatherRepository(db, nil)
+	client := services.NewNWSAPIClient(services.WithBaseURL(nws.URL), services.WithHTTPClient(nws.Client()))
+	handler := NewWeatherHandler(services.NewWeatherService(repo, client), opts...)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	api := app.Group(APIBasePath)
+	api.Get("/ws", handler.WeatherUpdates)
+	api.Get("/weather/stream", handler.GetWeatherStream)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+	return ln.Addr().String(), repo
+}
+
+func TestGetWeatherSetsCacheControl(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	for _, label := range []string{"fresh fetch", "cached"} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cc := resp.Header.Get(fiber.HeaderCacheControl)
+		if !strings.HasPrefix(cc, "public, max-age=") {
+			t.Fatalf("%s: Cache-Control = %q; want public max-age", label, cc)
+		}
+		if age, _ := strconv.Atoi(strings.TrimPrefix(cc, "public, max-age=")); age <= 3500 || age > 3600 {
+			t.Errorf("%s: max-age = %d; want close to the 1h data TTL", label, age)
+		}
+	}
+}
+
+func TestGetWeatherCacheHeader(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	for _, tc := range []struct {
+		label  string
+		header string
+		source string
+	}{
+		{"cold", "MISS", services.SourceLive},
+		{"warm", "HIT", repository.SourceSQLite},
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get(CacheStatusHeader); got != tc.header {
+			t.Errorf("%s: %s = %q; want %q", tc.label, CacheStatusHeader, got, tc.header)
+		}
+		var body models.WeatherResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Source != tc.source || body.CachedAt == nil {
+			t.Errorf("%s: source = %q, cached_at = %v; want %q with a timestamp", tc.label, body.Source, body.CachedAt, tc.source)
+		}
+	}
+}
+
+// TestGetWeatherETag documents the /weather validators: the ETag is weak and
+// tracks the cache entry behind the response, so it changes when the entry is
+// refreshed even if the forecast is unchanged, and Last-Modified is the time
+// the entry was fetched.
+func TestGetWeatherETag(t *testing.T) {
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	repo := repository.NewWeatherRepository(db, nil)
+	nws := fakeNWS(t)
+	service := services.NewWeatherService(repo,
+		services.NewNWSAPIClient(services.WithBaseURL(nws.URL), services.WithHTTPClient(nws.Client())))
+	app := fiber.New()
+	app.Get("/api/weather", NewWeatherHandler(service).GetWeather)
+
+	get := func(target, ifNoneMatch string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest("GET", target, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set(fiber.HeaderIfNoneMatch, ifNoneMatch)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	save := func(fetched time.Time) {
+		t.Helper()
+		entry := &models.WeatherCache{Latitude: 40.7128, Longitude: -74.006, Forecast: "Fog", TempC: 8, TempF: 46.4, Timestamp: fetched}
+		if err := repo.SaveToCache(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fetched := time.Now().Add(-time.Minute).Truncate(time.Second)
+	save(fetched)
+	const target = "/api/weather?lat=40.7128&lon=-74.006"
+	resp := get(target, "")
+	etag := resp.Header.Get(fiber.HeaderETag)
+	if resp.StatusCode != fiber.StatusOK || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("status = %d, ETag = %q; want 200 with a weak ETag", resp.StatusCode, etag)
+	}
+	if got, want := resp.Header.Get(fiber.HeaderLastModified), fetched.UTC().Format(http.TimeFormat); got != want {
+		t.Errorf("Last-Modified = %q; want %q", got, want)
+	}
+
+	tests := []struct {
+		name        string
+		target      string
+		ifNoneMatch string
+		wantStatus  int
+		wantSame    bool
+	}{
+		{"matching tag", target, etag, fiber.StatusNotModified, true},
+		{"matching tag in a list", target, `"other", ` + etag, fiber.StatusNotModified, true},
+		{"strong form of the tag", target, strings.TrimPrefix(etag, "W/"), fiber.StatusNotModified, true},
+		{"wildcard", target, "*", fiber.StatusNotModified, true},
+		{"other tag", target, `W/"other"`, fiber.StatusOK, true},
+		{"equivalent coordinates", "/api/weather?lat=40.712800&lon=-74.0060", etag, fiber.StatusNotModified, true},
+		{"other representation", target + "&units=metric", etag, fiber.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(tt.target, tt.ifNoneMatch)
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d; want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := resp.Header.Get(fiber.HeaderETag); (got == etag) != tt.wantSame {
+				t.Errorf("ETag = %q; same as %q: want %v", got, etag, tt.wantSame)
+			}
+			if body, _ := io.ReadAll(resp.Body); resp.StatusCode == fiber.StatusNotModified && len(body) > 0 {
+				t.Errorf("304 body = %q; want it empty", body)
+			}
+		})
+	}
+
+	// A refresh gets a new ETag even though the forecast reads the same
+	save(fetched.Add(30 * time.Second))
+	resp = get(target, etag)
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get(fiber.HeaderETag) == etag {
+		t.Errorf("after a refresh: status = %d, ETag = %q; want 200 with a new ETag", resp.StatusCode, resp.Header.Get(fiber.HeaderETag))
+	}
+}
+
+// TestGetWeatherETagFollowsAdvisories checks that one cache entry's ETag
+// changes when a period crossing out of the advisory window changes its
+// advisories, so a client holding the old one isn't told it is current
+func TestGetWeatherETagFollowsAdvisories(t *testing.T) {
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	repo := repository.NewWeatherRepository(db, nil)
+	nws := fakeNWS(t)
+	service := services.NewWeatherService(repo,
+		services.NewNWSAPIClient(services.WithBaseURL(nws.URL), services.WithHTTPClient(nws.Client())))
+	app := fiber.New()
+	app.Get("/api/weather", NewWeatherHandler(service).GetWeather)
+
+	// A freezing clear night that ends a moment from now
+	ctx := context.Background()
+	now := time.Now()
+	frostEnds := now.Add(500 * time.Millisecond)
+	entry := &models.WeatherCache{Latitude: 40.7128, Longitude: -74.006, Forecast: "Fog", TempC: 8, TempF: 46.4, Timestamp: now,
+		GridID: "OKX", GridX: 33, GridY: 35}
+	periods := &models.ForecastPeriodsCache{Timestamp: now, Periods: []models.ForecastPeriod{
+		{Name: "Tonight", StartTime: now.Add(-12 * time.Hour), EndTime: frostEnds, ShortForecast: "Clear", TempC: -5, TempF: 23},
+		{Name: "Today", StartTime: frostEnds, EndTime: frostEnds.Add(12 * time.Hour), ShortForecast: "Cloudy", TempC: 10, TempF: 50},
+	}}
+	if err := repo.SaveToCache(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveForecastPeriods(ctx, repository.DailyPeriods, "OKX", 33, 35, periods); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(ifNoneMatch string) (*http.Response, models.WeatherResponse) {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.006&include=advisories", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set(fiber.HeaderIfNoneMatch, ifNoneMatch)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body models.WeatherResponse
+		if resp.StatusCode == fiber.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp, body
+	}
+
+	resp, body := get("")
+	etag := resp.Header.Get(fiber.HeaderETag)
+	if body.Advisories == nil || !body.Advisories.FrostRisk {
+		t.Fatalf("advisories before the frost ends = %+v; want frost", body.Advisories)
+	}
+
+	time.Sleep(time.Until(frostEnds) + 10*time.Millisecond)
+	resp, body = get(etag)
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get(fiber.HeaderETag) == etag {
+		t.Fatalf("after the frost ends: status = %d, ETag = %q; want 200 with a new ETag", resp.StatusCode, resp.Header.Get(fiber.HeaderETag))
+	}
+	if body.Advisories == nil || body.Advisories.FrostRisk {
+		t.Errorf("advisories after the frost ends = %+v; want none", body.Advisories)
+	}
+}
+
+func TestGetWeatherUnits(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	tests := []struct {
+		units        string
+		status       int
+		wantC, wantF bool
+	}{
+		{"", fiber.StatusOK, true, true},
+		{"both", fiber.StatusOK, true, true},
+		{"metric", fiber.StatusOK, true, false},
+		{"imperial", fiber.StatusOK, false, true},
+		{"IMPERIAL", fiber.StatusOK, false, true},
+		{"kelvin", fiber.StatusBadRequest, false, false},
+	}
+	for _, tt := range tests {
+		t.Run("units="+tt.units, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060&units="+tt.units, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d; want %d", resp.StatusCode, tt.status)
+			}
+
+			var body map[string]interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if tt.status != fiber.StatusOK {
+				if body["code"] != models.ErrorCodeInvalidUnits || body["error"] == "" {
+					t.Errorf("body = %v; want an %s ErrorResponse", body, models.ErrorCodeInvalidUnits)
+				}
+				return
+			}
+			_, hasC := body["temperature_c"]
+			_, hasF := body["temperature_f"]
+			if hasC != tt.wantC || hasF != tt.wantF {
+				t.Errorf("temperature_c present %v, temperature_f present %v; want %v, %v", hasC, hasF, tt.wantC, tt.wantF)
+			}
+			if tt.wantF && body["temperature_f"] != 72.0 {
+				t.Errorf("temperature_f = %v; want 72", body["temperature_f"])
+			}
+			_, hasKmh := body["wind_speed_kmh"]
+			mph, hasMph := body["wind_speed_mph"]
+			if hasKmh != tt.wantC || hasMph != tt.wantF {
+				t.Errorf("wind_speed_kmh present %v, wind_speed_mph present %v; want %v, %v", hasKmh, hasMph, tt.wantC, tt.wantF)
+			}
+			if want := map[string]interface{}{"min": 5.0, "max": 10.0}; tt.wantF && !reflect.DeepEqual(mph, want) {
+				t.Errorf("wind_speed_mph = %v; want %v", mph, want)
+			}
+			if body["wind_direction"] != "W" {
+				t.Errorf("wind_direction = %v; want W", body["wind_direction"])
+			}
+			_, hasDewpointC := body["dewpoint_c"]
+			dewpointF, hasDewpointF := body["dewpoint_f"]
+			if hasDewpointC != tt.wantC || hasDewpointF != tt.wantF {
+				t.Errorf("dewpoint_c present %v, dewpoint_f present %v; want %v, %v", hasDewpointC, hasDewpointF, tt.wantC, tt.wantF)
+			}
+			if tt.wantF && dewpointF != 50.0 {
+				t.Errorf("dewpoint_f = %v; want 50", dewpointF)
+			}
+			if body["humidity_percent"] != 45.0 {
+				t.Errorf("humidity_percent = %v; want 45", body["humidity_percent"])
+			}
+			// The fake forecast gives no chance of precipitation, which isn't 0%
+			if p, ok := body["precipitation_probability"]; ok {
+				t.Errorf("precipitation_probability = %v; want it omitted", p)
+			}
+		})
+	}
+}
+
+func TestGetWeatherWindUnit(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	// The fake forecast's wind is 5 to 10 mph
+	tests := []struct {
+		path     string
+		status   int
+		wantMin  float64
+		wantMax  float64
+		wantUnit string
+	}{
+		{"/api/weather?lat=40.7128&lon=-74.0060&wind_unit=kn", fiber.StatusOK, 4.34, 8.69, "kn"},
+		{"/api/weather?lat=40.7128&lon=-74.0060&wind_unit=MPH", fiber.StatusOK, 5, 10, "mph"},
+		{"/api/weather?lat=40.7128&lon=-74.0060&wind_unit=knots", fiber.StatusOK, 4.34, 8.69, "kn"},
+		{"/api/weather/40.7128/-74.0060?wind_unit=ms", fiber.StatusOK, 2.24, 4.47, "ms"},
+		{"/api/weather?lat=40.7128&lon=-74.0060&wind_unit=furlongs", fiber.StatusBadRequest, 0, 0, ""},
+		{"/api/forecast?lat=40.7128&lon=-74.0060&wind_unit=beaufort", fiber.StatusBadRequest, 0, 0, ""},
+		{"/api/weather/hourly?lat=40.7128&lon=-74.0060&wind_unit=furlongs", fiber.StatusBadRequest, 0, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d; want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status != fiber.StatusOK {
+				var body models.ErrorResponse
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatal(err)
+				}
+				if body.Code != models.ErrorCodeInvalidWindUnit || !strings.Contains(body.Details, "mph, kmh, ms, kn") {
+					t.Errorf("body = %+v; want an %s error listing the accepted units", body, models.ErrorCodeInvalidWindUnit)
+				}
+				return
+			}
+
+			var body models.WeatherResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			speed := body.WindSpeed
+			if speed == nil || math.Abs(speed.Min-tt.wantMin) > 0.01 || math.Abs(speed.Max-tt.wantMax) > 0.01 || body.WindUnit != tt.wantUnit {
+				t.Errorf("wind_speed = %+v %q; want %v to %v %s", speed, body.WindUnit, tt.wantMin, tt.wantMax, tt.wantUnit)
+			}
+			// A chosen unit replaces both of the default speeds
+			if body.WindSpeedKmh != nil || body.WindSpeedMph != nil {
+				t.Errorf("wind_speed_kmh = %v, wind_speed_mph = %v; want neither", body.WindSpeedKmh, body.WindSpeedMph)
+			}
+		})
+	}
+
+	// Forecast periods reword the NWS wind in the chosen unit
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/forecast?lat=40.7128&lon=-74.0060&wind_unit=kmh", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var forecast models.ForecastResponse
+	if err := json.NewDecoder(resp.Body).Decode(&forecast); err != nil {
+		t.Fatal(err)
+	}
+	if len(forecast.Periods) == 0 || forecast.Periods[0].WindSpeed != "8 to 16.1 km/h" {
+		t.Errorf("periods = %+v; want an 8 to 16.1 km/h wind", forecast.Periods)
+	}
+}
+
+func TestGetWeatherIncludeDetailed(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+	const detailed = "Partly cloudy, with a high near 72. West wind 5 to 10 mph."
+
+	// The first request fetches from the NWS; the rest read the narrative back from SQLite
+	tests := []struct {
+		include string
+		want    string
+	}{
+		{"", ""},
+		{"detailed", detailed},
+		{"advisories", ""},
+		{"advisories, DETAILED", detailed},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060&include="+url.QueryEscape(tt.include), nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		got, present := body["detailed_forecast"]
+		if (tt.want == "" && present) || (tt.want != "" && got != tt.want) {
+			t.Errorf("include=%s: detailed_forecast = %v; want %q", tt.include, got, tt.want)
+		}
+	}
+}
+
+func TestGetWeatherIconSize(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	// The first request fetches from the NWS; the rest resize the cached icon
+	tests := []struct {
+		iconSize string
+		status   int
+		want     string
+	}{
+		{"", fiber.StatusOK, "https://api.weather.gov/icons/land/day/sct?size=medium"},
+		{"small", fiber.StatusOK, "https://api.weather.gov/icons/land/day/sct?size=small"},
+		{"LARGE", fiber.StatusOK, "https://api.weather.gov/icons/land/day/sct?size=large"},
+		{"huge", fiber.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060&icon_size="+tt.iconSize, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Fatalf("icon_size=%s: status = %d; want %d", tt.iconSize, resp.StatusCode, tt.status)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if tt.status != fiber.StatusOK {
+			if body["code"] != models.ErrorCodeInvalidIconSize {
+				t.Errorf("icon_size=%s: body = %v; want an %s ErrorResponse", tt.iconSize, body, models.ErrorCodeInvalidIconSize)
+			}
+			continue
+		}
+		if body["icon"] != tt.want {
+			t.Errorf("icon_size=%s: icon = %v; want %s", tt.iconSize, body["icon"], tt.want)
+		}
+	}
+}
+
+func TestGetWeatherTimeZone(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	// The location's own zone is used unless ?tz= overrides it
+	tests := []struct {
+		tz     string
+		status int
+		want   string
+	}{
+		{"", fiber.StatusOK, "America/New_York"},
+		{"Asia/Tokyo", fiber.StatusOK, "Asia/Tokyo"},
+		{"UTC", fiber.StatusOK, "UTC"},
+		{"Mars/Olympus_Mons", fiber.StatusBadRequest, ""},
+		{"Local", fiber.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060&tz="+tt.tz, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Fatalf("tz=%s: status = %d; want %d", tt.tz, resp.StatusCode, tt.status)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if tt.status != fiber.StatusOK {
+			if body["code"] != models.ErrorCodeInvalidTimeZone {
+				t.Errorf("tz=%s: body = %v; want an %s ErrorResponse", tt.tz, body, models.ErrorCodeInvalidTimeZone)
+			}
+			continue
+		}
+		if body["time_zone"] != tt.want {
+			t.Errorf("tz=%s: time_zone = %v; want %s", tt.tz, body["time_zone"], tt.want)
+		}
+		cachedAt, err := time.Parse(time.RFC3339, fmt.Sprint(body["cached_at"]))
+		if err != nil {
+			t.Fatalf("tz=%s: cached_at = %v: %v", tt.tz, body["cached_at"], err)
+		}
+		loc, _ := time.LoadLocation(tt.want)
+		if want := cachedAt.In(loc).Format(time.RFC3339Nano); body["cached_at_local"] != want {
+			t.Errorf("tz=%s: cached_at_local = %v; want %s", tt.tz, body["cached_at_local"], want)
+		}
+	}
+}
+
+func TestGetForecastTimeZone(t *testing.T) {
+	var nws *httptest.Server
+	nws = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/points/") {
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35, "forecast": "%s/gridpoints/OKX/33,35/forecast",
+				"timeZone": "America/New_York"}}`, nws.URL)
+			return
+		}
+		// Clocks spring forward at 2 AM on 2024-03-10, between these periods
+		fmt.Fprint(w, `{"properties": {"periods": [
+			{"name": "Tonight", "startTime": "2024-03-09T18:00:00-05:00", "endTime": "2024-03-10T06:00:00-04:00",
+			 "shortForecast": "Clear", "temperature": 35, "temperatureUnit": "F"},
+			{"name": "Sunday", "startTime": "2024-03-10T06:00:00-04:00", "endTime": "2024-03-10T18:00:00-04:00",
+			 "isDaytime": true, "shortForecast": "Sunny", "temperature": 52, "temperatureUnit": "F"}
+		]}}`)
+	}))
+	defer nws.Close()
+	app := newTestApp(t, nws)
+
+	tests := []struct {
+		tz                       string
+		wantZone                 string
+		wantStarts, wantLocalEnd []string
+	}{
+		{"", "America/New_York",
+			[]string{"2024-03-09T23:00:00Z", "2024-03-10T10:00:00Z"},
+			[]string{"2024-03-10T06:00:00-04:00", "2024-03-10T18:00:00-04:00"}},
+		// Chicago springs forward at its own 2 AM, an hour after New York
+		{"America/Chicago", "America/Chicago",
+			[]string{"2024-03-09T23:00:00Z", "2024-03-10T10:00:00Z"},
+			[]string{"2024-03-10T05:00:00-05:00", "2024-03-10T17:00:00-05:00"}},
+		{"Asia/Tokyo", "Asia/Tokyo",
+			[]string{"2024-03-09T23:00:00Z", "2024-03-10T10:00:00Z"},
+			[]string{"2024-03-10T19:00:00+09:00", "2024-03-11T07:00:00+09:00"}},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/forecast?lat=40.7128&lon=-74.0060&tz="+tt.tz, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("tz=%s: status = %d; want 200", tt.tz, resp.StatusCode)
+		}
+		var body struct {
+			TimeZone string `json:"time_zone"`
+			Periods  []struct {
+				StartTime    string `json:"start_time"`
+				EndTimeLocal string `json:"end_time_local"`
+			} `json:"periods"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body.TimeZone != tt.wantZone || len(body.Periods) != 2 {
+			t.Fatalf("tz=%s: time_zone = %q with %d periods; want %q with 2", tt.tz, body.TimeZone, len(body.Periods), tt.wantZone)
+		}
+		for i, p := range body.Periods {
+			if p.StartTime != tt.wantStarts[i] || p.EndTimeLocal != tt.wantLocalEnd[i] {
+				t.Errorf("tz=%s: period %d start_time, end_time_local = %s, %s; want %s, %s",
+					tt.tz, i, p.StartTime, p.EndTimeLocal, tt.wantStarts[i], tt.wantLocalEnd[i])
+			}
+		}
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/forecast?lat=40.7128&lon=-74.0060&tz=Eastern", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("tz=Eastern: status = %d; want 400", resp.StatusCode)
+	}
+}
+
+// placeGeocoder answers every lookup with a fixed list of places
+type placeGeocoder []models.Place
+
+func (g placeGeocoder) Geocode(context.Context, services.PlaceQuery) ([]models.Place, error) {
+	return g, nil
+}
+
+func TestGetWeatherByPlace(t *testing.T) {
+	portland := models.Place{Name: "Portland, Oregon", Latitude: 45.5202, Longitude: -122.6742}
+	nws := fakeNWS(t)
+	app := func(g services.Geocoder) *fiber.App {
+		db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		client := services.NewNWSAPIClient(services.WithBaseURL(nws.URL), services.WithHTTPClient(nws.Client()))
+		service := services.NewWeatherService(repository.NewWeatherRepository(db, nil), client, services.WithGeocoder(g))
+		app := fiber.New()
+		app.Get("/api/weather", NewWeatherHandler(service).GetWeather)
+		return app
+	}
+
+	tests := []struct {
+		name     string
+		geocoder services.Geocoder
+		query    string
+		status   int
+		code     string
+	}{
+		{"city", placeGeocoder{portland}, "city=Portland,OR", fiber.StatusOK, ""},
+		{"free text", placeGeocoder{portland}, "q=Portland%20Oregon", fiber.StatusOK, ""},
+		{"ambiguous", placeGeocoder{portland, {Name: "Portland, Maine", Latitude: 43.6591, Longitude: -70.2568}},
+			"city=Portland", fiber.StatusMultipleChoices, models.ErrorCodeAmbiguousLocation},
+		{"not found", placeGeocoder{}, "city=Atlantis", fiber.StatusNotFound, models.ErrorCodeLocationNotFound},
+		{"too long", placeGeocoder{portland}, "q=" + strings.Repeat("a", MaxLocationQueryLength+1), fiber.StatusBadRequest, models.ErrorCodeInvalidLocation},
+		{"disabled", nil, "city=Portland,OR", fiber.StatusBadRequest, models.ErrorCodeGeocodingDisabled},
+		{"neither", placeGeocoder{portland}, "", fiber.StatusBadRequest, models.ErrorCodeMissingLatitude},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app(tt.geocoder).Test(httptest.NewRequest("GET", "/api/weather?"+tt.query, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d; want %d", resp.StatusCode, tt.status)
+			}
+
+			var body struct {
+				models.WeatherResponse
+				models.AmbiguousLocationResponse
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.code {
+				t.Errorf("code = %q; want %q", body.Code, tt.code)
+			}
+			switch tt.status {
+			case fiber.StatusOK:
+				if body.Place == nil || *body.Place != portland || body.Forecast != "Partly Cloudy" {
+					t.Errorf("body = %+v; want the forecast with the resolved location", body.WeatherResponse)
+				}
+			case fiber.StatusMultipleChoices:
+				if len(body.Candidates) != 2 || body.Candidates[0] != portland {
+					t.Errorf("candidates = %+v; want both places, best first", body.Candidates)
+				}
+			}
+		})
+	}
+}
+
+// zipResolver is a ZIPResolver serving places from a map
+type zipResolver map[string]models.Place
+
+func (z zipResolver) ResolveZIP(ctx context.Context, zip string) (*models.Place, error) {
+	if place, ok := z[zip]; ok {
+		return &place, nil
+	}
+	return nil, nil
+}
+
+func TestGetWeatherByZIP(t *testing.T) {
+	chelsea := models.Place{Name: "New York City, NY 10001", Latitude: 40.7484, Longitude: -73.9967}
+	nws := fakeNWS(t)
+	app := func(z services.ZIPResolver) *fiber.App {
+		db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		client := services.NewNWSAPIClient(services.WithBaseURL(nws.URL), services.WithHTTPClient(nws.Client()))
+		service := services.NewWeatherService(repository.NewWeatherRepository(db, nil), client, services.WithZIPResolver(z))
+		app := fiber.New()
+		app.Get("/api/weather", NewWeatherHandler(service).GetWeather)
+		return app
+	}
+	resolver := zipResolver{"10001": chelsea}
+
+	tests := []struct {
+		name     string
+		resolver services.ZIPResolver
+		query    string
+		status   int
+		code     string
+	}{
+		{"zip", resolver, "zip=10001", fiber.StatusOK, ""},
+		{"zip+4", resolver, "zip=10001-2345", fiber.StatusOK, ""},
+		{"malformed", resolver, "zip=1000", fiber.StatusBadRequest, models.ErrorCodeInvalidZIP},
+		{"unknown", resolver, "zip=99999", fiber.StatusNotFound, models.ErrorCodeZIPNotFound},
+		{"with coordinates", resolver, "zip=10001&lat=40.7&lon=-74", fiber.StatusBadRequest, models.ErrorCodeConflictingLocation},
+		{"with a city", resolver, "zip=10001&city=Boston", fiber.StatusBadRequest, models.ErrorCodeConflictingLocation},
+		{"disabled", nil, "zip=10001", fiber.StatusBadRequest, models.ErrorCodeGeocodingDisabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app(tt.resolver).Test(httptest.NewRequest("GET", "/api/weather?"+tt.query, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d; want %d", resp.StatusCode, tt.status)
+			}
+
+			var body struct {
+				models.WeatherResponse
+				models.ErrorResponse
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.code {
+				t.Errorf("code = %q; want %q", body.Code, tt.code)
+			}
+			if tt.status == fiber.StatusOK && (body.Place == nil || *body.Place != chelsea || body.Forecast != "Partly Cloudy") {
+				t.Errorf("body = %+v; want the forecast with the resolved ZIP code", body.WeatherResponse)
+			}
+			if tt.code == models.ErrorCodeZIPNotFound && !strings.Contains(body.Details, "99999") {
+				t.Errorf("details = %q; want them to name the ZIP code", body.Details)
+			}
+		})
+	}
+}
+
+// fixedLocator is an IPLocator placing every address at one location,
+// recording the address it was asked about
+type fixedLocator struct {
+	loc *models.IPLocation
+	got netip.Addr
+}
+
+func (l *fixedLocator) LocateIP(addr netip.Addr) (*models.IPLocation, error) {
+	l.got = addr
+	return l.loc, nil
+}
+
+func TestGetWeatherByIP(t *testing.T) {
+	newYork := &models.IPLocation{Latitude: 40.7128, Longitude: -74.006, AccuracyKm: 20, City: "New York", Region: "NY", Country: "US"}
+	nws := fakeNWS(t)
+	app := func(l services.IPLocator, opts ...WeatherHandlerOption) *fiber.App {
+		db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		client := services.NewNWSAPIClient(services.WithBaseURL(nws.URL), services.WithHTTPClient(nws.Client()))
+		service := services.NewWeatherService(repository.NewWeatherRepository(db, nil), client, services.WithIPLocator(l))
+		app := fiber.New()
+		app.Get("/api/weather", NewWeatherHandler(service, opts...).GetWeather)
+		return app
+	}
+	trustProxy := WithProxy(config.Proxy{TrustForwardedFor: true})
+
+	tests := []struct {
+		name         string
+		locator      *fixedLocator
+		opts         []WeatherHandlerOption
+		query        string
+		forwardedFor string
+		status       int
+		code         string
+		wantAddr     string // the address located; empty when no lookup is made
+	}{
+		{"disabled", nil, nil, "", "", fiber.StatusBadRequest, models.ErrorCodeMissingLatitude, ""},
+		{"located", &fixedLocator{loc: newYork}, nil, "", "", fiber.StatusOK, "", "0.0.0.0"},
+		// Without TRUST_PROXY a client can't pick the address it is located by
+		{"forwarded header ignored", &fixedLocator{loc: newYork}, nil, "", "81.2.69.142", fiber.StatusOK, "", "0.0.0.0"},
+		{"forwarded header trusted", &fixedLocator{loc: newYork}, []WeatherHandlerOption{trustProxy}, "", "81.2.69.142", fiber.StatusOK, "", "81.2.69.142"},
+		{"not located", &fixedLocator{}, nil, "", "", fiber.StatusBadRequest, models.ErrorCodeIPNotLocated, "0.0.0.0"},
+		{"coordinates given", &fixedLocator{loc: newYork}, nil, "lat=40.7128", "", fiber.StatusBadRequest, models.ErrorCodeMissingLongitude, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var locator services.IPLocator
+			if tt.locator != nil {
+				locator = tt.locator
+			}
+			req := httptest.NewRequest("GET", "/api/weather?"+tt.query, nil)
+			if tt.forwardedFor != "" {
+				req.Header.Set(fiber.HeaderXForwardedFor, tt.forwardedFor)
+			}
+			resp, err := app(locator, tt.opts...).Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d; want %d", resp.StatusCode, tt.status)
+			}
+
+			var body struct {
+				models.WeatherResponse
+				models.ErrorResponse
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.code {
+				t.Errorf("code = %q; want %q", body.Code, tt.code)
+			}
+			if tt.locator != nil {
+				if got := tt.locator.got; (tt.wantAddr == "" && got.IsValid()) || (tt.wantAddr != "" && got.String() != tt.wantAddr) {
+					t.Errorf("located %v; want %q", got, tt.wantAddr)
+				}
+			}
+			if tt.status != fiber.StatusOK {
+				return
+			}
+			if body.ApproximateLocation == nil || *body.ApproximateLocation != *newYork || body.Forecast != "Partly Cloudy" {
+				t.Errorf("body = %+v; want the forecast marked with the approximate location", body.WeatherResponse)
+			}
+			// A shared cache must not hand one caller's location to another
+			if cc := resp.Header.Get(fiber.HeaderCacheControl); !strings.HasPrefix(cc, "private,") {
+				t.Errorf("Cache-Control = %q; want a private response", cc)
+			}
+		})
+	}
+}
+
+func TestGetWeatherBatch(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+	post := func(body string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/weather/batch", strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := post(`[{"lat": 40.7128, "lon": -74.0060}, {"lat": 40.7128}, {"lat": 95, "lon": 0}, {"lat": 35, "lon": -60}]`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d; want 200", resp.StatusCode)
+	}
+	var body models.BatchWeatherResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Results) != 4 {
+		t.Fatalf("got %d results; want 4", len(body.Results))
+	}
+	if w := body.Results[0].Weather; w == nil || w.Forecast != "Partly Cloudy" || body.Results[0].Error != nil {
+		t.Errorf("results[0] = %+v; want the forecast", body.Results[0])
+	}
+	if r := body.Results[0]; r.Lat == nil || *r.Lat != 40.7128 || r.Lon == nil || *r.Lon != -74.0060 {
+		t.Errorf("results[0] does not echo the requested coordinate: %+v", r)
+	}
+	for i, code := range map[int]string{
+		1: models.ErrorCodeMissingLongitude,
+		2: models.ErrorCodeCoordinatesOutOfRange,
+		3: models.ErrorCodeOutOfCoverage,
+	} {
+		if r := body.Results[i]; r.Weather != nil || r.Error == nil || r.Error.Code != code {
+			t.Errorf("results[%d] = %+v; want error %s", i, r, code)
+		}
+	}
+
+	tooMany := strings.Repeat(`{"lat": 40.7128, "lon": -74.0060},`, DefaultMaxBatchSize)
+	for _, tt := range []struct {
+		name, body, code string
+	}{
+		{"not an array", `{"lat": 40.7128, "lon": -74.0060}`, models.ErrorCodeInvalidBatch},
+		{"empty", `[]`, models.ErrorCodeInvalidBatch},
+		{"too large", "[" + tooMany + `{"lat": 40.7128, "lon": -74.0060}]`, models.ErrorCodeBatchTooLarge},
+	} {
+		resp := post(tt.body)
+		var e models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusBadRequest || e.Code != tt.code {
+			t.Errorf("%s: status %d code %q; want 400 %s", tt.name, resp.StatusCode, e.Code, tt.code)
+		}
+	}
+}
+
+func BenchmarkGetWeatherResponseCache(b *testing.B) {
+	for _, tc := range []struct {
+		name string
+		mw   []fiber.Handler
+	}{
+		{"uncached", nil},
+		{"response cache", []fiber.Handler{middleware.ResponseCache(middleware.DefaultResponseCacheConfig())}},
+	} {
+		app := newTestApp(b, fakeNWS(b), tc.mw...)
+		// Warm the data cache so both variants measure the steady state
+		if _, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060", nil)); err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(tc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060", nil)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGetWeatherCacheHit measures a warm /weather request through the
+// request log and recorder middleware, answered from the data cache or from
+// the HTTP response cache. It drives the app's handler directly so the numbers
+// cover our code rather than the test transport.
+func BenchmarkGetWeatherCacheHit(b *testing.B) {
+	for _, tc := range []struct {
+		name string
+		mw   []fiber.Handler
+	}{
+		{"data cache", nil},
+		{"response cache", []fiber.Handler{middleware.ResponseCache(middleware.DefaultResponseCacheConfig())}},
+	} {
+		recorder := metrics.NewRecorder(metrics.DefaultWindow)
+		requestLog := metrics.NewRequestLog(nil, 1)
+		mw := append([]fiber.Handler{recorder.Middleware(), requestLog.Middleware()}, tc.mw...)
+		handler := newTestApp(b, fakeNWS(b), mw...).Handler()
+
+		var ctx fasthttp.RequestCtx
+		ctx.Request.SetRequestURI("/api/weather?lat=40.7128&lon=-74.0060")
+		handler(&ctx)
+		if ctx.Response.StatusCode() != fiber.StatusOK {
+			b.Fatalf("warm-up status = %d", ctx.Response.StatusCode())
+		}
+
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ctx.Response.Reset()
+				handler(&ctx)
+			}
+		})
+	}
+}
+
+func TestGetWeatherShedsWith503(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	var nws *httptest.Server
+	nws = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/points/") {
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 1, "gridY": 1, "forecast": "%s/forecast"}}`, nws.URL)
+			return
+		}
+		started <- struct{}{}
+		<-release
+		fmt.Fprint(w, `{"properties": {"periods": [{"shortForecast": "Sunny", "temperature": 70, "temperatureUnit": "F"}]}}`)
+	}))
+	defer nws.Close()
+	defer close(release)
+
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	service := services.NewWeatherService(repository.NewWeatherRepository(db, nil),
+		services.NewNWSAPIClient(services.WithBaseURL(nws.URL), services.WithHTTPClient(nws.Client())),
+		services.WithLoadShedding(services.LoadSheddingConfig{MaxInFlight: 1, RetryAfter: 1500 * time.Millisecond}),
+	)
+	go service.GetWeather(context.Background(), 40.0, -74.0)
+	<-started
+
+	app := fiber.New()
+	app.Get("/api/weather", NewWeatherHandler(service).GetWeather)
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=41&lon=-75", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != fiber.StatusServiceUnavailable {
+		t.Errorf("status = %d; want 503", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q; want 2 (rounded up)", got)
+	}
+	var body models.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != models.ErrorCodeShed {
+		t.Errorf("code = %q; want %s", body.Code, models.ErrorCodeShed)
+	}
+}
+
+func TestGetWeatherCircuitOpen(t *testing.T) {
+	var requests int32
+	nws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer nws.Close()
+
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	client := services.NewNWSAPIClient(services.WithBaseURL(nws.URL), services.WithHTTPClient(nws.Client()),
+		services.WithRetry(services.RetryConfig{MaxAttempts: 1}),
+		services.WithCircuitBreaker(services.CircuitBreakerConfig{FailureThreshold: 1, OpenDuration: time.Minute}))
+	handler := NewWeatherHandler(services.NewWeatherService(repository.NewWeatherRepository(db, nil), client))
+	app := fiber.New()
+	app.Get("/api/weather", handler.GetWeather)
+	app.Get("/api/health", handler.GetHealth)
+
+	var codes []string
+	for i := 0; i < 2; i++ {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		codes = append(codes, fmt.Sprintf("%d %s", resp.StatusCode, body.Code))
+		if i == 1 && resp.Header.Get("Retry-After") != "60" {
+			t.Errorf("Retry-After = %q; want 60", resp.Header.Get("Retry-After"))
+		}
+	}
+	if want := []string{"502 " + models.ErrorCodeUpstreamUnavailable, "503 " + models.ErrorCodeCircuitOpen}; strings.Join(codes, ",") != strings.Join(want, ",") {
+		t.Errorf("responses = %v; want %v", codes, want)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("%d NWS requests; want 1 before the circuit opened", got)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/health", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var health models.HealthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	}
+	if health.Status != "degraded" || health.Dependencies == nil || health.Dependencies.NWS.Circuit != services.CircuitOpen {
+		t.Errorf("health = %s with %+v; want degraded with the NWS circuit open", health.Status, health.Dependencies)
+	}
+}
+
+func TestGetWeatherUpstreamBackoff(t *testing.T) {
+	var requests int32
+	nws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer nws.Close()
+
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	client := services.NewNWSAPIClient(services.WithBaseURL(nws.URL), services.WithHTTPClient(nws.Client()),
+		services.WithRetry(services.RetryConfig{MaxAttempts: 1}))
+	handler := NewWeatherHandler(services.NewWeatherService(repository.NewWeatherRepository(db, nil), client))
+	app := fiber.New()
+	app.Get("/api/weather", handler.GetWeather)
+	app.Get("/api/health", handler.GetHealth)
+
+	for i := 0; i < 2; i++ {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusServiceUnavailable || body.Code != models.ErrorCodeUpstreamBackoff {
+			t.Errorf("request %d: %d %s; want 503 %s", i+1, resp.StatusCode, body.Code, models.ErrorCodeUpstreamBackoff)
+		}
+		if retry, _ := strconv.Atoi(resp.Header.Get("Retry-After")); retry < 29 || retry > 30 {
+			t.Errorf("request %d: Retry-After = %q; want 30", i+1, resp.Header.Get("Retry-After"))
+		}
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("%d NWS requests; want 1 before the backoff", got)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/health", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var health models.HealthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	}
+	if health.Status != "degraded" || health.Dependencies == nil || health.Dependencies.NWS.BackoffUntil == nil {
+		t.Errorf("health = %s with %+v; want degraded with the NWS backing off", health.Status, health.Dependencies)
+	}
+}
+
+func TestGetWeatherNWSProblems(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		detail      string
+		wantStatus  int
+		wantCode    string
+		wantDetails string
+	}{
+		{"no data for the point", http.StatusNotFound, "Unable to provide data for requested point 40.5,-73.5",
+			fiber.StatusNotFound, models.ErrorCodeOutOfCoverage, "check that the latitude and longitude are correct"},
+		{"other error", http.StatusBadRequest, "Parameter \"point\" is invalid",
+			fiber.StatusBadGateway, models.ErrorCodeUpstreamBadResponse, `NWS points API returned status: 400: Parameter "point" is invalid`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/problem+json")
+				w.WriteHeader(tt.status)
+				json.NewEncoder(w).Encode(map[string]interface{}{"title": "Problem", "status": tt.status, "detail": tt.detail})
+			}))
+			defer nws.Close()
+			app := newTestApp(t, nws)
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.5&lon=-73.5", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body models.ErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || body.Code != tt.wantCode || !strings.Contains(body.Details, tt.wantDetails) {
+				t.Errorf("%d %s %q; want %d %s with details mentioning %q", resp.StatusCode, body.Code, body.Details,
+					tt.wantStatus, tt.wantCode, tt.wantDetails)
+			}
+		})
+	}
+}
+
+// scriptedProvider is a WeatherProvider failing every fetch with err
+type scriptedProvider struct {
+	err error
+}
+
+func (p scriptedProvider) Name() string                       { return "scripted" }
+func (p scriptedProvider) SupportsLocation(_, _ float64) bool { return true }
+func (p scriptedProvider) GetForecast(_ context.Context, _, _ float64) (*models.WeatherCache, error) {
+	return nil, p.err
+}
+
+func TestGetWeatherFailureStatuses(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		breakCache  bool
+		wantStatus  int
+		wantCode    string
+		wantDetails string
+	}{
+		{"provider unreachable", &url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("connection refused")}, false,
+			fiber.StatusBadGateway, models.ErrorCodeUpstreamUnavailable, "connection refused"},
+		{"provider 5xx", &services.ProblemError{API: "NWS forecast", Status: 503}, false,
+			fiber.StatusBadGateway, models.ErrorCodeUpstreamUnavailable, "NWS forecast API returned status: 503"},
+		{"provider 4xx", &services.ProblemError{API: "NWS points", Status: 400, Detail: "Invalid Parameter"}, false,
+			fiber.StatusBadGateway, models.ErrorCodeUpstreamBadResponse, "NWS points API returned status: 400: Invalid Parameter"},
+		{"provider timeout", &url.Error{Op: "Get", URL: "https://example.com", Err: context.DeadlineExceeded}, false,
+			fiber.StatusGatewayTimeout, models.ErrorCodeUpstreamTimeout, "deadline exceeded"},
+		{"internal failure", errors.New("unexpected period layout"), false,
+			fiber.StatusInternalServerError, models.ErrorCodeWeatherUnavailable, "unexpected period layout"},
+		{"internal failure with the cache down", errors.New("unexpected period layout"), true,
+			fiber.StatusInternalServerError, models.ErrorCodeCacheUnavailable, "sql: database is closed"},
+		{"provider down with the cache down", &services.ProblemError{API: "NWS forecast", Status: 500}, true,
+			fiber.StatusBadGateway, models.ErrorCodeUpstreamUnavailable, "NWS forecast API returned status: 500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			service := services.NewWeatherService(repository.NewWeatherRepository(db, nil), scriptedProvider{tt.err})
+			app := fiber.New()
+			app.Get("/api/weather", NewWeatherHandler(service).GetWeather)
+			if tt.breakCache {
+				db.Close()
+			}
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.006", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body models.ErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || body.Code != tt.wantCode || !strings.Contains(body.Details, tt.wantDetails) {
+				t.Errorf("%d %s %q; want %d %s with details mentioning %q", resp.StatusCode, body.Code, body.Details,
+					tt.wantStatus, tt.wantCode, tt.wantDetails)
+			}
+		})
+	}
+}
+
+func TestGetWeatherRequestTimeout(t *testing.T) {
+	aborted := make(chan struct{}, 1)
+	nws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		aborted <- struct{}{}
+	}))
+	defer nws.Close()
+
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	repo := repository.NewWeatherRepository(db, nil)
+	stale := &models.WeatherCache{Latitude: 40.7128, Longitude: -74.006, Forecast: "Fog", TempC: 8, TempF: 46.4, Timestamp: time.Now().Add(-services.DefaultMaxStale - time.Hour)}
+	if err := repo.SaveToCache(context.Background(), stale); err != nil {
+		t.Fatal(err)
+	}
+	service := services.NewWeatherService(repo,
+		services.NewNWSAPIClient(services.WithBaseURL(nws.URL), services.WithHTTPClient(nws.Client())))
+
+	app := fiber.New()
+	app.Get("/api/weather", NewWeatherHandler(service, WithRequestTimeout(50*time.Millisecond)).GetWeather)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantCache  string
+	}{
+		{"stale entry", "lat=40.7128&lon=-74.006", fiber.StatusOK, "STALE"},
+		{"no entry", "lat=39.9526&lon=-75.1652", fiber.StatusGatewayTimeout, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?"+tt.query, nil), 5000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("response took %v; want it at the request timeout", elapsed)
+			}
+			select {
+			case <-aborted:
+			case <-time.After(time.Second):
+				t.Error("upstream request was not aborted")
+			}
+			if resp.StatusCode != tt.wantStatus || resp.Header.Get(CacheStatusHeader) != tt.wantCache {
+				t.Errorf("status = %d, X-Cache = %q; want %d, %q", resp.StatusCode, resp.Header.Get(CacheStatusHeader), tt.wantStatus, tt.wantCache)
+			}
+		})
+	}
+}
+
+func TestWeatherXML(t *testing.T) {
+	var nws *httptest.Server
+	nws = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/points/") {
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35, "forecast": "%s/gridpoints/OKX/33,35/forecast"}}`, nws.URL)
+			return
+		}
+		fmt.Fprint(w, `{"properties": {"periods": [
+			{"name": "Tonight", "startTime": "2099-01-15T18:00:00-05:00", "endTime": "2099-01-16T06:00:00-05:00",
+			 "isDaytime": false, "shortForecast": "Rain & Snow", "temperature": 28, "temperatureUnit": "F"}
+		]}}`)
+	}))
+	defer nws.Close()
+	app := newTestApp(t, nws)
+
+	tests := []struct {
+		name       string
+		target     string
+		accept     string
+		wantStatus int
+		check      func(t *testing.T, body []byte)
+	}{
+		{"weather", "/api/weather?lat=40.7128&lon=-74.0060", "application/xml", fiber.StatusOK, func(t *testing.T, body []byte) {
+			var weather models.WeatherResponse
+			if err := xml.Unmarshal(body, &weather); err != nil {
+				t.Fatalf("decoding %s: %v", body, err)
+			}
+			if weather.XMLName.Local != "weather" || weather.Forecast != "Rain & Snow" || weather.TemperatureF == nil || *weather.TemperatureF != 28 || weather.CachedAt == nil {
+				t.Errorf("weather = %+v; want <weather> with the forecast", weather)
+			}
+		}},
+		{"forecast", "/api/forecast?lat=40.7128&lon=-74.0060&format=xml", "", fiber.StatusOK, func(t *testing.T, body []byte) {
+			var forecast models.ForecastResponse
+			if err := xml.Unmarshal(body, &forecast); err != nil {
+				t.Fatalf("decoding %s: %v", body, err)
+			}
+			if forecast.XMLName.Local != "forecast" || len(forecast.Periods) != 1 || forecast.Periods[0].Name != "Tonight" || forecast.Periods[0].TempF != 28 {
+				t.Errorf("forecast = %+v; want <forecast> with the Tonight period", forecast)
+			}
+			if !strings.Contains(string(body), "<periods><period>") {
+				t.Errorf("body = %s; want periods wrapped in <periods>", body)
+			}
+		}},
+		{"validation error", "/api/weather?lat=north&lon=-74.0060", "text/xml", fiber.StatusBadRequest, func(t *testing.T, body []byte) {
+			var e models.ErrorResponse
+			if err := xml.Unmarshal(body, &e); err != nil {
+				t.Fatalf("decoding %s: %v", body, err)
+			}
+			if e.XMLName.Local != "error" || e.Code != models.ErrorCodeInvalidLatitude || e.Error == "" {
+				t.Errorf("error = %+v; want <error> with code %s", e, models.ErrorCodeInvalidLatitude)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			if tt.accept != "" {
+				req.Header.Set(fiber.HeaderAccept, tt.accept)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d; want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if ct := resp.Header.Get(fiber.HeaderContentType); !strings.HasPrefix(ct, fiber.MIMEApplicationXML) {
+				t.Errorf("Content-Type = %q; want XML", ct)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			tt.check(t, body)
+		})
+	}
+}
+
+func TestCSVExport(t *testing.T) {
+	var nws *httptest.Server
+	nws = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/points/") {
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35, "forecast": "%s/gridpoints/OKX/33,35/forecast",
+				"timeZone": "America/New_York"}}`, nws.URL)
+			return
+		}
+		// Forecast text with a comma and quotes must survive CSV quoting
+		fmt.Fprint(w, `{"properties": {"periods": [
+			{"name": "Tonight", "startTime": "2099-01-15T18:00:00-05:00", "endTime": "2099-01-16T06:00:00-05:00",
+			 "shortForecast": "Rain, then \"Snow\"", "temperature": 32, "temperatureUnit": "F",
+			 "windSpeed": "5 to 10 mph", "windDirection": "NW", "probabilityOfPrecipitation": {"value": 80}},
+			{"name": "Friday", "startTime": "2099-01-16T06:00:00-05:00", "endTime": "2099-01-16T18:00:00-05:00",
+			 "isDaytime": true, "shortForecast": "Sunny", "temperature": 41, "temperatureUnit": "F",
+			 "probabilityOfPrecipitation": {"value": null}}
+		]}}`)
+	}))
+	defer nws.Close()
+	app := newTestApp(t, nws)
+
+	// Caching a forecast records today's history sample
+	if _, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	today := time.Now().UTC()
+	header := []string{"timestamp", "latitude", "longitude", "period", "short_forecast", "temp_c", "temp_f", "wind", "precipitation_probability"}
+	tests := []struct {
+		name         string
+		target       string
+		wantFilename string
+		wantRows     [][]string
+	}{
+		{
+			"forecast", "/api/forecast?lat=40.7128&lon=-74.0060&format=csv",
+			"forecast_40.7128_-74.006_" + today.Format(time.DateOnly) + ".csv",
+			[][]string{
+				header,
+				{"2099-01-15T18:00:00-05:00", "40.7128", "-74.006", "Tonight", `Rain, then "Snow"`, "0", "32", "NW 5 to 10 mph", "80"},
+				{"2099-01-16T06:00:00-05:00", "40.7128", "-74.006", "Friday", "Sunny", "5", "41", "", ""},
+			},
+		},
+		{
+			"history", "/api/weather/history?lat=40.7128&lon=-74.0060&format=CSV",
+			"history_40.7128_-74.006_" + today.AddDate(0, 0, 1-services.DefaultHistoryDays).Format(time.DateOnly) + "_" + today.Format(time.DateOnly) + ".csv",
+			[][]string{
+				header,
+				{today.Format(time.DateOnly) + "T00:00:00Z", "40.7128", "-74.006", "", `Rain, then "Snow"`, "0", "32", "", ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tt.target, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != fiber.StatusOK {
+				t.Fatalf("status = %d; want 200", resp.StatusCode)
+			}
+			if ct := resp.Header.Get(fiber.HeaderContentType); ct != "text/csv; charset=utf-8" {
+				t.Errorf("Content-Type = %q; want text/csv", ct)
+			}
+			if cd, want := resp.Header.Get(fiber.HeaderContentDisposition), `attachment; filename="`+tt.wantFilename+`"`; cd != want {
+				t.Errorf("Content-Disposition = %q; want %q", cd, want)
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			if !strings.Contains(string(body), `"Rain, then ""Snow"""`) {
+				t.Errorf("body = %s; want the forecast quoted with doubled quotes", body)
+			}
+			rows, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+			if err != nil {
+				t.Fatalf("parsing %s: %v", body, err)
+			}
+			if !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("rows = %q; want %q", rows, tt.wantRows)
+			}
+		})
+	}
+
+	// Errors are not tabular and stay JSON
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/forecast?lat=91&lon=-74&format=csv", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := resp.Header.Get(fiber.HeaderContentType); resp.StatusCode != fiber.StatusBadRequest || !strings.HasPrefix(ct, fiber.MIMEApplicationJSON) {
+		t.Errorf("invalid coordinates: status %d, Content-Type %q; want a JSON 400", resp.StatusCode, ct)
+	}
+}
+
+func TestGetWeatherHistory(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	// Caching a forecast records today's first history sample
+	if _, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query    string
+		wantCode int
+		wantDays int
+	}{
+		{"?lat=40.7128&lon=-74.0060", fiber.StatusOK, 1},
+		{"?lat=40.7128&lon=-74.0060&from=2024-01-01&to=2024-01-31", fiber.StatusOK, 0},
+		{"?lat=41&lon=-74", fiber.StatusOK, 0},
+		{"?lat=91&lon=-74", fiber.StatusBadRequest, 0},
+		{"?lon=-74", fiber.StatusBadRequest, 0},
+		{"?lat=40.7128&lon=-74.0060&from=yesterday", fiber.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/weather/history"+tt.query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.wantCode {
+			t.Errorf("%s: status = %d; want %d", tt.query, resp.StatusCode, tt.wantCode)
+			continue
+		}
+		if tt.wantCode != fiber.StatusOK {
+			continue
+		}
+		var body models.WeatherHistoryResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.Days) != tt.wantDays {
+			t.Errorf("%s: got %d days; want %d", tt.query, len(body.Days), tt.wantDays)
+		}
+	}
+}
+
+func TestGetWeatherPathCoordinates(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	tests := []struct {
+		path     string
+		wantCode int
+		wantErr  string
+	}{
+		{"/api/weather/40.7128/-74.0060", fiber.StatusOK, ""},
+		{"/api/weather/40.7128/-74.0060/", fiber.StatusOK, ""},
+		{"/api/weather/40.7128/%2D74.0060", fiber.StatusOK, ""},
+		{"/api/forecast/40.7128/-74.0060", fiber.StatusOK, ""},
+		{"/api/weather/north/-74.0060", fiber.StatusBadRequest, models.ErrorCodeInvalidLatitude},
+		{"/api/weather/40.7128/west", fiber.StatusBadRequest, models.ErrorCodeInvalidLongitude},
+		{"/api/forecast/40.7128/%2Dwest", fiber.StatusBadRequest, models.ErrorCodeInvalidLongitude},
+		{"/api/weather/95/-74.0060", fiber.StatusBadRequest, models.ErrorCodeCoordinatesOutOfRange},
+		{"/api/forecast/40.7128/%2D181", fiber.StatusBadRequest, models.ErrorCodeCoordinatesOutOfRange},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.wantCode {
+			t.Errorf("%s: status = %d; want %d", tt.path, resp.StatusCode, tt.wantCode)
+			continue
+		}
+		if tt.wantErr == "" {
+			continue
+		}
+		var body models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Code != tt.wantErr {
+			t.Errorf("%s: code = %q; want %q", tt.path, body.Code, tt.wantErr)
+		}
+	}
+
+	// Both forms answer with the same forecast
+	query, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := app.Test(httptest.NewRequest("GET", "/api/weather/40.7128/-74.0060", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromQuery, fromPath models.WeatherResponse
+	json.NewDecoder(query.Body).Decode(&fromQuery)
+	json.NewDecoder(path.Body).Decode(&fromPath)
+	if fromPath.Forecast != fromQuery.Forecast || fromPath.Temperature != fromQuery.Temperature {
+		t.Errorf("path form = %+v; want the query form's %+v", fromPath, fromQuery)
+	}
+}
+
+func TestPostWeather(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	post := func(contentType, body string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/weather?units=imperial", strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set(fiber.HeaderContentType, contentType)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// Unknown fields are ignored, and query options still apply
+	resp := post("application/json; charset=utf-8", `{"latitude": 40.7128, "longitude": -74.0060, "label": "office"}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d; want 200", resp.StatusCode)
+	}
+	var posted models.WeatherResponse
+	if err := json.NewDecoder(resp.Body).Decode(&posted); err != nil {
+		t.Fatal(err)
+	}
+	query, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060&units=imperial", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromQuery models.WeatherResponse
+	json.NewDecoder(query.Body).Decode(&fromQuery)
+	if posted.Forecast != fromQuery.Forecast || posted.TemperatureF == nil || posted.TemperatureC != nil {
+		t.Errorf("posted = %+v; want the query form's %+v in imperial units", posted, fromQuery)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    string
+		wantDetails string
+	}{
+		{"form body", "application/x-www-form-urlencoded", "latitude=40.7128&longitude=-74.0060", fiber.StatusUnsupportedMediaType, models.ErrorCodeUnsupportedMediaType, ""},
+		{"no content type", "", `{"latitude": 40.7128, "longitude": -74.0060}`, fiber.StatusUnsupportedMediaType, models.ErrorCodeUnsupportedMediaType, ""},
+		{"malformed", "application/json", `{"latitude": 40.7128,`, fiber.StatusBadRequest, models.ErrorCodeInvalidCoordinatesBody, "unexpected end of JSON input"},
+		{"wrong type", "application/json", `{"latitude": "north", "longitude": -74.0060}`, fiber.StatusBadRequest, models.ErrorCodeInvalidCoordinatesBody, "latitude"},
+		{"missing latitude", "application/json", `{"longitude": -74.0060}`, fiber.StatusBadRequest, models.ErrorCodeMissingLatitude, ""},
+		{"missing longitude", "application/json", `{"latitude": 40.7128}`, fiber.StatusBadRequest, models.ErrorCodeMissingLongitude, ""},
+		{"out of range", "application/json", `{"latitude": 95, "longitude": -74.0060}`, fiber.StatusBadRequest, models.ErrorCodeCoordinatesOutOfRange, ""},
+	}
+	for _, tt := range tests {
+		resp := post(tt.contentType, tt.body)
+		if resp.StatusCode != tt.wantStatus {
+			t.Errorf("%s: status = %d; want %d", tt.name, resp.StatusCode, tt.wantStatus)
+			continue
+		}
+		var body models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Code != tt.wantCode {
+			t.Errorf("%s: code = %q; want %q", tt.name, body.Code, tt.wantCode)
+		}
+		if !strings.Contains(body.Details, tt.wantDetails) {
+			t.Errorf("%s: details = %q; want the parse error %q", tt.name, body.Details, tt.wantDetails)
+		}
+	}
+}
+
+func TestGetWeatherAtValidation(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	tests := []struct {
+		at       string
+		wantCode int
+	}{
+		{"next-tuesday", fiber.StatusBadRequest},
+		{"2024-06-01", fiber.StatusBadRequest},
+		{"2000-01-01T00:00:00Z", fiber.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060&at="+tt.at, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.wantCode {
+			t.Errorf("at=%s: status = %d; want %d", tt.at, resp.StatusCode, tt.wantCode)
+		}
+	}
+}
+
+func TestGetWeatherLocalizedError(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+
+	req := httptest.NewRequest("GET", "/api/weather?lat=95&lon=-74", nil)
+	req.Header.Set("Accept-Language", "es-MX,es;q=0.9")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body models.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != models.ErrorCodeCoordinatesOutOfRange || body.Error != "Coordenadas no válidas" {
+		t.Errorf("body = %+v; want Spanish text with code %s", body, models.ErrorCodeCoordinatesOutOfRange)
+	}
+	if lang := resp.Header.Get("Content-Language"); lang != "es" {
+		t.Errorf("Content-Language = %q; want es", lang)
+	}
+}
+
+func TestGetCachedWeather(t *testing.T) {
+	var upstreamCalls int32
+	var nws *httptest.Server
+	nws = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&upstreamCalls, 1)
+		if strings.HasPrefix(r.URL.Path, "/points/") {
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35, "forecast": "%s/forecast"}}`, nws.URL)
+			return
+		}
+		fmt.Fprint(w, `{"properties": {"periods": [{"shortForecast": "Sunny", "temperature": 70, "temperatureUnit": "F"}]}}`)
+	}))
+	defer nws.Close()
+
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	repo := repository.NewWeatherRepository(db, nil)
+	handler := NewWeatherHandler(services.NewWeatherService(repo,
+		services.NewNWSAPIClient(services.WithBaseURL(nws.URL), services.WithHTTPClient(nws.Client()))))
+
+	app := fiber.New()
+	app.Get("/api/weather", handler.GetWeather)
+	app.Get("/api/weather/cached", handler.GetCachedWeather)
+
+	// Warm 40.7128,-74.0060; a stale entry for 34.0522,-118.2437 is not a hit
+	if _, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060", nil)); err != nil {
+		t.Fatal(err)
+	}
+	err = repo.SaveToCache(context.Background(), &models.WeatherCache{
+		Latitude: 34.0522, Longitude: -118.2437, Forecast: "Sunny", Timestamp: time.Now().Add(-2 * time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A point across the warmed grid cell, beyond the nearby cache radius, has
+	// no entry of its own
+	err = repo.SaveGridPoint(context.Background(), &models.GridPoint{
+		Latitude: 40.734, Longitude: -74.02, GridID: "OKX", GridX: 33, GridY: 35,
+		ForecastURL: nws.URL + "/forecast", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	warmed := atomic.LoadInt32(&upstreamCalls)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantCode   int
+		wantSource string
+	}{
+		{"fresh", "?lat=40.7128&lon=-74.0060", fiber.StatusNoContent, "sqlite"},
+		// Normalizes to the warmed coordinate's cache entry
+		{"fresh nearby", "?lat=40.71281&lon=-74.00601", fiber.StatusNoContent, "sqlite"},
+		{"fresh within the nearby cache radius", "?lat=40.7200&lon=-74.0060", fiber.StatusNoContent, "nearby:sqlite"},
+		{"fresh grid cell", "?lat=40.7340&lon=-74.0200", fiber.StatusNoContent, "grid:sqlite"},
+		{"stale", "?lat=34.0522&lon=-118.2437", fiber.StatusNotFound, ""},
+		{"absent", "?lat=41&lon=-75", fiber.StatusNotFound, ""},
+		{"invalid", "?lat=95&lon=-75", fiber.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		for _, method := range []string{"HEAD", "GET"} {
+			resp, err := app.Test(httptest.NewRequest(method, "/api/weather/cached"+tt.query, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("%s %s: status = %d; want %d", method, tt.name, resp.StatusCode, tt.wantCode)
+				continue
+			}
+			if got := resp.Header.Get("X-Data-Source"); got != tt.wantSource {
+				t.Errorf("%s %s: X-Data-Source = %q; want %q", method, tt.name, got, tt.wantSource)
+			}
+			if age := resp.Header.Get("Age"); (tt.wantSource != "") != (age != "") {
+				t.Errorf("%s %s: Age = %q; want it set only for a hit", method, tt.name, age)
+			}
+		}
+	}
+	if calls := atomic.LoadInt32(&upstreamCalls); calls != warmed {
+		t.Errorf("cache checks made %d NWS calls; want none", calls-warmed)
+	}
+
+	// A 204 predicts that /weather answers without an upstream fetch
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.71281&lon=-74.00601", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK || atomic.LoadInt32(&upstreamCalls) != warmed {
+		t.Errorf("/weather after a 204: status %d with %d NWS calls; want 200 with none",
+			resp.StatusCode, atomic.LoadInt32(&upstreamCalls)-warmed)
+	}
+}
+
+func TestGetForecast(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantPeriods []string
+	}{
+		{
+			name: "periods",
+			body: `{"properties": {"periods": [
+				{"name": "Tonight", "startTime": "2024-01-15T18:00:00-05:00", "endTime": "2024-01-16T06:00:00-05:00",
+				 "isDaytime": false, "shortForecast": "Mostly Clear", "detailedForecast": "Mostly clear, with a low around 28.",
+				 "temperature": 28, "temperatureUnit": "F"},
+				{"name": "Tuesday", "startTime": "2024-01-16T06:00:00-05:00", "endTime": "2024-01-16T18:00:00-05:00",
+				 "isDaytime": true, "shortForecast": "Sunny", "detailedForecast": "Sunny, with a high near 41.",
+				 "temperature": 41, "temperatureUnit": "F", "probabilityOfPrecipitation": {"unitCode": "wmoUnit:percent", "value": 10}}
+			]}}`,
+			wantStatus:  fiber.StatusOK,
+			wantPeriods: []string{"Tonight", "Tuesday"},
+		},
+		{"empty periods", `{"properties": {"periods": []}}`, fiber.StatusBadGateway, nil},
+		{"malformed JSON", `{"properties": {"periods": [{"name": `, fiber.StatusBadGateway, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var forecastCalls int32
+			var nws *httptest.Server
+			nws = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasPrefix(r.URL.Path, "/points/") {
+					fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35, "forecast": "%s/forecast"}}`, nws.URL)
+					return
+				}
+				atomic.AddInt32(&forecastCalls, 1)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer nws.Close()
+			app := newTestApp(t, nws)
+
+			// The second request is answered from the cached periods when the first succeeded
+			for i := 0; i < 2; i++ {
+				resp, err := app.Test(httptest.NewRequest("GET", "/api/forecast?lat=40.7128&lon=-74.0060", nil))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.StatusCode != tt.wantStatus {
+					t.Fatalf("request %d: status = %d; want %d", i+1, resp.StatusCode, tt.wantStatus)
+				}
+				if tt.wantStatus != fiber.StatusOK {
+					var body models.ErrorResponse
+					if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+						t.Fatal(err)
+					}
+					if body.Code != models.ErrorCodeUpstreamBadResponse {
+						t.Errorf("request %d: code = %q; want %s", i+1, body.Code, models.ErrorCodeUpstreamBadResponse)
+					}
+					continue
+				}
+
+				var body models.ForecastResponse
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatal(err)
+				}
+				if len(body.Periods) != len(tt.wantPeriods) {
+					t.Fatalf("request %d: got %d periods; want %d", i+1, len(body.Periods), len(tt.wantPeriods))
+				}
+				for j, name := range tt.wantPeriods {
+					if body.Periods[j].Name != name {
+						t.Errorf("request %d: period %d = %q; want %q", i+1, j, body.Periods[j].Name, name)
+					}
+				}
+				tonight, tuesday := body.Periods[0], body.Periods[1]
+				if tonight.IsDaytime || !tuesday.IsDaytime || tonight.DetailedForecast == "" {
+					t.Errorf("request %d: tonight = %+v, tuesday = %+v; want daytime flags and detailed forecasts", i+1, tonight, tuesday)
+				}
+				if tonight.TempF != 28 || math.Abs(tonight.TempC+2.2) > 0.1 {
+					t.Errorf("request %d: tonight temps = %vF/%vC; want 28F/-2.2C", i+1, tonight.TempF, tonight.TempC)
+				}
+				if p := tuesday.PrecipitationProbability; p == nil || *p != 10 {
+					t.Errorf("request %d: tuesday precipitation = %v; want 10", i+1, p)
+				}
+			}
+
+			// Failed fetches are not cached, so each request retries upstream
+			wantCalls := int32(2)
+			if tt.wantStatus == fiber.StatusOK {
+				wantCalls = 1
+			}
+			if calls := atomic.LoadInt32(&forecastCalls); calls != wantCalls {
+				t.Errorf("forecast fetched %d times; want %d", calls, wantCalls)
+			}
+		})
+	}
+}
+
+func TestGetHourlyForecast(t *testing.T) {
+	start := time.Now().Truncate(time.Hour)
+	var hours []string
+	for h := -1; h < 3; h++ {
+		s := start.Add(time.Duration(h) * time.Hour)
+		hours = append(hours, fmt.Sprintf(`{"startTime": %q, "endTime": %q, "shortForecast": "Sunny",
+			"temperature": %d, "temperatureUnit": "F", "windSpeed": "10 mph", "windDirection": "NW"}`,
+			s.Format(time.RFC3339), s.Add(time.Hour).Format(time.RFC3339), 50+h))
+	}
+
+	tests := []struct {
+		name       string
+		hourly     bool
+		wantStatus int
+		wantCode   string
+	}{
+		{"hourly URL", true, fiber.StatusOK, ""},
+		{"missing hourly URL", false, fiber.StatusNotFound, models.ErrorCodeNoHourlyForecast},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hourlyCalls int32
+			var nws *httptest.Server
+			nws = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.HasPrefix(r.URL.Path, "/points/"):
+					hourlyURL := ""
+					if tt.hourly {
+						hourlyURL = nws.URL + "/gridpoints/OKX/33,35/forecast/hourly"
+					}
+					fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35,
+						"forecast": "%s/gridpoints/OKX/33,35/forecast", "forecastHourly": %q}}`, nws.URL, hourlyURL)
+				case strings.HasSuffix(r.URL.Path, "/forecast/hourly"):
+					atomic.AddInt32(&hourlyCalls, 1)
+					fmt.Fprintf(w, `{"properties": {"periods": [%s]}}`, strings.Join(hours, ","))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer nws.Close()
+			app := newTestApp(t, nws)
+
+			for i := 0; i < 2; i++ {
+				resp, err := app.Test(httptest.NewRequest("GET", "/api/weather/hourly?lat=40.7128&lon=-74.0060", nil))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.StatusCode != tt.wantStatus {
+					t.Fatalf("request %d: status = %d; want %d", i+1, resp.StatusCode, tt.wantStatus)
+				}
+				if tt.wantCode != "" {
+					var body models.ErrorResponse
+					if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+						t.Fatal(err)
+					}
+					if body.Code != tt.wantCode {
+						t.Errorf("request %d: code = %q; want %s", i+1, body.Code, tt.wantCode)
+					}
+					continue
+				}
+
+				if cc := resp.Header.Get(fiber.HeaderCacheControl); !strings.HasPrefix(cc, "public, max-age=") {
+					t.Errorf("request %d: Cache-Control = %q; want public max-age", i+1, cc)
+				}
+				var body models.HourlyForecastResponse
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatal(err)
+				}
+				// The hour that has already ended is dropped
+				if len(body.Hours) != 3 {
+					t.Fatalf("request %d: got %d hours; want 3", i+1, len(body.Hours))
+				}
+				first := body.Hours[0]
+				if !first.Time.Equal(start) || first.TempF != 50 || first.WindSpeed != "10 mph" || first.WindDirection != "NW" {
+					t.Errorf("request %d: first hour = %+v; want %v at 50F with a 10 mph NW wind", i+1, first, start)
+				}
+				if math.Abs(first.TempC-10) > 0.1 {
+					t.Errorf("request %d: first hour temp_c = %v; want 10", i+1, first.TempC)
+				}
+			}
+
+			if tt.hourly {
+				// A chosen wind unit is served from the same cached hours
+				resp, err := app.Test(httptest.NewRequest("GET", "/api/weather/hourly?lat=40.7128&lon=-74.0060&wind_unit=kn", nil))
+				if err != nil {
+					t.Fatal(err)
+				}
+				var body models.HourlyForecastResponse
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatal(err)
+				}
+				if len(body.Hours) == 0 || body.Hours[0].WindSpeed != "8.7 kn" {
+					t.Errorf("hours = %+v; want an 8.7 kn wind", body.Hours)
+				}
+			}
+
+			wantCalls := int32(0)
+			if tt.hourly {
+				wantCalls = 1
+			}
+			if calls := atomic.LoadInt32(&hourlyCalls); calls != wantCalls {
+				t.Errorf("hourly forecast fetched %d times; want %d", calls, wantCalls)
+			}
+		})
+	}
+}
+
+func TestGetAlertsEmpty(t *testing.T) {
+	nws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type": "FeatureCollection", "features": [], "title": "Current watches, warnings, and advisories"}`)
+	}))
+	defer nws.Close()
+	app := newTestApp(t, nws)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/alerts?lat=40.7128&lon=-74.0060", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d; want 200", resp.StatusCode)
+	}
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(body["alerts"]); got != "[]" {
+		t.Errorf("alerts = %s; want an empty array", got)
+	}
+}
+
+func TestGetAlertsFilters(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Format(time.RFC3339)
+	var fetches int32
+	nws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		fmt.Fprintf(w, `{"type": "FeatureCollection", "features": [
+			{"properties": {"id": "tor", "event": "Tornado Warning", "severity": "Extreme", "urgency": "Immediate", "expires": %[1]q}},
+			{"properties": {"id": "svr", "event": "Severe Thunderstorm Warning", "severity": "Severe", "urgency": "Immediate", "expires": %[1]q}},
+			{"properties": {"id": "mws", "event": "Marine Weather Statement", "severity": "Minor", "urgency": "Expected", "expires": %[1]q}}
+		]}`, expires)
+	}))
+	defer nws.Close()
+	app := newTestApp(t, nws)
+
+	tests := []struct {
+		query   string
+		status  int
+		want    string // kept alert IDs, or the error code
+		details string // text the error details must contain
+	}{
+		{"", fiber.StatusOK, "tor,svr,mws", ""},
+		{"&severity=Severe,Extreme", fiber.StatusOK, "tor,svr", ""},
+		{"&severity=severe,extreme&event=tornado", fiber.StatusOK, "tor", ""},
+		{"&urgency=Expected&event=Warning", fiber.StatusOK, "", ""},
+		{"&severity=Severe,Bad", fiber.StatusBadRequest, models.ErrorCodeInvalidAlertSeverity, "Extreme, Severe, Moderate, Minor, Unknown"},
+		{"&urgency=Soon", fiber.StatusBadRequest, models.ErrorCodeInvalidAlertUrgency, "Immediate, Expected, Future, Past, Unknown"},
+		{"&active_only=maybe", fiber.StatusBadRequest, models.ErrorCodeInvalidActiveOnly, ""},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/alerts?lat=40.7128&lon=-74.0060"+tt.query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%q: status = %d; want %d", tt.query, resp.StatusCode, tt.status)
+			continue
+		}
+		if tt.status != fiber.StatusOK {
+			var body models.ErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.want || !strings.Contains(body.Details, tt.details) {
+				t.Errorf("%q: error %s (%s); want %s listing %q", tt.query, body.Code, body.Details, tt.want, tt.details)
+			}
+			continue
+		}
+		var body models.AlertsResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, alert := range body.Alerts {
+			ids = append(ids, alert.ID)
+		}
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("%q: alerts %q; want %q", tt.query, got, tt.want)
+		}
+	}
+
+	// Every filter is served from the one cached fetch
+	if got := atomic.LoadInt32(&fetches); got != 1 {
+		t.Errorf("%d upstream fetches; want 1", got)
+	}
+}
+
+func TestGetAlertsGeometry(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Format(time.RFC3339)
+	nws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/alerts/active":
+			fmt.Fprintf(w, `{"type": "FeatureCollection", "features": [
+				{"geometry": {"type": "Polygon", "coordinates": [[[-74.13, 40.79], [-73.93, 40.84], [-73.86, 40.69], [-74.13, 40.79]]]},
+					"properties": {"id": "svr", "event": "Severe Thunderstorm Warning", "expires": %[1]q}},
+				{"geometry": null, "properties": {"id": "heat", "event": "Heat Advisory", "affectedZones": ["https://api.weather.gov/zones/forecast/NYZ072"], "expires": %[1]q}}
+			]}`, expires)
+		case "/zones/forecast/NYZ072":
+			fmt.Fprint(w, `{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [[[-74.02, 40.70], [-73.97, 40.71], [-73.93, 40.80], [-74.02, 40.70]]]}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer nws.Close()
+	app := newTestApp(t, nws)
+
+	tests := []struct {
+		query  string
+		status int
+		want   string // each alert's geometry type, or the error code
+	}{
+		{"", fiber.StatusOK, "none,none"},
+		{"&geometry=false", fiber.StatusOK, "none,none"},
+		{"&geometry=true", fiber.StatusOK, "Polygon,Polygon"},
+		{"&geometry=yes", fiber.StatusBadRequest, models.ErrorCodeInvalidGeometry},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/alerts?lat=40.7128&lon=-74.0060"+tt.query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%q: status = %d; want %d", tt.query, resp.StatusCode, tt.status)
+			continue
+		}
+		if tt.status != fiber.StatusOK {
+			var body models.ErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.want {
+				t.Errorf("%q: error %s; want %s", tt.query, body.Code, tt.want)
+			}
+			continue
+		}
+		var body models.AlertsResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		var types []string
+		for _, alert := range body.Alerts {
+			if alert.Geometry == nil {
+				types = append(types, "none")
+			} else {
+				types = append(types, alert.Geometry.Type)
+			}
+		}
+		if got := strings.Join(types, ","); got != tt.want {
+			t.Errorf("%q: geometries %s; want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestGetWeatherTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	// Record the trace context the NWS receives
+	var upstreamParents []string
+	nws := fakeNWS(t)
+	traced := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamParents = append(upstreamParents, r.Header.Get("traceparent"))
+		nws.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(traced.Close)
+	nws.URL = traced.URL
+
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	repo := repository.NewWeatherRepository(db, rdb)
+	client := services.NewNWSAPIClient(services.WithBaseURL(traced.URL), services.WithHTTPClient(traced.Client()))
+	handler := NewWeatherHandler(services.NewWeatherService(repo, client))
+	app := fiber.New()
+	app.Group(APIBasePath, tracing.Middleware()).Get("/weather", handler.GetWeather)
+
+	// The caller's trace is continued
+	const callerTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060", nil)
+	req.Header.Set("traceparent", "00-"+callerTrace+"-00f067aa0ba902b7-01")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d; want 200", resp.StatusCode)
+	}
+
+	spans := exporter.GetSpans()
+	var server tracetest.SpanStub
+	for _, s := range spans {
+		if s.SpanKind == trace.SpanKindServer {
+			server = s
+		}
+	}
+	if server.Name != "GET /api/weather" {
+		t.Fatalf("server span = %q; want GET /api/weather", server.Name)
+	}
+	if got := server.SpanContext.TraceID().String(); got != callerTrace {
+		t.Errorf("server span trace = %s; want the caller's %s", got, callerTrace)
+	}
+
+	// Every other span is a direct child of the server span, in call order
+	sort.Slice(spans, func(i, j int) bool { return spans[i].StartTime.Before(spans[j].StartTime) })
+	var names []string
+	for _, s := range spans {
+		if s.SpanKind == trace.SpanKindServer {
+			continue
+		}
+		if s.Parent.SpanID() != server.SpanContext.SpanID() {
+			t.Errorf("%s: parent = %s; want the server span %s", s.Name, s.Parent.SpanID(), server.SpanContext.SpanID())
+		}
+		names = append(names, s.Name)
+	}
+	want := []string{
+		"redis.get", "sqlite.query", "sqlite.nearby", // coordinate cache miss
+		"redis.get", "sqlite.query", // grid cell cache miss
+		"redis.get",                // not known to be outside coverage
+		"nws.points", "cache.save", // grid cell resolved
+		"redis.get", "sqlite.query", "nws.forecast", "cache.save", // grid forecast fetched
+		"redis.get", "sqlite.query", "sqlite.nearby", // no previous entry to compare the temperature with
+		"cache.save", // coordinate cached
+	}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("child spans = %v; want %v", names, want)
+	}
+
+	attrs := func(s tracetest.SpanStub) map[attribute.Key]attribute.Value {
+		m := map[attribute.Key]attribute.Value{}
+		for _, kv := range s.Attributes {
+			m[kv.Key] = kv.Value
+		}
+		return m
+	}
+	for i, tier := range []string{repository.SourceRedis, repository.SourceSQLite} {
+		a := attrs(spans[i+1])
+		if a[tracing.CacheTierKey].AsString() != tier || a[tracing.CacheHitKey].AsBool() {
+			t.Errorf("%s: cache tier %q, hit %v; want a %s miss", spans[i+1].Name, a[tracing.CacheTierKey].AsString(), a[tracing.CacheHitKey].AsBool(), tier)
+		}
+	}
+	if a := attrs(spans[len(spans)-1]); a[tracing.LatitudeKey].AsFloat64() != 40.713 || a[tracing.LongitudeKey].AsFloat64() != -74.006 {
+		t.Errorf("cache.save coordinate = %v,%v; want 40.713,-74.006", a[tracing.LatitudeKey].AsFloat64(), a[tracing.LongitudeKey].AsFloat64())
+	}
+
+	// Outbound NWS requests carry the trace
+	if len(upstreamParents) != 2 {
+		t.Fatalf("NWS saw %d requests; want 2", len(upstreamParents))
+	}
+	for _, parent := range upstreamParents {
+		if !strings.HasPrefix(parent, "00-"+callerTrace+"-") {
+			t.Errorf("NWS traceparent = %q; want trace %s", parent, callerTrace)
+		}
+	}
+}
+
+func TestGetHealthDependencies(t *testing.T) {
+	failingNWS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(failingNWS.Close)
+
+	tests := []struct {
+		name       string
+		redis      bool
+		nws        *httptest.Server
+		breakDB    bool
+		breakRedis bool
+		wantCode   int
+		wantStatus string
+		wantDeps   [3]string // sqlite, redis, nws
+	}{
+		{"all up", true, fakeNWS(t), false, false, fiber.StatusOK, "healthy", [3]string{"up", "up", "up"}},
+		{"without redis", false, fakeNWS(t), false, false, fiber.StatusOK, "healthy", [3]string{"up", "disabled", "up"}},
+		{"redis down", true, fakeNWS(t), false, true, fiber.StatusOK, "degraded", [3]string{"up", "down", "up"}},
+		{"sqlite down, redis up", true, fakeNWS(t), true, false, fiber.StatusOK, "degraded", [3]string{"down", "up", "up"}},
+		{"sqlite down without redis", false, fakeNWS(t), true, false, fiber.StatusServiceUnavailable, "unhealthy", [3]string{"down", "disabled", "up"}},
+		{"sqlite and redis down", true, fakeNWS(t), true, true, fiber.StatusServiceUnavailable, "unhealthy", [3]string{"down", "down", "up"}},
+		{"nws down", true, failingNWS, false, false, fiber.StatusOK, "degraded", [3]string{"up", "up", "down"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatalf("InitDB: %v", err)
+			}
+			t.Cleanup(func() { db.Close() })
+			var rdb *redis.Client
+			var mr *miniredis.Miniredis
+			if tt.redis {
+				mr = miniredis.RunT(t)
+				rdb = redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+				t.Cleanup(func() { rdb.Close() })
+			}
+			repo := repository.NewWeatherRepository(db, rdb)
+			client := services.NewNWSAPIClient(services.WithBaseURL(tt.nws.URL), services.WithHTTPClient(tt.nws.Client()),
+				services.WithRetry(services.RetryConfig{MaxAttempts: 1}))
+			handler := NewWeatherHandler(services.NewWeatherService(repo, client))
+			app := fiber.New()
+			app.Get("/api/weather", handler.GetWeather)
+			app.Get("/api/health", handler.GetHealth)
+
+			health := func(wantCode int) models.HealthResponse {
+				t.Helper()
+				resp, err := app.Test(httptest.NewRequest("GET", "/api/health", nil))
+				if err != nil {
+					t.Fatal(err)
+				}
+				var body models.HealthResponse
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatal(err)
+				}
+				if resp.StatusCode != wantCode {
+					t.Errorf("status code = %d; want %d", resp.StatusCode, wantCode)
+				}
+				return body
+			}
+
+			// The NWS is reported from the last request made to it, never probed
+			if got := health(fiber.StatusOK); got.Dependencies == nil || got.Dependencies.NWS.Status != "unknown" {
+				t.Fatalf("dependencies before any NWS request = %+v; want the NWS unknown", got.Dependencies)
+			}
+			if _, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40.7128&lon=-74.0060", nil)); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.breakDB {
+				db.Close()
+			}
+			if tt.breakRedis {
+				mr.Close()
+			}
+			got := health(tt.wantCode)
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %q; want %q", got.Status, tt.wantStatus)
+			}
+			if got.Dependencies == nil {
+				t.Fatal("no dependencies reported")
+			}
+			deps := [3]models.DependencyHealth{got.Dependencies.SQLite, got.Dependencies.Redis, got.Dependencies.NWS}
+			for i, name := range []string{"sqlite", "redis", "nws"} {
+				if deps[i].Status != tt.wantDeps[i] {
+					t.Errorf("%s = %q; want %q", name, deps[i].Status, tt.wantDeps[i])
+				}
+				if (deps[i].Status == "down") != (deps[i].Error != "") {
+					t.Errorf("%s: status %q with error %q; want an error exactly when down", name, deps[i].Status, deps[i].Error)
+				}
+			}
+		})
+	}
+}
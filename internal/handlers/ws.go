@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/i18n"
+	"weather-api-go/internal/jsoncase"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/pubsub"
+	"weather-api-go/internal/services"
+)
+
+// maxUpdateMessageSize caps the size of a message a weather updates client sends
+const maxUpdateMessageSize = 64 << 10
+
+// updateWriteTimeout bounds each write to a weather updates client, so a
+// client that stops reading is disconnected instead of holding its updates
+const updateWriteTimeout = 10 * time.Second
+
+// WeatherUpdates handles GET /ws, a WebSocket that pushes the weather for
+// subscribed coordinates
+// @Summary Subscribe to weather updates
+// @Description Upgrades to a WebSocket. Send {"type":"subscribe","coordinates":[{"lat":40.7128,"lon":-74.006}]} to receive each coordinate's current /weather response at once, then again whenever its cache entry is refreshed; {"type":"unsubscribe",...} stops them. A connection may subscribe to as many coordinates as a batch request may carry.
+// @Tags weather
+// @Success 101 "Switching to the WebSocket protocol"
+// @Failure 426 {object} models.ErrorResponse
+// @Router /ws [get]
+func (h *WeatherHandler) WeatherUpdates(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return sendError(c, fiber.StatusUpgradeRequired, models.ErrorCodeWebSocketRequired)
+	}
+
+	// The request's context is gone once the connection is upgraded, so keep
+	// what its messages are encoded with
+	stream := &weatherStream{
+		handler: h,
+		lang:    i18n.Language(c),
+		decode:  c.App().Config().JSONDecoder,
+		encode:  messageEncoder(c),
+		subs:    make(map[string]*weatherStreamSub),
+	}
+	return websocket.New(stream.run)(c)
+}
+
+// messageEncoder returns the JSON encoder for messages sent after a request's
+// handler has returned, naming properties in the request's style
+func messageEncoder(c *fiber.Ctx) func(v interface{}) ([]byte, error) {
+	encode := c.App().Config().JSONEncoder
+	style, ok := jsoncase.StyleOf(c)
+	if !ok {
+		return encode
+	}
+	return func(v interface{}) ([]byte, error) {
+		return encode(jsoncase.Wrap(v, style))
+	}
+}
+
+// weatherStream is one weather updates connection
+type weatherStream struct {
+	handler *WeatherHandler
+	conn    *websocket.Conn
+	lang    string
+	decode  func(data []byte, v interface{}) error
+	encode  func(v interface{}) ([]byte, error)
+	// writeMu serializes writes from the read loop and the subscriptions
+	writeMu sync.Mutex
+	// subs holds the open subscriptions by services.WeatherKey. Only the read
+	// loop uses it.
+	subs map[string]*weatherStreamSub
+	// forwarding counts the goroutines forwarding subscriptions' updates
+	forwarding sync.WaitGroup
+}
+
+// weatherStreamSub is a connection's subscription to one coordinate
+type weatherStreamSub struct {
+	sub  *pubsub.Subscription[models.WeatherCache]
+	stop chan struct{}
+	// sent is when the weather last sent for the coordinate was fetched,
+	// guarded by writeMu
+	sent time.Time
+}
+
+// run reads subscribe and unsubscribe messages until the client disconnects,
+// then closes the connection's subscriptions
+func (s *weatherStream) run(conn *websocket.Conn) {
+	s.conn = conn
+	conn.SetReadLimit(maxUpdateMessageSize)
+	defer func() {
+		for key := range s.subs {
+			s.unsubscribe(key)
+		}
+		// The connection is released once run returns, so wait for the
+		// forwarders to stop writing to it
+		s.forwarding.Wait()
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg models.WeatherSubscription
+		if err := s.decode(data, &msg); err != nil || len(msg.Coordinates) == 0 ||
+			(msg.Type != models.UpdateTypeSubscribe && msg.Type != models.UpdateTypeUnsubscribe) {
+			s.sendError(models.BatchCoordinate{}, models.ErrorCodeInvalidSubscription)
+			continue
+		}
+		if msg.Type == models.UpdateTypeUnsubscribe {
+			for _, item := range msg.Coordinates {
+				if checkBatchCoordinate(item) == "" {
+					s.unsubscribe(services.WeatherKey(*item.Lat, *item.Lon))
+				}
+			}
+			continue
+		}
+		s.subscribe(msg.Coordinates)
+	}
+}
+
+// subscribe subscribes to coordinates and sends their current weather.
+// Invalid coordinates, and those past the connection's limit, are answered
+// with an error instead.
+func (s *weatherStream) subscribe(items []models.BatchCoordinate) {
+	limit := s.handler.maxBatchSize
+	coords := make([]models.Coordinates, 0, len(items))
+	// valid[i] is the request item for coords[i], subs[i] its subscription,
+	// and added[i] whether this message subscribed to it
+	valid := make([]models.BatchCoordinate, 0, len(items))
+	subs := make([]*weatherStreamSub, 0, len(items))
+	added := make([]bool, 0, len(items))
+	for _, item := range items {
+		if code := checkBatchCoordinate(item); code != "" {
+			s.sendError(item, code)
+			continue
+		}
+		key := services.WeatherKey(*item.Lat, *item.Lon)
+		sub, ok := s.subs[key]
+		if !ok {
+			if len(s.subs) >= limit {
+				s.sendError(item, models.ErrorCodeTooManySubscriptions, "max", strconv.Itoa(limit))
+				continue
+			}
+			// Subscribe before looking the weather up, so a refresh in between isn't missed
+			sub = &weatherStreamSub{
+				sub:  s.handler.service.SubscribeWeather(*item.Lat, *item.Lon),
+				stop: make(chan struct{}),
+			}
+			s.subs[key] = sub
+			s.forwarding.Add(1)
+			go s.forward(item, sub)
+		}
+		coords = append(coords, models.Coordinates{Latitude: *item.Lat, Longitude: *item.Lon})
+		valid = append(valid, item)
+		subs = append(subs, sub)
+		added = append(added, !ok)
+	}
+	if len(coords) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.handler.requestTimeout)
+	defer cancel()
+	for i, result := range s.handler.service.GetWeatherBatch(ctx, coords) {
+		if result.Err != nil {
+			code, params := batchErrorCode(result.Err)
+			s.sendError(valid[i], code, params...)
+			continue
+		}
+		// A lookup that refreshed the cache also pushed the refresh, which
+		// may have been sent already. Repeated subscriptions always get the
+		// current weather.
+		s.sendWeather(valid[i], subs[i], result.Weather, added[i])
+	}
+}
+
+// forward sends each refresh of a subscribed coordinate until the
+// subscription is stopped. A client that reads slower than the coordinate is
+// refreshed only receives the latest weather.
+func (s *weatherStream) forward(item models.BatchCoordinate, sub *weatherStreamSub) {
+	defer s.forwarding.Done()
+	for {
+		select {
+		case <-sub.stop:
+			return
+		case entry := <-sub.sub.Updates():
+			ctx, cancel := context.WithTimeout(context.Background(), s.handler.requestTimeout)
+			s.sendWeather(item, sub, s.handler.service.WeatherUpdate(ctx, entry, services.WeatherOptions{}), true)
+			cancel()
+		}
+	}
+}
+
+// unsubscribe closes the subscription to a coordinate, if there is one
+func (s *weatherStream) unsubscribe(key string) {
+	if sub, ok := s.subs[key]; ok {
+		sub.sub.Close()
+		close(sub.stop)
+		delete(s.subs, key)
+	}
+}
+
+// sendError sends the localized error for a coordinate, or for a whole
+// message when item is empty
+func (s *weatherStream) sendError(item models.BatchCoordinate, code string, params ...string) {
+	e := i18n.ErrorIn(s.lang, code, params...)
+	s.send(models.WeatherUpdate{Type: models.UpdateTypeError, Lat: item.Lat, Lon: item.Lon, Error: &e})
+}
+
+// sendWeather sends a subscribed coordinate's weather. With onlyNewer, weather
+// fetched no later than what was last sent for the coordinate is skipped.
+func (s *weatherStream) sendWeather(item models.BatchCoordinate, sub *weatherStreamSub, weather *models.WeatherResponse, onlyNewer bool) {
+	var fetchedAt time.Time
+	if weather.CachedAt != nil {
+		fetchedAt = *weather.CachedAt
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if onlyNewer && !sub.sent.IsZero() && !fetchedAt.After(sub.sent) {
+		return
+	}
+	if fetchedAt.After(sub.sent) {
+		sub.sent = fetchedAt
+	}
+	s.write(models.WeatherUpdate{Type: models.UpdateTypeWeather, Lat: item.Lat, Lon: item.Lon, Weather: weather})
+}
+
+// send writes a message to the client
+func (s *weatherStream) send(msg models.WeatherUpdate) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.write(msg)
+}
+
+// write writes a message to the client with writeMu held. A failed write
+// closes the connection, which ends the read loop.
+func (s *weatherStream) write(msg models.WeatherUpdate) {
+	data, err := s.encode(msg)
+	if err != nil {
+		return
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(updateWriteTimeout))
+	if err := s.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		s.conn.Close()
+	}
+}
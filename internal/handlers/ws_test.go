@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+	"weather-api-go/internal/services"
+)
+
+// serveWeatherUpdates serves the API on a local listener against a fake NWS
+// server, returning the /ws URL and the repository
+func serveWeatherUpdates(t *testing.T, opts ...WeatherHandlerOption) (string, *repository.WeatherRepository) {
+	t.Helper()
+	addr, repo := listenTestApp(t, opts...)
+	return "ws://" + addr + APIBasePath + "/ws", repo
+}
+
+// dialWeatherUpdates opens a weather updates connection
+func dialWeatherUpdates(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readUpdate reads the next message, failing the test if none arrives in time
+func readUpdate(t *testing.T, conn *websocket.Conn) models.WeatherUpdate {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg models.WeatherUpdate
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("reading update: %v", err)
+	}
+	return msg
+}
+
+// expectNoUpdate fails the test if a message arrives within a short wait. The
+// connection can't be read after the wait times out.
+func expectNoUpdate(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	var msg models.WeatherUpdate
+	if err := conn.ReadJSON(&msg); err == nil {
+		t.Errorf("got unexpected update %+v", msg)
+	}
+}
+
+func subscribeMessage(kind string, coords ...[2]float64) map[string]interface{} {
+	items := make([]map[string]float64, len(coords))
+	for i, c := range coords {
+		items[i] = map[string]float64{"lat": c[0], "lon": c[1]}
+	}
+	return map[string]interface{}{"type": kind, "coordinates": items}
+}
+
+func TestWeatherUpdatesPushesRefreshes(t *testing.T) {
+	url, repo := serveWeatherUpdates(t)
+	conn := dialWeatherUpdates(t, url)
+
+	if err := conn.WriteJSON(subscribeMessage("subscribe", [2]float64{40.7128, -74.006})); err != nil {
+		t.Fatal(err)
+	}
+	msg := readUpdate(t, conn)
+	if msg.Type != models.UpdateTypeWeather || msg.Weather == nil || msg.Weather.Forecast != "Partly Cloudy" {
+		t.Fatalf("first message = %+v; want the current weather", msg)
+	}
+	if msg.Lat == nil || *msg.Lat != 40.7128 || msg.Lon == nil || *msg.Lon != -74.006 {
+		t.Errorf("first message lat/lon = %v/%v; want the subscribed coordinate", msg.Lat, msg.Lon)
+	}
+
+	// A refresh of a nearby point shares the cache entry, so it is pushed
+	refreshed := time.Now().Add(time.Second)
+	err := repo.SaveToCache(context.Background(), &models.WeatherCache{
+		Latitude: 40.71284, Longitude: -74.00598, Forecast: "Thunderstorms", TempC: 30, TempF: 86, Timestamp: refreshed,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg = readUpdate(t, conn)
+	if msg.Type != models.UpdateTypeWeather || msg.Weather == nil || msg.Weather.Forecast != "Thunderstorms" {
+		t.Fatalf("pushed message = %+v; want the refreshed weather", msg)
+	}
+	if msg.Weather.Source != services.SourceLive || msg.Weather.CachedAt == nil || !msg.Weather.CachedAt.Equal(refreshed) {
+		t.Errorf("pushed provenance = %q at %v; want live at %v", msg.Weather.Source, msg.Weather.CachedAt, refreshed)
+	}
+	if msg.Lat == nil || *msg.Lat != 40.7128 {
+		t.Errorf("pushed lat = %v; want the subscribed 40.7128", msg.Lat)
+	}
+
+	if err := conn.WriteJSON(subscribeMessage("unsubscribe", [2]float64{40.7128, -74.006})); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for repo.CacheSubscribers(40.7128, -74.006) != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := repo.SaveToCache(context.Background(), &models.WeatherCache{Latitude: 40.7128, Longitude: -74.006, Forecast: "Fog", Timestamp: refreshed.Add(time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	expectNoUpdate(t, conn)
+}
+
+func TestWeatherUpdatesDisconnectClosesSubscriptions(t *testing.T) {
+	url, repo := serveWeatherUpdates(t)
+	conn := dialWeatherUpdates(t, url)
+
+	coords := [][2]float64{{40.7128, -74.006}, {40.8, -73.9}}
+	if err := conn.WriteJSON(subscribeMessage("subscribe", coords...)); err != nil {
+		t.Fatal(err)
+	}
+	for range coords {
+		readUpdate(t, conn)
+	}
+	for _, c := range coords {
+		if n := repo.CacheSubscribers(c[0], c[1]); n != 1 {
+			t.Errorf("subscribers to %v = %d; want 1", c, n)
+		}
+	}
+
+	conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for _, c := range coords {
+		for repo.CacheSubscribers(c[0], c[1]) != 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if n := repo.CacheSubscribers(c[0], c[1]); n != 0 {
+			t.Errorf("subscribers to %v after disconnect = %d; want 0", c, n)
+		}
+	}
+}
+
+func TestWeatherUpdatesErrors(t *testing.T) {
+	url, _ := serveWeatherUpdates(t, WithMaxBatchSize(1))
+
+	tests := []struct {
+		name     string
+		message  string
+		wantCode string
+		wantLat  bool
+	}{
+		{"not JSON", `subscribe`, models.ErrorCodeInvalidSubscription, false},
+		{"unknown type", `{"type": "refresh", "coordinates": [{"lat": 40.7128, "lon": -74.006}]}`, models.ErrorCodeInvalidSubscription, false},
+		{"no coordinates", `{"type": "subscribe", "coordinates": []}`, models.ErrorCodeInvalidSubscription, false},
+		{"missing longitude", `{"type": "subscribe", "coordinates": [{"lat": 40.7128}]}`, models.ErrorCodeMissingLongitude, true},
+		{"out of range", `{"type": "subscribe", "coordinates": [{"lat": 95, "lon": -74.006}]}`, models.ErrorCodeCoordinatesOutOfRange, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := dialWeatherUpdates(t, url)
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(tt.message)); err != nil {
+				t.Fatal(err)
+			}
+			msg := readUpdate(t, conn)
+			if msg.Type != models.UpdateTypeError || msg.Error == nil || msg.Error.Code != tt.wantCode {
+				t.Fatalf("message = %+v; want error %s", msg, tt.wantCode)
+			}
+			if (msg.Lat != nil) != tt.wantLat {
+				t.Errorf("lat = %v; want it echoed: %v", msg.Lat, tt.wantLat)
+			}
+		})
+	}
+
+	t.Run("too many subscriptions", func(t *testing.T) {
+		conn := dialWeatherUpdates(t, url)
+		if err := conn.WriteJSON(subscribeMessage("subscribe", [2]float64{40.7128, -74.006}, [2]float64{40.8, -73.9})); err != nil {
+			t.Fatal(err)
+		}
+		// The rejected coordinate is answered before the lookup finishes
+		msg := readUpdate(t, conn)
+		if msg.Error == nil || msg.Error.Code != models.ErrorCodeTooManySubscriptions || msg.Lat == nil || *msg.Lat != 40.8 {
+			t.Fatalf("first message = %+v; want TOO_MANY_SUBSCRIPTIONS for 40.8", msg)
+		}
+		if msg.Error.Details != "A connection may subscribe to at most 1 coordinates" {
+			t.Errorf("details = %q; want the limit", msg.Error.Details)
+		}
+		if msg := readUpdate(t, conn); msg.Weather == nil || *msg.Lat != 40.7128 {
+			t.Errorf("second message = %+v; want the weather for 40.7128", msg)
+		}
+		// Resubscribing to a coordinate already subscribed to is within the limit
+		if err := conn.WriteJSON(subscribeMessage("subscribe", [2]float64{40.7128, -74.006})); err != nil {
+			t.Fatal(err)
+		}
+		if msg := readUpdate(t, conn); msg.Weather == nil {
+			t.Errorf("resubscribe message = %+v; want the current weather", msg)
+		}
+	})
+}
+
+func TestWeatherUpdatesRequiresUpgrade(t *testing.T) {
+	app := newTestApp(t, fakeNWS(t))
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/ws", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUpgradeRequired {
+		t.Errorf("status = %d; want 426", resp.StatusCode)
+	}
+	var body models.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != models.ErrorCodeWebSocketRequired {
+		t.Errorf("code = %q; want %s", body.Code, models.ErrorCodeWebSocketRequired)
+	}
+}
@@ -0,0 +1,148 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+)
+
+// DefaultLanguage is used for unsupported languages and missing translations
+const DefaultLanguage = "en"
+
+// Message is the human-readable text for an error code. Details may contain
+// {name} placeholders filled from the parameters given to Error.
+type Message struct {
+	Error   string `json:"error"`
+	Details string `json:"details"`
+}
+
+//go:embed messages/*.json
+var messageFiles embed.FS
+
+// catalog maps language to error code to message
+var catalog = mustLoadCatalog()
+
+func mustLoadCatalog() map[string]map[string]Message {
+	files, err := messageFiles.ReadDir("messages")
+	if err != nil {
+		panic(err)
+	}
+
+	catalog := make(map[string]map[string]Message, len(files))
+	for _, f := range files {
+		data, err := messageFiles.ReadFile(path.Join("messages", f.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var messages map[string]Message
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: invalid message file %s: %v", f.Name(), err))
+		}
+		catalog[strings.TrimSuffix(f.Name(), ".json")] = messages
+	}
+	return catalog
+}
+
+// Languages lists the languages with a message file
+func Languages() []string {
+	langs := make([]string, 0, len(catalog))
+	for lang := range catalog {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Lookup returns the message for an error code in a language. Each text falls
+// back to English when the language or its translation is missing.
+func Lookup(lang, code string) Message {
+	msg := catalog[lang][code]
+	fallback := catalog[DefaultLanguage][code]
+	if msg.Error == "" {
+		msg.Error = fallback.Error
+	}
+	if msg.Details == "" {
+		msg.Details = fallback.Details
+	}
+	return msg
+}
+
+// supported returns the catalog language for a language tag (es-MX matches es)
+func supported(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if _, ok := catalog[tag]; ok {
+		return tag, true
+	}
+	base, _, _ := strings.Cut(tag, "-")
+	if _, ok := catalog[base]; ok {
+		return base, true
+	}
+	return "", false
+}
+
+// Negotiate picks the supported language an Accept-Language header prefers
+// most, or English when none is supported
+func Negotiate(header string) string {
+	best, bestQ := DefaultLanguage, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if lang, ok := supported(tag); ok && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// Language returns the language for a request's messages: the lang query
+// parameter when supported, otherwise the Accept-Language preference
+func Language(c *fiber.Ctx) string {
+	if lang, ok := supported(c.Query("lang")); ok {
+		return lang
+	}
+	return Negotiate(c.Get(fiber.HeaderAcceptLanguage))
+}
+
+// Error builds the error response for a code in the request's language,
+// filling placeholders from name/value pairs (Error(c, code, "station", id)).
+// Content-Language names the language of the error text, and the response is
+// marked as varying by Accept-Language.
+func Error(c *fiber.Ctx, code string, params ...string) models.ErrorResponse {
+	lang := Language(c)
+	resp := ErrorIn(lang, code, params...)
+	if catalog[lang][code].Error == "" {
+		lang = DefaultLanguage
+	}
+	c.Set(fiber.HeaderContentLanguage, lang)
+	c.Vary(fiber.HeaderAcceptLanguage)
+	return resp
+}
+
+// ErrorIn builds the error response for a code in a language, for errors sent
+// outside an HTTP response, such as over a WebSocket
+func ErrorIn(lang, code string, params ...string) models.ErrorResponse {
+	msg := Lookup(lang, code)
+	if len(params) > 0 {
+		pairs := make([]string, 0, len(params))
+		for i := 0; i+1 < len(params); i += 2 {
+			pairs = append(pairs, "{"+params[i]+"}", params[i+1])
+		}
+		r := strings.NewReplacer(pairs...)
+		msg.Error, msg.Details = r.Replace(msg.Error), r.Replace(msg.Details)
+	}
+	return models.ErrorResponse{Error: msg.Error, Details: msg.Details, Code: code}
+}
@@ -0,0 +1,213 @@
+package i18n
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+)
+
+// definedErrorCodes returns the values of the ErrorCode constants in the models package
+func definedErrorCodes(t *testing.T) map[string]string {
+	t.Helper()
+	pkgs, err := parser.ParseDir(token.NewFileSet(), "../models", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codes := map[string]string{}
+	for _, pkg := range pkgs {
+		ast.Inspect(pkg, func(n ast.Node) bool {
+			spec, ok := n.(*ast.ValueSpec)
+			if !ok {
+				return true
+			}
+			for i, name := range spec.Names {
+				if !strings.HasPrefix(name.Name, "ErrorCode") || i >= len(spec.Values) {
+					continue
+				}
+				if lit, ok := spec.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					codes[name.Name], _ = strconv.Unquote(lit.Value)
+				}
+			}
+			return true
+		})
+	}
+	if len(codes) == 0 {
+		t.Fatal("found no ErrorCode constants in the models package")
+	}
+	return codes
+}
+
+var placeholder = regexp.MustCompile(`\{[a-z]+\}`)
+
+func placeholders(s string) string {
+	found := placeholder.FindAllString(s, -1)
+	sort.Strings(found)
+	return strings.Join(found, ",")
+}
+
+func TestEveryErrorCodeHasEnglishMessage(t *testing.T) {
+	defined := map[string]bool{}
+	for name, code := range definedErrorCodes(t) {
+		defined[code] = true
+		msg, ok := catalog[DefaultLanguage][code]
+		if !ok || msg.Error == "" || msg.Details == "" {
+			t.Errorf("%s (%s) has no complete English message", name, code)
+		}
+	}
+
+	for _, lang := range Languages() {
+		for code, msg := range catalog[lang] {
+			if !defined[code] {
+				t.Errorf("%s message for %s matches no defined error code", lang, code)
+				continue
+			}
+			en := catalog[DefaultLanguage][code]
+			if msg.Details != "" && placeholders(msg.Details) != placeholders(en.Details) {
+				t.Errorf("%s details for %s use placeholders %q; English uses %q",
+					lang, code, placeholders(msg.Details), placeholders(en.Details))
+			}
+		}
+	}
+}
+
+// TestErrorResponsesUseCatalog fails on ErrorResponse literals outside this
+// package, so every error site resolves its text from the catalog
+func TestErrorResponsesUseCatalog(t *testing.T) {
+	root := "../.."
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case "i18n", "node_modules", "frontend", ".git":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			lit, ok := n.(*ast.CompositeLit)
+			if !ok {
+				return true
+			}
+			// Empty literals only name the type, as in the schema registry
+			if sel, ok := lit.Type.(*ast.SelectorExpr); ok && sel.Sel.Name == "ErrorResponse" && len(lit.Elts) > 0 {
+				t.Errorf("%s: inline ErrorResponse; use i18n.Error with an error code", fset.Position(lit.Pos()))
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"es", "es"},
+		{"es-MX,es;q=0.9,en;q=0.8", "es"},
+		{"en-US,en;q=0.9,es;q=0.8", "en"},
+		{"fr-FR,es;q=0.5", "es"},
+		{"fr-FR,de;q=0.5", "en"},
+		{"es;q=0,en;q=0.1", "en"},
+		{"*", "en"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q; want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	app := fiber.New()
+	app.Get("/:code", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusBadRequest).JSON(Error(c, c.Params("code"), "station", "KXYZ", "max", "72"))
+	})
+
+	tests := []struct {
+		code, query, acceptLanguage string
+		want                        models.ErrorResponse
+		wantLanguage                string
+	}{
+		{models.ErrorCodeStationNotFound, "", "", models.ErrorResponse{
+			Error: "Station not found", Details: "The NWS has no observation station with ID KXYZ", Code: models.ErrorCodeStationNotFound,
+		}, "en"},
+		{models.ErrorCodeStationNotFound, "", "es-ES,es;q=0.9", models.ErrorResponse{
+			Error: "Estación no encontrada", Details: "El NWS no tiene ninguna estación de observación con el ID KXYZ", Code: models.ErrorCodeStationNotFound,
+		}, "es"},
+		{models.ErrorCodeInvalidHours, "?lang=es", "en", models.ErrorResponse{
+			Error: "Parámetro hours no válido", Details: "Las horas deben ser un número entero positivo (como máximo 72)", Code: models.ErrorCodeInvalidHours,
+		}, "es"},
+		{models.ErrorCodeInvalidHours, "?lang=xx", "es", models.ErrorResponse{
+			Error: "Parámetro hours no válido", Details: "Las horas deben ser un número entero positivo (como máximo 72)", Code: models.ErrorCodeInvalidHours,
+		}, "es"},
+		// A missing translation falls back to English
+		{models.ErrorCodeInvalidResponse, "?lang=es", "", models.ErrorResponse{
+			Error: "Response failed OpenAPI validation", Details: "{cause}", Code: models.ErrorCodeInvalidResponse,
+		}, "en"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/"+tt.code+tt.query, nil)
+		req.Header.Set("Accept-Language", tt.acceptLanguage)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s%s (Accept-Language %q) = %+v; want %+v", tt.code, tt.query, tt.acceptLanguage, got, tt.want)
+		}
+		if lang := resp.Header.Get("Content-Language"); lang != tt.wantLanguage {
+			t.Errorf("%s%s: Content-Language = %q; want %q", tt.code, tt.query, lang, tt.wantLanguage)
+		}
+		if vary := resp.Header.Get("Vary"); !strings.Contains(vary, "Accept-Language") {
+			t.Errorf("%s%s: Vary = %q; want Accept-Language", tt.code, tt.query, vary)
+		}
+	}
+}
+
+func TestErrorIn(t *testing.T) {
+	tests := []struct {
+		lang string
+		want models.ErrorResponse
+	}{
+		{"es", models.ErrorResponse{Error: "Demasiadas suscripciones", Details: "Una conexión puede suscribirse como máximo a 50 coordenadas", Code: models.ErrorCodeTooManySubscriptions}},
+		{"xx", models.ErrorResponse{Error: "Too many subscriptions", Details: "A connection may subscribe to at most 50 coordinates", Code: models.ErrorCodeTooManySubscriptions}},
+	}
+	for _, tt := range tests {
+		if got := ErrorIn(tt.lang, models.ErrorCodeTooManySubscriptions, "max", "50"); got != tt.want {
+			t.Errorf("ErrorIn(%q) = %+v; want %+v", tt.lang, got, tt.want)
+		}
+	}
+}
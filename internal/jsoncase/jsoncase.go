@@ -0,0 +1,396 @@
+package jsoncase
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/i18n"
+	"weather-api-go/internal/models"
+)
+
+// Style is a JSON property naming convention. Our models are tagged in
+// snake_case; other styles are derived from the tags when encoding.
+type Style string
+
+// Styles accepted by Parse
+const (
+	Snake Style = "snake"
+	Camel Style = "camel"
+)
+
+// Parse returns the style with the given name; an empty name selects snake_case
+func Parse(name string) (Style, error) {
+	switch Style(strings.ToLower(name)) {
+	case "", Snake:
+		return Snake, nil
+	case Camel:
+		return Camel, nil
+	}
+	return "", fmt.Errorf("unknown JSON case %q (accepted: camel, snake)", name)
+}
+
+// Name converts a snake_case property name to the style
+func (s Style) Name(name string) string {
+	if s != Camel || !strings.Contains(name, "_") {
+		return name
+	}
+
+	var b strings.Builder
+	b.Grow(len(name))
+	upper := false
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+		switch {
+		case ch == '_':
+			upper = b.Len() > 0
+		case upper && 'a' <= ch && ch <= 'z':
+			b.WriteByte(ch - 'a' + 'A')
+			upper = false
+		default:
+			b.WriteByte(ch)
+			upper = false
+		}
+	}
+	return b.String()
+}
+
+// MarshalFunc encodes a value as JSON
+type MarshalFunc func(v interface{}) ([]byte, error)
+
+// Marshal encodes v with marshal, naming struct properties in the given style.
+// Map keys are data, not property names, and are left untouched. Leaf values
+// (scalars, times, and other json.Marshaler types) are encoded by marshal, so
+// output matches the codec apart from the property names.
+func Marshal(v interface{}, style Style, marshal MarshalFunc) ([]byte, error) {
+	if s, ok := v.(styled); ok {
+		v, style = s.value, s.style
+	}
+	if style == Snake {
+		return marshal(v)
+	}
+
+	e := &encoder{style: style, marshal: marshal}
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+// Encoder returns a fiber JSONEncoder that names properties in the given style,
+// or in the style a value was wrapped with by For
+func Encoder(marshal MarshalFunc, style Style) func(v interface{}) ([]byte, error) {
+	return func(v interface{}) ([]byte, error) {
+		return Marshal(v, style, marshal)
+	}
+}
+
+const localsKey = "jsoncase.style"
+
+// Middleware records the style responses to a request are encoded in: the
+// case query parameter when given, otherwise the deployment default. An
+// unknown case is rejected with 400.
+func Middleware(def Style) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		style := def
+		if name := c.Query("case"); name != "" {
+			var err error
+			if style, err = Parse(name); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(i18n.Error(c, models.ErrorCodeInvalidCase))
+			}
+		}
+		c.Locals(localsKey, style)
+		return c.Next()
+	}
+}
+
+// StyleOf returns the style recorded for a request by Middleware
+func StyleOf(c *fiber.Ctx) (Style, bool) {
+	style, ok := c.Locals(localsKey).(Style)
+	return style, ok
+}
+
+// For wraps a response value so it is encoded in the request's style. Values
+// for requests Middleware has not seen are returned unchanged.
+func For(c *fiber.Ctx, v interface{}) interface{} {
+	if style, ok := StyleOf(c); ok {
+		return Wrap(v, style)
+	}
+	return v
+}
+
+// Wrap pairs a value with the style it must be encoded in, for values encoded
+// after their request's context is gone, such as WebSocket messages
+func Wrap(v interface{}, style Style) interface{} {
+	return styled{value: v, style: style}
+}
+
+// styled is a value paired with the style it must be encoded in. It marshals
+// itself with encoding/json when the app's encoder is not an Encoder.
+type styled struct {
+	value interface{}
+	style Style
+}
+
+func (s styled) MarshalJSON() ([]byte, error) {
+	return Marshal(s.value, s.style, json.Marshal)
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// isLeaf reports whether a type encodes itself rather than by its fields or elements
+func isLeaf(t reflect.Type) bool {
+	return t.Implements(marshalerType) || t.Implements(textMarshalerType)
+}
+
+type encoder struct {
+	buf     bytes.Buffer
+	style   Style
+	marshal MarshalFunc
+}
+
+func (e *encoder) leaf(v reflect.Value) error {
+	data, err := e.marshal(v.Interface())
+	if err != nil {
+		return err
+	}
+	e.buf.Write(data)
+	return nil
+}
+
+func (e *encoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf.WriteString("null")
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		if v.Kind() == reflect.Ptr && isLeaf(v.Type()) {
+			return e.leaf(v)
+		}
+		return e.encode(v.Elem())
+	case reflect.Struct:
+		if isLeaf(v.Type()) {
+			return e.leaf(v)
+		}
+		return e.encodeStruct(v)
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		if v.Type().Key().Kind() != reflect.String || isLeaf(v.Type()) {
+			return e.leaf(v)
+		}
+		return e.encodeMap(v)
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 || isLeaf(v.Type()) {
+			return e.leaf(v)
+		}
+		return e.encodeList(v)
+	case reflect.Array:
+		if isLeaf(v.Type()) {
+			return e.leaf(v)
+		}
+		return e.encodeList(v)
+	}
+	return e.leaf(v)
+}
+
+func (e *encoder) encodeStruct(v reflect.Value) error {
+	e.buf.WriteByte('{')
+	first := true
+	for _, f := range cachedFields(v.Type()) {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitempty && isEmptyValue(fv)) {
+			continue
+		}
+		if !first {
+			e.buf.WriteByte(',')
+		}
+		first = false
+		if e.style == Camel {
+			e.buf.Write(f.camelKey)
+		} else {
+			e.buf.Write(f.key)
+		}
+		e.buf.WriteByte(':')
+		if err := e.encode(fv); err != nil {
+			return err
+		}
+	}
+	e.buf.WriteByte('}')
+	return nil
+}
+
+func (e *encoder) encodeMap(v reflect.Value) error {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	e.buf.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k.String())
+		if err != nil {
+			return err
+		}
+		e.buf.Write(key)
+		e.buf.WriteByte(':')
+		if err := e.encode(v.MapIndex(k)); err != nil {
+			return err
+		}
+	}
+	e.buf.WriteByte('}')
+	return nil
+}
+
+func (e *encoder) encodeList(v reflect.Value) error {
+	e.buf.WriteByte('[')
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		if err := e.encode(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	e.buf.WriteByte(']')
+	return nil
+}
+
+// field is an encoded struct property with its key quoted in each style
+type field struct {
+	index     []int
+	key       []byte
+	camelKey  []byte
+	omitempty bool
+}
+
+var fieldCache sync.Map // reflect.Type -> []field
+
+// cachedFields lists a struct's JSON properties following encoding/json's tag
+// rules. Untagged embedded structs are flattened; on a name clash the
+// shallower field wins.
+func cachedFields(t reflect.Type) []field {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.([]field)
+	}
+	fields, _ := fieldCache.LoadOrStore(t, typeFields(t, nil, map[string]bool{}))
+	return fields.([]field)
+}
+
+func typeFields(t reflect.Type, parent []int, seen map[string]bool) []field {
+	var fields, embedded []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		index := append(append([]int{}, parent...), i)
+
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && !isLeaf(ft) {
+				embedded = append(embedded, field{index: index})
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		key, _ := json.Marshal(name)
+		camelKey, _ := json.Marshal(Camel.Name(name))
+		fields = append(fields, field{
+			index:     index,
+			key:       key,
+			camelKey:  camelKey,
+			omitempty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+
+	for _, emb := range embedded {
+		ft := t.Field(emb.index[len(emb.index)-1]).Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		fields = append(fields, typeFields(ft, emb.index, seen)...)
+	}
+
+	// Embedded fields are encoded in declaration order, as encoding/json does
+	sort.SliceStable(fields, func(i, j int) bool {
+		a, b := fields[i].index, fields[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return fields
+}
+
+// fieldByIndex follows a field index path, reporting false through a nil embedded pointer
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isEmptyValue matches encoding/json's omitempty test
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package jsoncase
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+)
+
+func TestStyleName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"forecast", "forecast"},
+		{"temperature_c", "temperatureC"},
+		{"cache_hit_ratio", "cacheHitRatio"},
+		{"p95_ms", "p95Ms"},
+		{"heat_index_c", "heatIndexC"},
+		{"_private", "private"},
+		{"trailing_", "trailing"},
+		{"double__underscore", "doubleUnderscore"},
+		{"already_Upper", "alreadyUpper"},
+	}
+	for _, tt := range tests {
+		if got := Camel.Name(tt.in); got != tt.want {
+			t.Errorf("Camel.Name(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+		if got := Snake.Name(tt.in); got != tt.in {
+			t.Errorf("Snake.Name(%q) = %q; want unchanged", tt.in, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Style
+		wantErr bool
+	}{
+		{"", Snake, false},
+		{"snake", Snake, false},
+		{"CAMEL", Camel, false},
+		{"kebab", "", true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Parse(%q) = %q, %v; want %q (error %v)", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+type embedded struct {
+	InnerValue int    `json:"inner_value"`
+	Shadowed   string `json:"outer_name"`
+}
+
+type sample struct {
+	OuterName string `json:"outer_name"`
+	embedded
+	Optional *float64 `json:"optional_value,omitempty"`
+	Empty    string   `json:"empty_text,omitempty"`
+	Skipped  string   `json:"-"`
+	Untagged bool
+	Counts   map[string]int      `json:"route_counts"`
+	Nested   []models.Advisories `json:"nested_list"`
+	NilList  []string            `json:"nil_list"`
+	When     time.Time           `json:"observed_at"`
+	Raw      []byte              `json:"raw_bytes"`
+}
+
+func TestMarshal(t *testing.T) {
+	heat := 41.5
+	v := sample{
+		OuterName: "outer",
+		embedded:  embedded{InnerValue: 3, Shadowed: "hidden"},
+		Skipped:   "skipped",
+		Untagged:  true,
+		Counts:    map[string]int{"requests_shed": 2, "/api/weather": 1},
+		Nested:    []models.Advisories{{FrostRisk: true, HeatIndexC: &heat, Severity: "high"}},
+		When:      time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC),
+		Raw:       []byte("hi"),
+	}
+
+	snake, err := Marshal(v, Snake, json.Marshal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(v)
+	if string(snake) != string(want) {
+		t.Errorf("snake output differs from encoding/json\n got: %s\nwant: %s", snake, want)
+	}
+
+	camel, err := Marshal(v, Camel, json.Marshal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCamel := `{"outerName":"outer","innerValue":3,"Untagged":true,` +
+		`"routeCounts":{"/api/weather":1,"requests_shed":2},` +
+		`"nestedList":[{"frostRisk":true,"heatRisk":false,"heatIndexC":41.5,"severity":"high"}],` +
+		`"nilList":null,"observedAt":"2024-01-15T08:00:00Z","rawBytes":"aGk="}`
+	if string(camel) != wantCamel {
+		t.Errorf("camel output\n got: %s\nwant: %s", camel, wantCamel)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	app := fiber.New(fiber.Config{JSONEncoder: Encoder(json.Marshal, Camel)})
+	app.Use(Middleware(Camel))
+	tempC, tempF := 20.0, 0.0
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.JSON(For(c, models.WeatherResponse{Forecast: "Sunny", TemperatureC: &tempC, TemperatureF: &tempF}))
+	})
+
+	tests := []struct {
+		query    string
+		wantCode int
+		wantBody string
+	}{
+		{"", fiber.StatusOK, `{"forecast":"Sunny","temperature":"","temperatureC":20,"temperatureF":0}`},
+		{"?case=snake", fiber.StatusOK, `{"forecast":"Sunny","temperature":"","temperature_c":20,"temperature_f":0}`},
+		{"?case=camel", fiber.StatusOK, `{"forecast":"Sunny","temperature":"","temperatureC":20,"temperatureF":0}`},
+		{"?case=pascal", fiber.StatusBadRequest, `{"error":"Invalid case parameter","details":"Case must be camel or snake","code":"INVALID_CASE"}`},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", "/"+tt.query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != tt.wantCode || string(body) != tt.wantBody {
+			t.Errorf("%q: got %d %s; want %d %s", tt.query, resp.StatusCode, body, tt.wantCode, tt.wantBody)
+		}
+	}
+}
+
+func TestForWithoutEncoder(t *testing.T) {
+	// A styled value still honors its style under the default fiber encoder
+	app := fiber.New()
+	app.Use(Middleware(Snake))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.JSON(For(c, models.ErrorResponse{Error: "bad", Code: models.ErrorCodeShed}))
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/?case=camel", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if want := `{"error":"bad","code":"SHED"}`; string(body) != want {
+		t.Errorf("body = %s; want %s", body, want)
+	}
+}
@@ -0,0 +1,143 @@
+package jsoncodec
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+// observationSeries builds a 72-hour observation history, the largest response we serve
+func observationSeries() models.ObservationHistoryResponse {
+	start := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	resp := models.ObservationHistoryResponse{StationID: "KNYC", Hours: 72}
+	for i := 0; i < 72; i++ {
+		obs := models.Observation{
+			Timestamp:    start.Add(time.Duration(i) * time.Hour),
+			Description:  "Partly Cloudy",
+			TemperatureC: ptr(float64(i%30) / 3),
+			TemperatureF: ptr(32 + float64(i%30)*0.6),
+			WindSpeedKmh: ptr(12.96),
+		}
+		if i%4 != 0 {
+			obs.PressureHPa = ptr(1013.25)
+		}
+		resp.Observations = append(resp.Observations, obs)
+	}
+	return resp
+}
+
+// compatibilityCases covers every response model along with the encoding
+// details codecs tend to disagree on
+func compatibilityCases() map[string]interface{} {
+	sent := time.Date(2024, 1, 15, 8, 0, 0, 123456789, time.FixedZone("EST", -5*3600))
+	return map[string]interface{}{
+		"weather minimal": models.WeatherResponse{Forecast: "Sunny", Temperature: "moderate", TemperatureC: ptr(20), TemperatureF: ptr(68)},
+		"weather advisories": models.WeatherResponse{
+			Forecast: "Clear", Temperature: "cold", TemperatureC: ptr(-0.5555555555555556), TemperatureF: ptr(31),
+			Advisories: &models.Advisories{FrostRisk: true, HeatIndexC: ptr(41.123456789), Severity: "high"},
+		},
+		"weather zero values": models.WeatherResponse{},
+		"error escaping": models.ErrorResponse{
+			Error: `Invalid "lat" <parameter> & more`, Details: "line\nbreak\ttab   é 🌤️", Code: models.ErrorCodeShed,
+		},
+		"health":             models.HealthResponse{Status: "healthy", Timestamp: "2024-01-15T10:30:00Z"},
+		"observations":       observationSeries(),
+		"observations empty": models.ObservationHistoryResponse{StationID: "KNYC", Hours: 24, Observations: []models.Observation{}},
+		"observations nil":   models.ObservationHistoryResponse{StationID: "KNYC", Hours: 24},
+		"alert": models.Alert{
+			ID: "urn:oid:2.49.0.1.840.0.abc", Event: "Winter Storm Warning", Severity: "Severe", Urgency: "Expected",
+			Headline: "Warning <until> 6PM", MessageType: "Update", Sent: sent, Onset: &sent,
+			Expires: sent.Add(12 * time.Hour), AffectedZones: []string{"NYZ072"}, References: []string{"urn:a", "urn:b"},
+		},
+		"metrics": models.MetricsResponse{
+			UptimeSeconds: 3600, RecentLatencyMs: ptr(1.2),
+			Counters: map[string]int64{"requests_shed": 2, "a_first": 1, "Z_upper": 3},
+		},
+		// Exponents below 1e-6 are excluded: see the Codec doc comment
+		"floats": []float64{0, 0.1, 1e20, 1e21, 123456789.123, -0.000001},
+	}
+}
+
+func TestCodecsMatchEncodingJSON(t *testing.T) {
+	for _, name := range Names() {
+		codec, err := Lookup(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for caseName, v := range compatibilityCases() {
+			t.Run(name+"/"+caseName, func(t *testing.T) {
+				want, err := json.Marshal(v)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := codec.Marshal(v)
+				if err != nil {
+					t.Fatalf("Marshal: %v", err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("output differs from encoding/json\n got: %s\nwant: %s", got, want)
+				}
+
+				decoded := reflect.New(reflect.TypeOf(v))
+				if err := codec.Unmarshal(want, decoded.Interface()); err != nil {
+					t.Fatalf("Unmarshal: %v", err)
+				}
+				roundTrip, _ := json.Marshal(decoded.Elem().Interface())
+				if !bytes.Equal(roundTrip, want) {
+					t.Errorf("decode did not round-trip\n got: %s\nwant: %s", roundTrip, want)
+				}
+			})
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"", Std, false},
+		{"std", Std, false},
+		{"GOCCY", GoJSON, false},
+		{"jsoniter", Jsoniter, false},
+		{"sonic", "", true},
+	}
+	for _, tt := range tests {
+		codec, err := Lookup(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Lookup(%q) error = %v; wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if codec.Name != tt.want {
+			t.Errorf("Lookup(%q) = %s; want %s", tt.name, codec.Name, tt.want)
+		}
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	payloads := map[string]interface{}{
+		"weather":      compatibilityCases()["weather advisories"],
+		"observations": observationSeries(),
+	}
+	for _, payload := range []string{"weather", "observations"} {
+		for _, name := range Names() {
+			codec, _ := Lookup(name)
+			v := payloads[payload]
+			b.Run(payload+"/"+name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := codec.Marshal(v); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
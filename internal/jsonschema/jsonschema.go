@@ -0,0 +1,138 @@
+package jsonschema
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect produced by Generate
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Generate builds a JSON Schema for a Go value from its struct fields, json
+// tags, and example tags. Fields without omitempty are required; pointers and
+// omitempty fields are optional.
+func Generate(v interface{}) map[string]interface{} {
+	schema := schemaFor(reflect.TypeOf(v))
+	schema["$schema"] = Draft
+	return schema
+}
+
+// Schema builds the JSON Schema for a Go value without the $schema keyword,
+// suitable for embedding in an OpenAPI document
+func Schema(v interface{}) map[string]interface{} {
+	return schemaFor(reflect.TypeOf(v))
+}
+
+func schemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return structSchema(t)
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem())}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{}
+}
+
+func structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitempty, skip := jsonName(field)
+		if skip {
+			continue
+		}
+
+		prop := schemaFor(field.Type)
+		if example, ok := field.Tag.Lookup("example"); ok {
+			if value, ok := parseExample(example, prop); ok {
+				prop["examples"] = []interface{}{value}
+			}
+		}
+		properties[name] = prop
+
+		if !omitempty && field.Type.Kind() != reflect.Ptr {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// jsonName returns the JSON property name for a struct field and whether it is omitempty
+func jsonName(field reflect.StructField) (name string, omitempty bool, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, false
+}
+
+// parseExample converts an example tag into a value matching the property type
+func parseExample(example string, prop map[string]interface{}) (interface{}, bool) {
+	switch prop["type"] {
+	case "string":
+		return example, true
+	case "number":
+		v, err := strconv.ParseFloat(example, 64)
+		return v, err == nil
+	case "integer":
+		v, err := strconv.ParseInt(example, 10, 64)
+		return v, err == nil
+	case "boolean":
+		v, err := strconv.ParseBool(example)
+		return v, err == nil
+	case "array":
+		items, _ := prop["items"].(map[string]interface{})
+		if v, ok := parseExample(example, items); ok {
+			return []interface{}{v}, true
+		}
+	}
+	return nil, false
+}
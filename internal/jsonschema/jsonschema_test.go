@@ -0,0 +1,111 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type sampleNested struct {
+	Level string `json:"level" example:"high"`
+}
+
+type sampleModel struct {
+	Name     string        `json:"name" example:"Portland"`
+	Count    int           `json:"count" example:"3"`
+	Ratio    float64       `json:"ratio,omitempty" example:"0.5"`
+	Optional *float64      `json:"optional,omitempty"`
+	Flag     bool          `json:"flag"`
+	When     time.Time     `json:"when"`
+	Tags     []string      `json:"tags" example:"a"`
+	Nested   *sampleNested `json:"nested,omitempty"`
+	Hidden   string        `json:"-"`
+	internal string
+}
+
+func TestGenerate(t *testing.T) {
+	schema := Generate(sampleModel{})
+
+	if schema["$schema"] != Draft {
+		t.Errorf("$schema = %v; want %s", schema["$schema"], Draft)
+	}
+
+	wantRequired := []string{"name", "count", "flag", "when", "tags"}
+	if !reflect.DeepEqual(schema["required"], wantRequired) {
+		t.Errorf("required = %v; want %v", schema["required"], wantRequired)
+	}
+
+	props := schema["properties"].(map[string]interface{})
+	if _, ok := props["Hidden"]; ok {
+		t.Error("json:\"-\" field was included")
+	}
+	if _, ok := props["internal"]; ok {
+		t.Error("unexported field was included")
+	}
+
+	tests := []struct {
+		prop     string
+		wantType string
+	}{
+		{"name", "string"},
+		{"count", "integer"},
+		{"ratio", "number"},
+		{"optional", "number"},
+		{"flag", "boolean"},
+		{"when", "string"},
+		{"tags", "array"},
+		{"nested", "object"},
+	}
+	for _, tt := range tests {
+		prop := props[tt.prop].(map[string]interface{})
+		if prop["type"] != tt.wantType {
+			t.Errorf("%s type = %v; want %s", tt.prop, prop["type"], tt.wantType)
+		}
+	}
+
+	if format := props["when"].(map[string]interface{})["format"]; format != "date-time" {
+		t.Errorf("time format = %v; want date-time", format)
+	}
+	if ex := props["count"].(map[string]interface{})["examples"]; !reflect.DeepEqual(ex, []interface{}{int64(3)}) {
+		t.Errorf("count examples = %v; want [3]", ex)
+	}
+	if ex := props["tags"].(map[string]interface{})["examples"]; !reflect.DeepEqual(ex, []interface{}{[]interface{}{"a"}}) {
+		t.Errorf("tags examples = %v; want [[a]]", ex)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema := Generate(sampleModel{})
+
+	decode := func(raw string) interface{} {
+		var doc interface{}
+		if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+
+	valid := `{"name": "x", "count": 2, "flag": true, "when": "2024-01-15T10:00:00Z", "tags": [], "nested": {"level": "low"}}`
+	if err := Validate(schema, decode(valid)); err != nil {
+		t.Errorf("valid document rejected: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"wrong field type", `{"name": 5, "count": 2, "flag": true, "when": "x", "tags": []}`},
+		{"missing required", `{"count": 2, "flag": true, "when": "x", "tags": []}`},
+		{"fractional integer", `{"name": "x", "count": 2.5, "flag": true, "when": "x", "tags": []}`},
+		{"wrong item type", `{"name": "x", "count": 2, "flag": true, "when": "x", "tags": [1]}`},
+		{"nested wrong type", `{"name": "x", "count": 2, "flag": true, "when": "x", "tags": [], "nested": {"level": false}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(schema, decode(tt.doc)); err == nil {
+				t.Error("invalid document accepted")
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package jsonschema
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Validate checks a decoded JSON document against a schema produced by
+// Generate. It supports the keywords Generate emits (type, properties,
+// required, items, additionalProperties, enum) and returns every violation.
+func Validate(schema map[string]interface{}, doc interface{}) error {
+	var errs []string
+	validate(schema, doc, "$", &errs)
+	if len(errs) > 0 {
+		return fmt.Errorf("schema validation failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func validate(schema map[string]interface{}, doc interface{}, path string, errs *[]string) {
+	if want, ok := schema["type"].(string); ok && !matchesType(want, doc) {
+		*errs = append(*errs, fmt.Sprintf("%s: expected %s, got %s", path, want, typeName(doc)))
+		return
+	}
+
+	if enum, ok := schema["enum"]; ok && !inEnum(enum, doc) {
+		*errs = append(*errs, fmt.Sprintf("%s: value %v not in enum", path, doc))
+	}
+
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, name := range requiredNames(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, fmt.Sprintf("%s: missing required property %q", path, name))
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			propPath := path + "." + key
+			if prop, ok := properties[key].(map[string]interface{}); ok {
+				validate(prop, v[key], propPath, errs)
+			} else if extra, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+				validate(extra, v[key], propPath, errs)
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validate(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	}
+}
+
+func matchesType(want string, doc interface{}) bool {
+	switch want {
+	case "object":
+		_, ok := doc.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := doc.([]interface{})
+		return ok
+	case "string":
+		_, ok := doc.(string)
+		return ok
+	case "boolean":
+		_, ok := doc.(bool)
+		return ok
+	case "number":
+		_, ok := doc.(float64)
+		return ok
+	case "integer":
+		f, ok := doc.(float64)
+		return ok && f == math.Trunc(f)
+	case "null":
+		return doc == nil
+	}
+	return true
+}
+
+func typeName(doc interface{}) string {
+	switch doc.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	}
+	return fmt.Sprintf("%T", doc)
+}
+
+func requiredNames(v interface{}) []string {
+	switch r := v.(type) {
+	case []string:
+		return r
+	case []interface{}:
+		names := make([]string, 0, len(r))
+		for _, n := range r {
+			if s, ok := n.(string); ok {
+				names = append(names, s)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+func inEnum(enum interface{}, doc interface{}) bool {
+	switch values := enum.(type) {
+	case []string:
+		s, ok := doc.(string)
+		if !ok {
+			return false
+		}
+		for _, v := range values {
+			if v == s {
+				return true
+			}
+		}
+		return false
+	case []interface{}:
+		for _, v := range values {
+			if v == doc {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
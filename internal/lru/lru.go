@@ -0,0 +1,120 @@
+// Package lru is a size-bounded in-process cache whose entries expire a fixed
+// time after they are set, like keys written to Redis with a TTL.
+package lru
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache holds up to a fixed number of entries, evicting the least recently
+// used to make room. It is safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	maxEntries int
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu sync.Mutex
+	// order runs from the most to the least recently used entry
+	order   *list.List
+	entries map[K]*list.Element
+}
+
+// entry is a cached value and when it expires
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// New creates a cache of at most maxEntries entries, each expiring ttl after
+// it is set. maxEntries must be positive.
+func New[K comparable, V any](maxEntries int, ttl time.Duration) *Cache[K, V] {
+	return &Cache[K, V]{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		now:        time.Now,
+		order:      list.New(),
+		entries:    make(map[K]*list.Element, maxEntries),
+	}
+}
+
+// Get returns the value for key, reporting whether there was an unexpired one.
+// A hit makes the entry the most recently used.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	e := el.Value.(*entry[K, V])
+	if !c.now().Before(e.expires) {
+		c.remove(el)
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+// Set stores value under key, replacing any earlier value and restarting its
+// TTL, and evicts the least recently used entry if the cache is over capacity
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*entry[K, V])
+		e.value, e.expires = value, expires
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expires: expires})
+	if c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+// Delete removes key, reporting whether it was cached and unexpired
+func (c *Cache[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	live := c.now().Before(el.Value.(*entry[K, V]).expires)
+	c.remove(el)
+	return live
+}
+
+// Purge removes every entry, returning how many were unexpired
+func (c *Cache[K, V]) Purge() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now, live := c.now(), 0
+	for el := c.order.Front(); el != nil; el = el.Next() {
+		if now.Before(el.Value.(*entry[K, V]).expires) {
+			live++
+		}
+	}
+	c.order.Init()
+	c.entries = make(map[K]*list.Element, c.maxEntries)
+	return live
+}
+
+// Len returns the number of entries held, including expired ones not yet removed
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// remove drops an entry with mu held
+func (c *Cache[K, V]) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*entry[K, V]).key)
+}
@@ -0,0 +1,99 @@
+package lru
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New[string, int](2, time.Hour)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	// Reading a makes b the least recently used
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %d, %v; want 1, true", v, ok)
+	}
+	c.Set("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b is still cached; want it evicted")
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if v, ok := c.Get(key); !ok || v != want {
+			t.Errorf("Get(%s) = %d, %v; want %d, true", key, v, ok, want)
+		}
+	}
+	if n := c.Len(); n != 2 {
+		t.Errorf("Len = %d; want 2", n)
+	}
+}
+
+func TestCacheExpiresEntries(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	c := New[string, int](10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1)
+	now = now.Add(59 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a expired before its TTL")
+	}
+	// Setting a key again restarts its TTL
+	c.Set("a", 2)
+	now = now.Add(59 * time.Second)
+	if v, ok := c.Get("a"); !ok || v != 2 {
+		t.Fatalf("Get(a) after reset = %d, %v; want 2, true", v, ok)
+	}
+	now = now.Add(time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Error("a is still cached at its TTL")
+	}
+	if n := c.Len(); n != 0 {
+		t.Errorf("Len = %d; want the expired entry removed", n)
+	}
+}
+
+func TestCacheDeleteAndPurge(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	c := New[string, int](10, time.Minute)
+	c.now = func() time.Time { return now }
+	c.Set("old", 0)
+	now = now.Add(2 * time.Minute)
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	if !c.Delete("a") || c.Delete("a") {
+		t.Error("Delete(a) should report a removed once")
+	}
+	if n := c.Purge(); n != 1 {
+		t.Errorf("Purge = %d; want only b counted", n)
+	}
+	if n := c.Len(); n != 0 {
+		t.Errorf("Len after Purge = %d; want 0", n)
+	}
+}
+
+func TestCacheConcurrentUse(t *testing.T) {
+	c := New[string, int](64, time.Hour)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := strconv.Itoa((g*1000 + i) % 100)
+				c.Set(key, i)
+				c.Get(key)
+				if i%10 == 0 {
+					c.Delete(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if n := c.Len(); n > 64 {
+		t.Errorf("Len = %d; want at most 64", n)
+	}
+}
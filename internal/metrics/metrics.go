@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultWindow is the number of recent requests latency figures are computed over
+const DefaultWindow = 200
+
+// Counter names recorded by the service
+const (
+	// RequestsShed counts cache misses rejected because upstream capacity was saturated
+	RequestsShed = "requests_shed"
+	// RequestsOutOfCoverage counts coordinates found outside NWS coverage without
+	// an upstream call, whether turned away or sent to the fallback provider
+	RequestsOutOfCoverage = "requests_out_of_coverage"
+	// NWSCircuitOpened counts the NWS circuit breaker opening after repeated failures
+	NWSCircuitOpened = "nws_circuit_opened"
+	// RequestsCircuitOpen counts NWS requests refused while the circuit breaker was open
+	RequestsCircuitOpen = "requests_circuit_open"
+)
+
+// Recorder tracks process uptime, a rolling window of request latencies, and named counters
+type Recorder struct {
+	startedAt time.Time
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+	full      bool
+	counters  map[string]int64
+}
+
+// NewRecorder creates a recorder keeping the last window request latencies
+func NewRecorder(window int) *Recorder {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Recorder{
+		startedAt: time.Now(),
+		latencies: make([]time.Duration, window),
+		counters:  make(map[string]int64),
+	}
+}
+
+// Observe records the latency of a single request
+func (r *Recorder) Observe(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latencies[r.next] = d
+	r.next = (r.next + 1) % len(r.latencies)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Inc increments the named counter
+func (r *Recorder) Inc(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counters[name]++
+}
+
+// Counters returns a snapshot of all counters
+func (r *Recorder) Counters() map[string]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := make(map[string]int64, len(r.counters))
+	for name, v := range r.counters {
+		snapshot[name] = v
+	}
+	return snapshot
+}
+
+// Uptime returns how long the recorder (and so the process) has been running
+func (r *Recorder) Uptime() time.Duration {
+	return time.Since(r.startedAt)
+}
+
+// RecentLatency returns the median latency over the recent window. The second
+// return value is false when no requests have been observed yet.
+func (r *Recorder) RecentLatency() (time.Duration, bool) {
+	r.mu.Lock()
+	n := r.next
+	if r.full {
+		n = len(r.latencies)
+	}
+	samples := make([]time.Duration, n)
+	copy(samples, r.latencies[:n])
+	r.mu.Unlock()
+
+	if n == 0 {
+		return 0, false
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return samples[n/2], true
+}
+
+// Middleware returns a Fiber handler that records the latency of every request it wraps
+func (r *Recorder) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+		r.Observe(time.Since(start))
+		return err
+	}
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRecentLatency(t *testing.T) {
+	r := NewRecorder(3)
+
+	if _, ok := r.RecentLatency(); ok {
+		t.Error("RecentLatency reported a value before any observations")
+	}
+
+	tests := []struct {
+		observe time.Duration
+		want    time.Duration
+	}{
+		{10 * time.Millisecond, 10 * time.Millisecond},
+		{30 * time.Millisecond, 30 * time.Millisecond},
+		{20 * time.Millisecond, 20 * time.Millisecond},
+		// Window is full: the 10ms sample is evicted
+		{40 * time.Millisecond, 30 * time.Millisecond},
+		{50 * time.Millisecond, 40 * time.Millisecond},
+	}
+	for i, tt := range tests {
+		r.Observe(tt.observe)
+		got, ok := r.RecentLatency()
+		if !ok || got != tt.want {
+			t.Errorf("after observation %d: RecentLatency() = %v, %v; want %v, true", i, got, ok, tt.want)
+		}
+	}
+}
+
+func TestMiddlewareRecordsRequests(t *testing.T) {
+	r := NewRecorder(0)
+	app := fiber.New()
+	app.Use(r.Middleware())
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	if _, err := app.Test(httptest.NewRequest("GET", "/", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.RecentLatency(); !ok {
+		t.Error("middleware did not record the request latency")
+	}
+}
@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+)
+
+// DefaultRequestLogBuffer is how many entries may queue between flushes before new ones are dropped
+const DefaultRequestLogBuffer = 4096
+
+// cacheHitKey is the Fiber local a handler sets to report whether it was served from cache
+const cacheHitKey = "metrics.cacheHit"
+
+// coordinatesKey is the Fiber local a handler sets to the coordinates it parsed
+const coordinatesKey = "metrics.coordinates"
+
+// RequestLogStore persists batches of request log entries
+type RequestLogStore interface {
+	SaveRequestLog(ctx context.Context, entries []models.RequestLogEntry) error
+}
+
+// RequestLog records API requests and writes them to a store in batches,
+// off the request path
+type RequestLog struct {
+	store   RequestLogStore
+	entries chan models.RequestLogEntry
+	dropped atomic.Int64
+}
+
+// NewRequestLog creates a request log queueing up to buffer entries between flushes
+func NewRequestLog(store RequestLogStore, buffer int) *RequestLog {
+	if buffer <= 0 {
+		buffer = DefaultRequestLogBuffer
+	}
+	return &RequestLog{store: store, entries: make(chan models.RequestLogEntry, buffer)}
+}
+
+// MarkCacheHit records whether the current request was answered from a fresh cache
+func MarkCacheHit(c *fiber.Ctx, hit bool) {
+	c.Locals(cacheHitKey, hit)
+}
+
+// MarkCoordinates records the coordinates a handler parsed from the request, so
+// the request log doesn't parse the query again
+func MarkCoordinates(c *fiber.Ctx, coords models.Coordinates) {
+	c.Locals(coordinatesKey, coords)
+}
+
+// Record queues an entry, dropping it rather than blocking when the buffer is full
+func (l *RequestLog) Record(entry models.RequestLogEntry) {
+	select {
+	case l.entries <- entry:
+	default:
+		l.dropped.Add(1)
+	}
+}
+
+// Middleware returns a Fiber handler that records every request it wraps
+func (l *RequestLog) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if fe, ok := err.(*fiber.Error); ok {
+				status = fe.Code
+			}
+		}
+
+		l.Record(models.RequestLogEntry{
+			Timestamp:  start,
+			Route:      c.Route().Path,
+			Status:     status,
+			LatencyMs:  float64(time.Since(start).Microseconds()) / 1000,
+			Coordinate: requestCoordinate(c),
+			CacheHit:   cacheHit(c),
+		})
+		return err
+	}
+}
+
+// requestCoordinate returns the request's coordinates normalized to four
+// decimals ("%.4f,%.4f"), or "" when the request doesn't carry a valid
+// coordinate. Coordinates the handler marked are used without reparsing.
+func requestCoordinate(c *fiber.Ctx) string {
+	coords, ok := c.Locals(coordinatesKey).(models.Coordinates)
+	if !ok {
+		lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+		lon, errLon := strconv.ParseFloat(c.Query("lon"), 64)
+		if errLat != nil || errLon != nil {
+			return ""
+		}
+		coords = models.Coordinates{Latitude: lat, Longitude: lon}
+	}
+
+	var buf [48]byte
+	b := strconv.AppendFloat(buf[:0], coords.Latitude, 'f', 4, 64)
+	b = append(b, ',')
+	b = strconv.AppendFloat(b, coords.Longitude, 'f', 4, 64)
+	return string(b)
+}
+
+// cacheHit reports the cache outcome set by the handler, or by the HTTP
+// response cache when it answered without running the handler
+func cacheHit(c *fiber.Ctx) *bool {
+	if hit, ok := c.Locals(cacheHitKey).(bool); ok {
+		return &hit
+	}
+	if c.GetRespHeader("X-Response-Cache") == "HIT" {
+		hit := true
+		return &hit
+	}
+	return nil
+}
+
+// Run writes queued entries every flushEvery until ctx is cancelled, then flushes what remains
+func (l *RequestLog) Run(ctx context.Context, flushEvery time.Duration) {
+	ticker := time.NewTicker(flushEvery)
+	defer ticker.Stop()
+
+	var batch []models.RequestLogEntry
+	flush := func() {
+		if n := l.dropped.Swap(0); n > 0 {
+			log.Printf("Request log buffer full, dropped %d entries", n)
+		}
+		if len(batch) == 0 {
+			return
+		}
+		// The last flush runs once ctx is cancelled
+		if err := l.store.SaveRequestLog(context.WithoutCancel(ctx), batch); err != nil {
+			log.Printf("Failed to write %d request log entries: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case entry := <-l.entries:
+			batch = append(batch, entry)
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+			for {
+				select {
+				case entry := <-l.entries:
+					batch = append(batch, entry)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
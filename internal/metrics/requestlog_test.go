@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+)
+
+type memoryStore struct {
+	mu      sync.Mutex
+	entries []models.RequestLogEntry
+}
+
+func (m *memoryStore) SaveRequestLog(ctx context.Context, entries []models.RequestLogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, entries...)
+	return nil
+}
+
+func TestRequestLogMiddleware(t *testing.T) {
+	store := &memoryStore{}
+	l := NewRequestLog(store, 0)
+
+	app := fiber.New()
+	api := app.Group("/api")
+	api.Use(l.Middleware())
+	api.Get("/weather", func(c *fiber.Ctx) error {
+		MarkCacheHit(c, c.Query("lat") == "1")
+		return c.SendString("ok")
+	})
+	api.Get("/cached", func(c *fiber.Ctx) error {
+		c.Set("X-Response-Cache", "HIT")
+		return c.SendString("ok")
+	})
+	api.Get("/fail", func(c *fiber.Ctx) error { return fiber.ErrBadRequest })
+	api.Get("/marked", func(c *fiber.Ctx) error {
+		MarkCoordinates(c, models.Coordinates{Latitude: 40.71284, Longitude: -74.006})
+		return c.SendString("ok")
+	})
+
+	for _, url := range []string{"/api/weather?lat=1&lon=2.123456", "/api/weather?lat=3&lon=4", "/api/cached", "/api/fail", "/api/marked"} {
+		if _, err := app.Test(httptest.NewRequest("GET", url, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.Run(ctx, time.Hour)
+
+	tests := []struct {
+		route      string
+		status     int
+		coordinate string
+		cacheHit   *bool
+	}{
+		{"/api/weather", 200, "1.0000,2.1235", ptr(true)},
+		{"/api/weather", 200, "3.0000,4.0000", ptr(false)},
+		{"/api/cached", 200, "", ptr(true)},
+		{"/api/fail", 400, "", nil},
+		{"/api/marked", 200, "40.7128,-74.0060", nil},
+	}
+	if len(store.entries) != len(tests) {
+		t.Fatalf("logged %d entries; want %d", len(store.entries), len(tests))
+	}
+	for i, tt := range tests {
+		got := store.entries[i]
+		if got.Route != tt.route || got.Status != tt.status || got.Coordinate != tt.coordinate {
+			t.Errorf("entry %d = %s %d %q; want %s %d %q", i, got.Route, got.Status, got.Coordinate, tt.route, tt.status, tt.coordinate)
+		}
+		if (got.CacheHit == nil) != (tt.cacheHit == nil) || (got.CacheHit != nil && *got.CacheHit != *tt.cacheHit) {
+			t.Errorf("entry %d CacheHit = %v; want %v", i, got.CacheHit, tt.cacheHit)
+		}
+	}
+}
+
+func ptr(v bool) *bool { return &v }
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/i18n"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/negotiate"
+)
+
+// RequireAdminToken returns middleware that only lets through requests carrying
+// the admin token as "Authorization: Bearer <token>"
+func RequireAdminToken(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		presented, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+			return negotiate.Send(c.Status(fiber.StatusUnauthorized), i18n.Error(c, models.ErrorCodeUnauthorized))
+		}
+		return c.Next()
+	}
+}
+
+// RequireAdminTokenOrAPIKey returns middleware that lets through requests that
+// authenticated with an API key, as recorded by RequireAPIKey, or that carry
+// the admin token. With an empty token only API key holders are let through.
+func RequireAdminTokenOrAPIKey(token string) fiber.Handler {
+	admin := RequireAdminToken(token)
+	return func(c *fiber.Ctx) error {
+		if APIKeyID(c) != "" {
+			return c.Next()
+		}
+		return admin(c)
+	}
+}
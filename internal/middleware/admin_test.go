@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequireAdminToken(t *testing.T) {
+	app := fiber.New()
+	app.Get("/admin", RequireAdminToken("s3cret"), func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"no credentials", "", fiber.StatusUnauthorized},
+		{"wrong token", "Bearer nope", fiber.StatusUnauthorized},
+		{"wrong scheme", "Basic s3cret", fiber.StatusUnauthorized},
+		{"admin token", "Bearer s3cret", fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/admin", nil)
+		if tt.authorization != "" {
+			req.Header.Set("Authorization", tt.authorization)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d; want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestRequireAdminTokenOrAPIKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		apiKey        string
+		authorization string
+		want          int
+	}{
+		{"no credentials", "s3cret", "", "", fiber.StatusUnauthorized},
+		{"wrong token", "s3cret", "", "Bearer nope", fiber.StatusUnauthorized},
+		{"admin token", "s3cret", "", "Bearer s3cret", fiber.StatusOK},
+		{"API key", "s3cret", "a", "", fiber.StatusOK},
+		{"API key without an admin token", "", "a", "", fiber.StatusOK},
+		{"nothing accepted without an admin token or key", "", "", "Bearer ", fiber.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		app := fiber.New()
+		// The key is only checked when one is sent, so admin token requests get through
+		app.Use(func(c *fiber.Ctx) error {
+			if c.Get(APIKeyHeader) == "" {
+				return c.Next()
+			}
+			return RequireAPIKey(APIKeyConfig{Keys: ParseAPIKeys([]string{"alice:a"})})(c)
+		})
+		app.Get("/raw", RequireAdminTokenOrAPIKey(tt.token), func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+		req := httptest.NewRequest("GET", "/raw", nil)
+		if tt.apiKey != "" {
+			req.Header.Set(APIKeyHeader, tt.apiKey)
+		}
+		if tt.authorization != "" {
+			req.Header.Set("Authorization", tt.authorization)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d; want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/i18n"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/negotiate"
+)
+
+// APIKeyHeader carries a client's API key
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyIDKey is the Locals key holding the authenticated client's key ID
+const apiKeyIDKey = "middleware.apiKeyID"
+
+// HashAPIKey returns the hex-encoded SHA-256 of a key, the form keys are stored in
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseAPIKeys turns API_KEYS entries into keys. An entry is either "id:key"
+// or a bare key, whose ID is then derived from its hash.
+func ParseAPIKeys(entries []string) []models.APIKey {
+	keys := make([]models.APIKey, 0, len(entries))
+	for _, entry := range entries {
+		id, key, named := strings.Cut(entry, ":")
+		if !named {
+			key = entry
+		}
+		hash := HashAPIKey(key)
+		if !named || id == "" {
+			id = "key-" + hash[:8]
+		}
+		keys = append(keys, models.APIKey{ID: id, KeyHash: hash})
+	}
+	return keys
+}
+
+// APIKeyConfig configures API key authentication
+type APIKeyConfig struct {
+	// Keys are the accepted client keys; with none, every request is let through
+	Keys []models.APIKey
+	// Next skips authentication for requests it returns true for, such as health checks
+	Next func(c *fiber.Ctx) bool
+}
+
+// apiKeyEntry is an accepted key with its hash decoded for comparison
+type apiKeyEntry struct {
+	id   string
+	hash []byte
+}
+
+// RequireAPIKey returns middleware that requires a known key in the X-API-Key
+// header: 401 when it is missing and 403 when it matches no client. The
+// presented key's hash is compared against every accepted key in constant
+// time, and the matching key's ID is recorded for APIKeyID. Keys whose stored
+// hash is not valid hex are ignored.
+func RequireAPIKey(cfg APIKeyConfig) fiber.Handler {
+	var entries []apiKeyEntry
+	for _, key := range cfg.Keys {
+		hash, err := hex.DecodeString(key.KeyHash)
+		if err != nil || len(hash) != sha256.Size {
+			continue
+		}
+		entries = append(entries, apiKeyEntry{id: key.ID, hash: hash})
+	}
+	if len(entries) == 0 {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+
+	return func(c *fiber.Ctx) error {
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		presented := c.Get(APIKeyHeader)
+		if presented == "" {
+			return negotiate.Send(c.Status(fiber.StatusUnauthorized), i18n.Error(c, models.ErrorCodeAPIKeyRequired))
+		}
+
+		sum := sha256.Sum256([]byte(presented))
+		var id string
+		for _, entry := range entries {
+			// No early exit, so timing doesn't reveal which key matched
+			if subtle.ConstantTimeCompare(sum[:], entry.hash) == 1 {
+				id = entry.id
+			}
+		}
+		if id == "" {
+			return negotiate.Send(c.Status(fiber.StatusForbidden), i18n.Error(c, models.ErrorCodeAPIKeyInvalid))
+		}
+
+		c.Locals(apiKeyIDKey, id)
+		return c.Next()
+	}
+}
+
+// APIKeyID returns the ID of the API key a request authenticated with, or ""
+// when API keys are not required or the route skips them
+func APIKeyID(c *fiber.Ctx) string {
+	id, _ := c.Locals(apiKeyIDKey).(string)
+	return id
+}
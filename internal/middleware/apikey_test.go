@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	newApp := func(keys []models.APIKey) *fiber.App {
+		app := fiber.New()
+		app.Use(RequireAPIKey(APIKeyConfig{
+			Keys: keys,
+			Next: func(c *fiber.Ctx) bool { return c.Path() == "/health" },
+		}))
+		app.Get("/weather", func(c *fiber.Ctx) error { return c.SendString("client=" + APIKeyID(c)) })
+		app.Get("/health", func(c *fiber.Ctx) error { return c.SendString("ok") })
+		return app
+	}
+
+	keys := append(ParseAPIKeys([]string{"dashboard:d4sh", "b4re"}), models.APIKey{ID: "stored", KeyHash: HashAPIKey("st0red")})
+	tests := []struct {
+		name     string
+		keys     []models.APIKey
+		path     string
+		key      string
+		want     int
+		wantCode string
+		wantBody string
+	}{
+		{"open mode", nil, "/weather", "", fiber.StatusOK, "", "client="},
+		{"open mode ignores keys", nil, "/weather", "anything", fiber.StatusOK, "", "client="},
+		{"named key", keys, "/weather", "d4sh", fiber.StatusOK, "", "client=dashboard"},
+		{"bare key", keys, "/weather", "b4re", fiber.StatusOK, "", "client=key-" + HashAPIKey("b4re")[:8]},
+		{"stored key", keys, "/weather", "st0red", fiber.StatusOK, "", "client=stored"},
+		{"missing key", keys, "/weather", "", fiber.StatusUnauthorized, models.ErrorCodeAPIKeyRequired, ""},
+		{"bad key", keys, "/weather", "nope", fiber.StatusForbidden, models.ErrorCodeAPIKeyInvalid, ""},
+		{"key id is not a key", keys, "/weather", "dashboard", fiber.StatusForbidden, models.ErrorCodeAPIKeyInvalid, ""},
+		{"health stays open", keys, "/health", "", fiber.StatusOK, "", "ok"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set(APIKeyHeader, tt.key)
+			}
+			resp, err := newApp(tt.keys).Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d; want %d", resp.StatusCode, tt.want)
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			if tt.wantCode != "" {
+				var errResp models.ErrorResponse
+				if err := json.Unmarshal(body, &errResp); err != nil || errResp.Code != tt.wantCode || errResp.Error == "" {
+					t.Errorf("body = %s; want an ErrorResponse with code %s", body, tt.wantCode)
+				}
+				return
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q; want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
+	"github.com/valyala/fasthttp"
+)
+
+// CompressConfig configures response compression
+type CompressConfig struct {
+	// Level trades CPU for size; compress.LevelDisabled turns compression off
+	Level compress.Level
+	// MinSize is the smallest body, in bytes, that is compressed. Bodies under
+	// 200 bytes are never compressed, whatever the setting.
+	MinSize int
+}
+
+// Compress returns middleware that compresses response bodies with brotli or
+// gzip, as the client's Accept-Encoding allows. Unlike Fiber's compress
+// middleware it decides once the handler has run, so it can leave alone
+// bodies under MinSize, bodiless responses such as 304 Not Modified, and
+// streamed bodies, including server-sent event streams, which compression
+// would hold back in its buffers. It must run outside the response cache, so
+// cached bodies stay uncompressed and are compressed per client.
+func Compress(cfg CompressConfig) fiber.Handler {
+	var brotliLevel, gzipLevel int
+	switch cfg.Level {
+	case compress.LevelDisabled:
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	case compress.LevelBestSpeed:
+		brotliLevel, gzipLevel = fasthttp.CompressBrotliBestSpeed, fasthttp.CompressBestSpeed
+	case compress.LevelBestCompression:
+		brotliLevel, gzipLevel = fasthttp.CompressBrotliBestCompression, fasthttp.CompressBestCompression
+	default:
+		brotliLevel, gzipLevel = fasthttp.CompressBrotliDefaultCompression, fasthttp.CompressDefaultCompression
+	}
+	compressor := fasthttp.CompressHandlerBrotliLevel(func(*fasthttp.RequestCtx) {}, brotliLevel, gzipLevel)
+
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+		if compressible(c.Response(), cfg.MinSize) {
+			compressor(c.Context())
+		}
+		return nil
+	}
+}
+
+// compressible reports whether a response's body is worth compressing: a
+// buffered body of at least minSize bytes that isn't an event stream
+func compressible(resp *fasthttp.Response, minSize int) bool {
+	if resp.IsBodyStream() || strings.HasPrefix(string(resp.Header.ContentType()), "text/event-stream") {
+		return false
+	}
+	return len(resp.Body()) >= max(minSize, 1)
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
+	"github.com/valyala/fasthttp"
+)
+
+// newCompressedApp serves a JSON forecast of the given number of periods, an
+// event stream, and a 304 behind the compression middleware
+func newCompressedApp(cfg CompressConfig) *fiber.App {
+	app := fiber.New()
+	app.Use(Compress(cfg))
+	app.Get("/forecast", func(c *fiber.Ctx) error {
+		periods := make([]fiber.Map, c.QueryInt("periods", 50))
+		for i := range periods {
+			periods[i] = fiber.Map{"name": fmt.Sprintf("Period %d", i), "short_forecast": "Partly Cloudy", "temperature": 60 + i%10}
+		}
+		c.Set(fiber.HeaderETag, `W/"abc"`)
+		return c.JSON(fiber.Map{"periods": periods})
+	})
+	app.Get("/stream", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, "text/event-stream")
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			for i := 0; i < 3; i++ {
+				fmt.Fprintf(w, "data: %s\n\n", strings.Repeat("x", 500))
+				w.Flush()
+			}
+		})
+		return nil
+	})
+	app.Get("/unchanged", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderETag, `W/"abc"`)
+		c.Status(fiber.StatusNotModified)
+		return nil
+	})
+	return app
+}
+
+// fetch performs a GET with the given Accept-Encoding and returns the
+// response's Content-Encoding and raw body
+func fetch(t *testing.T, app *fiber.App, target, acceptEncoding string) (string, []byte) {
+	t.Helper()
+	req := httptest.NewRequest("GET", target, nil)
+	if acceptEncoding != "" {
+		req.Header.Set(fiber.HeaderAcceptEncoding, acceptEncoding)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.Header.Get(fiber.HeaderContentEncoding), body
+}
+
+func TestCompressGzip(t *testing.T) {
+	app := newCompressedApp(CompressConfig{MinSize: 1024})
+
+	encoding, plain := fetch(t, app, "/forecast", "")
+	if encoding != "" {
+		t.Fatalf("Content-Encoding without Accept-Encoding = %q; want none", encoding)
+	}
+	encoding, compressed := fetch(t, app, "/forecast", "gzip")
+	if encoding != "gzip" {
+		t.Fatalf("Content-Encoding = %q; want gzip", encoding)
+	}
+	if len(compressed) >= len(plain) {
+		t.Errorf("gzip body is %d bytes; want it smaller than the %d-byte original", len(compressed), len(plain))
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decompressed, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decompressed, plain) {
+		t.Errorf("decompressed body differs from the uncompressed response:\n%s\nwant\n%s", decompressed, plain)
+	}
+}
+
+func TestCompressEncodings(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            CompressConfig
+		target         string
+		acceptEncoding string
+		want           string
+	}{
+		{"brotli preferred", CompressConfig{MinSize: 1024}, "/forecast", "gzip, br", "br"},
+		{"best compression", CompressConfig{Level: compress.LevelBestCompression, MinSize: 1024}, "/forecast", "gzip", "gzip"},
+		{"below min size", CompressConfig{MinSize: 1024}, "/forecast?periods=2", "gzip", ""},
+		{"min size lowered", CompressConfig{MinSize: 200}, "/forecast?periods=4", "gzip", "gzip"},
+		{"disabled", CompressConfig{Level: compress.LevelDisabled}, "/forecast", "gzip", ""},
+		{"event stream", CompressConfig{MinSize: 1024}, "/stream", "gzip", ""},
+		{"not modified", CompressConfig{MinSize: 0}, "/unchanged", "gzip", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoding, body := fetch(t, newCompressedApp(tt.cfg), tt.target, tt.acceptEncoding)
+			if encoding != tt.want {
+				t.Errorf("Content-Encoding = %q; want %q", encoding, tt.want)
+			}
+			if tt.want == "br" {
+				if _, err := fasthttp.AppendUnbrotliBytes(nil, body); err != nil {
+					t.Errorf("brotli body doesn't decode: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/i18n"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/negotiate"
+)
+
+// Quota headers set on every response to a key with a daily quota
+const (
+	QuotaLimitHeader     = "X-Quota-Limit"
+	QuotaRemainingHeader = "X-Quota-Remaining"
+	// QuotaResetHeader is the number of seconds until the quota period ends
+	QuotaResetHeader = "X-Quota-Reset"
+)
+
+// quotaUsageKey is the Locals key holding the key's QuotaUsage
+const quotaUsageKey = "middleware.quotaUsage"
+
+// usageDayLayout formats the UTC day a request is counted on
+const usageDayLayout = "2006-01-02"
+
+// defaultUsageHistory is how many days GET /me/usage reports by default
+const defaultUsageHistory = 7
+
+// UsageStore keeps each API key's daily request counts
+type UsageStore interface {
+	// TakeAPIKeyQuota counts a request on day unless the key has made quota
+	// requests already, returning the day's count and whether it was counted
+	TakeAPIKeyQuota(ctx context.Context, keyID, day string, quota int) (int, bool, error)
+	// APIKeyUsage returns the key's count for day
+	APIKeyUsage(ctx context.Context, keyID, day string) (int, error)
+	// APIKeyUsageHistory returns the key's counts for days in [from, to)
+	APIKeyUsageHistory(ctx context.Context, keyID, from, to string) ([]models.DailyUsage, error)
+}
+
+// QuotaUsage is an API key's quota state after its current request, the same
+// values Quota reports in the X-Quota-* headers
+type QuotaUsage struct {
+	// Quota is how many requests the key may make a day; zero means unlimited
+	Quota int
+	// Used is how many requests have counted against today's quota
+	Used int
+	// PeriodStart is midnight UTC today, and ResetAt the next midnight
+	PeriodStart time.Time
+	ResetAt     time.Time
+	// Reset is how long until ResetAt
+	Reset time.Duration
+}
+
+// Remaining is how many requests the key has left today, when it has a quota
+func (u QuotaUsage) Remaining() int {
+	return max(u.Quota-u.Used, 0)
+}
+
+// ResetSeconds is Reset rounded up to whole seconds, as sent in X-Quota-Reset
+func (u QuotaUsage) ResetSeconds() int {
+	return int(math.Ceil(u.Reset.Seconds()))
+}
+
+// ClientQuotaUsage returns the quota state Quota recorded for the request's
+// API key; ok is false when the request didn't authenticate with a key or its
+// counters couldn't be read
+func ClientQuotaUsage(c *fiber.Ctx) (QuotaUsage, bool) {
+	usage, ok := c.Locals(quotaUsageKey).(QuotaUsage)
+	return usage, ok
+}
+
+// QuotaConfig configures per-key daily quotas
+type QuotaConfig struct {
+	// Keys are the accepted client keys, with their labels and quotas
+	Keys []models.APIKey
+	// Store keeps the daily counts
+	Store UsageStore
+	// Free reports the key's usage to requests it returns true for without
+	// counting them or refusing them, such as usage checks
+	Free func(c *fiber.Ctx) bool
+	// History is how many days, today included, GetUsage reports; defaults to 7
+	History int
+	// Now returns the current time; defaults to time.Now
+	Now func() time.Time
+}
+
+// Quota enforces each API key's daily quota and reports it to the key's
+// holder. Periods are UTC days.
+type Quota struct {
+	cfg  QuotaConfig
+	keys map[string]models.APIKey
+}
+
+// NewQuota creates a Quota for the keys in cfg
+func NewQuota(cfg QuotaConfig) *Quota {
+	if cfg.History <= 0 {
+		cfg.History = defaultUsageHistory
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	keys := make(map[string]models.APIKey, len(cfg.Keys))
+	for _, key := range cfg.Keys {
+		if key.Label == "" {
+			key.Label = key.ID
+		}
+		keys[key.ID] = key
+	}
+	return &Quota{cfg: cfg, keys: keys}
+}
+
+// Middleware counts each request authenticated with an API key against the
+// key's quota for the day. A key with a quota gets X-Quota-Limit,
+// X-Quota-Remaining, and X-Quota-Reset on every response, and ClientQuotaUsage
+// reports the same to the handler; a key over its quota gets 429 with
+// Retry-After until midnight UTC, and refused requests aren't counted. A
+// request whose count can't be updated is let through rather than failing
+// every client while the database is unavailable.
+func (q *Quota) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key, ok := q.keys[APIKeyID(c)]
+		if !ok {
+			return c.Next()
+		}
+
+		now := q.cfg.Now().UTC()
+		day := now.Format(usageDayLayout)
+		var used int
+		allowed := true
+		var err error
+		if q.cfg.Free != nil && q.cfg.Free(c) {
+			used, err = q.cfg.Store.APIKeyUsage(c.UserContext(), key.ID, day)
+		} else {
+			used, allowed, err = q.cfg.Store.TakeAPIKeyQuota(c.UserContext(), key.ID, day, key.DailyQuota)
+		}
+		if err != nil {
+			log.Printf("Quota count for API key %s failed, letting the request through: %v", key.ID, err)
+			return c.Next()
+		}
+
+		start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		usage := QuotaUsage{
+			Quota:       key.DailyQuota,
+			Used:        used,
+			PeriodStart: start,
+			ResetAt:     start.AddDate(0, 0, 1),
+		}
+		usage.Reset = usage.ResetAt.Sub(now)
+
+		if usage.Quota > 0 {
+			c.Set(QuotaLimitHeader, strconv.Itoa(usage.Quota))
+			c.Set(QuotaRemainingHeader, strconv.Itoa(usage.Remaining()))
+			c.Set(QuotaResetHeader, strconv.Itoa(usage.ResetSeconds()))
+		}
+		if !allowed {
+			retry := strconv.Itoa(usage.ResetSeconds())
+			c.Set(fiber.HeaderRetryAfter, retry)
+			return negotiate.Send(c.Status(fiber.StatusTooManyRequests),
+				i18n.Error(c, models.ErrorCodeAPIKeyQuotaExceeded, "quota", strconv.Itoa(usage.Quota), "retry", retry))
+		}
+		c.Locals(quotaUsageKey, usage)
+		return c.Next()
+	}
+}
+
+// history returns the key's counts for the configured number of days ending
+// with today, oldest first, taking today's from usage so it matches the headers
+func (q *Quota) history(ctx context.Context, keyID string, usage QuotaUsage) ([]models.DailyUsage, error) {
+	from := usage.PeriodStart.AddDate(0, 0, 1-q.cfg.History)
+	counts, err := q.cfg.Store.APIKeyUsageHistory(ctx, keyID, from.Format(usageDayLayout), usage.PeriodStart.Format(usageDayLayout))
+	if err != nil {
+		return nil, err
+	}
+	byDay := make(map[string]int, len(counts))
+	for _, day := range counts {
+		byDay[day.Date] = day.Requests
+	}
+
+	history := make([]models.DailyUsage, 0, q.cfg.History)
+	for day := from; day.Before(usage.PeriodStart); day = day.AddDate(0, 0, 1) {
+		date := day.Format(usageDayLayout)
+		history = append(history, models.DailyUsage{Date: date, Requests: byDay[date]})
+	}
+	return append(history, models.DailyUsage{Date: usage.PeriodStart.Format(usageDayLayout), Requests: usage.Used}), nil
+}
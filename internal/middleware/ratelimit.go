@@ -0,0 +1,276 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"weather-api-go/internal/i18n"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/negotiate"
+)
+
+// Rate limit headers set on every limited response
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	// RateLimitResetHeader is the number of seconds until the client's bucket
+	// is full again
+	RateLimitResetHeader = "X-RateLimit-Reset"
+)
+
+// rateUsageKey is the Locals key holding the client's RateUsage
+const rateUsageKey = "middleware.rateUsage"
+
+// RateUsage is a client's rate limit state after its current request, the
+// same values RateLimit reports in the X-RateLimit-* headers
+type RateUsage struct {
+	// Limit is the sustained number of requests allowed a minute
+	Limit int
+	// Burst is how many requests the bucket holds when full
+	Burst int
+	// Remaining is the number of whole requests left in the bucket
+	Remaining int
+	// Reset is how long until the bucket is full again, and ResetAt when
+	Reset   time.Duration
+	ResetAt time.Time
+}
+
+// ResetSeconds is Reset rounded up to whole seconds, as sent in X-RateLimit-Reset
+func (u RateUsage) ResetSeconds() int {
+	return int(math.Ceil(u.Reset.Seconds()))
+}
+
+// ClientRateUsage returns the rate limit state RateLimit recorded for the
+// request's client; ok is false when the request isn't rate limited
+func ClientRateUsage(c *fiber.Ctx) (RateUsage, bool) {
+	usage, ok := c.Locals(rateUsageKey).(RateUsage)
+	return usage, ok
+}
+
+// RateLimitConfig configures the per-client rate limit. Each client, identified
+// by its API key when it authenticated with one and by IP address otherwise,
+// gets a token bucket refilled at RequestsPerMinute and holding up to Burst.
+type RateLimitConfig struct {
+	// RequestsPerMinute is the sustained rate allowed per client; zero disables the limit
+	RequestsPerMinute int
+	// Burst is how many requests a client may send at once; defaults to RequestsPerMinute
+	Burst int
+	// Redis shares buckets across replicas; nil keeps them in memory
+	Redis *redis.Client
+	// RedisTimeout bounds each Redis lookup, after which the request is limited
+	// by the in-memory bucket instead; defaults to 100ms
+	RedisTimeout time.Duration
+	// Next skips the limit for requests it returns true for, such as health checks
+	Next func(c *fiber.Ctx) bool
+	// Free reports the client's bucket to requests it returns true for without
+	// taking from it or refusing them, such as usage checks
+	Free func(c *fiber.Ctx) bool
+	// Now returns the current time; defaults to time.Now
+	Now func() time.Time
+}
+
+// rateDecision is a bucket's answer to one request
+type rateDecision struct {
+	allowed bool
+	// remaining is the number of whole requests left in the bucket afterwards
+	remaining int
+	// retryAfter is how long until the next request would be allowed, when denied
+	retryAfter time.Duration
+	// reset is how long until the bucket is full again
+	reset time.Duration
+}
+
+// tokenBucket is the arithmetic shared by the memory and Redis limiters
+type tokenBucket struct {
+	perMilli float64 // tokens added per millisecond
+	burst    float64
+}
+
+// decide turns a bucket's token count after refilling into a decision and the
+// count to store, taking cost tokens: 1 for a request, 0 to only look
+func (b tokenBucket) decide(tokens, cost float64) (rateDecision, float64) {
+	if tokens >= cost {
+		tokens -= cost
+		return b.allowed(tokens), tokens
+	}
+	return rateDecision{retryAfter: b.refillTime(cost - tokens), reset: b.refillTime(b.burst - tokens)}, tokens
+}
+
+// allowed is the decision for a request that was let through, leaving tokens
+func (b tokenBucket) allowed(tokens float64) rateDecision {
+	return rateDecision{allowed: true, remaining: int(tokens), reset: b.refillTime(b.burst - tokens)}
+}
+
+// refillTime is how long the bucket takes to gain tokens
+func (b tokenBucket) refillTime(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens/b.perMilli)) * time.Millisecond
+}
+
+// memoryBucket is a client's bucket in the in-memory limiter
+type memoryBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// memoryLimiter keeps buckets in process memory
+type memoryLimiter struct {
+	tokenBucket
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+func (l *memoryLimiter) take(_ context.Context, client string, now time.Time, cost float64) rateDecision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Forget clients whose buckets have refilled, so the map stays bounded by
+	// recently active clients
+	full := time.Duration(l.burst/l.perMilli) * time.Millisecond
+	if now.Sub(l.lastSweep) > full {
+		for id, b := range l.buckets {
+			if now.Sub(b.last) > full {
+				delete(l.buckets, id)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &memoryBucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	elapsed := float64(now.Sub(b.last).Milliseconds())
+	b.last = now
+	var d rateDecision
+	d, b.tokens = l.decide(math.Min(l.burst, b.tokens+max(elapsed, 0)*l.perMilli), cost)
+	return d
+}
+
+// redisRateLimitTimeout bounds a Redis bucket lookup, so a slow Redis delays
+// requests by at most this before the in-memory bucket decides instead
+const redisRateLimitTimeout = 100 * time.Millisecond
+
+// takeScript refills and takes ARGV[4] tokens from a bucket stored as a Redis
+// hash. Token counts are returned as strings since Redis truncates Lua numbers
+// to integers.
+var takeScript = redis.NewScript(`
+local perMilli = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * perMilli)
+local allowed = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / perMilli))
+return {allowed, tostring(tokens)}
+`)
+
+// redisLimiter keeps buckets in Redis, falling back to memory when Redis fails
+// or times out. It never fails open: a client is still limited, per instance,
+// while Redis is unavailable.
+type redisLimiter struct {
+	tokenBucket
+	rdb      *redis.Client
+	timeout  time.Duration
+	fallback *memoryLimiter
+}
+
+func (l *redisLimiter) take(ctx context.Context, client string, now time.Time, cost float64) rateDecision {
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+	res, err := takeScript.Run(ctx, l.rdb, []string{"ratelimit:" + client},
+		strconv.FormatFloat(l.perMilli, 'g', -1, 64), strconv.FormatFloat(l.burst, 'g', -1, 64), now.UnixMilli(), cost,
+	).Slice()
+	if err != nil || len(res) != 2 {
+		log.Printf("Rate limit lookup in Redis failed, limiting in memory: %v", err)
+		return l.fallback.take(ctx, client, now, cost)
+	}
+	tokens, err := strconv.ParseFloat(res[1].(string), 64)
+	if err != nil {
+		return l.fallback.take(ctx, client, now, cost)
+	}
+	if res[0].(int64) == 1 {
+		return l.allowed(tokens)
+	}
+	d, _ := l.decide(tokens, cost)
+	return d
+}
+
+// RateLimit returns middleware enforcing a per-client rate limit. Every
+// limited response carries X-RateLimit-Limit, X-RateLimit-Remaining, and
+// X-RateLimit-Reset, and ClientRateUsage reports the same to the handler; a
+// denied request gets 429 with Retry-After. Free requests see the bucket
+// without taking from it.
+func RateLimit(cfg RateLimitConfig) fiber.Handler {
+	if cfg.RequestsPerMinute <= 0 {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = cfg.RequestsPerMinute
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+
+	bucket := tokenBucket{perMilli: float64(cfg.RequestsPerMinute) / float64(time.Minute.Milliseconds()), burst: float64(cfg.Burst)}
+	memory := &memoryLimiter{tokenBucket: bucket, buckets: map[string]*memoryBucket{}}
+	take := memory.take
+	if cfg.Redis != nil {
+		if cfg.RedisTimeout <= 0 {
+			cfg.RedisTimeout = redisRateLimitTimeout
+		}
+		take = (&redisLimiter{tokenBucket: bucket, rdb: cfg.Redis, timeout: cfg.RedisTimeout, fallback: memory}).take
+	}
+	limit := strconv.Itoa(cfg.RequestsPerMinute)
+
+	return func(c *fiber.Ctx) error {
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		client := "ip:" + c.IP()
+		if id := APIKeyID(c); id != "" {
+			client = "key:" + id
+		}
+		cost := 1.0
+		if cfg.Free != nil && cfg.Free(c) {
+			cost = 0
+		}
+		now := cfg.Now()
+		d := take(c.UserContext(), client, now, cost)
+		usage := RateUsage{
+			Limit:     cfg.RequestsPerMinute,
+			Burst:     cfg.Burst,
+			Remaining: d.remaining,
+			Reset:     d.reset,
+			ResetAt:   now.Add(d.reset),
+		}
+
+		c.Set(RateLimitLimitHeader, limit)
+		c.Set(RateLimitRemainingHeader, strconv.Itoa(usage.Remaining))
+		c.Set(RateLimitResetHeader, strconv.Itoa(usage.ResetSeconds()))
+		if !d.allowed {
+			retry := strconv.Itoa(int(math.Ceil(d.retryAfter.Seconds())))
+			c.Set(fiber.HeaderRetryAfter, retry)
+			return negotiate.Send(c.Status(fiber.StatusTooManyRequests),
+				i18n.Error(c, models.ErrorCodeRateLimited, "limit", limit, "retry", retry))
+		}
+		c.Locals(rateUsageKey, usage)
+		return c.Next()
+	}
+}
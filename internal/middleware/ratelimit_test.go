@@ -0,0 +1,273 @@
+package middleware
+
+import (
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeClock is a settable time source for rate limit tests
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.mu.Unlock()
+}
+
+func TestRateLimit(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	for _, tc := range []struct {
+		name string
+		rdb  *redis.Client
+	}{
+		{"memory", nil},
+		{"redis", rdb},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mr.FlushAll()
+			clock := &fakeClock{now: time.Now()}
+			app := fiber.New()
+			app.Use(RequireAPIKey(APIKeyConfig{
+				Keys: ParseAPIKeys([]string{"alice:a", "bob:b"}),
+				Next: func(c *fiber.Ctx) bool { return c.Path() == "/health" },
+			}))
+			// 60 a minute with a burst of 3: one request a second refills
+			app.Use(RateLimit(RateLimitConfig{
+				RequestsPerMinute: 60, Burst: 3, Redis: tc.rdb, Now: clock.Now,
+				Next: func(c *fiber.Ctx) bool { return c.Path() == "/health" },
+			}))
+			app.Get("/weather", func(c *fiber.Ctx) error { return c.SendString("ok") })
+			app.Get("/health", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+			send := func(path, key string) (int, string, string) {
+				t.Helper()
+				req := httptest.NewRequest("GET", path, nil)
+				if key != "" {
+					req.Header.Set(APIKeyHeader, key)
+				}
+				resp, err := app.Test(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.StatusCode == fiber.StatusOK && path != "/health" && resp.Header.Get(RateLimitLimitHeader) != "60" {
+					t.Errorf("%s = %q; want 60", RateLimitLimitHeader, resp.Header.Get(RateLimitLimitHeader))
+				}
+				return resp.StatusCode, resp.Header.Get(RateLimitRemainingHeader), resp.Header.Get(fiber.HeaderRetryAfter)
+			}
+
+			for i := 0; i < 3; i++ {
+				status, remaining, _ := send("/weather", "a")
+				if status != fiber.StatusOK || remaining != strconv.Itoa(2-i) {
+					t.Fatalf("request %d: status %d, remaining %q; want 200 with %d remaining", i+1, status, remaining, 2-i)
+				}
+			}
+			status, remaining, retry := send("/weather", "a")
+			if status != fiber.StatusTooManyRequests || remaining != "0" || retry != "1" {
+				t.Errorf("request over the burst: status %d, remaining %q, Retry-After %q; want 429, 0, 1", status, remaining, retry)
+			}
+
+			// Health checks are never limited, and other clients have their own bucket
+			for i := 0; i < 5; i++ {
+				if status, _, _ := send("/health", ""); status != fiber.StatusOK {
+					t.Fatalf("health check %d: status %d; want 200", i+1, status)
+				}
+			}
+			if status, remaining, _ := send("/weather", "b"); status != fiber.StatusOK || remaining != "2" {
+				t.Errorf("another key: status %d, remaining %q; want 200 with 2 remaining", status, remaining)
+			}
+
+			// A second refills one request
+			clock.Advance(time.Second)
+			if status, remaining, _ := send("/weather", "a"); status != fiber.StatusOK || remaining != "0" {
+				t.Errorf("after a second: status %d, remaining %q; want 200 with 0 remaining", status, remaining)
+			}
+			if status, _, _ := send("/weather", "a"); status != fiber.StatusTooManyRequests {
+				t.Errorf("second request after refilling one: status %d; want 429", status)
+			}
+		})
+	}
+}
+
+func TestRateLimitByIP(t *testing.T) {
+	app := fiber.New()
+	app.Use(RateLimit(RateLimitConfig{RequestsPerMinute: 2}))
+	app.Get("/weather", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	var statuses []int
+	for i := 0; i < 3; i++ {
+		resp, err := app.Test(httptest.NewRequest("GET", "/weather", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		statuses = append(statuses, resp.StatusCode)
+		if resp.StatusCode == fiber.StatusTooManyRequests {
+			if retry, _ := strconv.Atoi(resp.Header.Get(fiber.HeaderRetryAfter)); retry < 1 || retry > 30 {
+				t.Errorf("Retry-After = %q; want the ~30s until the next token", resp.Header.Get(fiber.HeaderRetryAfter))
+			}
+		}
+	}
+	if statuses[0] != fiber.StatusOK || statuses[1] != fiber.StatusOK || statuses[2] != fiber.StatusTooManyRequests {
+		t.Errorf("statuses = %v; want the third request over a burst of 2 rejected", statuses)
+	}
+}
+
+func TestRateLimitFallsBackWhenRedisFails(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { rdb.Close() })
+	mr.Close()
+
+	app := fiber.New()
+	app.Use(RateLimit(RateLimitConfig{RequestsPerMinute: 60, Burst: 1, Redis: rdb}))
+	app.Get("/weather", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	var statuses []int
+	for i := 0; i < 2; i++ {
+		resp, err := app.Test(httptest.NewRequest("GET", "/weather", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		statuses = append(statuses, resp.StatusCode)
+	}
+	if statuses[0] != fiber.StatusOK || statuses[1] != fiber.StatusTooManyRequests {
+		t.Errorf("statuses = %v; want the in-memory limit applied", statuses)
+	}
+}
+
+func TestRateLimitFallsBackWhenRedisStalls(t *testing.T) {
+	// A server that accepts connections but never answers
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		var conns []net.Conn
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				for _, c := range conns {
+					c.Close()
+				}
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+	rdb := redis.NewClient(&redis.Options{Addr: ln.Addr().String(), MaxRetries: -1, ContextTimeoutEnabled: true})
+	t.Cleanup(func() { rdb.Close() })
+
+	app := fiber.New()
+	app.Use(RateLimit(RateLimitConfig{RequestsPerMinute: 60, Burst: 1, Redis: rdb, RedisTimeout: 50 * time.Millisecond}))
+	app.Get("/weather", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	var statuses []int
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		resp, err := app.Test(httptest.NewRequest("GET", "/weather", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		statuses = append(statuses, resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("two requests took %v; want each Redis lookup cut off after 50ms", elapsed)
+	}
+	if statuses[0] != fiber.StatusOK || statuses[1] != fiber.StatusTooManyRequests {
+		t.Errorf("statuses = %v; want the in-memory limit applied", statuses)
+	}
+}
+
+func TestClientRateUsage(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	for _, tc := range []struct {
+		name string
+		rdb  *redis.Client
+	}{
+		{"memory", nil},
+		{"redis", rdb},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mr.FlushAll()
+			clock := &fakeClock{now: time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)}
+			app := fiber.New()
+			app.Use(RateLimit(RateLimitConfig{RequestsPerMinute: 60, Burst: 3, Redis: tc.rdb, Now: clock.Now}))
+			var usage RateUsage
+			app.Get("/weather", func(c *fiber.Ctx) error {
+				var ok bool
+				if usage, ok = ClientRateUsage(c); !ok {
+					t.Error("ClientRateUsage reported no rate limit")
+				}
+				return c.SendString("ok")
+			})
+
+			// Each request taken from the bucket is a second of refilling
+			for i := 1; i <= 3; i++ {
+				resp, err := app.Test(httptest.NewRequest("GET", "/weather", nil))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if reset := resp.Header.Get(RateLimitResetHeader); reset != strconv.Itoa(i) {
+					t.Errorf("request %d: %s = %q; want %d", i, RateLimitResetHeader, reset, i)
+				}
+				want := RateUsage{Limit: 60, Burst: 3, Remaining: 3 - i, Reset: time.Duration(i) * time.Second,
+					ResetAt: clock.Now().Add(time.Duration(i) * time.Second)}
+				if usage != want {
+					t.Errorf("request %d: usage = %+v; want %+v", i, usage, want)
+				}
+				if remaining := resp.Header.Get(RateLimitRemainingHeader); remaining != strconv.Itoa(usage.Remaining) {
+					t.Errorf("request %d: %s = %q; want %d as in the usage", i, RateLimitRemainingHeader, remaining, usage.Remaining)
+				}
+			}
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/weather", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != fiber.StatusTooManyRequests || resp.Header.Get(RateLimitResetHeader) != "3" {
+				t.Errorf("request over the burst: status %d, %s %q; want 429, 3", resp.StatusCode, RateLimitResetHeader, resp.Header.Get(RateLimitResetHeader))
+			}
+		})
+	}
+}
+
+func TestClientRateUsageUnlimited(t *testing.T) {
+	app := fiber.New()
+	app.Use(RateLimit(RateLimitConfig{}))
+	app.Get("/weather", func(c *fiber.Ctx) error {
+		if _, ok := ClientRateUsage(c); ok {
+			t.Error("ClientRateUsage reported a limit with rate limiting disabled")
+		}
+		return c.SendString("ok")
+	})
+	resp, err := app.Test(httptest.NewRequest("GET", "/weather", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reset := resp.Header.Get(RateLimitResetHeader); reset != "" {
+		t.Errorf("%s = %q; want none", RateLimitResetHeader, reset)
+	}
+}
@@ -0,0 +1,306 @@
+package middleware
+
+import (
+	"bytes"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/repository"
+)
+
+// ResponseCacheHeader reports whether a response was served from the response cache
+const ResponseCacheHeader = "X-Response-Cache"
+
+// dataCacheHeader is the handlers' X-Cache header, reporting whether the
+// weather data came from the data cache. It is replayed on hits so it still
+// describes the data served.
+const dataCacheHeader = "X-Cache"
+
+// coordinateParams are normalized to the precision the data cache keys on
+// (repository.CacheCoordinatePrecision, as in services.WeatherKey), so
+// lat=40.7128 and lat=40.71281 share an entry like they share cached weather
+var coordinateParams = map[string]bool{"lat": true, "lon": true}
+
+// varyHeaders are request headers that select a different representation
+var varyHeaders = []string{fiber.HeaderAccept, fiber.HeaderAcceptLanguage}
+
+// ResponseCacheConfig configures the HTTP response cache
+type ResponseCacheConfig struct {
+	// MaxTTL caps how long a response is reused, even if its data stays fresh longer
+	MaxTTL time.Duration
+	// MaxEntries bounds the number of cached responses
+	MaxEntries int
+	// Now returns the current time; defaults to time.Now
+	Now func() time.Time
+}
+
+// DefaultResponseCacheConfig returns the default response cache settings
+func DefaultResponseCacheConfig() ResponseCacheConfig {
+	return ResponseCacheConfig{
+		MaxTTL:     time.Minute,
+		MaxEntries: 10000,
+	}
+}
+
+type cachedResponse struct {
+	status      int
+	contentType string
+	body        []byte
+	// etag and lastModified are the validators the handler set, replayed so
+	// clients can revalidate responses served from the cache
+	etag         string
+	lastModified string
+	// dataCache is the handler's X-Cache status
+	dataCache string
+	// vary and contentLanguage describe the representation stored, replayed
+	// so shared caches downstream key and label hits as they did the miss
+	vary            string
+	contentLanguage string
+	// expires is when the entry is evicted; freshUntil is when the underlying
+	// data goes stale, used to keep the served max-age honest
+	expires    time.Time
+	freshUntil time.Time
+}
+
+type responseCache struct {
+	cfg     ResponseCacheConfig
+	mu      sync.RWMutex
+	entries map[string]*cachedResponse
+}
+
+// ResponseCache returns middleware that stores successful GET responses keyed
+// by path, normalized query parameters and path coordinates, the
+// Accept/Accept-Language headers, and the API key the request authenticated
+// with. Coordinates that round together share an entry, as they share cached
+// weather. Entries live for the response's Cache-Control max-age, capped at
+// MaxTTL, so responses built from stale data are never reused. Only mount it
+// on public, non-personalized routes, after RequireAPIKey; requests carrying
+// an Authorization header or cookies always bypass it, as do conditional
+// requests, which the handler answers with 304 when unchanged.
+func ResponseCache(cfg ResponseCacheConfig) fiber.Handler {
+	defaults := DefaultResponseCacheConfig()
+	if cfg.MaxTTL <= 0 {
+		cfg.MaxTTL = defaults.MaxTTL
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = defaults.MaxEntries
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+
+	rc := &responseCache{cfg: cfg, entries: make(map[string]*cachedResponse)}
+	return rc.handle
+}
+
+func (rc *responseCache) handle(c *fiber.Ctx) error {
+	if c.Method() != fiber.MethodGet || isAuthenticated(c) || c.Get(fiber.HeaderIfNoneMatch) != "" {
+		return c.Next()
+	}
+
+	key := cacheKey(c)
+	now := rc.cfg.Now()
+
+	rc.mu.RLock()
+	entry, ok := rc.entries[key]
+	rc.mu.RUnlock()
+	if ok && now.Before(entry.expires) {
+		c.Set(ResponseCacheHeader, "HIT")
+		c.Set(fiber.HeaderContentType, entry.contentType)
+		c.Set(fiber.HeaderCacheControl, "public, max-age="+strconv.Itoa(int(entry.freshUntil.Sub(now).Seconds())))
+		if entry.etag != "" {
+			c.Set(fiber.HeaderETag, entry.etag)
+		}
+		if entry.lastModified != "" {
+			c.Set(fiber.HeaderLastModified, entry.lastModified)
+		}
+		if entry.dataCache != "" {
+			c.Set(dataCacheHeader, entry.dataCache)
+		}
+		if entry.contentLanguage != "" {
+			c.Set(fiber.HeaderContentLanguage, entry.contentLanguage)
+		}
+		for _, field := range strings.Split(entry.vary, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				c.Vary(field)
+			}
+		}
+		// Stored bodies are never modified, so the response can share them
+		c.Status(entry.status).Response().SetBodyRaw(entry.body)
+		return nil
+	}
+
+	if err := c.Next(); err != nil {
+		return err
+	}
+	c.Set(ResponseCacheHeader, "MISS")
+
+	// Reading a streamed body would buffer it whole, so streams aren't stored
+	resp := c.Response()
+	if resp.StatusCode() != fiber.StatusOK || resp.IsBodyStream() || len(resp.Header.Peek(fiber.HeaderSetCookie)) > 0 {
+		return nil
+	}
+	fresh := maxAge(string(resp.Header.Peek(fiber.HeaderCacheControl)))
+	ttl := fresh
+	if ttl > rc.cfg.MaxTTL {
+		ttl = rc.cfg.MaxTTL
+	}
+	if ttl <= 0 {
+		return nil
+	}
+
+	rc.store(key, &cachedResponse{
+		status:          resp.StatusCode(),
+		contentType:     string(resp.Header.ContentType()),
+		body:            append([]byte(nil), resp.Body()...),
+		etag:            string(resp.Header.Peek(fiber.HeaderETag)),
+		lastModified:    string(resp.Header.Peek(fiber.HeaderLastModified)),
+		dataCache:       string(resp.Header.Peek(dataCacheHeader)),
+		vary:            string(resp.Header.Peek(fiber.HeaderVary)),
+		contentLanguage: string(resp.Header.Peek(fiber.HeaderContentLanguage)),
+		expires:         now.Add(ttl),
+		freshUntil:      now.Add(fresh),
+	}, now)
+	return nil
+}
+
+// store saves an entry, sweeping expired entries when the cache is full. If
+// the cache is still full afterwards the entry is dropped.
+func (rc *responseCache) store(key string, entry *cachedResponse, now time.Time) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if _, exists := rc.entries[key]; !exists && len(rc.entries) >= rc.cfg.MaxEntries {
+		for k, e := range rc.entries {
+			if !now.Before(e.expires) {
+				delete(rc.entries, k)
+			}
+		}
+		if len(rc.entries) >= rc.cfg.MaxEntries {
+			return
+		}
+	}
+	rc.entries[key] = entry
+}
+
+// isAuthenticated reports whether the request carries credentials the cache
+// can't key on, which may make the response personalized. API keys are keyed
+// on by their ID instead.
+func isAuthenticated(c *fiber.Ctx) bool {
+	return c.Get(fiber.HeaderAuthorization) != "" || c.Get(fiber.HeaderCookie) != ""
+}
+
+// queryParam is a decoded query parameter, borrowed from the request's args
+type queryParam struct {
+	name, value []byte
+}
+
+// cacheKey builds the cache key from the path, the sorted query parameters
+// with coordinates normalized, the headers the response varies on, and the
+// authenticated API key's ID, so clients never share responses. It reads
+// the already-parsed request args rather than reparsing the query string, since
+// it runs on every cached request.
+func cacheKey(c *fiber.Ctx) string {
+	var params [8]queryParam
+	query := params[:0]
+	c.Request().URI().QueryArgs().VisitAll(func(name, value []byte) {
+		query = append(query, queryParam{name, value})
+	})
+	slices.SortFunc(query, func(a, b queryParam) int {
+		if n := bytes.Compare(a.name, b.name); n != 0 {
+			return n
+		}
+		return bytes.Compare(a.value, b.value)
+	})
+
+	b := make([]byte, 0, 128)
+	b = appendPath(b, c.Path())
+	for _, p := range query {
+		b = append(b, '|')
+		b = append(b, p.name...)
+		b = append(b, '=')
+		if coordinateParams[string(p.name)] {
+			if f, err := strconv.ParseFloat(string(p.value), 64); err == nil {
+				b = appendCoordinate(b, f)
+				continue
+			}
+		}
+		b = append(b, p.value...)
+	}
+	for _, h := range varyHeaders {
+		b = append(b, '|')
+		b = append(b, h...)
+		b = append(b, ':')
+		b = append(b, c.Request().Header.Peek(h)...)
+	}
+	b = append(b, "|key:"...)
+	b = append(b, APIKeyID(c)...)
+	return string(b)
+}
+
+// appendPath appends the request path, normalizing a trailing {lat}/{lon}
+// pair like the query coordinates, so /weather/40.7128/-74.0060 and
+// /weather/40.71281/%2D74.006/ share an entry
+func appendPath(b []byte, path string) []byte {
+	rest, lonStr := cutLastSegment(strings.TrimSuffix(path, "/"))
+	prefix, latStr := cutLastSegment(rest)
+	lat, latErr := parsePathCoordinate(latStr)
+	lon, lonErr := parsePathCoordinate(lonStr)
+	if latErr != nil || lonErr != nil {
+		return append(b, path...)
+	}
+	b = append(b, prefix...)
+	b = append(b, '/')
+	b = appendCoordinate(b, lat)
+	b = append(b, '/')
+	return appendCoordinate(b, lon)
+}
+
+// cutLastSegment splits a path before its last "/"
+func cutLastSegment(path string) (string, string) {
+	i := strings.LastIndexByte(path, '/')
+	if i < 0 {
+		return "", path
+	}
+	return path[:i], path[i+1:]
+}
+
+// parsePathCoordinate parses a coordinate path segment, which may be
+// URL-encoded (%2D74.0060)
+func parsePathCoordinate(segment string) (float64, error) {
+	segment, err := url.PathUnescape(segment)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(segment, 64)
+}
+
+// appendCoordinate appends a coordinate rounded to the data cache's precision
+func appendCoordinate(b []byte, v float64) []byte {
+	return strconv.AppendFloat(b, repository.NormalizeCoordinate(v), 'f', repository.CacheCoordinatePrecision, 64)
+}
+
+// maxAge extracts the max-age directive from a Cache-Control header. Responses
+// marked private, no-store, or no-cache are not reusable.
+func maxAge(cacheControl string) time.Duration {
+	var age time.Duration
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "private" || directive == "no-store" || directive == "no-cache":
+			return 0
+		case strings.HasPrefix(directive, "max-age="):
+			seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+			if err != nil {
+				return 0
+			}
+			age = time.Duration(seconds) * time.Second
+		}
+	}
+	return age
+}
@@ -0,0 +1,291 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// newCachedApp serves a counting handler behind the response cache. The
+// handler's status and Cache-Control come from the status and cc query params,
+// and it sets an ETag, Last-Modified, X-Cache, Content-Language, and Vary like
+// the weather handler.
+func newCachedApp(t *testing.T, cfg ResponseCacheConfig) (*fiber.App, *int) {
+	t.Helper()
+	calls := 0
+	app := fiber.New()
+	app.Use(ResponseCache(cfg))
+	handler := func(c *fiber.Ctx) error {
+		calls++
+		status := fiber.StatusOK
+		if s := c.Query("status"); s != "" {
+			status, _ = strconv.Atoi(s)
+		}
+		cc := "public, max-age=600"
+		if v := c.Query("cc"); v != "" {
+			cc = v
+		}
+		c.Set(fiber.HeaderCacheControl, cc)
+		c.Set(fiber.HeaderETag, `W/"`+strconv.Itoa(calls)+`"`)
+		c.Set(fiber.HeaderLastModified, "Mon, 15 Jan 2024 10:30:00 GMT")
+		c.Set(dataCacheHeader, "STALE")
+		c.Set(fiber.HeaderContentLanguage, "es")
+		c.Vary(fiber.HeaderAccept, fiber.HeaderAcceptLanguage)
+		if c.Query("stream") != "" {
+			c.Status(status).Context().SetBodyStream(strings.NewReader(`{"call": `+strconv.Itoa(calls)+`}`), -1)
+			return nil
+		}
+		return c.Status(status).JSON(fiber.Map{"call": calls})
+	}
+	app.Get("/api/weather", handler)
+	app.Get("/api/weather/:lat/:lon", handler)
+	app.Post("/api/weather", handler)
+	return app, &calls
+}
+
+// get performs a request and returns the X-Response-Cache header and body
+func get(t *testing.T, app *fiber.App, method, target string, headers map[string]string) (string, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return resp.Header.Get(ResponseCacheHeader), string(body)
+}
+
+func TestResponseCacheHitAndMiss(t *testing.T) {
+	app, calls := newCachedApp(t, ResponseCacheConfig{})
+
+	status, first := get(t, app, "GET", "/api/weather?lat=40.7128&lon=-74.006", nil)
+	if status != "MISS" {
+		t.Errorf("first request X-Response-Cache = %q; want MISS", status)
+	}
+	// Coordinates are rounded to the data cache's three decimals
+	for _, target := range []string{
+		"/api/weather?lon=-74.006000&lat=40.712800",
+		"/api/weather?lat=40.71281&lon=-74.00601",
+	} {
+		status, second := get(t, app, "GET", target, nil)
+		if status != "HIT" {
+			t.Errorf("%s: X-Response-Cache = %q; want HIT", target, status)
+		}
+		if first != second {
+			t.Errorf("%s: cached body = %s; want %s", target, second, first)
+		}
+	}
+	if *calls != 1 {
+		t.Errorf("handler called %d times; want 1", *calls)
+	}
+}
+
+func TestResponseCachePathCoordinates(t *testing.T) {
+	app, calls := newCachedApp(t, ResponseCacheConfig{})
+
+	get(t, app, "GET", "/api/weather/40.7128/-74.0060", nil)
+	for _, target := range []string{
+		"/api/weather/40.7128/-74.0060",
+		"/api/weather/40.71281/%2D74.006/",
+	} {
+		if status, _ := get(t, app, "GET", target, nil); status != "HIT" {
+			t.Errorf("%s: X-Response-Cache = %q; want HIT", target, status)
+		}
+	}
+	if status, _ := get(t, app, "GET", "/api/weather/40.7138/-74.0060", nil); status != "MISS" {
+		t.Errorf("another cache cell X-Response-Cache = %q; want MISS", status)
+	}
+	if *calls != 2 {
+		t.Errorf("handler called %d times; want 2", *calls)
+	}
+}
+
+func TestResponseCacheVaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		headers map[string]string
+	}{
+		{"different coordinates", "/api/weather?lat=41&lon=-74", nil},
+		{"coordinates in another cache cell", "/api/weather?lat=40.001&lon=-74", nil},
+		{"units parameter", "/api/weather?lat=40&lon=-74&units=metric", nil},
+		{"lang parameter", "/api/weather?lat=40&lon=-74&lang=es", nil},
+		{"Accept header", "/api/weather?lat=40&lon=-74", map[string]string{"Accept": "application/geo+json"}},
+		{"Accept-Language header", "/api/weather?lat=40&lon=-74", map[string]string{"Accept-Language": "es"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, _ := newCachedApp(t, ResponseCacheConfig{})
+			get(t, app, "GET", "/api/weather?lat=40&lon=-74", nil)
+
+			if status, _ := get(t, app, "GET", tt.target, tt.headers); status != "MISS" {
+				t.Errorf("X-Response-Cache = %q; want MISS", status)
+			}
+		})
+	}
+}
+
+func TestResponseCacheSkipsUncacheable(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		headers map[string]string
+	}{
+		{"client error", "GET", "/api/weather?status=400", nil},
+		{"server error", "GET", "/api/weather?status=500", nil},
+		{"stale data", "GET", "/api/weather?cc=no-cache", nil},
+		{"private response", "GET", "/api/weather?cc=private,max-age=60", nil},
+		{"no max-age", "GET", "/api/weather?cc=public", nil},
+		{"non-GET", "POST", "/api/weather", nil},
+		{"authorization", "GET", "/api/weather", map[string]string{"Authorization": "Bearer token"}},
+		{"cookie", "GET", "/api/weather", map[string]string{"Cookie": "session=abc"}},
+		// Left to the handler, which answers 304 when the ETag still matches
+		{"conditional request", "GET", "/api/weather", map[string]string{"If-None-Match": `W/"1"`}},
+		// Streamed exports are left to stream
+		{"streamed body", "GET", "/api/weather?stream=1", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, calls := newCachedApp(t, ResponseCacheConfig{})
+			get(t, app, tt.method, tt.target, tt.headers)
+			if status, _ := get(t, app, tt.method, tt.target, tt.headers); status == "HIT" {
+				t.Error("uncacheable response was served from cache")
+			}
+			if *calls != 2 {
+				t.Errorf("handler called %d times; want 2", *calls)
+			}
+		})
+	}
+}
+
+func TestResponseCacheExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	app, calls := newCachedApp(t, ResponseCacheConfig{
+		MaxTTL: 30 * time.Second,
+		Now:    func() time.Time { return now },
+	})
+
+	target := "/api/weather?cc=max-age=20"
+	get(t, app, "GET", target, nil)
+
+	now = now.Add(15 * time.Second)
+	if status, _ := get(t, app, "GET", target, nil); status != "HIT" {
+		t.Errorf("within max-age X-Response-Cache = %q; want HIT", status)
+	}
+
+	now = now.Add(10 * time.Second)
+	if status, _ := get(t, app, "GET", target, nil); status != "MISS" {
+		t.Errorf("past max-age X-Response-Cache = %q; want MISS", status)
+	}
+
+	// Long-lived data is still capped at MaxTTL
+	target = "/api/weather?cc=max-age=3600"
+	get(t, app, "GET", target, nil)
+	now = now.Add(31 * time.Second)
+	if status, _ := get(t, app, "GET", target, nil); status != "MISS" {
+		t.Errorf("past MaxTTL X-Response-Cache = %q; want MISS", status)
+	}
+	if *calls != 4 {
+		t.Errorf("handler called %d times; want 4", *calls)
+	}
+}
+
+func TestResponseCacheHitReportsRemainingMaxAge(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	app, _ := newCachedApp(t, ResponseCacheConfig{Now: func() time.Time { return now }})
+
+	get(t, app, "GET", "/api/weather", nil)
+	now = now.Add(45 * time.Second)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/weather", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cc := resp.Header.Get(fiber.HeaderCacheControl); cc != "public, max-age=555" {
+		t.Errorf("Cache-Control = %q; want public, max-age=555", cc)
+	}
+}
+
+func TestResponseCacheMaxEntries(t *testing.T) {
+	app, calls := newCachedApp(t, ResponseCacheConfig{MaxEntries: 1})
+
+	get(t, app, "GET", "/api/weather?lat=1", nil)
+	get(t, app, "GET", "/api/weather?lat=2", nil)
+	if status, _ := get(t, app, "GET", "/api/weather?lat=2", nil); status != "MISS" {
+		t.Errorf("entry beyond MaxEntries X-Response-Cache = %q; want MISS", status)
+	}
+	if status, _ := get(t, app, "GET", "/api/weather?lat=1", nil); status != "HIT" {
+		t.Errorf("first entry X-Response-Cache = %q; want HIT", status)
+	}
+	if *calls != 3 {
+		t.Errorf("handler called %d times; want 3", *calls)
+	}
+}
+
+func TestResponseCacheReplaysHeaders(t *testing.T) {
+	app, _ := newCachedApp(t, ResponseCacheConfig{})
+
+	for _, want := range []string{"MISS", "HIT"} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/weather?lat=40&lon=-74", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get(ResponseCacheHeader); got != want {
+			t.Errorf("X-Response-Cache = %q; want %q", got, want)
+		}
+		if etag, modified := resp.Header.Get(fiber.HeaderETag), resp.Header.Get(fiber.HeaderLastModified); etag != `W/"1"` || modified != "Mon, 15 Jan 2024 10:30:00 GMT" {
+			t.Errorf("%s: ETag = %q, Last-Modified = %q; want the handler's", want, etag, modified)
+		}
+		// The data cache status still describes the data served
+		if got := resp.Header.Get(dataCacheHeader); got != "STALE" {
+			t.Errorf("%s: X-Cache = %q; want STALE", want, got)
+		}
+		if lang, vary := resp.Header.Get(fiber.HeaderContentLanguage), resp.Header.Get(fiber.HeaderVary); lang != "es" || vary != "Accept, Accept-Language" {
+			t.Errorf("%s: Content-Language = %q, Vary = %q; want the handler's", want, lang, vary)
+		}
+	}
+}
+
+func TestResponseCacheKeysOnAPIKey(t *testing.T) {
+	calls := 0
+	app := fiber.New()
+	app.Use(RequireAPIKey(APIKeyConfig{Keys: ParseAPIKeys([]string{"alice:alice-secret", "bob:bob-secret"})}))
+	app.Use(ResponseCache(ResponseCacheConfig{}))
+	app.Get("/api/weather", func(c *fiber.Ctx) error {
+		calls++
+		c.Set(fiber.HeaderCacheControl, "public, max-age=600")
+		return c.JSON(fiber.Map{"call": calls, "key": APIKeyID(c)})
+	})
+
+	tests := []struct {
+		key        string
+		wantStatus string
+		wantKey    string
+	}{
+		{"alice-secret", "MISS", "alice"},
+		{"alice-secret", "HIT", "alice"},
+		{"bob-secret", "MISS", "bob"},
+		{"bob-secret", "HIT", "bob"},
+	}
+	for i, tt := range tests {
+		status, body := get(t, app, "GET", "/api/weather", map[string]string{APIKeyHeader: tt.key})
+		if status != tt.wantStatus {
+			t.Errorf("request %d: X-Response-Cache = %q; want %s", i+1, status, tt.wantStatus)
+		}
+		if !strings.Contains(body, `"key":"`+tt.wantKey+`"`) {
+			t.Errorf("request %d: body = %s; want %s's response", i+1, body, tt.wantKey)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times; want 2", calls)
+	}
+}
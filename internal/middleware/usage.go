@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/i18n"
+	"weather-api-go/internal/jsoncase"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/negotiate"
+)
+
+// GetUsage handles GET /me/usage requests. It lives beside RequireAPIKey,
+// RateLimit, and Quota because it reports their per-request state: the
+// caller's key, the quota counters Quota enforces, and the bucket the limiter
+// holds, so the body always agrees with the response's X-Quota-* and
+// X-RateLimit-* headers. The route should be Free for both, so checking usage
+// neither spends it nor is refused once it runs out. Only the caller's own key
+// is reported.
+// @Summary Get your own API key usage
+// @Description Returns the calling API key's label, daily quota with today's usage and reset time, request counts for the last 7 days, and rate limit state, as sent in the X-Quota-* and X-RateLimit-* headers of the same response. Checking usage doesn't count against the quota or rate limit.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.UsageResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /me/usage [get]
+func (q *Quota) GetUsage(c *fiber.Ctx) error {
+	id := APIKeyID(c)
+	if id == "" {
+		return negotiate.Send(c.Status(fiber.StatusUnauthorized), i18n.Error(c, models.ErrorCodeAPIKeyRequired))
+	}
+	quota, ok := ClientQuotaUsage(c)
+	if !ok {
+		return negotiate.Send(c.Status(fiber.StatusServiceUnavailable), i18n.Error(c, models.ErrorCodeUsageUnavailable))
+	}
+	history, err := q.history(c.UserContext(), id, quota)
+	if err != nil {
+		return negotiate.Send(c.Status(fiber.StatusServiceUnavailable), i18n.Error(c, models.ErrorCodeUsageUnavailable))
+	}
+
+	usage := models.UsageResponse{
+		KeyID: id,
+		Label: q.keys[id].Label,
+		Quota: models.QuotaUsage{
+			Limit:        quota.Quota,
+			Used:         quota.Used,
+			PeriodStart:  quota.PeriodStart,
+			ResetSeconds: quota.ResetSeconds(),
+			ResetAt:      quota.ResetAt,
+		},
+		History: history,
+	}
+	if quota.Quota > 0 {
+		remaining := quota.Remaining()
+		usage.Quota.Remaining = &remaining
+	}
+	if limit, ok := ClientRateUsage(c); ok {
+		usage.RateLimit = &models.RateLimitUsage{
+			Limit:        limit.Limit,
+			Burst:        limit.Burst,
+			Remaining:    limit.Remaining,
+			ResetSeconds: limit.ResetSeconds(),
+			ResetAt:      limit.ResetAt.UTC(),
+		}
+	}
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.JSON(jsoncase.For(c, usage))
+}
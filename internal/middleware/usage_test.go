@@ -0,0 +1,257 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+)
+
+// memoryUsageStore is a UsageStore for tests
+type memoryUsageStore struct {
+	mu     sync.Mutex
+	counts map[string]map[string]int // key ID to day to count
+	err    error
+}
+
+func newMemoryUsageStore() *memoryUsageStore {
+	return &memoryUsageStore{counts: map[string]map[string]int{}}
+}
+
+func (s *memoryUsageStore) TakeAPIKeyQuota(_ context.Context, keyID, day string, quota int) (int, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return 0, false, s.err
+	}
+	if s.counts[keyID] == nil {
+		s.counts[keyID] = map[string]int{}
+	}
+	if quota > 0 && s.counts[keyID][day] >= quota {
+		return s.counts[keyID][day], false, nil
+	}
+	s.counts[keyID][day]++
+	return s.counts[keyID][day], true, nil
+}
+
+func (s *memoryUsageStore) APIKeyUsage(_ context.Context, keyID, day string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[keyID][day], s.err
+}
+
+func (s *memoryUsageStore) APIKeyUsageHistory(_ context.Context, keyID, from, to string) ([]models.DailyUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var days []models.DailyUsage
+	for day, n := range s.counts[keyID] {
+		if day >= from && day < to {
+			days = append(days, models.DailyUsage{Date: day, Requests: n})
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	return days, s.err
+}
+
+// newUsageApp serves /weather, counted and limited, and /me/usage, free, for
+// alice (2 requests a day) and bob (unlimited)
+func newUsageApp(clock *fakeClock, store UsageStore, requestsPerMinute int) *fiber.App {
+	keys := ParseAPIKeys([]string{"alice:a", "bob:b"})
+	keys[0].Label = "Alice's app"
+	keys[0].DailyQuota = 2
+	free := func(c *fiber.Ctx) bool { return c.Path() == "/me/usage" }
+	quota := NewQuota(QuotaConfig{Keys: keys, Store: store, Free: free, History: 3, Now: clock.Now})
+
+	app := fiber.New()
+	app.Use(RequireAPIKey(APIKeyConfig{Keys: keys}))
+	app.Use(RateLimit(RateLimitConfig{RequestsPerMinute: requestsPerMinute, Burst: 5, Free: free, Now: clock.Now}))
+	app.Use(quota.Middleware())
+	app.Get("/weather", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	app.Get("/me/usage", quota.GetUsage)
+	return app
+}
+
+func sendWithKey(t *testing.T, app *fiber.App, target, key string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest("GET", target, nil)
+	req.Header.Set(APIKeyHeader, key)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestGetUsage(t *testing.T) {
+	// 30 minutes before midnight UTC
+	clock := &fakeClock{now: time.Date(2024, 1, 15, 23, 30, 0, 0, time.UTC)}
+	store := newMemoryUsageStore()
+	app := newUsageApp(clock, store, 60)
+
+	// checkUsage asserts GET /me/usage answers 200 with the key's quota state,
+	// and that its body matches its X-Quota-* and X-RateLimit-* headers
+	checkUsage := func(name, key string, wantQuota, wantUsed, wantRateRemaining int, wantHistory []models.DailyUsage) models.UsageResponse {
+		t.Helper()
+		resp := sendWithKey(t, app, "/me/usage", key)
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("%s: status = %d; want 200", name, resp.StatusCode)
+		}
+		var body models.UsageResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		h := resp.Header
+		if h.Get(fiber.HeaderCacheControl) != "private, no-store" {
+			t.Errorf("%s: Cache-Control = %q; want private, no-store", name, h.Get(fiber.HeaderCacheControl))
+		}
+
+		q := body.Quota
+		if q.Limit != wantQuota || q.Used != wantUsed {
+			t.Errorf("%s: quota = %+v; want limit %d, used %d", name, q, wantQuota, wantUsed)
+		}
+		if wantQuota > 0 {
+			if q.Remaining == nil || *q.Remaining != wantQuota-wantUsed {
+				t.Errorf("%s: quota.remaining = %v; want %d", name, q.Remaining, wantQuota-wantUsed)
+			} else if h.Get(QuotaLimitHeader) != strconv.Itoa(q.Limit) || h.Get(QuotaRemainingHeader) != strconv.Itoa(*q.Remaining) ||
+				h.Get(QuotaResetHeader) != strconv.Itoa(q.ResetSeconds) {
+				t.Errorf("%s: X-Quota-Limit %q, -Remaining %q, -Reset %q; want the body's %+v", name,
+					h.Get(QuotaLimitHeader), h.Get(QuotaRemainingHeader), h.Get(QuotaResetHeader), q)
+			}
+		} else if q.Remaining != nil || h.Get(QuotaLimitHeader) != "" {
+			t.Errorf("%s: unlimited key got remaining %v and X-Quota-Limit %q; want neither", name, q.Remaining, h.Get(QuotaLimitHeader))
+		}
+		midnight := time.Date(clock.Now().Year(), clock.Now().Month(), clock.Now().Day()+1, 0, 0, 0, 0, time.UTC)
+		if !q.ResetAt.Equal(midnight) || !q.PeriodStart.Equal(midnight.AddDate(0, 0, -1)) ||
+			q.ResetSeconds != int(midnight.Sub(clock.Now()).Seconds()) {
+			t.Errorf("%s: period %v to %v resetting in %ds; want the UTC day ending %v", name, q.PeriodStart, q.ResetAt, q.ResetSeconds, midnight)
+		}
+		if len(body.History) != len(wantHistory) {
+			t.Errorf("%s: history = %+v; want %+v", name, body.History, wantHistory)
+		} else {
+			for i := range wantHistory {
+				if body.History[i] != wantHistory[i] {
+					t.Errorf("%s: history = %+v; want %+v", name, body.History, wantHistory)
+					break
+				}
+			}
+		}
+
+		rl := body.RateLimit
+		if rl == nil || rl.Remaining != wantRateRemaining || strconv.Itoa(rl.Limit) != h.Get(RateLimitLimitHeader) ||
+			strconv.Itoa(rl.Remaining) != h.Get(RateLimitRemainingHeader) || strconv.Itoa(rl.ResetSeconds) != h.Get(RateLimitResetHeader) {
+			t.Errorf("%s: rate_limit = %+v with X-RateLimit-Remaining %q; want %d remaining matching the headers", name, rl,
+				h.Get(RateLimitRemainingHeader), wantRateRemaining)
+		}
+		return body
+	}
+	history := func(counts ...int) []models.DailyUsage {
+		days := make([]models.DailyUsage, len(counts))
+		for i, n := range counts {
+			date := clock.Now().AddDate(0, 0, i-len(counts)+1).Format(usageDayLayout)
+			days[i] = models.DailyUsage{Date: date, Requests: n}
+		}
+		return days
+	}
+
+	// Checking usage is free: repeating it spends neither quota nor bucket
+	body := checkUsage("before any request", "a", 2, 0, 5, history(0, 0, 0))
+	checkUsage("checked again", "a", 2, 0, 5, history(0, 0, 0))
+	if body.KeyID != "alice" || body.Label != "Alice's app" {
+		t.Errorf("key = %q, label = %q; want alice, Alice's app", body.KeyID, body.Label)
+	}
+
+	for i := 1; i <= 2; i++ {
+		resp := sendWithKey(t, app, "/weather", "a")
+		if resp.StatusCode != fiber.StatusOK || resp.Header.Get(QuotaRemainingHeader) != strconv.Itoa(2-i) {
+			t.Fatalf("request %d: status %d, X-Quota-Remaining %q; want 200, %d", i, resp.StatusCode, resp.Header.Get(QuotaRemainingHeader), 2-i)
+		}
+	}
+	checkUsage("at the quota", "a", 2, 2, 3, history(0, 0, 2))
+
+	resp := sendWithKey(t, app, "/weather", "a")
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("over the quota: status = %d; want 429", resp.StatusCode)
+	}
+	var errBody models.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&errBody); err != nil {
+		t.Fatal(err)
+	}
+	if errBody.Code != models.ErrorCodeAPIKeyQuotaExceeded || resp.Header.Get(fiber.HeaderRetryAfter) != "1800" ||
+		resp.Header.Get(QuotaRemainingHeader) != "0" {
+		t.Errorf("over the quota: code %q, Retry-After %q, X-Quota-Remaining %q; want %s, 1800, 0", errBody.Code,
+			resp.Header.Get(fiber.HeaderRetryAfter), resp.Header.Get(QuotaRemainingHeader), models.ErrorCodeAPIKeyQuotaExceeded)
+	}
+
+	// The refused request isn't counted, and usage still answers. It took a
+	// rate limit token, since the limiter runs first.
+	checkUsage("after crossing the quota", "a", 2, 2, 2, history(0, 0, 2))
+
+	// Another key only ever sees its own usage
+	sendWithKey(t, app, "/weather", "b")
+	body = checkUsage("another key", "b", 0, 1, 4, history(0, 0, 1))
+	if body.KeyID != "bob" || body.Label != "bob" {
+		t.Errorf("key = %q, label = %q; want bob labelled by its ID", body.KeyID, body.Label)
+	}
+
+	// A new UTC day starts a new period, and yesterday moves into the history
+	clock.Advance(time.Hour)
+	if resp := sendWithKey(t, app, "/weather", "a"); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("next day: status = %d; want 200", resp.StatusCode)
+	}
+	checkUsage("next day", "a", 2, 1, 4, history(0, 2, 1))
+}
+
+func TestGetUsageWithoutLimitOrKey(t *testing.T) {
+	t.Run("without a rate limit", func(t *testing.T) {
+		clock := &fakeClock{now: time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)}
+		resp := sendWithKey(t, newUsageApp(clock, newMemoryUsageStore(), 0), "/me/usage", "a")
+		var body models.UsageResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusOK || body.KeyID != "alice" || body.RateLimit != nil || body.Quota.Limit != 2 {
+			t.Errorf("status %d, body %+v; want alice's quota without a rate limit", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("counters unavailable", func(t *testing.T) {
+		clock := &fakeClock{now: time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)}
+		store := newMemoryUsageStore()
+		store.err = errors.New("database is locked")
+		app := newUsageApp(clock, store, 0)
+		// Requests are let through rather than refused
+		if resp := sendWithKey(t, app, "/weather", "a"); resp.StatusCode != fiber.StatusOK {
+			t.Errorf("request status = %d; want 200", resp.StatusCode)
+		}
+		if resp := sendWithKey(t, app, "/me/usage", "a"); resp.StatusCode != fiber.StatusServiceUnavailable {
+			t.Errorf("usage status = %d; want 503", resp.StatusCode)
+		}
+	})
+
+	t.Run("no API keys", func(t *testing.T) {
+		quota := NewQuota(QuotaConfig{Store: newMemoryUsageStore()})
+		app := fiber.New()
+		app.Use(RequireAPIKey(APIKeyConfig{}))
+		app.Use(quota.Middleware())
+		app.Get("/me/usage", quota.GetUsage)
+
+		// Without API keys there is no caller to report on
+		resp := sendWithKey(t, app, "/me/usage", "a")
+		var body models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusUnauthorized || body.Code != models.ErrorCodeAPIKeyRequired {
+			t.Errorf("status %d, code %q; want 401 %s", resp.StatusCode, body.Code, models.ErrorCodeAPIKeyRequired)
+		}
+	})
+}
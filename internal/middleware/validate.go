@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/i18n"
+	"weather-api-go/internal/jsoncase"
+	"weather-api-go/internal/models"
+)
+
+// ValidationConfig configures the OpenAPI response validator
+type ValidationConfig struct {
+	// Spec is the OpenAPI document (JSON or YAML) responses are checked against
+	Spec []byte
+	// FailOnMismatch replaces invalid responses with a 500 instead of only logging them
+	FailOnMismatch bool
+	// Logf receives mismatch reports; defaults to log.Printf
+	Logf func(format string, args ...interface{})
+}
+
+// ValidateResponses returns middleware that validates every JSON response's
+// status and body against the OpenAPI document. Routes missing from the spec,
+// non-JSON responses (HTML docs, YAML, event streams), and responses in a
+// JSON case other than snake_case are skipped. It is intended for development
+// and CI, not production traffic.
+func ValidateResponses(cfg ValidationConfig) (fiber.Handler, error) {
+	if cfg.Logf == nil {
+		cfg.Logf = log.Printf
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(cfg.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI spec: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI spec: %w", err)
+	}
+
+	// Match on the server base path only so validation works on any host
+	doc.Servers = relativeServers(doc.Servers)
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OpenAPI router: %w", err)
+	}
+
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		contentType := string(c.Response().Header.ContentType())
+		if !strings.HasPrefix(contentType, fiber.MIMEApplicationJSON) {
+			return nil
+		}
+		// The spec documents the snake_case property names
+		if style, ok := jsoncase.StyleOf(c); ok && style != jsoncase.Snake {
+			return nil
+		}
+
+		req, err := http.NewRequest(c.Method(), c.OriginalURL(), nil)
+		if err != nil {
+			return nil
+		}
+
+		route, pathParams, err := router.FindRoute(req)
+		if err != nil {
+			return nil
+		}
+
+		if err := validateResponse(c, req, route, pathParams, contentType); err != nil {
+			cfg.Logf("OpenAPI response mismatch for %s %s: %v", c.Method(), c.OriginalURL(), err)
+			if cfg.FailOnMismatch {
+				c.Response().Reset()
+				return c.Status(fiber.StatusInternalServerError).JSON(jsoncase.For(c,
+					i18n.Error(c, models.ErrorCodeInvalidResponse, "cause", err.Error())))
+			}
+		}
+		return nil
+	}, nil
+}
+
+func validateResponse(c *fiber.Ctx, req *http.Request, route *routers.Route, pathParams map[string]string, contentType string) error {
+	header := http.Header{}
+	header.Set(fiber.HeaderContentType, contentType)
+
+	return openapi3filter.ValidateResponse(c.Context(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+		},
+		Status: c.Response().StatusCode(),
+		Header: header,
+		Body:   io.NopCloser(bytes.NewReader(c.Response().Body())),
+		Options: &openapi3filter.Options{
+			IncludeResponseStatus: true,
+			MultiError:            true,
+		},
+	})
+}
+
+// relativeServers strips scheme and host from server URLs, keeping the base path
+func relativeServers(servers openapi3.Servers) openapi3.Servers {
+	if len(servers) == 0 {
+		return openapi3.Servers{{URL: "/"}}
+	}
+
+	relative := make(openapi3.Servers, 0, len(servers))
+	for _, s := range servers {
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			continue
+		}
+		path := u.Path
+		if path == "" {
+			path = "/"
+		}
+		relative = append(relative, &openapi3.Server{URL: path})
+	}
+	return relative
+}
@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/handlers"
+	"weather-api-go/internal/models"
+)
+
+// newValidatedApp serves canned bodies for the documented routes behind the validator
+func newValidatedApp(t *testing.T, failOnMismatch bool, weatherBody interface{}, logs *[]string) *fiber.App {
+	t.Helper()
+
+	spec, err := handlers.OpenAPISpecJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validator, err := ValidateResponses(ValidationConfig{
+		Spec:           spec,
+		FailOnMismatch: failOnMismatch,
+		Logf: func(format string, args ...interface{}) {
+			*logs = append(*logs, fmt.Sprintf(format, args...))
+		},
+	})
+	if err != nil {
+		t.Fatalf("ValidateResponses: %v", err)
+	}
+
+	app := fiber.New()
+	app.Use(validator)
+	app.Get("/api/v1/weather", func(c *fiber.Ctx) error {
+		return c.JSON(weatherBody)
+	})
+	app.Get("/api/v1/health", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusTeapot).JSON(models.HealthResponse{Status: "healthy"})
+	})
+	app.Get("/docs", func(c *fiber.Ctx) error {
+		c.Type("html")
+		return c.SendString("<html></html>")
+	})
+	return app
+}
+
+func TestValidateResponsesPassesValidResponse(t *testing.T) {
+	var logs []string
+	tempC, tempF := 20.0, 68.0
+	body := models.WeatherResponse{Forecast: "Sunny", Temperature: "moderate", TemperatureC: &tempC, TemperatureF: &tempF}
+	app := newValidatedApp(t, true, body, &logs)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/weather?lat=40.7&lon=-74", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		b, _ := io.ReadAll(resp.Body)
+		t.Errorf("status = %d; want 200 (%s)", resp.StatusCode, b)
+	}
+	if len(logs) != 0 {
+		t.Errorf("valid response logged mismatches: %v", logs)
+	}
+}
+
+func TestValidateResponsesCatchesWrongFieldType(t *testing.T) {
+	malformed := map[string]interface{}{
+		"forecast":      "Sunny",
+		"temperature":   "moderate",
+		"temperature_c": "twenty",
+		"temperature_f": 68,
+	}
+
+	t.Run("fail mode", func(t *testing.T) {
+		var logs []string
+		app := newValidatedApp(t, true, malformed, &logs)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/weather?lat=40.7&lon=-74", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != 500 {
+			t.Errorf("status = %d; want 500", resp.StatusCode)
+		}
+		if !strings.Contains(string(body), "temperature_c") {
+			t.Errorf("error body does not name the bad field: %s", body)
+		}
+		if len(logs) != 1 {
+			t.Errorf("logged %d mismatches; want 1", len(logs))
+		}
+	})
+
+	t.Run("log mode", func(t *testing.T) {
+		var logs []string
+		app := newValidatedApp(t, false, malformed, &logs)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/weather?lat=40.7&lon=-74", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != 200 {
+			t.Errorf("status = %d; want original 200 in log mode", resp.StatusCode)
+		}
+		if len(logs) != 1 || !strings.Contains(logs[0], "/api/v1/weather") {
+			t.Errorf("logs = %v; want one mismatch for /api/weather", logs)
+		}
+	})
+}
+
+func TestValidateResponsesCatchesUndocumentedStatus(t *testing.T) {
+	var logs []string
+	app := newValidatedApp(t, true, nil, &logs)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/health", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 500 {
+		t.Errorf("status = %d; want 500 for undocumented 418", resp.StatusCode)
+	}
+}
+
+func TestValidateResponsesSkipsHTML(t *testing.T) {
+	var logs []string
+	app := newValidatedApp(t, true, nil, &logs)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/docs", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || len(logs) != 0 {
+		t.Errorf("HTML route status = %d, logs = %v; want untouched 200", resp.StatusCode, logs)
+	}
+}
+
+func BenchmarkValidateResponses(b *testing.B) {
+	spec, err := handlers.OpenAPISpecJSON()
+	if err != nil {
+		b.Fatal(err)
+	}
+	validator, err := ValidateResponses(ValidationConfig{Spec: spec})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	tempC, tempF := 20.0, 68.0
+	body := models.WeatherResponse{Forecast: "Sunny", Temperature: "moderate", TemperatureC: &tempC, TemperatureF: &tempF}
+	for _, tc := range []struct {
+		name    string
+		enabled bool
+	}{{"disabled", false}, {"enabled", true}} {
+		app := fiber.New()
+		if tc.enabled {
+			app.Use(validator)
+		}
+		app.Get("/api/v1/weather", func(c *fiber.Ctx) error { return c.JSON(body) })
+
+		b.Run(tc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := app.Test(httptest.NewRequest("GET", "/api/v1/weather?lat=40.7&lon=-74", nil)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,25 +1,470 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"encoding/xml"
+	"time"
+)
 
 // WeatherResponse represents the API response for weather data
 type WeatherResponse struct {
-	Forecast     string  `json:"forecast" example:"Partly Cloudy"`
-	Temperature  string  `json:"temperature" example:"moderate"`
-	TemperatureC float64 `json:"temperature_c" example:"22.5"`
-	TemperatureF float64 `json:"temperature_f" example:"72.5"`
+	XMLName xml.Name `json:"-" xml:"weather"`
+
+	Forecast string `json:"forecast" xml:"forecast" example:"Partly Cloudy"`
+	// PeriodName labels the forecast period, such as "Tonight" or "Friday",
+	// when the provider names it
+	PeriodName string `json:"period_name,omitempty" xml:"period_name,omitempty" example:"Tonight"`
+	// IsDaytime reports whether the period is daytime, for day and night icons
+	IsDaytime *bool `json:"is_daytime,omitempty" xml:"is_daytime,omitempty" example:"false"`
+	// Icon is the NWS icon URL for the period, sized by ?icon_size=, omitted
+	// when the provider gives none
+	Icon string `json:"icon,omitempty" xml:"icon,omitempty" example:"https://api.weather.gov/icons/land/night/few?size=medium"`
+	// DetailedForecast is the NWS's narrative for the period, set only with
+	// ?include=detailed and when the provider gives one
+	DetailedForecast string `json:"detailed_forecast,omitempty" xml:"detailed_forecast,omitempty" example:"Partly cloudy, with a low around 48. West wind 5 to 10 mph."`
+	Temperature      string `json:"temperature" xml:"temperature" example:"moderate"`
+	// TemperatureC and TemperatureF are omitted when ?units= selects the other system
+	TemperatureC *float64 `json:"temperature_c,omitempty" xml:"temperature_c,omitempty" example:"22.5"`
+	TemperatureF *float64 `json:"temperature_f,omitempty" xml:"temperature_f,omitempty" example:"72.5"`
+	// TemperatureTrend is the NWS's "rising" or "falling" when the temperature
+	// moves against the period's usual course, such as rising overnight;
+	// omitted when the NWS gives none
+	TemperatureTrend string `json:"temperature_trend,omitempty" xml:"temperature_trend,omitempty" example:"rising"`
+	// TemperatureChangeC is the change in degrees Celsius since the
+	// location's previous refresh, omitted for its first
+	TemperatureChangeC *float64 `json:"temperature_change_c,omitempty" xml:"temperature_change_c,omitempty" example:"-1.7"`
+	// WindSpeedKmh and WindSpeedMph are the forecast wind speed range, each
+	// omitted when ?units= selects the other system or no wind is forecast
+	WindSpeedKmh *SpeedRange `json:"wind_speed_kmh,omitempty" xml:"wind_speed_kmh,omitempty"`
+	WindSpeedMph *SpeedRange `json:"wind_speed_mph,omitempty" xml:"wind_speed_mph,omitempty"`
+	// WindSpeed is the wind speed range in the unit ?wind_unit= asks for,
+	// named by WindUnit, which replaces WindSpeedKmh and WindSpeedMph
+	WindSpeed *SpeedRange `json:"wind_speed,omitempty" xml:"wind_speed,omitempty"`
+	WindUnit  string      `json:"wind_unit,omitempty" xml:"wind_unit,omitempty" example:"kn"`
+	// WindDirection is the compass point the wind blows from
+	WindDirection string `json:"wind_direction,omitempty" xml:"wind_direction,omitempty" example:"NW"`
+	// PrecipitationProbability is the chance of precipitation in percent,
+	// omitted when the provider doesn't forecast it rather than reported as 0
+	PrecipitationProbability *float64 `json:"precipitation_probability,omitempty" xml:"precipitation_probability,omitempty" example:"30"`
+	// HumidityPercent is the relative humidity, omitted when not forecast
+	HumidityPercent *float64 `json:"humidity_percent,omitempty" xml:"humidity_percent,omitempty" example:"48"`
+	// DewpointC and DewpointF are omitted when ?units= selects the other
+	// system or no dewpoint is forecast
+	DewpointC *float64 `json:"dewpoint_c,omitempty" xml:"dewpoint_c,omitempty" example:"22.2"`
+	DewpointF *float64 `json:"dewpoint_f,omitempty" xml:"dewpoint_f,omitempty" example:"72"`
+	// UVIndex is the UV index forecast for the hour the data was fetched and
+	// UVCategory its WHO exposure category (low, moderate, high, very high,
+	// or extreme), set only with ?include=uv and when a UV forecast was found
+	UVIndex    *float64    `json:"uv_index,omitempty" xml:"uv_index,omitempty" example:"7"`
+	UVCategory string      `json:"uv_category,omitempty" xml:"uv_category,omitempty" example:"high"`
+	Advisories *Advisories `json:"advisories,omitempty" xml:"advisories,omitempty"`
+
+	// The following are set only for forecasts at a requested time (?at=)
+	// ValidAt is the instant the values describe: the requested time, or the
+	// start of the covering forecast period beyond the hourly horizon
+	ValidAt *time.Time `json:"valid_at,omitempty" xml:"valid_at,omitempty" example:"2024-06-01T18:00:00Z"`
+	// Interpolated is true when values were interpolated from hourly entries and
+	// false when they come from a covering day/night period
+	Interpolated *bool `json:"interpolated,omitempty" xml:"interpolated,omitempty"`
+
+	// Location names the nearest city to the point, as reported by the NWS
+	Location string `json:"location,omitempty" xml:"location,omitempty" example:"Newark, NJ"`
+	// Place is the place a ?zip=, ?city=, or ?q= lookup resolved to
+	Place *Place `json:"place,omitempty" xml:"place,omitempty"`
+	// ApproximateLocation is set when no location was given and the caller's
+	// was estimated from their IP address; the forecast is for its coordinates
+	ApproximateLocation *IPLocation `json:"approximate_location,omitempty" xml:"approximate_location,omitempty"`
+	// CachedPoint is set when the forecast was cached for a point near the
+	// requested coordinate, within the nearby cache radius, rather than for
+	// the coordinate itself
+	CachedPoint *CachedPoint `json:"cached_point,omitempty" xml:"cached_point,omitempty"`
+
+	// Provider is the forecast provider the data came from: nws, open-meteo
+	// (configured, or the fallback outside NWS coverage), or owm
+	Provider string `json:"provider,omitempty" xml:"provider,omitempty" example:"nws"`
+	// Source is where the data came from: live from the provider, a cache tier
+	// (redis or sqlite), or stale cached data served while it is refreshed in
+	// the background or after a failed upstream fetch
+	Source string `json:"source,omitempty" xml:"source,omitempty" example:"redis"`
+	// CachedAt is when the data was fetched from the provider, in UTC
+	CachedAt *time.Time `json:"cached_at,omitempty" xml:"cached_at,omitempty" example:"2024-01-15T10:30:00Z"`
+	// TimeZone is the IANA time zone local times are given in: the ?tz=
+	// override, or the location's own when the NWS reports it
+	TimeZone string `json:"time_zone,omitempty" xml:"time_zone,omitempty" example:"America/New_York"`
+	// CachedAtLocal is CachedAt in TimeZone
+	CachedAtLocal *time.Time `json:"cached_at_local,omitempty" xml:"cached_at_local,omitempty" example:"2024-01-15T05:30:00-05:00"`
+
+	// FreshUntil is when the underlying data goes stale; zero for stale fallbacks
+	FreshUntil time.Time `json:"-" xml:"-"`
+	// CacheHit reports the data was served from a fresh cache without an upstream fetch
+	CacheHit bool `json:"-" xml:"-"`
+}
+
+// SpeedRange is a forecast wind speed range, such as the NWS's "5 to 10 mph".
+// A single speed has Min equal to Max.
+type SpeedRange struct {
+	Min float64 `json:"min" xml:"min" example:"5"`
+	Max float64 `json:"max" xml:"max" example:"10"`
+}
+
+// Place is a named location resolved by geocoding
+type Place struct {
+	Name      string  `json:"name" xml:"name" example:"Portland, Multnomah County, Oregon, United States"`
+	Latitude  float64 `json:"latitude" xml:"latitude" example:"45.5202"`
+	Longitude float64 `json:"longitude" xml:"longitude" example:"-122.6742"`
+}
+
+// CachedPoint is the coordinate a nearby cached forecast was fetched for
+type CachedPoint struct {
+	Latitude  float64 `json:"latitude" xml:"latitude" example:"40.72"`
+	Longitude float64 `json:"longitude" xml:"longitude" example:"-74.006"`
+	// DistanceKm is its distance from the requested coordinate, to the nearest 10 m
+	DistanceKm float64 `json:"distance_km" xml:"distance_km" example:"0.78"`
 }
 
-// ErrorResponse represents an error response
+// IPLocation is a location estimated from an IP address. It is approximate:
+// the caller is likely within AccuracyKm of the coordinates.
+type IPLocation struct {
+	Latitude   float64 `json:"latitude" xml:"latitude" example:"51.5142"`
+	Longitude  float64 `json:"longitude" xml:"longitude" example:"-0.0931"`
+	AccuracyKm int     `json:"accuracy_km,omitempty" xml:"accuracy_km,omitempty" example:"20"`
+	City       string  `json:"city,omitempty" xml:"city,omitempty" example:"London"`
+	// Region is the ISO 3166-2 code of the state or province, without the country prefix
+	Region string `json:"region,omitempty" xml:"region,omitempty" example:"ENG"`
+	// Country is the ISO 3166-1 alpha-2 country code
+	Country string `json:"country,omitempty" xml:"country,omitempty" example:"GB"`
+}
+
+// AmbiguousLocationResponse is returned when a place lookup matches several
+// locations. It carries the ErrorResponse fields plus the candidates to pick from.
+type AmbiguousLocationResponse struct {
+	XMLName xml.Name `json:"-" xml:"error"`
+
+	Error   string `json:"error" xml:"error" example:"Ambiguous location"`
+	Details string `json:"details,omitempty" xml:"details,omitempty"`
+	Code    string `json:"code,omitempty" xml:"code,omitempty" example:"AMBIGUOUS_LOCATION"`
+	// Candidates are the matching places, best match first
+	Candidates []Place `json:"candidates" xml:"candidates>place"`
+}
+
+// Advisories holds frost and heat risk flags derived from the forecast.
+// They are heuristics computed locally and may precede official NWS advisories.
+type Advisories struct {
+	// FrostRisk is set when a sample is at or below the hard freeze threshold, or at or
+	// below the frost threshold with clear skies and light (or unreported) wind
+	FrostRisk bool `json:"frost_risk" xml:"frost_risk" example:"false"`
+	// HeatRisk is set when the heat index (air temperature when humidity is unknown)
+	// reaches the configured heat index threshold
+	HeatRisk bool `json:"heat_risk" xml:"heat_risk" example:"false"`
+	// HeatIndexC is the highest heat index across the evaluated samples
+	HeatIndexC *float64 `json:"heat_index_c,omitempty" xml:"heat_index_c,omitempty" example:"24.1"`
+	// Severity is the worst of the frost and heat levels: none, low, moderate, or high
+	Severity string `json:"severity" xml:"severity" example:"none"`
+}
+
+// ErrorResponse represents an error response. Error and Details are
+// human-readable and localized; Code is stable for programmatic handling.
 type ErrorResponse struct {
-	Error   string `json:"error" example:"Invalid latitude parameter"`
-	Details string `json:"details,omitempty" example:"Latitude must be between -90 and 90"`
+	XMLName xml.Name `json:"-" xml:"error"`
+
+	Error   string `json:"error" xml:"error" example:"Invalid coordinates"`
+	Details string `json:"details,omitempty" xml:"details,omitempty" example:"Latitude must be between -90 and 90, longitude between -180 and 180"`
+	// Code is a stable machine-readable error code
+	Code string `json:"code,omitempty" xml:"code,omitempty" example:"COORDINATES_OUT_OF_RANGE"`
+}
+
+// Error codes. Each has an English message in the i18n catalog.
+const (
+	// ErrorCodeShed marks a request rejected because upstream capacity is saturated
+	ErrorCodeShed = "SHED"
+	// ErrorCodeCircuitOpen marks a request rejected because NWS requests are
+	// paused after repeated failures
+	ErrorCodeCircuitOpen = "NWS_CIRCUIT_OPEN"
+	// ErrorCodeUpstreamBackoff marks a request rejected because the NWS asked,
+	// with Retry-After, for requests to stop for a while
+	ErrorCodeUpstreamBackoff = "NWS_BACKOFF"
+	// ErrorCodeRateLimited marks a request rejected by the per-client rate limit
+	ErrorCodeRateLimited = "RATE_LIMITED"
+	// ErrorCodeAPIKeyQuotaExceeded marks a request rejected because its API key
+	// has used its daily quota
+	ErrorCodeAPIKeyQuotaExceeded = "API_KEY_QUOTA_EXCEEDED"
+	// ErrorCodeUsageUnavailable marks a usage report whose counters couldn't be read
+	ErrorCodeUsageUnavailable = "USAGE_UNAVAILABLE"
+
+	ErrorCodeMissingLatitude        = "MISSING_LATITUDE"
+	ErrorCodeInvalidLatitude        = "INVALID_LATITUDE"
+	ErrorCodeMissingLongitude       = "MISSING_LONGITUDE"
+	ErrorCodeInvalidLongitude       = "INVALID_LONGITUDE"
+	ErrorCodeCoordinatesOutOfRange  = "COORDINATES_OUT_OF_RANGE"
+	ErrorCodeInvalidForecastTime    = "INVALID_FORECAST_TIME"
+	ErrorCodeInvalidUnits           = "INVALID_UNITS"
+	ErrorCodeInvalidIconSize        = "INVALID_ICON_SIZE"
+	ErrorCodeInvalidWindUnit        = "INVALID_WIND_UNIT"
+	ErrorCodeInvalidTimeZone        = "INVALID_TIME_ZONE"
+	ErrorCodeForecastTimeInPast     = "FORECAST_TIME_IN_PAST"
+	ErrorCodeBeyondForecastHorizon  = "BEYOND_FORECAST_HORIZON"
+	ErrorCodeOutOfCoverage          = "OUT_OF_COVERAGE"
+	ErrorCodeNoHourlyForecast       = "NO_HOURLY_FORECAST"
+	ErrorCodeInvalidDateRange       = "INVALID_DATE_RANGE"
+	ErrorCodeInvalidDate            = "INVALID_DATE"
+	ErrorCodeInvalidStationID       = "INVALID_STATION_ID"
+	ErrorCodeInvalidHours           = "INVALID_HOURS"
+	ErrorCodeInvalidPagination      = "INVALID_PAGINATION"
+	ErrorCodeStationNotFound        = "STATION_NOT_FOUND"
+	ErrorCodeNoObservationStation   = "NO_OBSERVATION_STATION"
+	ErrorCodeInvalidBatch           = "INVALID_BATCH"
+	ErrorCodeBatchTooLarge          = "BATCH_TOO_LARGE"
+	ErrorCodeInvalidCoordinatesBody = "INVALID_COORDINATES_BODY"
+	ErrorCodeUnsupportedMediaType   = "UNSUPPORTED_MEDIA_TYPE"
+	ErrorCodeInvalidPoints          = "INVALID_POINTS"
+	ErrorCodeInvalidGraphQL         = "INVALID_GRAPHQL_REQUEST"
+	ErrorCodeQueryTooDeep           = "QUERY_TOO_DEEP"
+	ErrorCodeQueryTooComplex        = "QUERY_TOO_COMPLEX"
+	ErrorCodeInvalidDays            = "INVALID_DAYS"
+	ErrorCodeWebSocketRequired      = "WEBSOCKET_REQUIRED"
+	ErrorCodeInvalidSubscription    = "INVALID_SUBSCRIPTION"
+	ErrorCodeTooManySubscriptions   = "TOO_MANY_SUBSCRIPTIONS"
+	ErrorCodeInvalidInterval        = "INVALID_INTERVAL"
+	ErrorCodeInvalidWebhookRequest  = "INVALID_SUBSCRIPTION_REQUEST"
+	ErrorCodeInvalidCallbackURL     = "INVALID_CALLBACK_URL"
+	ErrorCodeCallbackNotAllowed     = "CALLBACK_URL_NOT_ALLOWED"
+	ErrorCodeInvalidSeverity        = "INVALID_SEVERITY"
+	ErrorCodeInvalidAlertSeverity   = "INVALID_ALERT_SEVERITY"
+	ErrorCodeInvalidAlertUrgency    = "INVALID_ALERT_URGENCY"
+	ErrorCodeInvalidActiveOnly      = "INVALID_ACTIVE_ONLY"
+	ErrorCodeInvalidGeometry        = "INVALID_GEOMETRY"
+	ErrorCodeSubscriptionNotFound   = "SUBSCRIPTION_NOT_FOUND"
+	ErrorCodeInvalidLocation        = "INVALID_LOCATION"
+	ErrorCodeLocationNotFound       = "LOCATION_NOT_FOUND"
+	ErrorCodeInvalidZIP             = "INVALID_ZIP"
+	ErrorCodeZIPNotFound            = "ZIP_NOT_FOUND"
+	ErrorCodeConflictingLocation    = "CONFLICTING_LOCATION"
+	ErrorCodeAmbiguousLocation      = "AMBIGUOUS_LOCATION"
+	ErrorCodeGeocodingDisabled      = "GEOCODING_DISABLED"
+	ErrorCodeAirQualityDisabled     = "AIR_QUALITY_DISABLED"
+	ErrorCodeNoAirQuality           = "NO_AIR_QUALITY"
+	ErrorCodeIPNotLocated           = "IP_NOT_LOCATED"
+	ErrorCodeInvalidCase            = "INVALID_CASE"
+	ErrorCodeUnknownSchema          = "UNKNOWN_SCHEMA"
+	ErrorCodeUnauthorized           = "UNAUTHORIZED"
+	ErrorCodeAPIKeyRequired         = "API_KEY_REQUIRED"
+	ErrorCodeAPIKeyInvalid          = "API_KEY_INVALID"
+	ErrorCodeDocumentTooLarge       = "UPSTREAM_DOCUMENT_TOO_LARGE"
+	ErrorCodeWeatherUnavailable     = "WEATHER_UNAVAILABLE"
+	ErrorCodeUpstreamUnavailable    = "UPSTREAM_UNAVAILABLE"
+	ErrorCodeUpstreamBadResponse    = "UPSTREAM_BAD_RESPONSE"
+	ErrorCodeUpstreamTimeout        = "UPSTREAM_TIMEOUT"
+	ErrorCodeCacheUnavailable       = "CACHE_UNAVAILABLE"
+	ErrorCodeQuotaExceeded          = "PROVIDER_QUOTA_EXCEEDED"
+	ErrorCodeObservationUnavailable = "OBSERVATIONS_UNAVAILABLE"
+	ErrorCodeAlertsUnavailable      = "ALERTS_UNAVAILABLE"
+	ErrorCodeWebhooksUnavailable    = "SUBSCRIPTIONS_UNAVAILABLE"
+	ErrorCodeGeocodingUnavailable   = "GEOCODING_UNAVAILABLE"
+	ErrorCodeAirQualityUnavailable  = "AIR_QUALITY_UNAVAILABLE"
+	ErrorCodeMetadataUnavailable    = "METADATA_UNAVAILABLE"
+	ErrorCodeHistoryUnavailable     = "HISTORY_UNAVAILABLE"
+	ErrorCodeStatsUnavailable       = "STATS_UNAVAILABLE"
+	ErrorCodeCacheStatsUnavailable  = "CACHE_STATS_UNAVAILABLE"
+	ErrorCodeCacheInvalidation      = "CACHE_INVALIDATION_FAILED"
+	ErrorCodeWarmLocationNotFound   = "WARM_LOCATION_NOT_FOUND"
+	ErrorCodeWarmLocationsFailed    = "WARM_LOCATIONS_UNAVAILABLE"
+	ErrorCodeDocumentUnavailable    = "NWS_DOCUMENT_UNAVAILABLE"
+	ErrorCodeDocsUnavailable        = "DOCS_UNAVAILABLE"
+	ErrorCodeInvalidResponse        = "INVALID_RESPONSE"
+)
+
+// MetricsResponse represents the service metrics snapshot
+type MetricsResponse struct {
+	UptimeSeconds   int64            `json:"uptime_seconds" example:"3600"`
+	RecentLatencyMs *float64         `json:"recent_latency_ms,omitempty" example:"1.2"`
+	Counters        map[string]int64 `json:"counters"`
 }
 
 // HealthResponse represents the health check response
 type HealthResponse struct {
 	Status    string `json:"status" example:"healthy"`
 	Timestamp string `json:"timestamp" example:"2024-01-15T10:30:00Z"`
+	// Database is reported when the cache database has a size cap
+	Database *DatabaseUsage `json:"database,omitempty"`
+	// TemperatureThresholds are the effective hot/cold classification thresholds
+	TemperatureThresholds *TemperatureThresholds `json:"temperature_thresholds,omitempty"`
+	// Dependencies reports the state of the cache database, Redis, and the NWS
+	Dependencies *Dependencies `json:"dependencies,omitempty"`
+}
+
+// Dependencies reports the health of each service dependency
+type Dependencies struct {
+	SQLite DependencyHealth `json:"sqlite"`
+	Redis  DependencyHealth `json:"redis"`
+	NWS    DependencyHealth `json:"nws"`
+	// Postgres is reported when forecasts are kept in Postgres
+	Postgres *DependencyHealth `json:"postgres,omitempty"`
+}
+
+// DependencyHealth reports whether a dependency is usable and how long it took
+// to answer. For the NWS both come from its most recent request.
+type DependencyHealth struct {
+	// Status is up, down, disabled (Redis not configured), or unknown (no NWS request yet)
+	Status    string  `json:"status" example:"up"`
+	LatencyMs float64 `json:"latency_ms" example:"0.42"`
+	Error     string  `json:"error,omitempty"`
+	// Circuit is the state of the NWS circuit breaker: closed, open, or
+	// half-open; omitted for other dependencies and when there is no breaker
+	Circuit string `json:"circuit,omitempty" example:"closed"`
+	// BackoffUntil is when the NWS's last Retry-After runs out, omitted unless
+	// requests to it are currently held back
+	BackoffUntil *time.Time `json:"backoff_until,omitempty" example:"2024-01-15T10:31:00Z"`
+}
+
+// TemperatureThresholds reports the temperatures that separate hot, moderate, and cold
+type TemperatureThresholds struct {
+	HotC  float64 `json:"hot_c" example:"30"`
+	ColdC float64 `json:"cold_c" example:"10"`
+}
+
+// DatabaseUsage reports the SQLite cache database size against its cap
+type DatabaseUsage struct {
+	SizeBytes int64 `json:"size_bytes" example:"52428800"`
+	// MaxBytes is omitted when the database size is unlimited
+	MaxBytes   int64    `json:"max_bytes,omitempty" example:"104857600"`
+	UsageRatio *float64 `json:"usage_ratio,omitempty" example:"0.5"`
+}
+
+// CacheStatsResponse represents the cache statistics response
+type CacheStatsResponse struct {
+	Database DatabaseUsage `json:"database"`
+	// Tables maps each prunable cache table to its row count
+	Tables       map[string]int64 `json:"tables"`
+	PrunedRows   int64            `json:"pruned_rows" example:"1200"`
+	LastPrunedAt *time.Time       `json:"last_pruned_at,omitempty" example:"2024-01-15T10:00:00Z"`
+	// PurgedRows counts cached forecasts deleted for exceeding the cache retention
+	PurgedRows int64 `json:"purged_rows" example:"340"`
+	// LastPurgeAt is when the retention purge last ran, whether or not it deleted anything
+	LastPurgeAt *time.Time `json:"last_purge_at,omitempty" example:"2024-01-15T10:00:00Z"`
+	// Warming lists the locations the cache warmer keeps fresh
+	Warming []WarmLocation `json:"warming,omitempty"`
+}
+
+// Warm location sources
+const (
+	// WarmSourceConfig is a location listed in WARM_LOCATIONS
+	WarmSourceConfig = "config"
+	// WarmSourceAdmin is a location added through the admin endpoint
+	WarmSourceAdmin = "admin"
+)
+
+// WarmLocation is a coordinate the cache warmer refreshes shortly before its
+// cached forecast expires
+type WarmLocation struct {
+	Latitude  float64 `json:"latitude" example:"40.7128"`
+	Longitude float64 `json:"longitude" example:"-74.006"`
+	// Source is config for WARM_LOCATIONS, or admin for locations added
+	// through the admin endpoint
+	Source string `json:"source" example:"config"`
+	// LastWarmedAt is when the warmer last refreshed the location
+	LastWarmedAt *time.Time `json:"last_warmed_at,omitempty" example:"2024-01-15T10:55:00Z"`
+	// LastError is why the warmer's last refresh failed, if it did
+	LastError string `json:"last_error,omitempty" example:"NWS returned status: 503"`
+}
+
+// WarmLocationsResponse lists the locations the cache warmer keeps fresh
+type WarmLocationsResponse struct {
+	Locations []WarmLocation `json:"locations"`
+}
+
+// CacheInvalidationResponse reports how many cache entries an invalidation removed from each tier
+type CacheInvalidationResponse struct {
+	RedisKeys  int64 `json:"redis_keys" example:"2"`
+	SQLiteRows int64 `json:"sqlite_rows" example:"3"`
+	// PostgresRows counts forecasts removed from a Postgres forecast store
+	PostgresRows int64 `json:"postgres_rows,omitempty" example:"1"`
+}
+
+// APIKey is a client credential. Only the key's SHA-256 hash is kept; ID names
+// the client in logs and metrics.
+type APIKey struct {
+	ID string
+	// KeyHash is the hex-encoded SHA-256 of the key
+	KeyHash string
+	// Label is a human-readable name for the key's holder; empty uses the ID
+	Label string
+	// DailyQuota is how many requests the key may make each UTC day; zero
+	// means unlimited
+	DailyQuota int
+}
+
+// UsageResponse is an API key holder's view of its own usage
+type UsageResponse struct {
+	// KeyID names the key the request authenticated with
+	KeyID string `json:"key_id" example:"mobile-app"`
+	// Label is the key's human-readable name, or its ID when it has none
+	Label string `json:"label" example:"Mobile app"`
+	// Quota is the key's daily quota and how much of today's it has used
+	Quota QuotaUsage `json:"quota"`
+	// History is the key's request count for each recent day, oldest first,
+	// ending today
+	History []DailyUsage `json:"history"`
+	// RateLimit is the key's rate limit state after this request; omitted
+	// when clients aren't rate limited
+	RateLimit *RateLimitUsage `json:"rate_limit,omitempty"`
+}
+
+// QuotaUsage is an API key's daily quota, as reported in the X-Quota-*
+// headers. Periods are UTC days.
+type QuotaUsage struct {
+	// Limit is how many requests the key may make a day; zero means unlimited
+	Limit int `json:"limit" example:"10000"`
+	// Used is how many requests have counted against today's quota
+	Used int `json:"used" example:"1234"`
+	// Remaining is how many requests are left today; omitted when unlimited
+	Remaining *int `json:"remaining,omitempty" example:"8766"`
+	// PeriodStart is when today's period began; ResetSeconds is how long until
+	// it ends, and ResetAt when
+	PeriodStart  time.Time `json:"period_start" example:"2024-01-15T00:00:00Z"`
+	ResetSeconds int       `json:"reset_seconds" example:"48600"`
+	ResetAt      time.Time `json:"reset_at" example:"2024-01-16T00:00:00Z"`
+}
+
+// DailyUsage is how many requests counted against an API key's quota on a day
+type DailyUsage struct {
+	// Date is the UTC day, as YYYY-MM-DD
+	Date     string `json:"date" example:"2024-01-15"`
+	Requests int    `json:"requests" example:"1234"`
+}
+
+// RateLimitUsage is a client's token bucket, as reported in the
+// X-RateLimit-* headers
+type RateLimitUsage struct {
+	// Limit is the sustained number of requests allowed a minute
+	Limit int `json:"limit" example:"60"`
+	// Burst is how many requests may be sent at once when the bucket is full
+	Burst int `json:"burst" example:"60"`
+	// Remaining is how many requests may be sent now
+	Remaining int `json:"remaining" example:"42"`
+	// ResetSeconds is how long until the bucket is full again, and ResetAt when
+	ResetSeconds int       `json:"reset_seconds" example:"18"`
+	ResetAt      time.Time `json:"reset_at" example:"2024-01-15T10:30:18Z"`
+}
+
+// CachedLocation is a coordinate the SQLite cache holds a forecast for
+type CachedLocation struct {
+	Latitude  float64   `json:"latitude" example:"40.713"`
+	Longitude float64   `json:"longitude" example:"-74.006"`
+	Forecast  string    `json:"forecast" example:"Partly Cloudy"`
+	City      string    `json:"city,omitempty" example:"New York"`
+	State     string    `json:"state,omitempty" example:"NY"`
+	Timestamp time.Time `json:"timestamp" example:"2024-01-15T10:00:00Z"`
+	// IsFresh reports whether the entry is within the cache TTL and would be served without an NWS fetch
+	IsFresh bool `json:"is_fresh" example:"true"`
+}
+
+// CachedLocationsResponse is a page of cached locations, most recently refreshed first
+type CachedLocationsResponse struct {
+	Locations []CachedLocation `json:"locations"`
+	// Total is the number of cached locations across all pages
+	Total  int64 `json:"total" example:"350"`
+	Limit  int   `json:"limit" example:"100"`
+	Offset int   `json:"offset" example:"0"`
 }
 
 // WeatherCache represents cached weather data
@@ -30,6 +475,73 @@ type WeatherCache struct {
 	TempC     float64   `json:"temp_c"`
 	TempF     float64   `json:"temp_f"`
 	Timestamp time.Time `json:"timestamp"`
+	// DetailedForecast is the NWS's narrative for the period; empty for other
+	// providers and entries cached before it was recorded
+	DetailedForecast string `json:"detailed_forecast,omitempty"`
+	// WindKmh and WindMph are the period's wind speed range and WindDirection
+	// the compass point it blows from; unset for providers that don't forecast
+	// wind and entries cached before it was recorded
+	WindKmh       *SpeedRange `json:"wind_kmh,omitempty"`
+	WindMph       *SpeedRange `json:"wind_mph,omitempty"`
+	WindDirection string      `json:"wind_direction,omitempty"`
+	// PrecipitationProbability is the period's chance of precipitation in
+	// percent; nil when the provider gives none, which is not the same as 0
+	PrecipitationProbability *float64 `json:"precipitation_probability,omitempty"`
+	// RelativeHumidity is the period's relative humidity in percent and
+	// DewpointC and DewpointF its dewpoint; nil when the provider gives none
+	RelativeHumidity *float64 `json:"relative_humidity,omitempty"`
+	DewpointC        *float64 `json:"dewpoint_c,omitempty"`
+	DewpointF        *float64 `json:"dewpoint_f,omitempty"`
+	// PeriodName names the forecast period ("Tonight") and IsDaytime reports
+	// whether it is daytime; unset for providers that don't say and entries
+	// cached before they were recorded
+	PeriodName string `json:"period_name,omitempty"`
+	IsDaytime  *bool  `json:"is_daytime,omitempty"`
+	// Icon is the NWS icon URL for the period; empty for other providers and
+	// entries cached before it was recorded
+	Icon string `json:"icon,omitempty"`
+	// UVIndex is the UV index forecast for the hour the entry was fetched,
+	// added the first time a request includes it; nil until then, and when
+	// no UV forecast covers the location
+	UVIndex *float64 `json:"uv_index,omitempty"`
+	// TemperatureTrend is the NWS's "rising" or "falling" for a period whose
+	// temperature moves against its usual course; empty when the NWS gives
+	// none, for other providers, and entries cached before it was recorded
+	TemperatureTrend string `json:"temperature_trend,omitempty"`
+	// TemperatureChangeC is how far TempC moved from the coordinate's
+	// previously cached entry; nil for a coordinate's first entry and
+	// entries cached before it was recorded
+	TemperatureChangeC *float64 `json:"temperature_change_c,omitempty"`
+	// City and State name the NWS relative location of the coordinate, and
+	// TimeZone its IANA time zone; empty for grid-cell entries and rows cached
+	// before they were recorded
+	City     string `json:"city,omitempty"`
+	State    string `json:"state,omitempty"`
+	TimeZone string `json:"time_zone,omitempty"`
+	// Provider names the forecast provider the entry came from; empty for
+	// entries cached before it was recorded, which are the NWS's
+	Provider string `json:"provider,omitempty"`
+	// GridID, GridX, and GridY name the NWS grid cell the forecast is for;
+	// unset for other providers, grid-cell entries, and entries cached before
+	// it was recorded
+	GridID string `json:"grid_id,omitempty"`
+	GridX  int    `json:"grid_x,omitempty"`
+	GridY  int    `json:"grid_y,omitempty"`
+
+	// Raw is the forecast document the entry was parsed from, when freshly fetched
+	Raw *RawDocument `json:"-"`
+	// Source is the cache tier the entry was read from (redis or sqlite)
+	Source string `json:"-"`
+	// DistanceKm is how far the entry's coordinate is from the one looked up,
+	// when it was served for a nearby coordinate rather than its own
+	DistanceKm float64 `json:"-"`
+}
+
+// RawDocument is an upstream NWS response body kept verbatim for debugging
+type RawDocument struct {
+	ContentType string    `json:"content_type"`
+	Body        []byte    `json:"body"`
+	Timestamp   time.Time `json:"timestamp"`
 }
 
 // Coordinates represents geographic coordinates
@@ -41,17 +553,641 @@ type Coordinates struct {
 // NWSPointsResponse represents the NWS API points endpoint response
 type NWSPointsResponse struct {
 	Properties struct {
-		Forecast string `json:"forecast"`
+		GridID         string `json:"gridId"`
+		GridX          int    `json:"gridX"`
+		GridY          int    `json:"gridY"`
+		Forecast       string `json:"forecast"`
+		ForecastHourly string `json:"forecastHourly"`
+		ForecastZone   string `json:"forecastZone"`
+		// County is the URL of the point's county zone
+		County string `json:"county"`
+		// RadarStation is the ID of the nearest NEXRAD radar, such as KOKX
+		RadarStation string `json:"radarStation"`
+		// RelativeLocation is the nearest city to the point
+		RelativeLocation struct {
+			Properties struct {
+				City  string `json:"city"`
+				State string `json:"state"`
+			} `json:"properties"`
+		} `json:"relativeLocation"`
+		// TimeZone is the point's IANA time zone, such as America/New_York
+		TimeZone string `json:"timeZone"`
+		// ObservationStations lists the stations near the point, nearest first
+		ObservationStations string `json:"observationStations"`
 	} `json:"properties"`
 }
 
+// NWSStationsResponse represents an NWS observation station feature collection
+type NWSStationsResponse struct {
+	Features []struct {
+		Geometry struct {
+			// Coordinates is [longitude, latitude]
+			Coordinates []float64 `json:"coordinates"`
+		} `json:"geometry"`
+		Properties struct {
+			StationIdentifier string `json:"stationIdentifier"`
+			Name              string `json:"name"`
+		} `json:"properties"`
+	} `json:"features"`
+}
+
+// NearestStation represents the cached nearest observation station for a coordinate
+type NearestStation struct {
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	StationID string    `json:"station_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// UncoveredPoint records a normalized coordinate the NWS points API reported
+// no forecast coverage for
+type UncoveredPoint struct {
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PointMetadata represents cached NWS metadata resolved for a coordinate
+type PointMetadata struct {
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	ForecastZone string    `json:"forecast_zone"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// GridPoint maps a normalized coordinate to the ~2.5km NWS forecast grid cell containing it
+type GridPoint struct {
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	GridID      string  `json:"grid_id"`
+	GridX       int     `json:"grid_x"`
+	GridY       int     `json:"grid_y"`
+	ForecastURL string  `json:"forecast_url"`
+	// ForecastHourlyURL is empty when the NWS publishes no hourly forecast for the cell
+	ForecastHourlyURL string `json:"forecast_hourly_url"`
+	// City and State name the nearest city the NWS reports for the point
+	City  string `json:"city"`
+	State string `json:"state"`
+	// TimeZone is the point's IANA time zone name
+	TimeZone string `json:"time_zone"`
+	// ForecastZone and County are the IDs of the point's public forecast and
+	// county zones, such as NYZ072 and NYC061
+	ForecastZone string `json:"forecast_zone"`
+	County       string `json:"county"`
+	// RadarStation is the ID of the nearest NEXRAD radar
+	RadarStation string    `json:"radar_station"`
+	Timestamp    time.Time `json:"timestamp"`
+
+	// Raw is the points document the mapping was parsed from, when freshly fetched
+	Raw *RawDocument `json:"-"`
+}
+
+// Alert represents a normalized NWS weather alert
+type Alert struct {
+	ID            string     `json:"id" example:"urn:oid:2.49.0.1.840.0.abc123"`
+	Event         string     `json:"event" example:"Winter Storm Warning"`
+	Severity      string     `json:"severity" example:"Severe"`
+	Urgency       string     `json:"urgency" example:"Expected"`
+	Headline      string     `json:"headline" example:"Winter Storm Warning issued January 15 at 3:00AM EST"`
+	MessageType   string     `json:"message_type" example:"Alert"`
+	Sent          time.Time  `json:"sent" example:"2024-01-15T03:00:00-05:00"`
+	Onset         *time.Time `json:"onset,omitempty" example:"2024-01-15T18:00:00-05:00"`
+	Expires       time.Time  `json:"expires" example:"2024-01-16T06:00:00-05:00"`
+	AffectedZones []string   `json:"affected_zones" example:"NYZ072"`
+	References    []string   `json:"references,omitempty"`
+	// Geometry is the area the alert covers: the polygon the NWS drew for a
+	// storm-based warning, or the shapes of its affected zones. Set only with
+	// ?geometry=true, and omitted when no shape could be found.
+	Geometry *Geometry `json:"geometry,omitempty"`
+}
+
+// Geometry is a GeoJSON geometry with its coordinates as the NWS sent them
+type Geometry struct {
+	Type        string          `json:"type" example:"Polygon"`
+	Coordinates json.RawMessage `json:"coordinates" swaggertype:"array,number"`
+}
+
+// AlertsResponse represents the active alerts for a coordinate
+type AlertsResponse struct {
+	Latitude  float64 `json:"latitude" example:"40.7128"`
+	Longitude float64 `json:"longitude" example:"-74.006"`
+	// Alerts is empty, never null, when no alert is active
+	Alerts []Alert `json:"alerts"`
+
+	// FreshUntil is when the underlying data goes stale; zero for stale fallbacks
+	FreshUntil time.Time `json:"-"`
+	// CacheHit reports the data was served from a fresh cache without an upstream fetch
+	CacheHit bool `json:"-"`
+}
+
+// MetadataResponse is the NWS metadata a coordinate resolves to: its
+// forecast office and grid cell, zones, radar, and forecast URLs
+type MetadataResponse struct {
+	Latitude  float64 `json:"latitude" example:"40.7128"`
+	Longitude float64 `json:"longitude" example:"-74.006"`
+	// Office is the NWS forecast office whose grid contains the point (gridId)
+	Office       string `json:"office" example:"OKX"`
+	GridX        int    `json:"grid_x" example:"33"`
+	GridY        int    `json:"grid_y" example:"35"`
+	ForecastZone string `json:"forecast_zone" example:"NYZ072"`
+	County       string `json:"county" example:"NYC061"`
+	RadarStation string `json:"radar_station" example:"KOKX"`
+	// ForecastURL is the NWS API URL of the grid cell's forecast
+	ForecastURL string `json:"forecast_url" example:"https://api.weather.gov/gridpoints/OKX/33,35/forecast"`
+	// ForecastHourlyURL is omitted when the NWS publishes no hourly forecast for the cell
+	ForecastHourlyURL string `json:"forecast_hourly_url,omitempty" example:"https://api.weather.gov/gridpoints/OKX/33,35/forecast/hourly"`
+	City              string `json:"city" example:"New York"`
+	State             string `json:"state" example:"NY"`
+	TimeZone          string `json:"time_zone" example:"America/New_York"`
+
+	// FreshUntil is when the underlying data goes stale; zero for stale fallbacks
+	FreshUntil time.Time `json:"-"`
+	// CacheHit reports the data was served from a fresh cache without an upstream fetch
+	CacheHit bool `json:"-"`
+}
+
+// AirQualityResponse is the current air quality near a coordinate, described
+// by its worst pollutant
+type AirQualityResponse struct {
+	Latitude  float64 `json:"latitude" example:"40.7128"`
+	Longitude float64 `json:"longitude" example:"-74.006"`
+	// AQI is the highest AQI among the reported pollutants, and Category and
+	// PrimaryPollutant are that pollutant's
+	AQI              int    `json:"aqi" example:"152"`
+	Category         string `json:"category" example:"Unhealthy"`
+	PrimaryPollutant string `json:"primary_pollutant" example:"PM2.5"`
+	// ReportingArea names the area whose monitors report the primary
+	// pollutant, such as a city or metropolitan area
+	ReportingArea string `json:"reporting_area" example:"Northeast Urban NJ"`
+	StateCode     string `json:"state_code,omitempty" example:"NJ"`
+	// ObservedAt is the start of the hour the primary pollutant was observed,
+	// omitted when the reporting area's time zone isn't recognized
+	ObservedAt *time.Time `json:"observed_at,omitempty" example:"2023-06-07T14:00:00-05:00"`
+	// Pollutants lists every reported pollutant, worst first
+	Pollutants []AirQualityObservation `json:"pollutants"`
+
+	// FreshUntil is when the underlying data goes stale; zero for stale fallbacks
+	FreshUntil time.Time `json:"-"`
+	// CacheHit reports the data was served from a fresh cache without an upstream fetch
+	CacheHit bool `json:"-"`
+}
+
+// AirQualityObservation is a pollutant's current AQI at a reporting area
+type AirQualityObservation struct {
+	Pollutant string `json:"pollutant" example:"PM2.5"`
+	AQI       int    `json:"aqi" example:"152"`
+	// Category is the EPA AQI category: Good, Moderate, Unhealthy for
+	// Sensitive Groups, Unhealthy, Very Unhealthy, or Hazardous
+	Category      string     `json:"category" example:"Unhealthy"`
+	ReportingArea string     `json:"reporting_area" example:"Northeast Urban NJ"`
+	StateCode     string     `json:"state_code,omitempty" example:"NJ"`
+	ObservedAt    *time.Time `json:"observed_at,omitempty" example:"2023-06-07T14:00:00-05:00"`
+}
+
+// AirQualityCache represents the cached air quality observations near a
+// coordinate
+type AirQualityCache struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// Observations is empty when no monitors report near the coordinate
+	Observations []AirQualityObservation `json:"observations"`
+	Timestamp    time.Time               `json:"timestamp"`
+}
+
+// GeocodeCache represents the cached places a location query resolved to
+type GeocodeCache struct {
+	Query     string    `json:"query"`
+	Places    []Place   `json:"places"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AlertCache represents the cached active alerts for an NWS zone or coordinate
+type AlertCache struct {
+	// Zone is empty for a coordinate's alerts
+	Zone      string    `json:"zone"`
+	Alerts    []Alert   `json:"alerts"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NWSAlertsResponse represents the NWS active alerts GeoJSON feature collection
+type NWSAlertsResponse struct {
+	Features []struct {
+		// Geometry is null for alerts issued by zone
+		Geometry   *Geometry          `json:"geometry"`
+		Properties NWSAlertProperties `json:"properties"`
+	} `json:"features"`
+}
+
+// NWSZoneResponse represents the NWS zone endpoint response, trimmed to the
+// zone's shape
+type NWSZoneResponse struct {
+	Geometry *Geometry `json:"geometry"`
+}
+
+// NWSAlertProperties represents the properties of one NWS alert feature
+type NWSAlertProperties struct {
+	ID            string   `json:"id"`
+	AffectedZones []string `json:"affectedZones"`
+	References    []struct {
+		Identifier string `json:"identifier"`
+	} `json:"references"`
+	Sent        time.Time  `json:"sent"`
+	Onset       *time.Time `json:"onset"`
+	Expires     time.Time  `json:"expires"`
+	MessageType string     `json:"messageType"`
+	Severity    string     `json:"severity"`
+	Urgency     string     `json:"urgency"`
+	Event       string     `json:"event"`
+	Headline    string     `json:"headline"`
+}
+
 // NWSForecastResponse represents the NWS API forecast endpoint response
 type NWSForecastResponse struct {
 	Properties struct {
 		Periods []struct {
-			ShortForecast   string  `json:"shortForecast"`
-			Temperature     float64 `json:"temperature"`
-			TemperatureUnit string  `json:"temperatureUnit"`
+			Name                       string      `json:"name"`
+			StartTime                  time.Time   `json:"startTime"`
+			EndTime                    time.Time   `json:"endTime"`
+			IsDaytime                  bool        `json:"isDaytime"`
+			ShortForecast              string      `json:"shortForecast"`
+			DetailedForecast           string      `json:"detailedForecast"`
+			Temperature                float64     `json:"temperature"`
+			TemperatureUnit            string      `json:"temperatureUnit"`
+			TemperatureTrend           string      `json:"temperatureTrend"`
+			ProbabilityOfPrecipitation NWSQuantity `json:"probabilityOfPrecipitation"`
+			Dewpoint                   NWSQuantity `json:"dewpoint"`
+			RelativeHumidity           NWSQuantity `json:"relativeHumidity"`
+			WindSpeed                  string      `json:"windSpeed"`
+			WindDirection              string      `json:"windDirection"`
+			Icon                       string      `json:"icon"`
 		} `json:"periods"`
 	} `json:"properties"`
 }
+
+// Observation represents a single measured observation from an NWS station.
+// Measurements are nil when the station did not report them.
+type Observation struct {
+	Timestamp     time.Time `json:"timestamp" example:"2024-01-15T10:00:00Z"`
+	Description   string    `json:"description,omitempty" example:"Mostly Cloudy"`
+	TemperatureC  *float64  `json:"temperature_c,omitempty" example:"12.2"`
+	TemperatureF  *float64  `json:"temperature_f,omitempty" example:"54"`
+	DewpointC     *float64  `json:"dewpoint_c,omitempty" example:"6.1"`
+	DewpointF     *float64  `json:"dewpoint_f,omitempty" example:"43"`
+	WindSpeedKmh  *float64  `json:"wind_speed_kmh,omitempty" example:"14.8"`
+	WindSpeedMph  *float64  `json:"wind_speed_mph,omitempty" example:"9.2"`
+	WindDirection *float64  `json:"wind_direction_deg,omitempty" example:"270"`
+	PressureHPa   *float64  `json:"pressure_hpa,omitempty" example:"1016.3"`
+}
+
+// ObservationHistoryResponse represents a time-ordered observation series for a station
+type ObservationHistoryResponse struct {
+	StationID    string        `json:"station_id" example:"KNYC"`
+	Hours        int           `json:"hours" example:"24"`
+	Observations []Observation `json:"observations"`
+
+	// FreshUntil is when the underlying data goes stale; zero for stale fallbacks
+	FreshUntil time.Time `json:"-"`
+	// CacheHit reports the data was served from a fresh cache without an upstream fetch
+	CacheHit bool `json:"-"`
+}
+
+// CurrentConditionsResponse represents the latest observed conditions at the
+// observation station nearest a coordinate
+type CurrentConditionsResponse struct {
+	Latitude    float64     `json:"latitude" example:"40.7128"`
+	Longitude   float64     `json:"longitude" example:"-74.006"`
+	StationID   string      `json:"station_id" example:"KNYC"`
+	Observation Observation `json:"observation"`
+
+	// FreshUntil is when the underlying data goes stale; zero for stale fallbacks
+	FreshUntil time.Time `json:"-"`
+	// CacheHit reports the data was served from a fresh cache without an upstream fetch
+	CacheHit bool `json:"-"`
+}
+
+// BatchCoordinate is one coordinate of a batch weather request. The fields are
+// pointers so a missing coordinate can be told apart from zero.
+type BatchCoordinate struct {
+	Lat *float64 `json:"lat" example:"40.7128"`
+	Lon *float64 `json:"lon" example:"-74.006"`
+}
+
+// BatchWeatherResponse holds one result per requested coordinate, in request order
+type BatchWeatherResponse struct {
+	Results []BatchWeatherResult `json:"results"`
+}
+
+// BatchWeatherResult is the weather for one batch coordinate, or the error
+// that coordinate failed with. Exactly one of Weather and Error is set; Lat
+// and Lon echo the request and are omitted when it left them out.
+type BatchWeatherResult struct {
+	Lat     *float64         `json:"lat,omitempty" example:"40.7128"`
+	Lon     *float64         `json:"lon,omitempty" example:"-74.006"`
+	Weather *WeatherResponse `json:"weather,omitempty"`
+	Error   *ErrorResponse   `json:"error,omitempty"`
+}
+
+// Message types on the weather updates WebSocket
+const (
+	UpdateTypeSubscribe   = "subscribe"
+	UpdateTypeUnsubscribe = "unsubscribe"
+	UpdateTypeWeather     = "weather"
+	UpdateTypeError       = "error"
+)
+
+// WeatherSubscription is a message a weather updates client sends to start
+// (subscribe) or stop (unsubscribe) receiving the weather for coordinates
+type WeatherSubscription struct {
+	Type        string            `json:"type" example:"subscribe"`
+	Coordinates []BatchCoordinate `json:"coordinates"`
+}
+
+// WeatherUpdate is a message sent to a weather updates client: the weather
+// for a subscribed coordinate, or an error. Lat and Lon echo the subscribed
+// coordinate, and are omitted for errors about a whole message.
+type WeatherUpdate struct {
+	Type    string           `json:"type" example:"weather"`
+	Lat     *float64         `json:"lat,omitempty" example:"40.7128"`
+	Lon     *float64         `json:"lon,omitempty" example:"-74.006"`
+	Weather *WeatherResponse `json:"weather,omitempty"`
+	Error   *ErrorResponse   `json:"error,omitempty"`
+}
+
+// NWSObservationResponse represents a single NWS observation, such as a station's latest
+type NWSObservationResponse struct {
+	Properties NWSObservationProperties `json:"properties"`
+}
+
+// NWSQuantity represents an NWS quantitative value with its WMO unit code
+type NWSQuantity struct {
+	UnitCode string   `json:"unitCode"`
+	Value    *float64 `json:"value"`
+}
+
+// NWSObservationsResponse represents the NWS station observations endpoint response
+type NWSObservationsResponse struct {
+	Features []struct {
+		Properties NWSObservationProperties `json:"properties"`
+	} `json:"features"`
+}
+
+// NWSObservationProperties represents the measured values of one NWS observation
+type NWSObservationProperties struct {
+	Timestamp          time.Time   `json:"timestamp"`
+	TextDescription    string      `json:"textDescription"`
+	Temperature        NWSQuantity `json:"temperature"`
+	Dewpoint           NWSQuantity `json:"dewpoint"`
+	WindSpeed          NWSQuantity `json:"windSpeed"`
+	WindDirection      NWSQuantity `json:"windDirection"`
+	BarometricPressure NWSQuantity `json:"barometricPressure"`
+}
+
+// ObservationCache represents a cached observation series for a station
+type ObservationCache struct {
+	StationID    string        `json:"station_id"`
+	Hours        int           `json:"hours"`
+	Observations []Observation `json:"observations"`
+	Timestamp    time.Time     `json:"timestamp"`
+}
+
+// RequestLogEntry is one API request recorded for the daily rollups
+type RequestLogEntry struct {
+	Timestamp time.Time
+	Route     string
+	Status    int
+	LatencyMs float64
+	// Coordinate is the normalized "lat,lon" requested, empty when the route takes none
+	Coordinate string
+	// CacheHit is nil for routes that don't consult a cache
+	CacheHit *bool
+}
+
+// DailyStats summarizes one UTC day of API traffic
+type DailyStats struct {
+	Day                 string           `json:"day" example:"2024-01-15"`
+	TotalRequests       int64            `json:"total_requests" example:"1250"`
+	RouteCounts         map[string]int64 `json:"route_counts"`
+	ClientErrorCount    int64            `json:"client_error_count" example:"12"`
+	ErrorCount          int64            `json:"error_count" example:"3"`
+	CacheHitRatio       *float64         `json:"cache_hit_ratio,omitempty" example:"0.92"`
+	DistinctCoordinates int64            `json:"distinct_coordinates" example:"87"`
+	P50LatencyMs        float64          `json:"p50_latency_ms" example:"1.4"`
+	P95LatencyMs        float64          `json:"p95_latency_ms" example:"210.5"`
+}
+
+// DailyStatsResponse represents the daily stats series for a date range
+type DailyStatsResponse struct {
+	From string       `json:"from" example:"2024-01-01"`
+	To   string       `json:"to" example:"2024-01-31"`
+	Days []DailyStats `json:"days"`
+}
+
+// WeatherDay summarizes the cached forecasts for a coordinate over one UTC day
+type WeatherDay struct {
+	Day       string  `json:"day" example:"2024-01-15"`
+	MinTempC  float64 `json:"min_temp_c" example:"-1.5"`
+	MaxTempC  float64 `json:"max_temp_c" example:"6"`
+	MeanTempC float64 `json:"mean_temp_c" example:"2.3"`
+	MinTempF  float64 `json:"min_temp_f" example:"29"`
+	MaxTempF  float64 `json:"max_temp_f" example:"43"`
+	MeanTempF float64 `json:"mean_temp_f" example:"36.1"`
+	// Forecast is the most frequent short forecast of the day
+	Forecast string `json:"forecast" example:"Partly Cloudy"`
+	// Samples is the number of cached forecasts summarized
+	Samples int `json:"samples" example:"18"`
+}
+
+// WeatherHistoryResponse represents the daily forecast history for a coordinate
+type WeatherHistoryResponse struct {
+	Latitude  float64      `json:"latitude" example:"40.7128"`
+	Longitude float64      `json:"longitude" example:"-74.006"`
+	From      string       `json:"from" example:"2024-01-01"`
+	To        string       `json:"to" example:"2024-01-31"`
+	Days      []WeatherDay `json:"days"`
+}
+
+// ForecastPeriod is one normalized NWS forecast period, hourly or day/night
+type ForecastPeriod struct {
+	// Name labels day/night periods ("Tonight", "Tuesday"); hourly periods have none
+	Name      string    `json:"name,omitempty" xml:"name,omitempty" example:"Tonight"`
+	StartTime time.Time `json:"start_time" xml:"start_time" example:"2024-01-15T23:00:00Z"`
+	EndTime   time.Time `json:"end_time" xml:"end_time" example:"2024-01-16T11:00:00Z"`
+	// StartTimeLocal and EndTimeLocal are the same in the response's time zone
+	StartTimeLocal   *time.Time `json:"start_time_local,omitempty" xml:"start_time_local,omitempty" example:"2024-01-15T18:00:00-05:00"`
+	EndTimeLocal     *time.Time `json:"end_time_local,omitempty" xml:"end_time_local,omitempty" example:"2024-01-16T06:00:00-05:00"`
+	IsDaytime        bool       `json:"is_daytime" xml:"is_daytime" example:"false"`
+	ShortForecast    string     `json:"short_forecast" xml:"short_forecast" example:"Mostly Clear"`
+	DetailedForecast string     `json:"detailed_forecast,omitempty" xml:"detailed_forecast,omitempty" example:"Mostly clear, with a low around 28. Northwest wind around 9 mph."`
+	TempC            float64    `json:"temp_c" xml:"temp_c" example:"-2.2"`
+	TempF            float64    `json:"temp_f" xml:"temp_f" example:"28"`
+	// PrecipitationProbability is the chance of precipitation in percent, when forecast
+	PrecipitationProbability *float64 `json:"precipitation_probability,omitempty" xml:"precipitation_probability,omitempty" example:"20"`
+	// HumidityPercent is the relative humidity in percent, when forecast
+	HumidityPercent *float64 `json:"humidity_percent,omitempty" xml:"humidity_percent,omitempty" example:"65"`
+	// WindSpeed is as the NWS words it, such as "5 to 10 mph"
+	WindSpeed     string `json:"wind_speed,omitempty" xml:"wind_speed,omitempty" example:"9 mph"`
+	WindDirection string `json:"wind_direction,omitempty" xml:"wind_direction,omitempty" example:"NW"`
+	// Icon is the NWS icon URL for the period, sized by ?icon_size=
+	Icon string `json:"icon,omitempty" xml:"icon,omitempty" example:"https://api.weather.gov/icons/land/night/few?size=medium"`
+}
+
+// AstronomyResponse is a coordinate's sun and moon for one day, computed
+// locally rather than fetched
+type AstronomyResponse struct {
+	Latitude  float64 `json:"latitude" example:"40.7128"`
+	Longitude float64 `json:"longitude" example:"-74.006"`
+	// Date is the local calendar day the events fall on
+	Date string `json:"date" example:"2024-06-20"`
+	// TimeZone is the IANA time zone the times are given in; UTC when the
+	// location's is unknown
+	TimeZone string `json:"time_zone" example:"America/New_York"`
+	// Sunrise and Sunset are null on days the sun doesn't cross the horizon
+	Sunrise *time.Time `json:"sunrise" example:"2024-06-20T05:25:00-04:00"`
+	Sunset  *time.Time `json:"sunset" example:"2024-06-20T20:31:00-04:00"`
+	// PolarCondition says why sunrise and sunset are null: polar_night when
+	// the sun stays below the horizon, midnight_sun when it stays above
+	PolarCondition string    `json:"polar_condition,omitempty" example:"polar_night"`
+	SolarNoon      time.Time `json:"solar_noon" example:"2024-06-20T12:58:00-04:00"`
+	// DayLengthSeconds is the time between sunrise and sunset: 0 in polar
+	// night and 86400 under the midnight sun
+	DayLengthSeconds int64 `json:"day_length_seconds" example:"54360"`
+	Moon             Moon  `json:"moon"`
+}
+
+// Moon is the moon's phase at local noon
+type Moon struct {
+	// Phase names one of the eight phases, from new_moon through
+	// waxing_crescent, first_quarter, waxing_gibbous, full_moon,
+	// waning_gibbous, and last_quarter to waning_crescent
+	Phase string `json:"phase" example:"waxing_gibbous"`
+	// Illumination is the fraction of the disk lit, from 0 to 1
+	Illumination float64 `json:"illumination" example:"0.97"`
+	// AgeDays is the time since the last new moon
+	AgeDays float64 `json:"age_days" example:"13.6"`
+}
+
+// HourlyForecast is one hour of a coordinate's hourly forecast
+type HourlyForecast struct {
+	Time time.Time `json:"time" example:"2024-01-15T23:00:00Z"`
+	// TimeLocal is Time in the response's time zone
+	TimeLocal     *time.Time `json:"time_local,omitempty" example:"2024-01-15T18:00:00-05:00"`
+	TempC         float64    `json:"temp_c" example:"-1.1"`
+	TempF         float64    `json:"temp_f" example:"30"`
+	ShortForecast string     `json:"short_forecast" example:"Mostly Clear"`
+	WindSpeed     string     `json:"wind_speed,omitempty" example:"9 mph"`
+	WindDirection string     `json:"wind_direction,omitempty" example:"NW"`
+	// PrecipitationProbability is the chance of precipitation in percent, when forecast
+	PrecipitationProbability *float64 `json:"precipitation_probability,omitempty" example:"5"`
+}
+
+// HourlyForecastResponse represents the remaining hours of a coordinate's hourly forecast, oldest first
+type HourlyForecastResponse struct {
+	Latitude  float64          `json:"latitude" example:"40.7128"`
+	Longitude float64          `json:"longitude" example:"-74.006"`
+	Hours     []HourlyForecast `json:"hours"`
+	// TimeZone is the IANA time zone local times are given in
+	TimeZone string `json:"time_zone,omitempty" example:"America/New_York"`
+
+	// FreshUntil is when the underlying data goes stale; zero for stale fallbacks
+	FreshUntil time.Time `json:"-"`
+	// CacheHit reports the data was served from a fresh cache without an upstream fetch
+	CacheHit bool `json:"-"`
+}
+
+// ForecastResponse represents the day/night forecast periods for a coordinate, in NWS order
+type ForecastResponse struct {
+	XMLName xml.Name `json:"-" xml:"forecast"`
+
+	Latitude  float64          `json:"latitude" xml:"latitude" example:"40.7128"`
+	Longitude float64          `json:"longitude" xml:"longitude" example:"-74.006"`
+	Periods   []ForecastPeriod `json:"periods" xml:"periods>period"`
+	// TimeZone is the IANA time zone local times are given in
+	TimeZone string `json:"time_zone,omitempty" xml:"time_zone,omitempty" example:"America/New_York"`
+
+	// FreshUntil is when the underlying data goes stale; zero for stale fallbacks
+	FreshUntil time.Time `json:"-" xml:"-"`
+	// CacheHit reports the data was served from a fresh cache without an upstream fetch
+	CacheHit bool `json:"-" xml:"-"`
+}
+
+// ForecastPeriodsCache represents the cached forecast periods for an NWS grid cell
+type ForecastPeriodsCache struct {
+	Periods   []ForecastPeriod `json:"periods"`
+	Timestamp time.Time        `json:"timestamp"`
+	// CacheHit reports the data was served from a fresh cache without an upstream fetch
+	CacheHit bool `json:"-"`
+	// Source is where the periods came from: a cache tier (redis or sqlite),
+	// live from the NWS, or stale after a failed fetch
+	Source string `json:"-"`
+}
+
+// Webhook delivery statuses
+const (
+	// DeliveryPending is a delivery waiting for its first attempt or a retry
+	DeliveryPending = "pending"
+	// DeliveryDelivered is a delivery the callback accepted with a 2xx response
+	DeliveryDelivered = "delivered"
+	// DeliveryDead is a delivery that failed every attempt and won't be retried
+	DeliveryDead = "dead"
+)
+
+// CreateSubscriptionRequest is the body of a webhook subscription request
+type CreateSubscriptionRequest struct {
+	CallbackURL string   `json:"callback_url" example:"https://example.com/hooks/weather"`
+	Lat         *float64 `json:"lat" example:"40.7128"`
+	Lon         *float64 `json:"lon" example:"-74.006"`
+	// MinSeverity is the least severe NWS alert severity delivered (Minor,
+	// Moderate, Severe, or Extreme); empty means Severe
+	MinSeverity string `json:"min_severity,omitempty" example:"Severe"`
+}
+
+// WebhookSubscription is a callback notified of new alerts at a coordinate
+type WebhookSubscription struct {
+	ID          string    `json:"id" example:"3f2b9c6d1e8a4f70"`
+	CallbackURL string    `json:"callback_url" example:"https://example.com/hooks/weather"`
+	Latitude    float64   `json:"latitude" example:"40.7128"`
+	Longitude   float64   `json:"longitude" example:"-74.006"`
+	MinSeverity string    `json:"min_severity" example:"Severe"`
+	CreatedAt   time.Time `json:"created_at" example:"2024-01-15T10:00:00Z"`
+	// Secret keys the HMAC-SHA256 signature of each delivery. It is only
+	// returned when the subscription is created.
+	Secret string `json:"secret,omitempty" example:"9b1c0e5f..."`
+	// Owner is the ID of the API key that created the subscription, or empty
+	// when API keys aren't required. Only its owner can see or delete it.
+	Owner string `json:"-"`
+}
+
+// WebhookSubscriptionsResponse lists webhook subscriptions, oldest first
+type WebhookSubscriptionsResponse struct {
+	Subscriptions []WebhookSubscription `json:"subscriptions"`
+}
+
+// WebhookDelivery is one alert sent, or to be sent, to a subscription's callback
+type WebhookDelivery struct {
+	ID             int64  `json:"id" example:"42"`
+	SubscriptionID string `json:"subscription_id" example:"3f2b9c6d1e8a4f70"`
+	AlertID        string `json:"alert_id" example:"urn:oid:2.49.0.1.840.0.abc123"`
+	// Status is pending, delivered, or dead once every attempt has failed
+	Status    string `json:"status" example:"delivered"`
+	Attempts  int    `json:"attempts" example:"1"`
+	LastError string `json:"last_error,omitempty" example:"callback returned status 503"`
+	// NextAttemptAt is when a pending delivery is next tried
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty" example:"2024-01-15T10:05:00Z"`
+	CreatedAt     time.Time  `json:"created_at" example:"2024-01-15T10:00:00Z"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty" example:"2024-01-15T10:00:01Z"`
+	// Payload is the JSON body the callback is sent
+	Payload string `json:"-"`
+}
+
+// WebhookDeliveriesResponse lists a subscription's deliveries, newest first
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+// WebhookPayload is the body POSTed to a subscription's callback for a new alert
+type WebhookPayload struct {
+	SubscriptionID string  `json:"subscription_id" example:"3f2b9c6d1e8a4f70"`
+	Latitude       float64 `json:"latitude" example:"40.7128"`
+	Longitude      float64 `json:"longitude" example:"-74.006"`
+	Alert          Alert   `json:"alert"`
+}
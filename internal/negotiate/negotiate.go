@@ -0,0 +1,50 @@
+// Package negotiate picks the format of a response body: JSON by default, or
+// XML or CSV for clients that ask for it
+package negotiate
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/jsoncase"
+)
+
+// Formats accepted by the format query parameter
+const (
+	JSON = "json"
+	XML  = "xml"
+	// CSV is only offered by ?format=csv on endpoints with tabular data;
+	// Send writes JSON for it, so those endpoints check Format first
+	CSV = "csv"
+)
+
+// Format returns the body format a request asks for. ?format= takes
+// precedence over the Accept header; XML is chosen when the client prefers
+// application/xml or text/xml. Browsers, which rank HTML above XML, and
+// clients without a preference get JSON.
+func Format(c *fiber.Ctx) string {
+	if f := c.Query("format"); f != "" {
+		switch {
+		case strings.EqualFold(f, XML):
+			return XML
+		case strings.EqualFold(f, CSV):
+			return CSV
+		}
+		return JSON
+	}
+	switch c.Accepts(fiber.MIMETextHTML, fiber.MIMEApplicationJSON, fiber.MIMEApplicationXML, fiber.MIMETextXML) {
+	case fiber.MIMEApplicationXML, fiber.MIMETextXML:
+		return XML
+	}
+	return JSON
+}
+
+// Send writes v in the request's format: XML, or JSON in the request's
+// property naming style. v must carry xml tags to be sent as XML.
+func Send(c *fiber.Ctx, v interface{}) error {
+	c.Vary(fiber.HeaderAccept)
+	if Format(c) == XML {
+		return c.XML(v)
+	}
+	return c.JSON(jsoncase.For(c, v))
+}
@@ -0,0 +1,59 @@
+package negotiate
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"weather-api-go/internal/models"
+)
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		accept   string
+		wantType string
+		wantBody string
+	}{
+		{"default", "", "", fiber.MIMEApplicationJSON, `{"error":"Invalid coordinates","code":"INVALID_LATITUDE"}`},
+		{"any", "", "*/*", fiber.MIMEApplicationJSON, `{"error":"Invalid coordinates","code":"INVALID_LATITUDE"}`},
+		{"application/xml", "", "application/xml", fiber.MIMEApplicationXML, `<error><error>Invalid coordinates</error><code>INVALID_LATITUDE</code></error>`},
+		{"text/xml", "", "text/xml", fiber.MIMEApplicationXML, `<error><error>Invalid coordinates</error><code>INVALID_LATITUDE</code></error>`},
+		{"xml preferred", "", "application/json;q=0.5, application/xml", fiber.MIMEApplicationXML, `<error><error>Invalid coordinates</error><code>INVALID_LATITUDE</code></error>`},
+		{"browser", "", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", fiber.MIMEApplicationJSON, `{"error":"Invalid coordinates","code":"INVALID_LATITUDE"}`},
+		{"format parameter", "?format=XML", "", fiber.MIMEApplicationXML, `<error><error>Invalid coordinates</error><code>INVALID_LATITUDE</code></error>`},
+		{"format parameter overrides Accept", "?format=json", "application/xml", fiber.MIMEApplicationJSON, `{"error":"Invalid coordinates","code":"INVALID_LATITUDE"}`},
+		// Errors from CSV exports are not tabular
+		{"csv", "?format=csv", "", fiber.MIMEApplicationJSON, `{"error":"Invalid coordinates","code":"INVALID_LATITUDE"}`},
+	}
+
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return Send(c, models.ErrorResponse{Error: "Invalid coordinates", Code: "INVALID_LATITUDE"})
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set(fiber.HeaderAccept, tt.accept)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if got := resp.Header.Get(fiber.HeaderContentType); got != tt.wantType+"; charset=utf-8" && got != tt.wantType {
+				t.Errorf("Content-Type = %q; want %s", got, tt.wantType)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %s; want %s", body, tt.wantBody)
+			}
+			if vary := resp.Header.Get(fiber.HeaderVary); vary != fiber.HeaderAccept {
+				t.Errorf("Vary = %q; want Accept", vary)
+			}
+		})
+	}
+}
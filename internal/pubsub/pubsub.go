@@ -0,0 +1,85 @@
+// Package pubsub fans values published under a key out to in-process
+// subscribers of that key, without ever blocking the publisher.
+package pubsub
+
+import "sync"
+
+// Hub delivers values published under a key to that key's subscribers. Each
+// subscription buffers only the latest value: one published while the previous
+// is still unread replaces it, so a slow subscriber sees fewer updates rather
+// than holding up Publish.
+type Hub[T any] struct {
+	mu   sync.Mutex
+	subs map[string]map[*Subscription[T]]struct{}
+}
+
+// NewHub creates a hub with no subscribers
+func NewHub[T any]() *Hub[T] {
+	return &Hub[T]{subs: make(map[string]map[*Subscription[T]]struct{})}
+}
+
+// Subscription receives the values published under one key until it is closed
+type Subscription[T any] struct {
+	hub     *Hub[T]
+	key     string
+	updates chan T
+	once    sync.Once
+}
+
+// Subscribe starts receiving values published under key. The subscription
+// must be closed once it is no longer read.
+func (h *Hub[T]) Subscribe(key string) *Subscription[T] {
+	s := &Subscription[T]{hub: h, key: key, updates: make(chan T, 1)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[key] == nil {
+		h.subs[key] = make(map[*Subscription[T]]struct{})
+	}
+	h.subs[key][s] = struct{}{}
+	return s
+}
+
+// Publish delivers v to every subscriber of key, replacing any value a
+// subscriber has not read yet. It never blocks on subscribers.
+func (h *Hub[T]) Publish(key string, v T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs[key] {
+		// Publish holds the lock, so only the subscriber's own read can empty
+		// the buffer between the drain and the send
+		select {
+		case <-s.updates:
+		default:
+		}
+		s.updates <- v
+	}
+}
+
+// Subscribers returns the number of open subscriptions to key
+func (h *Hub[T]) Subscribers(key string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs[key])
+}
+
+// Key returns the key the subscription receives values for
+func (s *Subscription[T]) Key() string {
+	return s.key
+}
+
+// Updates returns the channel published values arrive on. It is never closed.
+func (s *Subscription[T]) Updates() <-chan T {
+	return s.updates
+}
+
+// Close stops the subscription receiving values. Closing it again is a no-op.
+func (s *Subscription[T]) Close() {
+	s.once.Do(func() {
+		s.hub.mu.Lock()
+		defer s.hub.mu.Unlock()
+		delete(s.hub.subs[s.key], s)
+		if len(s.hub.subs[s.key]) == 0 {
+			delete(s.hub.subs, s.key)
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHubDeliversByKey(t *testing.T) {
+	hub := NewHub[int]()
+	a1, a2, b := hub.Subscribe("a"), hub.Subscribe("a"), hub.Subscribe("b")
+	defer a1.Close()
+	defer a2.Close()
+	defer b.Close()
+
+	hub.Publish("a", 1)
+	for i, s := range []*Subscription[int]{a1, a2} {
+		select {
+		case v := <-s.Updates():
+			if v != 1 {
+				t.Errorf("subscriber %d got %d; want 1", i, v)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("subscriber %d got nothing", i)
+		}
+	}
+	select {
+	case v := <-b.Updates():
+		t.Errorf("subscriber to b got %d published to a", v)
+	default:
+	}
+}
+
+func TestHubCoalescesForSlowSubscribers(t *testing.T) {
+	hub := NewHub[int]()
+	s := hub.Subscribe("a")
+	defer s.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 100; i++ {
+			hub.Publish("a", i)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a subscriber that isn't reading")
+	}
+
+	if v := <-s.Updates(); v != 100 {
+		t.Errorf("got %d; want only the latest value, 100", v)
+	}
+	select {
+	case v := <-s.Updates():
+		t.Errorf("got %d after the latest value; want nothing", v)
+	default:
+	}
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	hub := NewHub[int]()
+	s1, s2 := hub.Subscribe("a"), hub.Subscribe("a")
+	if got := hub.Subscribers("a"); got != 2 {
+		t.Fatalf("Subscribers = %d; want 2", got)
+	}
+
+	s1.Close()
+	s1.Close()
+	if got := hub.Subscribers("a"); got != 1 {
+		t.Errorf("Subscribers after one Close = %d; want 1", got)
+	}
+	hub.Publish("a", 1)
+	select {
+	case v := <-s1.Updates():
+		t.Errorf("closed subscription got %d", v)
+	default:
+	}
+
+	s2.Close()
+	if got := hub.Subscribers("a"); got != 0 {
+		t.Errorf("Subscribers after closing all = %d; want 0", got)
+	}
+	if len(hub.subs) != 0 {
+		t.Errorf("hub kept %d empty keys", len(hub.subs))
+	}
+}
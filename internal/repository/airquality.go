@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// AirQualityCacheTTL is how long a coordinate's air quality is reused. AirNow
+// publishes observations hourly, so fetching more often finds nothing new.
+const AirQualityCacheTTL = time.Hour
+
+// GetAirQuality retrieves the cached air quality for a normalized coordinate
+// (Redis first, then SQLite)
+func (r *WeatherRepository) GetAirQuality(ctx context.Context, lat, lon float64) (*models.AirQualityCache, error) {
+	if r.rdb != nil {
+		var cache models.AirQualityCache
+		if r.getJSON(ctx, coordinateKey("airquality:", lat, lon), &cache) {
+			return &cache, nil
+		}
+	}
+
+	var payload string
+	cache := models.AirQualityCache{Latitude: lat, Longitude: lon}
+	err := r.db.QueryRowContext(ctx,
+		"SELECT payload, timestamp FROM air_quality_cache WHERE latitude = ? AND longitude = ?",
+		lat, lon,
+	).Scan(&payload, &cache.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(payload), &cache.Observations); err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// SaveAirQuality caches the air quality for a normalized coordinate (Redis and SQLite)
+func (r *WeatherRepository) SaveAirQuality(ctx context.Context, cache *models.AirQualityCache) error {
+	if r.rdb != nil {
+		r.setJSON(ctx, coordinateKey("airquality:", cache.Latitude, cache.Longitude), cache, AirQualityCacheTTL)
+	}
+
+	payload, err := json.Marshal(cache.Observations)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(writeContext(ctx),
+		"INSERT OR REPLACE INTO air_quality_cache (latitude, longitude, payload, timestamp) VALUES (?, ?, ?, ?)",
+		cache.Latitude, cache.Longitude, string(payload), cache.Timestamp.UTC(),
+	)
+	return err
+}
+
+// IsAirQualityFresh checks if cached air quality is within the TTL
+func (r *WeatherRepository) IsAirQualityFresh(cache *models.AirQualityCache) bool {
+	return time.Since(cache.Timestamp) < AirQualityCacheTTL
+}
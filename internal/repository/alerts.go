@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// AlertCacheTTL is how long a zone's active alerts are reused. Alerts are
+// time-critical, so this is much shorter than the forecast TTL.
+const AlertCacheTTL = 3 * time.Minute
+
+// GetAlerts retrieves the cached active alerts for a zone (Redis first, then SQLite)
+func (r *WeatherRepository) GetAlerts(ctx context.Context, zone string) (*models.AlertCache, error) {
+	cache, err := r.getAlerts(ctx, "alerts:zone:"+zone, zone)
+	if err != nil {
+		return nil, err
+	}
+	cache.Zone = zone
+	return cache, nil
+}
+
+// SaveAlerts caches the active alerts for a zone (Redis and SQLite)
+func (r *WeatherRepository) SaveAlerts(ctx context.Context, cache *models.AlertCache) error {
+	return r.saveAlerts(ctx, "alerts:zone:"+cache.Zone, cache.Zone, cache)
+}
+
+// GetPointAlerts retrieves the cached active alerts for a normalized coordinate
+// (Redis first, then SQLite)
+func (r *WeatherRepository) GetPointAlerts(ctx context.Context, lat, lon float64) (*models.AlertCache, error) {
+	return r.getAlerts(ctx, coordinateKey("alerts:point:", lat, lon), pointAlertsKey(lat, lon))
+}
+
+// SavePointAlerts caches the active alerts for a normalized coordinate (Redis and SQLite)
+func (r *WeatherRepository) SavePointAlerts(ctx context.Context, lat, lon float64, cache *models.AlertCache) error {
+	return r.saveAlerts(ctx, coordinateKey("alerts:point:", lat, lon), pointAlertsKey(lat, lon), cache)
+}
+
+// pointAlertsKey is the alert_cache row key for a coordinate's alerts. Zone IDs
+// never contain a colon, so the two kinds of row cannot collide.
+func pointAlertsKey(lat, lon float64) string {
+	return coordinateKey("point:", lat, lon)
+}
+
+// getAlerts reads an alert cache entry by its Redis key and alert_cache row key
+func (r *WeatherRepository) getAlerts(ctx context.Context, redisKey, rowKey string) (*models.AlertCache, error) {
+	if r.rdb != nil {
+		var cache models.AlertCache
+		if r.getJSON(ctx, redisKey, &cache) {
+			return &cache, nil
+		}
+	}
+
+	var payload string
+	var cache models.AlertCache
+	err := r.db.QueryRowContext(ctx,
+		"SELECT payload, timestamp FROM alert_cache WHERE zone = ?",
+		rowKey,
+	).Scan(&payload, &cache.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(payload), &cache.Alerts); err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// saveAlerts writes an alert cache entry under its Redis key and alert_cache row key
+func (r *WeatherRepository) saveAlerts(ctx context.Context, redisKey, rowKey string, cache *models.AlertCache) error {
+	if r.rdb != nil {
+		r.setJSON(ctx, redisKey, cache, alertCacheExpiry(cache))
+	}
+
+	payload, err := json.Marshal(cache.Alerts)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(writeContext(ctx),
+		"INSERT OR REPLACE INTO alert_cache (zone, payload, timestamp) VALUES (?, ?, ?)",
+		rowKey, string(payload), cache.Timestamp.UTC(),
+	)
+	return err
+}
+
+// IsAlertCacheFresh checks if cached alerts are within the TTL and none of them has expired
+func (r *WeatherRepository) IsAlertCacheFresh(cache *models.AlertCache) bool {
+	if time.Since(cache.Timestamp) >= AlertCacheTTL {
+		return false
+	}
+	now := time.Now()
+	for _, alert := range cache.Alerts {
+		if !alert.Expires.IsZero() && !alert.Expires.After(now) {
+			return false
+		}
+	}
+	return true
+}
+
+// alertCacheExpiry returns the Redis expiry for cached alerts: the TTL, or
+// sooner if one of the alerts expires first
+func alertCacheExpiry(cache *models.AlertCache) time.Duration {
+	expiry := AlertCacheTTL
+	for _, alert := range cache.Alerts {
+		if alert.Expires.IsZero() {
+			continue
+		}
+		if until := time.Until(alert.Expires); until < expiry {
+			expiry = until
+		}
+	}
+	if expiry < time.Second {
+		expiry = time.Second
+	}
+	return expiry
+}
+
+// FindSeenAlert looks up the recorded fingerprint for any of the given alert IDs.
+// It returns an empty string when none of them has been seen.
+func (r *WeatherRepository) FindSeenAlert(ctx context.Context, ids []string) (string, error) {
+	if len(ids) == 0 {
+		return "", nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	var fingerprint string
+	err := r.db.QueryRowContext(ctx,
+		"SELECT fingerprint FROM alert_seen WHERE alert_id IN ("+placeholders+") ORDER BY sent DESC LIMIT 1",
+		args...,
+	).Scan(&fingerprint)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return fingerprint, err
+}
+
+// MarkAlertSeen records an alert's fingerprint so later polls can detect updates
+func (r *WeatherRepository) MarkAlertSeen(ctx context.Context, zone string, alert models.Alert, fingerprint string) error {
+	_, err := r.db.ExecContext(writeContext(ctx),
+		"INSERT OR REPLACE INTO alert_seen (alert_id, zone, fingerprint, sent, expires) VALUES (?, ?, ?, ?, ?)",
+		alert.ID, zone, fingerprint, alert.Sent.UTC(), alert.Expires.UTC(),
+	)
+	return err
+}
+
+// PurgeExpiredAlerts removes seen-alert records that expired before the given time
+func (r *WeatherRepository) PurgeExpiredAlerts(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(writeContext(ctx), "DELETE FROM alert_seen WHERE expires < ?", before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
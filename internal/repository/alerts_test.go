@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+func newTestRepository(t *testing.T) *WeatherRepository {
+	t.Helper()
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewWeatherRepository(db, nil)
+}
+
+func TestAlertCacheRoundTrip(t *testing.T) {
+	repo := newTestRepository(t)
+	cache := &models.AlertCache{
+		Zone:      "NYZ072",
+		Alerts:    []models.Alert{{ID: "a1", Event: "Wind Advisory", Expires: time.Now().Add(time.Hour)}},
+		Timestamp: time.Now(),
+	}
+
+	if err := repo.SaveAlerts(context.Background(), cache); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.GetAlerts(context.Background(), "NYZ072")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Alerts) != 1 || got.Alerts[0].ID != "a1" {
+		t.Errorf("got %+v; want alert a1", got.Alerts)
+	}
+	if !repo.IsAlertCacheFresh(got) {
+		t.Error("freshly saved alerts reported stale")
+	}
+}
+
+func TestAlertCacheStaleWhenAlertExpires(t *testing.T) {
+	repo := newTestRepository(t)
+
+	cache := &models.AlertCache{
+		Zone:      "NYZ072",
+		Alerts:    []models.Alert{{ID: "a1", Expires: time.Now().Add(-time.Second)}},
+		Timestamp: time.Now(),
+	}
+	if repo.IsAlertCacheFresh(cache) {
+		t.Error("cache holding an expired alert reported fresh")
+	}
+
+	cache.Alerts = nil
+	cache.Timestamp = time.Now().Add(-AlertCacheTTL)
+	if repo.IsAlertCacheFresh(cache) {
+		t.Error("cache past its TTL reported fresh")
+	}
+}
+
+func TestPurgeExpiredAlerts(t *testing.T) {
+	repo := newTestRepository(t)
+	now := time.Now()
+
+	if err := repo.MarkAlertSeen(context.Background(), "NYZ072", models.Alert{ID: "old", Sent: now.Add(-2 * time.Hour), Expires: now.Add(-time.Hour)}, "f1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.MarkAlertSeen(context.Background(), "NYZ072", models.Alert{ID: "live", Sent: now, Expires: now.Add(time.Hour)}, "f2"); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := repo.PurgeExpiredAlerts(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("purged %d alerts; want 1", removed)
+	}
+
+	if fp, _ := repo.FindSeenAlert(context.Background(), []string{"old"}); fp != "" {
+		t.Error("expired alert still recorded as seen")
+	}
+	if fp, _ := repo.FindSeenAlert(context.Background(), []string{"live"}); fp != "f2" {
+		t.Errorf("live alert fingerprint = %q; want f2", fp)
+	}
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"weather-api-go/internal/models"
+)
+
+// ListAPIKeys returns the client API keys provisioned in the api_keys table
+func (r *WeatherRepository) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, key_hash, label, daily_quota FROM api_keys ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var key models.APIKey
+		if err := rows.Scan(&key.ID, &key.KeyHash, &key.Label, &key.DailyQuota); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// SaveAPIKey provisions a client API key, replacing any key with the same ID
+func (r *WeatherRepository) SaveAPIKey(ctx context.Context, key models.APIKey) error {
+	_, err := r.db.ExecContext(writeContext(ctx), "INSERT OR REPLACE INTO api_keys (id, key_hash, label, daily_quota) VALUES (?, ?, ?, ?)",
+		key.ID, key.KeyHash, key.Label, key.DailyQuota)
+	return err
+}
+
+// TakeAPIKeyQuota counts a request against an API key's quota for day, unless
+// the key has already made quota requests that day; zero quota never refuses.
+// It returns the day's count afterwards and whether the request was counted.
+func (r *WeatherRepository) TakeAPIKeyQuota(ctx context.Context, keyID, day string, quota int) (int, bool, error) {
+	var used int
+	err := r.db.QueryRowContext(writeContext(ctx), `
+		INSERT INTO api_key_usage (key_id, day, requests) VALUES (?, ?, 1)
+		ON CONFLICT (key_id, day) DO UPDATE SET requests = requests + 1 WHERE ? = 0 OR requests < ?
+		RETURNING requests
+	`, keyID, day, quota, quota).Scan(&used)
+	if errors.Is(err, sql.ErrNoRows) {
+		used, err = r.APIKeyUsage(ctx, keyID, day)
+		return used, false, err
+	}
+	return used, err == nil, err
+}
+
+// APIKeyUsage returns how many requests counted against an API key's quota on day
+func (r *WeatherRepository) APIKeyUsage(ctx context.Context, keyID, day string) (int, error) {
+	var used int
+	err := r.db.QueryRowContext(ctx, "SELECT requests FROM api_key_usage WHERE key_id = ? AND day = ?", keyID, day).Scan(&used)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return used, err
+}
+
+// APIKeyUsageHistory returns an API key's request counts for the days from
+// from up to but not including to, oldest first. Days without requests are
+// left out.
+func (r *WeatherRepository) APIKeyUsageHistory(ctx context.Context, keyID, from, to string) ([]models.DailyUsage, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT day, requests FROM api_key_usage WHERE key_id = ? AND day >= ? AND day < ? ORDER BY day", keyID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []models.DailyUsage
+	for rows.Next() {
+		var day models.DailyUsage
+		if err := rows.Scan(&day.Date, &day.Requests); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
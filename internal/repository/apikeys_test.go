@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"weather-api-go/internal/models"
+)
+
+func TestAPIKeysRoundTrip(t *testing.T) {
+	repo := newTestRepository(t)
+	if keys, err := repo.ListAPIKeys(context.Background()); err != nil || len(keys) != 0 {
+		t.Fatalf("ListAPIKeys on a new database = %v, %v; want none", keys, err)
+	}
+
+	for _, key := range []models.APIKey{
+		{ID: "mobile", KeyHash: "aa"},
+		{ID: "dashboard", KeyHash: "bb", Label: "Ops dashboard", DailyQuota: 1000},
+		{ID: "mobile", KeyHash: "cc"}, // rotated
+	} {
+		if err := repo.SaveAPIKey(context.Background(), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys, err := repo.ListAPIKeys(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.APIKey{{ID: "dashboard", KeyHash: "bb", Label: "Ops dashboard", DailyQuota: 1000}, {ID: "mobile", KeyHash: "cc"}}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("ListAPIKeys = %v; want %v", keys, want)
+	}
+}
+
+func TestTakeAPIKeyQuota(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		key, day string
+		quota    int
+		wantUsed int
+		wantOK   bool
+	}{
+		{"first request", "mobile", "2024-01-15", 2, 1, true},
+		{"reaches the quota", "mobile", "2024-01-15", 2, 2, true},
+		{"over the quota", "mobile", "2024-01-15", 2, 2, false},
+		// Refused requests aren't counted, so raising the quota lets the next through
+		{"quota raised", "mobile", "2024-01-15", 3, 3, true},
+		{"unlimited", "mobile", "2024-01-15", 0, 4, true},
+		{"next day", "mobile", "2024-01-16", 2, 1, true},
+		{"another key", "dashboard", "2024-01-15", 2, 1, true},
+	}
+	for _, tt := range tests {
+		used, ok, err := repo.TakeAPIKeyQuota(ctx, tt.key, tt.day, tt.quota)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if used != tt.wantUsed || ok != tt.wantOK {
+			t.Errorf("%s: TakeAPIKeyQuota = %d, %v; want %d, %v", tt.name, used, ok, tt.wantUsed, tt.wantOK)
+		}
+	}
+
+	if used, err := repo.APIKeyUsage(ctx, "mobile", "2024-01-15"); err != nil || used != 4 {
+		t.Errorf("APIKeyUsage = %d, %v; want 4", used, err)
+	}
+	if used, err := repo.APIKeyUsage(ctx, "mobile", "2024-01-14"); err != nil || used != 0 {
+		t.Errorf("APIKeyUsage on a day without requests = %d, %v; want 0", used, err)
+	}
+
+	history, err := repo.APIKeyUsageHistory(ctx, "mobile", "2024-01-10", "2024-01-16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.DailyUsage{{Date: "2024-01-15", Requests: 4}}
+	if !reflect.DeepEqual(history, want) {
+		t.Errorf("APIKeyUsageHistory = %v; want %v", history, want)
+	}
+}
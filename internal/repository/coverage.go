@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// UncoveredPointTTL is how long a coordinate the NWS reported no coverage for
+// is turned away without asking again. It is short enough that an extension
+// of NWS coverage is picked up the same day.
+const UncoveredPointTTL = 6 * time.Hour
+
+// GetUncoveredPoint retrieves the negative entry for a normalized coordinate (Redis first, then SQLite)
+func (r *WeatherRepository) GetUncoveredPoint(ctx context.Context, lat, lon float64) (*models.UncoveredPoint, error) {
+	if r.rdb != nil {
+		var point models.UncoveredPoint
+		if r.getJSON(ctx, coordinateKey("uncovered:", lat, lon), &point) {
+			return &point, nil
+		}
+	}
+
+	point := models.UncoveredPoint{Latitude: lat, Longitude: lon}
+	err := r.db.QueryRowContext(ctx,
+		"SELECT timestamp FROM uncovered_points WHERE latitude = ? AND longitude = ?",
+		lat, lon,
+	).Scan(&point.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &point, nil
+}
+
+// SaveUncoveredPoint records that the NWS has no coverage at a normalized coordinate (Redis and SQLite)
+func (r *WeatherRepository) SaveUncoveredPoint(ctx context.Context, point *models.UncoveredPoint) error {
+	if r.rdb != nil {
+		r.setJSON(ctx, coordinateKey("uncovered:", point.Latitude, point.Longitude), point, UncoveredPointTTL)
+	}
+
+	_, err := r.db.ExecContext(writeContext(ctx),
+		"INSERT OR REPLACE INTO uncovered_points (latitude, longitude, timestamp) VALUES (?, ?, ?)",
+		point.Latitude, point.Longitude, point.Timestamp.UTC(),
+	)
+	return err
+}
+
+// IsUncoveredPointFresh checks if a negative entry is still trusted
+func (r *WeatherRepository) IsUncoveredPointFresh(point *models.UncoveredPoint) bool {
+	return time.Since(point.Timestamp) < UncoveredPointTTL
+}
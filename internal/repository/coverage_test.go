@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"weather-api-go/internal/models"
+)
+
+func TestUncoveredPoint(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	repo := newTestRepository(t)
+	repo.rdb = rdb
+
+	if _, err := repo.GetUncoveredPoint(context.Background(), 51.5074, -0.1278); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("before saving: error = %v; want sql.ErrNoRows", err)
+	}
+
+	saved := &models.UncoveredPoint{Latitude: 51.5074, Longitude: -0.1278, Timestamp: time.Now().UTC().Truncate(time.Second)}
+	if err := repo.SaveUncoveredPoint(context.Background(), saved); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL("uncovered:51.507400:-0.127800"); ttl != UncoveredPointTTL {
+		t.Errorf("Redis TTL = %v; want %v", ttl, UncoveredPointTTL)
+	}
+
+	// Read back from Redis, then from SQLite once Redis has dropped it
+	for _, tier := range []string{SourceRedis, SourceSQLite} {
+		if tier == SourceSQLite {
+			mr.FlushAll()
+		}
+		got, err := repo.GetUncoveredPoint(context.Background(), 51.5074, -0.1278)
+		if err != nil {
+			t.Fatalf("%s: %v", tier, err)
+		}
+		if !got.Timestamp.Equal(saved.Timestamp) || !repo.IsUncoveredPointFresh(got) {
+			t.Errorf("%s: %+v; want the fresh entry saved at %v", tier, got, saved.Timestamp)
+		}
+	}
+
+	// An entry older than the TTL is no longer trusted
+	saved.Timestamp = time.Now().Add(-UncoveredPointTTL - time.Minute)
+	if err := repo.SaveUncoveredPoint(context.Background(), saved); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.GetUncoveredPoint(context.Background(), 51.5074, -0.1278)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.IsUncoveredPointFresh(got) {
+		t.Errorf("entry saved at %v is fresh; want expired", got.Timestamp)
+	}
+}
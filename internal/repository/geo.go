@@ -0,0 +1,40 @@
+package repository
+
+import "math"
+
+// earthRadiusKm is the mean radius of the Earth
+const earthRadiusKm = 6371.0
+
+// kmPerDegreeLatitude is the length of one degree of latitude
+const kmPerDegreeLatitude = earthRadiusKm * math.Pi / 180
+
+// DistanceKm returns the great-circle distance between two coordinates
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(math.Min(a, 1)))
+}
+
+// boundingBox returns latitude and longitude ranges holding every coordinate
+// within radiusKm of lat, lon, for prefiltering a proximity search through the
+// (latitude, longitude) index. Boxes reaching a pole or crossing the
+// antimeridian span every longitude rather than wrapping.
+func boundingBox(lat, lon, radiusKm float64) (minLat, maxLat, minLon, maxLon float64) {
+	dLat := radiusKm / kmPerDegreeLatitude
+	minLat, maxLat = math.Max(lat-dLat, -90), math.Min(lat+dLat, 90)
+	if minLat == -90 || maxLat == 90 {
+		return minLat, maxLat, -180, 180
+	}
+
+	// A degree of longitude is shortest at the box's edge farthest from the equator
+	widest := math.Max(math.Abs(minLat), math.Abs(maxLat))
+	dLon := radiusKm / (kmPerDegreeLatitude * math.Cos(widest*math.Pi/180))
+	minLon, maxLon = lon-dLon, lon+dLon
+	if minLon < -180 || maxLon > 180 {
+		return minLat, maxLat, -180, 180
+	}
+	return minLat, maxLat, minLon, maxLon
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceKm(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{"same point", 40.713, -74.006, 40.713, -74.006, 0},
+		{"one degree of latitude", 0, 0, 1, 0, 111.195},
+		{"one degree of longitude at 60N", 60, 0, 60, 1, 55.597},
+		{"New York to Los Angeles", 40.713, -74.006, 34.052, -118.244, 3935.78},
+		{"across the antimeridian", 0, 179.5, 0, -179.5, 111.195},
+		{"antipodes", 0, 0, 0, 180, math.Pi * earthRadiusKm},
+	}
+	for _, tt := range tests {
+		got := DistanceKm(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+		if math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("%s: DistanceKm = %.3f; want %.3f", tt.name, got, tt.want)
+		}
+		if back := DistanceKm(tt.lat2, tt.lon2, tt.lat1, tt.lon1); math.Abs(back-got) > 1e-9 {
+			t.Errorf("%s: DistanceKm isn't symmetric: %.3f and %.3f", tt.name, got, back)
+		}
+	}
+}
+
+func TestBoundingBox(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lon float64
+		radiusKm float64
+	}{
+		{"equator", 0, 0, 2.5},
+		{"New York", 40.713, -74.006, 2.5},
+		{"high latitude", 78.2, 15.6, 10},
+		{"near the antimeridian", -17.7, 179.99, 5},
+		{"near the pole", 89.99, 0, 5},
+	}
+	for _, tt := range tests {
+		minLat, maxLat, minLon, maxLon := boundingBox(tt.lat, tt.lon, tt.radiusKm)
+		// Every point on the circle at the radius must fall inside the box,
+		// give or take rounding
+		const eps = 1e-9
+		for deg := 0; deg < 360; deg++ {
+			lat, lon := destination(tt.lat, tt.lon, float64(deg), tt.radiusKm)
+			if lat < minLat-eps || lat > maxLat+eps || lon < minLon-eps || lon > maxLon+eps {
+				t.Errorf("%s: %.5f,%.5f at bearing %d is outside box %.5f..%.5f, %.5f..%.5f",
+					tt.name, lat, lon, deg, minLat, maxLat, minLon, maxLon)
+				break
+			}
+		}
+	}
+
+	// Boxes are tight away from the poles and the antimeridian
+	minLat, maxLat, minLon, maxLon := boundingBox(40.713, -74.006, 2.5)
+	if maxLat-minLat > 0.05 || maxLon-minLon > 0.07 {
+		t.Errorf("boundingBox(New York, 2.5 km) = %v..%v, %v..%v; want about 0.045 by 0.059 degrees", minLat, maxLat, minLon, maxLon)
+	}
+}
+
+// destination returns the coordinate km from lat, lon along a bearing in
+// degrees, with longitude in -180..180
+func destination(lat, lon, bearing, km float64) (float64, float64) {
+	rad := math.Pi / 180
+	d := km / earthRadiusKm
+	lat1, lon1, b := lat*rad, lon*rad, bearing*rad
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(b))
+	lon2 := lon1 + math.Atan2(math.Sin(b)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	return lat2 / rad, math.Remainder(lon2/rad, 360)
+}
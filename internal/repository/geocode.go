@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// GeocodeCacheTTL is how long a location query's places are reused. Place
+// coordinates practically never change, and the public geocoders ask clients
+// to cache their results.
+const GeocodeCacheTTL = 30 * 24 * time.Hour
+
+// GetGeocode retrieves the cached places for a normalized location query (Redis first, then SQLite)
+func (r *WeatherRepository) GetGeocode(ctx context.Context, query string) (*models.GeocodeCache, error) {
+	if r.rdb != nil {
+		var cache models.GeocodeCache
+		if r.getJSON(ctx, "geocode:"+query, &cache) {
+			return &cache, nil
+		}
+	}
+
+	var payload string
+	cache := models.GeocodeCache{Query: query}
+	err := r.db.QueryRowContext(ctx,
+		"SELECT places, timestamp FROM geocode_cache WHERE query = ?",
+		query,
+	).Scan(&payload, &cache.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(payload), &cache.Places); err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// SaveGeocode caches the places for a normalized location query (Redis and SQLite)
+func (r *WeatherRepository) SaveGeocode(ctx context.Context, cache *models.GeocodeCache) error {
+	if r.rdb != nil {
+		r.setJSON(ctx, "geocode:"+cache.Query, cache, GeocodeCacheTTL)
+	}
+
+	payload, err := json.Marshal(cache.Places)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(writeContext(ctx),
+		"INSERT OR REPLACE INTO geocode_cache (query, places, timestamp) VALUES (?, ?, ?)",
+		cache.Query, string(payload), cache.Timestamp.UTC(),
+	)
+	return err
+}
+
+// IsGeocodeFresh checks if cached places are still usable
+func (r *WeatherRepository) IsGeocodeFresh(cache *models.GeocodeCache) bool {
+	return time.Since(cache.Timestamp) < GeocodeCacheTTL
+}
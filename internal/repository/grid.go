@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/tracing"
+)
+
+// GridPointTTL is how long a coordinate-to-grid-cell mapping is reused. Like
+// zones, grid assignments only change when the NWS reworks its forecast grids.
+const GridPointTTL = PointMetadataTTL
+
+// GetGridPoint retrieves the cached grid cell for a normalized coordinate (Redis first, then SQLite)
+func (r *WeatherRepository) GetGridPoint(ctx context.Context, lat, lon float64) (*models.GridPoint, error) {
+	if r.rdb != nil {
+		var point models.GridPoint
+		// Entries cached before hourly URLs, locations, time zones, or zones
+		// were recorded have none; SQLite tells those apart from points the
+		// NWS publishes none for
+		if r.getJSON(ctx, coordinateKey("grid:point:", lat, lon), &point) && point.ForecastHourlyURL != "" && point.City != "" && point.TimeZone != "" &&
+			point.ForecastZone != "" {
+			return &point, nil
+		}
+	}
+
+	point := models.GridPoint{Latitude: lat, Longitude: lon}
+	var hourlyURL, city, state, timeZone, zone, county, radar sql.NullString
+	span := r.startSpan(ctx, "sqlite.query", append(tracing.Coordinate(lat, lon), tracing.CacheTierKey.String(SourceSQLite))...)
+	err := r.db.QueryRowContext(ctx,
+		"SELECT grid_id, grid_x, grid_y, forecast_url, forecast_hourly_url, city, state, time_zone, forecast_zone, county, radar_station, timestamp FROM grid_points WHERE latitude = ? AND longitude = ?",
+		lat, lon,
+	).Scan(&point.GridID, &point.GridX, &point.GridY, &point.ForecastURL, &hourlyURL, &city, &state, &timeZone, &zone, &county, &radar, &point.Timestamp)
+	endLookup(span, err)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows saved before the location, time zone, or zones were recorded are
+	// reported expired, so the next lookup refetches the points document;
+	// they still serve as a fallback
+	point.City, point.State, point.TimeZone = city.String, state.String, timeZone.String
+	point.ForecastZone, point.County, point.RadarStation = zone.String, county.String, radar.String
+	if !city.Valid || !timeZone.Valid || !zone.Valid {
+		point.Timestamp = time.Time{}
+	}
+
+	// Rows saved before the column existed are NULL; the NWS publishes the
+	// hourly forecast under the forecast URL's /hourly suffix
+	point.ForecastHourlyURL = hourlyURL.String
+	if !hourlyURL.Valid {
+		point.ForecastHourlyURL = point.ForecastURL + "/hourly"
+	}
+	return &point, nil
+}
+
+// SaveGridPoint caches the grid cell for a normalized coordinate (Redis and SQLite)
+func (r *WeatherRepository) SaveGridPoint(ctx context.Context, point *models.GridPoint) (err error) {
+	span := r.startSpan(ctx, "cache.save", tracing.Coordinate(point.Latitude, point.Longitude)...)
+	defer func() { tracing.End(span, err) }()
+
+	if r.rdb != nil {
+		r.setJSON(ctx, coordinateKey("grid:point:", point.Latitude, point.Longitude), point, GridPointTTL)
+	}
+
+	_, err = r.db.ExecContext(writeContext(ctx),
+		"INSERT OR REPLACE INTO grid_points (latitude, longitude, grid_id, grid_x, grid_y, forecast_url, forecast_hourly_url, city, state, time_zone, forecast_zone, county, radar_station, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		point.Latitude, point.Longitude, point.GridID, point.GridX, point.GridY, point.ForecastURL, point.ForecastHourlyURL, point.City, point.State, point.TimeZone,
+		point.ForecastZone, point.County, point.RadarStation, point.Timestamp.UTC(),
+	)
+	return err
+}
+
+// DeleteGridPoint drops the cached grid cell for a normalized coordinate, so the
+// next lookup resolves it again
+func (r *WeatherRepository) DeleteGridPoint(ctx context.Context, lat, lon float64) error {
+	if r.rdb != nil {
+		r.rdb.Del(ctx, coordinateKey("grid:point:", lat, lon))
+	}
+
+	_, err := r.db.ExecContext(writeContext(ctx), "DELETE FROM grid_points WHERE latitude = ? AND longitude = ?", lat, lon)
+	return err
+}
+
+// IsGridPointFresh checks if a cached grid mapping is still usable
+func (r *WeatherRepository) IsGridPointFresh(point *models.GridPoint) bool {
+	return time.Since(point.Timestamp) < GridPointTTL
+}
+
+// GetGridForecast retrieves the cached forecast for an NWS grid cell (Redis first, then SQLite).
+// The returned entry carries no coordinates.
+func (r *WeatherRepository) GetGridForecast(ctx context.Context, gridID string, gridX, gridY int) (*models.WeatherCache, error) {
+	if r.rdb != nil {
+		var cache models.WeatherCache
+		if r.getJSON(ctx, gridKey("weather:grid:", gridID, gridX, gridY), &cache) {
+			cache.Source = SourceRedis
+			return &cache, nil
+		}
+	}
+
+	cache := models.WeatherCache{Source: SourceSQLite}
+	var detailed sql.NullString
+	var period periodFields
+	span := r.startSpan(ctx, "sqlite.query", append(gridAttributes(gridID, gridX, gridY), tracing.CacheTierKey.String(SourceSQLite))...)
+	err := r.db.QueryRowContext(ctx,
+		"SELECT forecast, temp_c, temp_f, timestamp, detailed_forecast, "+periodColumns+" FROM grid_forecast_cache WHERE grid_id = ? AND grid_x = ? AND grid_y = ?",
+		gridID, gridX, gridY,
+	).Scan(append([]interface{}{&cache.Forecast, &cache.TempC, &cache.TempF, &cache.Timestamp, &detailed}, period.dest()...)...)
+	endLookup(span, err)
+	if err != nil {
+		return nil, err
+	}
+	cache.DetailedForecast = detailed.String
+	period.apply(&cache)
+
+	return &cache, nil
+}
+
+// SaveGridForecast caches the forecast for an NWS grid cell (Redis and SQLite)
+func (r *WeatherRepository) SaveGridForecast(ctx context.Context, gridID string, gridX, gridY int, weather *models.WeatherCache) (err error) {
+	span := r.startSpan(ctx, "cache.save", gridAttributes(gridID, gridX, gridY)...)
+	defer func() { tracing.End(span, err) }()
+
+	if r.rdb != nil {
+		r.setJSON(ctx, gridKey("weather:grid:", gridID, gridX, gridY), weather, r.cacheTTL)
+	}
+
+	_, err = r.db.ExecContext(writeContext(ctx),
+		"INSERT OR REPLACE INTO grid_forecast_cache (grid_id, grid_x, grid_y, forecast, temp_c, temp_f, timestamp, detailed_forecast, "+periodColumns+
+			") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		append([]interface{}{gridID, gridX, gridY, weather.Forecast, weather.TempC, weather.TempF, weather.Timestamp.UTC(), weather.DetailedForecast},
+			periodValues(weather)...)...,
+	)
+	return err
+}
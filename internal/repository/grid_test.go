@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+func TestGridPointHourlyURL(t *testing.T) {
+	repo := newTestRepository(t)
+
+	tests := []struct {
+		name      string
+		lat       float64
+		hourlyURL string
+		legacy    bool
+		want      string
+	}{
+		{"recorded", 40.7128, "https://api.weather.gov/gridpoints/OKX/33,35/forecast/hourly", false,
+			"https://api.weather.gov/gridpoints/OKX/33,35/forecast/hourly"},
+		{"not published", 41.0, "", false, ""},
+		// Rows saved before the column existed derive the URL from the forecast URL
+		{"legacy row", 42.0, "", true, "https://api.weather.gov/gridpoints/OKX/33,35/forecast/hourly"},
+	}
+	for _, tt := range tests {
+		err := repo.SaveGridPoint(context.Background(), &models.GridPoint{
+			Latitude: tt.lat, Longitude: -74.006, GridID: "OKX", GridX: 33, GridY: 35,
+			ForecastURL:       "https://api.weather.gov/gridpoints/OKX/33,35/forecast",
+			ForecastHourlyURL: tt.hourlyURL,
+			Timestamp:         time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.legacy {
+			if _, err := repo.db.Exec("UPDATE grid_points SET forecast_hourly_url = NULL WHERE latitude = ?", tt.lat); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		point, err := repo.GetGridPoint(context.Background(), tt.lat, -74.006)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if point.ForecastHourlyURL != tt.want {
+			t.Errorf("%s: ForecastHourlyURL = %q; want %q", tt.name, point.ForecastHourlyURL, tt.want)
+		}
+	}
+}
+
+func TestGridPointZones(t *testing.T) {
+	repo := newTestRepository(t)
+	err := repo.SaveGridPoint(context.Background(), &models.GridPoint{
+		Latitude: 40.7128, Longitude: -74.006, GridID: "OKX", GridX: 33, GridY: 35,
+		ForecastURL: "https://api.weather.gov/gridpoints/OKX/33,35/forecast",
+		City:        "New York", State: "NY", TimeZone: "America/New_York",
+		ForecastZone: "NYZ072", County: "NYC061", RadarStation: "KOKX",
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	point, err := repo.GetGridPoint(context.Background(), 40.7128, -74.006)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if point.ForecastZone != "NYZ072" || point.County != "NYC061" || point.RadarStation != "KOKX" || !repo.IsGridPointFresh(point) {
+		t.Errorf("point = %+v; want the fresh mapping with its zones and radar", point)
+	}
+
+	// Rows saved before the zones were recorded are refetched, but still
+	// serve as a fallback
+	if _, err := repo.db.Exec("UPDATE grid_points SET forecast_zone = NULL, county = NULL, radar_station = NULL"); err != nil {
+		t.Fatal(err)
+	}
+	point, err = repo.GetGridPoint(context.Background(), 40.7128, -74.006)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.IsGridPointFresh(point) || point.GridID != "OKX" {
+		t.Errorf("legacy point = %+v; want the mapping reported expired", point)
+	}
+}
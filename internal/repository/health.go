@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// PingDatabase runs a trivial query against the cache database, failing if it
+// doesn't answer within timeout, e.g. because another writer holds it locked
+func (r *WeatherRepository) PingDatabase(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var tables int
+	return r.db.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master").Scan(&tables)
+}
+
+// PingForecastStore checks a separately configured forecast store answers
+// within timeout. It reports false when forecasts are kept in the cache database.
+func (r *WeatherRepository) PingForecastStore(ctx context.Context, timeout time.Duration) (bool, error) {
+	if _, ok := r.store.(sqliteStore); ok {
+		return false, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return true, r.store.Ping(ctx)
+}
+
+// PingRedis sends Redis a PING, failing if it doesn't answer within timeout.
+// It reports false when no Redis is configured.
+func (r *WeatherRepository) PingRedis(ctx context.Context, timeout time.Duration) (bool, error) {
+	if r.rdb == nil {
+		return false, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return true, r.rdb.Ping(ctx).Err()
+}
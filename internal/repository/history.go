@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// DefaultHistoryRawRetention is how long individual weather_history rows are kept
+// before being downsampled into weather_daily
+const DefaultHistoryRawRetention = 14 * 24 * time.Hour
+
+// dayAggregate accumulates cached forecasts for one coordinate and UTC day.
+// Aggregates merge exactly, so raw rows can be folded into an existing summary.
+type dayAggregate struct {
+	samples          int
+	minC, maxC, sumC float64
+	minF, maxF, sumF float64
+	forecasts        map[string]int
+}
+
+func newDayAggregate() *dayAggregate {
+	return &dayAggregate{
+		minC: math.Inf(1), maxC: math.Inf(-1),
+		minF: math.Inf(1), maxF: math.Inf(-1),
+		forecasts: map[string]int{},
+	}
+}
+
+func (a *dayAggregate) add(forecast string, tempC, tempF float64) {
+	a.samples++
+	a.minC, a.maxC, a.sumC = math.Min(a.minC, tempC), math.Max(a.maxC, tempC), a.sumC+tempC
+	a.minF, a.maxF, a.sumF = math.Min(a.minF, tempF), math.Max(a.maxF, tempF), a.sumF+tempF
+	a.forecasts[forecast]++
+}
+
+func (a *dayAggregate) merge(b *dayAggregate) {
+	a.samples += b.samples
+	a.minC, a.maxC, a.sumC = math.Min(a.minC, b.minC), math.Max(a.maxC, b.maxC), a.sumC+b.sumC
+	a.minF, a.maxF, a.sumF = math.Min(a.minF, b.minF), math.Max(a.maxF, b.maxF), a.sumF+b.sumF
+	for forecast, n := range b.forecasts {
+		a.forecasts[forecast] += n
+	}
+}
+
+// dominantForecast returns the most frequent forecast, breaking ties alphabetically
+func (a *dayAggregate) dominantForecast() string {
+	var best string
+	for forecast, n := range a.forecasts {
+		if n > a.forecasts[best] || (n == a.forecasts[best] && forecast < best) {
+			best = forecast
+		}
+	}
+	return best
+}
+
+func (a *dayAggregate) summary(day string) models.WeatherDay {
+	n := float64(a.samples)
+	return models.WeatherDay{
+		Day:      day,
+		MinTempC: a.minC, MaxTempC: a.maxC, MeanTempC: a.sumC / n,
+		MinTempF: a.minF, MaxTempF: a.maxF, MeanTempF: a.sumF / n,
+		Forecast: a.dominantForecast(),
+		Samples:  a.samples,
+	}
+}
+
+// dayKey identifies one coordinate's UTC day
+type dayKey struct {
+	lat, lon float64
+	day      string
+}
+
+// rawWeatherRow is a weather_history row read for aggregation
+type rawWeatherRow struct {
+	id           int64
+	key          dayKey
+	forecast     string
+	tempC, tempF float64
+}
+
+// queryRawWeather reads weather_history rows, bucketing each by the UTC day of its
+// timestamp. Days are computed from the parsed instant rather than the stored
+// text, so rows written with any zone offset land in the right day.
+func queryRawWeather(ctx context.Context, q interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}, query string, args ...interface{}) ([]rawWeatherRow, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []rawWeatherRow
+	for rows.Next() {
+		var row rawWeatherRow
+		var forecast sql.NullString
+		var tempC, tempF sql.NullFloat64
+		var ts time.Time
+		if err := rows.Scan(&row.id, &row.key.lat, &row.key.lon, &forecast, &tempC, &tempF, &ts); err != nil {
+			return nil, err
+		}
+		row.key.day = ts.UTC().Format(DayFormat)
+		row.forecast, row.tempC, row.tempF = forecast.String, tempC.Float64, tempF.Float64
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// foldRawWeather buckets the raw rows from UTC days before cutoffDay by
+// coordinate and day, returning the buckets and the ids of the rows folded in
+func foldRawWeather(raw []rawWeatherRow, cutoffDay string) (map[dayKey]*dayAggregate, []int64) {
+	buckets := map[dayKey]*dayAggregate{}
+	var ids []int64
+	for _, row := range raw {
+		if row.key.day >= cutoffDay {
+			continue
+		}
+		agg, ok := buckets[row.key]
+		if !ok {
+			agg = newDayAggregate()
+			buckets[row.key] = agg
+		}
+		agg.add(row.forecast, row.tempC, row.tempF)
+		ids = append(ids, row.id)
+	}
+	return buckets, ids
+}
+
+// storedDayAggregate rebuilds the aggregate of a weather_daily row
+func storedDayAggregate(day string, samples int, minC, maxC, meanC, minF, maxF, meanF float64, forecastCounts string) (*dayAggregate, error) {
+	agg := &dayAggregate{samples: samples, minC: minC, maxC: maxC, minF: minF, maxF: maxF}
+	if err := json.Unmarshal([]byte(forecastCounts), &agg.forecasts); err != nil {
+		return nil, fmt.Errorf("corrupt forecast counts for %s: %w", day, err)
+	}
+	agg.sumC = meanC * float64(samples)
+	agg.sumF = meanF * float64(samples)
+	return agg, nil
+}
+
+// weatherSeries merges a coordinate's stored daily aggregates with its raw rows
+// into summaries of the UTC days from..to inclusive, oldest first
+func weatherSeries(daily map[string]*dayAggregate, raw []rawWeatherRow, from, to string) []models.WeatherDay {
+	for _, row := range raw {
+		if row.key.day < from || row.key.day > to {
+			continue
+		}
+		agg, ok := daily[row.key.day]
+		if !ok {
+			agg = newDayAggregate()
+			daily[row.key.day] = agg
+		}
+		agg.add(row.forecast, row.tempC, row.tempF)
+	}
+
+	history := make([]models.WeatherDay, 0, len(daily))
+	for day, agg := range daily {
+		history = append(history, agg.summary(day))
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].Day < history[j].Day })
+	return history
+}
+
+// DownsampleWeatherHistory folds weather_history rows from UTC days that ended
+// before the given time into per-coordinate daily summaries in weather_daily,
+// then deletes them. Only whole days are downsampled, and the merge and delete
+// share a transaction, so a re-run never counts a row twice. Returns the number
+// of raw rows downsampled.
+func (r *WeatherRepository) DownsampleWeatherHistory(ctx context.Context, before time.Time) (int64, error) {
+	return r.store.DownsampleHistory(writeContext(ctx), before)
+}
+
+// GetWeatherHistory returns daily summaries for a coordinate over the UTC days
+// from..to inclusive, oldest first. Downsampled days come from weather_daily and
+// recent days are summarized from weather_history on the fly, so the series is
+// continuous across the retention boundary.
+func (r *WeatherRepository) GetWeatherHistory(ctx context.Context, lat, lon float64, from, to string) ([]models.WeatherDay, error) {
+	return r.store.History(ctx, NormalizeCoordinate(lat), NormalizeCoordinate(lon), from, to)
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// seedHistory caches three forecasts a day at 02:00, 10:00 and 18:00 UTC for
+// each of the given days
+func seedHistory(t *testing.T, repo *WeatherRepository, lat, lon float64, first time.Time, days int) {
+	t.Helper()
+	for d := 0; d < days; d++ {
+		for i, hour := range []int{2, 10, 18} {
+			forecast := "Sunny"
+			if i == 2 {
+				forecast = "Cloudy"
+			}
+			tempC := float64(d + i*5)
+			err := repo.SaveToCache(context.Background(), &models.WeatherCache{
+				Latitude: lat, Longitude: lon, Forecast: forecast,
+				TempC: tempC, TempF: tempC*9/5 + 32,
+				Timestamp: first.AddDate(0, 0, d).Add(time.Duration(hour) * time.Hour),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestHistorySpansDownsampleBoundary(t *testing.T) {
+	repo := newTestRepository(t)
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	seedHistory(t, repo, 40.7128, -74.006, first, 10)
+	seedHistory(t, repo, 34.0522, -118.2437, first, 10)
+
+	before, err := repo.GetWeatherHistory(context.Background(), 40.7128, -74.006, "2024-01-01", "2024-01-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(before) != 10 {
+		t.Fatalf("got %d days before downsampling; want 10", len(before))
+	}
+
+	// A cutoff partway through Jan 6 downsamples Jan 1-5 only; re-running is a no-op
+	cutoff := time.Date(2024, 1, 6, 12, 0, 0, 0, time.UTC)
+	n, err := repo.DownsampleWeatherHistory(context.Background(), cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2*5*3 {
+		t.Errorf("downsampled %d rows; want %d", n, 2*5*3)
+	}
+	if n, err := repo.DownsampleWeatherHistory(context.Background(), cutoff); err != nil || n != 0 {
+		t.Errorf("re-run downsampled %d rows (err %v); want 0", n, err)
+	}
+
+	after, err := repo.GetWeatherHistory(context.Background(), 40.7128, -74.006, "2024-01-01", "2024-01-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(after, before) {
+		t.Errorf("history changed across downsampling\n got: %+v\nwant: %+v", after, before)
+	}
+
+	day := after[0]
+	want := models.WeatherDay{
+		Day: "2024-01-01", MinTempC: 0, MaxTempC: 10, MeanTempC: 5,
+		MinTempF: 32, MaxTempF: 50, MeanTempF: 41, Forecast: "Sunny", Samples: 3,
+	}
+	if day != want {
+		t.Errorf("first day = %+v; want %+v", day, want)
+	}
+
+	var remaining int
+	repo.db.QueryRow("SELECT COUNT(*) FROM weather_history").Scan(&remaining)
+	if remaining != 2*5*3 {
+		t.Errorf("%d raw rows remain; want %d", remaining, 2*5*3)
+	}
+}
+
+func TestDownsampleMergesLateRows(t *testing.T) {
+	repo := newTestRepository(t)
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	seedHistory(t, repo, 40.7128, -74.006, first, 1)
+	cutoff := first.AddDate(0, 0, 2)
+	if _, err := repo.DownsampleWeatherHistory(context.Background(), cutoff); err != nil {
+		t.Fatal(err)
+	}
+
+	// A forecast for an already-summarized day is folded in, not lost
+	err := repo.SaveToCache(context.Background(), &models.WeatherCache{
+		Latitude: 40.7128, Longitude: -74.006, Forecast: "Cloudy", TempC: 20, TempF: 68,
+		Timestamp: first.Add(23 * time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.DownsampleWeatherHistory(context.Background(), cutoff); err != nil {
+		t.Fatal(err)
+	}
+
+	days, err := repo.GetWeatherHistory(context.Background(), 40.7128, -74.006, "2024-01-01", "2024-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 1 || days[0].Samples != 4 || days[0].MaxTempC != 20 || days[0].MeanTempC != 8.75 || days[0].Forecast != "Cloudy" {
+		t.Errorf("merged day = %+v; want 4 samples, max 20, mean 8.75, Cloudy", days)
+	}
+}
+
+func TestHistoryBucketsByUTCDay(t *testing.T) {
+	repo := newTestRepository(t)
+	// 23:30 on Jan 1 in New York is 04:30 on Jan 2 UTC
+	ny := time.FixedZone("EST", -5*3600)
+	_, err := repo.db.Exec(
+		"INSERT INTO weather_history (latitude, longitude, forecast, temp_c, temp_f, timestamp) VALUES (?, ?, ?, ?, ?, ?)",
+		NormalizeCoordinate(40.7128), NormalizeCoordinate(-74.006), "Clear", 1, 33.8, time.Date(2024, 1, 1, 23, 30, 0, 0, ny),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	days, err := repo.GetWeatherHistory(context.Background(), 40.7128, -74.006, "2024-01-01", "2024-01-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 1 || days[0].Day != "2024-01-02" {
+		t.Fatalf("raw history = %+v; want one sample on 2024-01-02", days)
+	}
+
+	// The cutoff at Jan 2 must not downsample the row, which belongs to Jan 2 UTC
+	if n, _ := repo.DownsampleWeatherHistory(context.Background(), time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)); n != 0 {
+		t.Errorf("downsampled %d rows before their UTC day ended", n)
+	}
+	if n, _ := repo.DownsampleWeatherHistory(context.Background(), time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)); n != 1 {
+		t.Errorf("downsampled %d rows after their UTC day ended; want 1", n)
+	}
+	days, _ = repo.GetWeatherHistory(context.Background(), 40.7128, -74.006, "2024-01-01", "2024-01-03")
+	if len(days) != 1 || days[0].Day != "2024-01-02" {
+		t.Errorf("summarized history = %+v; want one sample on 2024-01-02", days)
+	}
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"weather-api-go/internal/models"
+)
+
+// redisDeleteBatch is how many keys a pattern delete removes per DEL
+const redisDeleteBatch = 500
+
+// DeleteWeather removes the cached forecast for a normalized coordinate from
+// every tier. Its refreshes stay in weather_history.
+func (r *WeatherRepository) DeleteWeather(ctx context.Context, lat, lon float64) (models.CacheInvalidationResponse, error) {
+	lat, lon = NormalizeCoordinate(lat), NormalizeCoordinate(lon)
+	var removed models.CacheInvalidationResponse
+	if r.rdb != nil {
+		n, err := r.rdb.Del(ctx, coordinateKey("weather:", lat, lon)).Result()
+		if err != nil {
+			return removed, err
+		}
+		removed.RedisKeys = n
+	}
+	if r.memory != nil {
+		r.memory.Delete(coordinateKey("weather:", lat, lon))
+	}
+
+	n, err := r.store.DeleteForecast(writeContext(ctx), lat, lon)
+	r.countStoreRows(&removed, n)
+	return removed, err
+}
+
+// countStoreRows adds rows removed from the forecast store to the tier it is in
+func (r *WeatherRepository) countStoreRows(removed *models.CacheInvalidationResponse, n int64) {
+	if r.store.Name() == SourcePostgres {
+		removed.PostgresRows += n
+	} else {
+		removed.SQLiteRows += n
+	}
+}
+
+// DeleteGridForecast removes everything cached from an NWS grid cell's forecast
+// documents: the parsed forecast, both period series, and the raw document
+func (r *WeatherRepository) DeleteGridForecast(ctx context.Context, gridID string, gridX, gridY int) (models.CacheInvalidationResponse, error) {
+	var removed models.CacheInvalidationResponse
+	if r.rdb != nil {
+		n, err := r.rdb.Del(ctx,
+			gridKey("weather:grid:", gridID, gridX, gridY),
+			fmt.Sprintf("periods:%s:%s:%d:%d", DailyPeriods, gridID, gridX, gridY),
+			fmt.Sprintf("periods:%s:%s:%d:%d", HourlyPeriods, gridID, gridX, gridY),
+			"raw:"+RawForecast+":"+RawForecastKey(gridID, gridX, gridY),
+		).Result()
+		if err != nil {
+			return removed, err
+		}
+		removed.RedisKeys = n
+	}
+
+	for _, q := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{"DELETE FROM grid_forecast_cache WHERE grid_id = ? AND grid_x = ? AND grid_y = ?", []interface{}{gridID, gridX, gridY}},
+		{"DELETE FROM forecast_periods WHERE grid_id = ? AND grid_x = ? AND grid_y = ?", []interface{}{gridID, gridX, gridY}},
+		{"DELETE FROM raw_documents WHERE kind = ? AND key = ?", []interface{}{RawForecast, RawForecastKey(gridID, gridX, gridY)}},
+	} {
+		result, err := r.db.ExecContext(writeContext(ctx), q.query, q.args...)
+		if err != nil {
+			return removed, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return removed, err
+		}
+		removed.SQLiteRows += n
+	}
+	return removed, nil
+}
+
+// DeleteAllWeather removes every cached forecast, for coordinates and grid
+// cells alike, from every tier. Forecast history and grid mappings are kept.
+func (r *WeatherRepository) DeleteAllWeather(ctx context.Context) (models.CacheInvalidationResponse, error) {
+	var removed models.CacheInvalidationResponse
+	if r.rdb != nil {
+		// weather:* covers both coordinate and weather:grid: entries
+		for _, pattern := range []string{"weather:*", "periods:*", "raw:" + RawForecast + ":*"} {
+			n, err := r.deleteMatching(ctx, pattern)
+			removed.RedisKeys += n
+			if err != nil {
+				return removed, err
+			}
+		}
+	}
+	if r.memory != nil {
+		r.memory.Purge()
+	}
+	n, err := r.store.DeleteAllForecasts(writeContext(ctx))
+	r.countStoreRows(&removed, n)
+	if err != nil {
+		return removed, err
+	}
+
+	for _, query := range []string{
+		"DELETE FROM grid_forecast_cache",
+		"DELETE FROM forecast_periods",
+		"DELETE FROM raw_documents WHERE kind = '" + RawForecast + "'",
+	} {
+		result, err := r.db.ExecContext(writeContext(ctx), query)
+		if err != nil {
+			return removed, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return removed, err
+		}
+		removed.SQLiteRows += n
+	}
+	return removed, nil
+}
+
+// deleteMatching deletes the Redis keys matching a glob pattern, returning how many were removed
+func (r *WeatherRepository) deleteMatching(ctx context.Context, pattern string) (int64, error) {
+	var deleted int64
+	var batch []string
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := r.rdb.Del(ctx, batch...).Result()
+		deleted += n
+		batch = batch[:0]
+		return err
+	}
+
+	iter := r.rdb.Scan(ctx, 0, pattern, redisDeleteBatch).Iterator()
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == redisDeleteBatch {
+			if err := flush(); err != nil {
+				return deleted, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+	return deleted, flush()
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"weather-api-go/internal/models"
+)
+
+// seedInvalidation caches two coordinates' forecasts and one grid cell's
+// forecast, periods, and raw document in both tiers
+func seedInvalidation(t *testing.T) (*WeatherRepository, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	repo := newTestRepository(t)
+	repo.rdb = rdb
+
+	now := time.Now()
+	for _, c := range [][2]float64{{40.7128, -74.006}, {34.0522, -118.2437}} {
+		err := repo.SaveToCache(context.Background(), &models.WeatherCache{Latitude: c[0], Longitude: c[1], Forecast: "Sunny", Timestamp: now})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.SaveGridForecast(context.Background(), "OKX", 33, 35, &models.WeatherCache{Forecast: "Sunny", Timestamp: now}); err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []string{DailyPeriods, HourlyPeriods} {
+		if err := repo.SaveForecastPeriods(context.Background(), kind, "OKX", 33, 35, &models.ForecastPeriodsCache{Timestamp: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := repo.SaveRawDocument(context.Background(), RawForecast, RawForecastKey("OKX", 33, 35), &models.RawDocument{ContentType: "application/geo+json", Body: []byte("{}"), Timestamp: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo, mr
+}
+
+func TestDeleteWeather(t *testing.T) {
+	repo, mr := seedInvalidation(t)
+
+	// Full-precision input finds the normalized entry
+	removed, err := repo.DeleteWeather(context.Background(), 40.71281, -74.00603)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (models.CacheInvalidationResponse{RedisKeys: 1, SQLiteRows: 1}); removed != want {
+		t.Errorf("DeleteWeather removed %+v; want %+v", removed, want)
+	}
+	if _, err := repo.GetFromCache(context.Background(), 40.7128, -74.006); err == nil {
+		t.Error("deleted coordinate is still cached")
+	}
+	if _, err := repo.GetFromCache(context.Background(), 34.0522, -118.2437); err != nil {
+		t.Errorf("other coordinate was deleted: %v", err)
+	}
+
+	if again, err := repo.DeleteWeather(context.Background(), 40.7128, -74.006); err != nil || again != (models.CacheInvalidationResponse{}) {
+		t.Errorf("second DeleteWeather = %+v, %v; want nothing removed", again, err)
+	}
+
+	removed, err = repo.DeleteGridForecast(context.Background(), "OKX", 33, 35)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (models.CacheInvalidationResponse{RedisKeys: 4, SQLiteRows: 4}); removed != want {
+		t.Errorf("DeleteGridForecast removed %+v; want %+v", removed, want)
+	}
+	if keys := mr.Keys(); len(keys) != 1 {
+		t.Errorf("Redis keys left = %v; want only the other coordinate", keys)
+	}
+}
+
+func TestDeleteAllWeather(t *testing.T) {
+	repo, mr := seedInvalidation(t)
+	if err := repo.SaveGridPoint(context.Background(), &models.GridPoint{Latitude: 40.7128, Longitude: -74.006, GridID: "OKX", GridX: 33, GridY: 35, ForecastURL: "https://example.test/forecast", Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := repo.DeleteAllWeather(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (models.CacheInvalidationResponse{RedisKeys: 6, SQLiteRows: 6}); removed != want {
+		t.Errorf("DeleteAllWeather removed %+v; want %+v", removed, want)
+	}
+
+	// Grid mappings and history survive
+	if keys := mr.Keys(); len(keys) != 1 || keys[0] != "grid:point:40.712800:-74.006000" {
+		t.Errorf("Redis keys left = %v; want only the grid mapping", keys)
+	}
+	var history int
+	repo.db.QueryRow("SELECT COUNT(*) FROM weather_history").Scan(&history)
+	if history != 2 {
+		t.Errorf("weather_history has %d rows; want both refreshes kept", history)
+	}
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// Forecast period series kept per grid cell
+const (
+	DailyPeriods  = "daily"
+	HourlyPeriods = "hourly"
+)
+
+// HourlyPeriodsTTL is the longest an hourly series is reused. The NWS revises
+// hourly forecasts more often than the day/night periods, which live as long
+// as the repository's weather (CacheTTL).
+const HourlyPeriodsTTL = 30 * time.Minute
+
+// ForecastPeriodsTTL returns how long a series of the given kind is reused:
+// CacheTTL for day/night periods, and the shorter of CacheTTL and
+// HourlyPeriodsTTL for hourly ones
+func (r *WeatherRepository) ForecastPeriodsTTL(kind string) time.Duration {
+	if kind == HourlyPeriods {
+		return min(HourlyPeriodsTTL, r.cacheTTL)
+	}
+	return r.cacheTTL
+}
+
+// GetForecastPeriods retrieves the cached forecast periods of a kind for an NWS
+// grid cell (Redis first, then SQLite)
+func (r *WeatherRepository) GetForecastPeriods(ctx context.Context, kind, gridID string, gridX, gridY int) (*models.ForecastPeriodsCache, error) {
+	key := fmt.Sprintf("periods:%s:%s:%d:%d", kind, gridID, gridX, gridY)
+	if r.rdb != nil {
+		var cache models.ForecastPeriodsCache
+		if r.getJSON(ctx, key, &cache) {
+			cache.Source = SourceRedis
+			return &cache, nil
+		}
+	}
+
+	var payload string
+	cache := models.ForecastPeriodsCache{Source: SourceSQLite}
+	err := r.db.QueryRowContext(ctx,
+		"SELECT payload, timestamp FROM forecast_periods WHERE kind = ? AND grid_id = ? AND grid_x = ? AND grid_y = ?",
+		kind, gridID, gridX, gridY,
+	).Scan(&payload, &cache.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(payload), &cache.Periods); err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// SaveForecastPeriods caches the forecast periods of a kind for an NWS grid cell (Redis and SQLite)
+func (r *WeatherRepository) SaveForecastPeriods(ctx context.Context, kind, gridID string, gridX, gridY int, cache *models.ForecastPeriodsCache) error {
+	if r.rdb != nil {
+		r.setJSON(ctx, fmt.Sprintf("periods:%s:%s:%d:%d", kind, gridID, gridX, gridY), cache, r.ForecastPeriodsTTL(kind))
+	}
+
+	payload, err := json.Marshal(cache.Periods)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(writeContext(ctx),
+		"INSERT OR REPLACE INTO forecast_periods (kind, grid_id, grid_x, grid_y, payload, timestamp) VALUES (?, ?, ?, ?, ?, ?)",
+		kind, gridID, gridX, gridY, string(payload), cache.Timestamp.UTC(),
+	)
+	return err
+}
+
+// IsForecastPeriodsFresh checks if cached forecast periods of a kind are still fresh
+func (r *WeatherRepository) IsForecastPeriodsFresh(kind string, cache *models.ForecastPeriodsCache) bool {
+	return time.Since(cache.Timestamp) < r.ForecastPeriodsTTL(kind)
+}
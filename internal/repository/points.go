@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// PointMetadataTTL is how long resolved NWS point metadata is reused. The
+// coordinate-to-zone mapping changes only when the NWS redraws its zones.
+const PointMetadataTTL = 30 * 24 * time.Hour
+
+// GetPointMetadata retrieves cached NWS point metadata (Redis first, then SQLite)
+func (r *WeatherRepository) GetPointMetadata(ctx context.Context, lat, lon float64) (*models.PointMetadata, error) {
+	if r.rdb != nil {
+		var meta models.PointMetadata
+		if r.getJSON(ctx, coordinateKey("point:", lat, lon), &meta) {
+			return &meta, nil
+		}
+	}
+
+	meta := models.PointMetadata{Latitude: lat, Longitude: lon}
+	err := r.db.QueryRowContext(ctx,
+		"SELECT forecast_zone, timestamp FROM point_metadata WHERE latitude = ? AND longitude = ?",
+		lat, lon,
+	).Scan(&meta.ForecastZone, &meta.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &meta, nil
+}
+
+// SavePointMetadata caches NWS point metadata (Redis and SQLite)
+func (r *WeatherRepository) SavePointMetadata(ctx context.Context, meta *models.PointMetadata) error {
+	if r.rdb != nil {
+		r.setJSON(ctx, coordinateKey("point:", meta.Latitude, meta.Longitude), meta, PointMetadataTTL)
+	}
+
+	_, err := r.db.ExecContext(writeContext(ctx),
+		"INSERT OR REPLACE INTO point_metadata (latitude, longitude, forecast_zone, timestamp) VALUES (?, ?, ?, ?)",
+		meta.Latitude, meta.Longitude, meta.ForecastZone, meta.Timestamp.UTC(),
+	)
+	return err
+}
+
+// IsPointMetadataFresh checks if cached point metadata is still usable
+func (r *WeatherRepository) IsPointMetadataFresh(meta *models.PointMetadata) bool {
+	return time.Since(meta.Timestamp) < PointMetadataTTL
+}
@@ -0,0 +1,471 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"weather-api-go/internal/models"
+)
+
+// Advisory lock keys serializing work that replicas sharing a Postgres store
+// would otherwise race on
+const (
+	postgresMigrationLock  int64 = 0x5745415448455201
+	postgresDownsampleLock int64 = 0x5745415448455202
+)
+
+// postgresMigrations brings a Postgres forecast store's schema up to date,
+// one version per entry. Applied versions are recorded in schema_migrations,
+// so a change to the schema is a new entry, never an edit to an applied one.
+var postgresMigrations = []string{
+	`CREATE TABLE weather_cache (
+		latitude DOUBLE PRECISION NOT NULL,
+		longitude DOUBLE PRECISION NOT NULL,
+		forecast TEXT NOT NULL,
+		temp_c DOUBLE PRECISION NOT NULL,
+		temp_f DOUBLE PRECISION NOT NULL,
+		timestamp TIMESTAMPTZ NOT NULL,
+		city TEXT NOT NULL DEFAULT '',
+		state TEXT NOT NULL DEFAULT '',
+		provider TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (latitude, longitude)
+	);
+	CREATE INDEX idx_weather_cache_timestamp ON weather_cache (timestamp);
+
+	CREATE TABLE weather_history (
+		id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+		latitude DOUBLE PRECISION NOT NULL,
+		longitude DOUBLE PRECISION NOT NULL,
+		forecast TEXT NOT NULL,
+		temp_c DOUBLE PRECISION NOT NULL,
+		temp_f DOUBLE PRECISION NOT NULL,
+		timestamp TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX idx_weather_history_coordinate ON weather_history (latitude, longitude, timestamp);
+	CREATE INDEX idx_weather_history_timestamp ON weather_history (timestamp);
+
+	CREATE TABLE weather_daily (
+		latitude DOUBLE PRECISION NOT NULL,
+		longitude DOUBLE PRECISION NOT NULL,
+		day TEXT NOT NULL,
+		samples INTEGER NOT NULL,
+		min_temp_c DOUBLE PRECISION NOT NULL,
+		max_temp_c DOUBLE PRECISION NOT NULL,
+		mean_temp_c DOUBLE PRECISION NOT NULL,
+		min_temp_f DOUBLE PRECISION NOT NULL,
+		max_temp_f DOUBLE PRECISION NOT NULL,
+		mean_temp_f DOUBLE PRECISION NOT NULL,
+		forecast TEXT NOT NULL,
+		forecast_counts TEXT NOT NULL,
+		PRIMARY KEY (latitude, longitude, day)
+	)`,
+	`ALTER TABLE weather_cache ADD COLUMN detailed_forecast TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE weather_cache
+		ADD COLUMN wind_min_kmh DOUBLE PRECISION,
+		ADD COLUMN wind_max_kmh DOUBLE PRECISION,
+		ADD COLUMN wind_min_mph DOUBLE PRECISION,
+		ADD COLUMN wind_max_mph DOUBLE PRECISION,
+		ADD COLUMN wind_direction TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE weather_cache ADD COLUMN precipitation_probability DOUBLE PRECISION`,
+	`ALTER TABLE weather_cache
+		ADD COLUMN relative_humidity DOUBLE PRECISION,
+		ADD COLUMN dewpoint_c DOUBLE PRECISION,
+		ADD COLUMN dewpoint_f DOUBLE PRECISION`,
+	`ALTER TABLE weather_cache
+		ADD COLUMN period_name TEXT NOT NULL DEFAULT '',
+		ADD COLUMN is_daytime BOOLEAN`,
+	`ALTER TABLE weather_cache ADD COLUMN icon TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE weather_cache ADD COLUMN time_zone TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE weather_cache ADD COLUMN uv_index DOUBLE PRECISION`,
+	`ALTER TABLE weather_cache
+		ADD COLUMN temperature_trend TEXT NOT NULL DEFAULT '',
+		ADD COLUMN temperature_change_c DOUBLE PRECISION`,
+	`ALTER TABLE weather_cache
+		ADD COLUMN grid_id TEXT NOT NULL DEFAULT '',
+		ADD COLUMN grid_x INTEGER NOT NULL DEFAULT 0,
+		ADD COLUMN grid_y INTEGER NOT NULL DEFAULT 0`,
+}
+
+// PostgresStore is a ForecastStore in PostgreSQL, so replicas behind a load
+// balancer share one set of cached forecasts and one history
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+// OpenPostgres connects to the Postgres database at url, which may carry pgx
+// pool settings such as pool_max_conns, and brings its schema up to date
+func OpenPostgres(ctx context.Context, url string) (*PostgresStore, error) {
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, err
+	}
+	return openPostgres(ctx, cfg)
+}
+
+// openPostgres connects with a parsed pool configuration and migrates the schema
+func openPostgres(ctx context.Context, cfg *pgxpool.Config) (*PostgresStore, error) {
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	if err := migratePostgres(ctx, pool); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("migrating the Postgres schema: %w", err)
+	}
+	return &PostgresStore{pool: pool}, nil
+}
+
+// migratePostgres applies the migrations the database hasn't seen yet, in one
+// transaction. Replicas starting together take turns, so each migration runs once.
+func migratePostgres(ctx context.Context, pool *pgxpool.Pool) error {
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", postgresMigrationLock); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`)
+		if err != nil {
+			return err
+		}
+		var version int
+		if err := tx.QueryRow(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+			return err
+		}
+		for ; version < len(postgresMigrations); version++ {
+			if _, err := tx.Exec(ctx, postgresMigrations[version]); err != nil {
+				return fmt.Errorf("version %d: %w", version+1, err)
+			}
+			if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close closes the store's connections
+func (s *PostgresStore) Close() {
+	s.pool.Close()
+}
+
+// Name implements ForecastStore
+func (s *PostgresStore) Name() string {
+	return SourcePostgres
+}
+
+// LatestForecast implements ForecastStore
+func (s *PostgresStore) LatestForecast(ctx context.Context, lat, lon float64) (*models.WeatherCache, error) {
+	cache := models.WeatherCache{Source: SourcePostgres, Latitude: lat, Longitude: lon}
+	var period periodFields
+	err := s.pool.QueryRow(ctx,
+		"SELECT forecast, temp_c, temp_f, timestamp, city, state, time_zone, provider, detailed_forecast, uv_index, temperature_change_c, grid_id, grid_x, grid_y, "+periodColumns+" FROM weather_cache WHERE latitude = $1 AND longitude = $2",
+		lat, lon,
+	).Scan(append([]interface{}{&cache.Forecast, &cache.TempC, &cache.TempF, &cache.Timestamp, &cache.City, &cache.State, &cache.TimeZone, &cache.Provider, &cache.DetailedForecast, &cache.UVIndex, &cache.TemperatureChangeC, &cache.GridID, &cache.GridX, &cache.GridY}, period.dest()...)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, sql.ErrNoRows
+	}
+	if err != nil {
+		return nil, err
+	}
+	period.apply(&cache)
+	return &cache, nil
+}
+
+// NearestForecast implements ForecastStore, prefiltering with a bounding box
+// over the (latitude, longitude) primary key
+func (s *PostgresStore) NearestForecast(ctx context.Context, lat, lon, radiusKm float64, freshAfter time.Time) (*models.WeatherCache, error) {
+	minLat, maxLat, minLon, maxLon := boundingBox(lat, lon, radiusKm)
+	rows, err := s.pool.Query(ctx,
+		`SELECT latitude, longitude, forecast, temp_c, temp_f, timestamp, city, state, time_zone, provider, detailed_forecast, uv_index, temperature_change_c, grid_id, grid_x, grid_y, `+periodColumns+` FROM weather_cache
+		WHERE latitude BETWEEN $1 AND $2 AND longitude BETWEEN $3 AND $4 AND timestamp > $5`,
+		minLat, maxLat, minLon, maxLon, freshAfter,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nearest *models.WeatherCache
+	for rows.Next() {
+		cache := models.WeatherCache{Source: SourcePostgres}
+		var period periodFields
+		dest := append([]interface{}{&cache.Latitude, &cache.Longitude, &cache.Forecast, &cache.TempC, &cache.TempF, &cache.Timestamp, &cache.City, &cache.State, &cache.TimeZone, &cache.Provider, &cache.DetailedForecast, &cache.UVIndex, &cache.TemperatureChangeC, &cache.GridID, &cache.GridX, &cache.GridY}, period.dest()...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		period.apply(&cache)
+		nearest = closer(nearest, &cache, lat, lon, radiusKm)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if nearest == nil {
+		return nil, sql.ErrNoRows
+	}
+	return nearest, nil
+}
+
+// SaveForecast implements ForecastStore, upserting the coordinate's row and
+// appending the refresh to weather_history, unless it is already there, in
+// one transaction
+func (s *PostgresStore) SaveForecast(ctx context.Context, weather *models.WeatherCache) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO weather_cache (latitude, longitude, forecast, temp_c, temp_f, timestamp, city, state, time_zone, provider, detailed_forecast, uv_index, temperature_change_c, grid_id, grid_x, grid_y, `+periodColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29)
+			ON CONFLICT (latitude, longitude) DO UPDATE SET forecast = excluded.forecast, temp_c = excluded.temp_c,
+				temp_f = excluded.temp_f, timestamp = excluded.timestamp, city = excluded.city, state = excluded.state,
+				time_zone = excluded.time_zone, provider = excluded.provider, detailed_forecast = excluded.detailed_forecast, uv_index = excluded.uv_index,
+				temperature_change_c = excluded.temperature_change_c, grid_id = excluded.grid_id, grid_x = excluded.grid_x, grid_y = excluded.grid_y,
+				period_name = excluded.period_name, is_daytime = excluded.is_daytime, icon = excluded.icon, wind_min_kmh = excluded.wind_min_kmh, wind_max_kmh = excluded.wind_max_kmh, wind_min_mph = excluded.wind_min_mph,
+				wind_max_mph = excluded.wind_max_mph, wind_direction = excluded.wind_direction,
+				precipitation_probability = excluded.precipitation_probability, relative_humidity = excluded.relative_humidity,
+				dewpoint_c = excluded.dewpoint_c, dewpoint_f = excluded.dewpoint_f, temperature_trend = excluded.temperature_trend`,
+			append([]interface{}{weather.Latitude, weather.Longitude, weather.Forecast, weather.TempC, weather.TempF, weather.Timestamp,
+				weather.City, weather.State, weather.TimeZone, weather.Provider, weather.DetailedForecast, weather.UVIndex, weather.TemperatureChangeC,
+				weather.GridID, weather.GridX, weather.GridY}, periodValues(weather)...)...,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO weather_history (latitude, longitude, forecast, temp_c, temp_f, timestamp) SELECT $1::DOUBLE PRECISION, $2::DOUBLE PRECISION, $3::TEXT, $4::DOUBLE PRECISION, $5::DOUBLE PRECISION, $6::TIMESTAMPTZ
+			WHERE NOT EXISTS (SELECT 1 FROM weather_history WHERE latitude = $1 AND longitude = $2 AND timestamp = $6)`,
+			weather.Latitude, weather.Longitude, weather.Forecast, weather.TempC, weather.TempF, weather.Timestamp,
+		)
+		return err
+	})
+}
+
+// ListForecasts implements ForecastStore
+func (s *PostgresStore) ListForecasts(ctx context.Context, limit, offset int) ([]models.CachedLocation, int64, error) {
+	var total int64
+	if err := s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM weather_cache").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.pool.Query(ctx,
+		`SELECT latitude, longitude, forecast, city, state, timestamp FROM weather_cache
+		ORDER BY timestamp DESC, latitude, longitude LIMIT $1 OFFSET $2`,
+		limit, offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	locations := []models.CachedLocation{}
+	for rows.Next() {
+		var loc models.CachedLocation
+		if err := rows.Scan(&loc.Latitude, &loc.Longitude, &loc.Forecast, &loc.City, &loc.State, &loc.Timestamp); err != nil {
+			return nil, 0, err
+		}
+		locations = append(locations, loc)
+	}
+	return locations, total, rows.Err()
+}
+
+// DeleteForecast implements ForecastStore
+func (s *PostgresStore) DeleteForecast(ctx context.Context, lat, lon float64) (int64, error) {
+	return s.exec(ctx, "DELETE FROM weather_cache WHERE latitude = $1 AND longitude = $2", lat, lon)
+}
+
+// DeleteAllForecasts implements ForecastStore
+func (s *PostgresStore) DeleteAllForecasts(ctx context.Context) (int64, error) {
+	return s.exec(ctx, "DELETE FROM weather_cache")
+}
+
+// PurgeForecasts implements ForecastStore
+func (s *PostgresStore) PurgeForecasts(ctx context.Context, before time.Time) (int64, error) {
+	return s.exec(ctx, "DELETE FROM weather_cache WHERE timestamp < $1", before)
+}
+
+// exec runs a statement, returning the rows it affected
+func (s *PostgresStore) exec(ctx context.Context, query string, args ...any) (int64, error) {
+	tag, err := s.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DownsampleHistory implements ForecastStore. Replicas take turns, and the
+// merge and delete share a transaction, so no refresh is counted twice.
+func (s *PostgresStore) DownsampleHistory(ctx context.Context, before time.Time) (int64, error) {
+	cutoff := before.UTC().Truncate(24 * time.Hour)
+	var folded int64
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", postgresDownsampleLock); err != nil {
+			return err
+		}
+		raw, err := pgRawWeather(ctx, tx,
+			"SELECT id, latitude, longitude, forecast, temp_c, temp_f, timestamp FROM weather_history WHERE timestamp < $1",
+			cutoff,
+		)
+		if err != nil {
+			return err
+		}
+
+		buckets, ids := foldRawWeather(raw, cutoff.Format(DayFormat))
+		for key, agg := range buckets {
+			existing, err := pgWeatherDaily(ctx, tx, key)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				agg.merge(existing)
+			}
+			if err := pgSaveWeatherDaily(ctx, tx, key, agg); err != nil {
+				return err
+			}
+		}
+		if len(ids) > 0 {
+			if _, err := tx.Exec(ctx, "DELETE FROM weather_history WHERE id = ANY($1)", ids); err != nil {
+				return err
+			}
+		}
+		folded = int64(len(ids))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return folded, nil
+}
+
+// pgRawWeather reads weather_history rows, bucketing each by the UTC day of its timestamp
+func pgRawWeather(ctx context.Context, tx pgx.Tx, query string, args ...any) ([]rawWeatherRow, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []rawWeatherRow
+	for rows.Next() {
+		var row rawWeatherRow
+		var ts time.Time
+		if err := rows.Scan(&row.id, &row.key.lat, &row.key.lon, &row.forecast, &row.tempC, &row.tempF, &ts); err != nil {
+			return nil, err
+		}
+		row.key.day = ts.UTC().Format(DayFormat)
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// weatherDailyColumns are the weather_daily columns storedDayAggregate rebuilds an aggregate from
+const weatherDailyColumns = "samples, min_temp_c, max_temp_c, mean_temp_c, min_temp_f, max_temp_f, mean_temp_f, forecast_counts"
+
+// pgWeatherDaily loads a stored daily summary as an aggregate, or nil when
+// there is none, locking its row until the transaction ends
+func pgWeatherDaily(ctx context.Context, tx pgx.Tx, key dayKey) (*dayAggregate, error) {
+	var samples int
+	var minC, maxC, meanC, minF, maxF, meanF float64
+	var forecasts string
+	err := tx.QueryRow(ctx,
+		"SELECT "+weatherDailyColumns+" FROM weather_daily WHERE latitude = $1 AND longitude = $2 AND day = $3 FOR UPDATE",
+		key.lat, key.lon, key.day,
+	).Scan(&samples, &minC, &maxC, &meanC, &minF, &maxF, &meanF, &forecasts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return storedDayAggregate(key.day, samples, minC, maxC, meanC, minF, maxF, meanF, forecasts)
+}
+
+// pgSaveWeatherDaily writes a daily summary, replacing any earlier one for the day
+func pgSaveWeatherDaily(ctx context.Context, tx pgx.Tx, key dayKey, agg *dayAggregate) error {
+	forecasts, err := json.Marshal(agg.forecasts)
+	if err != nil {
+		return err
+	}
+	s := agg.summary(key.day)
+	_, err = tx.Exec(ctx,
+		`INSERT INTO weather_daily
+			(latitude, longitude, day, samples, min_temp_c, max_temp_c, mean_temp_c, min_temp_f, max_temp_f, mean_temp_f, forecast, forecast_counts)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (latitude, longitude, day) DO UPDATE SET samples = excluded.samples,
+			min_temp_c = excluded.min_temp_c, max_temp_c = excluded.max_temp_c, mean_temp_c = excluded.mean_temp_c,
+			min_temp_f = excluded.min_temp_f, max_temp_f = excluded.max_temp_f, mean_temp_f = excluded.mean_temp_f,
+			forecast = excluded.forecast, forecast_counts = excluded.forecast_counts`,
+		key.lat, key.lon, key.day, s.Samples, s.MinTempC, s.MaxTempC, s.MeanTempC,
+		s.MinTempF, s.MaxTempF, s.MeanTempF, s.Forecast, string(forecasts),
+	)
+	return err
+}
+
+// History implements ForecastStore, reading the summaries and raw rows from
+// one snapshot so a concurrent downsample can't move rows between them
+func (s *PostgresStore) History(ctx context.Context, lat, lon float64, from, to string) ([]models.WeatherDay, error) {
+	start, err := time.Parse(DayFormat, from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.Parse(DayFormat, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var history []models.WeatherDay
+	err = pgx.BeginTxFunc(ctx, s.pool, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx,
+			"SELECT day, "+weatherDailyColumns+" FROM weather_daily WHERE latitude = $1 AND longitude = $2 AND day >= $3 AND day <= $4",
+			lat, lon, from, to,
+		)
+		if err != nil {
+			return err
+		}
+		daily := map[string]*dayAggregate{}
+		for rows.Next() {
+			var day, forecasts string
+			var samples int
+			var minC, maxC, meanC, minF, maxF, meanF float64
+			if err := rows.Scan(&day, &samples, &minC, &maxC, &meanC, &minF, &maxF, &meanF, &forecasts); err != nil {
+				rows.Close()
+				return err
+			}
+			agg, err := storedDayAggregate(day, samples, minC, maxC, meanC, minF, maxF, meanF, forecasts)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			daily[day] = agg
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		raw, err := pgRawWeather(ctx, tx,
+			`SELECT id, latitude, longitude, forecast, temp_c, temp_f, timestamp FROM weather_history
+			WHERE latitude = $1 AND longitude = $2 AND timestamp >= $3 AND timestamp < $4`,
+			lat, lon, start, end.Add(24*time.Hour),
+		)
+		if err != nil {
+			return err
+		}
+		history = weatherSeries(daily, raw, from, to)
+		return nil
+	})
+	return history, err
+}
+
+// Ping implements ForecastStore
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
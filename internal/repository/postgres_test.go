@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"weather-api-go/internal/models"
+)
+
+// openTestPostgres opens a store in a fresh schema of the database at
+// PG_TEST_DSN, dropped when the test ends, skipping the test when it is unset
+func openTestPostgres(t *testing.T) (*PostgresStore, func() *PostgresStore) {
+	t.Helper()
+	dsn := os.Getenv("PG_TEST_DSN")
+	if dsn == "" {
+		t.Skip("PG_TEST_DSN not set")
+	}
+	ctx := context.Background()
+
+	schema := fmt.Sprintf("weather_test_%d", time.Now().UnixNano())
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close(ctx)
+	if _, err := conn.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn, err := pgx.Connect(ctx, dsn)
+		if err != nil {
+			t.Errorf("dropping schema %s: %v", schema, err)
+			return
+		}
+		defer conn.Close(ctx)
+		if _, err := conn.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Errorf("dropping schema %s: %v", schema, err)
+		}
+	})
+
+	open := func() *PostgresStore {
+		t.Helper()
+		cfg, err := pgxpool.ParseConfig(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.ConnConfig.RuntimeParams["search_path"] = schema
+		store, err := openPostgres(ctx, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(store.Close)
+		return store
+	}
+	return open(), open
+}
+
+func TestPostgresStore(t *testing.T) {
+	store, _ := openTestPostgres(t)
+	testForecastStore(t, store)
+}
+
+func TestPostgresMigrationsRunOnce(t *testing.T) {
+	store, open := openTestPostgres(t)
+	// Reopening finds the schema current rather than recreating it
+	reopened := open()
+
+	var versions int
+	if err := reopened.pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM schema_migrations").Scan(&versions); err != nil {
+		t.Fatal(err)
+	}
+	if versions != len(postgresMigrations) {
+		t.Errorf("schema_migrations has %d versions; want %d", versions, len(postgresMigrations))
+	}
+
+	// Both connections share one cache
+	repo := NewWeatherRepository(newTestRepository(t).db, nil, WithForecastStore(store))
+	err := repo.SaveToCache(context.Background(), &models.WeatherCache{Latitude: 40.7128, Longitude: -74.006, Forecast: "Sunny", Timestamp: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := NewWeatherRepository(newTestRepository(t).db, nil, WithForecastStore(reopened))
+	cached, err := other.GetFromCache(context.Background(), 40.7128, -74.006)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.Source != SourcePostgres || cached.Forecast != "Sunny" {
+		t.Errorf("cached = %+v; want the forecast the other repository saved, from postgres", cached)
+	}
+}
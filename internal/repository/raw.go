@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// Raw document kinds, each kept as long as the parsed data it was read for
+const (
+	RawPoints   = "points"
+	RawForecast = "forecast"
+	// RawZone documents are NWS zones, kept for their shapes, which change
+	// only when the NWS redraws its zones
+	RawZone = "zone"
+)
+
+// rawDocumentTTL returns how long a raw document of the given kind is reused
+func (r *WeatherRepository) rawDocumentTTL(kind string) time.Duration {
+	switch kind {
+	case RawForecast:
+		return r.cacheTTL
+	case RawZone:
+		return PointMetadataTTL
+	}
+	return GridPointTTL
+}
+
+// RawPointsKey identifies the points document for a normalized coordinate
+func RawPointsKey(lat, lon float64) string {
+	return fmt.Sprintf("%.6f,%.6f", lat, lon)
+}
+
+// RawForecastKey identifies the forecast document for an NWS grid cell
+func RawForecastKey(gridID string, gridX, gridY int) string {
+	return fmt.Sprintf("%s/%d,%d", gridID, gridX, gridY)
+}
+
+// GetRawDocument retrieves a cached upstream document (Redis first, then SQLite)
+func (r *WeatherRepository) GetRawDocument(ctx context.Context, kind, key string) (*models.RawDocument, error) {
+	if r.rdb != nil {
+		var doc models.RawDocument
+		if r.getJSON(ctx, "raw:"+kind+":"+key, &doc) {
+			return &doc, nil
+		}
+	}
+
+	var doc models.RawDocument
+	err := r.db.QueryRowContext(ctx,
+		"SELECT content_type, body, timestamp FROM raw_documents WHERE kind = ? AND key = ?",
+		kind, key,
+	).Scan(&doc.ContentType, &doc.Body, &doc.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+// SaveRawDocument caches an upstream document (Redis and SQLite)
+func (r *WeatherRepository) SaveRawDocument(ctx context.Context, kind, key string, doc *models.RawDocument) error {
+	if r.rdb != nil {
+		r.setJSON(ctx, "raw:"+kind+":"+key, doc, r.rawDocumentTTL(kind))
+	}
+
+	_, err := r.db.ExecContext(writeContext(ctx),
+		"INSERT OR REPLACE INTO raw_documents (kind, key, content_type, body, timestamp) VALUES (?, ?, ?, ?, ?)",
+		kind, key, doc.ContentType, doc.Body, doc.Timestamp.UTC(),
+	)
+	return err
+}
+
+// IsRawDocumentFresh checks if a cached document is as fresh as the parsed data of its kind
+func (r *WeatherRepository) IsRawDocumentFresh(kind string, doc *models.RawDocument) bool {
+	return time.Since(doc.Timestamp) < r.rawDocumentTTL(kind)
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"weather-api-go/internal/tracing"
+)
+
+// maxPooledBuffer keeps unusually large documents from pinning memory in the pools
+const maxPooledBuffer = 64 << 10
+
+// coordinateKey builds prefix + "lat:lon" with six decimals, matching the
+// "%.6f:%.6f" keys written by earlier versions
+func coordinateKey(prefix string, lat, lon float64) string {
+	b := append(make([]byte, 0, 64), prefix...)
+	b = strconv.AppendFloat(b, lat, 'f', 6, 64)
+	b = append(b, ':')
+	b = strconv.AppendFloat(b, lon, 'f', 6, 64)
+	return string(b)
+}
+
+// gridKey builds prefix + "gridID:x:y" for an NWS grid cell
+func gridKey(prefix, gridID string, gridX, gridY int) string {
+	b := append(make([]byte, 0, 64), prefix...)
+	b = append(b, gridID...)
+	b = append(b, ':')
+	b = strconv.AppendInt(b, int64(gridX), 10)
+	b = append(b, ':')
+	b = strconv.AppendInt(b, int64(gridY), 10)
+	return string(b)
+}
+
+// jsonBuffers reuses the buffers Redis values are encoded into
+var jsonBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// setJSON stores v in Redis as JSON, ignoring failures like every Redis write.
+// The value is encoded into a pooled buffer, which the client has copied out of
+// by the time Set returns.
+func (r *WeatherRepository) setJSON(ctx context.Context, key string, v interface{}, ttl time.Duration) {
+	buf := jsonBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	if err := json.NewEncoder(buf).Encode(v); err == nil {
+		r.rdb.Set(ctx, key, buf.Bytes(), ttl)
+	}
+	if buf.Cap() <= maxPooledBuffer {
+		jsonBuffers.Put(buf)
+	}
+}
+
+// getJSON decodes the Redis value at key into v, reporting whether it was
+// present and valid
+func (r *WeatherRepository) getJSON(ctx context.Context, key string, v interface{}) bool {
+	span := r.startSpan(ctx, "redis.get", tracing.CacheTierKey.String(SourceRedis), attribute.String("cache.key", key))
+	data, err := r.rdb.Get(ctx, key).Bytes()
+	found := err == nil && json.Unmarshal(data, v) == nil
+	span.SetAttributes(tracing.CacheHitKey.Bool(found))
+	if err == redis.Nil {
+		err = nil
+	}
+	tracing.End(span, err)
+	return found
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"weather-api-go/internal/models"
+)
+
+func TestCacheKeysMatchFormattedKeys(t *testing.T) {
+	coords := [][2]float64{{40.7128, -74.006}, {-33.8688, 151.2093}, {0, 0}, {89.9999995, -179.9999995}, {40.71284999, -74.00605001}}
+	for _, c := range coords {
+		if got, want := coordinateKey("weather:", c[0], c[1]), fmt.Sprintf("weather:%.6f:%.6f", c[0], c[1]); got != want {
+			t.Errorf("coordinateKey(%v, %v) = %q; want %q", c[0], c[1], got, want)
+		}
+	}
+	if got, want := gridKey("weather:grid:", "OKX", 33, -1), fmt.Sprintf("weather:grid:%s:%d:%d", "OKX", 33, -1); got != want {
+		t.Errorf("gridKey = %q; want %q", got, want)
+	}
+
+	// The key string itself is the only allocation
+	if n := testing.AllocsPerRun(100, func() { coordinateKey("weather:", 40.7128, -74.006) }); n > 1 {
+		t.Errorf("coordinateKey allocates %v times per call; want 1", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { gridKey("weather:grid:", "OKX", 33, 35) }); n > 1 {
+		t.Errorf("gridKey allocates %v times per call; want 1", n)
+	}
+}
+
+func TestGetFromCacheRedisRoundTrip(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	repo := newTestRepository(t)
+	repo.rdb = rdb
+
+	saved := &models.WeatherCache{
+		Latitude: 40.7128, Longitude: -74.006, Forecast: "Sunny", TempC: 20, TempF: 68,
+		Timestamp: time.Now().UTC().Truncate(time.Second),
+	}
+	if err := repo.SaveToCache(context.Background(), saved); err != nil {
+		t.Fatal(err)
+	}
+	if !mr.Exists("weather:40.713000:-74.006000") {
+		t.Fatalf("Redis keys = %v; want weather:40.713000:-74.006000", mr.Keys())
+	}
+
+	got, err := repo.GetFromCache(context.Background(), 40.7128, -74.006)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Source != SourceRedis || got.Forecast != saved.Forecast || !got.Timestamp.Equal(saved.Timestamp) {
+		t.Errorf("GetFromCache = %+v; want %+v from redis", got, saved)
+	}
+
+	// An undecodable Redis value falls back to SQLite
+	mr.Set("weather:40.713000:-74.006000", "{")
+	if got, err := repo.GetFromCache(context.Background(), 40.7128, -74.006); err != nil || got.Source != SourceSQLite {
+		t.Errorf("GetFromCache with a corrupt Redis value = %+v, %v; want the SQLite copy", got, err)
+	}
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// pruneSteps is how many age cutoffs PruneToSize steps through, oldest first
+const pruneSteps = 10
+
+// lastRequestedCTE gives each coordinate's most recent request from the request
+// log, which serves as the popularity signal for coordinate-keyed tables. The
+// logged "lat,lon" is rounded to CacheCoordinatePrecision to match the
+// normalized coordinates weather is cached under.
+const lastRequestedCTE = `WITH last_requested AS (
+	SELECT printf('%.3f,%.3f',
+		CAST(substr(coordinate, 1, instr(coordinate, ',') - 1) AS REAL),
+		CAST(substr(coordinate, instr(coordinate, ',') + 1) AS REAL)) AS coordinate,
+		MAX(unixepoch(timestamp)) AS ts
+	FROM request_log WHERE coordinate IS NOT NULL GROUP BY 1
+) `
+
+// pruneTarget is a cache table size-based pruning may delete from. rows selects
+// each row's rowid (rid) and when it was last written or requested (last_used,
+// Unix seconds).
+type pruneTarget struct {
+	table string
+	rows  string
+}
+
+// pruneTargets are the tables that only cache upstream data or history. Grid and
+// point mappings, the request log, and the stats rollups are small and kept.
+var pruneTargets = []pruneTarget{
+	{"raw_documents", "SELECT rowid AS rid, COALESCE(unixepoch(timestamp), 0) AS last_used FROM raw_documents"},
+	{"weather_cache", `SELECT w.id AS rid, MAX(COALESCE(unixepoch(w.timestamp), 0), COALESCE(l.ts, 0)) AS last_used
+		FROM weather_cache w LEFT JOIN last_requested l ON l.coordinate = printf('%.3f,%.3f', w.latitude, w.longitude)`},
+	{"weather_history", `SELECT h.id AS rid, MAX(COALESCE(unixepoch(h.timestamp), 0), COALESCE(l.ts, 0)) AS last_used
+		FROM weather_history h LEFT JOIN last_requested l ON l.coordinate = printf('%.3f,%.3f', h.latitude, h.longitude)`},
+	{"weather_daily", `SELECT d.rowid AS rid, MAX(COALESCE(unixepoch(d.day, '+1 day'), 0), COALESCE(l.ts, 0)) AS last_used
+		FROM weather_daily d LEFT JOIN last_requested l ON l.coordinate = printf('%.3f,%.3f', d.latitude, d.longitude)`},
+	{"uncovered_points", "SELECT rowid AS rid, COALESCE(unixepoch(timestamp), 0) AS last_used FROM uncovered_points"},
+	{"observation_cache", "SELECT rowid AS rid, COALESCE(unixepoch(timestamp), 0) AS last_used FROM observation_cache"},
+	{"grid_forecast_cache", "SELECT rowid AS rid, COALESCE(unixepoch(timestamp), 0) AS last_used FROM grid_forecast_cache"},
+	{"forecast_periods", "SELECT rowid AS rid, COALESCE(unixepoch(timestamp), 0) AS last_used FROM forecast_periods"},
+	{"alert_cache", "SELECT rowid AS rid, COALESCE(unixepoch(timestamp), 0) AS last_used FROM alert_cache"},
+	{"geocode_cache", "SELECT rowid AS rid, COALESCE(unixepoch(timestamp), 0) AS last_used FROM geocode_cache"},
+	{"air_quality_cache", "SELECT rowid AS rid, COALESCE(unixepoch(timestamp), 0) AS last_used FROM air_quality_cache"},
+}
+
+// DatabaseSize returns the size of the SQLite database in bytes (page_count * page_size)
+func (r *WeatherRepository) DatabaseSize(ctx context.Context) (int64, error) {
+	var pages, pageSize int64
+	if err := r.db.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pages); err != nil {
+		return 0, err
+	}
+	if err := r.db.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, err
+	}
+	return pages * pageSize, nil
+}
+
+// CacheRowCounts returns the number of rows in each prunable cache table
+func (r *WeatherRepository) CacheRowCounts(ctx context.Context) (map[string]int64, error) {
+	counts := make(map[string]int64, len(pruneTargets))
+	for _, t := range pruneTargets {
+		var n int64
+		if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+t.table).Scan(&n); err != nil {
+			return nil, err
+		}
+		counts[t.table] = n
+	}
+	return counts, nil
+}
+
+// PruneToSize deletes the least recently used cache rows until the database is
+// at most target bytes, returning the number of rows deleted. Cutoffs step from
+// the oldest row's last use towards now; after each step freed pages are
+// returned to the filesystem with an incremental vacuum. A row's last use is
+// its write time or, for coordinate-keyed tables, the coordinate's most recent
+// request if that is later.
+func (r *WeatherRepository) PruneToSize(ctx context.Context, target int64) (int64, error) {
+	size, err := r.DatabaseSize(ctx)
+	if err != nil || size <= target {
+		return 0, err
+	}
+
+	oldest, err := r.oldestCacheUse(ctx)
+	if err != nil || !oldest.Valid {
+		return 0, err
+	}
+	now := time.Now().Unix()
+	span := now - oldest.Int64
+
+	var deleted int64
+	for step := int64(1); step <= pruneSteps && size > target; step++ {
+		cutoff := oldest.Int64 + span*step/pruneSteps
+		for _, t := range pruneTargets {
+			res, err := r.db.ExecContext(writeContext(ctx),
+				lastRequestedCTE+"DELETE FROM "+t.table+" WHERE rowid IN (SELECT rid FROM ("+t.rows+") WHERE last_used < ?)",
+				cutoff,
+			)
+			if err != nil {
+				return deleted, err
+			}
+			n, _ := res.RowsAffected()
+			deleted += n
+		}
+
+		if err := r.incrementalVacuum(ctx); err != nil {
+			return deleted, err
+		}
+		if size, err = r.DatabaseSize(ctx); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// oldestCacheUse returns the earliest last use across the prunable tables
+func (r *WeatherRepository) oldestCacheUse(ctx context.Context) (sql.NullInt64, error) {
+	var oldest sql.NullInt64
+	for _, t := range pruneTargets {
+		var v sql.NullInt64
+		if err := r.db.QueryRowContext(ctx, lastRequestedCTE+"SELECT MIN(last_used) FROM ("+t.rows+")").Scan(&v); err != nil {
+			return oldest, err
+		}
+		if v.Valid && (!oldest.Valid || v.Int64 < oldest.Int64) {
+			oldest = v
+		}
+	}
+	return oldest, nil
+}
+
+// incrementalVacuum returns all free pages of the cache database to the filesystem
+func (r *WeatherRepository) incrementalVacuum(ctx context.Context) error {
+	return incrementalVacuum(ctx, r.db)
+}
+
+// incrementalVacuum returns all of db's free pages to the filesystem. The
+// pragma frees a page per step, so its (empty) result set must be drained.
+func incrementalVacuum(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, "PRAGMA incremental_vacuum")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	return rows.Err()
+}
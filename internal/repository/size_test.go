@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+func TestPruneToSize(t *testing.T) {
+	repo := newTestRepository(t)
+	now := time.Now()
+
+	// One 32 KiB document per day over the last 20 days
+	body := make([]byte, 32<<10)
+	for d := 0; d < 20; d++ {
+		err := repo.SaveRawDocument(context.Background(), RawPoints, fmt.Sprintf("doc-%02d", d), &models.RawDocument{
+			ContentType: "application/geo+json", Body: body, Timestamp: now.AddDate(0, 0, -d),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Two forecasts cached a month ago; only the first coordinate is still requested
+	for _, lat := range []float64{40.7128, 34.0522} {
+		err := repo.SaveToCache(context.Background(), &models.WeatherCache{
+			Latitude: lat, Longitude: -74.006, Forecast: "Sunny", Timestamp: now.AddDate(0, 0, -30),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := repo.SaveRequestLog(context.Background(), []models.RequestLogEntry{{
+		Timestamp: now.Add(-time.Minute), Route: "/api/weather", Status: 200, Coordinate: "40.7128,-74.0060",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := repo.DatabaseSize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := before / 2
+	deleted, err := repo.PruneToSize(context.Background(), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted == 0 {
+		t.Fatal("pruned no rows")
+	}
+
+	after, err := repo.DatabaseSize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after > target {
+		t.Errorf("size after pruning = %d; want at most %d (was %d)", after, target, before)
+	}
+
+	// Oldest documents go first; the newest survive
+	if doc, _ := repo.GetRawDocument(context.Background(), RawPoints, "doc-19"); doc != nil {
+		t.Error("oldest document survived pruning")
+	}
+	if doc, _ := repo.GetRawDocument(context.Background(), RawPoints, "doc-00"); doc == nil {
+		t.Error("newest document was pruned")
+	}
+
+	// A recent request keeps an old forecast; an unrequested one is pruned
+	if cache, _ := repo.GetFromCache(context.Background(), 40.7128, -74.006); cache == nil {
+		t.Error("recently requested forecast was pruned")
+	}
+	if cache, _ := repo.GetFromCache(context.Background(), 34.0522, -74.006); cache != nil {
+		t.Error("unrequested month-old forecast survived pruning")
+	}
+
+	if n, err := repo.PruneToSize(context.Background(), after); err != nil || n != 0 {
+		t.Errorf("pruning to the current size deleted %d rows (err %v); want 0", n, err)
+	}
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// NearestStationTTL is how long a coordinate's nearest observation station is
+// reused. Stations are commissioned and retired more often than zones change.
+const NearestStationTTL = 7 * 24 * time.Hour
+
+// GetNearestStation retrieves the cached nearest station for a normalized coordinate (Redis first, then SQLite)
+func (r *WeatherRepository) GetNearestStation(ctx context.Context, lat, lon float64) (*models.NearestStation, error) {
+	if r.rdb != nil {
+		var station models.NearestStation
+		if r.getJSON(ctx, coordinateKey("station:nearest:", lat, lon), &station) {
+			return &station, nil
+		}
+	}
+
+	station := models.NearestStation{Latitude: lat, Longitude: lon}
+	err := r.db.QueryRowContext(ctx,
+		"SELECT station_id, timestamp FROM nearest_stations WHERE latitude = ? AND longitude = ?",
+		lat, lon,
+	).Scan(&station.StationID, &station.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &station, nil
+}
+
+// SaveNearestStation caches the nearest station for a normalized coordinate (Redis and SQLite)
+func (r *WeatherRepository) SaveNearestStation(ctx context.Context, station *models.NearestStation) error {
+	if r.rdb != nil {
+		r.setJSON(ctx, coordinateKey("station:nearest:", station.Latitude, station.Longitude), station, NearestStationTTL)
+	}
+
+	_, err := r.db.ExecContext(writeContext(ctx),
+		"INSERT OR REPLACE INTO nearest_stations (latitude, longitude, station_id, timestamp) VALUES (?, ?, ?, ?)",
+		station.Latitude, station.Longitude, station.StationID, station.Timestamp.UTC(),
+	)
+	return err
+}
+
+// IsNearestStationFresh checks if a cached nearest station is still usable
+func (r *WeatherRepository) IsNearestStationFresh(station *models.NearestStation) bool {
+	return time.Since(station.Timestamp) < NearestStationTTL
+}
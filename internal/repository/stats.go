@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"weather-api-go/internal/models"
+)
+
+// DayFormat is the layout of the UTC day keys used by the daily rollups and summaries
+const DayFormat = "2006-01-02"
+
+// SaveRequestLog appends a batch of request records to the raw request log
+func (r *WeatherRepository) SaveRequestLog(ctx context.Context, entries []models.RequestLogEntry) error {
+	tx, err := r.db.BeginTx(writeContext(ctx), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(writeContext(ctx),
+		"INSERT INTO request_log (day, timestamp, route, status, latency_ms, coordinate, cache_hit) VALUES (?, ?, ?, ?, ?, ?, ?)",
+	)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		ts := e.Timestamp.UTC()
+		var coordinate sql.NullString
+		if e.Coordinate != "" {
+			coordinate = sql.NullString{String: e.Coordinate, Valid: true}
+		}
+		var cacheHit sql.NullBool
+		if e.CacheHit != nil {
+			cacheHit = sql.NullBool{Bool: *e.CacheHit, Valid: true}
+		}
+		if _, err := stmt.ExecContext(writeContext(ctx), ts.Format(DayFormat), ts, e.Route, e.Status, e.LatencyMs, coordinate, cacheHit); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RollupDailyStats aggregates the raw request log for a UTC day into daily_stats,
+// replacing any earlier rollup of that day so re-runs are idempotent. Days with
+// no raw rows left (e.g. already purged) keep their existing rollup, and nil is returned.
+func (r *WeatherRepository) RollupDailyStats(ctx context.Context, day string) (*models.DailyStats, error) {
+	stats := models.DailyStats{Day: day, RouteCounts: map[string]int64{}}
+
+	var cacheHits, cacheLookups int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+			COALESCE(SUM(status >= 400 AND status < 500), 0),
+			COALESCE(SUM(status >= 500), 0),
+			COALESCE(SUM(cache_hit = 1), 0),
+			COUNT(cache_hit),
+			COUNT(DISTINCT coordinate)
+		FROM request_log WHERE day = ?`, day,
+	).Scan(&stats.TotalRequests, &stats.ClientErrorCount, &stats.ErrorCount, &cacheHits, &cacheLookups, &stats.DistinctCoordinates)
+	if err != nil {
+		return nil, err
+	}
+	if stats.TotalRequests == 0 {
+		return nil, nil
+	}
+	if cacheLookups > 0 {
+		ratio := float64(cacheHits) / float64(cacheLookups)
+		stats.CacheHitRatio = &ratio
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT route, COUNT(*) FROM request_log WHERE day = ? GROUP BY route", day)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var route string
+		var count int64
+		if err := rows.Scan(&route, &count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stats.RouteCounts[route] = count
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	latencies, err := r.dayLatencies(ctx, day)
+	if err != nil {
+		return nil, err
+	}
+	stats.P50LatencyMs = percentile(latencies, 0.50)
+	stats.P95LatencyMs = percentile(latencies, 0.95)
+
+	routeCounts, err := json.Marshal(stats.RouteCounts)
+	if err != nil {
+		return nil, err
+	}
+	_, err = r.db.ExecContext(writeContext(ctx),
+		`INSERT OR REPLACE INTO daily_stats
+			(day, total_requests, route_counts, client_error_count, error_count, cache_hit_ratio, distinct_coordinates, p50_latency_ms, p95_latency_ms, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		stats.Day, stats.TotalRequests, string(routeCounts), stats.ClientErrorCount, stats.ErrorCount,
+		stats.CacheHitRatio, stats.DistinctCoordinates, stats.P50LatencyMs, stats.P95LatencyMs, time.Now().UTC(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// dayLatencies returns the request latencies logged on a day in ascending order
+func (r *WeatherRepository) dayLatencies(ctx context.Context, day string) ([]float64, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT latency_ms FROM request_log WHERE day = ? ORDER BY latency_ms", day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var latencies []float64
+	for rows.Next() {
+		var v float64
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		latencies = append(latencies, v)
+	}
+	return latencies, rows.Err()
+}
+
+// percentile returns the nearest-rank percentile of ascending values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// GetDailyStats returns the rolled-up stats for the UTC days from..to inclusive, oldest first
+func (r *WeatherRepository) GetDailyStats(ctx context.Context, from, to string) ([]models.DailyStats, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT day, total_requests, route_counts, client_error_count, error_count, cache_hit_ratio,
+			distinct_coordinates, p50_latency_ms, p95_latency_ms
+		FROM daily_stats WHERE day >= ? AND day <= ? ORDER BY day`,
+		from, to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []models.DailyStats{}
+	for rows.Next() {
+		var s models.DailyStats
+		var routeCounts string
+		var ratio sql.NullFloat64
+		if err := rows.Scan(&s.Day, &s.TotalRequests, &routeCounts, &s.ClientErrorCount, &s.ErrorCount, &ratio,
+			&s.DistinctCoordinates, &s.P50LatencyMs, &s.P95LatencyMs); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(routeCounts), &s.RouteCounts); err != nil {
+			return nil, fmt.Errorf("corrupt route counts for %s: %w", s.Day, err)
+		}
+		if ratio.Valid {
+			s.CacheHitRatio = &ratio.Float64
+		}
+		days = append(days, s)
+	}
+	return days, rows.Err()
+}
+
+// PurgeRequestLog deletes raw request log rows logged before the given time,
+// but only for days that have already been rolled up
+func (r *WeatherRepository) PurgeRequestLog(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(writeContext(ctx),
+		"DELETE FROM request_log WHERE timestamp < ? AND day IN (SELECT day FROM daily_stats)",
+		before.UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// AcquireLock takes a named lock for ttl so only one instance runs a scheduled
+// job. Without Redis there is nothing to coordinate with, so the lock is always granted.
+func (r *WeatherRepository) AcquireLock(ctx context.Context, name string, ttl time.Duration) (bool, error) {
+	if r.rdb == nil {
+		return true, nil
+	}
+	err := r.rdb.SetArgs(ctx, "lock:"+name, "1", redis.SetArgs{Mode: "NX", TTL: ttl}).Err()
+	if err == redis.Nil {
+		return false, nil
+	}
+	return err == nil, err
+}
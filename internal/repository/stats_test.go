@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"weather-api-go/internal/models"
+)
+
+func boolPtr(v bool) *bool { return &v }
+
+// requestsOn logs n requests on the UTC day starting at day, with latencies 1..n ms
+func requestsOn(day time.Time, n int) []models.RequestLogEntry {
+	entries := make([]models.RequestLogEntry, n)
+	for i := range entries {
+		entries[i] = models.RequestLogEntry{
+			Timestamp: day.Add(time.Duration(i) * time.Minute),
+			Route:     "/api/weather",
+			Status:    200,
+			LatencyMs: float64(i + 1),
+		}
+	}
+	return entries
+}
+
+func TestRollupDailyStats(t *testing.T) {
+	repo := newTestRepository(t)
+	day := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	entries := requestsOn(day, 20)
+	entries[0].Coordinate, entries[0].CacheHit = "40.7128,-74.0060", boolPtr(true)
+	entries[1].Coordinate, entries[1].CacheHit = "40.7128,-74.0060", boolPtr(true)
+	entries[2].Coordinate, entries[2].CacheHit = "34.0522,-118.2437", boolPtr(false)
+	entries[3].Coordinate, entries[3].CacheHit = "34.0522,-118.2437", boolPtr(true)
+	entries[4].Route, entries[4].Status = "/api/health", 200
+	entries[5].Status = 400
+	entries[6].Status = 503
+	// Boundary rows belong to the neighbouring days
+	entries = append(entries,
+		models.RequestLogEntry{Timestamp: day.Add(-time.Nanosecond), Route: "/api/weather", Status: 200, LatencyMs: 999},
+		models.RequestLogEntry{Timestamp: day.Add(24 * time.Hour), Route: "/api/weather", Status: 500, LatencyMs: 999},
+	)
+	if err := repo.SaveRequestLog(context.Background(), entries); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rolling up twice must give the same single row
+	for run := 0; run < 2; run++ {
+		if _, err := repo.RollupDailyStats(context.Background(), "2024-01-15"); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+
+	days, err := repo.GetDailyStats(context.Background(), "2024-01-15", "2024-01-15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 1 {
+		t.Fatalf("got %d days; want 1", len(days))
+	}
+	got := days[0]
+	if got.TotalRequests != 20 {
+		t.Errorf("TotalRequests = %d; want 20", got.TotalRequests)
+	}
+	if got.RouteCounts["/api/weather"] != 19 || got.RouteCounts["/api/health"] != 1 {
+		t.Errorf("RouteCounts = %v; want weather=19 health=1", got.RouteCounts)
+	}
+	if got.ClientErrorCount != 1 || got.ErrorCount != 1 {
+		t.Errorf("ClientErrorCount, ErrorCount = %d, %d; want 1, 1", got.ClientErrorCount, got.ErrorCount)
+	}
+	if got.CacheHitRatio == nil || *got.CacheHitRatio != 0.75 {
+		t.Errorf("CacheHitRatio = %v; want 0.75", got.CacheHitRatio)
+	}
+	if got.DistinctCoordinates != 2 {
+		t.Errorf("DistinctCoordinates = %d; want 2", got.DistinctCoordinates)
+	}
+	if got.P50LatencyMs != 10 || got.P95LatencyMs != 19 {
+		t.Errorf("p50, p95 = %v, %v; want 10, 19", got.P50LatencyMs, got.P95LatencyMs)
+	}
+}
+
+func TestRollupDailyStatsWithoutCacheLookups(t *testing.T) {
+	repo := newTestRepository(t)
+	day := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	if err := repo.SaveRequestLog(context.Background(), requestsOn(day, 3)); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := repo.RollupDailyStats(context.Background(), "2024-01-15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.CacheHitRatio != nil {
+		t.Errorf("CacheHitRatio = %v; want nil without cache-backed requests", *stats.CacheHitRatio)
+	}
+}
+
+func TestPurgeRequestLogKeepsUnrolledDays(t *testing.T) {
+	repo := newTestRepository(t)
+	rolled := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	pending := rolled.AddDate(0, 0, 1)
+	if err := repo.SaveRequestLog(context.Background(), append(requestsOn(rolled, 5), requestsOn(pending, 3)...)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.RollupDailyStats(context.Background(), "2024-01-15"); err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := repo.PurgeRequestLog(context.Background(), pending.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 5 {
+		t.Errorf("purged %d rows; want only the 5 rolled-up ones", purged)
+	}
+
+	// A re-run after the purge must not wipe the existing rollup
+	stats, err := repo.RollupDailyStats(context.Background(), "2024-01-15")
+	if err != nil || stats != nil {
+		t.Errorf("re-run after purge = %v, %v; want nil, nil", stats, err)
+	}
+	days, _ := repo.GetDailyStats(context.Background(), "2024-01-15", "2024-01-16")
+	if len(days) != 1 || days[0].TotalRequests != 5 {
+		t.Errorf("daily stats after purge = %+v; want the original 2024-01-15 rollup", days)
+	}
+}
+
+func TestAcquireLock(t *testing.T) {
+	if ok, err := newTestRepository(t).AcquireLock(context.Background(), "job", time.Minute); !ok || err != nil {
+		t.Errorf("AcquireLock without Redis = %v, %v; want true, nil", ok, err)
+	}
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	first := NewWeatherRepository(nil, rdb)
+	second := NewWeatherRepository(nil, rdb)
+
+	if ok, err := first.AcquireLock(context.Background(), "job", time.Minute); !ok || err != nil {
+		t.Fatalf("first AcquireLock = %v, %v; want true, nil", ok, err)
+	}
+	if ok, err := second.AcquireLock(context.Background(), "job", time.Minute); ok || err != nil {
+		t.Errorf("second AcquireLock = %v, %v; want false, nil while held", ok, err)
+	}
+
+	mr.FastForward(time.Minute)
+	if ok, _ := second.AcquireLock(context.Background(), "job", time.Minute); !ok {
+		t.Error("lock was not released after its TTL")
+	}
+}
@@ -0,0 +1,453 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// ForecastStore persists per-coordinate forecasts: the latest for each
+// coordinate (weather_cache), every refresh (weather_history), and daily
+// summaries of older refreshes (weather_daily). Coordinates passed in are
+// already normalized. SQLite is the default; a PostgresStore lets replicas
+// share one.
+type ForecastStore interface {
+	// Name is the cache tier reported as the Source of forecasts read from the store
+	Name() string
+	// LatestForecast returns a coordinate's cached forecast, or sql.ErrNoRows
+	LatestForecast(ctx context.Context, lat, lon float64) (*models.WeatherCache, error)
+	// NearestForecast returns the cached forecast nearest a coordinate within
+	// radiusKm among those written after freshAfter, with its distance set,
+	// or sql.ErrNoRows
+	NearestForecast(ctx context.Context, lat, lon, radiusKm float64, freshAfter time.Time) (*models.WeatherCache, error)
+	// SaveForecast upserts a coordinate's forecast and appends it to its history
+	SaveForecast(ctx context.Context, weather *models.WeatherCache) error
+	// ListForecasts returns a page of cached coordinates, most recently
+	// refreshed first, and the total number cached
+	ListForecasts(ctx context.Context, limit, offset int) ([]models.CachedLocation, int64, error)
+	// DeleteForecast removes a coordinate's cached forecast, returning the rows removed
+	DeleteForecast(ctx context.Context, lat, lon float64) (int64, error)
+	// DeleteAllForecasts removes every cached forecast, returning the rows removed
+	DeleteAllForecasts(ctx context.Context) (int64, error)
+	// PurgeForecasts removes cached forecasts written before the given time,
+	// returning the rows removed. History is kept.
+	PurgeForecasts(ctx context.Context, before time.Time) (int64, error)
+	// DownsampleHistory folds the refreshes of UTC days that ended before the
+	// given time into daily summaries, returning the refreshes folded
+	DownsampleHistory(ctx context.Context, before time.Time) (int64, error)
+	// History summarizes a coordinate's UTC days from..to inclusive, oldest first
+	History(ctx context.Context, lat, lon float64, from, to string) ([]models.WeatherDay, error)
+	// Ping checks the store answers
+	Ping(ctx context.Context) error
+}
+
+// sqliteStore is the ForecastStore in the cache database
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// Name implements ForecastStore
+func (s sqliteStore) Name() string {
+	return SourceSQLite
+}
+
+// periodColumns are the weather_cache and grid_forecast_cache columns holding
+// a forecast period's name, day or night, icon, wind, chance of precipitation,
+// humidity, dewpoint, and temperature trend, in the order periodFields scans
+// them and periodValues writes them
+const periodColumns = "period_name, is_daytime, icon, wind_min_kmh, wind_max_kmh, wind_min_mph, wind_max_mph, wind_direction, " +
+	"precipitation_probability, relative_humidity, dewpoint_c, dewpoint_f, temperature_trend"
+
+// periodFields scans periodColumns, which are NULL where the forecast gave no
+// value and in rows cached before it was recorded
+type periodFields struct {
+	name, icon                     sql.NullString
+	daytime                        sql.NullBool
+	minKmh, maxKmh, minMph, maxMph sql.NullFloat64
+	direction                      sql.NullString
+	precipitation, humidity        sql.NullFloat64
+	dewpointC, dewpointF           sql.NullFloat64
+	trend                          sql.NullString
+}
+
+// dest returns the scan destinations for periodColumns
+func (p *periodFields) dest() []interface{} {
+	return []interface{}{&p.name, &p.daytime, &p.icon, &p.minKmh, &p.maxKmh, &p.minMph, &p.maxMph, &p.direction,
+		&p.precipitation, &p.humidity, &p.dewpointC, &p.dewpointF, &p.trend}
+}
+
+// apply sets a cache entry's period fields from the scanned columns
+func (p *periodFields) apply(cache *models.WeatherCache) {
+	cache.PeriodName, cache.Icon = p.name.String, p.icon.String
+	if p.daytime.Valid {
+		cache.IsDaytime = &p.daytime.Bool
+	}
+	if p.minKmh.Valid && p.maxKmh.Valid {
+		cache.WindKmh = &models.SpeedRange{Min: p.minKmh.Float64, Max: p.maxKmh.Float64}
+	}
+	if p.minMph.Valid && p.maxMph.Valid {
+		cache.WindMph = &models.SpeedRange{Min: p.minMph.Float64, Max: p.maxMph.Float64}
+	}
+	cache.WindDirection = p.direction.String
+	cache.PrecipitationProbability = nullFloat(p.precipitation)
+	cache.RelativeHumidity = nullFloat(p.humidity)
+	cache.DewpointC, cache.DewpointF = nullFloat(p.dewpointC), nullFloat(p.dewpointF)
+	cache.TemperatureTrend = p.trend.String
+}
+
+// nullFloat returns a scanned nullable number, nil for NULL
+func nullFloat(n sql.NullFloat64) *float64 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Float64
+}
+
+// periodValues returns a cache entry's period fields as values for
+// periodColumns, NULL where unset
+func periodValues(cache *models.WeatherCache) []interface{} {
+	var minKmh, maxKmh, minMph, maxMph *float64
+	if cache.WindKmh != nil {
+		minKmh, maxKmh = &cache.WindKmh.Min, &cache.WindKmh.Max
+	}
+	if cache.WindMph != nil {
+		minMph, maxMph = &cache.WindMph.Min, &cache.WindMph.Max
+	}
+	return []interface{}{cache.PeriodName, cache.IsDaytime, cache.Icon, minKmh, maxKmh, minMph, maxMph, cache.WindDirection,
+		cache.PrecipitationProbability, cache.RelativeHumidity, cache.DewpointC, cache.DewpointF, cache.TemperatureTrend}
+}
+
+// cachedWeatherQuery looks up a coordinate's cached forecast through the unique
+// (latitude, longitude) index, so its cost doesn't grow with the table
+const cachedWeatherQuery = "SELECT forecast, temp_c, temp_f, timestamp, city, state, time_zone, provider, detailed_forecast, uv_index, temperature_change_c, grid_id, grid_x, grid_y, " + periodColumns +
+	" FROM weather_cache WHERE latitude = ? AND longitude = ?"
+
+// LatestForecast implements ForecastStore
+func (s sqliteStore) LatestForecast(ctx context.Context, lat, lon float64) (*models.WeatherCache, error) {
+	cache := models.WeatherCache{Source: SourceSQLite, Latitude: lat, Longitude: lon}
+	// Rows cached before the location, time zone, provider, narrative, UV index, temperature change, or grid cell was recorded have NULLs there
+	var city, state, timeZone, provider, detailed, gridID sql.NullString
+	var uv, change sql.NullFloat64
+	var gridX, gridY sql.NullInt64
+	var period periodFields
+	dest := append([]interface{}{&cache.Forecast, &cache.TempC, &cache.TempF, &cache.Timestamp, &city, &state, &timeZone, &provider, &detailed, &uv, &change, &gridID, &gridX, &gridY}, period.dest()...)
+	if err := s.db.QueryRowContext(ctx, cachedWeatherQuery, lat, lon).Scan(dest...); err != nil {
+		return nil, err
+	}
+	cache.City, cache.State, cache.TimeZone = city.String, state.String, timeZone.String
+	cache.Provider, cache.DetailedForecast, cache.UVIndex = provider.String, detailed.String, nullFloat(uv)
+	cache.TemperatureChangeC = nullFloat(change)
+	cache.GridID, cache.GridX, cache.GridY = gridID.String, int(gridX.Int64), int(gridY.Int64)
+	period.apply(&cache)
+	return &cache, nil
+}
+
+// nearbyWeatherQuery finds the cached forecasts written after a time inside a
+// bounding box, scanning a latitude range of the unique (latitude, longitude)
+// index
+const nearbyWeatherQuery = `SELECT latitude, longitude, forecast, temp_c, temp_f, timestamp, city, state, time_zone, provider, detailed_forecast, uv_index, temperature_change_c, grid_id, grid_x, grid_y, ` + periodColumns + `
+	FROM weather_cache WHERE latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ? AND timestamp > ?`
+
+// NearestForecast implements ForecastStore
+func (s sqliteStore) NearestForecast(ctx context.Context, lat, lon, radiusKm float64, freshAfter time.Time) (*models.WeatherCache, error) {
+	minLat, maxLat, minLon, maxLon := boundingBox(lat, lon, radiusKm)
+	rows, err := s.db.QueryContext(ctx, nearbyWeatherQuery, minLat, maxLat, minLon, maxLon, freshAfter.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nearest *models.WeatherCache
+	for rows.Next() {
+		cache := models.WeatherCache{Source: SourceSQLite}
+		var city, state, timeZone, provider, detailed, gridID sql.NullString
+		var uv, change sql.NullFloat64
+		var gridX, gridY sql.NullInt64
+		var period periodFields
+		dest := append([]interface{}{&cache.Latitude, &cache.Longitude, &cache.Forecast, &cache.TempC, &cache.TempF, &cache.Timestamp, &city, &state, &timeZone, &provider, &detailed, &uv, &change, &gridID, &gridX, &gridY}, period.dest()...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		cache.City, cache.State, cache.TimeZone = city.String, state.String, timeZone.String
+		cache.Provider, cache.DetailedForecast, cache.UVIndex = provider.String, detailed.String, nullFloat(uv)
+		cache.TemperatureChangeC = nullFloat(change)
+		cache.GridID, cache.GridX, cache.GridY = gridID.String, int(gridX.Int64), int(gridY.Int64)
+		period.apply(&cache)
+		nearest = closer(nearest, &cache, lat, lon, radiusKm)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if nearest == nil {
+		return nil, sql.ErrNoRows
+	}
+	return nearest, nil
+}
+
+// closer returns whichever of nearest and candidate is closer to lat, lon,
+// setting the candidate's DistanceKm. A candidate farther than radiusKm, as
+// bounding box corners are, is never chosen.
+func closer(nearest, candidate *models.WeatherCache, lat, lon, radiusKm float64) *models.WeatherCache {
+	candidate.DistanceKm = DistanceKm(lat, lon, candidate.Latitude, candidate.Longitude)
+	if candidate.DistanceKm > radiusKm || (nearest != nil && nearest.DistanceKm <= candidate.DistanceKm) {
+		return nearest
+	}
+	return candidate
+}
+
+// SaveForecast implements ForecastStore, keeping one weather_cache row per
+// coordinate and appending the refresh to weather_history in one transaction.
+// Saving a refresh again, as when its UV index is added, only updates
+// weather_cache.
+func (s sqliteStore) SaveForecast(ctx context.Context, weather *models.WeatherCache) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO weather_cache (latitude, longitude, forecast, temp_c, temp_f, timestamp, city, state, time_zone, provider, detailed_forecast, uv_index, temperature_change_c, grid_id, grid_x, grid_y, `+periodColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (latitude, longitude) DO UPDATE SET forecast = excluded.forecast, temp_c = excluded.temp_c,
+			temp_f = excluded.temp_f, timestamp = excluded.timestamp, city = excluded.city, state = excluded.state,
+			time_zone = excluded.time_zone, provider = excluded.provider, detailed_forecast = excluded.detailed_forecast, uv_index = excluded.uv_index,
+			temperature_change_c = excluded.temperature_change_c, grid_id = excluded.grid_id, grid_x = excluded.grid_x, grid_y = excluded.grid_y,
+			period_name = excluded.period_name, is_daytime = excluded.is_daytime, icon = excluded.icon, wind_min_kmh = excluded.wind_min_kmh, wind_max_kmh = excluded.wind_max_kmh, wind_min_mph = excluded.wind_min_mph,
+			wind_max_mph = excluded.wind_max_mph, wind_direction = excluded.wind_direction,
+			precipitation_probability = excluded.precipitation_probability, relative_humidity = excluded.relative_humidity,
+			dewpoint_c = excluded.dewpoint_c, dewpoint_f = excluded.dewpoint_f, temperature_trend = excluded.temperature_trend`,
+		append([]interface{}{weather.Latitude, weather.Longitude, weather.Forecast, weather.TempC, weather.TempF, weather.Timestamp.UTC(),
+			weather.City, weather.State, weather.TimeZone, weather.Provider, weather.DetailedForecast, weather.UVIndex, weather.TemperatureChangeC,
+			weather.GridID, weather.GridX, weather.GridY}, periodValues(weather)...)...,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO weather_history (latitude, longitude, forecast, temp_c, temp_f, timestamp) SELECT ?, ?, ?, ?, ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM weather_history WHERE latitude = ? AND longitude = ? AND timestamp = ?)`,
+		weather.Latitude, weather.Longitude, weather.Forecast, weather.TempC, weather.TempF, weather.Timestamp.UTC(),
+		weather.Latitude, weather.Longitude, weather.Timestamp.UTC(),
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListForecasts implements ForecastStore
+func (s sqliteStore) ListForecasts(ctx context.Context, limit, offset int) ([]models.CachedLocation, int64, error) {
+	var total int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM weather_cache").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT latitude, longitude, forecast, city, state, timestamp FROM weather_cache
+		ORDER BY timestamp DESC, latitude, longitude LIMIT ? OFFSET ?`,
+		limit, offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	locations := []models.CachedLocation{}
+	for rows.Next() {
+		var loc models.CachedLocation
+		var forecast, city, state sql.NullString
+		if err := rows.Scan(&loc.Latitude, &loc.Longitude, &forecast, &city, &state, &loc.Timestamp); err != nil {
+			return nil, 0, err
+		}
+		loc.Forecast, loc.City, loc.State = forecast.String, city.String, state.String
+		locations = append(locations, loc)
+	}
+	return locations, total, rows.Err()
+}
+
+// DeleteForecast implements ForecastStore
+func (s sqliteStore) DeleteForecast(ctx context.Context, lat, lon float64) (int64, error) {
+	return s.exec(ctx, "DELETE FROM weather_cache WHERE latitude = ? AND longitude = ?", lat, lon)
+}
+
+// DeleteAllForecasts implements ForecastStore
+func (s sqliteStore) DeleteAllForecasts(ctx context.Context) (int64, error) {
+	return s.exec(ctx, "DELETE FROM weather_cache")
+}
+
+// PurgeForecasts implements ForecastStore, returning the freed pages to the filesystem
+func (s sqliteStore) PurgeForecasts(ctx context.Context, before time.Time) (int64, error) {
+	n, err := s.exec(ctx, "DELETE FROM weather_cache WHERE timestamp < ?", before.UTC())
+	if err != nil || n == 0 {
+		return n, err
+	}
+	return n, incrementalVacuum(ctx, s.db)
+}
+
+// exec runs a statement, returning the rows it affected
+func (s sqliteStore) exec(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DownsampleHistory implements ForecastStore. Only whole days are downsampled,
+// and the merge and delete share a transaction, so a re-run never counts a
+// row twice.
+func (s sqliteStore) DownsampleHistory(ctx context.Context, before time.Time) (int64, error) {
+	cutoff := before.UTC().Truncate(24 * time.Hour)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Timestamps compare as text in SQLite, so select a day of slack and
+	// apply the exact cutoff to the parsed times below
+	raw, err := queryRawWeather(ctx, tx,
+		"SELECT id, latitude, longitude, forecast, temp_c, temp_f, timestamp FROM weather_history WHERE timestamp < ?",
+		cutoff.Add(24*time.Hour),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	buckets, ids := foldRawWeather(raw, cutoff.Format(DayFormat))
+	for key, agg := range buckets {
+		existing, err := getWeatherDaily(tx, key)
+		if err != nil {
+			return 0, err
+		}
+		if existing != nil {
+			agg.merge(existing)
+		}
+		if err := saveWeatherDaily(tx, key, agg); err != nil {
+			return 0, err
+		}
+	}
+
+	del, err := tx.PrepareContext(ctx, "DELETE FROM weather_history WHERE id = ?")
+	if err != nil {
+		return 0, err
+	}
+	defer del.Close()
+	for _, id := range ids {
+		if _, err := del.ExecContext(ctx, id); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), nil
+}
+
+// getWeatherDaily loads a stored daily summary as an aggregate, or nil when there is none
+func getWeatherDaily(tx *sql.Tx, key dayKey) (*dayAggregate, error) {
+	var samples int
+	var minC, maxC, meanC, minF, maxF, meanF float64
+	var forecasts string
+	err := tx.QueryRow(
+		`SELECT samples, min_temp_c, max_temp_c, mean_temp_c, min_temp_f, max_temp_f, mean_temp_f, forecast_counts
+		FROM weather_daily WHERE latitude = ? AND longitude = ? AND day = ?`,
+		key.lat, key.lon, key.day,
+	).Scan(&samples, &minC, &maxC, &meanC, &minF, &maxF, &meanF, &forecasts)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return storedDayAggregate(key.day, samples, minC, maxC, meanC, minF, maxF, meanF, forecasts)
+}
+
+// saveWeatherDaily writes a daily summary, replacing any earlier one for the day
+func saveWeatherDaily(tx *sql.Tx, key dayKey, agg *dayAggregate) error {
+	forecasts, err := json.Marshal(agg.forecasts)
+	if err != nil {
+		return err
+	}
+	s := agg.summary(key.day)
+	_, err = tx.Exec(
+		`INSERT OR REPLACE INTO weather_daily
+			(latitude, longitude, day, samples, min_temp_c, max_temp_c, mean_temp_c, min_temp_f, max_temp_f, mean_temp_f, forecast, forecast_counts)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		key.lat, key.lon, key.day, s.Samples, s.MinTempC, s.MaxTempC, s.MeanTempC,
+		s.MinTempF, s.MaxTempF, s.MeanTempF, s.Forecast, string(forecasts),
+	)
+	return err
+}
+
+// weatherHistoryRangeQuery reads a coordinate's refreshes over a time range as
+// a range scan of idx_weather_history_coordinate
+const weatherHistoryRangeQuery = `SELECT id, latitude, longitude, forecast, temp_c, temp_f, timestamp FROM weather_history
+	WHERE latitude = ? AND longitude = ? AND timestamp >= ? AND timestamp < ?`
+
+// History implements ForecastStore, reading the summaries and raw rows in one
+// transaction so a concurrent downsample can't move rows between them
+func (s sqliteStore) History(ctx context.Context, lat, lon float64, from, to string) ([]models.WeatherDay, error) {
+	start, err := time.Parse(DayFormat, from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.Parse(DayFormat, to)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT day FROM weather_daily WHERE latitude = ? AND longitude = ? AND day >= ? AND day <= ?",
+		lat, lon, from, to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var days []string
+	for rows.Next() {
+		var day string
+		if err := rows.Scan(&day); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	daily := make(map[string]*dayAggregate, len(days))
+	for _, day := range days {
+		agg, err := getWeatherDaily(tx, dayKey{lat: lat, lon: lon, day: day})
+		if err != nil {
+			return nil, err
+		}
+		daily[day] = agg
+	}
+
+	// A day of slack either side covers rows stored with non-UTC offsets
+	raw, err := queryRawWeather(ctx, tx, weatherHistoryRangeQuery, lat, lon, start.Add(-24*time.Hour), end.Add(48*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	return weatherSeries(daily, raw, from, to), nil
+}
+
+// Ping implements ForecastStore
+func (s sqliteStore) Ping(ctx context.Context) error {
+	var tables int
+	return s.db.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master").Scan(&tables)
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// testForecastStore checks the behavior every ForecastStore shares. The store
+// must start empty.
+func testForecastStore(t *testing.T, store ForecastStore) {
+	ctx := context.Background()
+	noPrecipitation, humidity, dewpointC, dewpointF, night := 0.0, 65.0, 12.0, 53.6, false
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	yesterday := today.Add(-24 * time.Hour)
+	save := func(lat, lon float64, forecast string, tempC float64, ts time.Time) {
+		t.Helper()
+		err := store.SaveForecast(ctx, &models.WeatherCache{
+			Latitude: lat, Longitude: lon, Forecast: forecast, TempC: tempC, TempF: tempC*9/5 + 32,
+			Timestamp: ts, City: "New York", State: "NY", TimeZone: "America/New_York", Provider: "nws", DetailedForecast: forecast + ", with a high near 70.",
+			WindKmh: &models.SpeedRange{Min: 8.04672, Max: 16.09344}, WindMph: &models.SpeedRange{Min: 5, Max: 10}, WindDirection: "SW",
+			PeriodName: "Tonight", IsDaytime: &night, Icon: "https://api.weather.gov/icons/land/night/rain,40?size=medium",
+			PrecipitationProbability: &noPrecipitation, RelativeHumidity: &humidity, DewpointC: &dewpointC, DewpointF: &dewpointF,
+			GridID: "OKX", GridX: 33, GridY: 35,
+		})
+		if err != nil {
+			t.Fatalf("SaveForecast: %v", err)
+		}
+	}
+
+	if _, err := store.LatestForecast(ctx, 40.713, -74.006); err != sql.ErrNoRows {
+		t.Fatalf("LatestForecast on an empty store error = %v; want sql.ErrNoRows", err)
+	}
+
+	save(40.713, -74.006, "Rain", 10, yesterday.Add(6*time.Hour))
+	save(40.713, -74.006, "Sunny", 20, yesterday.Add(18*time.Hour))
+	save(40.713, -74.006, "Cloudy", 15, today.Add(time.Hour))
+	save(34.052, -118.244, "Clear", 25, today.Add(2*time.Hour))
+
+	// The latest refresh replaces the coordinate's entry
+	latest, err := store.LatestForecast(ctx, 40.713, -74.006)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Forecast != "Cloudy" || latest.TempC != 15 || latest.City != "New York" || latest.TimeZone != "America/New_York" || latest.Provider != "nws" ||
+		latest.DetailedForecast != "Cloudy, with a high near 70." || latest.Source != store.Name() ||
+		latest.WindMph == nil || *latest.WindMph != (models.SpeedRange{Min: 5, Max: 10}) ||
+		latest.WindKmh == nil || latest.WindKmh.Max != 16.09344 || latest.WindDirection != "SW" ||
+		latest.PrecipitationProbability == nil || *latest.PrecipitationProbability != 0 ||
+		latest.RelativeHumidity == nil || *latest.RelativeHumidity != 65 ||
+		latest.PeriodName != "Tonight" || latest.IsDaytime == nil || *latest.IsDaytime ||
+		latest.Icon != "https://api.weather.gov/icons/land/night/rain,40?size=medium" || latest.DewpointF == nil || *latest.DewpointF != 53.6 || !latest.Timestamp.Equal(today.Add(time.Hour)) ||
+		latest.GridID != "OKX" || latest.GridX != 33 || latest.GridY != 35 {
+		t.Errorf("LatestForecast = %+v; want the Cloudy refresh from %s", latest, store.Name())
+	}
+	if latest.UVIndex != nil {
+		t.Errorf("LatestForecast UV index = %v; want none before it is looked up", *latest.UVIndex)
+	}
+
+	// Saving the refresh again with its UV index updates the entry; the
+	// history checks below show it isn't counted twice
+	uv := 7.0
+	withUV := *latest
+	withUV.UVIndex = &uv
+	if err := store.SaveForecast(ctx, &withUV); err != nil {
+		t.Fatal(err)
+	}
+	if latest, err := store.LatestForecast(ctx, 40.713, -74.006); err != nil || latest.UVIndex == nil || *latest.UVIndex != 7 {
+		t.Errorf("LatestForecast after adding the UV index = %+v, %v; want UV index 7", latest, err)
+	}
+
+	// A nearby lookup finds the New York entry 0.78 km south, unless it is too
+	// far or too old
+	nearest, err := store.NearestForecast(ctx, 40.720, -74.006, 2.5, today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nearest.Forecast != "Cloudy" || nearest.DetailedForecast != "Cloudy, with a high near 70." || nearest.Latitude != 40.713 || nearest.Longitude != -74.006 ||
+		math.Abs(nearest.DistanceKm-0.778) > 0.01 || nearest.Source != store.Name() || nearest.GridID != "OKX" {
+		t.Errorf("NearestForecast = %+v; want the Cloudy refresh 0.778 km away from %s", nearest, store.Name())
+	}
+	if _, err := store.NearestForecast(ctx, 40.720, -74.006, 0.5, today); err != sql.ErrNoRows {
+		t.Errorf("NearestForecast beyond the radius error = %v; want sql.ErrNoRows", err)
+	}
+	if _, err := store.NearestForecast(ctx, 40.720, -74.006, 2.5, today.Add(2*time.Hour)); err != sql.ErrNoRows {
+		t.Errorf("NearestForecast of an older entry error = %v; want sql.ErrNoRows", err)
+	}
+
+	locations, total, err := store.ListForecasts(ctx, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(locations) != 1 || locations[0].Latitude != 34.052 {
+		t.Errorf("ListForecasts = %+v, %d; want the Los Angeles entry first of 2", locations, total)
+	}
+
+	from, to := yesterday.Format(DayFormat), today.Format(DayFormat)
+	want := []models.WeatherDay{
+		{Day: from, MinTempC: 10, MaxTempC: 20, MeanTempC: 15, MinTempF: 50, MaxTempF: 68, MeanTempF: 59, Forecast: "Rain", Samples: 2},
+		{Day: to, MinTempC: 15, MaxTempC: 15, MeanTempC: 15, MinTempF: 59, MaxTempF: 59, MeanTempF: 59, Forecast: "Cloudy", Samples: 1},
+	}
+	checkHistory := func(stage string) {
+		t.Helper()
+		history, err := store.History(ctx, 40.713, -74.006, from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != len(want) {
+			t.Fatalf("History %s = %+v; want %+v", stage, history, want)
+		}
+		for i := range want {
+			if history[i] != want[i] {
+				t.Errorf("History %s day %d = %+v; want %+v", stage, i, history[i], want[i])
+			}
+		}
+	}
+	checkHistory("before downsampling")
+
+	// Yesterday's two refreshes fold into a summary; the series doesn't change
+	n, err := store.DownsampleHistory(ctx, today.Add(12*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("DownsampleHistory folded %d refreshes; want 2", n)
+	}
+	checkHistory("after downsampling")
+	if n, err := store.DownsampleHistory(ctx, today.Add(12*time.Hour)); err != nil || n != 0 {
+		t.Errorf("second DownsampleHistory = %d, %v; want nothing left to fold", n, err)
+	}
+	checkHistory("after downsampling again")
+
+	// Purging drops entries written before the cutoff but keeps their history
+	if n, err := store.PurgeForecasts(ctx, today.Add(90*time.Minute)); err != nil || n != 1 {
+		t.Errorf("PurgeForecasts = %d, %v; want the New York entry removed", n, err)
+	}
+	if _, err := store.LatestForecast(ctx, 40.713, -74.006); err != sql.ErrNoRows {
+		t.Errorf("LatestForecast after purge error = %v; want sql.ErrNoRows", err)
+	}
+	checkHistory("after purging")
+
+	if n, err := store.DeleteForecast(ctx, 34.052, -118.244); err != nil || n != 1 {
+		t.Errorf("DeleteForecast = %d, %v; want 1 row", n, err)
+	}
+	save(34.052, -118.244, "Clear", 25, today.Add(3*time.Hour))
+	if n, err := store.DeleteAllForecasts(ctx); err != nil || n != 1 {
+		t.Errorf("DeleteAllForecasts = %d, %v; want 1 row", n, err)
+	}
+	if _, total, err := store.ListForecasts(ctx, 10, 0); err != nil || total != 0 {
+		t.Errorf("ListForecasts after deleting all = %d, %v; want none", total, err)
+	}
+	if err := store.Ping(ctx); err != nil {
+		t.Errorf("Ping: %v", err)
+	}
+}
+
+func TestSQLiteStore(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	testForecastStore(t, sqliteStore{db: db})
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"weather-api-go/internal/tracing"
+)
+
+var tracer = otel.Tracer("weather-api-go/internal/repository")
+
+// startSpan starts a client span for a cache operation under ctx
+func (r *WeatherRepository) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) trace.Span {
+	_, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return span
+}
+
+// endLookup ends a cache read span, recording whether it hit. A missing row
+// is a miss rather than an error.
+func endLookup(span trace.Span, err error) {
+	span.SetAttributes(tracing.CacheHitKey.Bool(err == nil))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
+
+// gridAttributes returns the span attributes for an NWS grid cell
+func gridAttributes(gridID string, gridX, gridY int) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("nws.grid_id", gridID),
+		attribute.Int("nws.grid_x", gridX),
+		attribute.Int("nws.grid_y", gridY),
+	}
+}
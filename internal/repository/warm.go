@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"weather-api-go/internal/models"
+)
+
+// ErrWarmLocationNotFound is returned when removing a location that isn't in the warm_locations table
+var ErrWarmLocationNotFound = errors.New("warm location not found")
+
+// ListWarmLocations returns the coordinates added to the warm_locations
+// table, oldest first
+func (r *WeatherRepository) ListWarmLocations(ctx context.Context) ([]models.Coordinates, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT latitude, longitude FROM warm_locations ORDER BY created_at, rowid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locations []models.Coordinates
+	for rows.Next() {
+		var loc models.Coordinates
+		if err := rows.Scan(&loc.Latitude, &loc.Longitude); err != nil {
+			return nil, err
+		}
+		locations = append(locations, loc)
+	}
+	return locations, rows.Err()
+}
+
+// SaveWarmLocation adds a coordinate to the warm_locations table, normalized
+// so nearby coordinates sharing a cache entry are stored once
+func (r *WeatherRepository) SaveWarmLocation(ctx context.Context, lat, lon float64) error {
+	_, err := r.db.ExecContext(writeContext(ctx),
+		"INSERT OR IGNORE INTO warm_locations (latitude, longitude) VALUES (?, ?)",
+		NormalizeCoordinate(lat), NormalizeCoordinate(lon),
+	)
+	return err
+}
+
+// DeleteWarmLocation removes a coordinate from the warm_locations table,
+// returning ErrWarmLocationNotFound when it isn't there
+func (r *WeatherRepository) DeleteWarmLocation(ctx context.Context, lat, lon float64) error {
+	result, err := r.db.ExecContext(writeContext(ctx),
+		"DELETE FROM warm_locations WHERE latitude = ? AND longitude = ?",
+		NormalizeCoordinate(lat), NormalizeCoordinate(lon),
+	)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrWarmLocationNotFound
+	}
+	return nil
+}
@@ -4,81 +4,361 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
 	"time"
 
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/redis/go-redis/v9"
+	"weather-api-go/internal/lru"
 	"weather-api-go/internal/models"
+	"weather-api-go/internal/pubsub"
+	"weather-api-go/internal/tracing"
 )
 
-var ctx = context.Background()
-
 // WeatherRepository handles weather data persistence
 type WeatherRepository struct {
 	db  *sql.DB
 	rdb *redis.Client
+	// store keeps per-coordinate forecasts and their history; SQLite in db
+	// unless configured with WithForecastStore
+	store ForecastStore
+	// cacheTTL is how long weather forecasts are fresh, and their Redis expiry
+	cacheTTL time.Duration
+	// updates announces each successful SaveToCache to the coordinate's subscribers
+	updates *pubsub.Hub[models.WeatherCache]
+	// memory holds recent forecasts in front of SQLite when Redis isn't
+	// configured; nil when disabled
+	memory *lru.Cache[string, models.WeatherCache]
+	// memoryEntries and memoryTTL size the memory tier; see WithMemoryCache
+	memoryEntries int
+	memoryTTL     time.Duration
+	// nearbyRadiusKm is how far GetFromCache looks for a fresh entry at another
+	// coordinate when the requested one has none; zero disables it
+	nearbyRadiusKm float64
+}
+
+// Cache tiers an entry can be read from
+const (
+	SourceMemory   = "memory"
+	SourceRedis    = "redis"
+	SourceSQLite   = "sqlite"
+	SourcePostgres = "postgres"
+)
+
+// WeatherRepositoryOption configures optional WeatherRepository behavior
+type WeatherRepositoryOption func(*WeatherRepository)
+
+// WithCacheTTL sets how long cached weather forecasts are fresh, both for
+// IsCacheFresh and as their Redis expiry. Non-positive values keep the default.
+func WithCacheTTL(ttl time.Duration) WeatherRepositoryOption {
+	return func(r *WeatherRepository) {
+		if ttl > 0 {
+			r.cacheTTL = ttl
+		}
+	}
+}
+
+// WithForecastStore keeps per-coordinate forecasts and their history in store
+// rather than the SQLite cache database
+func WithForecastStore(store ForecastStore) WeatherRepositoryOption {
+	return func(r *WeatherRepository) {
+		r.store = store
+	}
+}
+
+// DefaultMemoryCacheSize is how many forecasts the memory tier holds by default
+const DefaultMemoryCacheSize = 10000
+
+// WithMemoryCache keeps up to maxEntries forecasts in an in-process LRU in
+// front of SQLite, each expiring ttl after it is written like a Redis key. A
+// non-positive ttl uses the cache TTL. It only applies when Redis isn't
+// configured; a non-positive maxEntries disables it.
+func WithMemoryCache(maxEntries int, ttl time.Duration) WeatherRepositoryOption {
+	return func(r *WeatherRepository) {
+		r.memoryEntries, r.memoryTTL = maxEntries, ttl
+	}
+}
+
+// DefaultNearbyCacheRadiusKm is how far GetFromCache looks for a nearby fresh
+// forecast by default: the NWS's 2.5 km grid resolution, within which points
+// usually share a forecast
+const DefaultNearbyCacheRadiusKm = 2.5
+
+// WithNearbyCacheRadius sets how far GetFromCache looks for a fresh forecast
+// cached for another coordinate when the requested one has none. Zero
+// disables the lookup; negative values keep the default.
+func WithNearbyCacheRadius(km float64) WeatherRepositoryOption {
+	return func(r *WeatherRepository) {
+		if km >= 0 {
+			r.nearbyRadiusKm = km
+		}
+	}
 }
 
 // NewWeatherRepository creates a new weather repository
-func NewWeatherRepository(db *sql.DB, rdb *redis.Client) *WeatherRepository {
-	return &WeatherRepository{
-		db:  db,
-		rdb: rdb,
+func NewWeatherRepository(db *sql.DB, rdb *redis.Client, opts ...WeatherRepositoryOption) *WeatherRepository {
+	r := &WeatherRepository{
+		db:             db,
+		rdb:            rdb,
+		store:          sqliteStore{db: db},
+		cacheTTL:       DefaultWeatherCacheTTL,
+		updates:        pubsub.NewHub[models.WeatherCache](),
+		nearbyRadiusKm: DefaultNearbyCacheRadiusKm,
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	if rdb == nil && r.memoryEntries > 0 {
+		ttl := r.memoryTTL
+		if ttl <= 0 {
+			ttl = r.cacheTTL
+		}
+		r.memory = lru.New[string, models.WeatherCache](r.memoryEntries, ttl)
+	}
+	return r
+}
+
+// writeContext returns the context cache writes run under: ctx without its
+// cancellation, so data already fetched upstream is kept even when the
+// request that fetched it has given up
+func writeContext(ctx context.Context) context.Context {
+	return context.WithoutCancel(ctx)
 }
 
-// GetFromCache retrieves weather data from cache (Redis first, then SQLite)
-func (r *WeatherRepository) GetFromCache(lat, lon float64) (*models.WeatherCache, error) {
+// CacheCoordinatePrecision is the number of decimal places weather is cached
+// at. Three decimals (~110 m) fold full-precision GPS fixes of one spot into a
+// single entry while staying well inside the NWS's 2.5 km grid cells.
+const CacheCoordinatePrecision = 3
+
+// NormalizeCoordinate rounds a latitude or longitude to CacheCoordinatePrecision,
+// the form weather is cached and looked up under
+func NormalizeCoordinate(v float64) float64 {
+	return math.Round(v*1e3) / 1e3
+}
+
+// Coordinates is a requested coordinate parsed once, by the handler, and
+// passed through the service into the cache, so the normalized coordinate and
+// the keys derived from it aren't recomputed at each layer. Build one with
+// NewCoordinates.
+type Coordinates struct {
+	// Latitude and Longitude are the coordinate as requested
+	Latitude, Longitude float64
+	// CacheLatitude and CacheLongitude are rounded to CacheCoordinatePrecision
+	CacheLatitude, CacheLongitude float64
+	// Key is "lat:lon" at CacheCoordinatePrecision, identifying the cache entry
+	Key string
+
+	// cacheKey is the entry's Redis and memory tier key
+	cacheKey string
+}
+
+// NewCoordinates normalizes a coordinate and derives its cache keys
+func NewCoordinates(lat, lon float64) Coordinates {
+	c := Coordinates{
+		Latitude:       lat,
+		Longitude:      lon,
+		CacheLatitude:  NormalizeCoordinate(lat),
+		CacheLongitude: NormalizeCoordinate(lon),
+	}
+	b := strconv.AppendFloat(make([]byte, 0, 32), c.CacheLatitude, 'f', CacheCoordinatePrecision, 64)
+	b = append(b, ':')
+	b = strconv.AppendFloat(b, c.CacheLongitude, 'f', CacheCoordinatePrecision, 64)
+	c.Key = string(b)
+	c.cacheKey = coordinateKey("weather:", c.CacheLatitude, c.CacheLongitude)
+	return c
+}
+
+// GetFromCache retrieves weather data from cache (Redis or the memory tier
+// first, then the forecast store). Coordinates are normalized, so nearby
+// inputs share an entry. When the store has no fresh entry for the
+// coordinate, the nearest fresh one within the nearby cache radius is
+// returned instead, with its own coordinates and DistanceKm set.
+func (r *WeatherRepository) GetFromCache(ctx context.Context, lat, lon float64) (*models.WeatherCache, error) {
+	return r.GetFromCacheAt(ctx, NewCoordinates(lat, lon))
+}
+
+// GetFromCacheAt is GetFromCache for coordinates already parsed with
+// NewCoordinates
+func (r *WeatherRepository) GetFromCacheAt(ctx context.Context, c Coordinates) (*models.WeatherCache, error) {
+	lat, lon := c.CacheLatitude, c.CacheLongitude
+
 	// Try Redis first
 	if r.rdb != nil {
-		key := fmt.Sprintf("weather:%.6f:%.6f", lat, lon)
-		data, err := r.rdb.Get(ctx, key).Result()
-		if err == nil {
-			var cache models.WeatherCache
-			if err := json.Unmarshal([]byte(data), &cache); err == nil {
-				return &cache, nil
-			}
+		var cache models.WeatherCache
+		if r.getJSON(ctx, c.cacheKey, &cache) {
+			cache.Source = SourceRedis
+			cache.Latitude, cache.Longitude = lat, lon
+			return &cache, nil
 		}
 	}
+	if r.memory != nil {
+		if cache, ok := r.memory.Get(c.cacheKey); ok {
+			cache.Source = SourceMemory
+			return &cache, nil
+		}
+	}
+
+	// Fallback to the forecast store
+	tier := r.store.Name()
+	span := r.startSpan(ctx, tier+".query", append(tracing.Coordinate(lat, lon), tracing.CacheTierKey.String(tier))...)
+	cache, err := r.store.LatestForecast(ctx, lat, lon)
+	endLookup(span, err)
+	if (err != nil && !errors.Is(err, sql.ErrNoRows)) || (err == nil && r.IsCacheFresh(cache)) {
+		return cache, err
+	}
+	if nearby := r.nearestFresh(ctx, lat, lon); nearby != nil {
+		return nearby, nil
+	}
+	return cache, err
+}
+
+// nearestFresh returns the store's nearest fresh entry within the nearby
+// cache radius of a normalized coordinate, or nil
+func (r *WeatherRepository) nearestFresh(ctx context.Context, lat, lon float64) *models.WeatherCache {
+	if r.nearbyRadiusKm <= 0 {
+		return nil
+	}
+	tier := r.store.Name()
+	span := r.startSpan(ctx, tier+".nearby", append(tracing.Coordinate(lat, lon), tracing.CacheTierKey.String(tier))...)
+	cache, err := r.store.NearestForecast(ctx, lat, lon, r.nearbyRadiusKm, time.Now().Add(-r.cacheTTL))
+	endLookup(span, err)
+	if err != nil {
+		return nil
+	}
+	return cache
+}
+
+// SaveToCache saves weather data to cache (Redis or the memory tier, and the
+// forecast store) under its normalized coordinates. The store keeps one entry
+// per coordinate, overwritten on each refresh, and appends the refresh to the
+// coordinate's history. Once the write commits, the memory tier and the
+// coordinate's SubscribeCache subscribers get it.
+func (r *WeatherRepository) SaveToCache(ctx context.Context, weather *models.WeatherCache) (err error) {
+	lat, lon := NormalizeCoordinate(weather.Latitude), NormalizeCoordinate(weather.Longitude)
+	span := r.startSpan(ctx, "cache.save", tracing.Coordinate(lat, lon)...)
+	defer func() { tracing.End(span, err) }()
+
+	// Cache in Redis
+	if r.rdb != nil {
+		r.setJSON(ctx, coordinateKey("weather:", lat, lon), weather, r.cacheTTL)
+	}
+
+	// Also persist to the store, keeping the data's own timestamp so entries
+	// copied from a grid-cell forecast don't outlive it
+	saved := *weather
+	saved.Latitude, saved.Longitude, saved.Source = lat, lon, ""
+	if saved.Timestamp.IsZero() {
+		saved.Timestamp = time.Now()
+	}
+	if err := r.store.SaveForecast(writeContext(ctx), &saved); err != nil {
+		return err
+	}
+
+	if r.memory != nil {
+		r.memory.Set(coordinateKey("weather:", lat, lon), saved)
+	}
+	r.updates.Publish(coordinateKey("", lat, lon), saved)
+	return nil
+}
+
+// SubscribeCache returns a subscription that receives each entry SaveToCache
+// writes for a coordinate, with normalized coordinates and no Source. A
+// subscriber that falls behind only receives the latest entry. The
+// subscription must be closed when no longer needed.
+func (r *WeatherRepository) SubscribeCache(lat, lon float64) *pubsub.Subscription[models.WeatherCache] {
+	return r.updates.Subscribe(coordinateKey("", NormalizeCoordinate(lat), NormalizeCoordinate(lon)))
+}
+
+// CacheSubscribers returns the number of open SubscribeCache subscriptions to
+// a coordinate's entry
+func (r *WeatherRepository) CacheSubscribers(lat, lon float64) int {
+	return r.updates.Subscribers(coordinateKey("", NormalizeCoordinate(lat), NormalizeCoordinate(lon)))
+}
 
-	// Fallback to SQLite
-	var cache models.WeatherCache
-	err := r.db.QueryRow(
-		"SELECT forecast, temp_c, temp_f, timestamp FROM weather_cache WHERE latitude = ? AND longitude = ? ORDER BY timestamp DESC LIMIT 1",
-		lat, lon,
-	).Scan(&cache.Forecast, &cache.TempC, &cache.TempF, &cache.Timestamp)
+// ListCached returns a page of the coordinates in the forecast store, most
+// recently refreshed first, along with the total number of cached coordinates.
+// IsFresh is left for the caller to fill in.
+func (r *WeatherRepository) ListCached(ctx context.Context, limit, offset int) ([]models.CachedLocation, int64, error) {
+	return r.store.ListForecasts(ctx, limit, offset)
+}
+
+// PurgeWeatherCache deletes cached coordinate forecasts written before the
+// given time. Their refreshes stay in the history. Returns the number of rows
+// deleted.
+func (r *WeatherRepository) PurgeWeatherCache(ctx context.Context, before time.Time) (int64, error) {
+	return r.store.PurgeForecasts(writeContext(ctx), before)
+}
+
+// DefaultWeatherCacheTTL is how long a coordinate's or grid cell's forecast is
+// reused unless configured with WithCacheTTL
+const DefaultWeatherCacheTTL = time.Hour
+
+// CacheTTL returns how long cached weather forecasts are fresh
+func (r *WeatherRepository) CacheTTL() time.Duration {
+	return r.cacheTTL
+}
+
+// IsCacheFresh checks if cached data is still fresh (within CacheTTL)
+func (r *WeatherRepository) IsCacheFresh(cache *models.WeatherCache) bool {
+	return time.Since(cache.Timestamp) < r.cacheTTL
+}
+
+// ObservationCacheTTL is how long a station's observation series is reused
+const ObservationCacheTTL = 5 * time.Minute
+
+// LatestObservation is the hours value a station's current conditions are
+// cached under, alongside its observation series
+const LatestObservation = 0
 
+// GetObservations retrieves a cached observation series (Redis first, then SQLite)
+func (r *WeatherRepository) GetObservations(ctx context.Context, stationID string, hours int) (*models.ObservationCache, error) {
+	if r.rdb != nil {
+		var cache models.ObservationCache
+		if r.getJSON(ctx, fmt.Sprintf("observations:%s:%d", stationID, hours), &cache) {
+			return &cache, nil
+		}
+	}
+
+	var payload string
+	cache := models.ObservationCache{StationID: stationID, Hours: hours}
+	err := r.db.QueryRowContext(ctx,
+		"SELECT payload, timestamp FROM observation_cache WHERE station_id = ? AND hours = ?",
+		stationID, hours,
+	).Scan(&payload, &cache.Timestamp)
 	if err != nil {
 		return nil, err
 	}
 
-	cache.Latitude = lat
-	cache.Longitude = lon
+	if err := json.Unmarshal([]byte(payload), &cache.Observations); err != nil {
+		return nil, err
+	}
 	return &cache, nil
 }
 
-// SaveToCache saves weather data to cache (Redis and SQLite)
-func (r *WeatherRepository) SaveToCache(weather *models.WeatherCache) error {
-	// Cache in Redis
+// SaveObservations caches an observation series (Redis and SQLite)
+func (r *WeatherRepository) SaveObservations(ctx context.Context, cache *models.ObservationCache) error {
 	if r.rdb != nil {
-		key := fmt.Sprintf("weather:%.6f:%.6f", weather.Latitude, weather.Longitude)
-		data, err := json.Marshal(weather)
-		if err == nil {
-			r.rdb.Set(ctx, key, data, 1*time.Hour)
-		}
+		r.setJSON(ctx, fmt.Sprintf("observations:%s:%d", cache.StationID, cache.Hours), cache, ObservationCacheTTL)
 	}
 
-	// Also cache in SQLite for persistence
-	_, err := r.db.Exec(
-		"INSERT INTO weather_cache (latitude, longitude, forecast, temp_c, temp_f) VALUES (?, ?, ?, ?, ?)",
-		weather.Latitude, weather.Longitude, weather.Forecast, weather.TempC, weather.TempF,
+	payload, err := json.Marshal(cache.Observations)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(writeContext(ctx),
+		"INSERT OR REPLACE INTO observation_cache (station_id, hours, payload, timestamp) VALUES (?, ?, ?, ?)",
+		cache.StationID, cache.Hours, string(payload), cache.Timestamp.UTC(),
 	)
 	return err
 }
 
-// IsCacheFresh checks if cached data is still fresh (within 1 hour)
-func (r *WeatherRepository) IsCacheFresh(cache *models.WeatherCache) bool {
-	return time.Since(cache.Timestamp) < time.Hour
+// IsObservationCacheFresh checks if a cached observation series is still fresh
+func (r *WeatherRepository) IsObservationCacheFresh(cache *models.ObservationCache) bool {
+	return time.Since(cache.Timestamp) < ObservationCacheTTL
 }
 
 // InitDB initializes the database schema
@@ -97,8 +377,344 @@ func InitDB(dbPath string) (*sql.DB, error) {
 			temp_c REAL,
 			temp_f REAL,
 			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS weather_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			latitude REAL NOT NULL,
+			longitude REAL NOT NULL,
+			forecast TEXT,
+			temp_c REAL,
+			temp_f REAL,
+			timestamp DATETIME NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_weather_history_coordinate ON weather_history (latitude, longitude, timestamp);
+
+		CREATE TABLE IF NOT EXISTS weather_daily (
+			latitude REAL NOT NULL,
+			longitude REAL NOT NULL,
+			day TEXT NOT NULL,
+			samples INTEGER NOT NULL,
+			min_temp_c REAL NOT NULL,
+			max_temp_c REAL NOT NULL,
+			mean_temp_c REAL NOT NULL,
+			min_temp_f REAL NOT NULL,
+			max_temp_f REAL NOT NULL,
+			mean_temp_f REAL NOT NULL,
+			forecast TEXT NOT NULL,
+			forecast_counts TEXT NOT NULL,
+			PRIMARY KEY (latitude, longitude, day)
+		);
+
+		CREATE TABLE IF NOT EXISTS observation_cache (
+			station_id TEXT NOT NULL,
+			hours INTEGER NOT NULL,
+			payload TEXT NOT NULL,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (station_id, hours)
+		);
+
+		CREATE TABLE IF NOT EXISTS point_metadata (
+			latitude REAL NOT NULL,
+			longitude REAL NOT NULL,
+			forecast_zone TEXT NOT NULL,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (latitude, longitude)
+		);
+
+		CREATE TABLE IF NOT EXISTS nearest_stations (
+			latitude REAL NOT NULL,
+			longitude REAL NOT NULL,
+			station_id TEXT NOT NULL,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (latitude, longitude)
+		);
+
+		CREATE TABLE IF NOT EXISTS uncovered_points (
+			latitude REAL NOT NULL,
+			longitude REAL NOT NULL,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (latitude, longitude)
+		);
+
+		CREATE TABLE IF NOT EXISTS grid_points (
+			latitude REAL NOT NULL,
+			longitude REAL NOT NULL,
+			grid_id TEXT NOT NULL,
+			grid_x INTEGER NOT NULL,
+			grid_y INTEGER NOT NULL,
+			forecast_url TEXT NOT NULL,
+			forecast_hourly_url TEXT,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (latitude, longitude)
+		);
+
+		CREATE TABLE IF NOT EXISTS grid_forecast_cache (
+			grid_id TEXT NOT NULL,
+			grid_x INTEGER NOT NULL,
+			grid_y INTEGER NOT NULL,
+			forecast TEXT,
+			temp_c REAL,
+			temp_f REAL,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (grid_id, grid_x, grid_y)
+		);
+
+		CREATE TABLE IF NOT EXISTS forecast_periods (
+			kind TEXT NOT NULL,
+			grid_id TEXT NOT NULL,
+			grid_x INTEGER NOT NULL,
+			grid_y INTEGER NOT NULL,
+			payload TEXT NOT NULL,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (kind, grid_id, grid_x, grid_y)
+		);
+
+		CREATE TABLE IF NOT EXISTS raw_documents (
+			kind TEXT NOT NULL,
+			key TEXT NOT NULL,
+			content_type TEXT NOT NULL,
+			body BLOB NOT NULL,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (kind, key)
+		);
+
+		CREATE TABLE IF NOT EXISTS alert_cache (
+			zone TEXT PRIMARY KEY,
+			payload TEXT NOT NULL,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS geocode_cache (
+			query TEXT PRIMARY KEY,
+			places TEXT NOT NULL,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS air_quality_cache (
+			latitude REAL NOT NULL,
+			longitude REAL NOT NULL,
+			payload TEXT NOT NULL,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (latitude, longitude)
+		);
+
+		CREATE TABLE IF NOT EXISTS request_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			day TEXT NOT NULL,
+			timestamp DATETIME NOT NULL,
+			route TEXT NOT NULL,
+			status INTEGER NOT NULL,
+			latency_ms REAL NOT NULL,
+			coordinate TEXT,
+			cache_hit BOOLEAN
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_request_log_day ON request_log (day);
+
+		CREATE TABLE IF NOT EXISTS daily_stats (
+			day TEXT PRIMARY KEY,
+			total_requests INTEGER NOT NULL,
+			route_counts TEXT NOT NULL,
+			client_error_count INTEGER NOT NULL,
+			error_count INTEGER NOT NULL,
+			cache_hit_ratio REAL,
+			distinct_coordinates INTEGER NOT NULL,
+			p50_latency_ms REAL NOT NULL,
+			p95_latency_ms REAL NOT NULL,
+			updated_at DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id TEXT PRIMARY KEY,
+			key_hash TEXT NOT NULL UNIQUE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS api_key_usage (
+			key_id TEXT NOT NULL,
+			day TEXT NOT NULL,
+			requests INTEGER NOT NULL,
+			PRIMARY KEY (key_id, day)
+		);
+
+		CREATE TABLE IF NOT EXISTS alert_seen (
+			alert_id TEXT PRIMARY KEY,
+			zone TEXT NOT NULL,
+			fingerprint TEXT NOT NULL,
+			sent DATETIME NOT NULL,
+			expires DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+			id TEXT PRIMARY KEY,
+			callback_url TEXT NOT NULL,
+			latitude REAL NOT NULL,
+			longitude REAL NOT NULL,
+			min_severity TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			owner TEXT NOT NULL DEFAULT ''
+		);
+
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			subscription_id TEXT NOT NULL,
+			alert_id TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			next_attempt_at DATETIME,
+			created_at DATETIME NOT NULL,
+			delivered_at DATETIME,
+			UNIQUE(subscription_id, alert_id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+
+		CREATE TABLE IF NOT EXISTS warm_locations (
+			latitude REAL NOT NULL,
+			longitude REAL NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (latitude, longitude)
 		)
 	`)
+	if err != nil {
+		return db, err
+	}
+
+	// Columns added after their table was first released
+	for _, c := range []struct{ table, column, decl string }{
+		{"grid_points", "forecast_hourly_url", "TEXT"},
+		{"grid_points", "city", "TEXT"},
+		{"grid_points", "state", "TEXT"},
+		{"weather_cache", "city", "TEXT"},
+		{"weather_cache", "state", "TEXT"},
+		{"weather_cache", "provider", "TEXT"},
+		{"weather_cache", "detailed_forecast", "TEXT"},
+		{"grid_forecast_cache", "detailed_forecast", "TEXT"},
+		{"weather_cache", "wind_min_kmh", "REAL"},
+		{"weather_cache", "wind_max_kmh", "REAL"},
+		{"weather_cache", "wind_min_mph", "REAL"},
+		{"weather_cache", "wind_max_mph", "REAL"},
+		{"weather_cache", "wind_direction", "TEXT"},
+		{"grid_forecast_cache", "wind_min_kmh", "REAL"},
+		{"grid_forecast_cache", "wind_max_kmh", "REAL"},
+		{"grid_forecast_cache", "wind_min_mph", "REAL"},
+		{"grid_forecast_cache", "wind_max_mph", "REAL"},
+		{"grid_forecast_cache", "wind_direction", "TEXT"},
+		{"weather_cache", "precipitation_probability", "REAL"},
+		{"grid_forecast_cache", "precipitation_probability", "REAL"},
+		{"weather_cache", "relative_humidity", "REAL"},
+		{"weather_cache", "dewpoint_c", "REAL"},
+		{"weather_cache", "dewpoint_f", "REAL"},
+		{"grid_forecast_cache", "relative_humidity", "REAL"},
+		{"grid_forecast_cache", "dewpoint_c", "REAL"},
+		{"grid_forecast_cache", "dewpoint_f", "REAL"},
+		{"weather_cache", "period_name", "TEXT"},
+		{"weather_cache", "is_daytime", "BOOLEAN"},
+		{"grid_forecast_cache", "period_name", "TEXT"},
+		{"grid_forecast_cache", "is_daytime", "BOOLEAN"},
+		{"weather_cache", "icon", "TEXT"},
+		{"grid_forecast_cache", "icon", "TEXT"},
+		{"grid_points", "time_zone", "TEXT"},
+		{"weather_cache", "time_zone", "TEXT"},
+		{"weather_cache", "uv_index", "REAL"},
+		{"grid_points", "forecast_zone", "TEXT"},
+		{"grid_points", "county", "TEXT"},
+		{"grid_points", "radar_station", "TEXT"},
+		{"weather_cache", "temperature_trend", "TEXT"},
+		{"grid_forecast_cache", "temperature_trend", "TEXT"},
+		{"weather_cache", "temperature_change_c", "REAL"},
+		{"webhook_subscriptions", "owner", "TEXT NOT NULL DEFAULT ''"},
+		{"weather_cache", "grid_id", "TEXT"},
+		{"weather_cache", "grid_x", "INTEGER"},
+		{"weather_cache", "grid_y", "INTEGER"},
+		{"api_keys", "label", "TEXT NOT NULL DEFAULT ''"},
+		{"api_keys", "daily_quota", "INTEGER NOT NULL DEFAULT 0"},
+	} {
+		if err := addColumn(db, c.table, c.column, c.decl); err != nil {
+			return db, err
+		}
+	}
 
-	return db, err
+	// Rows cached before coordinates were normalized are rounded so lookups
+	// find them. A daily summary whose rounded coordinate already has one for
+	// that day keeps its original coordinate.
+	if _, err := db.Exec(`
+		UPDATE weather_cache SET latitude = round(latitude, 3), longitude = round(longitude, 3)
+			WHERE latitude != round(latitude, 3) OR longitude != round(longitude, 3);
+		UPDATE OR IGNORE weather_daily SET latitude = round(latitude, 3), longitude = round(longitude, 3)
+			WHERE latitude != round(latitude, 3) OR longitude != round(longitude, 3)
+	`); err != nil {
+		return db, err
+	}
+
+	if err := collapseWeatherCache(db); err != nil {
+		return db, err
+	}
+
+	// Incremental auto-vacuum lets size-based pruning return freed pages to the
+	// filesystem. Existing databases need a one-off VACUUM to switch modes.
+	var autoVacuum int
+	if err := db.QueryRow("PRAGMA auto_vacuum").Scan(&autoVacuum); err != nil {
+		return db, err
+	}
+	if autoVacuum != autoVacuumIncremental {
+		if _, err := db.Exec("PRAGMA auto_vacuum = INCREMENTAL; VACUUM"); err != nil {
+			return db, err
+		}
+	}
+
+	return db, nil
+}
+
+// collapseWeatherCache migrates databases from before weather_cache was keyed
+// by coordinate, when every refresh added a row: all rows are copied into
+// weather_history, only the newest per coordinate is kept, and the unique index
+// the upsert in SaveToCache relies on is created. It is a no-op once the index exists.
+func collapseWeatherCache(db *sql.DB) error {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_weather_cache_coordinate'").Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO weather_history (latitude, longitude, forecast, temp_c, temp_f, timestamp)
+			SELECT latitude, longitude, forecast, temp_c, temp_f, COALESCE(timestamp, CURRENT_TIMESTAMP) FROM weather_cache ORDER BY id;
+		DELETE FROM weather_cache WHERE id NOT IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY latitude, longitude ORDER BY timestamp DESC, id DESC) AS rank
+				FROM weather_cache
+			) WHERE rank = 1
+		);
+		CREATE UNIQUE INDEX idx_weather_cache_coordinate ON weather_cache (latitude, longitude)
+	`)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// autoVacuumIncremental is the PRAGMA auto_vacuum value for INCREMENTAL mode
+const autoVacuumIncremental = 2
+
+// addColumn adds a column to a table created by an earlier version, if missing
+func addColumn(db *sql.DB, table, column, decl string) error {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl))
+	return err
 }
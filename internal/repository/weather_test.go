@@ -0,0 +1,770 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"weather-api-go/internal/models"
+)
+
+// BenchmarkGetFromCache measures a coordinate cache hit in each tier, for
+// coordinates parsed once up front as the /weather handler does
+func BenchmarkGetFromCache(b *testing.B) {
+	mr := miniredis.RunT(b)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	b.Cleanup(func() { rdb.Close() })
+
+	db, err := InitDB(b.TempDir() + "/bench.db")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+
+	for _, tc := range []struct {
+		name string
+		repo *WeatherRepository
+	}{
+		{"redis", NewWeatherRepository(db, rdb)},
+		{"sqlite", NewWeatherRepository(db, nil)},
+		{"memory", NewWeatherRepository(db, nil, WithMemoryCache(1000, 0))},
+	} {
+		err := tc.repo.SaveToCache(context.Background(), &models.WeatherCache{
+			Latitude: 40.7128, Longitude: -74.006, Forecast: "Partly Cloudy",
+			TempC: 22.2, TempF: 72, Timestamp: time.Now(),
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		coords := NewCoordinates(40.7128, -74.006)
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := tc.repo.GetFromCacheAt(context.Background(), coords); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(tc.name+"/parallel", func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := tc.repo.GetFromCacheAt(context.Background(), coords); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkCacheLookupLargeTable measures SQLite lookups against 100k cached
+// coordinates and 100k history rows; indexed lookups stay flat as tables grow
+func BenchmarkCacheLookupLargeTable(b *testing.B) {
+	db, err := InitDB(b.TempDir() + "/bench.db")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+	repo := NewWeatherRepository(db, nil)
+
+	const rows = 100000
+	tx, err := db.Begin()
+	if err != nil {
+		b.Fatal(err)
+	}
+	now := time.Now().UTC()
+	for i := 0; i < rows; i++ {
+		lat, lon := 25+float64(i/1000)*0.01, -120+float64(i%1000)*0.01
+		if _, err := tx.Exec("INSERT INTO weather_cache (latitude, longitude, forecast, temp_c, temp_f, timestamp) VALUES (?, ?, 'Sunny', 20, 68, ?)",
+			NormalizeCoordinate(lat), NormalizeCoordinate(lon), now); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := tx.Exec("INSERT INTO weather_history (latitude, longitude, forecast, temp_c, temp_f, timestamp) VALUES (?, ?, 'Sunny', 20, 68, ?)",
+			40.713, -74.006-float64(i%100)*0.001, now.Add(-time.Duration(i)*time.Minute)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("cache", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := repo.GetFromCache(context.Background(), 25.5, -115); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("history", func(b *testing.B) {
+		today := now.Format(DayFormat)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := repo.GetWeatherHistory(context.Background(), 40.713, -74.006, today, today); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestCacheLookupsUseIndexes(t *testing.T) {
+	repo := newTestRepository(t)
+
+	for _, tc := range []struct {
+		name, query, index string
+		args               []interface{}
+	}{
+		{"weather_cache", cachedWeatherQuery, "idx_weather_cache_coordinate", []interface{}{40.713, -74.006}},
+		{"nearby weather_cache", nearbyWeatherQuery, "idx_weather_cache_coordinate", []interface{}{40.69, 40.74, -74.04, -73.98, time.Now().Add(-time.Hour)}},
+		{"weather_history", weatherHistoryRangeQuery, "idx_weather_history_coordinate", []interface{}{40.713, -74.006, time.Now().Add(-time.Hour), time.Now()}},
+	} {
+		rows, err := repo.db.Query("EXPLAIN QUERY PLAN "+tc.query, tc.args...)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		var plan []string
+		for rows.Next() {
+			var id, parent, notUsed int
+			var detail string
+			if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+				t.Fatal(err)
+			}
+			plan = append(plan, detail)
+		}
+		rows.Close()
+
+		if len(plan) != 1 || !strings.Contains(plan[0], "USING INDEX "+tc.index) {
+			t.Errorf("%s lookup plan = %q; want a search using %s", tc.name, plan, tc.index)
+		}
+	}
+}
+
+func TestGetFromCacheNormalizesCoordinates(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	for _, tc := range []struct {
+		name string
+		rdb  *redis.Client
+	}{
+		{"redis", rdb},
+		{"sqlite", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newTestRepository(t)
+			repo.rdb = tc.rdb
+			// Only normalization shares entries here; see TestGetFromCacheNearby
+			repo.nearbyRadiusKm = 0
+			mr.FlushAll()
+
+			err := repo.SaveToCache(context.Background(), &models.WeatherCache{
+				Latitude: 40.71283127, Longitude: -74.00597431, Forecast: "Sunny", Timestamp: time.Now(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.rdb != nil && !mr.Exists("weather:40.713000:-74.006000") {
+				t.Fatalf("Redis keys = %v; want the normalized weather:40.713000:-74.006000", mr.Keys())
+			}
+
+			tests := []struct {
+				name     string
+				lat, lon float64
+				hit      bool
+			}{
+				{"same input", 40.71283127, -74.00597431, true},
+				{"extra precision", 40.71280001, -74.00600001, true},
+				{"a few meters away", 40.7131, -74.0062, true},
+				{"next block", 40.7145, -74.0060, false},
+				{"another city", 34.0522, -118.2437, false},
+			}
+			for _, tt := range tests {
+				cached, err := repo.GetFromCache(context.Background(), tt.lat, tt.lon)
+				if hit := err == nil; hit != tt.hit {
+					t.Errorf("%s: GetFromCache(%v, %v) hit = %v; want %v", tt.name, tt.lat, tt.lon, hit, tt.hit)
+					continue
+				}
+				if tt.hit && (cached.Latitude != 40.713 || cached.Longitude != -74.006) {
+					t.Errorf("%s: entry coordinates = %v,%v; want the normalized 40.713,-74.006", tt.name, cached.Latitude, cached.Longitude)
+				}
+			}
+		})
+	}
+
+	if got := NormalizeCoordinate(-74.00551); got != -74.006 {
+		t.Errorf("NormalizeCoordinate(-74.00551) = %v; want -74.006", got)
+	}
+	c := NewCoordinates(40.71283127, -74.00597431)
+	if c.Latitude != 40.71283127 || c.Longitude != -74.00597431 || c.CacheLatitude != 40.713 || c.CacheLongitude != -74.006 ||
+		c.Key != "40.713:-74.006" || c.cacheKey != "weather:40.713000:-74.006000" {
+		t.Errorf("NewCoordinates = %+v; want the requested and normalized coordinates with their keys", c)
+	}
+}
+
+func TestGetFromCacheNearby(t *testing.T) {
+	repo := newTestRepository(t)
+	save := func(lat, lon float64, forecast string, ts time.Time) {
+		t.Helper()
+		if err := repo.SaveToCache(context.Background(), &models.WeatherCache{Latitude: lat, Longitude: lon, Forecast: forecast, Timestamp: ts}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save(40.713, -74.006, "Sunny", time.Now())
+	save(40.800, -74.006, "Stale", time.Now().Add(-2*DefaultWeatherCacheTTL))
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		forecast string // empty for a miss
+		km       float64
+	}{
+		{"exact coordinate", 40.713, -74.006, "Sunny", 0},
+		{"within the radius", 40.720, -74.006, "Sunny", 0.778},
+		{"diagonal within the radius", 40.725, -73.990, "Sunny", 1.902},
+		{"outside the radius", 40.750, -74.006, "", 0},
+		{"inside the box but outside the radius", 40.733, -73.981, "", 0},
+		{"stale own entry", 40.800, -74.006, "Stale", 0},
+		{"near only a stale entry", 40.805, -74.006, "", 0},
+	}
+	for _, tt := range tests {
+		cached, err := repo.GetFromCache(context.Background(), tt.lat, tt.lon)
+		if tt.forecast == "" {
+			if !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("%s: GetFromCache(%v, %v) = %+v, %v; want sql.ErrNoRows", tt.name, tt.lat, tt.lon, cached, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: GetFromCache(%v, %v): %v", tt.name, tt.lat, tt.lon, err)
+			continue
+		}
+		if cached.Forecast != tt.forecast || math.Abs(cached.DistanceKm-tt.km) > 0.01 || cached.Source != SourceSQLite {
+			t.Errorf("%s: GetFromCache(%v, %v) = %q from %s, %.3f km away; want %q from sqlite, %.3f km away",
+				tt.name, tt.lat, tt.lon, cached.Forecast, cached.Source, cached.DistanceKm, tt.forecast, tt.km)
+		}
+		if tt.km > 0 && (cached.Latitude != 40.713 || cached.Longitude != -74.006) {
+			t.Errorf("%s: entry coordinates = %v,%v; want the cached point 40.713,-74.006", tt.name, cached.Latitude, cached.Longitude)
+		}
+	}
+
+	// A stale entry of the coordinate's own gives way to a fresh neighbor
+	save(40.790, -74.006, "Cloudy", time.Now())
+	cached, err := repo.GetFromCache(context.Background(), 40.800, -74.006)
+	if err != nil || cached.Forecast != "Cloudy" || math.Abs(cached.DistanceKm-1.112) > 0.01 {
+		t.Errorf("stale entry with a fresh neighbor = %+v, %v; want Cloudy from 1.112 km away", cached, err)
+	}
+
+	repo.nearbyRadiusKm = 0
+	if _, err := repo.GetFromCache(context.Background(), 40.720, -74.006); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetFromCache with nearby lookups disabled error = %v; want sql.ErrNoRows", err)
+	}
+}
+
+func TestLocationColumnsMigrateLegacyRows(t *testing.T) {
+	// A database written before city, state, and the detailed forecast were recorded
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = legacy.Exec(`
+		CREATE TABLE weather_cache (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			latitude REAL NOT NULL,
+			longitude REAL NOT NULL,
+			forecast TEXT,
+			temp_c REAL,
+			temp_f REAL,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE grid_points (
+			latitude REAL NOT NULL,
+			longitude REAL NOT NULL,
+			grid_id TEXT NOT NULL,
+			grid_x INTEGER NOT NULL,
+			grid_y INTEGER NOT NULL,
+			forecast_url TEXT NOT NULL,
+			forecast_hourly_url TEXT,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (latitude, longitude)
+		);
+		CREATE TABLE grid_forecast_cache (
+			grid_id TEXT NOT NULL,
+			grid_x INTEGER NOT NULL,
+			grid_y INTEGER NOT NULL,
+			forecast TEXT,
+			temp_c REAL,
+			temp_f REAL,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (grid_id, grid_x, grid_y)
+		);
+		INSERT INTO weather_cache (latitude, longitude, forecast, temp_c, temp_f, timestamp)
+			VALUES (40.7357, -74.1724, 'Sunny', 20, 68, datetime('now'));
+		INSERT INTO grid_forecast_cache (grid_id, grid_x, grid_y, forecast, temp_c, temp_f, timestamp)
+			VALUES ('OKX', 28, 35, 'Sunny', 20, 68, datetime('now'));
+		INSERT INTO grid_points (latitude, longitude, grid_id, grid_x, grid_y, forecast_url, timestamp)
+			VALUES (40.7357, -74.1724, 'OKX', 28, 35, 'https://api.weather.gov/gridpoints/OKX/28,35/forecast', datetime('now'));
+	`)
+	legacy.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := InitDB(path)
+	if err != nil {
+		t.Fatalf("InitDB on a legacy database: %v", err)
+	}
+	defer db.Close()
+	repo := NewWeatherRepository(db, nil)
+
+	cached, err := repo.GetFromCache(context.Background(), 40.7357, -74.1724)
+	if err != nil {
+		t.Fatalf("legacy weather row: %v", err)
+	}
+	if cached.Forecast != "Sunny" || cached.City != "" || cached.State != "" || cached.TimeZone != "" || cached.DetailedForecast != "" ||
+		cached.WindKmh != nil || cached.WindMph != nil || cached.WindDirection != "" || cached.PrecipitationProbability != nil ||
+		cached.RelativeHumidity != nil || cached.DewpointC != nil || cached.PeriodName != "" || cached.IsDaytime != nil || cached.Icon != "" {
+		t.Errorf("legacy weather row = %+v; want its forecast and no location, detailed forecast, wind, precipitation, humidity, or period", cached)
+	}
+	if grid, err := repo.GetGridForecast(context.Background(), "OKX", 28, 35); err != nil || grid.Forecast != "Sunny" || grid.DetailedForecast != "" ||
+		grid.WindKmh != nil || grid.PrecipitationProbability != nil || grid.RelativeHumidity != nil || grid.IsDaytime != nil {
+		t.Errorf("legacy grid forecast = %+v, %v; want its forecast and no detailed forecast, wind, or precipitation", grid, err)
+	}
+
+	// A legacy grid mapping is still returned, but expired so it gets refetched
+	point, err := repo.GetGridPoint(context.Background(), 40.7357, -74.1724)
+	if err != nil {
+		t.Fatalf("legacy grid point: %v", err)
+	}
+	if point.GridID != "OKX" || repo.IsGridPointFresh(point) {
+		t.Errorf("legacy grid point = %+v (fresh %v); want an expired OKX mapping", point, repo.IsGridPointFresh(point))
+	}
+
+	// New rows round-trip the location, detailed forecast, wind, and chance of
+	// precipitation, which stay unset when the forecast has none
+	const detailed = "Mostly cloudy, with a high near 61."
+	err = repo.SaveToCache(context.Background(), &models.WeatherCache{
+		Latitude: 40.7357, Longitude: -74.1724, Forecast: "Cloudy", Timestamp: time.Now().Add(time.Second),
+		City: "Newark", State: "NJ", TimeZone: "America/New_York", DetailedForecast: detailed,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached, err := repo.GetFromCache(context.Background(), 40.7357, -74.1724); err != nil || cached.City != "Newark" || cached.State != "NJ" ||
+		cached.TimeZone != "America/New_York" || cached.DetailedForecast != detailed ||
+		cached.WindKmh != nil || cached.WindMph != nil || cached.PrecipitationProbability != nil {
+		t.Errorf("GetFromCache = %+v, %v; want Newark, NJ with the detailed forecast and no wind or precipitation", cached, err)
+	}
+	wind, precipitation, dewpointC, daytime := &models.SpeedRange{Min: 5, Max: 10}, 80.0, 9.5, true
+	err = repo.SaveGridForecast(context.Background(), "OKX", 28, 35, &models.WeatherCache{
+		Forecast: "Cloudy", DetailedForecast: detailed, WindKmh: wind, WindMph: wind, WindDirection: "NW", Timestamp: time.Now(),
+		PrecipitationProbability: &precipitation, DewpointC: &dewpointC, PeriodName: "Friday", IsDaytime: &daytime,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grid, err := repo.GetGridForecast(context.Background(), "OKX", 28, 35); err != nil || grid.DetailedForecast != detailed ||
+		!reflect.DeepEqual(grid.WindKmh, wind) || !reflect.DeepEqual(grid.WindMph, wind) || grid.WindDirection != "NW" ||
+		grid.PrecipitationProbability == nil || *grid.PrecipitationProbability != 80 ||
+		grid.DewpointC == nil || *grid.DewpointC != 9.5 || grid.RelativeHumidity != nil ||
+		grid.PeriodName != "Friday" || grid.IsDaytime == nil || !*grid.IsDaytime {
+		t.Errorf("GetGridForecast = %+v, %v; want the detailed forecast, wind, dewpoint, period, and an 80%% chance of precipitation", grid, err)
+	}
+	err = repo.SaveGridPoint(context.Background(), &models.GridPoint{
+		Latitude: 40.7357, Longitude: -74.1724, GridID: "OKX", GridX: 28, GridY: 35,
+		ForecastURL: "https://api.weather.gov/gridpoints/OKX/28,35/forecast", City: "Newark", State: "NJ", TimeZone: "America/New_York", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if point, err := repo.GetGridPoint(context.Background(), 40.7357, -74.1724); err != nil || point.City != "Newark" ||
+		point.TimeZone != "America/New_York" || !repo.IsGridPointFresh(point) {
+		t.Errorf("GetGridPoint = %+v, %v; want a fresh mapping in Newark, America/New_York", point, err)
+	}
+
+	// A mapping saved before time zones were recorded is expired too
+	if _, err := db.Exec("UPDATE grid_points SET time_zone = NULL"); err != nil {
+		t.Fatal(err)
+	}
+	if point, err := repo.GetGridPoint(context.Background(), 40.7357, -74.1724); err != nil || point.City != "Newark" || repo.IsGridPointFresh(point) {
+		t.Errorf("GetGridPoint = %+v, %v; want an expired mapping in Newark", point, err)
+	}
+}
+
+func TestSaveToCacheKeepsOneRowPerCoordinate(t *testing.T) {
+	repo := newTestRepository(t)
+	start := time.Now().Add(-3 * time.Hour)
+	for i, forecast := range []string{"Sunny", "Cloudy", "Rain"} {
+		err := repo.SaveToCache(context.Background(), &models.WeatherCache{
+			Latitude: 40.7128, Longitude: -74.006, Forecast: forecast, TempC: float64(i),
+			Timestamp: start.Add(time.Duration(i) * time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	counts := map[string]int{}
+	for _, table := range []string{"weather_cache", "weather_history"} {
+		var n int
+		if err := repo.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		counts[table] = n
+	}
+	if counts["weather_cache"] != 1 || counts["weather_history"] != 3 {
+		t.Errorf("row counts = %v; want 1 cached row and 3 history rows", counts)
+	}
+
+	cached, err := repo.GetFromCache(context.Background(), 40.7128, -74.006)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.Forecast != "Rain" || cached.TempC != 2 {
+		t.Errorf("cached = %+v; want the latest refresh", cached)
+	}
+}
+
+func TestSaveToCacheNotifiesSubscribers(t *testing.T) {
+	repo := newTestRepository(t)
+	// Nearby coordinates share a cache entry, so they share its updates
+	sub := repo.SubscribeCache(40.71284, -74.00601)
+	defer sub.Close()
+	other := repo.SubscribeCache(41.8781, -87.6298)
+	defer other.Close()
+	if n := repo.CacheSubscribers(40.713, -74.006); n != 1 {
+		t.Errorf("CacheSubscribers = %d; want 1", n)
+	}
+
+	fetched := time.Now().Add(-time.Minute).UTC()
+	err := repo.SaveToCache(context.Background(), &models.WeatherCache{
+		Latitude: 40.7128, Longitude: -74.006, Forecast: "Sunny", TempC: 20, Timestamp: fetched, Source: "live",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-sub.Updates():
+		want := models.WeatherCache{Latitude: 40.713, Longitude: -74.006, Forecast: "Sunny", TempC: 20, Timestamp: fetched}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("update = %+v; want %+v", got, want)
+		}
+	default:
+		t.Fatal("subscriber got no update after SaveToCache")
+	}
+	select {
+	case got := <-other.Updates():
+		t.Errorf("subscriber to another coordinate got %+v", got)
+	default:
+	}
+
+	// A failed write announces nothing
+	repo.db.Close()
+	if err := repo.SaveToCache(context.Background(), &models.WeatherCache{Latitude: 40.7128, Longitude: -74.006, Forecast: "Rain"}); err == nil {
+		t.Fatal("SaveToCache on a closed database succeeded")
+	}
+	select {
+	case got := <-sub.Updates():
+		t.Errorf("failed write sent update %+v", got)
+	default:
+	}
+}
+
+func TestInitDBCollapsesDuplicateCacheRows(t *testing.T) {
+	// A database written when every refresh added a weather_cache row
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = legacy.Exec(`
+		CREATE TABLE weather_cache (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			latitude REAL NOT NULL,
+			longitude REAL NOT NULL,
+			forecast TEXT,
+			temp_c REAL,
+			temp_f REAL,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		INSERT INTO weather_cache (latitude, longitude, forecast, temp_c, temp_f, timestamp) VALUES
+			(40.713, -74.006, 'Newest', 20, 68, datetime('now', '-1 hour')),
+			(40.713, -74.006, 'Oldest', 18, 64.4, datetime('now', '-3 hours')),
+			(40.713, -74.006, 'Middle', 19, 66.2, datetime('now', '-2 hours')),
+			(34.052, -118.244, 'Only', 25, 77, datetime('now', '-1 hour'));
+	`)
+	legacy.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := InitDB(path)
+	if err != nil {
+		t.Fatalf("InitDB on a legacy database: %v", err)
+	}
+	first.Close()
+	// Reopening must not copy the rows into the history again
+	db, err := InitDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	repo := NewWeatherRepository(db, nil)
+
+	var cached, history int
+	db.QueryRow("SELECT COUNT(*) FROM weather_cache").Scan(&cached)
+	db.QueryRow("SELECT COUNT(*) FROM weather_history").Scan(&history)
+	if cached != 2 || history != 4 {
+		t.Errorf("weather_cache has %d rows and weather_history %d; want 2 and 4", cached, history)
+	}
+
+	if row, err := repo.GetFromCache(context.Background(), 40.713, -74.006); err != nil || row.Forecast != "Newest" {
+		t.Errorf("GetFromCache = %+v, %v; want the newest legacy row", row, err)
+	}
+	if err := repo.SaveToCache(context.Background(), &models.WeatherCache{Latitude: 40.713, Longitude: -74.006, Forecast: "Fresh", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("SaveToCache after the migration: %v", err)
+	}
+	db.QueryRow("SELECT COUNT(*) FROM weather_cache").Scan(&cached)
+	if cached != 2 {
+		t.Errorf("weather_cache has %d rows after a save; want 2", cached)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	tests := []struct {
+		name   string
+		opts   []WeatherRepositoryOption
+		want   time.Duration
+		hourly time.Duration
+	}{
+		{"default", nil, DefaultWeatherCacheTTL, HourlyPeriodsTTL},
+		{"configured", []WeatherRepositoryOption{WithCacheTTL(15 * time.Minute)}, 15 * time.Minute, 15 * time.Minute},
+		{"longer", []WeatherRepositoryOption{WithCacheTTL(3 * time.Hour)}, 3 * time.Hour, HourlyPeriodsTTL},
+		{"zero keeps default", []WeatherRepositoryOption{WithCacheTTL(0)}, DefaultWeatherCacheTTL, HourlyPeriodsTTL},
+		{"negative keeps default", []WeatherRepositoryOption{WithCacheTTL(-time.Minute)}, DefaultWeatherCacheTTL, HourlyPeriodsTTL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewWeatherRepository(db, rdb, tt.opts...)
+			if got := repo.CacheTTL(); got != tt.want {
+				t.Fatalf("CacheTTL() = %v; want %v", got, tt.want)
+			}
+
+			// Freshness flips at the configured TTL
+			for _, c := range []struct {
+				age   time.Duration
+				fresh bool
+			}{
+				{tt.want - time.Second, true},
+				{tt.want + time.Second, false},
+			} {
+				cache := &models.WeatherCache{Timestamp: time.Now().Add(-c.age)}
+				if got := repo.IsCacheFresh(cache); got != c.fresh {
+					t.Errorf("IsCacheFresh at age %v = %v; want %v", c.age, got, c.fresh)
+				}
+			}
+
+			// The Redis expiry matches the freshness window
+			err := repo.SaveToCache(context.Background(), &models.WeatherCache{Latitude: 40.7128, Longitude: -74.006, Forecast: "Sunny", Timestamp: time.Now()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ttl := mr.TTL("weather:40.713000:-74.006000"); ttl != tt.want {
+				t.Errorf("Redis TTL = %v; want %v", ttl, tt.want)
+			}
+
+			// Forecast periods follow it, with hourly series capped at HourlyPeriodsTTL
+			for kind, want := range map[string]time.Duration{DailyPeriods: tt.want, HourlyPeriods: tt.hourly} {
+				if got := repo.ForecastPeriodsTTL(kind); got != want {
+					t.Errorf("ForecastPeriodsTTL(%q) = %v; want %v", kind, got, want)
+				}
+				periods := &models.ForecastPeriodsCache{Timestamp: time.Now()}
+				if err := repo.SaveForecastPeriods(context.Background(), kind, "OKX", 33, 35, periods); err != nil {
+					t.Fatal(err)
+				}
+				if ttl := mr.TTL("periods:" + kind + ":OKX:33:35"); ttl != want {
+					t.Errorf("%s periods Redis TTL = %v; want %v", kind, ttl, want)
+				}
+				periods.Timestamp = time.Now().Add(-want - time.Second)
+				if repo.IsForecastPeriodsFresh(kind, periods) {
+					t.Errorf("%s periods older than %v are still fresh", kind, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	repo := NewWeatherRepository(db, nil, WithMemoryCache(2, time.Hour))
+
+	save := func(lat, lon float64, forecast string) {
+		t.Helper()
+		err := repo.SaveToCache(context.Background(), &models.WeatherCache{Latitude: lat, Longitude: lon, Forecast: forecast, Timestamp: time.Now()})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	source := func(lat, lon float64) string {
+		t.Helper()
+		cached, err := repo.GetFromCache(context.Background(), lat, lon)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cached.Source
+	}
+
+	save(40.7128, -74.006, "Sunny")
+	// Nearby coordinates share the normalized entry
+	cached, err := repo.GetFromCache(context.Background(), 40.71281, -74.00601)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.Source != SourceMemory || cached.Forecast != "Sunny" || cached.Latitude != 40.713 || cached.Longitude != -74.006 {
+		t.Errorf("cached = %+v; want the normalized entry from memory", cached)
+	}
+	// A refresh replaces the entry rather than serving the old one
+	save(40.7128, -74.006, "Rain")
+	if cached, _ := repo.GetFromCache(context.Background(), 40.7128, -74.006); cached.Forecast != "Rain" {
+		t.Errorf("forecast after refresh = %q; want Rain", cached.Forecast)
+	}
+
+	// The least recently used entry falls back to SQLite once the tier is full
+	save(34.0522, -118.2437, "Clear")
+	save(41.8781, -87.6298, "Windy")
+	if got := source(40.7128, -74.006); got != SourceSQLite {
+		t.Errorf("evicted entry source = %q; want %q", got, SourceSQLite)
+	}
+	if got := source(41.8781, -87.6298); got != SourceMemory {
+		t.Errorf("recent entry source = %q; want %q", got, SourceMemory)
+	}
+
+	if _, err := repo.DeleteWeather(context.Background(), 41.8781, -87.6298); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetFromCache(context.Background(), 41.8781, -87.6298); err != sql.ErrNoRows {
+		t.Errorf("GetFromCache after DeleteWeather error = %v; want sql.ErrNoRows", err)
+	}
+	if _, err := repo.DeleteAllWeather(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetFromCache(context.Background(), 34.0522, -118.2437); err != sql.ErrNoRows {
+		t.Errorf("GetFromCache after DeleteAllWeather error = %v; want sql.ErrNoRows", err)
+	}
+}
+
+func TestMemoryCacheExpires(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	tests := []struct {
+		name string
+		opts []WeatherRepositoryOption
+	}{
+		{"own ttl", []WeatherRepositoryOption{WithMemoryCache(10, 50*time.Millisecond)}},
+		{"cache ttl", []WeatherRepositoryOption{WithCacheTTL(50 * time.Millisecond), WithMemoryCache(10, 0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewWeatherRepository(db, nil, tt.opts...)
+			// Expiry counts from the write, not the forecast's own timestamp
+			err := repo.SaveToCache(context.Background(), &models.WeatherCache{Latitude: 40.7128, Longitude: -74.006, Forecast: "Sunny", Timestamp: time.Now().Add(-time.Hour)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cached, err := repo.GetFromCache(context.Background(), 40.7128, -74.006); err != nil || cached.Source != SourceMemory {
+				t.Fatalf("GetFromCache = %+v, %v; want a memory hit", cached, err)
+			}
+			time.Sleep(60 * time.Millisecond)
+			if cached, err := repo.GetFromCache(context.Background(), 40.7128, -74.006); err != nil || cached.Source != SourceSQLite {
+				t.Errorf("GetFromCache after expiry = %+v, %v; want the SQLite row", cached, err)
+			}
+		})
+	}
+
+	// Redis takes the memory tier's place when configured
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	if repo := NewWeatherRepository(db, rdb, WithMemoryCache(10, 0)); repo.memory != nil {
+		t.Error("memory tier enabled alongside Redis")
+	}
+}
+
+func TestListCached(t *testing.T) {
+	repo := newTestRepository(t)
+	now := time.Now()
+	for i, c := range [][2]float64{{40.7128, -74.006}, {34.0522, -118.2437}, {41.8781, -87.6298}} {
+		err := repo.SaveToCache(context.Background(), &models.WeatherCache{
+			Latitude: c[0], Longitude: c[1], Forecast: "Sunny", City: "City", Timestamp: now.Add(-time.Duration(i) * time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A second refresh updates the coordinate instead of listing it twice
+	if err := repo.SaveToCache(context.Background(), &models.WeatherCache{Latitude: 41.8781, Longitude: -87.6298, Forecast: "Snow", Timestamp: now.Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		limit, offset int
+		want          []float64 // latitudes in order
+	}{
+		{10, 0, []float64{41.878, 40.713, 34.052}},
+		{2, 0, []float64{41.878, 40.713}},
+		{2, 2, []float64{34.052}},
+		{2, 5, nil},
+	}
+	for _, tt := range tests {
+		locations, total, err := repo.ListCached(context.Background(), tt.limit, tt.offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 3 {
+			t.Errorf("ListCached(%d, %d) total = %d; want 3", tt.limit, tt.offset, total)
+		}
+		var got []float64
+		for _, loc := range locations {
+			got = append(got, loc.Latitude)
+		}
+		if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("ListCached(%d, %d) latitudes = %v; want %v", tt.limit, tt.offset, got, tt.want)
+		}
+	}
+
+	locations, _, _ := repo.ListCached(context.Background(), 1, 0)
+	if loc := locations[0]; loc.Forecast != "Snow" || loc.Longitude != -87.63 {
+		t.Errorf("latest location = %+v; want Chicago's second refresh", loc)
+	}
+}
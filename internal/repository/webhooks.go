@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// ErrSubscriptionNotFound is returned when no webhook subscription has the requested ID
+var ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+
+// SaveWebhookSubscription stores a new webhook subscription
+func (r *WeatherRepository) SaveWebhookSubscription(ctx context.Context, sub models.WebhookSubscription) error {
+	_, err := r.db.ExecContext(writeContext(ctx),
+		"INSERT INTO webhook_subscriptions (id, callback_url, latitude, longitude, min_severity, secret, created_at, owner) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		sub.ID, sub.CallbackURL, sub.Latitude, sub.Longitude, sub.MinSeverity, sub.Secret, sub.CreatedAt.UTC(), sub.Owner,
+	)
+	return err
+}
+
+// webhookSubscriptionColumns are the columns scanned by scanWebhookSubscription
+const webhookSubscriptionColumns = "id, callback_url, latitude, longitude, min_severity, secret, created_at, owner"
+
+// scanWebhookSubscription scans a row of webhookSubscriptionColumns
+func scanWebhookSubscription(row interface{ Scan(...interface{}) error }) (models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	err := row.Scan(&sub.ID, &sub.CallbackURL, &sub.Latitude, &sub.Longitude, &sub.MinSeverity, &sub.Secret, &sub.CreatedAt, &sub.Owner)
+	return sub, err
+}
+
+// ListWebhookSubscriptions returns every webhook subscription, oldest first.
+// Secrets are included, so callers showing them to clients must clear them.
+func (r *WeatherRepository) ListWebhookSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	return r.listWebhookSubscriptions(ctx, "SELECT "+webhookSubscriptionColumns+" FROM webhook_subscriptions ORDER BY created_at, id")
+}
+
+// ListOwnedWebhookSubscriptions returns the webhook subscriptions created by
+// an owner, oldest first, with their secrets
+func (r *WeatherRepository) ListOwnedWebhookSubscriptions(ctx context.Context, owner string) ([]models.WebhookSubscription, error) {
+	return r.listWebhookSubscriptions(ctx, "SELECT "+webhookSubscriptionColumns+" FROM webhook_subscriptions WHERE owner = ? ORDER BY created_at, id", owner)
+}
+
+func (r *WeatherRepository) listWebhookSubscriptions(ctx context.Context, query string, args ...interface{}) ([]models.WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []models.WebhookSubscription
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// GetWebhookSubscription returns a webhook subscription, including its secret
+func (r *WeatherRepository) GetWebhookSubscription(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	sub, err := scanWebhookSubscription(r.db.QueryRowContext(ctx,
+		"SELECT "+webhookSubscriptionColumns+" FROM webhook_subscriptions WHERE id = ?", id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// DeleteWebhookSubscription removes an owner's webhook subscription and its
+// deliveries. A subscription created by another owner is reported as
+// ErrSubscriptionNotFound.
+func (r *WeatherRepository) DeleteWebhookSubscription(ctx context.Context, owner, id string) error {
+	ctx = writeContext(ctx)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM webhook_subscriptions WHERE id = ? AND owner = ?", id, owner)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrSubscriptionNotFound
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE subscription_id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// EnqueueWebhookDelivery queues an alert for delivery to a subscription, due
+// at once. It reports false, queuing nothing, when the subscription has
+// already been sent the alert.
+func (r *WeatherRepository) EnqueueWebhookDelivery(ctx context.Context, subscriptionID, alertID, payload string, now time.Time) (bool, error) {
+	result, err := r.db.ExecContext(writeContext(ctx),
+		`INSERT OR IGNORE INTO webhook_deliveries (subscription_id, alert_id, payload, status, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		subscriptionID, alertID, payload, models.DeliveryPending, now.UTC(), now.UTC(),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// DueWebhookDelivery is a pending delivery with where and how to send it
+type DueWebhookDelivery struct {
+	models.WebhookDelivery
+	CallbackURL string
+	Secret      string
+}
+
+// DueWebhookDeliveries returns up to limit pending deliveries whose next
+// attempt is at or before now, longest waiting first
+func (r *WeatherRepository) DueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]DueWebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT d.id, d.subscription_id, d.alert_id, d.payload, d.attempts, d.created_at, s.callback_url, s.secret
+		FROM webhook_deliveries d JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		WHERE d.status = ? AND d.next_attempt_at <= ?
+		ORDER BY d.next_attempt_at, d.id LIMIT ?`,
+		models.DeliveryPending, now.UTC(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []DueWebhookDelivery
+	for rows.Next() {
+		d := DueWebhookDelivery{WebhookDelivery: models.WebhookDelivery{Status: models.DeliveryPending}}
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.AlertID, &d.Payload, &d.Attempts, &d.CreatedAt, &d.CallbackURL, &d.Secret); err != nil {
+			return nil, err
+		}
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+// UpdateWebhookDelivery records the outcome of a delivery attempt: its
+// status, attempts, last error, and next attempt or delivery time
+func (r *WeatherRepository) UpdateWebhookDelivery(ctx context.Context, d models.WebhookDelivery) error {
+	_, err := r.db.ExecContext(writeContext(ctx),
+		"UPDATE webhook_deliveries SET status = ?, attempts = ?, last_error = ?, next_attempt_at = ?, delivered_at = ? WHERE id = ?",
+		d.Status, d.Attempts, d.LastError, utcOrNil(d.NextAttemptAt), utcOrNil(d.DeliveredAt), d.ID,
+	)
+	return err
+}
+
+// ListWebhookDeliveries returns up to limit of a subscription's deliveries,
+// newest first
+func (r *WeatherRepository) ListWebhookDeliveries(ctx context.Context, subscriptionID string, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, subscription_id, alert_id, status, attempts, last_error, next_attempt_at, created_at, delivered_at
+		FROM webhook_deliveries WHERE subscription_id = ? ORDER BY id DESC LIMIT ?`,
+		subscriptionID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var next, delivered sql.NullTime
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.AlertID, &d.Status, &d.Attempts, &d.LastError, &next, &d.CreatedAt, &delivered); err != nil {
+			return nil, err
+		}
+		if next.Valid {
+			d.NextAttemptAt = &next.Time
+		}
+		if delivered.Valid {
+			d.DeliveredAt = &delivered.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// utcOrNil returns t in UTC, or nil to store NULL when t is nil
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
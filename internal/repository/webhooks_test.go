@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+func TestWebhookDeliveryQueue(t *testing.T) {
+	repo := newTestRepository(t)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	sub := models.WebhookSubscription{
+		ID: "sub1", CallbackURL: "https://example.com/hook", Latitude: 40.7128, Longitude: -74.006,
+		MinSeverity: "Severe", Secret: "s3cret", CreatedAt: now, Owner: "mobile-app",
+	}
+	if err := repo.SaveWebhookSubscription(context.Background(), sub); err != nil {
+		t.Fatal(err)
+	}
+	for owner, want := range map[string]int{"mobile-app": 1, "dashboard": 0, "": 0} {
+		if owned, err := repo.ListOwnedWebhookSubscriptions(context.Background(), owner); err != nil || len(owned) != want || (want == 1 && owned[0] != sub) {
+			t.Errorf("ListOwnedWebhookSubscriptions(%q) = %+v, %v; want %d", owner, owned, err, want)
+		}
+	}
+
+	// An alert is queued once per subscription, however often it is seen
+	for i, want := range []bool{true, false} {
+		queued, err := repo.EnqueueWebhookDelivery(context.Background(), "sub1", "alert1", `{}`, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if queued != want {
+			t.Errorf("enqueue %d: queued = %v; want %v", i+1, queued, want)
+		}
+	}
+
+	due, err := repo.DueWebhookDeliveries(context.Background(), now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || due[0].CallbackURL != sub.CallbackURL || due[0].Secret != sub.Secret || due[0].Payload != `{}` {
+		t.Fatalf("due = %+v; want alert1 with the subscription's callback and secret", due)
+	}
+
+	next := now.Add(time.Minute)
+	d := due[0].WebhookDelivery
+	d.Attempts, d.LastError, d.NextAttemptAt = 1, "callback returned status 503", &next
+	if err := repo.UpdateWebhookDelivery(context.Background(), d); err != nil {
+		t.Fatal(err)
+	}
+	if due, err := repo.DueWebhookDeliveries(context.Background(), now.Add(30*time.Second), 10); err != nil || len(due) != 0 {
+		t.Errorf("due before the retry = %v, %v; want none", due, err)
+	}
+	if due, err := repo.DueWebhookDeliveries(context.Background(), next, 10); err != nil || len(due) != 1 {
+		t.Errorf("due at the retry = %v, %v; want alert1", due, err)
+	}
+
+	listed, err := repo.ListWebhookDeliveries(context.Background(), "sub1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].Attempts != 1 || listed[0].NextAttemptAt == nil || !listed[0].NextAttemptAt.Equal(next) {
+		t.Errorf("listed = %+v; want one attempt with the retry at %v", listed, next)
+	}
+
+	// Only the owner can delete the subscription, which drops its deliveries
+	if err := repo.DeleteWebhookSubscription(context.Background(), "dashboard", "sub1"); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("DeleteWebhookSubscription by another owner error = %v; want ErrSubscriptionNotFound", err)
+	}
+	if err := repo.DeleteWebhookSubscription(context.Background(), "mobile-app", "sub1"); err != nil {
+		t.Fatal(err)
+	}
+	if listed, err := repo.ListWebhookDeliveries(context.Background(), "sub1", 10); err != nil || len(listed) != 0 {
+		t.Errorf("deliveries after delete = %v, %v; want none", listed, err)
+	}
+	if _, err := repo.GetWebhookSubscription(context.Background(), "sub1"); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("GetWebhookSubscription after delete error = %v; want ErrSubscriptionNotFound", err)
+	}
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+// Advisory severity levels, ordered from least to most severe
+const (
+	SeverityNone     = "none"
+	SeverityLow      = "low"
+	SeverityModerate = "moderate"
+	SeverityHigh     = "high"
+)
+
+var severityRank = map[string]int{
+	SeverityNone:     0,
+	SeverityLow:      1,
+	SeverityModerate: 2,
+	SeverityHigh:     3,
+}
+
+// AdvisoryThresholds configures the frost and heat heuristics
+type AdvisoryThresholds struct {
+	// FrostTempC is the temperature at or below which clear, calm conditions are frost-prone
+	FrostTempC float64
+	// HardFreezeC is the temperature at or below which frost is flagged regardless of sky or wind
+	HardFreezeC float64
+	// FrostMaxWindKmh is the highest wind speed still considered light enough for frost to settle
+	FrostMaxWindKmh float64
+	// HeatIndexC is the heat index at or above which heat risk is flagged
+	HeatIndexC float64
+	// HeatIndexDangerC is the heat index at or above which heat risk is high severity
+	HeatIndexDangerC float64
+}
+
+// DefaultAdvisoryThresholds returns thresholds based on common agricultural and NWS heat index guidance
+func DefaultAdvisoryThresholds() AdvisoryThresholds {
+	return AdvisoryThresholds{
+		FrostTempC:       2.0,
+		HardFreezeC:      -2.0,
+		FrostMaxWindKmh:  10.0,
+		HeatIndexC:       32.0,
+		HeatIndexDangerC: 39.0,
+	}
+}
+
+// Validate checks that the thresholds are internally consistent
+func (t AdvisoryThresholds) Validate() error {
+	if t.HardFreezeC >= t.FrostTempC {
+		return fmt.Errorf("hard freeze threshold (%.1f°C) must be below frost threshold (%.1f°C)", t.HardFreezeC, t.FrostTempC)
+	}
+	if t.FrostMaxWindKmh < 0 {
+		return fmt.Errorf("frost max wind (%.1f km/h) must not be negative", t.FrostMaxWindKmh)
+	}
+	if t.HeatIndexDangerC <= t.HeatIndexC {
+		return fmt.Errorf("heat index danger threshold (%.1f°C) must be above heat index threshold (%.1f°C)", t.HeatIndexDangerC, t.HeatIndexC)
+	}
+	return nil
+}
+
+// AdvisoryInput is a single forecast sample used by the advisory heuristics.
+// Wind and humidity are optional because not every upstream period provides them.
+type AdvisoryInput struct {
+	TempC            float64
+	Forecast         string
+	WindKmh          *float64
+	RelativeHumidity *float64
+}
+
+// ComputeAdvisories derives frost and heat risk flags from forecast samples.
+// Each flag is raised if any sample qualifies, and the severity is the worst across all samples.
+func ComputeAdvisories(samples []AdvisoryInput, t AdvisoryThresholds) *models.Advisories {
+	adv := &models.Advisories{Severity: SeverityNone}
+
+	for _, sample := range samples {
+		if severity := frostSeverity(sample, t); severity != SeverityNone {
+			adv.FrostRisk = true
+			adv.Severity = maxSeverity(adv.Severity, severity)
+		}
+
+		heatIndex := HeatIndexC(sample.TempC, sample.RelativeHumidity)
+		if adv.HeatIndexC == nil || heatIndex > *adv.HeatIndexC {
+			hi := heatIndex
+			adv.HeatIndexC = &hi
+		}
+		if severity := heatSeverity(heatIndex, t); severity != SeverityNone {
+			adv.HeatRisk = true
+			adv.Severity = maxSeverity(adv.Severity, severity)
+		}
+	}
+
+	return adv
+}
+
+// frostSeverity applies the frost heuristic to one sample. Below the hard freeze
+// threshold frost is assumed; between that and the frost threshold the sky must
+// be clear and the wind light. Missing wind data is treated as light wind so the
+// flag errs on the side of warning.
+func frostSeverity(sample AdvisoryInput, t AdvisoryThresholds) string {
+	if sample.TempC <= t.HardFreezeC {
+		return SeverityHigh
+	}
+	if sample.TempC > t.FrostTempC {
+		return SeverityNone
+	}
+	if !isClearSky(sample.Forecast) {
+		return SeverityNone
+	}
+	if sample.WindKmh != nil && *sample.WindKmh > t.FrostMaxWindKmh {
+		return SeverityNone
+	}
+	if sample.TempC <= 0 {
+		return SeverityModerate
+	}
+	return SeverityLow
+}
+
+func heatSeverity(heatIndexC float64, t AdvisoryThresholds) string {
+	if heatIndexC >= t.HeatIndexDangerC {
+		return SeverityHigh
+	}
+	if heatIndexC >= t.HeatIndexC {
+		return SeverityModerate
+	}
+	return SeverityNone
+}
+
+// isClearSky reports whether a short forecast describes clear or mostly clear skies
+func isClearSky(forecast string) bool {
+	f := strings.ToLower(forecast)
+	for _, word := range []string{"clear", "sunny", "fair"} {
+		if strings.Contains(f, word) && !strings.Contains(f, "partly") {
+			return true
+		}
+	}
+	return false
+}
+
+// HeatIndexC computes the NWS heat index (Rothfusz regression with the standard
+// low-humidity and high-humidity adjustments). Below 80°F the simpler Steadman
+// approximation is used, and without humidity data the air temperature is returned.
+func HeatIndexC(tempC float64, relativeHumidity *float64) float64 {
+	if relativeHumidity == nil {
+		return tempC
+	}
+
+	t := tempC*9/5 + 32
+	rh := *relativeHumidity
+
+	// Simple formula first, as the NWS does; the full regression only applies at 80°F and above
+	hi := 0.5 * (t + 61.0 + (t-68.0)*1.2 + rh*0.094)
+	if (hi+t)/2 < 80 {
+		return (hi - 32) * 5 / 9
+	}
+
+	hi = -42.379 + 2.04901523*t + 10.14333127*rh -
+		0.22475541*t*rh - 0.00683783*t*t - 0.05481717*rh*rh +
+		0.00122874*t*t*rh + 0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+
+	if rh < 13 && t >= 80 && t <= 112 {
+		hi -= ((13 - rh) / 4) * math.Sqrt((17-math.Abs(t-95))/17)
+	} else if rh > 85 && t >= 80 && t <= 87 {
+		hi += ((rh - 85) / 10) * ((87 - t) / 5)
+	}
+
+	return (hi - 32) * 5 / 9
+}
+
+func maxSeverity(a, b string) string {
+	if severityRank[b] > severityRank[a] {
+		return b
+	}
+	return a
+}
+
+// AdvisoryWindow is how far ahead ?include=advisories looks for frost and heat
+const AdvisoryWindow = 24 * time.Hour
+
+// advisorySamples returns the samples a forecast's advisories are computed
+// from: the forecast itself and the grid cell's day/night periods within
+// AdvisoryWindow of now, so a daytime request still sees the overnight low
+// and a morning one the afternoon high. The periods are read from the cache
+// only, where fetching the cell's forecast leaves them; for other providers,
+// entries cached before their grid cell was recorded, or when none are
+// cached, only the forecast itself counts.
+func (s *WeatherService) advisorySamples(ctx context.Context, weather *models.WeatherCache, now time.Time) []AdvisoryInput {
+	samples := []AdvisoryInput{weatherAdvisoryInput(weather)}
+	if weather.GridID == "" {
+		return samples
+	}
+	daily, err := s.repo.GetForecastPeriods(ctx, repository.DailyPeriods, weather.GridID, weather.GridX, weather.GridY)
+	if err != nil {
+		return samples
+	}
+
+	end := now.Add(AdvisoryWindow)
+	for _, p := range daily.Periods {
+		if p.EndTime.After(now) && p.StartTime.Before(end) {
+			samples = append(samples, periodAdvisoryInput(p))
+		}
+	}
+	return samples
+}
+
+// weatherAdvisoryInput is the advisory sample for a cached forecast
+func weatherAdvisoryInput(weather *models.WeatherCache) AdvisoryInput {
+	sample := AdvisoryInput{TempC: weather.TempC, Forecast: weather.Forecast, RelativeHumidity: weather.RelativeHumidity}
+	if weather.WindKmh != nil {
+		sample.WindKmh = &weather.WindKmh.Max
+	}
+	return sample
+}
+
+// periodAdvisoryInput is the advisory sample for a forecast period
+func periodAdvisoryInput(p models.ForecastPeriod) AdvisoryInput {
+	sample := AdvisoryInput{TempC: p.TempC, Forecast: p.ShortForecast, RelativeHumidity: p.HumidityPercent}
+	if kmh, _ := normalizeWind(p.WindSpeed); kmh != nil {
+		sample.WindKmh = &kmh.Max
+	}
+	return sample
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func TestComputeAdvisoriesFrost(t *testing.T) {
+	thresholds := DefaultAdvisoryThresholds()
+
+	tests := []struct {
+		name      string
+		sample    AdvisoryInput
+		wantFrost bool
+		severity  string
+	}{
+		{"Clear and calm at frost boundary", AdvisoryInput{TempC: 2.0, Forecast: "Clear", WindKmh: floatPtr(5)}, true, SeverityLow},
+		{"Just above frost boundary", AdvisoryInput{TempC: 2.1, Forecast: "Clear", WindKmh: floatPtr(5)}, false, SeverityNone},
+		{"Clear at freezing", AdvisoryInput{TempC: 0, Forecast: "Mostly Clear", WindKmh: floatPtr(5)}, true, SeverityModerate},
+		{"Cloudy near freezing", AdvisoryInput{TempC: 1.0, Forecast: "Cloudy", WindKmh: floatPtr(5)}, false, SeverityNone},
+		{"Partly cloudy near freezing", AdvisoryInput{TempC: 1.0, Forecast: "Partly Cloudy"}, false, SeverityNone},
+		{"Wind at limit", AdvisoryInput{TempC: 1.0, Forecast: "Clear", WindKmh: floatPtr(10)}, true, SeverityLow},
+		{"Wind above limit", AdvisoryInput{TempC: 1.0, Forecast: "Clear", WindKmh: floatPtr(10.1)}, false, SeverityNone},
+		{"Missing wind treated as light", AdvisoryInput{TempC: 1.0, Forecast: "Clear"}, true, SeverityLow},
+		{"Hard freeze boundary ignores sky and wind", AdvisoryInput{TempC: -2.0, Forecast: "Snow", WindKmh: floatPtr(40)}, true, SeverityHigh},
+		{"Above hard freeze with wind", AdvisoryInput{TempC: -1.9, Forecast: "Clear", WindKmh: floatPtr(40)}, false, SeverityNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adv := ComputeAdvisories([]AdvisoryInput{tt.sample}, thresholds)
+			if adv.FrostRisk != tt.wantFrost {
+				t.Errorf("FrostRisk = %v; want %v", adv.FrostRisk, tt.wantFrost)
+			}
+			if adv.Severity != tt.severity {
+				t.Errorf("Severity = %s; want %s", adv.Severity, tt.severity)
+			}
+		})
+	}
+}
+
+func TestComputeAdvisoriesHeat(t *testing.T) {
+	thresholds := DefaultAdvisoryThresholds()
+
+	tests := []struct {
+		name     string
+		sample   AdvisoryInput
+		wantHeat bool
+		severity string
+	}{
+		{"Missing humidity uses air temperature at threshold", AdvisoryInput{TempC: 32.0, Forecast: "Sunny"}, true, SeverityModerate},
+		{"Missing humidity below threshold", AdvisoryInput{TempC: 31.9, Forecast: "Sunny"}, false, SeverityNone},
+		{"Missing humidity at danger threshold", AdvisoryInput{TempC: 39.0, Forecast: "Sunny"}, true, SeverityHigh},
+		{"Humid air pushes heat index over threshold", AdvisoryInput{TempC: 30.0, Forecast: "Sunny", RelativeHumidity: floatPtr(70)}, true, SeverityModerate},
+		{"Dry air keeps heat index under threshold", AdvisoryInput{TempC: 31.0, Forecast: "Sunny", RelativeHumidity: floatPtr(10)}, false, SeverityNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adv := ComputeAdvisories([]AdvisoryInput{tt.sample}, thresholds)
+			if adv.HeatRisk != tt.wantHeat {
+				t.Errorf("HeatRisk = %v (heat index %.2f); want %v", adv.HeatRisk, *adv.HeatIndexC, tt.wantHeat)
+			}
+			if adv.Severity != tt.severity {
+				t.Errorf("Severity = %s; want %s", adv.Severity, tt.severity)
+			}
+		})
+	}
+}
+
+func TestComputeAdvisoriesWorstSeverityAcrossSamples(t *testing.T) {
+	samples := []AdvisoryInput{
+		{TempC: 1.0, Forecast: "Clear"},
+		{TempC: -5.0, Forecast: "Cloudy"},
+	}
+
+	adv := ComputeAdvisories(samples, DefaultAdvisoryThresholds())
+	if !adv.FrostRisk || adv.Severity != SeverityHigh {
+		t.Errorf("got frost=%v severity=%s; want frost=true severity=high", adv.FrostRisk, adv.Severity)
+	}
+	if adv.HeatRisk {
+		t.Error("HeatRisk = true; want false")
+	}
+}
+
+func TestHeatIndexC(t *testing.T) {
+	// 90°F at 70% humidity is 105-106°F on the NWS heat index chart
+	got := HeatIndexC(32.22, floatPtr(70))
+	want := (105.9 - 32) * 5 / 9
+	if math.Abs(got-want) > 0.5 {
+		t.Errorf("HeatIndexC(90°F, 70%%) = %.2f°C; want %.2f°C", got, want)
+	}
+
+	if got := HeatIndexC(25.0, nil); got != 25.0 {
+		t.Errorf("HeatIndexC without humidity = %.2f; want 25.0", got)
+	}
+}
+
+func TestAdvisoryThresholdsValidate(t *testing.T) {
+	if err := DefaultAdvisoryThresholds().Validate(); err != nil {
+		t.Fatalf("default thresholds invalid: %v", err)
+	}
+
+	bad := DefaultAdvisoryThresholds()
+	bad.HardFreezeC = bad.FrostTempC
+	if err := bad.Validate(); err == nil {
+		t.Error("expected error when hard freeze is not below frost threshold")
+	}
+
+	bad = DefaultAdvisoryThresholds()
+	bad.HeatIndexDangerC = bad.HeatIndexC
+	if err := bad.Validate(); err == nil {
+		t.Error("expected error when danger threshold is not above heat threshold")
+	}
+}
+
+func TestGetWeatherAdvisoriesOverForecastPeriods(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Hour)
+	period := func(name string, start time.Time, daytime bool, forecast string, tempF int, humidity string) string {
+		return fmt.Sprintf(`{"name": %q, "startTime": %q, "endTime": %q, "isDaytime": %v, "shortForecast": %q,
+			"temperature": %d, "temperatureUnit": "F", "windSpeed": "5 mph", "relativeHumidity": {"value": %s}}`,
+			name, start.Format(time.RFC3339), start.Add(12*time.Hour).Format(time.RFC3339), daytime, forecast, tempF, humidity)
+	}
+	var periods string
+	var requests atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if strings.HasPrefix(r.URL.Path, "/points/") {
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35, "forecast": "%s/gridpoints/OKX/33,35/forecast"}}`, server.URL)
+			return
+		}
+		fmt.Fprintf(w, `{"properties": {"periods": [%s]}}`, periods)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		periods      []string
+		wantFrost    bool
+		wantHeat     bool
+		wantSeverity string
+	}{
+		{
+			// A mild afternoon ahead of a clear, freezing night
+			"overnight frost",
+			[]string{
+				period("This Afternoon", now.Add(-time.Hour), true, "Sunny", 55, "40"),
+				period("Tonight", now.Add(11*time.Hour), false, "Clear", 27, "80"),
+			},
+			true, false, SeverityHigh,
+		},
+		{
+			// A pleasant morning ahead of a hot, humid afternoon
+			"afternoon heat",
+			[]string{
+				period("This Morning", now.Add(-time.Hour), true, "Sunny", 75, "50"),
+				period("This Afternoon", now.Add(11*time.Hour), true, "Sunny", 92, "60"),
+			},
+			false, true, SeverityHigh,
+		},
+		{
+			// Periods beyond the window don't count
+			"frost after the window",
+			[]string{
+				period("Today", now.Add(-time.Hour), true, "Sunny", 55, "40"),
+				period("Tonight", now.Add(11*time.Hour), false, "Cloudy", 45, "80"),
+				period("Thursday Night", now.Add(35*time.Hour), false, "Clear", 20, "80"),
+			},
+			false, false, SeverityNone,
+		},
+	}
+	for _, tt := range tests {
+		periods = strings.Join(tt.periods, ",")
+		service := NewWeatherService(newTestRepo(t), newTestNWSClient(server))
+		sub := service.SubscribeWeather(40.7128, -74.0060)
+		opts := WeatherOptions{IncludeAdvisories: true}
+		resp, err := service.GetWeatherWithOptions(context.Background(), 40.7128, -74.0060, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		adv := resp.Advisories
+		if adv == nil || adv.FrostRisk != tt.wantFrost || adv.HeatRisk != tt.wantHeat || adv.Severity != tt.wantSeverity {
+			t.Errorf("%s: advisories = %+v; want frost=%v heat=%v severity=%s", tt.name, adv, tt.wantFrost, tt.wantHeat, tt.wantSeverity)
+		}
+
+		// Cache hits and pushed updates read the periods the fetch cached
+		fetched := requests.Load()
+		hit, err := service.GetWeatherWithOptions(context.Background(), 40.7128, -74.0060, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var update *models.WeatherResponse
+		select {
+		case entry := <-sub.Updates():
+			update = service.WeatherUpdate(context.Background(), entry, opts)
+		case <-time.After(time.Second):
+			t.Fatalf("%s: no update after fetching the coordinate", tt.name)
+		}
+		sub.Close()
+		if !reflect.DeepEqual(hit.Advisories, adv) || !reflect.DeepEqual(update.Advisories, adv) {
+			t.Errorf("%s: cache hit advisories = %+v, update = %+v; want %+v", tt.name, hit.Advisories, update.Advisories, adv)
+		}
+		if got := requests.Load(); got != fetched {
+			t.Errorf("%s: %d NWS requests after the fetch; want none", tt.name, got-fetched)
+		}
+	}
+}
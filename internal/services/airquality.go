@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+// ErrAirQualityDisabled is returned for air quality lookups when no provider is configured
+var ErrAirQualityDisabled = errors.New("air quality is not configured")
+
+// ErrNoAirQuality is returned when no air quality monitors report near a coordinate
+var ErrNoAirQuality = errors.New("no air quality observations near this location")
+
+// AirQualityProvider looks up current air quality
+type AirQualityProvider interface {
+	// CurrentAirQuality returns the latest observation of each pollutant
+	// reported near a coordinate, with upstream requests bound by ctx. No
+	// monitors nearby is an empty result, not an error.
+	CurrentAirQuality(ctx context.Context, lat, lon float64) ([]models.AirQualityObservation, error)
+}
+
+// WithAirQualityProvider enables air quality lookups (/airquality) through
+// the given provider; nil disables them
+func WithAirQualityProvider(p AirQualityProvider) WeatherServiceOption {
+	return func(s *WeatherService) {
+		s.airQuality = p
+	}
+}
+
+// GetAirQuality retrieves the current air quality near a coordinate, described
+// by its worst pollutant. Observations, including finding none, are cached per
+// normalized coordinate for AirQualityCacheTTL, and stale ones are served if
+// the provider fails. ErrNoAirQuality is returned when no monitors report
+// nearby.
+func (s *WeatherService) GetAirQuality(ctx context.Context, lat, lon float64) (*models.AirQualityResponse, error) {
+	if s.airQuality == nil {
+		return nil, ErrAirQualityDisabled
+	}
+	keyLat, keyLon := repository.NormalizeCoordinate(lat), repository.NormalizeCoordinate(lon)
+
+	cached, err := s.repo.GetAirQuality(ctx, keyLat, keyLon)
+	hit := err == nil && s.repo.IsAirQualityFresh(cached)
+	stale := false
+	if !hit {
+		observations, fetchErr := s.airQuality.CurrentAirQuality(ctx, keyLat, keyLon)
+		switch {
+		case fetchErr == nil:
+			cached = &models.AirQualityCache{Latitude: keyLat, Longitude: keyLon, Observations: observations, Timestamp: time.Now()}
+			_ = s.repo.SaveAirQuality(ctx, cached)
+		case cached == nil:
+			return nil, fetchErr
+		default:
+			stale = true
+		}
+	}
+	if len(cached.Observations) == 0 {
+		return nil, ErrNoAirQuality
+	}
+
+	pollutants := append([]models.AirQualityObservation(nil), cached.Observations...)
+	sort.SliceStable(pollutants, func(i, j int) bool { return pollutants[i].AQI > pollutants[j].AQI })
+	worst := pollutants[0]
+	resp := &models.AirQualityResponse{
+		Latitude:         lat,
+		Longitude:        lon,
+		AQI:              worst.AQI,
+		Category:         worst.Category,
+		PrimaryPollutant: worst.Pollutant,
+		ReportingArea:    worst.ReportingArea,
+		StateCode:        worst.StateCode,
+		ObservedAt:       worst.ObservedAt,
+		Pollutants:       pollutants,
+		CacheHit:         hit,
+	}
+	if !stale {
+		resp.FreshUntil = cached.Timestamp.Add(repository.AirQualityCacheTTL)
+	}
+	return resp, nil
+}
+
+// DefaultAirNowURL is the AirNow API, which publishes the US EPA's hourly air
+// quality observations
+const DefaultAirNowURL = "https://www.airnowapi.org"
+
+// DefaultAirNowDistanceMiles is how far from a coordinate AirNow looks for a
+// reporting area when none contains it
+const DefaultAirNowDistanceMiles = 25
+
+// AirNowConfig configures an AirNowClient
+type AirNowConfig struct {
+	// APIKey is the AirNow API key, which every request requires
+	APIKey string
+	// BaseURL is the AirNow host; empty uses DefaultAirNowURL
+	BaseURL string
+	// DistanceMiles is the search radius; zero uses DefaultAirNowDistanceMiles
+	DistanceMiles int
+	// HTTPClient replaces the default client, such as for tests
+	HTTPClient *http.Client
+}
+
+// AirNowClient is an AirQualityProvider backed by the AirNow API
+type AirNowClient struct {
+	cfg AirNowConfig
+}
+
+// NewAirNowClient creates an AirNow client
+func NewAirNowClient(cfg AirNowConfig) *AirNowClient {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultAirNowURL
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.DistanceMiles <= 0 {
+		cfg.DistanceMiles = DefaultAirNowDistanceMiles
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: DefaultUpstreamTimeout}
+	}
+	return &AirNowClient{cfg: cfg}
+}
+
+// airNowObservation is a pollutant's current observation at an AirNow
+// reporting area
+type airNowObservation struct {
+	// DateObserved is the local day, such as "2023-06-07 " (with the
+	// trailing space AirNow sends)
+	DateObserved  string `json:"DateObserved"`
+	HourObserved  int    `json:"HourObserved"`
+	LocalTimeZone string `json:"LocalTimeZone"`
+	ReportingArea string `json:"ReportingArea"`
+	StateCode     string `json:"StateCode"`
+	ParameterName string `json:"ParameterName"`
+	// AQI is -1 when the pollutant has no valid reading this hour
+	AQI      int `json:"AQI"`
+	Category struct {
+		Number int    `json:"Number"`
+		Name   string `json:"Name"`
+	} `json:"Category"`
+}
+
+// airNowZoneOffsets are the UTC offsets, in hours, of the time zone
+// abbreviations AirNow reports local times in
+var airNowZoneOffsets = map[string]int{
+	"AST": -4, "ADT": -3,
+	"EST": -5, "EDT": -4,
+	"CST": -6, "CDT": -5,
+	"MST": -7, "MDT": -6,
+	"PST": -8, "PDT": -7,
+	"AKST": -9, "AKDT": -8,
+	"HST": -10, "SST": -11, "CHST": 10,
+}
+
+// observedAt returns when an observation's hour started, or nil when its day
+// or time zone isn't recognized
+func (o airNowObservation) observedAt() *time.Time {
+	offset, ok := airNowZoneOffsets[strings.ToUpper(strings.TrimSpace(o.LocalTimeZone))]
+	if !ok {
+		return nil
+	}
+	zone := time.FixedZone(strings.TrimSpace(o.LocalTimeZone), offset*3600)
+	day, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(o.DateObserved), zone)
+	if err != nil {
+		return nil
+	}
+	at := day.Add(time.Duration(o.HourObserved) * time.Hour)
+	return &at
+}
+
+// CurrentAirQuality looks up the current observations of the reporting area
+// nearest a coordinate. Pollutants without a valid reading are left out.
+func (c *AirNowClient) CurrentAirQuality(ctx context.Context, lat, lon float64) ([]models.AirQualityObservation, error) {
+	query := url.Values{}
+	query.Set("format", "application/json")
+	query.Set("latitude", strconv.FormatFloat(lat, 'f', -1, 64))
+	query.Set("longitude", strconv.FormatFloat(lon, 'f', -1, 64))
+	query.Set("distance", strconv.Itoa(c.cfg.DistanceMiles))
+	query.Set("API_KEY", c.cfg.APIKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		c.cfg.BaseURL+"/aq/observation/latLong/current/?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		// The request URL carries the API key, so keep it out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to fetch air quality: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("air quality lookup returned status: %d", resp.StatusCode)
+	}
+
+	var docs []airNowObservation
+	if err := json.NewDecoder(io.LimitReader(resp.Body, MaxDocumentBytes)).Decode(&docs); err != nil {
+		return nil, fmt.Errorf("failed to decode air quality: %w", err)
+	}
+	observations := []models.AirQualityObservation{}
+	for _, doc := range docs {
+		if doc.AQI < 0 {
+			continue
+		}
+		observations = append(observations, models.AirQualityObservation{
+			Pollutant:     doc.ParameterName,
+			AQI:           doc.AQI,
+			Category:      doc.Category.Name,
+			ReportingArea: doc.ReportingArea,
+			StateCode:     doc.StateCode,
+			ObservedAt:    doc.observedAt(),
+		})
+	}
+	return observations, nil
+}
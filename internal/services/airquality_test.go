@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+func TestAirNowClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		status  int
+		want    []string
+		wantErr bool
+	}{
+		// AirNow reports a pollutant without a valid reading this hour as AQI -1
+		{"recorded observations", "airnow_observations.json", http.StatusOK, []string{"O3 101", "PM2.5 255"}, false},
+		{"no monitors nearby", "airnow_empty.json", http.StatusOK, nil, false},
+		{"invalid key", "", http.StatusUnauthorized, nil, true},
+	}
+	for _, tt := range tests {
+		var body []byte
+		if tt.fixture != "" {
+			var err error
+			if body, err = os.ReadFile(filepath.Join("testdata", tt.fixture)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var gotPath, gotQuery string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotPath, gotQuery = r.URL.Path, r.URL.RawQuery
+			w.WriteHeader(tt.status)
+			w.Write(body)
+		}))
+		client := NewAirNowClient(AirNowConfig{APIKey: "secret", BaseURL: server.URL + "/", HTTPClient: server.Client()})
+		got, err := client.CurrentAirQuality(context.Background(), 40.713, -74.006)
+		server.Close()
+
+		if gotPath != "/aq/observation/latLong/current/" ||
+			gotQuery != "API_KEY=secret&distance=25&format=application%2Fjson&latitude=40.713&longitude=-74.006" {
+			t.Errorf("%s: requested %s?%s", tt.name, gotPath, gotQuery)
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v; want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got == nil {
+			t.Errorf("%s: nil observations; want an empty list", tt.name)
+		}
+		var pollutants []string
+		for _, o := range got {
+			pollutants = append(pollutants, fmt.Sprintf("%s %d", o.Pollutant, o.AQI))
+		}
+		if strings.Join(pollutants, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: pollutants = %v; want %v", tt.name, pollutants, tt.want)
+		}
+	}
+
+	// Observations carry their reporting area and the hour they were observed
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join("testdata", "airnow_observations.json"))
+	}))
+	defer server.Close()
+	got, err := NewAirNowClient(AirNowConfig{APIKey: "secret", BaseURL: server.URL}).CurrentAirQuality(context.Background(), 40.713, -74.006)
+	if err != nil || len(got) == 0 {
+		t.Fatalf("CurrentAirQuality = %v, %v", got, err)
+	}
+	pm25 := got[1]
+	if pm25.Category != "Very Unhealthy" || pm25.ReportingArea != "New York City Region" || pm25.StateCode != "NY" {
+		t.Errorf("PM2.5 = %+v; want Very Unhealthy in the New York City Region, NY", pm25)
+	}
+	if want := time.Date(2023, 6, 7, 19, 0, 0, 0, time.UTC); pm25.ObservedAt == nil || !pm25.ObservedAt.Equal(want) {
+		t.Errorf("PM2.5 observed at %v; want %v (14:00 EST)", pm25.ObservedAt, want)
+	}
+}
+
+func TestAirNowClientKeepsKeyOutOfErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+	_, err := NewAirNowClient(AirNowConfig{APIKey: "secret", BaseURL: server.URL}).CurrentAirQuality(context.Background(), 40.713, -74.006)
+	if err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("error = %v; want a connection error without the API key", err)
+	}
+}
+
+// fakeAirQualityProvider answers every lookup with observations or err, counting them
+type fakeAirQualityProvider struct {
+	observations []models.AirQualityObservation
+	err          error
+	lookups      int
+}
+
+func (f *fakeAirQualityProvider) CurrentAirQuality(_ context.Context, _, _ float64) ([]models.AirQualityObservation, error) {
+	f.lookups++
+	return f.observations, f.err
+}
+
+func TestGetAirQuality(t *testing.T) {
+	provider := &fakeAirQualityProvider{observations: []models.AirQualityObservation{
+		{Pollutant: "O3", AQI: 101, Category: "Unhealthy for Sensitive Groups", ReportingArea: "New York City Region", StateCode: "NY"},
+		{Pollutant: "PM2.5", AQI: 255, Category: "Very Unhealthy", ReportingArea: "New York City Region", StateCode: "NY"},
+	}}
+	repo := newTestRepo(t)
+	service := NewWeatherService(repo, nil, WithAirQualityProvider(provider))
+
+	// The worst pollutant describes the air, and nearby coordinates share the
+	// cached lookup
+	for i, lat := range []float64{40.7128, 40.7131} {
+		resp, err := service.GetAirQuality(context.Background(), lat, -74.006)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.AQI != 255 || resp.Category != "Very Unhealthy" || resp.PrimaryPollutant != "PM2.5" ||
+			resp.ReportingArea != "New York City Region" || resp.Pollutants[0].Pollutant != "PM2.5" {
+			t.Errorf("request %d: %+v; want PM2.5 at 255 first", i+1, resp)
+		}
+		if resp.CacheHit != (i > 0) || resp.FreshUntil.IsZero() {
+			t.Errorf("request %d: cache hit %v, fresh until %v", i+1, resp.CacheHit, resp.FreshUntil)
+		}
+	}
+	if provider.lookups != 1 {
+		t.Errorf("%d lookups; want 1", provider.lookups)
+	}
+
+	// An expired entry is served when the provider fails
+	cached, err := repo.GetAirQuality(context.Background(), 40.713, -74.006)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached.Timestamp = time.Now().Add(-2 * time.Hour)
+	if err := repo.SaveAirQuality(context.Background(), cached); err != nil {
+		t.Fatal(err)
+	}
+	provider.err = errors.New("connection refused")
+	resp, err := service.GetAirQuality(context.Background(), 40.7128, -74.006)
+	if err != nil || resp.AQI != 255 || !resp.FreshUntil.IsZero() {
+		t.Errorf("stale fallback = %+v, %v; want the expired entry without a freshness", resp, err)
+	}
+
+	// Finding no monitors nearby is cached too
+	empty := &fakeAirQualityProvider{observations: []models.AirQualityObservation{}}
+	service = NewWeatherService(newTestRepo(t), nil, WithAirQualityProvider(empty))
+	for i := 0; i < 2; i++ {
+		if _, err := service.GetAirQuality(context.Background(), 40.7128, -74.006); !errors.Is(err, ErrNoAirQuality) {
+			t.Errorf("no monitors, request %d: error = %v; want ErrNoAirQuality", i+1, err)
+		}
+	}
+	if empty.lookups != 1 {
+		t.Errorf("no monitors: %d lookups; want 1", empty.lookups)
+	}
+
+	// Without a cached entry to fall back on
+	for _, tt := range []struct {
+		name     string
+		provider AirQualityProvider
+		want     error
+	}{
+		{"upstream failure", &fakeAirQualityProvider{err: provider.err}, provider.err},
+		{"not configured", nil, ErrAirQualityDisabled},
+	} {
+		service := NewWeatherService(newTestRepo(t), nil, WithAirQualityProvider(tt.provider))
+		if _, err := service.GetAirQuality(context.Background(), 40.7128, -74.006); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v; want %v", tt.name, err, tt.want)
+		}
+	}
+}
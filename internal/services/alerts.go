@@ -0,0 +1,383 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"weather-api-go/internal/metrics"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+// GetAlerts retrieves the active alerts for a coordinate. Alerts are cached per
+// NWS forecast zone, so every point in a zone shares one upstream fetch.
+func (s *WeatherService) GetAlerts(ctx context.Context, lat, lon float64) ([]models.Alert, error) {
+	zone, err := s.resolveForecastZone(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+
+	cached, err := s.repo.GetAlerts(ctx, zone)
+	if err == nil && s.repo.IsAlertCacheFresh(cached) {
+		return activeAlerts(cached.Alerts, time.Now()), nil
+	}
+
+	alerts, err := s.nwsClient.GetActiveAlerts(ctx, zone)
+	if err != nil {
+		// Return stale alerts if available, dropping any that have since expired
+		if cached != nil {
+			return activeAlerts(cached.Alerts, time.Now()), nil
+		}
+		return nil, err
+	}
+
+	alerts = activeAlerts(alerts, time.Now())
+
+	// Save to cache (ignore errors, don't fail the request)
+	_ = s.repo.SaveAlerts(ctx, &models.AlertCache{
+		Zone:      zone,
+		Alerts:    alerts,
+		Timestamp: time.Now(),
+	})
+
+	return alerts, nil
+}
+
+// GetPointAlerts retrieves the alerts active at a coordinate, including
+// storm-based warnings that the zone lookup in GetAlerts can miss. Alerts are
+// cached per normalized coordinate for AlertCacheTTL, or until one expires.
+func (s *WeatherService) GetPointAlerts(ctx context.Context, lat, lon float64) (*models.AlertsResponse, error) {
+	if err := s.requireNWS(); err != nil {
+		return nil, err
+	}
+	if err := s.checkCoverage(lat, lon); err != nil {
+		return nil, err
+	}
+	pointLat, pointLon := normalizePointCoordinate(lat), normalizePointCoordinate(lon)
+	resp := &models.AlertsResponse{Latitude: lat, Longitude: lon}
+
+	cached, err := s.repo.GetPointAlerts(ctx, pointLat, pointLon)
+	if err == nil && s.repo.IsAlertCacheFresh(cached) {
+		resp.Alerts = activeAlerts(cached.Alerts, time.Now())
+		resp.FreshUntil = alertsFreshUntil(cached)
+		resp.CacheHit = true
+		return resp, nil
+	}
+
+	var alerts []models.Alert
+	err = s.upstream(func() (err error) {
+		alerts, err = s.nwsClient.GetActivePointAlerts(ctx, pointLat, pointLon)
+		return err
+	})
+	if err != nil {
+		// Return stale alerts if available, dropping any that have since expired
+		if cached != nil {
+			resp.Alerts = activeAlerts(cached.Alerts, time.Now())
+			return resp, nil
+		}
+		if errors.Is(err, ErrUpstreamSaturated) && s.metrics != nil {
+			s.metrics.Inc(metrics.RequestsShed)
+		}
+		return nil, err
+	}
+
+	fresh := &models.AlertCache{Alerts: activeAlerts(alerts, time.Now()), Timestamp: time.Now()}
+	_ = s.repo.SavePointAlerts(ctx, pointLat, pointLon, fresh)
+
+	resp.Alerts = fresh.Alerts
+	resp.FreshUntil = alertsFreshUntil(fresh)
+	return resp, nil
+}
+
+// WithAlertGeometry returns alerts with Geometry set to the area each covers.
+// Storm-based warnings keep the polygon the NWS drew; alerts issued by zone
+// get the shapes of their affected zones, merged into a MultiPolygon when
+// there are several. Zone documents are cached for PointMetadataTTL, since
+// zones are rarely redrawn, and a zone whose shape can't be fetched is left
+// out rather than failing the alerts.
+func (s *WeatherService) WithAlertGeometry(ctx context.Context, alerts []models.Alert) []models.Alert {
+	out := make([]models.Alert, len(alerts))
+	for i, alert := range alerts {
+		if alert.Geometry == nil {
+			var shapes []*models.Geometry
+			for _, zone := range alert.AffectedZones {
+				if shape := s.zoneShape(ctx, zone); shape != nil {
+					shapes = append(shapes, shape)
+				}
+			}
+			if len(shapes) == 1 {
+				alert.Geometry = shapes[0]
+			} else {
+				alert.Geometry = mergePolygons(shapes)
+			}
+		}
+		out[i] = alert
+	}
+	return out
+}
+
+// zoneShape returns the shape of an NWS zone, fetching its document when the
+// cached one is missing or stale; nil when it can't be found
+func (s *WeatherService) zoneShape(ctx context.Context, zone string) *models.Geometry {
+	cached, err := s.repo.GetRawDocument(ctx, repository.RawZone, zone)
+	if (err != nil || !s.repo.IsRawDocumentFresh(repository.RawZone, cached)) && s.nwsClient != nil {
+		var doc *models.RawDocument
+		err := s.upstream(func() (err error) {
+			doc, err = s.nwsClient.GetZone(ctx, zone)
+			return err
+		})
+		if err == nil {
+			cached = doc
+			_ = s.repo.SaveRawDocument(ctx, repository.RawZone, zone, doc)
+		} else {
+			log.Printf("Zone %s lookup failed: %v", zone, err)
+		}
+	}
+	if cached == nil {
+		return nil
+	}
+	shape, err := zoneGeometry(cached)
+	if err != nil {
+		log.Printf("Zone %s: %v", zone, err)
+		return nil
+	}
+	return shape
+}
+
+// mergePolygons combines polygon and multipolygon shapes into one
+// MultiPolygon; nil when there are none
+func mergePolygons(shapes []*models.Geometry) *models.Geometry {
+	var polygons []json.RawMessage
+	for _, shape := range shapes {
+		switch shape.Type {
+		case "Polygon":
+			polygons = append(polygons, shape.Coordinates)
+		case "MultiPolygon":
+			var parts []json.RawMessage
+			if err := json.Unmarshal(shape.Coordinates, &parts); err == nil {
+				polygons = append(polygons, parts...)
+			}
+		}
+	}
+	if len(polygons) == 0 {
+		return nil
+	}
+	coordinates, err := json.Marshal(polygons)
+	if err != nil {
+		return nil
+	}
+	return &models.Geometry{Type: "MultiPolygon", Coordinates: coordinates}
+}
+
+// alertsFreshUntil is when cached alerts go stale: after AlertCacheTTL, or when
+// the first of them expires
+func alertsFreshUntil(cache *models.AlertCache) time.Time {
+	until := cache.Timestamp.Add(repository.AlertCacheTTL)
+	for _, alert := range cache.Alerts {
+		if !alert.Expires.IsZero() && alert.Expires.Before(until) {
+			until = alert.Expires
+		}
+	}
+	return until
+}
+
+// resolveForecastZone maps a coordinate to its NWS forecast zone, caching the mapping
+func (s *WeatherService) resolveForecastZone(ctx context.Context, lat, lon float64) (string, error) {
+	if err := s.requireNWS(); err != nil {
+		return "", err
+	}
+	if err := s.checkCoverage(lat, lon); err != nil {
+		return "", err
+	}
+	// The grid mapping's points document names the zone too
+	point, err := s.repo.GetGridPoint(ctx, normalizePointCoordinate(lat), normalizePointCoordinate(lon))
+	if err == nil && s.repo.IsGridPointFresh(point) && point.ForecastZone != "" {
+		return point.ForecastZone, nil
+	}
+	meta, err := s.repo.GetPointMetadata(ctx, lat, lon)
+	if err == nil && s.repo.IsPointMetadataFresh(meta) {
+		return meta.ForecastZone, nil
+	}
+	if err := s.checkUncovered(ctx, lat, lon); err != nil {
+		return "", err
+	}
+
+	zone, err := s.nwsClient.GetForecastZone(ctx, lat, lon)
+	if err != nil {
+		s.recordCoverage(ctx, lat, lon, err)
+		return "", err
+	}
+
+	_ = s.repo.SavePointMetadata(ctx, &models.PointMetadata{
+		Latitude:     lat,
+		Longitude:    lon,
+		ForecastZone: zone,
+		Timestamp:    time.Now(),
+	})
+
+	return zone, nil
+}
+
+// NewOrUpdatedAlerts filters alerts down to those that are new or materially
+// updated since they were last seen for the zone, and records them as seen.
+// An NWS update references the alerts it supersedes, so an extension (new
+// expiry) is reported while a re-issue with unchanged content is not.
+func (s *WeatherService) NewOrUpdatedAlerts(ctx context.Context, zone string, alerts []models.Alert) ([]models.Alert, error) {
+	var changed []models.Alert
+
+	for _, alert := range alerts {
+		fingerprint := alertFingerprint(alert)
+
+		previous, err := s.repo.FindSeenAlert(ctx, append([]string{alert.ID}, alert.References...))
+		if err != nil {
+			return nil, err
+		}
+		if previous != fingerprint {
+			changed = append(changed, alert)
+		}
+
+		if err := s.repo.MarkAlertSeen(ctx, zone, alert, fingerprint); err != nil {
+			return nil, err
+		}
+	}
+
+	return changed, nil
+}
+
+// alertFingerprint hashes the fields whose change makes an alert worth re-notifying.
+// The headline is excluded because the NWS rewrites it on every re-issue.
+func alertFingerprint(alert models.Alert) string {
+	parts := []string{
+		alert.Event,
+		alert.Severity,
+		alert.Urgency,
+		strconv.FormatInt(alert.Expires.Unix(), 10),
+		strconv.FormatBool(alert.MessageType == "Cancel"),
+	}
+	if alert.Onset != nil {
+		parts = append(parts, strconv.FormatInt(alert.Onset.Unix(), 10))
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// activeAlerts drops alerts whose expiry has passed
+func activeAlerts(alerts []models.Alert, now time.Time) []models.Alert {
+	active := make([]models.Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		if alert.Expires.IsZero() || alert.Expires.After(now) {
+			active = append(active, alert)
+		}
+	}
+	return active
+}
+
+// AlertSeverities and AlertUrgencies are the NWS alert severities and
+// urgencies, most serious first, as ?severity= and ?urgency= accept them
+var (
+	AlertSeverities = []string{"Extreme", "Severe", "Moderate", "Minor", "Unknown"}
+	AlertUrgencies  = []string{"Immediate", "Expected", "Future", "Past", "Unknown"}
+)
+
+// ErrInvalidAlertSeverity and ErrInvalidAlertUrgency are returned for alert
+// filters naming a severity or urgency the NWS doesn't use
+var (
+	ErrInvalidAlertSeverity = errors.New("unknown alert severity")
+	ErrInvalidAlertUrgency  = errors.New("unknown alert urgency")
+)
+
+// AlertFilter narrows a coordinate's alerts after they are read from the
+// cache, so every filter shares one upstream fetch. The zero value keeps
+// every alert.
+type AlertFilter struct {
+	// Severities and Urgencies keep alerts with any of the listed values;
+	// empty keeps any
+	Severities []string
+	Urgencies  []string
+	// Event keeps alerts whose event contains it, ignoring case
+	Event string
+	// ActiveOnly keeps only alerts in effect now: begun, by their onset, and
+	// not yet expired
+	ActiveOnly bool
+}
+
+// ParseAlertFilter builds an AlertFilter from comma-separated severities and
+// urgencies, matched case-insensitively and kept in NWS spelling, an event
+// substring, and the active-only flag
+func ParseAlertFilter(severity, urgency, event string, activeOnly bool) (AlertFilter, error) {
+	severities, err := parseAlertValues(severity, AlertSeverities)
+	if err != nil {
+		return AlertFilter{}, fmt.Errorf("%w %v", ErrInvalidAlertSeverity, err)
+	}
+	urgencies, err := parseAlertValues(urgency, AlertUrgencies)
+	if err != nil {
+		return AlertFilter{}, fmt.Errorf("%w %v", ErrInvalidAlertUrgency, err)
+	}
+	return AlertFilter{
+		Severities: severities,
+		Urgencies:  urgencies,
+		Event:      strings.TrimSpace(event),
+		ActiveOnly: activeOnly,
+	}, nil
+}
+
+// parseAlertValues splits a comma-separated list, mapping each entry to its
+// spelling in accepted; blank entries are skipped
+func parseAlertValues(list string, accepted []string) ([]string, error) {
+	var values []string
+	for _, v := range strings.Split(list, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		i := slices.IndexFunc(accepted, func(a string) bool { return strings.EqualFold(a, v) })
+		if i < 0 {
+			return nil, fmt.Errorf("%q", v)
+		}
+		values = append(values, accepted[i])
+	}
+	return values, nil
+}
+
+// Apply returns the alerts the filter keeps at the given time, as a new slice
+func (f AlertFilter) Apply(alerts []models.Alert, now time.Time) []models.Alert {
+	kept := make([]models.Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		if f.keeps(alert, now) {
+			kept = append(kept, alert)
+		}
+	}
+	return kept
+}
+
+// keeps reports whether an alert passes every part of the filter
+func (f AlertFilter) keeps(alert models.Alert, now time.Time) bool {
+	if len(f.Severities) > 0 && !slices.Contains(f.Severities, alert.Severity) {
+		return false
+	}
+	if len(f.Urgencies) > 0 && !slices.Contains(f.Urgencies, alert.Urgency) {
+		return false
+	}
+	if f.Event != "" && !strings.Contains(strings.ToLower(alert.Event), strings.ToLower(f.Event)) {
+		return false
+	}
+	if f.ActiveOnly {
+		if alert.Onset != nil && alert.Onset.After(now) {
+			return false
+		}
+		if !alert.Expires.IsZero() && !alert.Expires.After(now) {
+			return false
+		}
+	}
+	return true
+}
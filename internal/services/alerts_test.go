@@ -0,0 +1,344 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+// pointAlertsFixture is an /alerts/active?point= response trimmed to the fields
+// we read, with its expiry times replaced by %[1]s (future) and %[2]s (past)
+const pointAlertsFixture = `{
+  "type": "FeatureCollection",
+  "features": [
+    {"id": "https://api.weather.gov/alerts/urn:oid:2.49.0.1.840.0.tor1", "type": "Feature", "properties": {
+      "id": "urn:oid:2.49.0.1.840.0.tor1",
+      "areaDesc": "Kings, NY; Queens, NY",
+      "affectedZones": ["https://api.weather.gov/zones/county/NYC047", "https://api.weather.gov/zones/county/NYC081"],
+      "references": [],
+      "sent": "2024-06-01T17:42:00-04:00",
+      "onset": "2024-06-01T17:42:00-04:00",
+      "expires": %[1]q,
+      "messageType": "Alert",
+      "severity": "Extreme",
+      "urgency": "Immediate",
+      "event": "Tornado Warning",
+      "headline": "Tornado Warning issued June 1 at 5:42PM EDT until June 1 at 6:15PM EDT by NWS Upton NY"
+    }},
+    {"id": "https://api.weather.gov/alerts/urn:oid:2.49.0.1.840.0.svr2", "type": "Feature", "properties": {
+      "id": "urn:oid:2.49.0.1.840.0.svr2",
+      "areaDesc": "New York (Manhattan)",
+      "affectedZones": ["https://api.weather.gov/zones/forecast/NYZ072"],
+      "references": [{"identifier": "urn:oid:2.49.0.1.840.0.svr1", "sender": "w-nws.webmaster@noaa.gov"}],
+      "sent": "2024-06-01T16:10:00-04:00",
+      "onset": null,
+      "expires": %[1]q,
+      "messageType": "Update",
+      "severity": "Moderate",
+      "urgency": "Expected",
+      "event": "Heat Advisory",
+      "headline": "Heat Advisory issued June 1 at 4:10PM EDT until June 1 at 8:00PM EDT by NWS Upton NY"
+    }},
+    {"id": "https://api.weather.gov/alerts/urn:oid:2.49.0.1.840.0.old3", "type": "Feature", "properties": {
+      "id": "urn:oid:2.49.0.1.840.0.old3",
+      "affectedZones": ["https://api.weather.gov/zones/forecast/NYZ072"],
+      "references": [],
+      "sent": "2024-06-01T05:00:00-04:00",
+      "expires": %[2]q,
+      "messageType": "Alert",
+      "severity": "Minor",
+      "urgency": "Expected",
+      "event": "Air Quality Alert",
+      "headline": "Air Quality Alert issued June 1 at 5:00AM EDT"
+    }}
+  ]
+}`
+
+func newTestRepo(t *testing.T) *repository.WeatherRepository {
+	t.Helper()
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return repository.NewWeatherRepository(db, nil)
+}
+
+func TestNewOrUpdatedAlerts(t *testing.T) {
+	service := NewWeatherService(newTestRepo(t), nil)
+	sent := time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)
+
+	original := models.Alert{
+		ID:       "urn:alert:1",
+		Event:    "Winter Storm Warning",
+		Severity: "Severe",
+		Urgency:  "Expected",
+		Headline: "Winter Storm Warning issued January 15 at 3:00AM EST",
+		Sent:     sent,
+		Expires:  sent.Add(12 * time.Hour),
+	}
+
+	changed, err := service.NewOrUpdatedAlerts(context.Background(), "NYZ072", []models.Alert{original})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 1 {
+		t.Fatalf("first sighting returned %d alerts; want 1", len(changed))
+	}
+
+	changed, _ = service.NewOrUpdatedAlerts(context.Background(), "NYZ072", []models.Alert{original})
+	if len(changed) != 0 {
+		t.Errorf("repeat poll returned %d alerts; want 0", len(changed))
+	}
+
+	reissued := original
+	reissued.ID = "urn:alert:2"
+	reissued.MessageType = "Update"
+	reissued.Headline = "Winter Storm Warning issued January 15 at 9:00AM EST"
+	reissued.Sent = sent.Add(6 * time.Hour)
+	reissued.References = []string{original.ID}
+
+	changed, _ = service.NewOrUpdatedAlerts(context.Background(), "NYZ072", []models.Alert{reissued})
+	if len(changed) != 0 {
+		t.Errorf("re-issue with unchanged content returned %d alerts; want 0", len(changed))
+	}
+
+	extended := reissued
+	extended.ID = "urn:alert:3"
+	extended.Sent = sent.Add(9 * time.Hour)
+	extended.Expires = sent.Add(24 * time.Hour)
+	extended.References = []string{original.ID, reissued.ID}
+
+	changed, _ = service.NewOrUpdatedAlerts(context.Background(), "NYZ072", []models.Alert{extended})
+	if len(changed) != 1 || changed[0].ID != extended.ID {
+		t.Errorf("extension returned %v; want the extended alert", changed)
+	}
+}
+
+func TestGetAlertsSharesFetchAcrossZone(t *testing.T) {
+	var alertFetches int32
+	expires := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	expired := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/points/"):
+			fmt.Fprint(w, `{"properties": {"forecastZone": "https://api.weather.gov/zones/forecast/NYZ072"}}`)
+		case r.URL.Path == "/alerts/active":
+			atomic.AddInt32(&alertFetches, 1)
+			if zone := r.URL.Query().Get("zone"); zone != "NYZ072" {
+				t.Errorf("alerts requested for zone %q; want NYZ072", zone)
+			}
+			fmt.Fprintf(w, `{"features": [
+				{"properties": {"id": "a1", "event": "Wind Advisory", "expires": %q,
+				  "affectedZones": ["https://api.weather.gov/zones/forecast/NYZ072"]}},
+				{"properties": {"id": "a2", "event": "Frost Advisory", "expires": %q}}
+			]}`, expires, expired)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	service := NewWeatherService(newTestRepo(t), newTestNWSClient(server))
+
+	first, err := service.GetAlerts(context.Background(), 40.7128, -74.0060)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 1 || first[0].ID != "a1" {
+		t.Fatalf("got %v; want only the unexpired alert a1", first)
+	}
+	if got := first[0].AffectedZones; len(got) != 1 || got[0] != "NYZ072" {
+		t.Errorf("affected zones = %v; want [NYZ072]", got)
+	}
+
+	if _, err := service.GetAlerts(context.Background(), 40.7306, -73.9866); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&alertFetches); n != 1 {
+		t.Errorf("alerts fetched %d times for one zone; want 1", n)
+	}
+}
+
+func TestGetPointAlerts(t *testing.T) {
+	var alertFetches int32
+	expires := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	expired := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/alerts/active" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&alertFetches, 1)
+		if point := r.URL.Query().Get("point"); point != "40.7128,-74.0060" {
+			t.Errorf("alerts requested for point %q; want 40.7128,-74.0060", point)
+		}
+		fmt.Fprintf(w, pointAlertsFixture, expires, expired)
+	}))
+	defer server.Close()
+
+	service := NewWeatherService(newTestRepo(t), newTestNWSClient(server))
+
+	// Both coordinates normalize to the same point, so the second is a cache hit
+	for i, lon := range []float64{-74.0060, -74.00601} {
+		resp, err := service.GetPointAlerts(context.Background(), 40.7128, lon)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.CacheHit != (i > 0) {
+			t.Errorf("call %d: CacheHit = %v; want %v", i+1, resp.CacheHit, i > 0)
+		}
+		if len(resp.Alerts) != 2 {
+			t.Fatalf("call %d: got %d alerts; want the 2 unexpired ones", i+1, len(resp.Alerts))
+		}
+
+		tornado, heat := resp.Alerts[0], resp.Alerts[1]
+		if tornado.Event != "Tornado Warning" || tornado.Severity != "Extreme" || tornado.Urgency != "Immediate" || tornado.Onset == nil {
+			t.Errorf("call %d: first alert = %+v; want the tornado warning with an onset", i+1, tornado)
+		}
+		if got := tornado.AffectedZones; len(got) != 2 || got[0] != "NYC047" || got[1] != "NYC081" {
+			t.Errorf("call %d: affected zones = %v; want [NYC047 NYC081]", i+1, got)
+		}
+		if heat.Onset != nil || len(heat.References) != 1 || heat.References[0] != "urn:oid:2.49.0.1.840.0.svr1" {
+			t.Errorf("call %d: second alert = %+v; want no onset and one reference", i+1, heat)
+		}
+		if time.Until(resp.FreshUntil) > repository.AlertCacheTTL {
+			t.Errorf("call %d: fresh until %v; want within the alert TTL", i+1, resp.FreshUntil)
+		}
+	}
+	if n := atomic.LoadInt32(&alertFetches); n != 1 {
+		t.Errorf("alerts fetched %d times; want 1", n)
+	}
+}
+
+func TestAlertFilter(t *testing.T) {
+	now := time.Date(2024, 6, 1, 22, 0, 0, 0, time.UTC)
+	later, earlier := now.Add(time.Hour), now.Add(-time.Hour)
+	alerts := []models.Alert{
+		{ID: "tor", Event: "Tornado Warning", Severity: "Extreme", Urgency: "Immediate", Onset: &earlier, Expires: later},
+		{ID: "tow", Event: "Tornado Watch", Severity: "Severe", Urgency: "Future", Onset: &later, Expires: later.Add(time.Hour)},
+		{ID: "svr", Event: "Severe Thunderstorm Warning", Severity: "Severe", Urgency: "Immediate", Expires: later},
+		{ID: "mws", Event: "Marine Weather Statement", Severity: "Minor", Urgency: "Expected", Expires: earlier},
+	}
+
+	tests := []struct {
+		name                            string
+		severity, urgency, event        string
+		activeOnly                      bool
+		want                            string
+		wantSeverityErr, wantUrgencyErr bool
+	}{
+		{name: "no filter", want: "tor,tow,svr,mws"},
+		{name: "severities in any case", severity: "severe, EXTREME", want: "tor,tow,svr"},
+		{name: "event substring", event: "tornado", want: "tor,tow"},
+		{name: "severity and event", severity: "Severe", event: "Tornado", want: "tow"},
+		{name: "severity and urgency", severity: "Severe,Extreme", urgency: "Immediate", want: "tor,svr"},
+		{name: "active only leaves out future onsets and expiries", activeOnly: true, want: "tor,svr"},
+		{name: "every filter", severity: "Extreme,Severe", urgency: "immediate,future", event: "Tornado", activeOnly: true, want: "tor"},
+		{name: "nothing matches", severity: "Minor", event: "Tornado", want: ""},
+		{name: "unknown severity", severity: "Severe,Dangerous", wantSeverityErr: true},
+		{name: "unknown urgency", urgency: "Soon", wantUrgencyErr: true},
+	}
+	for _, tt := range tests {
+		filter, err := ParseAlertFilter(tt.severity, tt.urgency, tt.event, tt.activeOnly)
+		if errors.Is(err, ErrInvalidAlertSeverity) != tt.wantSeverityErr || errors.Is(err, ErrInvalidAlertUrgency) != tt.wantUrgencyErr {
+			t.Errorf("%s: error = %v", tt.name, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		var ids []string
+		for _, alert := range filter.Apply(alerts, now) {
+			ids = append(ids, alert.ID)
+		}
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("%s: kept %q; want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWithAlertGeometry(t *testing.T) {
+	zoneFetches := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/alerts/active":
+			http.ServeFile(w, r, filepath.Join("testdata", "nws_alerts_geometry.json"))
+		case "/zones/forecast/NYZ072", "/zones/county/NYC061":
+			zoneFetches[r.URL.Path]++
+			http.ServeFile(w, r, filepath.Join("testdata", "nws_zone_NYZ072.json"))
+		default:
+			zoneFetches[r.URL.Path]++
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client := newTestNWSClient(server)
+	service := NewWeatherService(newTestRepo(t), client)
+
+	alerts, err := client.GetActivePointAlerts(context.Background(), 40.7128, -74.006)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 2 || alerts[0].Geometry == nil || alerts[0].Geometry.Type != "Polygon" || alerts[1].Geometry != nil {
+		t.Fatalf("alerts = %+v; want a polygon warning and a zone-only advisory", alerts)
+	}
+	alerts = append(alerts,
+		models.Alert{ID: "two zones", AffectedZones: []string{"NYZ072", "NYC061"}},
+		models.Alert{ID: "unknown zone", AffectedZones: []string{"NYZ999"}},
+	)
+
+	tests := []struct {
+		id, want string
+	}{
+		// The NWS's polygon is kept, not replaced by its counties
+		{"urn:oid:2.49.0.1.840.0.svr1", "Polygon [[[-74.13,40.79]"},
+		{"urn:oid:2.49.0.1.840.0.heat1", "Polygon [[[-74.0179,40.7012]"},
+		{"two zones", "MultiPolygon of 2"},
+		{"unknown zone", "none"},
+	}
+	// Zone shapes are fetched once, then served from the cache
+	for pass := 1; pass <= 2; pass++ {
+		got := service.WithAlertGeometry(context.Background(), alerts)
+		for i, tt := range tests {
+			g := got[i].Geometry
+			var desc string
+			switch {
+			case g == nil:
+				desc = "none"
+			case g.Type == "MultiPolygon":
+				var polygons []json.RawMessage
+				if err := json.Unmarshal(g.Coordinates, &polygons); err != nil {
+					t.Fatal(err)
+				}
+				desc = fmt.Sprintf("MultiPolygon of %d", len(polygons))
+			default:
+				desc = g.Type + " " + strings.ReplaceAll(string(g.Coordinates), " ", "")
+			}
+			if got[i].ID != tt.id || !strings.HasPrefix(desc, tt.want) {
+				t.Errorf("pass %d: %s geometry = %.40s; want %s", pass, got[i].ID, desc, tt.want)
+			}
+		}
+	}
+	if alerts[1].Geometry != nil {
+		t.Error("the alerts passed in were modified")
+	}
+	for path, want := range map[string]int{"/zones/forecast/NYZ072": 1, "/zones/county/NYC061": 1, "/zones/forecast/NYZ999": 2} {
+		if zoneFetches[path] != want {
+			t.Errorf("%s fetched %d times; want %d", path, zoneFetches[path], want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"weather-api-go/internal/astronomy"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+// ErrInvalidDate is returned when a calendar day is malformed
+var ErrInvalidDate = errors.New("invalid date")
+
+// GetAstronomy computes a coordinate's sunrise, sunset, solar noon, day length,
+// and moon phase for a YYYY-MM-DD day, an empty date being today. Nothing is
+// fetched: times are given in the tz override, else the location's time zone
+// when its NWS grid mapping is cached, else UTC.
+func (s *WeatherService) GetAstronomy(ctx context.Context, lat, lon float64, date string, tz *time.Location) (*models.AstronomyResponse, error) {
+	loc := tz
+	if loc == nil {
+		loc = s.cachedTimeZone(ctx, lat, lon)
+	}
+
+	day := time.Now().In(loc)
+	if date != "" {
+		var err error
+		if day, err = time.ParseInLocation(repository.DayFormat, date, loc); err != nil {
+			return nil, fmt.Errorf("%w: date must be a YYYY-MM-DD day", ErrInvalidDate)
+		}
+	}
+	year, month, d := day.Date()
+	noon := time.Date(year, month, d, 12, 0, 0, 0, loc)
+
+	sun := astronomy.Sun(noon, lat, lon)
+	moon := astronomy.Moon(noon)
+	return &models.AstronomyResponse{
+		Latitude:         lat,
+		Longitude:        lon,
+		Date:             noon.Format(repository.DayFormat),
+		TimeZone:         loc.String(),
+		Sunrise:          sun.Sunrise,
+		Sunset:           sun.Sunset,
+		PolarCondition:   sun.Polar,
+		SolarNoon:        sun.SolarNoon,
+		DayLengthSeconds: int64(sun.DayLength / time.Second),
+		Moon: models.Moon{
+			Phase:        moon.Phase,
+			Illumination: math.Round(moon.Illumination*1000) / 1000,
+			AgeDays:      math.Round(moon.AgeDays*10) / 10,
+		},
+	}, nil
+}
+
+// cachedTimeZone returns a coordinate's time zone from its cached NWS grid
+// mapping, without resolving the point upstream, or UTC when none is cached
+func (s *WeatherService) cachedTimeZone(ctx context.Context, lat, lon float64) *time.Location {
+	point, err := s.repo.GetGridPoint(ctx, normalizePointCoordinate(lat), normalizePointCoordinate(lon))
+	if err == nil {
+		if loc := loadZone(point.TimeZone); loc != nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+func TestGetAstronomyTimeZone(t *testing.T) {
+	repo := newTestRepo(t)
+	service := NewWeatherService(repo, nil)
+	chicago, _ := time.LoadLocation("America/Chicago")
+
+	// Before the location's grid mapping is cached the times are in UTC
+	got, err := service.GetAstronomy(context.Background(), 40.7128, -74.006, "2024-06-20", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TimeZone != "UTC" || got.Date != "2024-06-20" || got.Sunrise == nil || got.Sunrise.Format(time.RFC3339) != "2024-06-20T09:24:48Z" {
+		t.Errorf("uncached = %s on %s, sunrise %v; want UTC on 2024-06-20, sunrise 09:24:48Z", got.TimeZone, got.Date, got.Sunrise)
+	}
+
+	err = repo.SaveGridPoint(context.Background(), &models.GridPoint{
+		Latitude: 40.7128, Longitude: -74.006, GridID: "OKX", GridX: 33, GridY: 35,
+		City: "New York", State: "NY", TimeZone: "America/New_York", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		tz          *time.Location
+		wantZone    string
+		wantSunrise string
+	}{
+		{nil, "America/New_York", "2024-06-20T05:24:48-04:00"},
+		{chicago, "America/Chicago", "2024-06-20T04:24:48-05:00"},
+	}
+	for _, tt := range tests {
+		got, err := service.GetAstronomy(context.Background(), 40.7128, -74.006, "2024-06-20", tt.tz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.TimeZone != tt.wantZone || got.Sunrise == nil || got.Sunrise.Format(time.RFC3339) != tt.wantSunrise {
+			t.Errorf("tz %v: %s, sunrise %v; want %s, sunrise %s", tt.tz, got.TimeZone, got.Sunrise, tt.wantZone, tt.wantSunrise)
+		}
+		if got.DayLengthSeconds != 54345 || got.Moon.Phase != "waxing_gibbous" {
+			t.Errorf("tz %v: day length %ds, moon %+v; want 54345s and waxing gibbous", tt.tz, got.DayLengthSeconds, got.Moon)
+		}
+	}
+}
+
+func TestGetAstronomyDate(t *testing.T) {
+	service := NewWeatherService(newTestRepo(t), nil)
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+
+	// An empty date is today where the times are given
+	got, err := service.GetAstronomy(context.Background(), 35.6762, 139.6503, "", tokyo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if today := time.Now().In(tokyo).Format(time.DateOnly); got.Date != today {
+		t.Errorf("date = %s; want today in Tokyo, %s", got.Date, today)
+	}
+
+	for _, date := range []string{"2024-13-01", "06/20/2024", "2024-06-20T00:00:00Z"} {
+		if _, err := service.GetAstronomy(context.Background(), 35.6762, 139.6503, date, nil); !errors.Is(err, ErrInvalidDate) {
+			t.Errorf("date %q error = %v; want ErrInvalidDate", date, err)
+		}
+	}
+
+	// Polar days name the reason rather than inventing times
+	polar, err := service.GetAstronomy(context.Background(), 69.6492, 18.9553, "2024-12-21", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if polar.Sunrise != nil || polar.Sunset != nil || polar.PolarCondition != "polar_night" || polar.DayLengthSeconds != 0 {
+		t.Errorf("Tromsø in December = %+v; want polar night", polar)
+	}
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUpstreamBackoff is the cause of a *ShedError returned, without calling
+// the NWS, while it has asked with a Retry-After header for requests to stop
+var ErrUpstreamBackoff = errors.New("NWS asked for requests to back off")
+
+// MaxUpstreamBackoff caps how long a Retry-After is honored, so a mistaken
+// header can't stop NWS requests for days
+const MaxUpstreamBackoff = 10 * time.Minute
+
+// retryAfter returns how long a 429 or 503 response asks requests to wait,
+// from its Retry-After header in either delay-seconds or HTTP-date form. It
+// reports false for other responses, and for headers that are missing,
+// malformed, or already past.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return 0, false
+	}
+	header := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if header == "" {
+		return 0, false
+	}
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(header); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(header); err == nil {
+		wait = at.Sub(now)
+	}
+	if wait <= 0 {
+		return 0, false
+	}
+	return min(wait, MaxUpstreamBackoff), true
+}
+
+// upstreamBackoff remembers until when the NWS asked for requests to stop,
+// shared by copies of a client
+type upstreamBackoff struct {
+	now func() time.Time
+
+	mu    sync.Mutex
+	until time.Time
+}
+
+// check returns a *ShedError caused by ErrUpstreamBackoff while backing off
+func (b *upstreamBackoff) check() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if wait := b.until.Sub(b.now()); wait > 0 {
+		return &ShedError{RetryAfter: wait, Cause: ErrUpstreamBackoff}
+	}
+	return nil
+}
+
+// observe starts or extends the backoff when a response asks for one,
+// returning how long it asked for
+func (b *upstreamBackoff) observe(resp *http.Response) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	wait, ok := retryAfter(resp, now)
+	if ok && now.Add(wait).After(b.until) {
+		b.until = now.Add(wait)
+	}
+	return wait, ok
+}
+
+// BackoffUntil reports until when the NWS has asked for requests to stop, or
+// the zero time when it hasn't
+func (c *NWSAPIClient) BackoffUntil() time.Time {
+	b := c.backoff
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.until.After(b.now()) {
+		return time.Time{}
+	}
+	return b.until
+}
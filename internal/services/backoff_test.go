@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		status int
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"seconds", http.StatusTooManyRequests, "30", 30 * time.Second, true},
+		{"padded seconds", http.StatusServiceUnavailable, " 5 ", 5 * time.Second, true},
+		{"HTTP date", http.StatusServiceUnavailable, "Mon, 15 Jan 2024 10:32:00 GMT", 2 * time.Minute, true},
+		{"capped", http.StatusTooManyRequests, "86400", MaxUpstreamBackoff, true},
+		{"past date", http.StatusTooManyRequests, "Mon, 15 Jan 2024 10:29:00 GMT", 0, false},
+		{"zero", http.StatusTooManyRequests, "0", 0, false},
+		{"negative", http.StatusTooManyRequests, "-5", 0, false},
+		{"malformed", http.StatusTooManyRequests, "soon", 0, false},
+		{"missing", http.StatusTooManyRequests, "", 0, false},
+		{"other status", http.StatusInternalServerError, "30", 0, false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		if tt.header != "" {
+			resp.Header.Set("Retry-After", tt.header)
+		}
+		got, ok := retryAfter(resp, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: retryAfter = %v, %v; want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGetWeatherUpstreamBackoff(t *testing.T) {
+	var requests int32
+	nws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer nws.Close()
+
+	now := time.Now()
+	client := NewNWSAPIClient(WithBaseURL(nws.URL), WithHTTPClient(nws.Client()), WithRetry(RetryConfig{MaxAttempts: 3, MaxElapsed: 10 * time.Second}))
+	client.backoff.now = func() time.Time { return now }
+	repo := newTestRepo(t)
+	err := repo.SaveToCache(context.Background(), &models.WeatherCache{
+		Latitude: 40.7128, Longitude: -74.006, Forecast: "Sunny", Timestamp: time.Now().Add(-2 * time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewWeatherService(repo, client)
+
+	// The 429 starts the backoff; its Retry-After outlasts the retry budget
+	var shed *ShedError
+	if _, err := service.GetWeather(context.Background(), 39.9526, -75.1652); !errors.As(err, &shed) || !errors.Is(err, ErrUpstreamBackoff) || shed.RetryAfter != 30*time.Second {
+		t.Fatalf("throttled request: error = %v; want a shed for 30s with ErrUpstreamBackoff", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Fatalf("%d NWS requests; want 1, without retries", got)
+	}
+	if until := client.BackoffUntil(); !until.Equal(now.Add(30 * time.Second)) {
+		t.Errorf("BackoffUntil = %v; want 30s from now", until)
+	}
+	if deps := service.CheckDependencies(context.Background()); deps.NWS.BackoffUntil == nil {
+		t.Error("health doesn't report the backoff")
+	}
+
+	// During the window nothing reaches the NWS
+	now = now.Add(10 * time.Second)
+	if _, err := service.GetWeather(context.Background(), 39.9526, -75.1652); !errors.As(err, &shed) || shed.RetryAfter != 20*time.Second {
+		t.Errorf("uncached coordinate: error = %v; want a shed for the 20s left", err)
+	}
+	resp, err := service.GetWeather(context.Background(), 40.7128, -74.006)
+	if err != nil || resp.Source != SourceStale || resp.Forecast != "Sunny" {
+		t.Errorf("cached coordinate = %+v, %v; want the stale entry", resp, err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("%d NWS requests during the backoff; want none beyond the first", got)
+	}
+
+	// Once it has passed, requests go through again
+	now = now.Add(20 * time.Second)
+	if !client.BackoffUntil().IsZero() {
+		t.Errorf("BackoffUntil = %v after the window; want zero", client.BackoffUntil())
+	}
+	service.GetWeather(context.Background(), 39.9526, -75.1652)
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("%d NWS requests after the backoff; want 2", got)
+	}
+}
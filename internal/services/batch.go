@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"weather-api-go/internal/models"
+)
+
+// DefaultBatchConcurrency is how many batch coordinates are looked up at once
+// when no concurrency is configured
+const DefaultBatchConcurrency = 8
+
+// WithBatchConcurrency bounds how many coordinates of a batch are looked up at
+// once. Upstream fetches remain subject to the load-shedding limiter.
+func WithBatchConcurrency(n int) WeatherServiceOption {
+	return func(s *WeatherService) {
+		if n > 0 {
+			s.batchConcurrency = n
+		}
+	}
+}
+
+// BatchWeatherResult is the outcome of one coordinate in a batch lookup:
+// either Weather or Err is set
+type BatchWeatherResult struct {
+	Weather *models.WeatherResponse
+	Err     error
+}
+
+// GetWeatherBatch retrieves weather for many coordinates through GetWeather,
+// returning one result per coordinate in input order. Repeated coordinates are
+// looked up once, and a failed coordinate does not affect the others.
+func (s *WeatherService) GetWeatherBatch(ctx context.Context, coords []models.Coordinates) []BatchWeatherResult {
+	// Map each distinct coordinate to the first index it appears at
+	first := make(map[models.Coordinates]int, len(coords))
+	unique := make([]int, 0, len(coords))
+	for i, c := range coords {
+		if _, ok := first[c]; !ok {
+			first[c] = i
+			unique = append(unique, i)
+		}
+	}
+
+	results := make([]BatchWeatherResult, len(coords))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(s.batchConcurrency, len(unique)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				weather, err := s.GetWeather(ctx, coords[i].Latitude, coords[i].Longitude)
+				results[i] = BatchWeatherResult{Weather: weather, Err: err}
+			}
+		}()
+	}
+	for _, i := range unique {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, c := range coords {
+		results[i] = results[first[c]]
+	}
+	return results
+}
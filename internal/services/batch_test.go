@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"weather-api-go/internal/models"
+)
+
+func TestGetWeatherBatch(t *testing.T) {
+	server, hits := fakeGridNWS(t, http.StatusOK)
+	service := NewWeatherService(newTestRepo(t), newTestNWSClient(server), WithBatchConcurrency(4))
+
+	nyc := models.Coordinates{Latitude: 40.7128, Longitude: -74.0060}
+	north := models.Coordinates{Latitude: 41.5, Longitude: -74.0}
+	atlantic := models.Coordinates{Latitude: 35, Longitude: -60}
+	results := service.GetWeatherBatch(context.Background(), []models.Coordinates{nyc, north, nyc, atlantic, nyc})
+
+	if len(results) != 5 {
+		t.Fatalf("got %d results; want one per coordinate", len(results))
+	}
+	for _, i := range []int{0, 1, 2, 4} {
+		if results[i].Err != nil || results[i].Weather == nil || results[i].Weather.Forecast != "Partly Cloudy" {
+			t.Errorf("results[%d] = %+v, %v; want the forecast", i, results[i].Weather, results[i].Err)
+		}
+	}
+	if !errors.Is(results[3].Err, ErrOutsideCoverage) {
+		t.Errorf("results[3] error = %v; want ErrOutsideCoverage without failing the batch", results[3].Err)
+	}
+
+	// The repeated coordinate is looked up once, so its cell is fetched once
+	// even though the lookups run concurrently
+	if got := atomic.LoadInt32(hits["points"]); got != 2 {
+		t.Errorf("points resolved %d times; want 2 (one per distinct coordinate)", got)
+	}
+	for _, cell := range []string{"OKX/33,35", "OKX/40,40"} {
+		if got := atomic.LoadInt32(hits[cell]); got != 1 {
+			t.Errorf("forecast for %s fetched %d times; want 1", cell, got)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"weather-api-go/internal/metrics"
+)
+
+// ErrCircuitOpen is the cause of a *ShedError returned without calling the
+// NWS while its circuit breaker is open
+var ErrCircuitOpen = errors.New("NWS circuit breaker is open")
+
+// Circuit breaker states reported by CircuitState
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// CircuitBreakerConfig configures the circuit breaker around NWS requests
+type CircuitBreakerConfig struct {
+	// FailureThreshold is how many requests in a row must fail (network
+	// errors, timeouts, or 5xx responses after retries) to open the
+	// circuit; zero disables the breaker
+	FailureThreshold int
+	// OpenDuration is how long the circuit stays open before probing the NWS
+	OpenDuration time.Duration
+	// HalfOpenProbes is how many probe requests are let through at once
+	// while half-open, and how many must succeed to close the circuit
+	HalfOpenProbes int
+	// Recorder, if set, counts the circuit opening and the requests it rejects
+	Recorder *metrics.Recorder
+}
+
+// Circuit breaker defaults for settings left zero
+const (
+	DefaultCircuitOpenDuration   = 30 * time.Second
+	DefaultCircuitHalfOpenProbes = 1
+)
+
+// WithCircuitBreaker stops sending requests to the NWS after repeated
+// failures. While the circuit is open, requests fail at once with a
+// *ShedError caused by ErrCircuitOpen, so callers serve stale cache or a 503
+// without waiting out timeouts; after OpenDuration probe requests decide
+// whether it closes again.
+func WithCircuitBreaker(cfg CircuitBreakerConfig) NWSClientOption {
+	return func(c *NWSAPIClient) {
+		if cfg.FailureThreshold <= 0 {
+			c.breaker = nil
+			return
+		}
+		if cfg.OpenDuration <= 0 {
+			cfg.OpenDuration = DefaultCircuitOpenDuration
+		}
+		if cfg.HalfOpenProbes <= 0 {
+			cfg.HalfOpenProbes = DefaultCircuitHalfOpenProbes
+		}
+		c.breaker = &circuitBreaker{cfg: cfg, state: CircuitClosed, now: time.Now}
+	}
+}
+
+// CircuitState reports the state of the client's circuit breaker, or "" when
+// it has none
+func (c *NWSAPIClient) CircuitState() string {
+	return c.breaker.currentState()
+}
+
+// circuitBreaker tracks consecutive NWS failures, shared by copies of a client
+type circuitBreaker struct {
+	cfg CircuitBreakerConfig
+	now func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	// opened counts openings, so outcomes of requests allowed before the
+	// latest one are told apart
+	opened int
+	// probes is the number of half-open requests in flight, and successes
+	// how many have succeeded since the circuit last opened
+	probes    int
+	successes int
+}
+
+// allow reports whether a request may be sent, returning a *ShedError when
+// the circuit is open or every half-open probe slot is taken. A request that
+// is allowed reports its outcome through the returned func: what it says
+// about the NWS's health, as from upstreamError. A request cancelled by its
+// caller or refused by the outbound rate limit says nothing either way.
+func (b *circuitBreaker) allow() (func(error), error) {
+	if b == nil {
+		return func(error) {}, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen {
+		reopensIn := b.openedAt.Add(b.cfg.OpenDuration).Sub(b.now())
+		if reopensIn > 0 {
+			b.reject()
+			return nil, &ShedError{RetryAfter: reopensIn, Cause: ErrCircuitOpen}
+		}
+		b.state, b.probes, b.successes = CircuitHalfOpen, 0, 0
+	}
+	probe := b.state == CircuitHalfOpen
+	if probe {
+		if b.probes >= b.cfg.HalfOpenProbes {
+			b.reject()
+			return nil, &ShedError{RetryAfter: time.Second, Cause: ErrCircuitOpen}
+		}
+		b.probes++
+	}
+	opened := b.opened
+	return func(err error) { b.record(probe, opened, err) }, nil
+}
+
+// record notes the outcome of a request allowed while the circuit had been
+// opened the given number of times
+func (b *circuitBreaker) record(probe bool, opened int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if opened != b.opened {
+		// The circuit has opened since the request was sent
+		return
+	}
+
+	neutral := errors.Is(err, context.Canceled) || errors.Is(err, ErrRateLimited)
+	if probe {
+		b.probes--
+		switch {
+		case neutral:
+		case err != nil:
+			b.open()
+		default:
+			b.successes++
+			if b.successes >= b.cfg.HalfOpenProbes {
+				b.state, b.failures = CircuitClosed, 0
+			}
+		}
+		return
+	}
+	if b.state != CircuitClosed || neutral {
+		return
+	}
+	if err == nil {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.cfg.FailureThreshold {
+		b.open()
+	}
+}
+
+// open opens the circuit from now; the caller holds b.mu
+func (b *circuitBreaker) open() {
+	b.state, b.openedAt = CircuitOpen, b.now()
+	b.opened++
+	if b.cfg.Recorder != nil {
+		b.cfg.Recorder.Inc(metrics.NWSCircuitOpened)
+	}
+}
+
+// reject counts a request turned away by the circuit; the caller holds b.mu
+func (b *circuitBreaker) reject() {
+	if b.cfg.Recorder != nil {
+		b.cfg.Recorder.Inc(metrics.RequestsCircuitOpen)
+	}
+}
+
+// currentState returns the circuit's state, reporting an open circuit whose
+// OpenDuration has passed as half-open
+func (b *circuitBreaker) currentState() string {
+	if b == nil {
+		return ""
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && !b.now().Before(b.openedAt.Add(b.cfg.OpenDuration)) {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/metrics"
+	"weather-api-go/internal/models"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	recorder := metrics.NewRecorder(0)
+	client := NewNWSAPIClient(WithCircuitBreaker(CircuitBreakerConfig{
+		FailureThreshold: 3, OpenDuration: 30 * time.Second, HalfOpenProbes: 2, Recorder: recorder,
+	}))
+	client.breaker.now = func() time.Time { return now }
+
+	outage := errors.New("NWS returned status: 503")
+	steps := []struct {
+		name      string
+		advance   time.Duration
+		outcome   error
+		wantRetry time.Duration // refused with this Retry-After when nonzero
+		wantState string
+	}{
+		{"first failure", 0, outage, 0, CircuitClosed},
+		{"success resets the count", 0, nil, 0, CircuitClosed},
+		{"failure", 0, outage, 0, CircuitClosed},
+		{"failure", 0, outage, 0, CircuitClosed},
+		{"cancelled by the caller", 0, context.Canceled, 0, CircuitClosed},
+		{"refused by the rate limit", 0, &RateLimitedError{Wait: time.Second}, 0, CircuitClosed},
+		{"third failure in a row opens", 0, outage, 0, CircuitOpen},
+		{"refused while open", 10 * time.Second, nil, 20 * time.Second, CircuitOpen},
+		{"failed probe reopens", 20 * time.Second, outage, 0, CircuitOpen},
+		{"refused after reopening", 29 * time.Second, nil, time.Second, CircuitOpen},
+		{"first successful probe", time.Second, nil, 0, CircuitHalfOpen},
+		{"second successful probe closes", 0, nil, 0, CircuitClosed},
+	}
+	for i, tt := range steps {
+		now = now.Add(tt.advance)
+		done, err := client.breaker.allow()
+		if tt.wantRetry > 0 {
+			var shed *ShedError
+			if !errors.As(err, &shed) || !errors.Is(err, ErrCircuitOpen) || shed.RetryAfter != tt.wantRetry {
+				t.Errorf("step %d (%s): error = %v; want a shed with Retry-After %v", i+1, tt.name, err, tt.wantRetry)
+			}
+		} else if err != nil {
+			t.Fatalf("step %d (%s): refused: %v", i+1, tt.name, err)
+		} else {
+			done(tt.outcome)
+		}
+		if got := client.CircuitState(); got != tt.wantState {
+			t.Errorf("step %d (%s): state = %s; want %s", i+1, tt.name, got, tt.wantState)
+		}
+	}
+
+	// Half-open lets HalfOpenProbes requests through at once, and ignores
+	// requests sent before the circuit last opened
+	var stragglers []func(error)
+	for i := 0; i < 3; i++ {
+		done, _ := client.breaker.allow()
+		stragglers = append(stragglers, done)
+		done(outage)
+	}
+	now = now.Add(30 * time.Second)
+	if got := client.CircuitState(); got != CircuitHalfOpen {
+		t.Errorf("after the open duration: state = %s; want %s", got, CircuitHalfOpen)
+	}
+	first, err1 := client.breaker.allow()
+	second, err2 := client.breaker.allow()
+	if _, err := client.breaker.allow(); err1 != nil || err2 != nil || !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("probes: errors %v, %v, %v; want two allowed and the third refused", err1, err2, err)
+	}
+	stragglers[0](outage)
+	first(nil)
+	second(nil)
+	if got := client.CircuitState(); got != CircuitClosed {
+		t.Errorf("after both probes succeeded: state = %s; want %s", got, CircuitClosed)
+	}
+
+	counters := recorder.Counters()
+	if counters[metrics.NWSCircuitOpened] != 3 || counters[metrics.RequestsCircuitOpen] != 3 {
+		t.Errorf("counters = %v; want 3 openings and 3 refused requests", counters)
+	}
+}
+
+func TestGetWeatherCircuitBreaker(t *testing.T) {
+	var requests int32
+	var failing atomic.Bool
+	failing.Store(true)
+	server, _ := fakeGridNWS(t, http.StatusOK)
+	nws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer nws.Close()
+
+	now := time.Now()
+	client := NewNWSAPIClient(WithBaseURL(nws.URL), WithHTTPClient(nws.Client()), WithRetry(RetryConfig{MaxAttempts: 1}),
+		WithCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 2, OpenDuration: time.Minute}))
+	client.breaker.now = func() time.Time { return now }
+	repo := newTestRepo(t)
+	err := repo.SaveToCache(context.Background(), &models.WeatherCache{
+		Latitude: 40.7128, Longitude: -74.006, Forecast: "Sunny", Timestamp: time.Now().Add(-2 * time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewWeatherService(repo, client)
+
+	// Closed: failures reach the NWS until the threshold opens the circuit
+	for i := 1; i <= 2; i++ {
+		if _, err := service.GetWeather(context.Background(), 39.9526, -75.1652); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("failure %d: error = %v; want the NWS's", i, err)
+		}
+	}
+	if client.CircuitState() != CircuitOpen || atomic.LoadInt32(&requests) != 2 {
+		t.Fatalf("state %s after %d NWS requests; want open after 2", client.CircuitState(), requests)
+	}
+
+	// Open: nothing reaches the NWS; cached weather is served stale and
+	// uncached requests are shed
+	resp, err := service.GetWeather(context.Background(), 40.7128, -74.006)
+	if err != nil || resp.Source != SourceStale || resp.Forecast != "Sunny" {
+		t.Errorf("cached coordinate = %+v, %v; want the stale entry", resp, err)
+	}
+	var shed *ShedError
+	if _, err := service.GetWeather(context.Background(), 39.9526, -75.1652); !errors.As(err, &shed) || !errors.Is(err, ErrCircuitOpen) || shed.RetryAfter != time.Minute {
+		t.Errorf("uncached coordinate: error = %v; want a shed for a minute with ErrCircuitOpen", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("%d NWS requests while open; want none beyond the first 2", got)
+	}
+
+	// Half-open: once the NWS recovers, the probe closes the circuit
+	now = now.Add(time.Minute)
+	failing.Store(false)
+	if client.CircuitState() != CircuitHalfOpen {
+		t.Errorf("after a minute: state = %s; want %s", client.CircuitState(), CircuitHalfOpen)
+	}
+	resp, err = service.GetWeather(context.Background(), 39.9526, -75.1652)
+	if err != nil || resp.Source != SourceLive {
+		t.Fatalf("after recovery = %+v, %v; want a live forecast", resp, err)
+	}
+	if client.CircuitState() != CircuitClosed {
+		t.Errorf("after a successful probe: state = %s; want %s", client.CircuitState(), CircuitClosed)
+	}
+}
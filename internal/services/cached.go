@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+// CachedForecast describes the fresh cached forecast a weather request for a
+// coordinate would be answered from
+type CachedForecast struct {
+	// Source is the cache tier holding the forecast: redis, memory, sqlite, or
+	// postgres for the coordinate's own entry, nearby:sqlite or nearby:postgres
+	// for a nearby coordinate's, grid:redis or grid:sqlite for its grid cell's
+	Source    string
+	Timestamp time.Time
+}
+
+// CachedWeather reports whether GetWeather would answer a coordinate from a
+// fresh cache entry, following the same lookups without ever calling the NWS
+func (s *WeatherService) CachedWeather(ctx context.Context, lat, lon float64) (*CachedForecast, bool) {
+	cached, err := s.getCached(ctx, repository.NewCoordinates(lat, lon))
+	if err == nil && s.repo.IsCacheFresh(cached) {
+		source := cached.Source
+		if cached.DistanceKm > 0 {
+			source = "nearby:" + source
+		}
+		return &CachedForecast{Source: source, Timestamp: cached.Timestamp}, true
+	}
+
+	// Grid cells are only consulted when the NWS provides forecasts
+	if _, ok := s.provider.(*NWSAPIClient); !ok {
+		return nil, false
+	}
+
+	// An expired grid mapping is only reused when the NWS is unreachable, so
+	// it doesn't predict a hit
+	point, err := s.repo.GetGridPoint(ctx, normalizePointCoordinate(lat), normalizePointCoordinate(lon))
+	if err != nil || !s.repo.IsGridPointFresh(point) {
+		return nil, false
+	}
+
+	forecast, err := s.repo.GetGridForecast(ctx, point.GridID, point.GridX, point.GridY)
+	if err != nil || !s.repo.IsCacheFresh(forecast) {
+		return nil, false
+	}
+	return &CachedForecast{Source: "grid:" + forecast.Source, Timestamp: forecast.Timestamp}, true
+}
+
+const (
+	// DefaultCachedLocationsLimit is the page size for listing cached locations
+	DefaultCachedLocationsLimit = 100
+	// MaxCachedLocationsLimit caps the page size for listing cached locations
+	MaxCachedLocationsLimit = 1000
+)
+
+// ListCachedLocations returns a page of the coordinates with a cached forecast,
+// most recently refreshed first, each flagged with whether it is still fresh
+func (s *WeatherService) ListCachedLocations(ctx context.Context, limit, offset int) (*models.CachedLocationsResponse, error) {
+	locations, total, err := s.repo.ListCached(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for i := range locations {
+		locations[i].IsFresh = s.repo.IsCacheFresh(&models.WeatherCache{Timestamp: locations[i].Timestamp})
+	}
+	return &models.CachedLocationsResponse{Locations: locations, Total: total, Limit: limit, Offset: offset}, nil
+}
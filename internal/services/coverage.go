@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"weather-api-go/internal/coverage"
+	"weather-api-go/internal/metrics"
+	"weather-api-go/internal/models"
+)
+
+// ErrOutsideCoverage is returned for coordinates outside every NWS forecast area
+var ErrOutsideCoverage = errors.New("coordinate is outside NWS forecast coverage")
+
+// WithCoverageCheck turns the coverage pre-check on or off. It is on by default;
+// turn it off if the NWS extends its coverage before the embedded outlines are
+// updated, letting every coordinate through to the points API again.
+func WithCoverageCheck(enabled bool) WeatherServiceOption {
+	return func(s *WeatherService) {
+		s.coverageCheck = enabled
+	}
+}
+
+// checkCoverage rejects coordinates outside the NWS forecast areas, which the
+// points API would answer with a 404, before they cost an upstream request
+func (s *WeatherService) checkCoverage(lat, lon float64) error {
+	if !s.coverageCheck || coverage.Covered(lat, lon) {
+		return nil
+	}
+	if s.metrics != nil {
+		s.metrics.Inc(metrics.RequestsOutOfCoverage)
+	}
+	return ErrOutsideCoverage
+}
+
+// checkUncovered rejects coordinates the points API has answered with a 404
+// within repository.UncoveredPointTTL. It is checked just before a points
+// request, after the cached mappings that would make one unnecessary.
+func (s *WeatherService) checkUncovered(ctx context.Context, lat, lon float64) error {
+	uncovered, err := s.repo.GetUncoveredPoint(ctx, normalizePointCoordinate(lat), normalizePointCoordinate(lon))
+	if err != nil || !s.repo.IsUncoveredPointFresh(uncovered) {
+		return nil
+	}
+	if s.metrics != nil {
+		s.metrics.Inc(metrics.RequestsOutOfCoverage)
+	}
+	return ErrOutsideCoverage
+}
+
+// recordCoverage stores a negative entry for a coordinate when err is the
+// points API's definitive answer that it isn't covered. Any other error, such
+// as a timeout or a 5xx, may be transient and is not recorded.
+func (s *WeatherService) recordCoverage(ctx context.Context, lat, lon float64, err error) {
+	if !errors.Is(err, ErrOutsideCoverage) {
+		return
+	}
+	lat, lon = normalizePointCoordinate(lat), normalizePointCoordinate(lon)
+	if saveErr := s.repo.SaveUncoveredPoint(ctx, &models.UncoveredPoint{Latitude: lat, Longitude: lon, Timestamp: time.Now()}); saveErr != nil {
+		log.Printf("Failed to record %.4f,%.4f as outside coverage: %v", lat, lon, saveErr)
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+func TestUncoveredPointsAreCached(t *testing.T) {
+	var requests int32
+	status := int32(http.StatusNotFound)
+	nws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, `{"title": "Data Unavailable For Requested Point"}`, int(atomic.LoadInt32(&status)))
+	}))
+	defer nws.Close()
+
+	// The coverage pre-check is off, so only the points API's answer can
+	// turn the coordinate away
+	repo := newTestRepo(t)
+	service := NewWeatherService(repo, newTestNWSClient(nws), WithCoverageCheck(false))
+
+	if _, err := service.GetWeather(context.Background(), 51.5074, -0.1278); !errors.Is(err, ErrOutsideCoverage) {
+		t.Fatalf("first request: error = %v; want ErrOutsideCoverage", err)
+	}
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Fatalf("first request: %d NWS requests; want 1", atomic.LoadInt32(&requests))
+	}
+
+	// Every later points lookup near the coordinate is answered from the
+	// negative entry
+	if _, err := service.GetWeather(context.Background(), 51.50741, -0.12781); !errors.Is(err, ErrOutsideCoverage) {
+		t.Errorf("second request: error = %v; want ErrOutsideCoverage", err)
+	}
+	if _, err := service.GetRawPoints(context.Background(), 51.5074, -0.1278); !errors.Is(err, ErrOutsideCoverage) {
+		t.Errorf("raw points: error = %v; want ErrOutsideCoverage", err)
+	}
+	if _, err := service.GetAlerts(context.Background(), 51.5074, -0.1278); !errors.Is(err, ErrOutsideCoverage) {
+		t.Errorf("alerts: error = %v; want ErrOutsideCoverage", err)
+	}
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("%d NWS requests; want only the first", atomic.LoadInt32(&requests))
+	}
+
+	// Once the entry expires the NWS is asked again
+	err := repo.SaveUncoveredPoint(context.Background(), &models.UncoveredPoint{Latitude: 51.5074, Longitude: -0.1278, Timestamp: time.Now().Add(-7 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.GetWeather(context.Background(), 51.5074, -0.1278); !errors.Is(err, ErrOutsideCoverage) || atomic.LoadInt32(&requests) != 2 {
+		t.Errorf("after expiry: error = %v after %d NWS requests; want ErrOutsideCoverage after 2", err, atomic.LoadInt32(&requests))
+	}
+
+	// A transient failure is never recorded as missing coverage
+	atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+	for i := 1; i <= 2; i++ {
+		before := atomic.LoadInt32(&requests)
+		if _, err := service.GetWeather(context.Background(), 40.7128, -74.006); err == nil || errors.Is(err, ErrOutsideCoverage) {
+			t.Errorf("transient failure, request %d: error = %v; want the upstream error", i, err)
+		}
+		if atomic.LoadInt32(&requests) == before {
+			t.Errorf("transient failure, request %d: the NWS wasn't asked", i)
+		}
+	}
+	if _, err := repo.GetUncoveredPoint(context.Background(), 40.7128, -74.006); err == nil {
+		t.Error("a 503 left a negative entry")
+	}
+}
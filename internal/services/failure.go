@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
+
+// Kinds of failure a forecast fetch is classified as, so callers can tell a
+// provider outage from a fault in this service. An error matching none of
+// them is internal.
+var (
+	// ErrUpstreamUnavailable marks a provider that couldn't be reached or
+	// answered with a 5xx
+	ErrUpstreamUnavailable = errors.New("weather provider unavailable")
+	// ErrUpstreamBadResponse marks a provider answer that can't be used: an
+	// unexpected 4xx, or a document that is malformed, too large, or empty
+	ErrUpstreamBadResponse = errors.New("weather provider returned an unusable response")
+	// ErrUpstreamTimeout marks a provider that didn't answer in time
+	ErrUpstreamTimeout = errors.New("weather provider timed out")
+	// ErrCacheUnavailable marks a failed fetch for which the cache couldn't be
+	// read either, so no stale data could be served in its place
+	ErrCacheUnavailable = errors.New("weather cache unavailable")
+)
+
+// classifyFailure marks an error from fetching a forecast with the kind of
+// failure it is. Errors already given a meaning of their own, such as shed
+// requests or coordinates outside coverage, and errors of this service's
+// making are returned unchanged.
+func classifyFailure(err error) error {
+	var problem *ProblemError
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == nil, errors.Is(err, ErrUpstreamUnavailable), errors.Is(err, ErrUpstreamBadResponse),
+		errors.Is(err, ErrUpstreamTimeout), errors.Is(err, ErrUpstreamSaturated), errors.Is(err, ErrOutsideCoverage),
+		errors.Is(err, ErrQuotaExceeded), errors.Is(err, ErrInvalidAPIKey), errors.Is(err, context.Canceled):
+		return err
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Errorf("%w: %w", ErrUpstreamTimeout, err)
+	case errors.As(err, &problem):
+		if problem.Status >= http.StatusInternalServerError {
+			return fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
+		}
+		return fmt.Errorf("%w: %w", ErrUpstreamBadResponse, err)
+	case errors.As(err, &netErr):
+		return fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, ErrDocumentTooLarge), errors.Is(err, ErrForecastNotFound), errors.Is(err, errNoForecastURL),
+		errors.Is(err, errNoForecastPeriods):
+		return fmt.Errorf("%w: %w", ErrUpstreamBadResponse, err)
+	}
+	return err
+}
+
+// cacheFailure returns the cache lookup error behind a failed fetch wrapped
+// in ErrCacheUnavailable, or nil when the lookup merely missed
+func cacheFailure(lookupErr error) error {
+	if lookupErr == nil || errors.Is(lookupErr, sql.ErrNoRows) || errors.Is(lookupErr, errOtherProvider) {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrCacheUnavailable, lookupErr)
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	refused := &url.Error{Op: "Get", URL: "https://api.weather.gov/points/40.7,-74", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}
+	timeout := &url.Error{Op: "Get", URL: "https://api.weather.gov/points/40.7,-74", Err: context.DeadlineExceeded}
+	tests := []struct {
+		name string
+		err  error
+		want error // nil when the error is returned unchanged
+	}{
+		{"connection refused", fmt.Errorf("failed to fetch points data: %w", refused), ErrUpstreamUnavailable},
+		{"5xx", &ProblemError{API: "NWS forecast", Status: 503}, ErrUpstreamUnavailable},
+		{"unexpected 4xx", &ProblemError{API: "NWS points", Status: 400}, ErrUpstreamBadResponse},
+		{"malformed document", fmt.Errorf("failed to decode: %w", &json.SyntaxError{}), ErrUpstreamBadResponse},
+		{"no periods", errNoForecastPeriods, ErrUpstreamBadResponse},
+		{"document too large", ErrDocumentTooLarge, ErrUpstreamBadResponse},
+		{"client timeout", timeout, ErrUpstreamTimeout},
+		{"request deadline", context.DeadlineExceeded, ErrUpstreamTimeout},
+		{"shed", &ShedError{}, nil},
+		{"outside coverage", fmt.Errorf("%w: %w", ErrOutsideCoverage, &ProblemError{API: "NWS points", Status: 404}), nil},
+		{"quota", ErrQuotaExceeded, nil},
+		{"caller went away", context.Canceled, nil},
+		{"internal", errors.New("template: missing key"), nil},
+	}
+	for _, tt := range tests {
+		got := classifyFailure(tt.err)
+		if !errors.Is(got, tt.err) {
+			t.Errorf("%s: %v no longer matches the original error", tt.name, got)
+		}
+		if tt.want == nil {
+			if got != tt.err {
+				t.Errorf("%s: classified as %v; want it unchanged", tt.name, got)
+			}
+			continue
+		}
+		if !errors.Is(got, tt.want) {
+			t.Errorf("%s: classified as %v; want %v", tt.name, got, tt.want)
+		}
+		if classifyFailure(got) != got {
+			t.Errorf("%s: classified twice", tt.name)
+		}
+	}
+
+	if cacheFailure(nil) != nil || cacheFailure(errOtherProvider) != nil || cacheFailure(fmt.Errorf("query: %w", sql.ErrNoRows)) != nil {
+		t.Error("cache misses reported as failures")
+	}
+	if err := cacheFailure(errors.New("database is closed")); !errors.Is(err, ErrCacheUnavailable) {
+		t.Errorf("cacheFailure = %v; want ErrCacheUnavailable", err)
+	}
+}
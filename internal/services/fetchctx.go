@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// fetchTimeout bounds a coalesced fetch, which runs independent of any one
+// of the requests waiting on it
+const fetchTimeout = 30 * time.Second
+
+// fetchContexts hands out the contexts coalesced fetches run under. A fetch
+// shared by several callers mustn't end with the one that happened to start
+// it, so its context is detached from the callers' cancellation and is
+// cancelled once the last of them has stopped waiting. The cancelled fetch is
+// then forgotten, so a caller arriving while it winds down starts a new one
+// rather than joining it.
+type fetchContexts struct {
+	mu      sync.Mutex
+	fetches map[string]*fetchContext
+	// forget drops a key's in-flight fetch from coalescing
+	forget func(key string)
+}
+
+// fetchContext is the context of one key's fetch and the callers waiting on it
+type fetchContext struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+}
+
+func newFetchContexts(forget func(key string)) *fetchContexts {
+	return &fetchContexts{fetches: make(map[string]*fetchContext), forget: forget}
+}
+
+// join registers a caller waiting on key's fetch and returns the context the
+// fetch runs under. The first caller's context supplies its values, such as
+// the trace, without its cancellation; the fetch is bounded by fetchTimeout.
+// leave must be called once the caller stops waiting.
+func (f *fetchContexts) join(ctx context.Context, key string) (context.Context, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fetch, ok := f.fetches[key]
+	if !ok {
+		fetch = &fetchContext{}
+		fetch.ctx, fetch.cancel = context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+		f.fetches[key] = fetch
+	}
+	fetch.waiters++
+
+	return fetch.ctx, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if fetch.waiters--; fetch.waiters == 0 {
+			fetch.cancel()
+			delete(f.fetches, key)
+			f.forget(key)
+		}
+	}
+}
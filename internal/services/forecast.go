@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"weather-api-go/internal/metrics"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+// GetForecast retrieves every day/night forecast period for a coordinate. The
+// periods are cached per grid cell, shared with the ?at= forecasts beyond the
+// hourly horizon, and a stale series is served if the upstream fetch fails.
+func (s *WeatherService) GetForecast(ctx context.Context, lat, lon float64) (*models.ForecastResponse, error) {
+	forecast, err := s.getForecast(ctx, lat, lon)
+	if errors.Is(err, ErrUpstreamSaturated) && s.metrics != nil {
+		s.metrics.Inc(metrics.RequestsShed)
+	}
+	return forecast, classifyFailure(err)
+}
+
+func (s *WeatherService) getForecast(ctx context.Context, lat, lon float64) (*models.ForecastResponse, error) {
+	point, err := s.resolveGridPoint(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+
+	daily, err := s.getForecastPeriods(ctx, point, repository.DailyPeriods)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ForecastResponse{
+		Latitude:   lat,
+		Longitude:  lon,
+		Periods:    daily.Periods,
+		TimeZone:   point.TimeZone,
+		FreshUntil: daily.Timestamp.Add(s.repo.ForecastPeriodsTTL(repository.DailyPeriods)),
+		CacheHit:   daily.CacheHit,
+	}, nil
+}
+
+// GetHourlyForecast retrieves the remaining hours of a coordinate's hourly
+// forecast. The series is cached per grid cell for ForecastPeriodsTTL, and a stale
+// series is served if the upstream fetch fails. ErrNoHourlyForecast is returned
+// when the NWS publishes no hourly forecast for the location.
+func (s *WeatherService) GetHourlyForecast(ctx context.Context, lat, lon float64) (*models.HourlyForecastResponse, error) {
+	forecast, err := s.getHourlyForecast(ctx, lat, lon)
+	if errors.Is(err, ErrUpstreamSaturated) && s.metrics != nil {
+		s.metrics.Inc(metrics.RequestsShed)
+	}
+	return forecast, classifyFailure(err)
+}
+
+func (s *WeatherService) getHourlyForecast(ctx context.Context, lat, lon float64) (*models.HourlyForecastResponse, error) {
+	point, err := s.resolveGridPoint(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+
+	hourly, err := s.getForecastPeriods(ctx, point, repository.HourlyPeriods)
+	if err != nil {
+		return nil, err
+	}
+
+	// A cached series may start with hours that have already passed
+	now := time.Now()
+	hours := make([]models.HourlyForecast, 0, len(hourly.Periods))
+	for _, p := range hourly.Periods {
+		if !p.EndTime.After(now) {
+			continue
+		}
+		hours = append(hours, models.HourlyForecast{
+			Time:                     p.StartTime,
+			TempC:                    p.TempC,
+			TempF:                    p.TempF,
+			ShortForecast:            p.ShortForecast,
+			WindSpeed:                p.WindSpeed,
+			WindDirection:            p.WindDirection,
+			PrecipitationProbability: p.PrecipitationProbability,
+		})
+	}
+
+	return &models.HourlyForecastResponse{
+		Latitude:   lat,
+		Longitude:  lon,
+		Hours:      hours,
+		TimeZone:   point.TimeZone,
+		FreshUntil: hourly.Timestamp.Add(s.repo.ForecastPeriodsTTL(repository.HourlyPeriods)),
+		CacheHit:   hourly.CacheHit,
+	}, nil
+}
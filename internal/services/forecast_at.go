@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+var (
+	// ErrForecastTimeInPast is returned when a forecast is requested for a time already gone
+	ErrForecastTimeInPast = errors.New("requested forecast time is in the past")
+	// ErrBeyondForecastHorizon is returned when no forecast period covers the requested time
+	ErrBeyondForecastHorizon = errors.New("requested forecast time is beyond the forecast horizon")
+)
+
+// pastTolerance lets a requested time of "now" survive the trip to the server
+const pastTolerance = time.Minute
+
+// localTimeLayouts are the accepted forms of a date-time without a UTC offset
+var localTimeLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04"}
+
+// ForecastTime is a requested forecast instant. A local time carries no offset
+// and is resolved in the location's time zone, using the offsets the NWS
+// reports on the location's forecast periods.
+type ForecastTime struct {
+	t     time.Time
+	local bool
+}
+
+// ParseForecastTime parses an RFC 3339 timestamp (2024-06-01T18:00:00Z), or a
+// local date-time without offset (2024-06-01T18:00)
+func ParseForecastTime(s string) (ForecastTime, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return ForecastTime{t: t}, nil
+	}
+	for _, layout := range localTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return ForecastTime{t: t, local: true}, nil
+		}
+	}
+	return ForecastTime{}, fmt.Errorf("invalid forecast time %q: use RFC 3339 or a local YYYY-MM-DDTHH:MM[:SS]", s)
+}
+
+// resolve returns the requested instant. A local time takes the UTC offset of
+// the period its wall-clock time falls in, so DST changes within the series are
+// honored; failing that, the offset of the first period.
+func (ft ForecastTime) resolve(series ...[]models.ForecastPeriod) time.Time {
+	if !ft.local {
+		return ft.t
+	}
+
+	wall := func(loc *time.Location) time.Time {
+		return time.Date(ft.t.Year(), ft.t.Month(), ft.t.Day(), ft.t.Hour(), ft.t.Minute(), ft.t.Second(), 0, loc)
+	}
+	for _, periods := range series {
+		for _, p := range periods {
+			candidate := wall(p.StartTime.Location())
+			if !candidate.Before(p.StartTime) && candidate.Before(p.EndTime) {
+				return candidate
+			}
+		}
+	}
+	for _, periods := range series {
+		if len(periods) > 0 {
+			return wall(periods[0].StartTime.Location())
+		}
+	}
+	return wall(time.UTC)
+}
+
+// forecastSample is the forecast at a single instant
+type forecastSample struct {
+	validAt      time.Time
+	forecast     string
+	periodName   string
+	isDaytime    bool
+	icon         string
+	tempC, tempF float64
+	precip       *float64
+	interpolated bool
+}
+
+// lerp interpolates linearly from a to b by fraction f
+func lerp(a, b, f float64) float64 {
+	return a + (b-a)*f
+}
+
+// interpolateHourly returns the forecast at an instant within the hourly series.
+// Each entry's values are taken to hold at its start time; between two entries
+// temperatures and precipitation probability are interpolated linearly, and the
+// forecast text is the nearer entry's (the earlier on a tie). Instants before
+// the first entry take its values. ok is false past the end of the last entry.
+func interpolateHourly(periods []models.ForecastPeriod, at time.Time) (forecastSample, bool) {
+	if len(periods) == 0 {
+		return forecastSample{}, false
+	}
+
+	i := 0
+	for i+1 < len(periods) && !periods[i+1].StartTime.After(at) {
+		i++
+	}
+	p := periods[i]
+	sample := forecastSample{
+		validAt: at, forecast: p.ShortForecast, periodName: p.Name, isDaytime: p.IsDaytime, icon: p.Icon,
+		tempC: p.TempC, tempF: p.TempF, precip: p.PrecipitationProbability,
+		interpolated: true,
+	}
+
+	if i == len(periods)-1 {
+		return sample, at.Before(p.EndTime)
+	}
+	if at.Before(p.StartTime) {
+		return sample, true
+	}
+
+	next := periods[i+1]
+	f := float64(at.Sub(p.StartTime)) / float64(next.StartTime.Sub(p.StartTime))
+	sample.tempC = lerp(p.TempC, next.TempC, f)
+	sample.tempF = lerp(p.TempF, next.TempF, f)
+
+	nearer := p
+	if f > 0.5 {
+		nearer = next
+	}
+	sample.forecast, sample.periodName, sample.isDaytime, sample.icon = nearer.ShortForecast, nearer.Name, nearer.IsDaytime, nearer.Icon
+	if p.PrecipitationProbability != nil && next.PrecipitationProbability != nil {
+		v := lerp(*p.PrecipitationProbability, *next.PrecipitationProbability, f)
+		sample.precip = &v
+	} else {
+		sample.precip = nearer.PrecipitationProbability
+	}
+	return sample, true
+}
+
+// coveringPeriod returns the forecast from the period containing an instant
+func coveringPeriod(periods []models.ForecastPeriod, at time.Time) (forecastSample, bool) {
+	for _, p := range periods {
+		if !at.Before(p.StartTime) && at.Before(p.EndTime) {
+			return forecastSample{
+				validAt: p.StartTime, forecast: p.ShortForecast, periodName: p.Name, isDaytime: p.IsDaytime, icon: p.Icon,
+				tempC: p.TempC, tempF: p.TempF, precip: p.PrecipitationProbability,
+			}, true
+		}
+	}
+	return forecastSample{}, false
+}
+
+// getWeatherAt builds the forecast for a requested instant from the grid cell's
+// hourly forecast, falling back to its day/night periods beyond the hourly horizon
+func (s *WeatherService) getWeatherAt(ctx context.Context, lat, lon float64, at ForecastTime, opts WeatherOptions) (*models.WeatherResponse, error) {
+	cutoff := time.Now().Add(-pastTolerance)
+	// An absolute time can be rejected before any upstream work
+	if !at.local && at.t.Before(cutoff) {
+		return nil, ErrForecastTimeInPast
+	}
+
+	point, err := s.resolveGridPoint(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+
+	hourly, err := s.getForecastPeriods(ctx, point, repository.HourlyPeriods)
+	if err != nil {
+		return nil, err
+	}
+
+	instant := at.resolve(hourly.Periods)
+	if instant.Before(cutoff) {
+		return nil, ErrForecastTimeInPast
+	}
+
+	source, sourceKind := hourly, repository.HourlyPeriods
+	sample, ok := interpolateHourly(hourly.Periods, instant)
+	if !ok {
+		daily, err := s.getForecastPeriods(ctx, point, repository.DailyPeriods)
+		if err != nil {
+			return nil, err
+		}
+		source, sourceKind = daily, repository.DailyPeriods
+		if sample, ok = coveringPeriod(daily.Periods, at.resolve(hourly.Periods, daily.Periods)); !ok {
+			return nil, ErrBeyondForecastHorizon
+		}
+	}
+
+	entry := &models.WeatherCache{
+		Forecast:   sample.forecast,
+		PeriodName: sample.periodName,
+		IsDaytime:  &sample.isDaytime,
+		Icon:       sample.icon,
+		TempC:      sample.tempC,
+		TempF:      sample.tempF,
+		City:       point.City,
+		State:      point.State,
+		TimeZone:   point.TimeZone,
+
+		PrecipitationProbability: sample.precip,
+	}
+	resp := s.buildResponse(ctx, entry, opts)
+	// The advisories describe the requested instant, not the periods after now
+	if opts.IncludeAdvisories {
+		resp.Advisories = ComputeAdvisories([]AdvisoryInput{weatherAdvisoryInput(entry)}, s.advisories)
+	}
+	validAt := sample.validAt
+	resp.ValidAt = &validAt
+	resp.Interpolated = &sample.interpolated
+	resp.FreshUntil = source.Timestamp.Add(s.repo.ForecastPeriodsTTL(sourceKind))
+	resp.CacheHit = source.CacheHit
+	setProvenance(resp, source.Source, source.Timestamp)
+	return resp, nil
+}
+
+// getForecastPeriods returns a grid cell's hourly or day/night forecast periods,
+// reusing a fresh cached series and falling back to a stale one if the fetch fails
+func (s *WeatherService) getForecastPeriods(ctx context.Context, point *models.GridPoint, kind string) (*models.ForecastPeriodsCache, error) {
+	cached, err := s.repo.GetForecastPeriods(ctx, kind, point.GridID, point.GridX, point.GridY)
+	if err == nil && s.repo.IsForecastPeriodsFresh(kind, cached) {
+		cached.CacheHit = true
+		return cached, nil
+	}
+
+	var periods []models.ForecastPeriod
+	err = s.upstream(func() (err error) {
+		if kind == repository.HourlyPeriods {
+			periods, err = s.nwsClient.GetHourlyForecast(ctx, point.ForecastHourlyURL)
+		} else {
+			periods, err = s.nwsClient.GetForecastPeriods(ctx, point.ForecastURL)
+		}
+		return err
+	})
+	if err != nil {
+		if cached != nil {
+			cached.Source = SourceStale
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	fresh := &models.ForecastPeriodsCache{Periods: periods, Timestamp: time.Now(), Source: SourceLive}
+	_ = s.repo.SaveForecastPeriods(ctx, kind, point.GridID, point.GridX, point.GridY, fresh)
+	return fresh, nil
+}
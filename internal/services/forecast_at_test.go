@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+func pct(v float64) *float64 { return &v }
+
+func TestInterpolateHourly(t *testing.T) {
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	hour := func(h int) time.Time { return base.Add(time.Duration(h) * time.Hour) }
+	periods := []models.ForecastPeriod{
+		{StartTime: hour(0), EndTime: hour(1), ShortForecast: "Sunny", TempC: 10, TempF: 50, PrecipitationProbability: pct(20)},
+		{StartTime: hour(1), EndTime: hour(2), ShortForecast: "Cloudy", TempC: 14, TempF: 57.2, PrecipitationProbability: pct(40)},
+		{StartTime: hour(2), EndTime: hour(3), ShortForecast: "Rain", TempC: 12, TempF: 53.6},
+	}
+
+	tests := []struct {
+		name         string
+		at           time.Time
+		wantOK       bool
+		wantC        float64
+		wantForecast string
+		wantPrecip   *float64
+	}{
+		{"on an hour", hour(1), true, 14, "Cloudy", pct(40)},
+		{"quarter past", base.Add(15 * time.Minute), true, 11, "Sunny", pct(25)},
+		{"half past takes the earlier text", base.Add(30 * time.Minute), true, 12, "Sunny", pct(30)},
+		{"quarter to takes the later text", base.Add(45 * time.Minute), true, 13, "Cloudy", pct(35)},
+		{"missing precip uses the nearer entry", hour(1).Add(15 * time.Minute), true, 13.5, "Cloudy", pct(40)},
+		{"nearer entry without precip", hour(1).Add(45 * time.Minute), true, 12.5, "Rain", nil},
+		{"within the last entry", hour(2).Add(30 * time.Minute), true, 12, "Rain", nil},
+		{"before the first entry", base.Add(-10 * time.Minute), true, 10, "Sunny", pct(20)},
+		{"end of the last entry", hour(3), false, 0, "", nil},
+	}
+	for _, tt := range tests {
+		got, ok := interpolateHourly(periods, tt.at)
+		if ok != tt.wantOK {
+			t.Errorf("%s: ok = %v; want %v", tt.name, ok, tt.wantOK)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if math.Abs(got.tempC-tt.wantC) > 1e-9 || got.forecast != tt.wantForecast || !got.interpolated {
+			t.Errorf("%s: got %.2f°C %q interpolated=%v; want %.2f°C %q interpolated", tt.name, got.tempC, got.forecast, got.interpolated, tt.wantC, tt.wantForecast)
+		}
+		switch {
+		case tt.wantPrecip == nil && got.precip != nil:
+			t.Errorf("%s: precip = %v; want none", tt.name, *got.precip)
+		case tt.wantPrecip != nil && (got.precip == nil || math.Abs(*got.precip-*tt.wantPrecip) > 1e-9):
+			t.Errorf("%s: precip = %v; want %v", tt.name, got.precip, *tt.wantPrecip)
+		}
+	}
+}
+
+func TestParseForecastTime(t *testing.T) {
+	edt := time.FixedZone("", -4*3600)
+	est := time.FixedZone("", -5*3600)
+	// The clocks fall back at 02:00 local on Nov 3, 2024
+	periods := []models.ForecastPeriod{
+		{StartTime: time.Date(2024, 11, 2, 18, 0, 0, 0, edt), EndTime: time.Date(2024, 11, 3, 6, 0, 0, 0, est)},
+		{StartTime: time.Date(2024, 11, 3, 6, 0, 0, 0, est), EndTime: time.Date(2024, 11, 3, 18, 0, 0, 0, est)},
+	}
+
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2024-11-03T17:00:00Z", time.Date(2024, 11, 3, 17, 0, 0, 0, time.UTC), false},
+		{"2024-11-03T12:00:00-07:00", time.Date(2024, 11, 3, 19, 0, 0, 0, time.UTC), false},
+		{"2024-11-03T12:00", time.Date(2024, 11, 3, 17, 0, 0, 0, time.UTC), false},
+		{"2024-11-02T20:30:00", time.Date(2024, 11, 3, 0, 30, 0, 0, time.UTC), false},
+		// Outside every period the first period's offset applies
+		{"2024-11-10T12:00", time.Date(2024, 11, 10, 16, 0, 0, 0, time.UTC), false},
+		{"tomorrow", time.Time{}, true},
+		{"2024-11-03", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		at, err := ParseForecastTime(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseForecastTime(%q) error = %v; wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got := at.resolve(periods); !got.Equal(tt.want) {
+			t.Errorf("ParseForecastTime(%q) resolves to %v; want %v", tt.in, got, tt.want.UTC())
+		}
+	}
+}
+
+// forecastNWS serves a 6-hour hourly series and two 12-hour day/night periods
+// starting at the top of the current hour
+func forecastNWS(t *testing.T, start time.Time) *httptest.Server {
+	t.Helper()
+	type period struct {
+		StartTime       time.Time `json:"startTime"`
+		EndTime         time.Time `json:"endTime"`
+		ShortForecast   string    `json:"shortForecast"`
+		Temperature     float64   `json:"temperature"`
+		TemperatureUnit string    `json:"temperatureUnit"`
+	}
+	var hourly, daily []period
+	for h := 0; h < 6; h++ {
+		s := start.Add(time.Duration(h) * time.Hour)
+		hourly = append(hourly, period{s, s.Add(time.Hour), "Sunny", float64(10 + h), "C"})
+	}
+	for i, forecast := range []string{"Mostly Sunny", "Clear"} {
+		s := start.Add(time.Duration(12*i) * time.Hour)
+		daily = append(daily, period{s, s.Add(12 * time.Hour), forecast, float64(20 - 10*i), "C"})
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var periods []period
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/points/"):
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35, "forecast": "%[1]s/gridpoints/OKX/33,35/forecast", "forecastHourly": "%[1]s/gridpoints/OKX/33,35/forecast/hourly"}}`, server.URL)
+			return
+		case strings.HasSuffix(r.URL.Path, "/forecast/hourly"):
+			periods = hourly
+		case strings.HasSuffix(r.URL.Path, "/forecast"):
+			periods = daily
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		var doc struct {
+			Properties struct {
+				Periods []period `json:"periods"`
+			} `json:"properties"`
+		}
+		doc.Properties.Periods = periods
+		json.NewEncoder(w).Encode(doc)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGetWeatherAt(t *testing.T) {
+	start := time.Now().UTC().Truncate(time.Hour)
+	service := NewWeatherService(newTestRepo(t), newTestNWSClient(forecastNWS(t, start)))
+
+	tests := []struct {
+		name             string
+		at               time.Time
+		wantErr          error
+		wantC            float64
+		wantForecast     string
+		wantInterpolated bool
+		wantValidAt      time.Time
+	}{
+		{"within the hourly series", start.Add(90 * time.Minute), nil, 11.5, "Sunny", true, start.Add(90 * time.Minute)},
+		{"beyond the hourly series", start.Add(8 * time.Hour), nil, 20, "Mostly Sunny", false, start},
+		{"night period", start.Add(15 * time.Hour), nil, 10, "Clear", false, start.Add(12 * time.Hour)},
+		{"beyond the forecast", start.Add(30 * time.Hour), ErrBeyondForecastHorizon, 0, "", false, time.Time{}},
+		{"in the past", start.Add(-2 * time.Hour), ErrForecastTimeInPast, 0, "", false, time.Time{}},
+	}
+	for _, tt := range tests {
+		at, err := ParseForecastTime(tt.at.Format(time.RFC3339))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := service.GetWeatherWithOptions(context.Background(), 40.7128, -74.0060, WeatherOptions{At: &at})
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: err = %v; want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if *resp.TemperatureC != tt.wantC || resp.Forecast != tt.wantForecast {
+			t.Errorf("%s: got %.2f°C %q; want %.2f°C %q", tt.name, *resp.TemperatureC, resp.Forecast, tt.wantC, tt.wantForecast)
+		}
+		if resp.Interpolated == nil || *resp.Interpolated != tt.wantInterpolated {
+			t.Errorf("%s: interpolated = %v; want %v", tt.name, resp.Interpolated, tt.wantInterpolated)
+		}
+		if resp.ValidAt == nil || !resp.ValidAt.Equal(tt.wantValidAt) {
+			t.Errorf("%s: valid_at = %v; want %v", tt.name, resp.ValidAt, tt.wantValidAt)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// Geocoder resolves place names to coordinates
+type Geocoder interface {
+	// Geocode returns the places matching a query, best match first, with
+	// upstream requests bound by ctx. No match is an empty slice, not an error.
+	Geocode(ctx context.Context, query PlaceQuery) ([]models.Place, error)
+}
+
+// PlaceQuery is a location to geocode: a city with an optional state, or free text
+type PlaceQuery struct {
+	City  string
+	State string
+	Text  string
+}
+
+// ParseCityQuery splits a "City, ST" value into a city and state. The state is
+// optional, so "Portland" and "Portland,OR" are both accepted.
+func ParseCityQuery(city string) PlaceQuery {
+	if i := strings.LastIndex(city, ","); i >= 0 {
+		return PlaceQuery{City: strings.TrimSpace(city[:i]), State: strings.TrimSpace(city[i+1:])}
+	}
+	return PlaceQuery{City: strings.TrimSpace(city)}
+}
+
+// Key is the query normalized for use as a cache key, so spacing and case
+// variants of the same query share one entry
+func (q PlaceQuery) Key() string {
+	if q.Text != "" {
+		return "q:" + strings.ToLower(strings.Join(strings.Fields(q.Text), " "))
+	}
+	city := strings.ToLower(strings.Join(strings.Fields(q.City), " "))
+	return "city:" + city + "," + strings.ToLower(stateName(q.State))
+}
+
+// String renders the query as a user would have typed it
+func (q PlaceQuery) String() string {
+	switch {
+	case q.Text != "":
+		return q.Text
+	case q.State == "":
+		return q.City
+	}
+	return q.City + ", " + q.State
+}
+
+// DefaultNominatimURL is the public OpenStreetMap Nominatim instance
+const DefaultNominatimURL = "https://nominatim.openstreetmap.org"
+
+// DefaultNominatimInterval paces requests to the public Nominatim instance,
+// whose usage policy allows at most one request per second
+const DefaultNominatimInterval = time.Second
+
+// nominatimCountries limits matches to the areas the NWS forecasts for
+const nominatimCountries = "us,pr,vi,gu,as,mp"
+
+// NominatimConfig configures a NominatimGeocoder
+type NominatimConfig struct {
+	// BaseURL is the Nominatim host; empty uses DefaultNominatimURL
+	BaseURL string
+	// UserAgent identifies the application, as the Nominatim usage policy requires
+	UserAgent string
+	// MinInterval is the least time between requests; zero disables pacing
+	MinInterval time.Duration
+	// HTTPClient replaces the default client, such as for tests
+	HTTPClient *http.Client
+}
+
+// NominatimGeocoder geocodes with the OpenStreetMap Nominatim search API
+type NominatimGeocoder struct {
+	cfg NominatimConfig
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewNominatimGeocoder creates a Nominatim geocoder
+func NewNominatimGeocoder(cfg NominatimConfig) *NominatimGeocoder {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultNominatimURL
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = "weather-api-go"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: DefaultUpstreamTimeout}
+	}
+	return &NominatimGeocoder{cfg: cfg}
+}
+
+// nominatimResult is one match from the Nominatim search API (format=jsonv2)
+type nominatimResult struct {
+	Lat         string `json:"lat"`
+	Lon         string `json:"lon"`
+	DisplayName string `json:"display_name"`
+}
+
+// Geocode searches Nominatim for a query. City queries use the structured
+// search restricted to settlements; free text is searched as given.
+func (g *NominatimGeocoder) Geocode(ctx context.Context, query PlaceQuery) ([]models.Place, error) {
+	params := url.Values{
+		"format":       {"jsonv2"},
+		"limit":        {"10"},
+		"countrycodes": {nominatimCountries},
+	}
+	if query.Text != "" {
+		params.Set("q", query.Text)
+	} else {
+		params.Set("city", query.City)
+		params.Set("featureType", "settlement")
+		if query.State != "" {
+			params.Set("state", stateName(query.State))
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.cfg.BaseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", g.cfg.UserAgent)
+
+	if err := g.pace(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := g.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch geocoding results: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("geocoder returned status: %d", resp.StatusCode)
+	}
+
+	var results []nominatimResult
+	if err := json.NewDecoder(io.LimitReader(resp.Body, MaxDocumentBytes)).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to decode geocoding results: %w", err)
+	}
+
+	places := make([]models.Place, 0, len(results))
+	for _, r := range results {
+		lat, latErr := strconv.ParseFloat(r.Lat, 64)
+		lon, lonErr := strconv.ParseFloat(r.Lon, 64)
+		if latErr != nil || lonErr != nil {
+			continue
+		}
+		places = append(places, models.Place{Name: r.DisplayName, Latitude: lat, Longitude: lon})
+	}
+	return places, nil
+}
+
+// pace blocks until MinInterval has passed since the previous request, or
+// returns ctx's error if it is cancelled first
+func (g *NominatimGeocoder) pace(ctx context.Context) error {
+	if g.cfg.MinInterval <= 0 {
+		return nil
+	}
+	g.mu.Lock()
+	now := time.Now()
+	wait := g.next.Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+	g.next = now.Add(wait + g.cfg.MinInterval)
+	g.mu.Unlock()
+	return sleepContext(ctx, wait)
+}
+
+// usStates maps USPS state and territory abbreviations to the names geocoders index
+var usStates = map[string]string{
+	"AL": "Alabama", "AK": "Alaska", "AZ": "Arizona", "AR": "Arkansas", "CA": "California",
+	"CO": "Colorado", "CT": "Connecticut", "DE": "Delaware", "DC": "District of Columbia",
+	"FL": "Florida", "GA": "Georgia", "HI": "Hawaii", "ID": "Idaho", "IL": "Illinois",
+	"IN": "Indiana", "IA": "Iowa", "KS": "Kansas", "KY": "Kentucky", "LA": "Louisiana",
+	"ME": "Maine", "MD": "Maryland", "MA": "Massachusetts", "MI": "Michigan", "MN": "Minnesota",
+	"MS": "Mississippi", "MO": "Missouri", "MT": "Montana", "NE": "Nebraska", "NV": "Nevada",
+	"NH": "New Hampshire", "NJ": "New Jersey", "NM": "New Mexico", "NY": "New York",
+	"NC": "North Carolina", "ND": "North Dakota", "OH": "Ohio", "OK": "Oklahoma", "OR": "Oregon",
+	"PA": "Pennsylvania", "RI": "Rhode Island", "SC": "South Carolina", "SD": "South Dakota",
+	"TN": "Tennessee", "TX": "Texas", "UT": "Utah", "VT": "Vermont", "VA": "Virginia",
+	"WA": "Washington", "WV": "West Virginia", "WI": "Wisconsin", "WY": "Wyoming",
+	"PR": "Puerto Rico", "VI": "United States Virgin Islands", "GU": "Guam",
+	"AS": "American Samoa", "MP": "Northern Mariana Islands",
+}
+
+// stateName expands a state abbreviation, returning anything else unchanged
+func stateName(state string) string {
+	if name, ok := usStates[strings.ToUpper(strings.TrimSpace(state))]; ok {
+		return name
+	}
+	return strings.TrimSpace(state)
+}
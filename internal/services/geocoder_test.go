@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"weather-api-go/internal/models"
+)
+
+func TestParseCityQuery(t *testing.T) {
+	tests := []struct {
+		in   string
+		want PlaceQuery
+	}{
+		{"Portland,OR", PlaceQuery{City: "Portland", State: "OR"}},
+		{" Portland , OR ", PlaceQuery{City: "Portland", State: "OR"}},
+		{"Portland", PlaceQuery{City: "Portland"}},
+		{"Washington, District of Columbia", PlaceQuery{City: "Washington", State: "District of Columbia"}},
+	}
+	for _, tt := range tests {
+		if got := ParseCityQuery(tt.in); got != tt.want {
+			t.Errorf("ParseCityQuery(%q) = %+v; want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPlaceQueryKey(t *testing.T) {
+	// Abbreviated and spelled-out states, case, and spacing share a key
+	same := []PlaceQuery{
+		{City: "Portland", State: "OR"},
+		{City: "portland", State: "or"},
+		{City: " Portland ", State: "Oregon"},
+	}
+	for _, q := range same[1:] {
+		if q.Key() != same[0].Key() {
+			t.Errorf("%+v key %q; want %q", q, q.Key(), same[0].Key())
+		}
+	}
+	if (PlaceQuery{Text: "Portland OR"}).Key() == same[0].Key() {
+		t.Error("free-text and city queries share a key")
+	}
+}
+
+func TestNominatimGeocoder(t *testing.T) {
+	var query map[string]string
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		query = map[string]string{}
+		for k := range r.URL.Query() {
+			query[k] = r.URL.Query().Get(k)
+		}
+		userAgent = r.Header.Get("User-Agent")
+		w.Write([]byte(`[
+			{"lat": "45.5202471", "lon": "-122.674194", "display_name": "Portland, Multnomah County, Oregon, United States"},
+			{"lat": "not a number", "lon": "-122.6", "display_name": "Broken"}
+		]`))
+	}))
+	defer server.Close()
+	g := NewNominatimGeocoder(NominatimConfig{BaseURL: server.URL + "/", UserAgent: "test-agent", HTTPClient: server.Client()})
+
+	places, err := g.Geocode(context.Background(), PlaceQuery{City: "Portland", State: "OR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.Place{Name: "Portland, Multnomah County, Oregon, United States", Latitude: 45.5202471, Longitude: -122.674194}
+	if len(places) != 1 || places[0] != want {
+		t.Errorf("places = %+v; want only %+v", places, want)
+	}
+	if query["city"] != "Portland" || query["state"] != "Oregon" || query["featureType"] != "settlement" || query["q"] != "" {
+		t.Errorf("city query params = %v; want a structured settlement search with the state spelled out", query)
+	}
+	if query["countrycodes"] != nominatimCountries || query["format"] != "jsonv2" {
+		t.Errorf("query params = %v; want jsonv2 results limited to NWS coverage", query)
+	}
+	if userAgent != "test-agent" {
+		t.Errorf("User-Agent = %q; want the configured agent", userAgent)
+	}
+
+	if _, err := g.Geocode(context.Background(), PlaceQuery{Text: "Mount Rainier"}); err != nil {
+		t.Fatal(err)
+	}
+	if query["q"] != "Mount Rainier" || query["city"] != "" || query["featureType"] != "" {
+		t.Errorf("free-text query params = %v; want q only", query)
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// Dependency statuses reported by CheckDependencies
+const (
+	DependencyUp   = "up"
+	DependencyDown = "down"
+	// DependencyDisabled marks Redis when none is configured
+	DependencyDisabled = "disabled"
+	// DependencyUnknown marks the NWS before any request has been made to it
+	DependencyUnknown = "unknown"
+)
+
+// HealthCheckTimeout bounds each dependency check
+const HealthCheckTimeout = time.Second
+
+// upstreamHealth remembers the outcome of the most recent NWS request, so
+// health checks can report the NWS without calling it on every probe
+type upstreamHealth struct {
+	mu      sync.Mutex
+	checked bool
+	latency time.Duration
+	err     error
+}
+
+// record notes the outcome of an NWS request
+func (h *upstreamHealth) record(latency time.Duration, err error) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checked, h.latency, h.err = true, latency, err
+}
+
+// report returns the NWS's health as of its most recent request
+func (h *upstreamHealth) report() models.DependencyHealth {
+	if h == nil {
+		return models.DependencyHealth{Status: DependencyUnknown}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.checked {
+		return models.DependencyHealth{Status: DependencyUnknown}
+	}
+	return dependencyHealth(h.latency, h.err)
+}
+
+// dependencyHealth builds the health of a dependency from a check's latency and error
+func dependencyHealth(latency time.Duration, err error) models.DependencyHealth {
+	health := models.DependencyHealth{Status: DependencyUp, LatencyMs: float64(latency.Microseconds()) / 1000}
+	if err != nil {
+		health.Status = DependencyDown
+		health.Error = err.Error()
+	}
+	return health
+}
+
+// CheckDependencies queries the cache database and pings Redis and any
+// Postgres forecast store, each within HealthCheckTimeout, and reports the NWS
+// from its most recent request
+func (s *WeatherService) CheckDependencies(ctx context.Context) models.Dependencies {
+	var deps models.Dependencies
+
+	start := time.Now()
+	err := s.repo.PingDatabase(ctx, HealthCheckTimeout)
+	deps.SQLite = dependencyHealth(time.Since(start), err)
+
+	start = time.Now()
+	configured, err := s.repo.PingRedis(ctx, HealthCheckTimeout)
+	deps.Redis = dependencyHealth(time.Since(start), err)
+	if !configured {
+		deps.Redis = models.DependencyHealth{Status: DependencyDisabled}
+	}
+
+	start = time.Now()
+	if configured, err := s.repo.PingForecastStore(ctx, HealthCheckTimeout); configured {
+		postgres := dependencyHealth(time.Since(start), err)
+		deps.Postgres = &postgres
+	}
+
+	deps.NWS = models.DependencyHealth{Status: DependencyDisabled}
+	if s.nwsClient != nil {
+		deps.NWS = s.nwsClient.health.report()
+		deps.NWS.Circuit = s.nwsClient.CircuitState()
+		if until := s.nwsClient.BackoffUntil(); !until.IsZero() {
+			deps.NWS.BackoffUntil = &until
+		}
+	}
+	return deps
+}
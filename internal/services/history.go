@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+const (
+	// DefaultHistoryDays is the history window returned when no start day is requested
+	DefaultHistoryDays = 30
+	// MaxHistoryDays caps a single history query
+	MaxHistoryDays = 366
+)
+
+// GetWeatherHistory returns daily forecast summaries for a coordinate between two
+// YYYY-MM-DD UTC days inclusive. An empty to defaults to today and an empty from
+// to the DefaultHistoryDays before it.
+func (s *WeatherService) GetWeatherHistory(ctx context.Context, lat, lon float64, from, to string) (*models.WeatherHistoryResponse, error) {
+	from, to, err := parseDayRange(from, to, time.Now(), DefaultHistoryDays, MaxHistoryDays)
+	if err != nil {
+		return nil, err
+	}
+	days, err := s.repo.GetWeatherHistory(ctx, lat, lon, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &models.WeatherHistoryResponse{Latitude: lat, Longitude: lon, From: from, To: to, Days: days}, nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"weather-api-go/internal/models"
+)
+
+// NWS icon sizes accepted by ?icon_size=
+const (
+	IconSmall  = "small"
+	IconMedium = "medium"
+	IconLarge  = "large"
+)
+
+// IconSizes lists the accepted icon sizes in display order
+var IconSizes = []string{IconSmall, IconMedium, IconLarge}
+
+// ParseIconSize validates an icon size name. Empty keeps the size the NWS gave.
+func ParseIconSize(s string) (string, error) {
+	size := strings.ToLower(strings.TrimSpace(s))
+	switch size {
+	case "", IconSmall, IconMedium, IconLarge:
+		return size, nil
+	}
+	return "", fmt.Errorf("unknown icon size %q (accepted: %s)", s, strings.Join(IconSizes, ", "))
+}
+
+// ResizeIcon returns an NWS icon URL with its size query parameter set,
+// keeping any other parameters. Empty URLs and sizes, and URLs that don't
+// parse, are returned unchanged.
+func ResizeIcon(icon, size string) string {
+	if icon == "" || size == "" {
+		return icon
+	}
+	u, err := url.Parse(icon)
+	if err != nil {
+		return icon
+	}
+	query := u.Query()
+	query.Set("size", size)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// ResizeIcons returns forecast periods with their icons resized, copying them
+// so cached periods are left as they are
+func ResizeIcons(periods []models.ForecastPeriod, size string) []models.ForecastPeriod {
+	if size == "" {
+		return periods
+	}
+	resized := make([]models.ForecastPeriod, len(periods))
+	for i, p := range periods {
+		p.Icon = ResizeIcon(p.Icon, size)
+		resized[i] = p
+	}
+	return resized
+}
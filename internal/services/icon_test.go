@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+
+	"weather-api-go/internal/models"
+)
+
+func TestParseIconSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"small", IconSmall, false},
+		{" Large ", IconLarge, false},
+		{"MEDIUM", IconMedium, false},
+		{"huge", "", true},
+		{"64", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseIconSize(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseIconSize(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestResizeIcon(t *testing.T) {
+	tests := []struct {
+		icon, size, want string
+	}{
+		{"https://api.weather.gov/icons/land/day/few?size=medium", IconLarge, "https://api.weather.gov/icons/land/day/few?size=large"},
+		{"https://api.weather.gov/icons/land/day/few", IconSmall, "https://api.weather.gov/icons/land/day/few?size=small"},
+		// Combined icons keep the comma-separated chances in their path
+		{"https://api.weather.gov/icons/land/day/tsra_hi,20/rain,40?size=medium", IconSmall,
+			"https://api.weather.gov/icons/land/day/tsra_hi,20/rain,40?size=small"},
+		// Other query parameters are kept
+		{"https://api.weather.gov/icons/land/night/few?fontsize=12&size=medium", IconLarge,
+			"https://api.weather.gov/icons/land/night/few?fontsize=12&size=large"},
+		{"https://api.weather.gov/icons/land/night/few?size=medium&size=small", IconLarge,
+			"https://api.weather.gov/icons/land/night/few?size=large"},
+		// No size keeps the URL as the NWS gave it
+		{"https://api.weather.gov/icons/land/day/few?size=medium", "", "https://api.weather.gov/icons/land/day/few?size=medium"},
+		{"", IconLarge, ""},
+		{"://not a url", IconLarge, "://not a url"},
+	}
+	for _, tt := range tests {
+		if got := ResizeIcon(tt.icon, tt.size); got != tt.want {
+			t.Errorf("ResizeIcon(%q, %q) = %q; want %q", tt.icon, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestResizeIconsCopiesPeriods(t *testing.T) {
+	const icon = "https://api.weather.gov/icons/land/day/few?size=medium"
+	periods := []models.ForecastPeriod{{Name: "Today", Icon: icon}, {Name: "Tonight"}}
+
+	resized := ResizeIcons(periods, IconSmall)
+	if resized[0].Icon != "https://api.weather.gov/icons/land/day/few?size=small" || resized[1].Icon != "" {
+		t.Errorf("resized icons = %q, %q; want the small icon and none", resized[0].Icon, resized[1].Icon)
+	}
+	if periods[0].Icon != icon {
+		t.Errorf("original icon = %q; want it left as %q", periods[0].Icon, icon)
+	}
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+
+	"weather-api-go/internal/models"
+)
+
+// InvalidateWeather drops a coordinate's cached forecast so the next request
+// refetches it from the NWS. The forecast cached for the coordinate's grid
+// cell is dropped too when the cell is known, since it would otherwise refill
+// the coordinate's entry.
+func (s *WeatherService) InvalidateWeather(ctx context.Context, lat, lon float64) (*models.CacheInvalidationResponse, error) {
+	removed, err := s.repo.DeleteWeather(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+
+	point, err := s.repo.GetGridPoint(ctx, normalizePointCoordinate(lat), normalizePointCoordinate(lon))
+	if err == nil {
+		cell, err := s.repo.DeleteGridForecast(ctx, point.GridID, point.GridX, point.GridY)
+		if err != nil {
+			return nil, err
+		}
+		removed.RedisKeys += cell.RedisKeys
+		removed.SQLiteRows += cell.SQLiteRows
+	}
+	return &removed, nil
+}
+
+// InvalidateAllWeather drops every cached forecast; history and grid mappings are kept
+func (s *WeatherService) InvalidateAllWeather(ctx context.Context) (*models.CacheInvalidationResponse, error) {
+	removed, err := s.repo.DeleteAllWeather(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &removed, nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"errors"
+	"net/netip"
+
+	"weather-api-go/internal/models"
+)
+
+// ErrIPLocationDisabled is returned for IP lookups when no IP locator is configured
+var ErrIPLocationDisabled = errors.New("IP location is not configured")
+
+// ErrIPNotLocated is returned when an IP address has no known location
+var ErrIPNotLocated = errors.New("no location is known for the IP address")
+
+// IPLocator estimates locations from IP addresses, such as a GeoLite2 City
+// database opened with geoip.Open
+type IPLocator interface {
+	// LocateIP returns the approximate location of an address, or nil when
+	// none is known
+	LocateIP(addr netip.Addr) (*models.IPLocation, error)
+}
+
+// WithIPLocator enables estimating a caller's location from their IP address
+// when a request gives none
+func WithIPLocator(l IPLocator) WeatherServiceOption {
+	return func(s *WeatherService) {
+		s.ipLocator = l
+	}
+}
+
+// LocateIP estimates the location of an IP address. ErrIPLocationDisabled is
+// returned when no IP locator is configured, and ErrIPNotLocated when the
+// address's location is unknown.
+func (s *WeatherService) LocateIP(addr netip.Addr) (*models.IPLocation, error) {
+	if s.ipLocator == nil {
+		return nil, ErrIPLocationDisabled
+	}
+	loc, err := s.ipLocator.LocateIP(addr)
+	if err != nil {
+		return nil, err
+	}
+	if loc == nil {
+		return nil, ErrIPNotLocated
+	}
+	return loc, nil
+}
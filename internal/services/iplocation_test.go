@@ -0,0 +1,43 @@
+package services
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+
+	"weather-api-go/internal/models"
+)
+
+// stubLocator is an IPLocator answering every lookup the same way
+type stubLocator struct {
+	loc *models.IPLocation
+	err error
+}
+
+func (l stubLocator) LocateIP(netip.Addr) (*models.IPLocation, error) {
+	return l.loc, l.err
+}
+
+func TestLocateIP(t *testing.T) {
+	london := &models.IPLocation{Latitude: 51.5142, Longitude: -0.0931, City: "London", Country: "GB"}
+	errCorrupt := errors.New("corrupt database")
+	tests := []struct {
+		name    string
+		locator IPLocator
+		want    *models.IPLocation
+		wantErr error
+	}{
+		{"located", stubLocator{loc: london}, london, nil},
+		{"unknown address", stubLocator{}, nil, ErrIPNotLocated},
+		{"lookup failure", stubLocator{err: errCorrupt}, nil, errCorrupt},
+		{"disabled", nil, nil, ErrIPLocationDisabled},
+	}
+
+	for _, tt := range tests {
+		service := NewWeatherService(&fakeStore{}, &fakeProvider{}, WithIPLocator(tt.locator))
+		got, err := service.LocateIP(netip.MustParseAddr("81.2.69.142"))
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("%s: LocateIP = %+v, %v; want %+v, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrUpstreamSaturated is returned when a request needs the NWS but upstream
+// capacity is saturated and no cached data exists to serve instead
+var ErrUpstreamSaturated = errors.New("upstream capacity saturated")
+
+// ShedError reports a request shed under load and when the client should retry
+type ShedError struct {
+	RetryAfter time.Duration
+	// Cause is why the request was shed when the reason is other than
+	// saturated upstream capacity, such as ErrCircuitOpen
+	Cause error
+}
+
+func (e *ShedError) Error() string {
+	cause := ErrUpstreamSaturated
+	if e.Cause != nil {
+		cause = e.Cause
+	}
+	return fmt.Sprintf("%v; retry after %s", cause, e.RetryAfter)
+}
+
+// Is lets errors.Is match ShedError against ErrUpstreamSaturated
+func (e *ShedError) Is(target error) bool {
+	return target == ErrUpstreamSaturated
+}
+
+// Unwrap returns the cause of the shed, if any
+func (e *ShedError) Unwrap() error {
+	return e.Cause
+}
+
+// LoadSheddingConfig bounds how much work may pile up behind the NWS
+type LoadSheddingConfig struct {
+	// MaxInFlight is the number of concurrent upstream requests; zero disables the limit
+	MaxInFlight int
+	// MaxQueueDepth is how many requests may wait for an upstream slot before
+	// new ones are shed immediately
+	MaxQueueDepth int
+	// MaxQueueWait is how long a request waits for a slot before being shed; zero waits indefinitely
+	MaxQueueWait time.Duration
+	// RetryAfter is the back-off suggested to shed clients
+	RetryAfter time.Duration
+}
+
+// DefaultRetryAfter is suggested to shed clients when none is configured
+const DefaultRetryAfter = 5 * time.Second
+
+// upstreamLimiter is a counting semaphore with a bounded, time-limited wait queue
+type upstreamLimiter struct {
+	cfg     LoadSheddingConfig
+	slots   chan struct{}
+	waiting atomic.Int64
+}
+
+func newUpstreamLimiter(cfg LoadSheddingConfig) *upstreamLimiter {
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = DefaultRetryAfter
+	}
+	return &upstreamLimiter{cfg: cfg, slots: make(chan struct{}, cfg.MaxInFlight)}
+}
+
+// acquire takes an upstream slot, returning a release func, or a *ShedError
+// when the queue is full or the wait exceeds MaxQueueWait
+func (l *upstreamLimiter) acquire() (func(), error) {
+	release := func() { <-l.slots }
+
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if l.waiting.Add(1) > int64(l.cfg.MaxQueueDepth) {
+		l.waiting.Add(-1)
+		return nil, &ShedError{RetryAfter: l.cfg.RetryAfter}
+	}
+	defer l.waiting.Add(-1)
+
+	var timeout <-chan time.Time
+	if l.cfg.MaxQueueWait > 0 {
+		timer := time.NewTimer(l.cfg.MaxQueueWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	case <-timeout:
+		return nil, &ShedError{RetryAfter: l.cfg.RetryAfter}
+	}
+}
+
+// upstream runs fn under the load-shedding limiter, if one is configured. A
+// request refused by the client's outbound rate limit is shed like one refused
+// an upstream slot, so callers serve stale cache when they have it.
+func (s *WeatherService) upstream(fn func() error) error {
+	if s.limiter != nil {
+		release, err := s.limiter.acquire()
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	err := fn()
+	var limited *RateLimitedError
+	if errors.As(err, &limited) {
+		return &ShedError{RetryAfter: limited.Wait}
+	}
+	return err
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/metrics"
+	"weather-api-go/internal/models"
+)
+
+// saturatedNWS serves points immediately but holds every forecast request
+// until release is closed, signalling started as each one arrives
+func saturatedNWS(t *testing.T) (server *httptest.Server, started chan struct{}, release chan struct{}) {
+	t.Helper()
+	started = make(chan struct{}, 16)
+	release = make(chan struct{})
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/points/"):
+			var lat, lon float64
+			fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/points/"), "%f,%f", &lat, &lon)
+			x := int(lat * 10)
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": %d, "gridY": 1, "forecast": "%s/gridpoints/OKX/%d,1/forecast"}}`,
+				x, server.URL, x)
+		case strings.HasSuffix(r.URL.Path, "/forecast"):
+			started <- struct{}{}
+			<-release
+			fmt.Fprint(w, `{"properties": {"periods": [
+				{"shortForecast": "Sunny", "temperature": 20, "temperatureUnit": "C"}
+			]}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+	return server, started, release
+}
+
+// occupyUpstream starts a cache-miss request that holds the only upstream slot
+func occupyUpstream(t *testing.T, service *WeatherService, started chan struct{}) chan error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() {
+		_, err := service.GetWeather(context.Background(), 40.0, -74.0)
+		done <- err
+	}()
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream request never started")
+	}
+	return done
+}
+
+func TestLoadSheddingShedsUncachedRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     LoadSheddingConfig
+		minWait time.Duration
+	}{
+		{"queue full", LoadSheddingConfig{MaxInFlight: 1, MaxQueueDepth: 0, RetryAfter: 7 * time.Second}, 0},
+		{"wait exceeded", LoadSheddingConfig{MaxInFlight: 1, MaxQueueDepth: 4, MaxQueueWait: 30 * time.Millisecond, RetryAfter: 7 * time.Second}, 30 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, started, release := saturatedNWS(t)
+			recorder := metrics.NewRecorder(0)
+			service := NewWeatherService(newTestRepo(t), newTestNWSClient(server),
+				WithLoadShedding(tt.cfg), WithMetrics(recorder))
+
+			done := occupyUpstream(t, service, started)
+
+			begin := time.Now()
+			_, err := service.GetWeather(context.Background(), 45.0, -74.0)
+			var shed *ShedError
+			if !errors.As(err, &shed) || !errors.Is(err, ErrUpstreamSaturated) {
+				t.Fatalf("GetWeather under saturation = %v; want ShedError", err)
+			}
+			if shed.RetryAfter != 7*time.Second {
+				t.Errorf("RetryAfter = %v; want 7s", shed.RetryAfter)
+			}
+			if waited := time.Since(begin); waited < tt.minWait {
+				t.Errorf("shed after %v; want at least %v", waited, tt.minWait)
+			}
+			if got := recorder.Counters()[metrics.RequestsShed]; got != 1 {
+				t.Errorf("%s = %d; want 1", metrics.RequestsShed, got)
+			}
+
+			close(release)
+			if err := <-done; err != nil {
+				t.Errorf("request holding the slot failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadSheddingNeverShedsCachedData(t *testing.T) {
+	old := time.Now().Add(-3 * time.Hour)
+
+	tests := []struct {
+		name string
+		seed func(t *testing.T, service *WeatherService)
+	}{
+		{"stale coordinate cache", func(t *testing.T, service *WeatherService) {
+			err := service.repo.SaveToCache(context.Background(), &models.WeatherCache{
+				Latitude: 45.0, Longitude: -74.0, Forecast: "Cached", TempC: 5, TempF: 41, Timestamp: old,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"stale grid forecast", func(t *testing.T, service *WeatherService) {
+			err := service.repo.SaveGridPoint(context.Background(), &models.GridPoint{
+				Latitude: 45.0, Longitude: -74.0, GridID: "OKX", GridX: 450, GridY: 1,
+				ForecastURL: "unused", Timestamp: time.Now(),
+			})
+			if err == nil {
+				err = service.repo.SaveGridForecast(context.Background(), "OKX", 450, 1, &models.WeatherCache{
+					Forecast: "Cached", TempC: 5, TempF: 41, Timestamp: old,
+				})
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, started, release := saturatedNWS(t)
+			recorder := metrics.NewRecorder(0)
+			service := NewWeatherService(newTestRepo(t), newTestNWSClient(server),
+				WithLoadShedding(LoadSheddingConfig{MaxInFlight: 1}), WithMetrics(recorder))
+			tt.seed(t, service)
+
+			done := occupyUpstream(t, service, started)
+
+			resp, err := service.GetWeather(context.Background(), 45.0, -74.0)
+			if err != nil {
+				t.Fatalf("cached coordinate was shed: %v", err)
+			}
+			if resp.Forecast != "Cached" {
+				t.Errorf("Forecast = %q; want cached data", resp.Forecast)
+			}
+			if got := recorder.Counters()[metrics.RequestsShed]; got != 0 {
+				t.Errorf("%s = %d; want 0", metrics.RequestsShed, got)
+			}
+
+			close(release)
+			<-done
+		})
+	}
+}
+
+func TestUpstreamLimiterQueuesUntilSlotFree(t *testing.T) {
+	l := newUpstreamLimiter(LoadSheddingConfig{MaxInFlight: 1, MaxQueueDepth: 1})
+
+	release, err := l.acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		r, err := l.acquire()
+		if err == nil {
+			r()
+		}
+		acquired <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	release()
+
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Errorf("queued acquire = %v; want slot once released", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("queued acquire never got the released slot")
+	}
+}
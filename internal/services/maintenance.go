@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+// DefaultMaintenanceInterval is how often the maintenance job runs
+const DefaultMaintenanceInterval = time.Hour
+
+// DefaultCacheRetention is how long a cached coordinate forecast is kept after
+// it was fetched. Well past the cache TTL, so recently expired entries remain
+// available as a stale fallback.
+const DefaultCacheRetention = 72 * time.Hour
+
+// DefaultPruneLowWater is the fraction of the size cap pruning shrinks the database to
+const DefaultPruneLowWater = 0.8
+
+// MaintenanceConfig controls the periodic cache maintenance job
+type MaintenanceConfig struct {
+	// HistoryRawRetention is how long individual cached forecasts are kept
+	// before being downsampled into daily summaries
+	HistoryRawRetention time.Duration
+	// CacheRetention is how long cached coordinate forecasts are kept; 0 keeps them indefinitely
+	CacheRetention time.Duration
+	// MaxDatabaseBytes caps the SQLite database size; 0 means unlimited
+	MaxDatabaseBytes int64
+	// PruneLowWater is the fraction of MaxDatabaseBytes that pruning stops at,
+	// leaving headroom so the cap isn't hit again on the next pass
+	PruneLowWater float64
+}
+
+// DefaultMaintenanceConfig returns the default maintenance settings
+func DefaultMaintenanceConfig() MaintenanceConfig {
+	return MaintenanceConfig{
+		HistoryRawRetention: repository.DefaultHistoryRawRetention,
+		CacheRetention:      DefaultCacheRetention,
+		PruneLowWater:       DefaultPruneLowWater,
+	}
+}
+
+// Maintenance periodically compacts the persistent cache
+type Maintenance struct {
+	repo *repository.WeatherRepository
+	cfg  MaintenanceConfig
+	now  func() time.Time
+
+	mu           sync.Mutex
+	prunedRows   int64
+	lastPrunedAt *time.Time
+	purgedRows   int64
+	lastPurgeAt  *time.Time
+}
+
+// NewMaintenance creates the maintenance job
+func NewMaintenance(repo *repository.WeatherRepository, cfg MaintenanceConfig) *Maintenance {
+	if cfg.HistoryRawRetention <= 0 {
+		cfg.HistoryRawRetention = repository.DefaultHistoryRawRetention
+	}
+	if cfg.PruneLowWater <= 0 || cfg.PruneLowWater > 1 {
+		cfg.PruneLowWater = DefaultPruneLowWater
+	}
+	return &Maintenance{repo: repo, cfg: cfg, now: time.Now}
+}
+
+// RunOnce performs a single maintenance pass
+func (m *Maintenance) RunOnce(ctx context.Context) error {
+	n, err := m.repo.DownsampleWeatherHistory(ctx, m.now().Add(-m.cfg.HistoryRawRetention))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("Downsampled %d cached forecasts into daily summaries", n)
+	}
+	if err := m.purgeExpired(ctx); err != nil {
+		return err
+	}
+	return m.enforceSizeCap(ctx)
+}
+
+// purgeExpired deletes cached coordinate forecasts older than CacheRetention
+func (m *Maintenance) purgeExpired(ctx context.Context) error {
+	if m.cfg.CacheRetention <= 0 {
+		return nil
+	}
+	now := m.now()
+	n, err := m.repo.PurgeWeatherCache(ctx, now.Add(-m.cfg.CacheRetention))
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.purgedRows += n
+	m.lastPurgeAt = &now
+	m.mu.Unlock()
+	if n > 0 {
+		log.Printf("Purged %d cached forecasts older than %s", n, m.cfg.CacheRetention)
+	}
+	return nil
+}
+
+// enforceSizeCap prunes the least recently used cache rows once the database
+// exceeds its size cap, down to the low-water mark
+func (m *Maintenance) enforceSizeCap(ctx context.Context) error {
+	if m.cfg.MaxDatabaseBytes <= 0 {
+		return nil
+	}
+	size, err := m.repo.DatabaseSize(ctx)
+	if err != nil || size <= m.cfg.MaxDatabaseBytes {
+		return err
+	}
+
+	target := int64(float64(m.cfg.MaxDatabaseBytes) * m.cfg.PruneLowWater)
+	n, err := m.repo.PruneToSize(ctx, target)
+	if n > 0 {
+		now := m.now()
+		m.mu.Lock()
+		m.prunedRows += n
+		m.lastPrunedAt = &now
+		m.mu.Unlock()
+		log.Printf("Database at %d bytes exceeded its %d byte cap; pruned %d cache rows", size, m.cfg.MaxDatabaseBytes, n)
+	}
+	return err
+}
+
+// DatabaseUsage reports the database size against its cap
+func (m *Maintenance) DatabaseUsage(ctx context.Context) (models.DatabaseUsage, error) {
+	size, err := m.repo.DatabaseSize(ctx)
+	if err != nil {
+		return models.DatabaseUsage{}, err
+	}
+	usage := models.DatabaseUsage{SizeBytes: size, MaxBytes: m.cfg.MaxDatabaseBytes}
+	if m.cfg.MaxDatabaseBytes > 0 {
+		ratio := float64(size) / float64(m.cfg.MaxDatabaseBytes)
+		usage.UsageRatio = &ratio
+	}
+	return usage, nil
+}
+
+// CacheStats reports database usage, cached rows per table, and rows removed
+// by retention purging and size-based pruning so far
+func (m *Maintenance) CacheStats(ctx context.Context) (*models.CacheStatsResponse, error) {
+	usage, err := m.DatabaseUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := m.repo.CacheRowCounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return &models.CacheStatsResponse{
+		Database:     usage,
+		Tables:       tables,
+		PrunedRows:   m.prunedRows,
+		LastPrunedAt: m.lastPrunedAt,
+		PurgedRows:   m.purgedRows,
+		LastPurgeAt:  m.lastPurgeAt,
+	}, nil
+}
+
+// Run performs a maintenance pass immediately and then every interval until ctx is cancelled
+func (m *Maintenance) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := m.RunOnce(ctx); err != nil {
+			log.Printf("Cache maintenance failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
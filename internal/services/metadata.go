@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+// GetMetadata returns the NWS metadata a coordinate resolves to, from the
+// points document behind its grid mapping. The metadata is cached with the
+// mapping for GridPointTTL, as it only changes when the NWS reworks its grids.
+func (s *WeatherService) GetMetadata(ctx context.Context, lat, lon float64) (*models.MetadataResponse, error) {
+	point, err := s.resolveGridPoint(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.MetadataResponse{
+		Latitude:          lat,
+		Longitude:         lon,
+		Office:            point.GridID,
+		GridX:             point.GridX,
+		GridY:             point.GridY,
+		ForecastZone:      point.ForecastZone,
+		County:            point.County,
+		RadarStation:      point.RadarStation,
+		ForecastURL:       point.ForecastURL,
+		ForecastHourlyURL: point.ForecastHourlyURL,
+		City:              point.City,
+		State:             point.State,
+		TimeZone:          point.TimeZone,
+	}
+	// An expired mapping is only returned when refetching it failed
+	if s.repo.IsGridPointFresh(point) {
+		resp.FreshUntil = point.Timestamp.Add(repository.GridPointTTL)
+		// A freshly resolved mapping carries its points document
+		resp.CacheHit = point.Raw == nil
+	}
+	return resp, nil
+}
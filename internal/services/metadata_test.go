@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGetMetadataSharesGridMapping(t *testing.T) {
+	server, hits := nwsFixture(t, http.StatusOK, "nws_points.json", http.StatusOK, "nws_forecast.json")
+	service := NewWeatherService(newTestRepo(t), newTestNWSClient(server))
+
+	meta, err := service.GetMetadata(context.Background(), 40.7128, -74.006)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Office != "OKX" || meta.GridX != 33 || meta.GridY != 35 || meta.ForecastZone != "NYZ072" ||
+		meta.County != "NYC061" || meta.RadarStation != "KOKX" || meta.City != "New York" || meta.TimeZone != "America/New_York" {
+		t.Errorf("metadata = %+v; want OKX 33,35 in New York", meta)
+	}
+	if meta.CacheHit || meta.FreshUntil.IsZero() {
+		t.Errorf("first lookup: cache hit %v, fresh until %v; want a fresh fetch", meta.CacheHit, meta.FreshUntil)
+	}
+
+	// The forecast, the metadata again, and the alert zone all reuse the mapping
+	if _, err := service.GetWeather(context.Background(), 40.7128, -74.006); err != nil {
+		t.Fatal(err)
+	}
+	if meta, err = service.GetMetadata(context.Background(), 40.7128, -74.006); err != nil || !meta.CacheHit {
+		t.Errorf("second lookup = %+v, %v; want a cache hit", meta, err)
+	}
+	if zone, err := service.resolveForecastZone(context.Background(), 40.7128, -74.006); err != nil || zone != "NYZ072" {
+		t.Errorf("forecast zone = %q, %v; want NYZ072", zone, err)
+	}
+	if n := atomic.LoadInt32(hits["points"]); n != 1 {
+		t.Errorf("points fetched %d times; want 1", n)
+	}
+}
@@ -1,91 +1,692 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"golang.org/x/time/rate"
 	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+	"weather-api-go/internal/tracing"
+	"weather-api-go/internal/units"
 )
 
+// ErrStationNotFound is returned when the NWS does not know the requested station
+var ErrStationNotFound = errors.New("observation station not found")
+
+// ErrNoHourlyForecast is returned when the points response carries no forecastHourly URL
+var ErrNoHourlyForecast = errors.New("no hourly forecast for this location")
+
+// ErrNoObservationStation is returned when the NWS lists no observation station near a point
+var ErrNoObservationStation = errors.New("no observation station near this location")
+
+// ErrForecastNotFound is returned when a grid cell's forecast URL no longer exists,
+// typically because the NWS reassigned the cell
+var ErrForecastNotFound = errors.New("NWS forecast URL not found")
+
+// ErrDocumentTooLarge is returned when an upstream document exceeds MaxDocumentBytes
+var ErrDocumentTooLarge = errors.New("upstream document too large")
+
+// errNoForecastURL and errNoForecastPeriods report NWS documents missing what
+// a forecast is built from
+var (
+	errNoForecastURL     = errors.New("no forecast URL found in points response")
+	errNoForecastPeriods = errors.New("no forecast periods found")
+)
+
+// DefaultUpstreamTimeout bounds a single request to a forecast provider or
+// geocoder, response body included
+const DefaultUpstreamTimeout = 10 * time.Second
+
+// MaxDocumentBytes caps the size of a points or forecast document read from the NWS
+const MaxDocumentBytes = 1 << 20
+
+// DefaultNWSUserAgent identifies the service to api.weather.gov, whose terms
+// require a descriptive User-Agent with contact information
+const DefaultNWSUserAgent = "weather-api-go (https://github.com/4cecoder/weather-api-go)"
+
 // NWSAPIClient handles communication with National Weather Service API
 type NWSAPIClient struct {
-	baseURL    string
+	baseURL string
+	// httpClient is built from transport unless given by WithHTTPClient
 	httpClient *http.Client
+	transport  TransportConfig
+	userAgent  string
+	retry      RetryConfig
+	// limiter spaces outbound requests; nil sends them unthrottled
+	limiter     *rate.Limiter
+	maxRateWait time.Duration
+	// health remembers the outcome of the latest request, shared by copies
+	health *upstreamHealth
+	// breaker stops requests after repeated failures, shared by copies; nil
+	// always sends them
+	breaker *circuitBreaker
+	// backoff stops requests while the NWS has asked for it with Retry-After,
+	// shared by copies
+	backoff *upstreamBackoff
+}
+
+// NWSClientOption configures optional NWSAPIClient behavior
+type NWSClientOption func(*NWSAPIClient)
+
+// WithBaseURL points the client at a different NWS-compatible host, such as a test server
+func WithBaseURL(baseURL string) NWSClientOption {
+	return func(c *NWSAPIClient) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithHTTPClient replaces the HTTP client used for upstream requests, such as
+// one going through a proxy; its own timeout and transport apply
+func WithHTTPClient(httpClient *http.Client) NWSClientOption {
+	return func(c *NWSAPIClient) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithUserAgent sets the User-Agent sent on every NWS request; empty keeps
+// DefaultNWSUserAgent
+func WithUserAgent(userAgent string) NWSClientOption {
+	return func(c *NWSAPIClient) {
+		if userAgent != "" {
+			c.userAgent = userAgent
+		}
+	}
 }
 
 // NewNWSAPIClient creates a new NWS API client
-func NewNWSAPIClient() *NWSAPIClient {
-	return &NWSAPIClient{
-		baseURL: "https://api.weather.gov",
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+func NewNWSAPIClient(opts ...NWSClientOption) *NWSAPIClient {
+	c := &NWSAPIClient{
+		baseURL:   "https://api.weather.gov",
+		transport: DefaultTransportConfig(),
+		userAgent: DefaultNWSUserAgent,
+		retry:     DefaultRetryConfig(),
+		health:    &upstreamHealth{},
+		backoff:   &upstreamBackoff{now: time.Now},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.httpClient == nil {
+		c.httpClient = c.transport.newHTTPClient()
 	}
+	return c
 }
 
-// GetForecast fetches weather forecast for given coordinates
-func (c *NWSAPIClient) GetForecast(lat, lon float64) (*models.WeatherCache, error) {
-	// Step 1: Get forecast URL from points endpoint
-	pointsURL := fmt.Sprintf("%s/points/%f,%f", c.baseURL, lat, lon)
+// get issues a GET request to the NWS with the client's User-Agent, retrying
+// transient failures
+func (c *NWSAPIClient) get(ctx context.Context, docURL string) (*http.Response, error) {
+	return c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, docURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", c.userAgent)
+		otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+		return req, nil
+	})
+}
 
-	pointsResp, err := c.httpClient.Get(pointsURL)
+// readDocument reads an upstream response body verbatim, refusing bodies over MaxDocumentBytes
+func readDocument(resp *http.Response) (*models.RawDocument, error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxDocumentBytes+1))
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch points data: %w", err)
+		return nil, err
+	}
+	if len(body) > MaxDocumentBytes {
+		return nil, ErrDocumentTooLarge
+	}
+	return &models.RawDocument{
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+		Timestamp:   time.Now(),
+	}, nil
+}
+
+// pointsPrecision is the most decimal places api.weather.gov accepts in a
+// points URL; more precise coordinates are answered with a 301 to the truncated form
+const pointsPrecision = 4
+
+// FormatPointCoordinate formats a coordinate for an NWS points URL: rounded
+// to four decimal places with trailing zeros trimmed, e.g. 40.7128 or -74
+func FormatPointCoordinate(v float64) string {
+	scale := math.Pow10(pointsPrecision)
+	rounded := math.Round(v*scale) / scale
+	if rounded == 0 {
+		rounded = 0 // avoid "-0"
+	}
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
+
+// getPoints fetches the NWS points metadata for given coordinates, along with the raw document
+func (c *NWSAPIClient) getPoints(ctx context.Context, lat, lon float64) (_ *models.NWSPointsResponse, _ *models.RawDocument, err error) {
+	ctx, span := startSpan(ctx, "nws.points", tracing.Coordinate(lat, lon)...)
+	defer func() { tracing.End(span, err) }()
+
+	pointsURL := fmt.Sprintf("%s/points/%s,%s", c.baseURL, FormatPointCoordinate(lat), FormatPointCoordinate(lon))
+
+	pointsResp, err := c.get(ctx, pointsURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch points data: %w", err)
 	}
 	defer pointsResp.Body.Close()
 
 	if pointsResp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("NWS points API returned status: %d", pointsResp.StatusCode)
+		// The NWS answers points it doesn't forecast for with a 404 whose
+		// detail reads "Unable to provide data for requested point"
+		problem := readProblem("NWS points", pointsResp)
+		if problem.outsideCoverage() {
+			return nil, nil, fmt.Errorf("%w: %w", ErrOutsideCoverage, problem)
+		}
+		return nil, nil, problem
+	}
+
+	doc, err := readDocument(pointsResp)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read points response: %w", err)
 	}
 
 	var pointsData models.NWSPointsResponse
-	if err := json.NewDecoder(pointsResp.Body).Decode(&pointsData); err != nil {
-		return nil, fmt.Errorf("failed to decode points response: %w", err)
+	if err := json.Unmarshal(doc.Body, &pointsData); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode points response: %w", err)
+	}
+
+	return &pointsData, doc, nil
+}
+
+// GetForecastZone resolves the NWS forecast zone ID (e.g. NYZ072) for given coordinates
+func (c *NWSAPIClient) GetForecastZone(ctx context.Context, lat, lon float64) (string, error) {
+	pointsData, _, err := c.getPoints(ctx, lat, lon)
+	if err != nil {
+		return "", err
+	}
+
+	zone := lastPathSegment(pointsData.Properties.ForecastZone)
+	if zone == "" {
+		return "", fmt.Errorf("no forecast zone found in points response")
+	}
+	return zone, nil
+}
+
+// GetActiveAlerts fetches the active alerts for an NWS zone
+func (c *NWSAPIClient) GetActiveAlerts(ctx context.Context, zone string) ([]models.Alert, error) {
+	return c.getActiveAlerts(ctx, fmt.Sprintf("%s/alerts/active?zone=%s", c.baseURL, url.QueryEscape(zone)))
+}
+
+// GetActivePointAlerts fetches the active alerts whose area contains a
+// coordinate, including storm-based warnings drawn as polygons rather than zones
+func (c *NWSAPIClient) GetActivePointAlerts(ctx context.Context, lat, lon float64) ([]models.Alert, error) {
+	return c.getActiveAlerts(ctx, fmt.Sprintf("%s/alerts/active?point=%.4f,%.4f", c.baseURL, lat, lon))
+}
+
+// getActiveAlerts fetches and normalizes an NWS active alerts feature collection
+func (c *NWSAPIClient) getActiveAlerts(ctx context.Context, alertsURL string) ([]models.Alert, error) {
+	resp, err := c.get(ctx, alertsURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch alerts: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, readProblem("NWS alerts", resp)
 	}
 
-	if pointsData.Properties.Forecast == "" {
-		return nil, fmt.Errorf("no forecast URL found in points response")
+	var alertsData models.NWSAlertsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&alertsData); err != nil {
+		return nil, fmt.Errorf("failed to decode alerts response: %w", err)
+	}
+
+	alerts := make([]models.Alert, 0, len(alertsData.Features))
+	for _, feature := range alertsData.Features {
+		p := feature.Properties
+
+		zones := make([]string, 0, len(p.AffectedZones))
+		for _, z := range p.AffectedZones {
+			zones = append(zones, lastPathSegment(z))
+		}
+
+		var refs []string
+		for _, ref := range p.References {
+			refs = append(refs, ref.Identifier)
+		}
+
+		alerts = append(alerts, models.Alert{
+			ID:            p.ID,
+			Event:         p.Event,
+			Severity:      p.Severity,
+			Urgency:       p.Urgency,
+			Headline:      p.Headline,
+			MessageType:   p.MessageType,
+			Sent:          p.Sent,
+			Onset:         p.Onset,
+			Expires:       p.Expires,
+			AffectedZones: zones,
+			References:    refs,
+			Geometry:      feature.Geometry,
+		})
+	}
+
+	return alerts, nil
+}
+
+// zoneType returns the NWS zone type of an alert's affected zone from its
+// UGC code: NYC047 is a county and NYZ072 a public forecast zone
+func zoneType(zone string) string {
+	if len(zone) > 2 && zone[2] == 'C' {
+		return "county"
+	}
+	return "forecast"
+}
+
+// GetZone fetches the document of an NWS county or forecast zone verbatim
+func (c *NWSAPIClient) GetZone(ctx context.Context, zone string) (*models.RawDocument, error) {
+	resp, err := c.get(ctx, fmt.Sprintf("%s/zones/%s/%s", c.baseURL, zoneType(zone), url.PathEscape(zone)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch zone: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, readProblem("NWS zones", resp)
+	}
+
+	doc, err := readDocument(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zone response: %w", err)
+	}
+	return doc, nil
+}
+
+// zoneGeometry reads the shape from an NWS zone document
+func zoneGeometry(doc *models.RawDocument) (*models.Geometry, error) {
+	var zoneData models.NWSZoneResponse
+	if err := json.Unmarshal(doc.Body, &zoneData); err != nil {
+		return nil, fmt.Errorf("failed to decode zone response: %w", err)
+	}
+	if zoneData.Geometry == nil {
+		return nil, fmt.Errorf("no geometry found in zone")
+	}
+	return zoneData.Geometry, nil
+}
+
+// lastPathSegment returns the final segment of an NWS resource URL
+func lastPathSegment(resource string) string {
+	return resource[strings.LastIndex(resource, "/")+1:]
+}
+
+// GetGridPoint resolves the NWS forecast grid cell containing the given coordinates
+func (c *NWSAPIClient) GetGridPoint(ctx context.Context, lat, lon float64) (*models.GridPoint, error) {
+	pointsData, doc, err := c.getPoints(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+
+	p := pointsData.Properties
+	if p.Forecast == "" {
+		return nil, errNoForecastURL
+	}
+
+	return &models.GridPoint{
+		Latitude:          lat,
+		Longitude:         lon,
+		GridID:            p.GridID,
+		GridX:             p.GridX,
+		GridY:             p.GridY,
+		ForecastURL:       p.Forecast,
+		ForecastHourlyURL: p.ForecastHourly,
+		City:              p.RelativeLocation.Properties.City,
+		State:             p.RelativeLocation.Properties.State,
+		TimeZone:          p.TimeZone,
+		ForecastZone:      lastPathSegment(p.ForecastZone),
+		County:            lastPathSegment(p.County),
+		RadarStation:      p.RadarStation,
+		Timestamp:         time.Now(),
+		Raw:               doc,
+	}, nil
+}
+
+// GetForecast fetches weather forecast for given coordinates, with requests
+// bound by ctx. It implements WeatherProvider.
+func (c *NWSAPIClient) GetForecast(ctx context.Context, lat, lon float64) (*models.WeatherCache, error) {
+
+	// Step 1: Get forecast URL from points endpoint
+	point, err := c.GetGridPoint(ctx, lat, lon)
+	if err != nil {
+		return nil, err
 	}
 
 	// Step 2: Get actual forecast data
-	forecastResp, err := c.httpClient.Get(pointsData.Properties.Forecast)
+	weather, err := c.GetGridForecast(ctx, point.ForecastURL)
+	if err != nil {
+		return nil, err
+	}
+
+	weather.Latitude = lat
+	weather.Longitude = lon
+	return weather, nil
+}
+
+// GetGridForecast fetches the forecast for a grid cell from its NWS forecast URL.
+// The returned cache entry carries no coordinates.
+func (c *NWSAPIClient) GetGridForecast(ctx context.Context, forecastURL string) (_ *models.WeatherCache, err error) {
+	ctx, span := startSpan(ctx, "nws.forecast", semconv.URLFull(forecastURL))
+	defer func() { tracing.End(span, err) }()
+
+	forecastResp, err := c.get(ctx, forecastURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch forecast data: %w", err)
 	}
 	defer forecastResp.Body.Close()
 
+	if forecastResp.StatusCode == http.StatusNotFound {
+		return nil, ErrForecastNotFound
+	}
 	if forecastResp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("NWS forecast API returned status: %d", forecastResp.StatusCode)
+		return nil, readProblem("NWS forecast", forecastResp)
+	}
+
+	doc, err := readDocument(forecastResp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read forecast response: %w", err)
 	}
 
 	var forecastData models.NWSForecastResponse
-	if err := json.NewDecoder(forecastResp.Body).Decode(&forecastData); err != nil {
+	if err := json.Unmarshal(doc.Body, &forecastData); err != nil {
 		return nil, fmt.Errorf("failed to decode forecast response: %w", err)
 	}
 
 	if len(forecastData.Properties.Periods) == 0 {
-		return nil, fmt.Errorf("no forecast periods found")
+		return nil, errNoForecastPeriods
 	}
 
 	// Parse first period (today's forecast)
 	today := forecastData.Properties.Periods[0]
 
-	// Convert temperature to Celsius if needed
-	var tempC float64
-	if today.TemperatureUnit == "F" {
-		tempC = (today.Temperature - 32) * 5 / 9
-	} else {
-		tempC = today.Temperature
-	}
+	tempC, tempF := normalizeTemperature(today.Temperature, today.TemperatureUnit)
+	windKmh, windMph := normalizeWind(today.WindSpeed)
+	dewpointC, dewpointF := temperatureQuantity(today.Dewpoint)
 
 	return &models.WeatherCache{
-		Latitude:  lat,
-		Longitude: lon,
-		Forecast:  today.ShortForecast,
-		TempC:     tempC,
-		TempF:     today.Temperature,
-		Timestamp: time.Now(),
+		Forecast:         today.ShortForecast,
+		PeriodName:       today.Name,
+		IsDaytime:        &today.IsDaytime,
+		Icon:             today.Icon,
+		DetailedForecast: today.DetailedForecast,
+		TempC:            tempC,
+		TempF:            tempF,
+		TemperatureTrend: today.TemperatureTrend,
+		WindKmh:          windKmh,
+		WindMph:          windMph,
+		WindDirection:    strings.TrimSpace(today.WindDirection),
+		Timestamp:        time.Now(),
+		Raw:              doc,
+
+		PrecipitationProbability: today.ProbabilityOfPrecipitation.Value,
+		RelativeHumidity:         today.RelativeHumidity.Value,
+		DewpointC:                dewpointC,
+		DewpointF:                dewpointF,
 	}, nil
 }
+
+// GetForecastPeriods fetches every period of an NWS forecast document, such as
+// a grid cell's forecast or forecast/hourly URL, normalized and in NWS order
+func (c *NWSAPIClient) GetForecastPeriods(ctx context.Context, forecastURL string) (_ []models.ForecastPeriod, err error) {
+	ctx, span := startSpan(ctx, "nws.forecast", semconv.URLFull(forecastURL))
+	defer func() { tracing.End(span, err) }()
+
+	resp, err := c.get(ctx, forecastURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch forecast data: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, readProblem("NWS forecast", resp)
+	}
+
+	var forecastData models.NWSForecastResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, MaxDocumentBytes)).Decode(&forecastData); err != nil {
+		return nil, fmt.Errorf("failed to decode forecast response: %w", err)
+	}
+
+	return forecastPeriods(&forecastData)
+}
+
+// decodeForecastPeriods normalizes the periods of an NWS forecast document
+// already read, such as a WeatherCache's Raw document
+func decodeForecastPeriods(body []byte) ([]models.ForecastPeriod, error) {
+	var forecastData models.NWSForecastResponse
+	if err := json.Unmarshal(body, &forecastData); err != nil {
+		return nil, fmt.Errorf("failed to decode forecast response: %w", err)
+	}
+	return forecastPeriods(&forecastData)
+}
+
+// forecastPeriods normalizes a decoded forecast document's periods
+func forecastPeriods(forecastData *models.NWSForecastResponse) ([]models.ForecastPeriod, error) {
+	if len(forecastData.Properties.Periods) == 0 {
+		return nil, errNoForecastPeriods
+	}
+
+	periods := make([]models.ForecastPeriod, 0, len(forecastData.Properties.Periods))
+	for _, p := range forecastData.Properties.Periods {
+		tempC, tempF := normalizeTemperature(p.Temperature, p.TemperatureUnit)
+		periods = append(periods, models.ForecastPeriod{
+			Name:                     p.Name,
+			StartTime:                p.StartTime,
+			EndTime:                  p.EndTime,
+			IsDaytime:                p.IsDaytime,
+			ShortForecast:            p.ShortForecast,
+			DetailedForecast:         p.DetailedForecast,
+			TempC:                    tempC,
+			TempF:                    tempF,
+			PrecipitationProbability: p.ProbabilityOfPrecipitation.Value,
+			HumidityPercent:          p.RelativeHumidity.Value,
+			WindSpeed:                p.WindSpeed,
+			WindDirection:            p.WindDirection,
+			Icon:                     p.Icon,
+		})
+	}
+
+	return periods, nil
+}
+
+// GetHourlyForecast fetches a grid cell's hourly forecast from the forecastHourly
+// URL of its points response, normalized and in NWS order
+func (c *NWSAPIClient) GetHourlyForecast(ctx context.Context, hourlyURL string) ([]models.ForecastPeriod, error) {
+	if hourlyURL == "" {
+		return nil, ErrNoHourlyForecast
+	}
+	return c.GetForecastPeriods(ctx, hourlyURL)
+}
+
+// normalizeTemperature returns an NWS forecast temperature in Celsius and Fahrenheit
+func normalizeTemperature(value float64, unit string) (float64, float64) {
+	if unit == "F" {
+		return units.FahrenheitToCelsius(value), value
+	}
+	return value, units.CelsiusToFahrenheit(value)
+}
+
+// normalizeWind converts an NWS wind speed such as "5 to 10 mph" into km/h
+// and mph ranges, both nil when the speed is absent or can't be parsed
+func normalizeWind(speed string) (kmh, mph *models.SpeedRange) {
+	r, err := units.ParseWindSpeed(speed)
+	if err != nil {
+		return nil, nil
+	}
+	k, m := r.Convert(units.KMH), r.Convert(units.MPH)
+	return &models.SpeedRange{Min: k.Min, Max: k.Max}, &models.SpeedRange{Min: m.Min, Max: m.Max}
+}
+
+// GetStationObservations fetches observations for a station between start and end,
+// normalized into our units and ordered oldest first
+func (c *NWSAPIClient) GetStationObservations(ctx context.Context, stationID string, start, end time.Time) ([]models.Observation, error) {
+	obsURL := fmt.Sprintf("%s/stations/%s/observations?start=%s&end=%s",
+		c.baseURL,
+		url.PathEscape(stationID),
+		url.QueryEscape(start.UTC().Format(time.RFC3339)),
+		url.QueryEscape(end.UTC().Format(time.RFC3339)),
+	)
+
+	resp, err := c.get(ctx, obsURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch observations: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrStationNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, readProblem("NWS observations", resp)
+	}
+
+	var obsData models.NWSObservationsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&obsData); err != nil {
+		return nil, fmt.Errorf("failed to decode observations response: %w", err)
+	}
+
+	observations := make([]models.Observation, 0, len(obsData.Features))
+	for _, feature := range obsData.Features {
+		observations = append(observations, normalizeObservation(feature.Properties))
+	}
+
+	sort.Slice(observations, func(i, j int) bool {
+		return observations[i].Timestamp.Before(observations[j].Timestamp)
+	})
+
+	return observations, nil
+}
+
+// GetNearestStation resolves the observation station nearest a coordinate from
+// the station list linked by its points response
+func (c *NWSAPIClient) GetNearestStation(ctx context.Context, lat, lon float64) (string, error) {
+	pointsData, _, err := c.getPoints(ctx, lat, lon)
+	if err != nil {
+		return "", err
+	}
+	stationsURL := pointsData.Properties.ObservationStations
+	if stationsURL == "" {
+		return "", ErrNoObservationStation
+	}
+
+	resp, err := c.get(ctx, stationsURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch observation stations: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", readProblem("NWS stations", resp)
+	}
+
+	var stationsData models.NWSStationsResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, MaxDocumentBytes)).Decode(&stationsData); err != nil {
+		return "", fmt.Errorf("failed to decode stations response: %w", err)
+	}
+
+	// Pick by distance rather than trusting the list order; stations without a
+	// location only win when none has one
+	nearest, nearestKm := "", math.Inf(1)
+	for _, f := range stationsData.Features {
+		id := f.Properties.StationIdentifier
+		if id == "" {
+			continue
+		}
+		km := math.Inf(1)
+		if coords := f.Geometry.Coordinates; len(coords) >= 2 {
+			km = repository.DistanceKm(lat, lon, coords[1], coords[0])
+		}
+		if nearest == "" || km < nearestKm {
+			nearest, nearestKm = id, km
+		}
+	}
+	if nearest == "" {
+		return "", ErrNoObservationStation
+	}
+	return nearest, nil
+}
+
+// GetLatestObservation fetches a station's most recent observation, normalized into our units
+func (c *NWSAPIClient) GetLatestObservation(ctx context.Context, stationID string) (*models.Observation, error) {
+	obsURL := fmt.Sprintf("%s/stations/%s/observations/latest", c.baseURL, url.PathEscape(stationID))
+
+	resp, err := c.get(ctx, obsURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest observation: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrStationNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, readProblem("NWS observations", resp)
+	}
+
+	var obsData models.NWSObservationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&obsData); err != nil {
+		return nil, fmt.Errorf("failed to decode observation response: %w", err)
+	}
+
+	obs := normalizeObservation(obsData.Properties)
+	return &obs, nil
+}
+
+// normalizeObservation converts NWS quantities into the units used by our API
+func normalizeObservation(p models.NWSObservationProperties) models.Observation {
+	obs := models.Observation{
+		Timestamp:   p.Timestamp,
+		Description: p.TextDescription,
+	}
+
+	obs.TemperatureC, obs.TemperatureF = temperatureQuantity(p.Temperature)
+	obs.DewpointC, obs.DewpointF = temperatureQuantity(p.Dewpoint)
+
+	if p.WindSpeed.Value != nil {
+		from := units.KMH
+		if p.WindSpeed.UnitCode == "wmoUnit:m_s-1" {
+			from = units.MS
+		}
+		kmh := units.ConvertWind(*p.WindSpeed.Value, from, units.KMH)
+		mph := units.ConvertWind(*p.WindSpeed.Value, from, units.MPH)
+		obs.WindSpeedKmh, obs.WindSpeedMph = &kmh, &mph
+	}
+
+	obs.WindDirection = p.WindDirection.Value
+
+	if p.BarometricPressure.Value != nil {
+		hpa := *p.BarometricPressure.Value / 100
+		obs.PressureHPa = &hpa
+	}
+
+	return obs
+}
+
+// temperatureQuantity returns a temperature quantity in Celsius and Fahrenheit
+func temperatureQuantity(q models.NWSQuantity) (*float64, *float64) {
+	if q.Value == nil {
+		return nil, nil
+	}
+
+	c := *q.Value
+	if q.UnitCode == "wmoUnit:degF" {
+		c = units.FahrenheitToCelsius(*q.Value)
+	}
+	f := units.CelsiusToFahrenheit(c)
+	return &c, &f
+}
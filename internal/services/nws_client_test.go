@@ -0,0 +1,672 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+func TestNWSClientSendsUserAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []NWSClientOption
+		want string
+	}{
+		{"default", nil, DefaultNWSUserAgent},
+		{"configured", []NWSClientOption{WithUserAgent("example-app (ops@example.com)")}, "example-app (ops@example.com)"},
+		{"empty keeps default", []NWSClientOption{WithUserAgent("")}, DefaultNWSUserAgent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// api.weather.gov rejects requests without a descriptive User-Agent
+				if ua := r.Header.Get("User-Agent"); ua != tt.want {
+					t.Errorf("%s: User-Agent = %q; want %q", r.URL.Path, ua, tt.want)
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				switch {
+				case strings.HasPrefix(r.URL.Path, "/points/"):
+					if r.URL.Path != "/points/40.7128,-74.006" {
+						t.Errorf("points path = %q; want /points/40.7128,-74.006", r.URL.Path)
+					}
+					fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35, "forecast": "%s/gridpoints/OKX/33,35/forecast"}}`, server.URL)
+				case strings.HasSuffix(r.URL.Path, "/forecast"):
+					fmt.Fprint(w, `{"properties": {"periods": [
+						{"shortForecast": "Sunny", "temperature": 72, "temperatureUnit": "F"}
+					]}}`)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			opts := append([]NWSClientOption{WithBaseURL(server.URL), WithHTTPClient(server.Client())}, tt.opts...)
+			client := NewNWSAPIClient(opts...)
+
+			point, err := client.GetGridPoint(context.Background(), 40.7128, -74.0060)
+			if err != nil {
+				t.Fatalf("GetGridPoint: %v", err)
+			}
+			if _, err := client.GetGridForecast(context.Background(), point.ForecastURL); err != nil {
+				t.Fatalf("GetGridForecast: %v", err)
+			}
+		})
+	}
+}
+
+func TestNWSClientRetries(t *testing.T) {
+	fastRetry := RetryConfig{MaxAttempts: 3, MaxElapsed: time.Second, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+	tests := []struct {
+		name         string
+		statuses     []int // response per attempt; the last repeats
+		retry        RetryConfig
+		wantAttempts int32
+		wantErr      bool
+	}{
+		{"fails twice then succeeds", []int{http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusOK}, fastRetry, 3, false},
+		{"gives up at max attempts", []int{http.StatusServiceUnavailable}, fastRetry, 3, true},
+		{"4xx is not retried", []int{http.StatusNotFound}, fastRetry, 1, true},
+		{"retries disabled", []int{http.StatusServiceUnavailable}, RetryConfig{MaxAttempts: 1}, 1, true},
+		// The first backoff (50-100ms) fits the budget; the second (100-200ms) doesn't
+		{
+			"time budget stops retries", []int{http.StatusServiceUnavailable},
+			RetryConfig{MaxAttempts: 10, MaxElapsed: 140 * time.Millisecond, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second},
+			2, true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(atomic.AddInt32(&attempts, 1))
+				status := tt.statuses[min(n, len(tt.statuses))-1]
+				if status != http.StatusOK {
+					w.WriteHeader(status)
+					return
+				}
+				fmt.Fprint(w, `{"properties": {"periods": [
+					{"shortForecast": "Sunny", "temperature": 72, "temperatureUnit": "F"}
+				]}}`)
+			}))
+			defer server.Close()
+
+			client := NewNWSAPIClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithRetry(tt.retry))
+			_, err := client.GetGridForecast(context.Background(), server.URL+"/gridpoints/OKX/33,35/forecast")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetGridForecast error = %v; wantErr %v", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(&attempts); got != tt.wantAttempts {
+				t.Errorf("attempts = %d; want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestNWSClientRetriesNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	baseURL := server.URL
+	server.Close()
+
+	client := NewNWSAPIClient(WithBaseURL(baseURL), WithRetry(RetryConfig{MaxAttempts: 2, MaxElapsed: time.Second, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}))
+	start := time.Now()
+	if _, err := client.GetGridPoint(context.Background(), 40.7128, -74.0060); err == nil {
+		t.Fatal("GetGridPoint against a closed server succeeded")
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("retries took %v; want them within the time budget", time.Since(start))
+	}
+}
+
+func TestNWSClientRateLimit(t *testing.T) {
+	var mu sync.Mutex
+	var arrivals []time.Time
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		arrivals = append(arrivals, time.Now())
+		mu.Unlock()
+		if strings.HasPrefix(r.URL.Path, "/points/") {
+			fmt.Fprintf(w, `{"properties": {"forecast": "%s/gridpoints/OKX/33,35/forecast"}}`, server.URL)
+			return
+		}
+		fmt.Fprint(w, `{"properties": {"periods": [
+			{"shortForecast": "Sunny", "temperature": 72, "temperatureUnit": "F"}
+		]}}`)
+	}))
+	defer server.Close()
+
+	// 20 requests per second with no burst spaces requests 50ms apart
+	const interval = 50 * time.Millisecond
+	client := NewNWSAPIClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()),
+		WithRateLimit(RateLimitConfig{RequestsPerSecond: 20, Burst: 1, MaxWait: 5 * time.Second}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetForecast(context.Background(), 40.7128, -74.0060); err != nil {
+				t.Errorf("GetForecast: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Each GetForecast makes a points and a forecast request
+	if len(arrivals) != 8 {
+		t.Fatalf("upstream saw %d requests; want 8", len(arrivals))
+	}
+	sort.Slice(arrivals, func(i, j int) bool { return arrivals[i].Before(arrivals[j]) })
+	for i := 1; i < len(arrivals); i++ {
+		// Allow for timer slack between the limiter and the server
+		if gap := arrivals[i].Sub(arrivals[i-1]); gap < interval-10*time.Millisecond {
+			t.Errorf("requests %d and %d arrived %v apart; want about %v", i, i+1, gap, interval)
+		}
+	}
+}
+
+func TestNWSClientRateLimitDeadline(t *testing.T) {
+	server, _ := fakeGridNWS(t, http.StatusOK)
+	client := NewNWSAPIClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()),
+		WithRateLimit(RateLimitConfig{RequestsPerSecond: 1, Burst: 1, MaxWait: 10 * time.Millisecond}))
+
+	if _, err := client.GetGridPoint(context.Background(), 40.7128, -74.0060); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	start := time.Now()
+	_, err := client.GetGridPoint(context.Background(), 40.7128, -74.0060)
+	var limited *RateLimitedError
+	if !errors.As(err, &limited) || !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("second request error = %v; want ErrRateLimited", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("rate-limited request waited %v; want it refused without waiting", elapsed)
+	}
+	if limited.Wait <= 10*time.Millisecond || limited.Wait > time.Second {
+		t.Errorf("Wait = %v; want the time until the next allowed request", limited.Wait)
+	}
+}
+
+func TestFormatPointCoordinate(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{40.7128, "40.7128"},
+		{-74.006, "-74.006"},
+		{-74, "-74"},
+		{40.700000, "40.7"},
+		{40.71284999, "40.7128"},
+		{-74.00605, "-74.0061"},
+		{0, "0"},
+		{-0.00001, "0"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatPointCoordinate(tt.in); got != tt.want {
+			t.Errorf("FormatPointCoordinate(%v) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNWSClientAbortsOnCancel(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int // 0 holds the request open until it is cancelled
+		retry   RetryConfig
+		timeout bool // a deadline rather than an explicit cancel
+		wantErr error
+	}{
+		{"deadline in flight", 0, DefaultRetryConfig(), true, context.DeadlineExceeded},
+		{"cancel in flight", 0, DefaultRetryConfig(), false, context.Canceled},
+		// Without a deadline the 10s backoff is started, then cut short
+		{"cancel during backoff", http.StatusServiceUnavailable, RetryConfig{MaxAttempts: 3, MaxElapsed: time.Minute, BaseDelay: 10 * time.Second, MaxDelay: 10 * time.Second}, false, context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			aborted := make(chan struct{}, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				select {
+				case <-r.Context().Done():
+					aborted <- struct{}{}
+				case <-time.After(5 * time.Second):
+				}
+			}))
+			defer server.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.timeout {
+				ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+			} else {
+				time.AfterFunc(50*time.Millisecond, cancel)
+			}
+			defer cancel()
+			client := NewNWSAPIClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithRetry(tt.retry))
+
+			start := time.Now()
+			_, err := client.GetGridPoint(ctx, 40.7128, -74.0060)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetGridPoint error = %v; want %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("GetGridPoint returned after %v; want it to stop once cancelled", elapsed)
+			}
+			if tt.status == 0 {
+				select {
+				case <-aborted:
+				case <-time.After(time.Second):
+					t.Error("upstream request was not aborted")
+				}
+			}
+			if got := atomic.LoadInt32(&attempts); got != 1 {
+				t.Errorf("attempts = %d; want 1", got)
+			}
+		})
+	}
+}
+
+// nwsFixture serves recorded NWS points and forecast documents, with their
+// api.weather.gov links pointed at the test server. An empty fixture name
+// answers with just the status.
+func nwsFixture(t *testing.T, pointsStatus int, points string, forecastStatus int, forecast string) (*httptest.Server, map[string]*int32) {
+	t.Helper()
+	read := func(fixture string) []byte {
+		if fixture == "" {
+			return nil
+		}
+		body, err := os.ReadFile(filepath.Join("testdata", fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+	pointsBody, forecastBody := read(points), read(forecast)
+
+	hits := map[string]*int32{"points": new(int32), "forecast": new(int32)}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, body := pointsStatus, pointsBody
+		switch {
+		case r.URL.Path == "/points/40.7128,-74.006" || r.URL.Path == "/points/40.5,-72":
+			atomic.AddInt32(hits["points"], 1)
+		case r.URL.Path == "/gridpoints/OKX/33,35/forecast" || r.URL.Path == "/gridpoints/OKX/98,27/forecast":
+			atomic.AddInt32(hits["forecast"], 1)
+			status, body = forecastStatus, forecastBody
+		default:
+			t.Errorf("unexpected request for %s", r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/geo+json")
+		w.WriteHeader(status)
+		w.Write([]byte(strings.ReplaceAll(string(body), "https://api.weather.gov", server.URL)))
+	}))
+	t.Cleanup(server.Close)
+	return server, hits
+}
+
+func TestNWSGetForecast(t *testing.T) {
+	tests := []struct {
+		name           string
+		lat, lon       float64
+		pointsStatus   int
+		points         string
+		forecastStatus int
+		forecast       string
+		wantErr        string
+		wantErrIs      error
+		wantForecasts  int32
+	}{
+		{
+			name: "recorded forecast", lat: 40.7128, lon: -74.006,
+			pointsStatus: http.StatusOK, points: "nws_points.json",
+			forecastStatus: http.StatusOK, forecast: "nws_forecast.json",
+			wantForecasts: 1,
+		},
+		{
+			name: "point outside NWS coverage", lat: 40.7128, lon: -74.006,
+			pointsStatus: http.StatusNotFound, wantErrIs: ErrOutsideCoverage,
+		},
+		{
+			name: "points response without a forecast URL", lat: 40.5, lon: -72,
+			pointsStatus: http.StatusOK, points: "nws_points_no_forecast.json",
+			wantErr: "no forecast URL",
+		},
+		{
+			name: "forecast without periods", lat: 40.7128, lon: -74.006,
+			pointsStatus: http.StatusOK, points: "nws_points.json",
+			forecastStatus: http.StatusOK, forecast: "nws_forecast_empty.json",
+			wantErr: "no forecast periods", wantForecasts: 1,
+		},
+		{
+			name: "forecast endpoint unavailable", lat: 40.7128, lon: -74.006,
+			pointsStatus: http.StatusOK, points: "nws_points.json",
+			forecastStatus: http.StatusServiceUnavailable,
+			wantErr:        "status: 503", wantForecasts: 1,
+		},
+		{
+			name: "forecast URL retired", lat: 40.7128, lon: -74.006,
+			pointsStatus: http.StatusOK, points: "nws_points.json",
+			forecastStatus: http.StatusNotFound,
+			wantErrIs:      ErrForecastNotFound, wantForecasts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, hits := nwsFixture(t, tt.pointsStatus, tt.points, tt.forecastStatus, tt.forecast)
+			client := NewNWSAPIClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithRetry(RetryConfig{MaxAttempts: 1}))
+
+			weather, err := client.GetForecast(context.Background(), tt.lat, tt.lon)
+			switch {
+			case tt.wantErrIs != nil:
+				if !errors.Is(err, tt.wantErrIs) {
+					t.Errorf("GetForecast error = %v; want %v", err, tt.wantErrIs)
+				}
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("GetForecast error = %v; want one mentioning %q", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("GetForecast: %v", err)
+			default:
+				// The first period is today's: 95°F is 35°C
+				if weather.Forecast != "Hot then Slight Chance Showers And Thunderstorms" || weather.TempF != 95 || weather.TempC != 35 {
+					t.Errorf("forecast = %q at %v°C/%v°F; want today's period at 35°C/95°F", weather.Forecast, weather.TempC, weather.TempF)
+				}
+				if want := "A slight chance of showers and thunderstorms after 2pm. Mostly sunny and hot, with a high near 95."; weather.DetailedForecast != want {
+					t.Errorf("detailed forecast = %q; want %q", weather.DetailedForecast, want)
+				}
+				if !reflect.DeepEqual(weather.WindMph, &models.SpeedRange{Min: 5, Max: 10}) || weather.WindDirection != "SW" {
+					t.Errorf("wind = %+v mph from %q; want 5 to 10 mph from SW", weather.WindMph, weather.WindDirection)
+				}
+				if weather.PeriodName != "Today" || weather.IsDaytime == nil || !*weather.IsDaytime {
+					t.Errorf("period = %q, daytime %v; want Today in daytime", weather.PeriodName, weather.IsDaytime)
+				}
+				if want := "/icons/land/day/hot/tsra_hi,20?size=medium"; !strings.HasSuffix(weather.Icon, want) {
+					t.Errorf("icon = %q; want the NWS icon %s", weather.Icon, want)
+				}
+				if weather.PrecipitationProbability == nil || *weather.PrecipitationProbability != 20 {
+					t.Errorf("precipitation probability = %v; want 20", weather.PrecipitationProbability)
+				}
+				if weather.Latitude != tt.lat || weather.Longitude != tt.lon {
+					t.Errorf("coordinate = %v,%v; want %v,%v", weather.Latitude, weather.Longitude, tt.lat, tt.lon)
+				}
+				if weather.Raw == nil || !strings.Contains(string(weather.Raw.Body), "BaselineForecastGenerator") {
+					t.Error("forecast doesn't carry the raw NWS document")
+				}
+			}
+			if got := atomic.LoadInt32(hits["points"]); got != 1 {
+				t.Errorf("points requests = %d; want 1", got)
+			}
+			if got := atomic.LoadInt32(hits["forecast"]); got != tt.wantForecasts {
+				t.Errorf("forecast requests = %d; want %d", got, tt.wantForecasts)
+			}
+		})
+	}
+}
+
+func TestNWSProblemDetails(t *testing.T) {
+	outside, err := os.ReadFile(filepath.Join("testdata", "nws_points_outside_coverage.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		wantCoverage bool
+		wantMsg      string
+	}{
+		{"point outside coverage", http.StatusNotFound, string(outside), true,
+			"NWS points API returned status: 404: Unable to provide data for requested point 40.7128,-74.006"},
+		{"404 without a problem body", http.StatusNotFound, "<html>Not Found</html>", true,
+			"NWS points API returned status: 404"},
+		{"problem with only a title", http.StatusBadRequest, `{"title": "Invalid Parameter", "status": 400}`, false,
+			"NWS points API returned status: 400: Invalid Parameter"},
+		{"server error", http.StatusInternalServerError,
+			`{"title": "Unexpected Problem", "type": "https://api.weather.gov/problems/UnexpectedProblem", "detail": "An unexpected problem has occurred."}`, false,
+			"NWS points API returned status: 500: An unexpected problem has occurred."},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(tt.status)
+			fmt.Fprint(w, tt.body)
+		}))
+		client := NewNWSAPIClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithRetry(RetryConfig{MaxAttempts: 1}))
+		_, err := client.GetGridPoint(context.Background(), 40.7128, -74.006)
+		server.Close()
+
+		var problem *ProblemError
+		if !errors.As(err, &problem) || problem.Status != tt.status {
+			t.Errorf("%s: error = %v; want a ProblemError with status %d", tt.name, err, tt.status)
+			continue
+		}
+		if errors.Is(err, ErrOutsideCoverage) != tt.wantCoverage {
+			t.Errorf("%s: out of coverage = %v; want %v", tt.name, errors.Is(err, ErrOutsideCoverage), tt.wantCoverage)
+		}
+		if problem.Error() != tt.wantMsg {
+			t.Errorf("%s: message = %q; want %q", tt.name, problem.Error(), tt.wantMsg)
+		}
+	}
+}
+
+func TestNWSGetGridPointLocation(t *testing.T) {
+	server, _ := nwsFixture(t, http.StatusOK, "nws_points.json", http.StatusOK, "nws_forecast.json")
+	client := NewNWSAPIClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+
+	point, err := client.GetGridPoint(context.Background(), 40.7128, -74.006)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if point.City != "New York" || point.State != "NY" || point.TimeZone != "America/New_York" {
+		t.Errorf("point in %s, %s (%s); want New York, NY in America/New_York", point.City, point.State, point.TimeZone)
+	}
+}
+
+func TestNWSGetGridPointMetadata(t *testing.T) {
+	server, _ := nwsFixture(t, http.StatusOK, "nws_points.json", http.StatusOK, "nws_forecast.json")
+	client := NewNWSAPIClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+
+	point, err := client.GetGridPoint(context.Background(), 40.7128, -74.006)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Zones are given as URLs and kept as their IDs
+	if point.GridID != "OKX" || point.GridX != 33 || point.GridY != 35 ||
+		point.ForecastZone != "NYZ072" || point.County != "NYC061" || point.RadarStation != "KOKX" {
+		t.Errorf("point = %+v; want OKX 33,35 in NYZ072 and NYC061 near KOKX", point)
+	}
+	if point.ForecastURL != server.URL+"/gridpoints/OKX/33,35/forecast" {
+		t.Errorf("forecast URL = %q", point.ForecastURL)
+	}
+}
+
+func TestNWSGetForecastPeriods(t *testing.T) {
+	server, _ := nwsFixture(t, http.StatusOK, "nws_points.json", http.StatusOK, "nws_forecast.json")
+	client := NewNWSAPIClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+
+	periods, err := client.GetForecastPeriods(context.Background(), server.URL+"/gridpoints/OKX/33,35/forecast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(periods) != 2 {
+		t.Fatalf("got %d periods; want 2", len(periods))
+	}
+	tonight := periods[1]
+	// 77°F is 25°C
+	if tonight.Name != "Tonight" || tonight.IsDaytime || tonight.TempF != 77 || tonight.TempC != 25 ||
+		tonight.WindSpeed != "5 mph" || tonight.PrecipitationProbability == nil || *tonight.PrecipitationProbability != 30 {
+		t.Errorf("second period = %+v; want Tonight at 25°C/77°F with a 30%% chance of rain", tonight)
+	}
+}
+
+func TestNWSGetGridForecastPrecipitation(t *testing.T) {
+	// The NWS often sends a null value, which must stay unknown rather than
+	// read as a 0% chance
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"null", "<nil>"},
+		{"0", "0"},
+		{"80", "80"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"properties": {"periods": [{"name": "Today", "shortForecast": "Rain", "temperature": 50,
+					"temperatureUnit": "F", "probabilityOfPrecipitation": {"unitCode": "wmoUnit:percent", "value": %s}}]}}`, tt.value)
+			}))
+			t.Cleanup(server.Close)
+			client := NewNWSAPIClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+
+			weather, err := client.GetGridForecast(context.Background(), server.URL+"/gridpoints/OKX/33,35/forecast")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := "<nil>"
+			if weather.PrecipitationProbability != nil {
+				got = fmt.Sprint(*weather.PrecipitationProbability)
+			}
+			if got != tt.want {
+				t.Errorf("precipitation probability = %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNWSGetGridForecastHumidity(t *testing.T) {
+	tests := []struct {
+		fixture                        string
+		humidity, dewpointC, dewpointF string // <nil> when absent
+	}{
+		// The NWS reports dewpoints in wmoUnit:degC even for US-unit forecasts
+		{"nws_forecast.json", "48.00", "22.22", "72.00"},
+		{"nws_forecast_null_humidity.json", "<nil>", "<nil>", "<nil>"},
+	}
+
+	format := func(v *float64) string {
+		if v == nil {
+			return "<nil>"
+		}
+		return fmt.Sprintf("%.2f", *v)
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			server, _ := nwsFixture(t, http.StatusOK, "nws_points.json", http.StatusOK, tt.fixture)
+			client := NewNWSAPIClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+
+			weather, err := client.GetGridForecast(context.Background(), server.URL+"/gridpoints/OKX/33,35/forecast")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := format(weather.RelativeHumidity); got != tt.humidity {
+				t.Errorf("relative humidity = %s; want %s", got, tt.humidity)
+			}
+			if c, f := format(weather.DewpointC), format(weather.DewpointF); c != tt.dewpointC || f != tt.dewpointF {
+				t.Errorf("dewpoint = %s°C/%s°F; want %s°C/%s°F", c, f, tt.dewpointC, tt.dewpointF)
+			}
+		})
+	}
+}
+
+func TestNWSGetGridForecastTemperatureTrend(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    string
+	}{
+		// Most periods have a null trend
+		{"nws_forecast.json", ""},
+		{"nws_forecast_null_humidity.json", "rising"},
+	}
+	for _, tt := range tests {
+		server, _ := nwsFixture(t, http.StatusOK, "nws_points.json", http.StatusOK, tt.fixture)
+		client := NewNWSAPIClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+
+		weather, err := client.GetGridForecast(context.Background(), server.URL+"/gridpoints/OKX/33,35/forecast")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if weather.TemperatureTrend != tt.want {
+			t.Errorf("%s: temperature trend = %q; want %q", tt.fixture, weather.TemperatureTrend, tt.want)
+		}
+	}
+}
+
+func TestNormalizeWind(t *testing.T) {
+	tests := []struct {
+		speed    string
+		kmh, mph *models.SpeedRange
+	}{
+		{"5 to 10 mph", &models.SpeedRange{Min: 8.04672, Max: 16.09344}, &models.SpeedRange{Min: 5, Max: 10}},
+		{"10 mph", &models.SpeedRange{Min: 16.09344, Max: 16.09344}, &models.SpeedRange{Min: 10, Max: 10}},
+		{"0 mph", &models.SpeedRange{}, &models.SpeedRange{}},
+		{"15 to 25 km/h", &models.SpeedRange{Min: 15, Max: 25}, &models.SpeedRange{Min: 9.32057, Max: 15.53428}},
+		{"", nil, nil},
+		{"Calm", nil, nil},
+		{"10 mph gusting to 25", nil, nil},
+	}
+
+	near := func(got, want *models.SpeedRange) bool {
+		if got == nil || want == nil {
+			return got == want
+		}
+		return math.Abs(got.Min-want.Min) < 1e-4 && math.Abs(got.Max-want.Max) < 1e-4
+	}
+	for _, tt := range tests {
+		kmh, mph := normalizeWind(tt.speed)
+		if !near(kmh, tt.kmh) || !near(mph, tt.mph) {
+			t.Errorf("normalizeWind(%q) = %+v km/h, %+v mph; want %+v km/h, %+v mph", tt.speed, kmh, mph, tt.kmh, tt.mph)
+		}
+	}
+}
+
+func TestNormalizeTemperature(t *testing.T) {
+	tests := []struct {
+		value        float64
+		unit         string
+		wantC, wantF float64
+	}{
+		{95, "F", 35, 95},
+		{32, "F", 0, 32},
+		{-40, "F", -40, -40},
+		{20, "C", 20, 68},
+		{-10, "C", -10, 14},
+	}
+
+	for _, tt := range tests {
+		c, f := normalizeTemperature(tt.value, tt.unit)
+		if math.Abs(c-tt.wantC) > 1e-9 || math.Abs(f-tt.wantF) > 1e-9 {
+			t.Errorf("normalizeTemperature(%v, %q) = %v°C/%v°F; want %v°C/%v°F", tt.value, tt.unit, c, f, tt.wantC, tt.wantF)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"weather-api-go/internal/metrics"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+const (
+	// DefaultObservationHours is the history window used when none is requested
+	DefaultObservationHours = 24
+	// MaxObservationHours caps the history window to keep payloads bounded
+	MaxObservationHours = 72
+)
+
+// NWS station identifiers are 3-5 uppercase alphanumerics (e.g. KNYC, PAFA, D5520)
+var stationIDPattern = regexp.MustCompile(`^[A-Z0-9]{3,5}$`)
+
+// NormalizeStationID uppercases and validates an NWS station identifier
+func NormalizeStationID(id string) (string, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(id))
+	if !stationIDPattern.MatchString(normalized) {
+		return "", fmt.Errorf("invalid station ID %q", id)
+	}
+	return normalized, nil
+}
+
+// ClampObservationHours limits a requested history window to MaxObservationHours
+func ClampObservationHours(hours int) int {
+	if hours > MaxObservationHours {
+		return MaxObservationHours
+	}
+	return hours
+}
+
+// GetStationObservations retrieves the recent observation series for a station with brief caching
+func (s *WeatherService) GetStationObservations(ctx context.Context, stationID string, hours int) (*models.ObservationHistoryResponse, error) {
+	if err := s.requireNWS(); err != nil {
+		return nil, err
+	}
+	stationID, err := NormalizeStationID(stationID)
+	if err != nil {
+		return nil, err
+	}
+	hours = ClampObservationHours(hours)
+
+	cached, err := s.repo.GetObservations(ctx, stationID, hours)
+	if err == nil && s.repo.IsObservationCacheFresh(cached) {
+		resp := observationResponse(cached)
+		resp.FreshUntil = cached.Timestamp.Add(repository.ObservationCacheTTL)
+		resp.CacheHit = true
+		return resp, nil
+	}
+
+	end := time.Now()
+	start := end.Add(-time.Duration(hours) * time.Hour)
+	observations, err := s.nwsClient.GetStationObservations(ctx, stationID, start, end)
+	if err != nil {
+		if cached != nil && !errors.Is(err, ErrStationNotFound) {
+			return observationResponse(cached), nil
+		}
+		return nil, err
+	}
+
+	fresh := &models.ObservationCache{
+		StationID:    stationID,
+		Hours:        hours,
+		Observations: observations,
+		Timestamp:    end,
+	}
+
+	// Save to cache (ignore errors, don't fail the request)
+	_ = s.repo.SaveObservations(ctx, fresh)
+
+	resp := observationResponse(fresh)
+	resp.FreshUntil = end.Add(repository.ObservationCacheTTL)
+	return resp, nil
+}
+
+func observationResponse(cache *models.ObservationCache) *models.ObservationHistoryResponse {
+	return &models.ObservationHistoryResponse{
+		StationID:    cache.StationID,
+		Hours:        cache.Hours,
+		Observations: cache.Observations,
+	}
+}
+
+// observationFallbackWindow is how far back GetCurrentConditions looks for an
+// observation with a temperature when the latest one has none
+const observationFallbackWindow = 3 * time.Hour
+
+// GetCurrentConditions retrieves the latest observation from the station nearest
+// a coordinate. The station is resolved once per coordinate and cached; the
+// observation is cached per station for ObservationCacheTTL.
+func (s *WeatherService) GetCurrentConditions(ctx context.Context, lat, lon float64) (*models.CurrentConditionsResponse, error) {
+	conditions, err := s.getCurrentConditions(ctx, lat, lon)
+	if errors.Is(err, ErrUpstreamSaturated) && s.metrics != nil {
+		s.metrics.Inc(metrics.RequestsShed)
+	}
+	return conditions, err
+}
+
+func (s *WeatherService) getCurrentConditions(ctx context.Context, lat, lon float64) (*models.CurrentConditionsResponse, error) {
+	stationID, err := s.resolveNearestStation(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	resp := &models.CurrentConditionsResponse{Latitude: lat, Longitude: lon, StationID: stationID}
+
+	cached, err := s.repo.GetObservations(ctx, stationID, repository.LatestObservation)
+	if err != nil || len(cached.Observations) == 0 {
+		cached = nil
+	}
+	if cached != nil && s.repo.IsObservationCacheFresh(cached) {
+		resp.Observation = cached.Observations[0]
+		resp.FreshUntil = cached.Timestamp.Add(repository.ObservationCacheTTL)
+		resp.CacheHit = true
+		return resp, nil
+	}
+
+	var obs *models.Observation
+	err = s.upstream(func() (err error) {
+		obs, err = s.latestObservation(ctx, stationID)
+		return err
+	})
+	if err != nil {
+		if cached != nil && !errors.Is(err, ErrStationNotFound) {
+			resp.Observation = cached.Observations[0]
+			return resp, nil
+		}
+		return nil, err
+	}
+
+	fresh := &models.ObservationCache{
+		StationID:    stationID,
+		Hours:        repository.LatestObservation,
+		Observations: []models.Observation{*obs},
+		Timestamp:    time.Now(),
+	}
+	_ = s.repo.SaveObservations(ctx, fresh)
+
+	resp.Observation = *obs
+	resp.FreshUntil = fresh.Timestamp.Add(repository.ObservationCacheTTL)
+	return resp, nil
+}
+
+// latestObservation returns a station's latest observation. Some stations
+// report observations without a temperature; then the most recent one within
+// observationFallbackWindow that has one is returned instead, if any.
+func (s *WeatherService) latestObservation(ctx context.Context, stationID string) (*models.Observation, error) {
+	latest, err := s.nwsClient.GetLatestObservation(ctx, stationID)
+	if err != nil || latest.TemperatureC != nil {
+		return latest, err
+	}
+
+	end := time.Now()
+	recent, err := s.nwsClient.GetStationObservations(ctx, stationID, end.Add(-observationFallbackWindow), end)
+	if err != nil {
+		return latest, nil
+	}
+	for i := len(recent) - 1; i >= 0; i-- {
+		if recent[i].TemperatureC != nil {
+			return &recent[i], nil
+		}
+	}
+	return latest, nil
+}
+
+// resolveNearestStation maps a coordinate to its nearest observation station,
+// caching the mapping under the normalized coordinate
+func (s *WeatherService) resolveNearestStation(ctx context.Context, lat, lon float64) (string, error) {
+	if err := s.requireNWS(); err != nil {
+		return "", err
+	}
+	if err := s.checkCoverage(lat, lon); err != nil {
+		return "", err
+	}
+	lat, lon = normalizePointCoordinate(lat), normalizePointCoordinate(lon)
+
+	cached, err := s.repo.GetNearestStation(ctx, lat, lon)
+	if err == nil && s.repo.IsNearestStationFresh(cached) {
+		return cached.StationID, nil
+	}
+	if err := s.checkUncovered(ctx, lat, lon); err != nil {
+		return "", err
+	}
+
+	var stationID string
+	err = s.upstream(func() (err error) {
+		stationID, err = s.nwsClient.GetNearestStation(ctx, lat, lon)
+		return err
+	})
+	if err != nil {
+		s.recordCoverage(ctx, lat, lon, err)
+		// A station that was nearest recently beats failing
+		if cached != nil && !errors.Is(err, ErrNoObservationStation) {
+			return cached.StationID, nil
+		}
+		return "", err
+	}
+
+	_ = s.repo.SaveNearestStation(ctx, &models.NearestStation{
+		Latitude:  lat,
+		Longitude: lon,
+		StationID: stationID,
+		Timestamp: time.Now(),
+	})
+	return stationID, nil
+}
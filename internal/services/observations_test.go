@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const observationsFixture = `{
+  "features": [
+    {"properties": {
+      "timestamp": "2024-01-15T11:00:00+00:00",
+      "textDescription": "Cloudy",
+      "temperature": {"unitCode": "wmoUnit:degC", "value": 10.0},
+      "dewpoint": {"unitCode": "wmoUnit:degC", "value": null},
+      "windSpeed": {"unitCode": "wmoUnit:km_h-1", "value": 16.09},
+      "windDirection": {"unitCode": "wmoUnit:degree_(angle)", "value": 270},
+      "barometricPressure": {"unitCode": "wmoUnit:Pa", "value": 101630}
+    }},
+    {"properties": {
+      "timestamp": "2024-01-15T10:00:00+00:00",
+      "textDescription": "Clear",
+      "temperature": {"unitCode": "wmoUnit:degC", "value": null},
+      "dewpoint": {"unitCode": "wmoUnit:degC", "value": 2.5},
+      "windSpeed": {"unitCode": "wmoUnit:m_s-1", "value": 5},
+      "windDirection": {"unitCode": "wmoUnit:degree_(angle)", "value": null},
+      "barometricPressure": {"unitCode": "wmoUnit:Pa", "value": null}
+    }}
+  ]
+}`
+
+func newTestNWSClient(server *httptest.Server) *NWSAPIClient {
+	return NewNWSAPIClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+}
+
+func TestGetStationObservations(t *testing.T) {
+	var gotPath, gotStart string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotStart = r.URL.Query().Get("start")
+		w.Write([]byte(observationsFixture))
+	}))
+	defer server.Close()
+
+	start := time.Date(2024, 1, 14, 12, 0, 0, 0, time.UTC)
+	obs, err := newTestNWSClient(server).GetStationObservations(context.Background(), "KNYC", start, start.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetStationObservations returned error: %v", err)
+	}
+
+	if gotPath != "/stations/KNYC/observations" {
+		t.Errorf("path = %s; want /stations/KNYC/observations", gotPath)
+	}
+	if gotStart != "2024-01-14T12:00:00Z" {
+		t.Errorf("start = %s; want 2024-01-14T12:00:00Z", gotStart)
+	}
+
+	if len(obs) != 2 {
+		t.Fatalf("got %d observations; want 2", len(obs))
+	}
+	if !obs[0].Timestamp.Before(obs[1].Timestamp) {
+		t.Error("observations are not ordered oldest first")
+	}
+
+	older, newer := obs[0], obs[1]
+	if older.TemperatureC != nil {
+		t.Errorf("null temperature decoded as %v; want nil", *older.TemperatureC)
+	}
+	if older.DewpointF == nil || math.Abs(*older.DewpointF-36.5) > 0.01 {
+		t.Errorf("dewpoint_f = %v; want 36.5", older.DewpointF)
+	}
+	if older.WindSpeedKmh == nil || math.Abs(*older.WindSpeedKmh-18) > 0.01 {
+		t.Errorf("m/s wind converted to %v km/h; want 18", older.WindSpeedKmh)
+	}
+	if newer.TemperatureF == nil || math.Abs(*newer.TemperatureF-50) > 0.01 {
+		t.Errorf("temperature_f = %v; want 50", newer.TemperatureF)
+	}
+	if newer.WindSpeedMph == nil || math.Abs(*newer.WindSpeedMph-10) > 0.01 {
+		t.Errorf("wind_speed_mph = %v; want 10", newer.WindSpeedMph)
+	}
+	if newer.PressureHPa == nil || math.Abs(*newer.PressureHPa-1016.3) > 0.01 {
+		t.Errorf("pressure_hpa = %v; want 1016.3", newer.PressureHPa)
+	}
+}
+
+func TestGetStationObservationsNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	_, err := newTestNWSClient(server).GetStationObservations(context.Background(), "ZZZZ", time.Now().Add(-time.Hour), time.Now())
+	if !errors.Is(err, ErrStationNotFound) {
+		t.Errorf("error = %v; want ErrStationNotFound", err)
+	}
+}
+
+func TestNormalizeStationID(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"KNYC", "KNYC", false},
+		{"knyc", "KNYC", false},
+		{" PAFA ", "PAFA", false},
+		{"D5520", "D5520", false},
+		{"KN", "", true},
+		{"KNYC12", "", true},
+		{"KN-Y", "", true},
+		{"../x", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := NormalizeStationID(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeStationID(%q) error = %v; wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("NormalizeStationID(%q) = %q; want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestClampObservationHours(t *testing.T) {
+	if got := ClampObservationHours(24); got != 24 {
+		t.Errorf("ClampObservationHours(24) = %d; want 24", got)
+	}
+	if got := ClampObservationHours(500); got != MaxObservationHours {
+		t.Errorf("ClampObservationHours(500) = %d; want %d", got, MaxObservationHours)
+	}
+}
+
+func TestGetCurrentConditions(t *testing.T) {
+	obs := func(ts string, tempC string) string {
+		return fmt.Sprintf(`{"properties": {"timestamp": %q, "textDescription": "Clear",
+			"temperature": {"unitCode": "wmoUnit:degC", "value": %s},
+			"dewpoint": {"unitCode": "wmoUnit:degC", "value": 1.5},
+			"windSpeed": {"unitCode": "wmoUnit:km_h-1", "value": 9},
+			"windDirection": {"unitCode": "wmoUnit:degree_(angle)", "value": 180},
+			"barometricPressure": {"unitCode": "wmoUnit:Pa", "value": 101500}}}`, ts, tempC)
+	}
+	now := time.Now().UTC().Truncate(time.Hour)
+	latest, previous := now.Format(time.RFC3339), now.Add(-time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name        string
+		latestTemp  string
+		stations    string
+		wantStation string
+		wantTime    string
+		wantErr     error
+	}{
+		// KJFK is listed first but KNYC is nearer to the coordinate
+		{"latest", "12.5", `[
+			{"geometry": {"coordinates": [-73.7639, 40.6392]}, "properties": {"stationIdentifier": "KJFK"}},
+			{"geometry": {"coordinates": [-73.9692, 40.7789]}, "properties": {"stationIdentifier": "KNYC"}}
+		]`, "KNYC", latest, nil},
+		{"null temperature falls back", "null", `[
+			{"geometry": {"coordinates": [-73.9692, 40.7789]}, "properties": {"stationIdentifier": "KNYC"}}
+		]`, "KNYC", previous, nil},
+		{"no stations", "12.5", `[]`, "", "", ErrNoObservationStation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				switch {
+				case strings.HasPrefix(r.URL.Path, "/points/"):
+					fmt.Fprintf(w, `{"properties": {"observationStations": "%s/gridpoints/OKX/33,35/stations"}}`, server.URL)
+				case r.URL.Path == "/gridpoints/OKX/33,35/stations":
+					fmt.Fprintf(w, `{"features": %s}`, tt.stations)
+				case r.URL.Path == "/stations/"+tt.wantStation+"/observations/latest":
+					fmt.Fprint(w, obs(latest, tt.latestTemp))
+				case r.URL.Path == "/stations/"+tt.wantStation+"/observations":
+					fmt.Fprintf(w, `{"features": [%s, %s]}`, obs(latest, tt.latestTemp), obs(previous, "11"))
+				default:
+					t.Errorf("unexpected request %s", r.URL.Path)
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+			service := NewWeatherService(newTestRepo(t), newTestNWSClient(server))
+
+			resp, err := service.GetCurrentConditions(context.Background(), 40.7128, -74.0060)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v; want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StationID != tt.wantStation || resp.Observation.Timestamp.Format(time.RFC3339) != tt.wantTime {
+				t.Errorf("station %s observed at %v; want %s at %s", resp.StationID, resp.Observation.Timestamp, tt.wantStation, tt.wantTime)
+			}
+			if resp.Observation.TemperatureC == nil || resp.Observation.PressureHPa == nil || *resp.Observation.PressureHPa != 1015 {
+				t.Errorf("observation = %+v; want a temperature and 1015 hPa", resp.Observation)
+			}
+
+			// Both the station and its observation are cached
+			before := atomic.LoadInt32(&calls)
+			resp, err = service.GetCurrentConditions(context.Background(), 40.71281, -74.00601)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := atomic.LoadInt32(&calls) - before; n != 0 || !resp.CacheHit {
+				t.Errorf("repeat lookup made %d NWS calls (cache hit %v); want none", n, resp.CacheHit)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/tracing"
+	"weather-api-go/internal/units"
+)
+
+// DefaultOpenMeteoURL is the public Open-Meteo forecast API, free for
+// non-commercial use without an API key
+const DefaultOpenMeteoURL = "https://api.open-meteo.com"
+
+// OpenMeteoConfig configures an OpenMeteoClient
+type OpenMeteoConfig struct {
+	// BaseURL is the Open-Meteo host; empty uses DefaultOpenMeteoURL
+	BaseURL string
+	// HTTPClient replaces the default client, such as for tests
+	HTTPClient *http.Client
+}
+
+// OpenMeteoClient is a WeatherProvider backed by the Open-Meteo forecast API.
+// It covers the whole globe, so it serves coordinates outside NWS coverage.
+type OpenMeteoClient struct {
+	cfg OpenMeteoConfig
+}
+
+// NewOpenMeteoClient creates an Open-Meteo client
+func NewOpenMeteoClient(cfg OpenMeteoConfig) *OpenMeteoClient {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultOpenMeteoURL
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: DefaultUpstreamTimeout}
+	}
+	return &OpenMeteoClient{cfg: cfg}
+}
+
+// Name identifies Open-Meteo in responses and cache entries
+func (c *OpenMeteoClient) Name() string {
+	return ProviderOpenMeteo
+}
+
+// SupportsLocation reports whether a coordinate is on the globe; Open-Meteo
+// forecasts everywhere
+func (c *OpenMeteoClient) SupportsLocation(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+// openMeteoResponse is the part of an Open-Meteo forecast response we read
+type openMeteoResponse struct {
+	Current struct {
+		Temperature float64 `json:"temperature_2m"`
+		WeatherCode int     `json:"weather_code"`
+	} `json:"current"`
+}
+
+// GetForecast fetches the current conditions forecast for a coordinate
+func (c *OpenMeteoClient) GetForecast(ctx context.Context, lat, lon float64) (_ *models.WeatherCache, err error) {
+	ctx, span := tracer.Start(ctx, "open_meteo.forecast", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(tracing.Coordinate(lat, lon)...))
+	defer func() { tracing.End(span, err) }()
+
+	params := url.Values{
+		"latitude":         {strconv.FormatFloat(lat, 'f', -1, 64)},
+		"longitude":        {strconv.FormatFloat(lon, 'f', -1, 64)},
+		"current":          {"temperature_2m,weather_code"},
+		"temperature_unit": {"celsius"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.BaseURL+"/v1/forecast?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Open-Meteo forecast: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, readProblem("Open-Meteo", resp)
+	}
+
+	var data openMeteoResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, MaxDocumentBytes)).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode Open-Meteo forecast: %w", err)
+	}
+
+	tempC := data.Current.Temperature
+	return &models.WeatherCache{
+		Forecast:  WeatherCodeForecast(data.Current.WeatherCode),
+		TempC:     tempC,
+		TempF:     units.CelsiusToFahrenheit(tempC),
+		Timestamp: time.Now(),
+	}, nil
+}
+
+// weatherCodeForecasts maps WMO weather interpretation codes, as reported by
+// Open-Meteo, to short forecasts worded like the NWS's
+var weatherCodeForecasts = map[int]string{
+	0:  "Clear",
+	1:  "Mostly Clear",
+	2:  "Partly Cloudy",
+	3:  "Cloudy",
+	45: "Fog",
+	48: "Freezing Fog",
+	51: "Light Drizzle",
+	53: "Drizzle",
+	55: "Heavy Drizzle",
+	56: "Light Freezing Drizzle",
+	57: "Freezing Drizzle",
+	61: "Light Rain",
+	63: "Rain",
+	65: "Heavy Rain",
+	66: "Light Freezing Rain",
+	67: "Freezing Rain",
+	71: "Light Snow",
+	73: "Snow",
+	75: "Heavy Snow",
+	77: "Snow Grains",
+	80: "Light Rain Showers",
+	81: "Rain Showers",
+	82: "Heavy Rain Showers",
+	85: "Light Snow Showers",
+	86: "Heavy Snow Showers",
+	95: "Thunderstorms",
+	96: "Thunderstorms With Hail",
+	99: "Thunderstorms With Heavy Hail",
+}
+
+// WeatherCodeForecast returns the short forecast for a WMO weather code, or
+// "Unknown" for a code outside the WMO table
+func WeatherCodeForecast(code int) string {
+	if forecast, ok := weatherCodeForecasts[code]; ok {
+		return forecast
+	}
+	return "Unknown"
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenMeteoGetForecast(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/v1/forecast" || q.Get("latitude") != "51.5074" || q.Get("longitude") != "-0.1278" {
+			t.Errorf("request = %s; want /v1/forecast for 51.5074,-0.1278", r.URL)
+		}
+		if q.Get("current") != "temperature_2m,weather_code" {
+			t.Errorf("current = %q; want temperature_2m,weather_code", q.Get("current"))
+		}
+		fmt.Fprint(w, `{"latitude": 51.5, "longitude": -0.12, "current_units": {"temperature_2m": "°C"},
+			"current": {"time": "2024-01-15T10:00", "interval": 900, "temperature_2m": 10, "weather_code": 61}}`)
+	}))
+	defer server.Close()
+
+	client := NewOpenMeteoClient(OpenMeteoConfig{BaseURL: server.URL, HTTPClient: server.Client()})
+	weather, err := client.GetForecast(context.Background(), 51.5074, -0.1278)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weather.Forecast != "Light Rain" || weather.TempC != 10 || weather.TempF != 50 {
+		t.Errorf("forecast = %q at %v°C/%v°F; want Light Rain at 10°C/50°F", weather.Forecast, weather.TempC, weather.TempF)
+	}
+	if weather.Timestamp.IsZero() {
+		t.Error("Timestamp not set")
+	}
+}
+
+func TestOpenMeteoGetForecastError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": true, "reason": "Latitude must be in range of -90 to 90°."}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := NewOpenMeteoClient(OpenMeteoConfig{BaseURL: server.URL, HTTPClient: server.Client()})
+	if _, err := client.GetForecast(context.Background(), 51.5074, -0.1278); err == nil {
+		t.Error("GetForecast against a 400 succeeded")
+	}
+}
+
+func TestWeatherCodeForecast(t *testing.T) {
+	tests := []struct {
+		code int
+		want string
+	}{
+		{0, "Clear"},
+		{2, "Partly Cloudy"},
+		{45, "Fog"},
+		{63, "Rain"},
+		{75, "Heavy Snow"},
+		{95, "Thunderstorms"},
+		{42, "Unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := WeatherCodeForecast(tt.code); got != tt.want {
+			t.Errorf("WeatherCodeForecast(%d) = %q; want %q", tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/tracing"
+	"weather-api-go/internal/units"
+)
+
+// DefaultOWMURL is the OpenWeatherMap API host
+const DefaultOWMURL = "https://api.openweathermap.org"
+
+// OWMConfig configures an OWMClient
+type OWMConfig struct {
+	// APIKey is the OpenWeatherMap API key sent as appid; required
+	APIKey string
+	// BaseURL is the OpenWeatherMap host; empty uses DefaultOWMURL
+	BaseURL string
+	// HTTPClient replaces the default client, such as for tests
+	HTTPClient *http.Client
+	// Retry bounds retries of transient failures; zero uses DefaultRetryConfig
+	Retry RetryConfig
+}
+
+// OWMClient is a WeatherProvider backed by the OpenWeatherMap current weather API
+type OWMClient struct {
+	cfg OWMConfig
+}
+
+// NewOWMClient creates an OpenWeatherMap client
+func NewOWMClient(cfg OWMConfig) *OWMClient {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultOWMURL
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: DefaultUpstreamTimeout}
+	}
+	if cfg.Retry.MaxAttempts < 1 {
+		cfg.Retry = DefaultRetryConfig()
+	}
+	return &OWMClient{cfg: cfg}
+}
+
+// Name identifies OpenWeatherMap in responses and cache entries
+func (c *OWMClient) Name() string {
+	return ProviderOWM
+}
+
+// SupportsLocation reports whether a coordinate is on the globe;
+// OpenWeatherMap covers everywhere
+func (c *OWMClient) SupportsLocation(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+// owmResponse is the part of an OpenWeatherMap current weather response we read
+type owmResponse struct {
+	Weather []struct {
+		Main        string `json:"main"`
+		Description string `json:"description"`
+	} `json:"weather"`
+	Main struct {
+		// Temp is in Kelvin, the API's default units
+		Temp float64 `json:"temp"`
+	} `json:"main"`
+	Name string `json:"name"`
+}
+
+// GetForecast fetches the current weather for a coordinate. Transient
+// failures are retried; a used-up quota fails with ErrQuotaExceeded.
+func (c *OWMClient) GetForecast(ctx context.Context, lat, lon float64) (_ *models.WeatherCache, err error) {
+	ctx, span := tracer.Start(ctx, "owm.weather", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(tracing.Coordinate(lat, lon)...))
+	defer func() { tracing.End(span, err) }()
+
+	params := url.Values{
+		"lat":   {strconv.FormatFloat(lat, 'f', -1, 64)},
+		"lon":   {strconv.FormatFloat(lon, 'f', -1, 64)},
+		"appid": {c.cfg.APIKey},
+	}
+	weatherURL := c.cfg.BaseURL + "/data/2.5/weather?" + params.Encode()
+	resp, err := retryRequest(ctx, c.cfg.Retry, "OpenWeatherMap",
+		func() (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, weatherURL, nil)
+			if err != nil {
+				return nil, err
+			}
+			otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+			return req, nil
+		},
+		c.cfg.HTTPClient.Do,
+	)
+	if err != nil {
+		// The request URL carries the API key, so report only the cause
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to fetch OpenWeatherMap weather: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		return nil, fmt.Errorf("%w: OpenWeatherMap returned status: %d", ErrQuotaExceeded, resp.StatusCode)
+	case http.StatusUnauthorized:
+		return nil, fmt.Errorf("%w: OpenWeatherMap returned status: %d", ErrInvalidAPIKey, resp.StatusCode)
+	default:
+		return nil, readProblem("OpenWeatherMap", resp)
+	}
+
+	var data owmResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, MaxDocumentBytes)).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode OpenWeatherMap weather: %w", err)
+	}
+	if len(data.Weather) == 0 {
+		return nil, fmt.Errorf("no weather condition in OpenWeatherMap response")
+	}
+
+	tempC := units.KelvinToCelsius(data.Main.Temp)
+	return &models.WeatherCache{
+		Forecast:  owmForecast(data.Weather[0].Description, data.Weather[0].Main),
+		TempC:     tempC,
+		TempF:     units.CelsiusToFahrenheit(tempC),
+		Timestamp: time.Now(),
+		City:      data.Name,
+	}, nil
+}
+
+// owmForecast turns an OpenWeatherMap condition description, such as "light
+// rain", into a title-cased short forecast like the NWS's, falling back to the
+// condition group when the description is empty
+func owmForecast(description, group string) string {
+	if strings.TrimSpace(description) == "" {
+		return group
+	}
+	words := strings.Fields(description)
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// owmFixture serves a recorded OpenWeatherMap response with the given status
+func owmFixture(t *testing.T, status int, fixture string) (*httptest.Server, *int32) {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		q := r.URL.Query()
+		if r.URL.Path != "/data/2.5/weather" || q.Get("lat") == "" || q.Get("lon") == "" || q.Get("appid") != "test-key" {
+			t.Errorf("request = %s; want /data/2.5/weather for a coordinate with the API key", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func newTestOWMClient(server *httptest.Server) *OWMClient {
+	return NewOWMClient(OWMConfig{
+		APIKey:     "test-key",
+		BaseURL:    server.URL,
+		HTTPClient: server.Client(),
+		Retry:      RetryConfig{MaxAttempts: 3, MaxElapsed: time.Second, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+	})
+}
+
+func TestOWMGetForecast(t *testing.T) {
+	server, _ := owmFixture(t, http.StatusOK, "owm_weather.json")
+
+	weather, err := newTestOWMClient(server).GetForecast(context.Background(), 51.5074, -0.1278)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 283.15 K is 10°C
+	if weather.Forecast != "Light Rain" || math.Abs(weather.TempC-10) > 1e-9 || math.Abs(weather.TempF-50) > 1e-9 {
+		t.Errorf("forecast = %q at %v°C/%v°F; want Light Rain at 10°C/50°F", weather.Forecast, weather.TempC, weather.TempF)
+	}
+	if weather.City != "London" {
+		t.Errorf("City = %q; want London", weather.City)
+	}
+}
+
+func TestOWMGetForecastErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		fixture      string
+		wantErr      error
+		wantRequests int32
+	}{
+		{"quota", http.StatusTooManyRequests, "owm_quota.json", ErrQuotaExceeded, 1},
+		{"invalid key", http.StatusUnauthorized, "owm_invalid_key.json", ErrInvalidAPIKey, 1},
+		// 5xx responses are retried like NWS ones
+		{"unavailable", http.StatusServiceUnavailable, "owm_quota.json", nil, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := owmFixture(t, tt.status, tt.fixture)
+			_, err := newTestOWMClient(server).GetForecast(context.Background(), 51.5074, -0.1278)
+			if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("GetForecast error = %v; want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && errors.Is(err, ErrQuotaExceeded) {
+				t.Errorf("GetForecast error = %v; want it distinct from ErrQuotaExceeded", err)
+			}
+			if got := atomic.LoadInt32(requests); got != tt.wantRequests {
+				t.Errorf("requests = %d; want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestOWMErrorsOmitAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	baseURL := server.URL
+	server.Close()
+
+	client := NewOWMClient(OWMConfig{APIKey: "secret-key", BaseURL: baseURL, Retry: RetryConfig{MaxAttempts: 1}})
+	_, err := client.GetForecast(context.Background(), 51.5074, -0.1278)
+	if err == nil {
+		t.Fatal("GetForecast against a closed server succeeded")
+	}
+	if strings.Contains(err.Error(), "secret-key") {
+		t.Errorf("error %q leaks the API key", err)
+	}
+}
+
+func TestGetWeatherServesCacheWhenQuotaExceeded(t *testing.T) {
+	server, _ := owmFixture(t, http.StatusTooManyRequests, "owm_quota.json")
+	repo := newTestRepo(t)
+	service := NewWeatherService(repo, newTestOWMClient(server))
+
+	stale := &models.WeatherCache{Latitude: 51.5074, Longitude: -0.1278, Forecast: "Overcast Clouds", TempC: 9, TempF: 48.2,
+		Timestamp: time.Now().Add(-DefaultMaxStale - time.Hour), Provider: ProviderOWM}
+	if err := repo.SaveToCache(context.Background(), stale); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := service.GetWeather(context.Background(), 51.5074, -0.1278)
+	if err != nil {
+		t.Fatalf("GetWeather over quota with a cached entry: %v", err)
+	}
+	if resp.Forecast != "Overcast Clouds" || resp.Source != SourceStale || resp.Provider != ProviderOWM {
+		t.Errorf("Forecast = %q from %s (%s); want the cached owm entry marked stale", resp.Forecast, resp.Provider, resp.Source)
+	}
+
+	if _, err := service.GetWeather(context.Background(), 48.8566, 2.3522); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("uncached GetWeather over quota: error = %v; want ErrQuotaExceeded", err)
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+// ErrGeocodingDisabled is returned for place lookups when no geocoder is configured
+var ErrGeocodingDisabled = errors.New("place lookup is not configured")
+
+// ErrPlaceNotFound is returned when no place matches a location query
+var ErrPlaceNotFound = errors.New("no place matches the query")
+
+// ErrInvalidZIP is returned for a ZIP code that isn't five digits or ZIP+4
+var ErrInvalidZIP = errors.New("ZIP code must be five digits or ZIP+4")
+
+// ErrZIPNotFound is returned for a well-formed ZIP code that doesn't exist
+var ErrZIPNotFound = errors.New("no such ZIP code")
+
+// AmbiguousPlaceError is returned when a location query matches several
+// distinct places, so the caller can offer them instead of guessing
+type AmbiguousPlaceError struct {
+	Candidates []models.Place
+}
+
+func (e *AmbiguousPlaceError) Error() string {
+	return fmt.Sprintf("location query matches %d places", len(e.Candidates))
+}
+
+// samePlaceKm is how close two matches must be to count as the same place.
+// Geocoders often return a city's boundary and its center as separate matches.
+const samePlaceKm = 25.0
+
+// maxPlaceCandidates caps the candidates offered for an ambiguous query
+const maxPlaceCandidates = 5
+
+// WithGeocoder enables place lookups (?city= and ?q=) through the given geocoder
+func WithGeocoder(g Geocoder) WeatherServiceOption {
+	return func(s *WeatherService) {
+		s.geocoder = g
+	}
+}
+
+// WithZIPResolver enables ZIP code lookups (?zip=) through the given resolver
+func WithZIPResolver(z ZIPResolver) WeatherServiceOption {
+	return func(s *WeatherService) {
+		s.zipResolver = z
+	}
+}
+
+// ResolvePlace geocodes a location query to a single place. Results are cached
+// per normalized query, and a stale result is used if the geocoder fails.
+// ErrPlaceNotFound is returned when nothing matches and *AmbiguousPlaceError
+// when several distinct places do.
+func (s *WeatherService) ResolvePlace(ctx context.Context, query PlaceQuery) (*models.Place, error) {
+	if s.geocoder == nil {
+		return nil, ErrGeocodingDisabled
+	}
+
+	key := query.Key()
+	cached, err := s.repo.GetGeocode(ctx, key)
+	if err != nil || !s.repo.IsGeocodeFresh(cached) {
+		places, geocodeErr := s.geocoder.Geocode(ctx, query)
+		switch {
+		case geocodeErr == nil:
+			cached = &models.GeocodeCache{Query: key, Places: places, Timestamp: time.Now()}
+			_ = s.repo.SaveGeocode(ctx, cached)
+		case cached == nil:
+			return nil, geocodeErr
+		}
+	}
+
+	places := distinctPlaces(cached.Places)
+	switch {
+	case len(places) == 0:
+		return nil, ErrPlaceNotFound
+	case len(places) > 1:
+		return nil, &AmbiguousPlaceError{Candidates: places[:min(len(places), maxPlaceCandidates)]}
+	}
+	return &places[0], nil
+}
+
+// distinctPlaces drops matches within samePlaceKm of a better-ranked match,
+// keeping the geocoder's order
+func distinctPlaces(places []models.Place) []models.Place {
+	var out []models.Place
+	for _, p := range places {
+		duplicate := false
+		for _, kept := range out {
+			if repository.DistanceKm(p.Latitude, p.Longitude, kept.Latitude, kept.Longitude) < samePlaceKm {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// ResolveZIP resolves a ZIP or ZIP+4 code to the place at its centroid.
+// Results, unknown ZIP codes included, are cached with geocoding results, and
+// a stale result is used if the resolver fails.
+func (s *WeatherService) ResolveZIP(ctx context.Context, raw string) (*models.Place, error) {
+	if s.zipResolver == nil {
+		return nil, ErrGeocodingDisabled
+	}
+	zip, ok := ParseZIP(raw)
+	if !ok {
+		return nil, ErrInvalidZIP
+	}
+
+	key := "zip:" + zip
+	cached, err := s.repo.GetGeocode(ctx, key)
+	if err != nil || !s.repo.IsGeocodeFresh(cached) {
+		place, resolveErr := s.zipResolver.ResolveZIP(ctx, zip)
+		switch {
+		case resolveErr == nil:
+			cached = &models.GeocodeCache{Query: key, Timestamp: time.Now()}
+			if place != nil {
+				cached.Places = []models.Place{*place}
+			}
+			_ = s.repo.SaveGeocode(ctx, cached)
+		case cached == nil:
+			return nil, resolveErr
+		}
+	}
+
+	if len(cached.Places) == 0 {
+		return nil, ErrZIPNotFound
+	}
+	return &cached.Places[0], nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// fakeGeocoder returns canned places per query key, counting lookups
+type fakeGeocoder struct {
+	places map[string][]models.Place
+	err    error
+	calls  int
+}
+
+func (g *fakeGeocoder) Geocode(ctx context.Context, query PlaceQuery) ([]models.Place, error) {
+	g.calls++
+	if g.err != nil {
+		return nil, g.err
+	}
+	return g.places[query.Key()], nil
+}
+
+func TestResolvePlace(t *testing.T) {
+	portlandOR := models.Place{Name: "Portland, Oregon", Latitude: 45.5202, Longitude: -122.6742}
+	portlandME := models.Place{Name: "Portland, Maine", Latitude: 43.6591, Longitude: -70.2568}
+	geocoder := &fakeGeocoder{places: map[string][]models.Place{
+		// The city center and its boundary, a few km apart
+		PlaceQuery{City: "Portland", State: "OR"}.Key(): {portlandOR, {Name: "Portland boundary", Latitude: 45.54, Longitude: -122.65}},
+		PlaceQuery{City: "Portland"}.Key():              {portlandOR, portlandME},
+	}}
+	repo := newTestRepo(t)
+	service := NewWeatherService(repo, nil, WithGeocoder(geocoder))
+
+	place, err := service.ResolvePlace(context.Background(), PlaceQuery{City: "Portland", State: "OR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *place != portlandOR {
+		t.Errorf("place = %+v; want %+v", place, portlandOR)
+	}
+
+	var ambiguous *AmbiguousPlaceError
+	if _, err := service.ResolvePlace(context.Background(), PlaceQuery{City: "Portland"}); !errors.As(err, &ambiguous) {
+		t.Fatalf("err = %v; want AmbiguousPlaceError", err)
+	}
+	if len(ambiguous.Candidates) != 2 || ambiguous.Candidates[0] != portlandOR || ambiguous.Candidates[1] != portlandME {
+		t.Errorf("candidates = %+v; want both Portlands, best first", ambiguous.Candidates)
+	}
+
+	if _, err := service.ResolvePlace(context.Background(), PlaceQuery{Text: "Atlantis"}); !errors.Is(err, ErrPlaceNotFound) {
+		t.Errorf("err = %v; want ErrPlaceNotFound", err)
+	}
+
+	// Cached per normalized query, including misses
+	calls := geocoder.calls
+	for _, q := range []PlaceQuery{{City: "portland", State: "Oregon"}, {Text: "atlantis"}} {
+		service.ResolvePlace(context.Background(), q)
+	}
+	if geocoder.calls != calls {
+		t.Errorf("repeat lookups made %d geocoder calls; want none", geocoder.calls-calls)
+	}
+
+	// A stale result is used when the geocoder fails
+	stale := &models.GeocodeCache{Query: PlaceQuery{Text: "Bend"}.Key(), Places: []models.Place{portlandOR}, Timestamp: time.Now().Add(-60 * 24 * time.Hour)}
+	if err := repo.SaveGeocode(context.Background(), stale); err != nil {
+		t.Fatal(err)
+	}
+	geocoder.err = errors.New("geocoder down")
+	if place, err := service.ResolvePlace(context.Background(), PlaceQuery{Text: "Bend"}); err != nil || *place != portlandOR {
+		t.Errorf("stale lookup = %+v, %v; want the cached place", place, err)
+	}
+	if _, err := service.ResolvePlace(context.Background(), PlaceQuery{Text: "Salem"}); !errors.Is(err, geocoder.err) {
+		t.Errorf("uncached lookup err = %v; want the geocoder error", err)
+	}
+
+	if _, err := NewWeatherService(repo, nil).ResolvePlace(context.Background(), PlaceQuery{Text: "Bend"}); !errors.Is(err, ErrGeocodingDisabled) {
+		t.Errorf("err = %v; want ErrGeocodingDisabled without a geocoder", err)
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxProblemBytes caps how much of an error response body is read for its detail
+const maxProblemBytes = 64 << 10
+
+// outsideCoverageDetail starts the detail of the NWS's answer for points it
+// doesn't forecast for, e.g. "Unable to provide data for requested point 0,0"
+const outsideCoverageDetail = "Unable to provide data for requested point"
+
+// ProblemError is an unexpected response status from an upstream API, with
+// the title and detail of its application/problem+json body when it has one,
+// as the NWS sends
+type ProblemError struct {
+	// API names the upstream endpoint, e.g. "NWS points"
+	API    string
+	Status int
+	// Type is the problem's URI, e.g. https://api.weather.gov/problems/InvalidPoint
+	Type   string
+	Title  string
+	Detail string
+}
+
+func (e *ProblemError) Error() string {
+	msg := fmt.Sprintf("%s API returned status: %d", e.API, e.Status)
+	switch {
+	case e.Detail != "":
+		msg += ": " + e.Detail
+	case e.Title != "":
+		msg += ": " + e.Title
+	}
+	return msg
+}
+
+// outsideCoverage reports whether the NWS answered that it has no data for
+// the requested point
+func (e *ProblemError) outsideCoverage() bool {
+	return e.Status == http.StatusNotFound || strings.HasPrefix(e.Detail, outsideCoverageDetail)
+}
+
+// readProblem reads an unexpected upstream response into a *ProblemError. Bodies
+// that aren't problem+json, or can't be read, leave the title and detail empty.
+func readProblem(api string, resp *http.Response) *ProblemError {
+	problem := &ProblemError{API: api, Status: resp.StatusCode}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProblemBytes))
+	if err != nil {
+		return problem
+	}
+	var doc struct {
+		Type   string `json:"type"`
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+	}
+	if json.Unmarshal(body, &doc) == nil {
+		problem.Type, problem.Title, problem.Detail = doc.Type, strings.TrimSpace(doc.Title), strings.TrimSpace(doc.Detail)
+	}
+	return problem
+}
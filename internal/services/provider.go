@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"weather-api-go/internal/coverage"
+	"weather-api-go/internal/metrics"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+// Names of the built-in forecast providers, as reported in responses and
+// recorded with cached forecasts
+const (
+	ProviderNWS       = "nws"
+	ProviderOpenMeteo = "open-meteo"
+	ProviderOWM       = "owm"
+)
+
+// WeatherProvider is a source of current forecasts. The NWS is the default;
+// other backends, and test doubles, plug in through this interface.
+type WeatherProvider interface {
+	// Name identifies the provider in responses and cache entries
+	Name() string
+	// GetForecast fetches the current forecast for a coordinate. The returned
+	// entry carries its fetch time in Timestamp.
+	GetForecast(ctx context.Context, lat, lon float64) (*models.WeatherCache, error)
+	// SupportsLocation reports whether the provider forecasts for a coordinate,
+	// so unsupported ones are rejected without an upstream request
+	SupportsLocation(lat, lon float64) bool
+}
+
+// ErrQuotaExceeded is returned when a forecast provider refuses requests
+// because the account's quota is used up. It is kept distinct from other
+// upstream failures so callers can serve cached data and report it as such.
+var ErrQuotaExceeded = errors.New("forecast provider quota exceeded")
+
+// ErrInvalidAPIKey is returned when a forecast provider rejects the configured API key
+var ErrInvalidAPIKey = errors.New("forecast provider rejected the API key")
+
+// errOtherProvider reports a cached forecast from a provider the service no
+// longer uses, which is treated as a miss
+var errOtherProvider = errors.New("cached forecast is from another provider")
+
+// ErrNWSUnavailable is returned by NWS-specific features (alerts, observations,
+// period forecasts, raw documents) when the service has no NWS client
+var ErrNWSUnavailable = errors.New("this feature requires the NWS, which is not configured")
+
+// WithNWSFeatures provides the NWS client used for NWS-specific features when
+// the forecast provider is another backend. With an NWS forecast provider
+// they use it, and this option is unnecessary.
+func WithNWSFeatures(c *NWSAPIClient) WeatherServiceOption {
+	return func(s *WeatherService) {
+		s.nwsClient = c
+	}
+}
+
+// WithFallbackProvider serves coordinates outside the forecast provider's
+// coverage from another provider, such as Open-Meteo for points the NWS
+// doesn't forecast for. Without one they fail with ErrOutsideCoverage.
+func WithFallbackProvider(p WeatherProvider) WeatherServiceOption {
+	return func(s *WeatherService) {
+		s.fallback = p
+	}
+}
+
+// requireNWS returns ErrNWSUnavailable when the service has no NWS client
+func (s *WeatherService) requireNWS() error {
+	if s.nwsClient == nil {
+		return ErrNWSUnavailable
+	}
+	return nil
+}
+
+// Name identifies the NWS in responses and cache entries
+func (c *NWSAPIClient) Name() string {
+	return ProviderNWS
+}
+
+// SupportsLocation reports whether a coordinate falls inside NWS forecast coverage
+func (c *NWSAPIClient) SupportsLocation(lat, lon float64) bool {
+	return coverage.Covered(lat, lon)
+}
+
+// getCached returns a coordinate's cached forecast. Entries recorded by a
+// provider the service doesn't use are reported as errOtherProvider, so data
+// from different sources isn't mixed.
+func (s *WeatherService) getCached(ctx context.Context, c repository.Coordinates) (*models.WeatherCache, error) {
+	cached, err := s.repo.GetFromCacheAt(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if !s.usesProvider(cached.Provider) {
+		return nil, errOtherProvider
+	}
+	return cached, nil
+}
+
+// usesProvider reports whether the service fetches forecasts from the named
+// provider. Entries cached before providers were recorded are the NWS's.
+func (s *WeatherService) usesProvider(name string) bool {
+	if name == "" {
+		name = ProviderNWS
+	}
+	return (s.provider != nil && s.provider.Name() == name) || (s.fallback != nil && s.fallback.Name() == name)
+}
+
+// providerForecast fetches the forecast for a coordinate from the configured
+// provider, turning to the fallback provider for coordinates outside its
+// coverage. The string reports the forecast's provenance as getGridForecast does.
+func (s *WeatherService) providerForecast(ctx context.Context, lat, lon float64) (*models.WeatherCache, string, error) {
+	weather, source, err := s.forecastFrom(ctx, s.provider, lat, lon)
+	if errors.Is(err, ErrOutsideCoverage) && s.fallback != nil {
+		weather, source, err = s.forecastFrom(ctx, s.fallback, lat, lon)
+	}
+	return weather, source, err
+}
+
+// forecastFrom fetches the forecast for a coordinate from one provider and
+// records the provider on it. The NWS goes through its grid cell, sharing the
+// cell's cached forecast with nearby coordinates; other providers are asked
+// directly.
+func (s *WeatherService) forecastFrom(ctx context.Context, provider WeatherProvider, lat, lon float64) (*models.WeatherCache, string, error) {
+	if nws, ok := provider.(*NWSAPIClient); ok {
+		weather, source, err := s.getGridForecast(ctx, nws, lat, lon)
+		if err != nil {
+			return nil, "", err
+		}
+		weather.Provider = ProviderNWS
+		return weather, source, nil
+	}
+
+	if !provider.SupportsLocation(lat, lon) {
+		if s.metrics != nil {
+			s.metrics.Inc(metrics.RequestsOutOfCoverage)
+		}
+		return nil, "", ErrOutsideCoverage
+	}
+	var weather *models.WeatherCache
+	err := s.upstream(func() (err error) {
+		weather, err = provider.GetForecast(ctx, lat, lon)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	weather.Latitude = lat
+	weather.Longitude = lon
+	weather.Provider = provider.Name()
+	return weather, SourceLive, nil
+}
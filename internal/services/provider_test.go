@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// fakeProvider is a WeatherProvider serving a fixed forecast inside a latitude band
+type fakeProvider struct {
+	name     string
+	forecast string
+	err      error
+	minLat   float64
+	maxLat   float64
+	calls    int32
+}
+
+func (p *fakeProvider) Name() string {
+	if p.name == "" {
+		return "fake"
+	}
+	return p.name
+}
+
+func (p *fakeProvider) GetForecast(ctx context.Context, lat, lon float64) (*models.WeatherCache, error) {
+	atomic.AddInt32(&p.calls, 1)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &models.WeatherCache{Forecast: p.forecast, TempC: 20, TempF: 68, Timestamp: time.Now()}, nil
+}
+
+func (p *fakeProvider) SupportsLocation(lat, lon float64) bool {
+	return lat >= p.minLat && lat <= p.maxLat
+}
+
+func TestGetWeatherFromProvider(t *testing.T) {
+	provider := &fakeProvider{forecast: "Drizzle", minLat: 40, maxLat: 60}
+	service := NewWeatherService(newTestRepo(t), provider)
+
+	tests := []struct {
+		name      string
+		wantHit   bool
+		wantCalls int32
+	}{
+		{"miss fetches from the provider", false, 1},
+		{"hit is served from cache", true, 1},
+	}
+
+	for _, tt := range tests {
+		resp, err := service.GetWeather(context.Background(), 51.5074, -0.1278)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if resp.Forecast != "Drizzle" || resp.CacheHit != tt.wantHit || resp.Provider != "fake" {
+			t.Errorf("%s: Forecast = %q from %q (hit %v); want Drizzle from fake (hit %v)", tt.name, resp.Forecast, resp.Provider, resp.CacheHit, tt.wantHit)
+		}
+		if got := atomic.LoadInt32(&provider.calls); got != tt.wantCalls {
+			t.Errorf("%s: provider called %d times; want %d", tt.name, got, tt.wantCalls)
+		}
+	}
+
+	// Unsupported coordinates are rejected before reaching the provider
+	if _, err := service.GetWeather(context.Background(), 35.6762, 139.6503); !errors.Is(err, ErrOutsideCoverage) {
+		t.Errorf("unsupported coordinate error = %v; want ErrOutsideCoverage", err)
+	}
+	if got := atomic.LoadInt32(&provider.calls); got != 1 {
+		t.Errorf("provider called %d times after an unsupported coordinate; want 1", got)
+	}
+
+	// NWS-specific features need an NWS client
+	if _, err := service.GetRawPoints(context.Background(), 51.5074, -0.1278); !errors.Is(err, ErrNWSUnavailable) {
+		t.Errorf("GetRawPoints error = %v; want ErrNWSUnavailable", err)
+	}
+}
+
+func TestGetWeatherFallsBackToStaleWhenProviderFails(t *testing.T) {
+	unavailable := errors.New("provider unavailable")
+	provider := &fakeProvider{err: unavailable, minLat: 40, maxLat: 60}
+	repo := newTestRepo(t)
+	service := NewWeatherService(repo, provider)
+
+	stale := &models.WeatherCache{Latitude: 51.5074, Longitude: -0.1278, Forecast: "Fog", TempC: 8, TempF: 46.4, Timestamp: time.Now().Add(-DefaultMaxStale - time.Hour), Provider: "fake"}
+	if err := repo.SaveToCache(context.Background(), stale); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := service.GetWeather(context.Background(), 51.5074, -0.1278)
+	if err != nil {
+		t.Fatalf("GetWeather with a stale entry: %v", err)
+	}
+	if resp.Forecast != "Fog" || resp.Source != SourceStale {
+		t.Errorf("Forecast = %q from %q; want the stale entry", resp.Forecast, resp.Source)
+	}
+
+	// Without cached data the provider's error is returned
+	if _, err := service.GetWeather(context.Background(), 48.8566, 2.3522); !errors.Is(err, unavailable) {
+		t.Errorf("uncached GetWeather error = %v; want the provider's error", err)
+	}
+	if got := atomic.LoadInt32(&provider.calls); got != 2 {
+		t.Errorf("provider called %d times; want 2", got)
+	}
+}
+
+func TestGetWeatherFallsBackOutsideNWSCoverage(t *testing.T) {
+	var points int32
+	nws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The NWS answers points outside its coverage with a 404
+		atomic.AddInt32(&points, 1)
+		http.Error(w, `{"title": "Data Unavailable For Requested Point"}`, http.StatusNotFound)
+	}))
+	defer nws.Close()
+
+	tests := []struct {
+		name       string
+		check      bool
+		wantPoints int32
+	}{
+		// The coverage pre-check routes the coordinate without asking the NWS
+		{"pre-checked", true, 0},
+		{"points 404", false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&points, 0)
+			fallback := &fakeProvider{name: ProviderOpenMeteo, forecast: "Light Rain", minLat: -90, maxLat: 90}
+			service := NewWeatherService(newTestRepo(t), newTestNWSClient(nws),
+				WithCoverageCheck(tt.check), WithFallbackProvider(fallback))
+
+			resp, err := service.GetWeather(context.Background(), 51.5074, -0.1278)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Forecast != "Light Rain" || resp.Provider != ProviderOpenMeteo || resp.Source != SourceLive {
+				t.Errorf("Forecast = %q from %q (%s); want the fallback's live forecast", resp.Forecast, resp.Provider, resp.Source)
+			}
+			if got := atomic.LoadInt32(&points); got != tt.wantPoints {
+				t.Errorf("points requests = %d; want %d", got, tt.wantPoints)
+			}
+
+			// The fallback's forecast is cached with its provider
+			resp, err = service.GetWeather(context.Background(), 51.5074, -0.1278)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !resp.CacheHit || resp.Provider != ProviderOpenMeteo || atomic.LoadInt32(&fallback.calls) != 1 {
+				t.Errorf("second request: provider %q (hit %v) after %d fallback calls; want a cached open-meteo hit after 1", resp.Provider, resp.CacheHit, fallback.calls)
+			}
+		})
+	}
+
+	// Without a fallback the coordinate is rejected rather than failing upstream
+	service := NewWeatherService(newTestRepo(t), newTestNWSClient(nws), WithCoverageCheck(false))
+	if _, err := service.GetWeather(context.Background(), 51.5074, -0.1278); !errors.Is(err, ErrOutsideCoverage) {
+		t.Errorf("points 404 without a fallback: error = %v; want ErrOutsideCoverage", err)
+	}
+}
+
+func TestGetWeatherIgnoresOtherProvidersCache(t *testing.T) {
+	provider := &fakeProvider{forecast: "Drizzle", minLat: -90, maxLat: 90}
+	repo := newTestRepo(t)
+	service := NewWeatherService(repo, provider)
+
+	// A fresh entry left by a provider the service no longer uses
+	other := &models.WeatherCache{Latitude: 51.5074, Longitude: -0.1278, Forecast: "Sunny", Timestamp: time.Now(), Provider: "retired"}
+	if err := repo.SaveToCache(context.Background(), other); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := service.GetWeather(context.Background(), 51.5074, -0.1278)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Forecast != "Drizzle" || resp.CacheHit {
+		t.Errorf("Forecast = %q (hit %v); want a fresh fetch from the configured provider", resp.Forecast, resp.CacheHit)
+	}
+	if _, ok := service.CachedWeather(context.Background(), 51.5074, -0.1278); !ok {
+		t.Error("CachedWeather after the refetch = miss; want the configured provider's entry")
+	}
+	cached, err := repo.GetFromCache(context.Background(), 51.5074, -0.1278)
+	if err != nil || cached.Provider != "fake" {
+		t.Errorf("cached entry = %+v, %v; want it replaced by the configured provider's", cached, err)
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrRateLimited is returned when the outbound rate limit would delay an NWS
+// request past its deadline
+var ErrRateLimited = errors.New("NWS request rate limit exceeded")
+
+// RateLimitedError reports an NWS request refused by the outbound rate limit
+// and how long it would have had to wait
+type RateLimitedError struct {
+	Wait time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("%v; next request allowed in %s", ErrRateLimited, e.Wait)
+}
+
+// Is lets errors.Is match RateLimitedError against ErrRateLimited
+func (e *RateLimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RateLimitConfig bounds the rate of outbound NWS requests, including retries
+type RateLimitConfig struct {
+	// RequestsPerSecond is the sustained request rate; zero disables the limit
+	RequestsPerSecond float64
+	// Burst is how many requests may be sent at once after a quiet period
+	Burst int
+	// MaxWait is the request deadline: a request that would wait longer for
+	// its turn fails with ErrRateLimited instead
+	MaxWait time.Duration
+}
+
+// DefaultRateLimitWait is the longest a request waits for its turn when no
+// MaxWait is configured
+const DefaultRateLimitWait = 2 * time.Second
+
+// WithRateLimit spaces outbound NWS requests to the configured rate
+func WithRateLimit(cfg RateLimitConfig) NWSClientOption {
+	return func(c *NWSAPIClient) {
+		if cfg.RequestsPerSecond <= 0 {
+			c.limiter = nil
+			return
+		}
+		if cfg.MaxWait <= 0 {
+			cfg.MaxWait = DefaultRateLimitWait
+		}
+		c.limiter = rate.NewLimiter(rate.Limit(cfg.RequestsPerSecond), max(cfg.Burst, 1))
+		c.maxRateWait = cfg.MaxWait
+	}
+}
+
+// waitTurn blocks until the rate limit allows another request, or returns a
+// *RateLimitedError without waiting when that would take longer than MaxWait
+// or outlast ctx's deadline. Cancelling ctx ends the wait.
+func (c *NWSAPIClient) waitTurn(ctx context.Context) error {
+	if c.limiter == nil {
+		return nil
+	}
+	r := c.limiter.Reserve()
+	wait := r.Delay()
+	deadline, bounded := ctx.Deadline()
+	if wait > c.maxRateWait || (bounded && time.Until(deadline) < wait) {
+		r.Cancel()
+		return &RateLimitedError{Wait: wait}
+	}
+	if err := sleepContext(ctx, wait); err != nil {
+		r.Cancel()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+// GetRawPoints returns the NWS points document for a coordinate exactly as the
+// NWS served it. The copy kept when the grid mapping was last resolved is reused
+// while fresh; otherwise the document is fetched with fetchGridPoint, under the
+// same coverage checks as resolveGridPoint, refreshing the mapping at the same
+// time. A stale copy is served if that fails.
+func (s *WeatherService) GetRawPoints(ctx context.Context, lat, lon float64) (*models.RawDocument, error) {
+	if err := s.requireNWS(); err != nil {
+		return nil, err
+	}
+	if err := s.checkCoverage(lat, lon); err != nil {
+		return nil, err
+	}
+	lat, lon = normalizePointCoordinate(lat), normalizePointCoordinate(lon)
+
+	cached, err := s.repo.GetRawDocument(ctx, repository.RawPoints, repository.RawPointsKey(lat, lon))
+	if err == nil && s.repo.IsRawDocumentFresh(repository.RawPoints, cached) {
+		return cached, nil
+	}
+	if err := s.checkUncovered(ctx, lat, lon); err != nil {
+		return nil, err
+	}
+
+	point, err := s.fetchGridPoint(ctx, s.nwsClient, lat, lon)
+	if err != nil {
+		if cached != nil {
+			return cached, nil
+		}
+		return nil, err
+	}
+	return point.Raw, nil
+}
+
+// GetRawForecast returns the NWS forecast document for the grid cell containing
+// a coordinate exactly as the NWS served it, reusing the copy kept with the
+// cell's parsed forecast while fresh, and a stale copy if a refetch fails
+func (s *WeatherService) GetRawForecast(ctx context.Context, lat, lon float64) (*models.RawDocument, error) {
+	point, err := s.resolveGridPoint(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	key := repository.RawForecastKey(point.GridID, point.GridX, point.GridY)
+
+	cached, err := s.repo.GetRawDocument(ctx, repository.RawForecast, key)
+	if err == nil && s.repo.IsRawDocumentFresh(repository.RawForecast, cached) {
+		return cached, nil
+	}
+
+	var forecast *models.WeatherCache
+	err = s.upstream(func() (err error) {
+		forecast, err = s.nwsClient.GetGridForecast(ctx, point.ForecastURL)
+		return err
+	})
+	if err != nil {
+		if cached != nil {
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	s.saveGridForecast(ctx, point, forecast)
+	return forecast.Raw, nil
+}
+
+// saveGridPoint caches a freshly fetched grid mapping along with its points document
+func (s *WeatherService) saveGridPoint(ctx context.Context, point *models.GridPoint) {
+	_ = s.repo.SaveGridPoint(ctx, point)
+	if point.Raw != nil {
+		_ = s.repo.SaveRawDocument(ctx, repository.RawPoints, repository.RawPointsKey(point.Latitude, point.Longitude), point.Raw)
+	}
+}
+
+// saveGridForecast caches a freshly fetched grid forecast along with its
+// forecast document and the day/night periods in it
+func (s *WeatherService) saveGridForecast(ctx context.Context, point *models.GridPoint, forecast *models.WeatherCache) {
+	_ = s.repo.SaveGridForecast(ctx, point.GridID, point.GridX, point.GridY, forecast)
+	if forecast.Raw != nil {
+		key := repository.RawForecastKey(point.GridID, point.GridX, point.GridY)
+		_ = s.repo.SaveRawDocument(ctx, repository.RawForecast, key, forecast.Raw)
+		// The document holds the cell's day/night periods too, cached so
+		// advisories can read them without a fetch of their own
+		if periods, err := decodeForecastPeriods(forecast.Raw.Body); err == nil {
+			daily := &models.ForecastPeriodsCache{Periods: periods, Timestamp: forecast.Raw.Timestamp, Source: SourceLive}
+			_ = s.repo.SaveForecastPeriods(ctx, repository.DailyPeriods, point.GridID, point.GridX, point.GridY, daily)
+		}
+	}
+}
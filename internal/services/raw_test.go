@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const rawForecastBody = `{"properties": {"periods": [{"shortForecast": "Sunny",  "temperature": 72, "temperatureUnit": "F", "extra": [1, 2]}]}}`
+
+// rawNWS serves geo+json documents, counting upstream requests. forecastPad
+// adds that many bytes of whitespace to the forecast body.
+func rawNWS(t *testing.T, forecastPad int) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/geo+json")
+		if strings.HasPrefix(r.URL.Path, "/points/") {
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35, "forecast": "%s/gridpoints/OKX/33,35/forecast"}}`, server.URL)
+			return
+		}
+		fmt.Fprint(w, rawForecastBody+strings.Repeat(" ", forecastPad))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestRawDocumentsReuseParsedFetch(t *testing.T) {
+	server, calls := rawNWS(t, 0)
+	service := NewWeatherService(newTestRepo(t), newTestNWSClient(server))
+
+	if _, err := service.GetWeather(context.Background(), 40.7128, -74.0060); err != nil {
+		t.Fatal(err)
+	}
+	fetched := atomic.LoadInt32(calls)
+
+	forecast, err := service.GetRawForecast(context.Background(), 40.7128, -74.0060)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(forecast.Body) != rawForecastBody {
+		t.Errorf("raw forecast = %s; want the untouched upstream body", forecast.Body)
+	}
+	if forecast.ContentType != "application/geo+json" {
+		t.Errorf("ContentType = %q; want application/geo+json", forecast.ContentType)
+	}
+
+	points, err := service.GetRawPoints(context.Background(), 40.71281, -74.00601)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(points.Body), `"gridX": 33`) {
+		t.Errorf("raw points = %s; want the points document", points.Body)
+	}
+
+	if got := atomic.LoadInt32(calls); got != fetched {
+		t.Errorf("raw lookups made %d upstream requests; want 0 after a parsed fetch", got-fetched)
+	}
+}
+
+func TestRawDocumentSizeCap(t *testing.T) {
+	server, _ := rawNWS(t, MaxDocumentBytes)
+	service := NewWeatherService(newTestRepo(t), newTestNWSClient(server))
+
+	if _, err := service.GetRawForecast(context.Background(), 40.7128, -74.0060); !errors.Is(err, ErrDocumentTooLarge) {
+		t.Errorf("GetRawForecast on an oversized document = %v; want ErrDocumentTooLarge", err)
+	}
+}
+
+func TestRawPointsCoverage(t *testing.T) {
+	var requests int32
+	nws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, `{"title": "Data Unavailable For Requested Point"}`, http.StatusNotFound)
+	}))
+	defer nws.Close()
+
+	// Outside the coverage polygons the NWS is never asked
+	service := NewWeatherService(newTestRepo(t), newTestNWSClient(nws))
+	if _, err := service.GetRawPoints(context.Background(), 51.5074, -0.1278); !errors.Is(err, ErrOutsideCoverage) {
+		t.Errorf("pre-check: error = %v; want ErrOutsideCoverage", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("pre-check: %d NWS requests; want 0", n)
+	}
+
+	// A points 404 on a raw lookup is recorded like one on a parsed lookup
+	service = NewWeatherService(newTestRepo(t), newTestNWSClient(nws), WithCoverageCheck(false))
+	if _, err := service.GetRawPoints(context.Background(), 51.5074, -0.1278); !errors.Is(err, ErrOutsideCoverage) {
+		t.Fatalf("raw points: error = %v; want ErrOutsideCoverage", err)
+	}
+	if _, err := service.GetWeather(context.Background(), 51.5074, -0.1278); !errors.Is(err, ErrOutsideCoverage) {
+		t.Errorf("weather after raw points: error = %v; want ErrOutsideCoverage", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("%d NWS requests; want only the raw lookup's", n)
+	}
+}
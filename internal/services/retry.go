@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// RetryConfig bounds how transient upstream failures are retried. Network errors
+// and 5xx responses are retried with exponential backoff and jitter; other
+// responses, including 4xx, are returned as they are.
+type RetryConfig struct {
+	// MaxAttempts is the most times a request is tried, including the first; 1 disables retries
+	MaxAttempts int
+	// MaxElapsed is the total time budget for a request; no retry is started
+	// whose backoff would end past it
+	MaxElapsed time.Duration
+	// BaseDelay is the backoff before the first retry, doubling for each later one
+	BaseDelay time.Duration
+	// MaxDelay caps a single backoff
+	MaxDelay time.Duration
+}
+
+// DefaultRetryConfig returns retry limits suited to the NWS's brief 500/503 blips
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxAttempts: 3,
+		MaxElapsed:  5 * time.Second,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+	}
+}
+
+// WithRetry overrides the default retry limits for transient NWS failures
+func WithRetry(cfg RetryConfig) NWSClientOption {
+	return func(c *NWSAPIClient) {
+		if cfg.MaxAttempts < 1 {
+			cfg.MaxAttempts = 1
+		}
+		c.retry = cfg
+	}
+}
+
+// retryable reports whether an attempt failed in a way a retry may fix
+func retryable(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// upstreamError returns the error, if any, that an attempt says about the
+// NWS's health: a network error or a 5xx response
+func upstreamError(resp *http.Response, err error) error {
+	if err == nil && resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("NWS returned status: %d", resp.StatusCode)
+	}
+	return err
+}
+
+// backoff returns the jittered delay before the given retry (1 for the first):
+// a random duration between half and all of the capped exponential delay
+func (cfg RetryConfig) backoff(retry int) time.Duration {
+	delay := cfg.BaseDelay << (retry - 1)
+	if delay > cfg.MaxDelay || delay <= 0 {
+		delay = cfg.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// do sends a request built by newRequest, retrying transient failures within
+// the client's RetryConfig. Every attempt waits its turn under the outbound
+// rate limit. The last attempt's response or error is returned, and its
+// outcome is remembered for health checks and by the circuit breaker, which
+// refuses the request outright while open. A 429 or 503 with Retry-After
+// stops requests for as long as it asks: it and the requests made meanwhile
+// fail with a *ShedError caused by ErrUpstreamBackoff.
+func (c *NWSAPIClient) do(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	if err := c.backoff.check(); err != nil {
+		return nil, err
+	}
+	done, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := retryRequest(ctx, c.retry, "NWS",
+		func() (*http.Request, error) {
+			req, err := newRequest()
+			if err != nil {
+				return nil, err
+			}
+			return req, c.waitTurn(ctx)
+		},
+		func(req *http.Request) (*http.Response, error) {
+			sent := time.Now()
+			resp, err := c.httpClient.Do(req)
+			c.health.record(time.Since(sent), upstreamError(resp, err))
+			return resp, err
+		},
+	)
+	done(upstreamError(resp, err))
+	if wait, ok := c.backoff.observe(resp); ok {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxProblemBytes))
+		resp.Body.Close()
+		return nil, &ShedError{RetryAfter: wait, Cause: ErrUpstreamBackoff}
+	}
+	return resp, err
+}
+
+// retryRequest sends requests built by newRequest with send, retrying
+// transient failures within cfg and ctx's deadline. An error from newRequest
+// ends the attempts at once, as does cancelling ctx during a backoff. upstream names the service in log messages. The
+// last attempt's response or error is returned.
+func retryRequest(ctx context.Context, cfg RetryConfig, upstream string,
+	newRequest func() (*http.Request, error), send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := send(req)
+		if !retryable(resp, err) {
+			if attempt > 1 {
+				log.Printf("%s request %s returned %d after %d attempts", upstream, req.URL.Path, resp.StatusCode, attempt)
+			}
+			return resp, err
+		}
+
+		// A Retry-After asking for longer than the backoff is honored
+		delay := cfg.backoff(attempt)
+		if wait, ok := retryAfter(resp, time.Now()); ok && wait > delay {
+			delay = wait
+		}
+		deadline, bounded := ctx.Deadline()
+		if attempt >= cfg.MaxAttempts || time.Since(start)+delay > cfg.MaxElapsed ||
+			(bounded && time.Until(deadline) < delay) {
+			if attempt > 1 {
+				log.Printf("%s request %s failed after %d attempts", upstream, req.URL.Path, attempt)
+			}
+			return resp, err
+		}
+
+		// Drain the failed response so its connection can be reused
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxProblemBytes))
+			resp.Body.Close()
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleepContext pauses for d, returning ctx's error early if it is cancelled first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
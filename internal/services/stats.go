@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+const (
+	// DefaultRequestLogRetention is how long raw request log rows are kept once rolled up
+	DefaultRequestLogRetention = 7 * 24 * time.Hour
+	// DefaultStatsRangeDays is the range returned when no start day is requested
+	DefaultStatsRangeDays = 30
+	// MaxStatsRangeDays caps a single daily stats query
+	MaxStatsRangeDays = 366
+
+	// rollupDelay is how long after midnight UTC the previous day is rolled up,
+	// leaving time for buffered request log writes to land
+	rollupDelay = 5 * time.Minute
+	// rollupLockTTL bounds how long one instance holds a day's rollup
+	rollupLockTTL = 10 * time.Minute
+)
+
+// ErrInvalidDayRange is returned when a range of days is malformed or too wide
+var ErrInvalidDayRange = errors.New("invalid date range")
+
+// StatsService rolls the raw request log up into daily stats and serves them
+type StatsService struct {
+	repo      *repository.WeatherRepository
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewStatsService creates a stats service that purges rolled-up raw rows older than retention
+func NewStatsService(repo *repository.WeatherRepository, retention time.Duration) *StatsService {
+	if retention <= 0 {
+		retention = DefaultRequestLogRetention
+	}
+	return &StatsService{repo: repo, retention: retention, now: time.Now}
+}
+
+// RollupDay aggregates one UTC day into daily_stats and then purges raw rows
+// past retention. Only the instance holding the day's lock does the work, and
+// re-running a day replaces its earlier rollup.
+func (s *StatsService) RollupDay(ctx context.Context, day time.Time) error {
+	key := day.UTC().Format(repository.DayFormat)
+	ok, err := s.repo.AcquireLock(ctx, "stats:rollup:"+key, rollupLockTTL)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	if _, err := s.repo.RollupDailyStats(ctx, key); err != nil {
+		return fmt.Errorf("rollup %s: %w", key, err)
+	}
+	if _, err := s.repo.PurgeRequestLog(ctx, s.now().Add(-s.retention)); err != nil {
+		return fmt.Errorf("purge request log: %w", err)
+	}
+	return nil
+}
+
+// Run rolls up the previous day on start, then again shortly after each UTC
+// midnight, until ctx is cancelled
+func (s *StatsService) Run(ctx context.Context) {
+	for {
+		yesterday := s.now().UTC().AddDate(0, 0, -1)
+		if err := s.RollupDay(ctx, yesterday); err != nil {
+			log.Printf("Daily stats rollup failed: %v", err)
+		}
+
+		now := s.now().UTC()
+		next := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1).Add(rollupDelay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+		}
+	}
+}
+
+// GetDailyStats returns the daily stats between two YYYY-MM-DD days inclusive.
+// An empty to defaults to today and an empty from to the DefaultStatsRangeDays before it.
+func (s *StatsService) GetDailyStats(ctx context.Context, from, to string) (*models.DailyStatsResponse, error) {
+	from, to, err := parseDayRange(from, to, s.now(), DefaultStatsRangeDays, MaxStatsRangeDays)
+	if err != nil {
+		return nil, err
+	}
+	days, err := s.repo.GetDailyStats(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DailyStatsResponse{From: from, To: to, Days: days}, nil
+}
+
+// parseDayRange validates an inclusive range of YYYY-MM-DD UTC days. An empty
+// to defaults to the day of now and an empty from to defaultDays ending at to.
+func parseDayRange(from, to string, now time.Time, defaultDays, maxDays int) (string, string, error) {
+	end := now.UTC().Truncate(24 * time.Hour)
+	if to != "" {
+		var err error
+		if end, err = time.Parse(repository.DayFormat, to); err != nil {
+			return "", "", fmt.Errorf("%w: to must be a YYYY-MM-DD date", ErrInvalidDayRange)
+		}
+	}
+	start := end.AddDate(0, 0, -(defaultDays - 1))
+	if from != "" {
+		var err error
+		if start, err = time.Parse(repository.DayFormat, from); err != nil {
+			return "", "", fmt.Errorf("%w: from must be a YYYY-MM-DD date", ErrInvalidDayRange)
+		}
+	}
+
+	if start.After(end) {
+		return "", "", fmt.Errorf("%w: from is after to", ErrInvalidDayRange)
+	}
+	if end.Sub(start) >= time.Duration(maxDays)*24*time.Hour {
+		return "", "", fmt.Errorf("%w: at most %d days may be requested", ErrInvalidDayRange, maxDays)
+	}
+	return start.Format(repository.DayFormat), end.Format(repository.DayFormat), nil
+}
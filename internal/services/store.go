@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/pubsub"
+	"weather-api-go/internal/repository"
+)
+
+// WeatherStore is the cache WeatherService reads and writes.
+// *repository.WeatherRepository is the real one; tests can substitute a fake
+// to drive the cache-hit, stale, and failure paths without databases.
+type WeatherStore interface {
+	// Per-coordinate forecasts
+	GetFromCacheAt(ctx context.Context, c repository.Coordinates) (*models.WeatherCache, error)
+	SaveToCache(ctx context.Context, weather *models.WeatherCache) error
+	IsCacheFresh(cache *models.WeatherCache) bool
+	CacheTTL() time.Duration
+	SubscribeCache(lat, lon float64) *pubsub.Subscription[models.WeatherCache]
+	ListCached(ctx context.Context, limit, offset int) ([]models.CachedLocation, int64, error)
+	GetWeatherHistory(ctx context.Context, lat, lon float64, from, to string) ([]models.WeatherDay, error)
+	DeleteWeather(ctx context.Context, lat, lon float64) (models.CacheInvalidationResponse, error)
+	DeleteAllWeather(ctx context.Context) (models.CacheInvalidationResponse, error)
+
+	// NWS grid cells: coordinate mappings, forecasts, and period series
+	GetGridPoint(ctx context.Context, lat, lon float64) (*models.GridPoint, error)
+	SaveGridPoint(ctx context.Context, point *models.GridPoint) error
+	IsGridPointFresh(point *models.GridPoint) bool
+	DeleteGridPoint(ctx context.Context, lat, lon float64) error
+	GetGridForecast(ctx context.Context, gridID string, gridX, gridY int) (*models.WeatherCache, error)
+	SaveGridForecast(ctx context.Context, gridID string, gridX, gridY int, weather *models.WeatherCache) error
+	DeleteGridForecast(ctx context.Context, gridID string, gridX, gridY int) (models.CacheInvalidationResponse, error)
+	GetForecastPeriods(ctx context.Context, kind, gridID string, gridX, gridY int) (*models.ForecastPeriodsCache, error)
+	SaveForecastPeriods(ctx context.Context, kind, gridID string, gridX, gridY int, cache *models.ForecastPeriodsCache) error
+	IsForecastPeriodsFresh(kind string, cache *models.ForecastPeriodsCache) bool
+	ForecastPeriodsTTL(kind string) time.Duration
+	GetPointMetadata(ctx context.Context, lat, lon float64) (*models.PointMetadata, error)
+	SavePointMetadata(ctx context.Context, meta *models.PointMetadata) error
+	IsPointMetadataFresh(meta *models.PointMetadata) bool
+	GetUncoveredPoint(ctx context.Context, lat, lon float64) (*models.UncoveredPoint, error)
+	SaveUncoveredPoint(ctx context.Context, point *models.UncoveredPoint) error
+	IsUncoveredPointFresh(point *models.UncoveredPoint) bool
+	GetRawDocument(ctx context.Context, kind, key string) (*models.RawDocument, error)
+	SaveRawDocument(ctx context.Context, kind, key string, doc *models.RawDocument) error
+	IsRawDocumentFresh(kind string, doc *models.RawDocument) bool
+
+	// Alerts
+	GetAlerts(ctx context.Context, zone string) (*models.AlertCache, error)
+	SaveAlerts(ctx context.Context, cache *models.AlertCache) error
+	IsAlertCacheFresh(cache *models.AlertCache) bool
+	GetPointAlerts(ctx context.Context, lat, lon float64) (*models.AlertCache, error)
+	SavePointAlerts(ctx context.Context, lat, lon float64, cache *models.AlertCache) error
+	FindSeenAlert(ctx context.Context, ids []string) (string, error)
+	MarkAlertSeen(ctx context.Context, zone string, alert models.Alert, fingerprint string) error
+
+	// Observations and geocoding
+	GetNearestStation(ctx context.Context, lat, lon float64) (*models.NearestStation, error)
+	SaveNearestStation(ctx context.Context, station *models.NearestStation) error
+	IsNearestStationFresh(station *models.NearestStation) bool
+	GetObservations(ctx context.Context, stationID string, hours int) (*models.ObservationCache, error)
+	SaveObservations(ctx context.Context, cache *models.ObservationCache) error
+	IsObservationCacheFresh(cache *models.ObservationCache) bool
+	GetGeocode(ctx context.Context, query string) (*models.GeocodeCache, error)
+	SaveGeocode(ctx context.Context, cache *models.GeocodeCache) error
+	IsGeocodeFresh(cache *models.GeocodeCache) bool
+
+	// Air quality
+	GetAirQuality(ctx context.Context, lat, lon float64) (*models.AirQualityCache, error)
+	SaveAirQuality(ctx context.Context, cache *models.AirQualityCache) error
+	IsAirQualityFresh(cache *models.AirQualityCache) bool
+
+	// Health checks
+	PingDatabase(ctx context.Context, timeout time.Duration) error
+	PingRedis(ctx context.Context, timeout time.Duration) (bool, error)
+	PingForecastStore(ctx context.Context, timeout time.Duration) (bool, error)
+}
+
+var _ WeatherStore = (*repository.WeatherRepository)(nil)
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+// fakeStore is a WeatherStore holding at most one forecast, for driving
+// WeatherService without a database. Methods it doesn't override panic.
+type fakeStore struct {
+	WeatherStore
+	cached  *models.WeatherCache
+	saveErr error
+	saves   int32
+}
+
+func (f *fakeStore) GetFromCacheAt(ctx context.Context, c repository.Coordinates) (*models.WeatherCache, error) {
+	if f.cached == nil {
+		return nil, sql.ErrNoRows
+	}
+	cached := *f.cached
+	return &cached, nil
+}
+
+func (f *fakeStore) SaveToCache(ctx context.Context, weather *models.WeatherCache) error {
+	atomic.AddInt32(&f.saves, 1)
+	if f.saveErr != nil {
+		return f.saveErr
+	}
+	saved := *weather
+	f.cached = &saved
+	return nil
+}
+
+func (f *fakeStore) IsCacheFresh(cache *models.WeatherCache) bool {
+	return time.Since(cache.Timestamp) < f.CacheTTL()
+}
+
+func (f *fakeStore) CacheTTL() time.Duration {
+	return 30 * time.Minute
+}
+
+func TestGetWeatherWithFakeStore(t *testing.T) {
+	errUpstream := errors.New("upstream down")
+	tests := []struct {
+		name        string
+		cached      *models.WeatherCache
+		providerErr error
+		saveErr     error
+		wantSource  string
+		wantHit     bool
+		wantCalls   int32
+		wantSaves   int32
+	}{
+		{
+			name:       "fresh entry skips the provider",
+			cached:     &models.WeatherCache{Forecast: "Cached", Provider: "fake", Source: repository.SourceMemory, Timestamp: time.Now()},
+			wantSource: repository.SourceMemory,
+			wantHit:    true,
+		},
+		{
+			name:        "expired entry is served when the provider fails",
+			cached:      &models.WeatherCache{Forecast: "Cached", Provider: "fake", Source: repository.SourceSQLite, Timestamp: time.Now().Add(-2 * time.Hour)},
+			providerErr: errUpstream,
+			wantSource:  SourceStale,
+			wantCalls:   1,
+		},
+		{
+			name:       "cache write failure doesn't fail the request",
+			saveErr:    errors.New("disk full"),
+			wantSource: SourceLive,
+			wantCalls:  1,
+			wantSaves:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		store := &fakeStore{cached: tt.cached, saveErr: tt.saveErr}
+		provider := &fakeProvider{forecast: "Drizzle", err: tt.providerErr, minLat: -90, maxLat: 90}
+		service := NewWeatherService(store, provider)
+
+		resp, err := service.GetWeather(context.Background(), 51.5074, -0.1278)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if resp.Source != tt.wantSource || resp.CacheHit != tt.wantHit {
+			t.Errorf("%s: Source = %q (hit %v); want %q (hit %v)", tt.name, resp.Source, resp.CacheHit, tt.wantSource, tt.wantHit)
+		}
+		if got := atomic.LoadInt32(&provider.calls); got != tt.wantCalls {
+			t.Errorf("%s: provider called %d times; want %d", tt.name, got, tt.wantCalls)
+		}
+		if got := atomic.LoadInt32(&store.saves); got != tt.wantSaves {
+			t.Errorf("%s: SaveToCache called %d times; want %d", tt.name, got, tt.wantSaves)
+		}
+	}
+
+	// With nothing cached, a provider failure is the caller's
+	store := &fakeStore{}
+	service := NewWeatherService(store, &fakeProvider{err: errUpstream, minLat: -90, maxLat: 90})
+	if _, err := service.GetWeather(context.Background(), 51.5074, -0.1278); !errors.Is(err, errUpstream) {
+		t.Errorf("uncached provider failure error = %v; want %v", err, errUpstream)
+	}
+}
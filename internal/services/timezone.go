@@ -0,0 +1,106 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// zones caches loaded time zones by IANA name, since time.LoadLocation reads
+// the zone database on every call
+var zones sync.Map
+
+// ParseTimeZone validates a ?tz= override, an IANA time zone name such as
+// America/Chicago. Empty returns nil, keeping each location's own zone.
+func ParseTimeZone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil
+	}
+	// Local is the server's zone, which says nothing about the location
+	if name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	loc := loadZone(name)
+	if loc == nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// loadZone returns the time zone with an IANA name, or nil when the name is
+// empty or unknown
+func loadZone(name string) *time.Location {
+	if name == "" {
+		return nil
+	}
+	if loc, ok := zones.Load(name); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil
+	}
+	zones.Store(name, loc)
+	return loc
+}
+
+// responseZone returns the zone local times are given in: the override when
+// there is one, otherwise the named zone of the location
+func responseZone(name string, override *time.Location) *time.Location {
+	if override != nil {
+		return override
+	}
+	return loadZone(name)
+}
+
+// localTime returns t in loc, or nil without a zone
+func localTime(t time.Time, loc *time.Location) *time.Time {
+	if loc == nil {
+		return nil
+	}
+	local := t.In(loc)
+	return &local
+}
+
+// LocalizeForecast returns a forecast with its period times in UTC and in
+// the override zone, or the location's own when override is nil. Periods are
+// copied so cached ones are left as they are. Without a known zone only the
+// UTC times are given.
+func LocalizeForecast(forecast *models.ForecastResponse, override *time.Location) *models.ForecastResponse {
+	localized := *forecast
+	loc := responseZone(forecast.TimeZone, override)
+	localized.TimeZone = zoneName(loc)
+	localized.Periods = make([]models.ForecastPeriod, len(forecast.Periods))
+	for i, p := range forecast.Periods {
+		p.StartTimeLocal, p.EndTimeLocal = localTime(p.StartTime, loc), localTime(p.EndTime, loc)
+		p.StartTime, p.EndTime = p.StartTime.UTC(), p.EndTime.UTC()
+		localized.Periods[i] = p
+	}
+	return &localized
+}
+
+// LocalizeHourly is LocalizeForecast for an hourly forecast
+func LocalizeHourly(forecast *models.HourlyForecastResponse, override *time.Location) *models.HourlyForecastResponse {
+	localized := *forecast
+	loc := responseZone(forecast.TimeZone, override)
+	localized.TimeZone = zoneName(loc)
+	localized.Hours = make([]models.HourlyForecast, len(forecast.Hours))
+	for i, h := range forecast.Hours {
+		h.TimeLocal = localTime(h.Time, loc)
+		h.Time = h.Time.UTC()
+		localized.Hours[i] = h
+	}
+	return &localized
+}
+
+// zoneName returns a zone's IANA name, or empty without a zone
+func zoneName(loc *time.Location) string {
+	if loc == nil {
+		return ""
+	}
+	return loc.String()
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+func TestParseTimeZone(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"America/Chicago", "America/Chicago", false},
+		{" Europe/London ", "Europe/London", false},
+		{"UTC", "UTC", false},
+		{"Local", "", true},
+		{"Mars/Olympus_Mons", "", true},
+		{"america/chicago", "", true},
+		{"../../etc/passwd", "", true},
+	}
+	for _, tt := range tests {
+		loc, err := ParseTimeZone(tt.in)
+		if (err != nil) != tt.wantErr || zoneName(loc) != tt.want {
+			t.Errorf("ParseTimeZone(%q) = %q, %v; want %q, error %v", tt.in, zoneName(loc), err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestLocalizeHourlyAcrossDST(t *testing.T) {
+	hour := func(s string) models.HourlyForecast {
+		at, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return models.HourlyForecast{Time: at}
+	}
+	// New York springs forward at 2 AM on 2024-03-10 and falls back at 2 AM
+	// on 2024-11-03, so 1 AM happens twice that morning
+	forecast := &models.HourlyForecastResponse{
+		TimeZone: "America/New_York",
+		Hours: []models.HourlyForecast{
+			hour("2024-03-10T01:00:00-05:00"),
+			hour("2024-03-10T03:00:00-04:00"),
+			hour("2024-11-03T01:30:00-04:00"),
+			hour("2024-11-03T01:30:00-05:00"),
+		},
+	}
+
+	tests := []struct {
+		override  string
+		wantZone  string
+		wantLocal []string
+	}{
+		{"", "America/New_York", []string{
+			"2024-03-10T01:00:00-05:00", "2024-03-10T03:00:00-04:00",
+			"2024-11-03T01:30:00-04:00", "2024-11-03T01:30:00-05:00",
+		}},
+		{"America/Phoenix", "America/Phoenix", []string{
+			"2024-03-09T23:00:00-07:00", "2024-03-10T00:00:00-07:00",
+			"2024-11-02T22:30:00-07:00", "2024-11-02T23:30:00-07:00",
+		}},
+	}
+	wantUTC := []string{"2024-03-10T06:00:00Z", "2024-03-10T07:00:00Z", "2024-11-03T05:30:00Z", "2024-11-03T06:30:00Z"}
+	for _, tt := range tests {
+		override, err := ParseTimeZone(tt.override)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := LocalizeHourly(forecast, override)
+		if got.TimeZone != tt.wantZone {
+			t.Errorf("override %q: TimeZone = %q; want %q", tt.override, got.TimeZone, tt.wantZone)
+		}
+		for i, h := range got.Hours {
+			if utc := h.Time.Format(time.RFC3339); utc != wantUTC[i] {
+				t.Errorf("override %q: hour %d Time = %s; want %s", tt.override, i, utc, wantUTC[i])
+			}
+			if h.TimeLocal == nil || h.TimeLocal.Format(time.RFC3339) != tt.wantLocal[i] {
+				t.Errorf("override %q: hour %d TimeLocal = %v; want %s", tt.override, i, h.TimeLocal, tt.wantLocal[i])
+			}
+		}
+	}
+	if forecast.Hours[0].TimeLocal != nil || forecast.Hours[0].Time.Location() == time.UTC {
+		t.Error("LocalizeHourly changed the hours it was given")
+	}
+}
+
+func TestLocalizeForecastWithoutZone(t *testing.T) {
+	start := time.Date(2024, 3, 10, 6, 0, 0, 0, time.FixedZone("EDT", -4*3600))
+	for _, name := range []string{"", "Mars/Olympus_Mons"} {
+		forecast := &models.ForecastResponse{
+			TimeZone: name,
+			Periods:  []models.ForecastPeriod{{Name: "Sunday", StartTime: start, EndTime: start.Add(12 * time.Hour)}},
+		}
+		got := LocalizeForecast(forecast, nil)
+		p := got.Periods[0]
+		if got.TimeZone != "" || p.StartTimeLocal != nil || p.EndTimeLocal != nil {
+			t.Errorf("zone %q: TimeZone = %q with local times %v, %v; want none", name, got.TimeZone, p.StartTimeLocal, p.EndTimeLocal)
+		}
+		if p.StartTime.Format(time.RFC3339) != "2024-03-10T10:00:00Z" || p.EndTime.Format(time.RFC3339) != "2024-03-10T22:00:00Z" {
+			t.Errorf("zone %q: period = %s to %s; want it in UTC", name, p.StartTime, p.EndTime)
+		}
+	}
+}
+
+func TestSetProvenanceLocalTime(t *testing.T) {
+	// 06:59 UTC is 01:59 EST; a minute later is 03:00 EDT
+	for _, tt := range []struct {
+		fetchedAt time.Time
+		want      string
+	}{
+		{time.Date(2024, 3, 10, 6, 59, 0, 0, time.UTC), "2024-03-10T01:59:00-05:00"},
+		{time.Date(2024, 3, 10, 7, 0, 0, 0, time.UTC), "2024-03-10T03:00:00-04:00"},
+	} {
+		resp := &models.WeatherResponse{TimeZone: "America/New_York"}
+		setProvenance(resp, SourceLive, tt.fetchedAt)
+		if resp.CachedAtLocal == nil || resp.CachedAtLocal.Format(time.RFC3339) != tt.want {
+			t.Errorf("fetched at %s: CachedAtLocal = %v; want %s", tt.fetchedAt, resp.CachedAtLocal, tt.want)
+		}
+	}
+
+	resp := &models.WeatherResponse{}
+	setProvenance(resp, SourceLive, time.Date(2024, 3, 10, 7, 0, 0, 0, time.UTC))
+	if resp.CachedAtLocal != nil {
+		t.Errorf("CachedAtLocal = %v without a time zone; want none", resp.CachedAtLocal)
+	}
+}
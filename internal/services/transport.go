@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// TransportConfig tunes the HTTP client the NWS client builds for itself
+type TransportConfig struct {
+	// Timeout bounds a single request, response body included
+	Timeout time.Duration
+	// MaxIdleConnsPerHost is how many idle keep-alive connections are kept
+	// to the NWS; net/http's default of 2 forces new TLS handshakes under load
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept before closing
+	IdleConnTimeout time.Duration
+	// DialTimeout bounds establishing a TCP connection
+	DialTimeout time.Duration
+	// TLSHandshakeTimeout bounds the TLS handshake on a new connection
+	TLSHandshakeTimeout time.Duration
+}
+
+// DefaultTransportConfig returns transport settings suited to a steady stream
+// of requests to api.weather.gov
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		Timeout:             DefaultUpstreamTimeout,
+		MaxIdleConnsPerHost: 16,
+		IdleConnTimeout:     90 * time.Second,
+		DialTimeout:         5 * time.Second,
+		TLSHandshakeTimeout: 5 * time.Second,
+	}
+}
+
+// WithTransport overrides the default transport settings; zero fields keep
+// their defaults. A client given by WithHTTPClient is used as it is.
+func WithTransport(cfg TransportConfig) NWSClientOption {
+	return func(c *NWSAPIClient) {
+		defaults := DefaultTransportConfig()
+		if cfg.Timeout <= 0 {
+			cfg.Timeout = defaults.Timeout
+		}
+		if cfg.MaxIdleConnsPerHost <= 0 {
+			cfg.MaxIdleConnsPerHost = defaults.MaxIdleConnsPerHost
+		}
+		if cfg.IdleConnTimeout <= 0 {
+			cfg.IdleConnTimeout = defaults.IdleConnTimeout
+		}
+		if cfg.DialTimeout <= 0 {
+			cfg.DialTimeout = defaults.DialTimeout
+		}
+		if cfg.TLSHandshakeTimeout <= 0 {
+			cfg.TLSHandshakeTimeout = defaults.TLSHandshakeTimeout
+		}
+		c.transport = cfg
+	}
+}
+
+// String describes the settings for the startup log
+func (cfg TransportConfig) String() string {
+	return fmt.Sprintf("timeout %s, %d idle connections per host kept for %s, dial timeout %s, TLS handshake timeout %s",
+		cfg.Timeout, cfg.MaxIdleConnsPerHost, cfg.IdleConnTimeout, cfg.DialTimeout, cfg.TLSHandshakeTimeout)
+}
+
+// newHTTPClient builds an HTTP client with these settings, otherwise behaving
+// like http.DefaultTransport (proxies from the environment, HTTP/2)
+func (cfg TransportConfig) newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	if transport.MaxIdleConns < cfg.MaxIdleConnsPerHost {
+		transport.MaxIdleConns = cfg.MaxIdleConnsPerHost
+	}
+	transport.IdleConnTimeout = cfg.IdleConnTimeout
+	transport.TLSHandshakeTimeout = cfg.TLSHandshakeTimeout
+	return &http.Client{Timeout: cfg.Timeout, Transport: transport}
+}
+
+// Transport returns the transport settings the client was built with, which
+// don't apply when it was given its own HTTP client
+func (c *NWSAPIClient) Transport() TransportConfig {
+	return c.transport
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewNWSAPIClientTransport(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []NWSClientOption
+		want TransportConfig
+	}{
+		{"defaults", nil, DefaultTransportConfig()},
+		{"overrides", []NWSClientOption{WithTransport(TransportConfig{
+			Timeout: 3 * time.Second, MaxIdleConnsPerHost: 64, IdleConnTimeout: time.Minute,
+			DialTimeout: time.Second, TLSHandshakeTimeout: 2 * time.Second,
+		})}, TransportConfig{3 * time.Second, 64, time.Minute, time.Second, 2 * time.Second}},
+		{"zero fields keep their defaults", []NWSClientOption{WithTransport(TransportConfig{MaxIdleConnsPerHost: 4})},
+			TransportConfig{DefaultUpstreamTimeout, 4, 90 * time.Second, 5 * time.Second, 5 * time.Second}},
+	}
+	for _, tt := range tests {
+		client := NewNWSAPIClient(tt.opts...)
+		if got := client.Transport(); got != tt.want {
+			t.Errorf("%s: settings = %+v; want %+v", tt.name, got, tt.want)
+		}
+		transport, ok := client.httpClient.Transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("%s: transport = %T; want *http.Transport", tt.name, client.httpClient.Transport)
+		}
+		if client.httpClient.Timeout != tt.want.Timeout || transport.MaxIdleConnsPerHost != tt.want.MaxIdleConnsPerHost ||
+			transport.IdleConnTimeout != tt.want.IdleConnTimeout || transport.TLSHandshakeTimeout != tt.want.TLSHandshakeTimeout {
+			t.Errorf("%s: client timeout %s with transport %d idle per host, idle timeout %s, TLS timeout %s; want %+v", tt.name,
+				client.httpClient.Timeout, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout, transport.TLSHandshakeTimeout, tt.want)
+		}
+		if transport.Proxy == nil {
+			t.Errorf("%s: transport ignores proxies from the environment", tt.name)
+		}
+	}
+
+	// An injected client is used as it is, whatever the transport settings
+	custom := &http.Client{Timeout: time.Minute}
+	client := NewNWSAPIClient(WithHTTPClient(custom), WithTransport(TransportConfig{Timeout: time.Second}))
+	if client.httpClient != custom {
+		t.Errorf("injected client replaced by %+v", client.httpClient)
+	}
+}
+
+func BenchmarkGetForecastConnectionReuse(b *testing.B) {
+	var connections int32
+	var server *httptest.Server
+	server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/points/") {
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35, "forecast": "%s/gridpoints/OKX/33,35/forecast"}}`, server.URL)
+			return
+		}
+		fmt.Fprint(w, `{"properties": {"periods": [{"shortForecast": "Sunny", "temperature": 72, "temperatureUnit": "F"}]}}`)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+	client := NewNWSAPIClient(WithBaseURL(server.URL))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.GetForecast(context.Background(), 40.7128, -74.006); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	// Every request after the first reuses the kept-alive connection
+	if got := atomic.LoadInt32(&connections); got != 1 {
+		b.Errorf("%d connections for %d forecasts; want 1", got, b.N)
+	}
+	b.ReportMetric(float64(atomic.LoadInt32(&connections))/float64(b.N), "conns/op")
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+// ErrNoUVForecast is returned when no UV index forecast covers a place and hour
+var ErrNoUVForecast = errors.New("no UV index forecast for this location and hour")
+
+// UV exposure categories of the WHO UV index scale
+const (
+	UVLow      = "low"
+	UVModerate = "moderate"
+	UVHigh     = "high"
+	UVVeryHigh = "very high"
+	UVExtreme  = "extreme"
+)
+
+// UVCategory returns the WHO exposure category of a UV index. The scale is
+// defined on whole numbers, so the index is rounded first.
+func UVCategory(index float64) string {
+	switch rounded := math.Round(index); {
+	case rounded >= 11:
+		return UVExtreme
+	case rounded >= 8:
+		return UVVeryHigh
+	case rounded >= 6:
+		return UVHigh
+	case rounded >= 3:
+		return UVModerate
+	}
+	return UVLow
+}
+
+// UVForecaster looks up UV index forecasts
+type UVForecaster interface {
+	// UVIndex returns the UV index forecast for the hour containing at in a
+	// US city, with upstream requests bound by ctx. The forecast's local
+	// hours are read in at's location. ErrNoUVForecast is returned when no
+	// forecast covers the city and hour.
+	UVIndex(ctx context.Context, city, state string, at time.Time) (float64, error)
+}
+
+// WithUVForecaster enables the UV index (?include=uv) through the given
+// forecaster; nil disables it
+func WithUVForecaster(f UVForecaster) WeatherServiceOption {
+	return func(s *WeatherService) {
+		s.uvForecaster = f
+	}
+}
+
+// withUVIndex returns a weather entry with its UV index for the hour it was
+// fetched, looking the index up and caching it with the entry the first time
+// it is asked for. The UV index is an extra: a failed lookup is logged and
+// leaves it unset rather than failing the weather. The entry itself is not
+// modified.
+func (s *WeatherService) withUVIndex(ctx context.Context, weather *models.WeatherCache) *models.WeatherCache {
+	if weather.UVIndex != nil || s.uvForecaster == nil || weather.City == "" || weather.State == "" {
+		return weather
+	}
+	// The forecast's hours are local, so they can't be matched without the zone
+	zone := loadZone(weather.TimeZone)
+	if zone == nil {
+		return weather
+	}
+
+	index, err := s.uvForecaster.UVIndex(ctx, weather.City, weather.State, weather.Timestamp.In(zone))
+	if err != nil {
+		if !errors.Is(err, ErrNoUVForecast) {
+			log.Printf("UV index lookup for %s, %s failed: %v", weather.City, weather.State, err)
+		}
+		return weather
+	}
+	withUV := *weather
+	withUV.UVIndex = &index
+	// Save errors only cost a lookup next time
+	_ = s.repo.SaveToCache(ctx, &withUV)
+	return &withUV
+}
+
+// DefaultEPAUVURL is the EPA Envirofacts data service, which publishes the
+// National Weather Service's hourly UV index forecast
+const DefaultEPAUVURL = "https://data.epa.gov/efservice"
+
+// EPAUVConfig configures an EPAUVClient
+type EPAUVConfig struct {
+	// BaseURL is the Envirofacts host; empty uses DefaultEPAUVURL
+	BaseURL string
+	// HTTPClient replaces the default client, such as for tests
+	HTTPClient *http.Client
+}
+
+// EPAUVClient is a UVForecaster backed by the EPA's hourly UV index forecast,
+// which covers a day of hours for US cities
+type EPAUVClient struct {
+	cfg EPAUVConfig
+}
+
+// NewEPAUVClient creates an EPA UV index client
+func NewEPAUVClient(cfg EPAUVConfig) *EPAUVClient {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultEPAUVURL
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: DefaultUpstreamTimeout}
+	}
+	return &EPAUVClient{cfg: cfg}
+}
+
+// epaUVHour is an hour of the EPA's hourly UV index forecast
+type epaUVHour struct {
+	// DateTime is the hour's local start, such as "Jun/20/2024 01 PM"
+	DateTime string  `json:"DATE_TIME"`
+	UVValue  float64 `json:"UV_VALUE"`
+}
+
+// epaUVTimeLayout parses epaUVHour.DateTime; month names match in any case,
+// as the EPA writes both "Jun" and "JUN"
+const epaUVTimeLayout = "Jan/02/2006 03 PM"
+
+// UVIndex looks up a city's hourly UV index forecast and returns the hour
+// containing at. Cities the EPA doesn't forecast for have an empty forecast.
+func (c *EPAUVClient) UVIndex(ctx context.Context, city, state string, at time.Time) (float64, error) {
+	docURL := fmt.Sprintf("%s/getEnvirofactsUVHOURLY/CITY/%s/STATE/%s/JSON", c.cfg.BaseURL,
+		url.PathEscape(strings.ToUpper(city)), url.PathEscape(strings.ToUpper(state)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, docURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch UV index forecast: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("UV index forecast returned status: %d", resp.StatusCode)
+	}
+
+	var hours []epaUVHour
+	if err := json.NewDecoder(io.LimitReader(resp.Body, MaxDocumentBytes)).Decode(&hours); err != nil {
+		return 0, fmt.Errorf("failed to decode UV index forecast: %w", err)
+	}
+	for _, hour := range hours {
+		start, err := time.ParseInLocation(epaUVTimeLayout, hour.DateTime, at.Location())
+		if err != nil {
+			continue
+		}
+		if !at.Before(start) && at.Before(start.Add(time.Hour)) {
+			return hour.UVValue, nil
+		}
+	}
+	return 0, ErrNoUVForecast
+}
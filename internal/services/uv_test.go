@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+func TestUVCategory(t *testing.T) {
+	tests := []struct {
+		index float64
+		want  string
+	}{
+		{0, UVLow},
+		{2, UVLow},
+		{2.49, UVLow},
+		{2.5, UVModerate},
+		{3, UVModerate},
+		{5, UVModerate},
+		{6, UVHigh},
+		{7, UVHigh},
+		{7.5, UVVeryHigh},
+		{8, UVVeryHigh},
+		{10, UVVeryHigh},
+		{11, UVExtreme},
+		{14, UVExtreme},
+	}
+	for _, tt := range tests {
+		if got := UVCategory(tt.index); got != tt.want {
+			t.Errorf("UVCategory(%v) = %q; want %q", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestEPAUVClient(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "epa_uv_new_york.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, _ := time.LoadLocation("America/New_York")
+	tests := []struct {
+		name    string
+		status  int
+		at      time.Time
+		want    float64
+		wantErr bool
+	}{
+		{"start of an hour", http.StatusOK, time.Date(2024, 6, 20, 12, 0, 0, 0, newYork), 9, false},
+		// The EPA writes months in either case
+		{"within an hour", http.StatusOK, time.Date(2024, 6, 20, 13, 59, 0, 0, newYork), 10, false},
+		{"UTC instant", http.StatusOK, time.Date(2024, 6, 20, 17, 30, 0, 0, time.UTC).In(newYork), 10, false},
+		{"outside the forecast day", http.StatusOK, time.Date(2024, 6, 21, 13, 0, 0, 0, newYork), 0, true},
+		{"upstream failure", http.StatusInternalServerError, time.Date(2024, 6, 20, 13, 0, 0, 0, newYork), 0, true},
+	}
+	for _, tt := range tests {
+		var gotPath string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotPath = r.URL.EscapedPath()
+			w.WriteHeader(tt.status)
+			if tt.status == http.StatusOK {
+				w.Write(body)
+			}
+		}))
+		client := NewEPAUVClient(EPAUVConfig{BaseURL: server.URL + "/", HTTPClient: server.Client()})
+		got, err := client.UVIndex(context.Background(), "New York", "ny", tt.at)
+		server.Close()
+
+		if gotPath != "/getEnvirofactsUVHOURLY/CITY/NEW%20YORK/STATE/NY/JSON" {
+			t.Errorf("%s: requested %s", tt.name, gotPath)
+		}
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: UVIndex = %v, %v; want %v, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+		// Only a forecast without the hour is ErrNoUVForecast, which isn't logged
+		if noForecast := tt.status == http.StatusOK && tt.wantErr; errors.Is(err, ErrNoUVForecast) != noForecast {
+			t.Errorf("%s: error = %v; ErrNoUVForecast %v", tt.name, err, noForecast)
+		}
+	}
+}
+
+// fakeUVForecaster answers every lookup with index or err, counting them
+type fakeUVForecaster struct {
+	index   float64
+	err     error
+	lookups int
+	at      time.Time
+}
+
+func (f *fakeUVForecaster) UVIndex(_ context.Context, _, _ string, at time.Time) (float64, error) {
+	f.lookups++
+	f.at = at
+	return f.index, f.err
+}
+
+func TestGetWeatherUVIndex(t *testing.T) {
+	fetched := time.Now().Add(-10 * time.Minute)
+	newService := func(forecaster UVForecaster) (*WeatherService, WeatherStore) {
+		repo := newTestRepo(t)
+		err := repo.SaveToCache(context.Background(), &models.WeatherCache{
+			Latitude: 40.7128, Longitude: -74.006, Forecast: "Sunny", TempC: 28, TempF: 82.4, Timestamp: fetched,
+			City: "New York", State: "NY", TimeZone: "America/New_York",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewWeatherService(repo, NewNWSAPIClient(), WithUVForecaster(forecaster)), repo
+	}
+
+	forecaster := &fakeUVForecaster{index: 7}
+	service, repo := newService(forecaster)
+
+	// The default path never looks the UV index up
+	resp, err := service.GetWeather(context.Background(), 40.7128, -74.006)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.UVIndex != nil || resp.UVCategory != "" || forecaster.lookups != 0 {
+		t.Errorf("without include=uv: UV %v %q after %d lookups; want none", resp.UVIndex, resp.UVCategory, forecaster.lookups)
+	}
+
+	// It is looked up once for the hour the weather was fetched, then served
+	// from the cache with the weather
+	for i := 0; i < 2; i++ {
+		resp, err := service.GetWeatherWithOptions(context.Background(), 40.7128, -74.006, WeatherOptions{IncludeUV: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.UVIndex == nil || *resp.UVIndex != 7 || resp.UVCategory != UVHigh || resp.Forecast != "Sunny" {
+			t.Errorf("request %d: UV %v %q, forecast %q; want 7 (high) with the cached weather", i+1, resp.UVIndex, resp.UVCategory, resp.Forecast)
+		}
+	}
+	if forecaster.lookups != 1 || !forecaster.at.Equal(fetched) || forecaster.at.Location().String() != "America/New_York" {
+		t.Errorf("%d lookups at %v; want 1 at the fetch time in New York", forecaster.lookups, forecaster.at)
+	}
+	if cached, err := repo.GetFromCacheAt(context.Background(), repository.NewCoordinates(40.7128, -74.006)); err != nil || cached.UVIndex == nil || *cached.UVIndex != 7 {
+		t.Errorf("cached entry = %+v, %v; want the UV index saved with it", cached, err)
+	}
+
+	// A failed lookup omits the UV index without failing the weather
+	for _, tt := range []struct {
+		name       string
+		forecaster UVForecaster
+	}{
+		{"upstream failure", &fakeUVForecaster{err: errors.New("connection refused")}},
+		{"no forecast for the city", &fakeUVForecaster{err: ErrNoUVForecast}},
+		{"not configured", nil},
+	} {
+		service, repo := newService(tt.forecaster)
+		resp, err := service.GetWeatherWithOptions(context.Background(), 40.7128, -74.006, WeatherOptions{IncludeUV: true})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if resp.UVIndex != nil || resp.UVCategory != "" || resp.Forecast != "Sunny" {
+			t.Errorf("%s: UV %v %q, forecast %q; want the weather without UV", tt.name, resp.UVIndex, resp.UVCategory, resp.Forecast)
+		}
+		if cached, err := repo.GetFromCacheAt(context.Background(), repository.NewCoordinates(40.7128, -74.006)); err != nil || cached.UVIndex != nil {
+			t.Errorf("%s: cached entry = %+v, %v; want no UV index saved", tt.name, cached, err)
+		}
+	}
+}
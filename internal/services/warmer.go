@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+const (
+	// DefaultWarmInterval is how often the cache warmer checks its locations
+	DefaultWarmInterval = time.Minute
+	// DefaultWarmLead is how long before a location's cached forecast expires
+	// that the warmer refreshes it
+	DefaultWarmLead = 5 * time.Minute
+	// DefaultWarmStagger is the pause between the warmer's refreshes, so a
+	// pass doesn't send the NWS a burst of requests
+	DefaultWarmStagger = 2 * time.Second
+)
+
+// WarmerConfig controls the cache warmer
+type WarmerConfig struct {
+	// Locations are kept fresh in addition to those in the warm_locations table
+	Locations []models.Coordinates
+	// Lead is how long before a cached forecast expires that it is refreshed.
+	// It is capped at half the cache TTL.
+	Lead time.Duration
+	// Stagger is the pause between refreshes
+	Stagger time.Duration
+}
+
+// CacheWarmer refreshes the forecasts of configured locations shortly before
+// their cache entries expire, so requests for them don't wait on the NWS
+type CacheWarmer struct {
+	repo    *repository.WeatherRepository
+	weather *WeatherService
+	cfg     WarmerConfig
+	now     func() time.Time
+
+	mu sync.Mutex
+	// status holds each location's last refresh by WeatherKey
+	status map[string]warmStatus
+}
+
+// warmStatus is the outcome of a location's last refreshes
+type warmStatus struct {
+	lastWarmedAt *time.Time
+	lastError    string
+}
+
+// NewCacheWarmer creates the cache warmer, refreshing forecasts through weather
+func NewCacheWarmer(repo *repository.WeatherRepository, weather *WeatherService, cfg WarmerConfig) *CacheWarmer {
+	if cfg.Lead <= 0 {
+		cfg.Lead = DefaultWarmLead
+	}
+	if cfg.Stagger < 0 {
+		cfg.Stagger = 0
+	}
+	return &CacheWarmer{repo: repo, weather: weather, cfg: cfg, now: time.Now, status: make(map[string]warmStatus)}
+}
+
+// Locations returns the configured locations, then those added to the
+// warm_locations table, each with its last refresh. A location listed both
+// ways is reported once, as configured.
+func (w *CacheWarmer) Locations(ctx context.Context) ([]models.WarmLocation, error) {
+	added, err := w.repo.ListWarmLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	locations := make([]models.WarmLocation, 0, len(w.cfg.Locations)+len(added))
+	seen := make(map[string]bool, cap(locations))
+	add := func(c models.Coordinates, source string) {
+		key := WeatherKey(c.Latitude, c.Longitude)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		status := w.status[key]
+		locations = append(locations, models.WarmLocation{
+			Latitude:     c.Latitude,
+			Longitude:    c.Longitude,
+			Source:       source,
+			LastWarmedAt: status.lastWarmedAt,
+			LastError:    status.lastError,
+		})
+	}
+	for _, c := range w.cfg.Locations {
+		add(c, models.WarmSourceConfig)
+	}
+	for _, c := range added {
+		add(c, models.WarmSourceAdmin)
+	}
+	return locations, nil
+}
+
+// AddLocation adds a location to the warm_locations table; it is first
+// refreshed on the warmer's next pass
+func (w *CacheWarmer) AddLocation(ctx context.Context, lat, lon float64) error {
+	return w.repo.SaveWarmLocation(ctx, lat, lon)
+}
+
+// RemoveLocation removes a location from the warm_locations table, returning
+// repository.ErrWarmLocationNotFound when it isn't there. Configured locations
+// can't be removed.
+func (w *CacheWarmer) RemoveLocation(ctx context.Context, lat, lon float64) error {
+	return w.repo.DeleteWarmLocation(ctx, lat, lon)
+}
+
+// RunOnce refreshes each location whose cached forecast is missing or expires
+// within the lead time, pausing between refreshes. A refresh that fails is
+// logged and retried on the next pass. It stops early when ctx is cancelled.
+func (w *CacheWarmer) RunOnce(ctx context.Context) error {
+	locations, err := w.Locations(ctx)
+	if err != nil {
+		return err
+	}
+
+	ttl := w.repo.CacheTTL()
+	lead := w.cfg.Lead
+	if lead > ttl/2 {
+		lead = ttl / 2
+	}
+	// Forecasts fetched before this expire within the lead time
+	threshold := w.now().Add(lead - ttl)
+
+	refreshed := 0
+	for _, loc := range locations {
+		// A nearby coordinate's entry doesn't keep this one's warm
+		cached, err := w.repo.GetFromCache(ctx, loc.Latitude, loc.Longitude)
+		if err == nil && cached.DistanceKm == 0 && !cached.Timestamp.Before(threshold) {
+			continue
+		}
+		if refreshed > 0 && w.cfg.Stagger > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(w.cfg.Stagger):
+			}
+		}
+		refreshed++
+
+		err = w.weather.RefreshWeather(ctx, loc.Latitude, loc.Longitude, threshold)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		key := WeatherKey(loc.Latitude, loc.Longitude)
+		w.mu.Lock()
+		status := w.status[key]
+		if err != nil {
+			status.lastError = err.Error()
+			log.Printf("Warming the cache for %s failed: %v", key, err)
+		} else {
+			now := w.now()
+			status = warmStatus{lastWarmedAt: &now}
+		}
+		w.status[key] = status
+		w.mu.Unlock()
+	}
+	return nil
+}
+
+// Run checks the locations immediately and then every interval until ctx is cancelled
+func (w *CacheWarmer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Cache warming failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
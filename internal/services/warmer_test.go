@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+func TestCacheWarmer(t *testing.T) {
+	server, hits := fakeGridNWS(t, http.StatusOK)
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	repo := repository.NewWeatherRepository(db, nil, repository.WithCacheTTL(2*time.Second))
+	warmer := NewCacheWarmer(repo, NewWeatherService(repo, newTestNWSClient(server)), WarmerConfig{
+		// Two coordinates in grid cell OKX/33,35
+		Locations: []models.Coordinates{{Latitude: 40.7128, Longitude: -74.006}, {Latitude: 40.72, Longitude: -74.01}},
+		Lead:      time.Second,
+		Stagger:   50 * time.Millisecond,
+	})
+	// One in OKX/40,40, and a configured one again
+	for _, c := range []models.Coordinates{{Latitude: 41.5, Longitude: -74}, {Latitude: 40.72, Longitude: -74.01}} {
+		if err := warmer.AddLocation(context.Background(), c.Latitude, c.Longitude); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fetches := func() (int32, int32) {
+		return atomic.LoadInt32(hits["OKX/33,35"]), atomic.LoadInt32(hits["OKX/40,40"])
+	}
+	start := time.Now()
+	if err := warmer.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// Three refreshes are staggered twice
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("pass took %v; want the refreshes staggered", elapsed)
+	}
+	// A grid cell's forecast is fetched once for both its coordinates
+	if a, b := fetches(); a != 1 || b != 1 {
+		t.Errorf("forecast fetches = %d, %d; want 1 per grid cell", a, b)
+	}
+	for _, c := range []models.Coordinates{{Latitude: 40.7128, Longitude: -74.006}, {Latitude: 40.72, Longitude: -74.01}, {Latitude: 41.5, Longitude: -74}} {
+		cached, err := repo.GetFromCache(context.Background(), c.Latitude, c.Longitude)
+		if err != nil || !repo.IsCacheFresh(cached) {
+			t.Errorf("%v not warmed: %v", c, err)
+		}
+	}
+
+	locations, err := warmer.Locations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locations) != 3 || locations[1].Source != models.WarmSourceConfig || locations[2].Source != models.WarmSourceAdmin {
+		t.Fatalf("locations = %+v; want the 2 configured then the 1 added", locations)
+	}
+	for _, loc := range locations {
+		if loc.LastWarmedAt == nil || loc.LastError != "" {
+			t.Errorf("location %+v; want a last warm time", loc)
+		}
+	}
+
+	// Fresh entries are left alone until they are within the lead time of expiring
+	if err := warmer.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := fetches(); a != 1 || b != 1 {
+		t.Errorf("forecast fetches after a pass over fresh entries = %d, %d; want no more", a, b)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if err := warmer.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := fetches(); a != 2 || b != 2 {
+		t.Errorf("forecast fetches after the lead time = %d, %d; want each cell refetched once", a, b)
+	}
+
+	if err := warmer.RemoveLocation(context.Background(), 41.5, -74); err != nil {
+		t.Fatal(err)
+	}
+	if err := warmer.RemoveLocation(context.Background(), 40.7128, -74.006); !errors.Is(err, repository.ErrWarmLocationNotFound) {
+		t.Errorf("removing a configured location error = %v; want ErrWarmLocationNotFound", err)
+	}
+}
+
+func TestCacheWarmerStopsOnCancel(t *testing.T) {
+	server, hits := fakeGridNWS(t, http.StatusOK)
+	repo := newTestRepo(t)
+	warmer := NewCacheWarmer(repo, NewWeatherService(repo, newTestNWSClient(server)), WarmerConfig{
+		Locations: []models.Coordinates{{Latitude: 40.7128, Longitude: -74.006}, {Latitude: 41.5, Longitude: -74}},
+		Stagger:   time.Hour,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		warmer.Run(ctx, time.Hour)
+		close(done)
+	}()
+	// The first location is refreshed, then the warmer waits out the stagger
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(hits["OKX/33,35"]) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run didn't return after cancellation")
+	}
+	if n := atomic.LoadInt32(hits["OKX/40,40"]); n != 0 {
+		t.Errorf("second location fetched %d times; want the pass stopped first", n)
+	}
+}
@@ -1,69 +1,639 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"weather-api-go/internal/metrics"
 	"weather-api-go/internal/models"
+	"weather-api-go/internal/pubsub"
 	"weather-api-go/internal/repository"
+	"weather-api-go/internal/units"
 )
 
 // WeatherService handles weather-related business logic
 type WeatherService struct {
-	repo      *repository.WeatherRepository
-	nwsClient *NWSAPIClient
+	repo WeatherStore
+	// provider fetches current forecasts
+	provider WeatherProvider
+	// fallback serves coordinates outside the provider's coverage; nil for none
+	fallback WeatherProvider
+	// nwsClient serves the NWS-specific features; nil when there is no NWS
+	nwsClient  *NWSAPIClient
+	advisories AdvisoryThresholds
+	// temperature classifies readings as hot, cold, or moderate; the zero value uses the defaults
+	temperature TemperatureThresholds
+	limiter     *upstreamLimiter
+	metrics     *metrics.Recorder
+	// coverageCheck rejects coordinates outside NWS coverage before any upstream call
+	coverageCheck bool
+	// batchConcurrency bounds the coordinates of a batch looked up at once
+	batchConcurrency int
+	// geocoder resolves place names for ?city= and ?q= lookups; nil disables them
+	geocoder Geocoder
+	// zipResolver resolves ?zip= lookups; nil disables them
+	zipResolver ZIPResolver
+	// ipLocator estimates the location of callers who give none; nil disables it
+	ipLocator IPLocator
+	// uvForecaster looks up the UV index for ?include=uv; nil disables it
+	uvForecaster UVForecaster
+	// airQuality looks up current air quality for /airquality; nil disables it
+	airQuality AirQualityProvider
+	// flights coalesces concurrent cache misses for the same coordinate into
+	// one fetch; a pointer so copies made by RefreshWeather share it
+	flights *singleflight.Group
+	// fetchContexts holds the contexts of the fetches in flights, which end
+	// once no caller is waiting on them
+	fetchContexts *fetchContexts
+	// maxStale is how old expired weather may be to be served while it is
+	// refreshed in the background; zero disables stale-while-revalidate
+	maxStale time.Duration
+	// refreshing holds the coordinate keys with a background refresh running
+	refreshing *sync.Map
+	// refreshBefore makes cached grid forecasts fetched before it count as
+	// expired, so RefreshWeather refetches them while they are still fresh
+	refreshBefore time.Time
+}
+
+// WeatherServiceOption configures optional WeatherService behavior
+type WeatherServiceOption func(*WeatherService)
+
+// WithAdvisoryThresholds overrides the default frost and heat advisory thresholds
+func WithAdvisoryThresholds(t AdvisoryThresholds) WeatherServiceOption {
+	return func(s *WeatherService) {
+		s.advisories = t
+	}
 }
 
-// NewWeatherService creates a new weather service
-func NewWeatherService(repo *repository.WeatherRepository, nwsClient *NWSAPIClient) *WeatherService {
-	return &WeatherService{
-		repo:      repo,
-		nwsClient: nwsClient,
+// WithTemperatureThresholds overrides the default hot and cold classification thresholds
+func WithTemperatureThresholds(t TemperatureThresholds) WeatherServiceOption {
+	return func(s *WeatherService) {
+		s.temperature = t
 	}
 }
 
+// DefaultMaxStale is the suggested stale-while-revalidate window: expired
+// weather up to this old is served while it is refreshed
+const DefaultMaxStale = 6 * time.Hour
+
+// WithStaleWhileRevalidate serves expired weather up to maxStale old
+// immediately, refreshing it in the background instead of blocking on the NWS
+func WithStaleWhileRevalidate(maxStale time.Duration) WeatherServiceOption {
+	return func(s *WeatherService) {
+		s.maxStale = maxStale
+	}
+}
+
+// WithLoadShedding bounds concurrent upstream requests and sheds cache misses
+// that would queue beyond the configured depth or wait
+func WithLoadShedding(cfg LoadSheddingConfig) WeatherServiceOption {
+	return func(s *WeatherService) {
+		if cfg.MaxInFlight > 0 {
+			s.limiter = newUpstreamLimiter(cfg)
+		}
+	}
+}
+
+// WithMetrics records service counters, such as shed requests, on the given recorder
+func WithMetrics(recorder *metrics.Recorder) WeatherServiceOption {
+	return func(s *WeatherService) {
+		s.metrics = recorder
+	}
+}
+
+// WeatherOptions selects optional parts of a weather response
+type WeatherOptions struct {
+	IncludeAdvisories bool
+	// IncludeDetailed adds the provider's narrative forecast, when it has one
+	IncludeDetailed bool
+	// IncludeUV adds the UV index and its category, looked up and cached with
+	// the weather the first time; ignored with At
+	IncludeUV bool
+	// At requests the forecast for a future instant instead of the current period
+	At *ForecastTime
+	// Units is the unit system to report values in (units.Metric, units.Imperial,
+	// or units.Both); empty reports both
+	Units string
+	// IconSize resizes the forecast icon (IconSmall, IconMedium, or IconLarge);
+	// empty keeps the size the NWS gave
+	IconSize string
+	// WindUnit reports the wind speed in one unit (units.MPH, units.KMH,
+	// units.MS, or units.KN) instead of the unit system's; empty follows Units
+	WindUnit string
+	// TimeZone overrides the zone local times are given in; nil uses the
+	// location's own
+	TimeZone *time.Location
+}
+
+// NewWeatherService creates a new weather service fetching forecasts from
+// provider. When the provider is the NWS it also serves the NWS-specific
+// features; otherwise those need WithNWSFeatures.
+func NewWeatherService(repo WeatherStore, provider WeatherProvider, opts ...WeatherServiceOption) *WeatherService {
+	nwsClient, _ := provider.(*NWSAPIClient)
+	s := &WeatherService{
+		repo:             repo,
+		provider:         provider,
+		nwsClient:        nwsClient,
+		advisories:       DefaultAdvisoryThresholds(),
+		coverageCheck:    true,
+		batchConcurrency: DefaultBatchConcurrency,
+		flights:          new(singleflight.Group),
+		refreshing:       new(sync.Map),
+	}
+	s.fetchContexts = newFetchContexts(s.flights.Forget)
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// TemperatureThresholds configures how temperatures are characterized
+type TemperatureThresholds struct {
+	// HotC is the temperature at or above which a reading is hot
+	HotC float64
+	// ColdC is the temperature at or below which a reading is cold
+	ColdC float64
+}
+
+// DefaultTemperatureThresholds returns the standard hot (30°C) and cold (10°C) thresholds
+func DefaultTemperatureThresholds() TemperatureThresholds {
+	return TemperatureThresholds{HotC: 30.0, ColdC: 10.0}
+}
+
+// Validate checks that the thresholds leave a moderate band between cold and hot
+func (t TemperatureThresholds) Validate() error {
+	if t.HotC <= t.ColdC {
+		return fmt.Errorf("hot threshold (%.1f°C) must be above cold threshold (%.1f°C)", t.HotC, t.ColdC)
+	}
+	return nil
+}
+
+// TemperatureThresholds returns the effective hot and cold thresholds
+func (s *WeatherService) TemperatureThresholds() TemperatureThresholds {
+	if s.temperature == (TemperatureThresholds{}) {
+		return DefaultTemperatureThresholds()
+	}
+	return s.temperature
+}
+
 // GetTemperatureCharacterization categorizes temperature as hot, cold, or moderate
 func (s *WeatherService) GetTemperatureCharacterization(tempC float64) string {
-	if tempC >= 30.0 {
+	t := s.TemperatureThresholds()
+	if tempC >= t.HotC {
 		return "hot"
-	} else if tempC <= 10.0 {
+	} else if tempC <= t.ColdC {
 		return "cold"
 	}
 	return "moderate"
 }
 
+// Provenance of a weather response beyond the cache tiers it can be read from
+// (repository.SourceRedis, repository.SourceMemory, repository.SourceSQLite,
+// and repository.SourcePostgres)
+const (
+	// SourceLive marks data fetched from the NWS for the request
+	SourceLive = "live"
+	// SourceStale marks expired cached data served because the NWS fetch failed
+	SourceStale = "stale"
+)
+
 // GetWeather retrieves weather data with caching
-func (s *WeatherService) GetWeather(lat, lon float64) (*models.WeatherResponse, error) {
+func (s *WeatherService) GetWeather(ctx context.Context, lat, lon float64) (*models.WeatherResponse, error) {
+	return s.GetWeatherWithOptions(ctx, lat, lon, WeatherOptions{})
+}
+
+// GetWeatherWithOptions retrieves weather data with caching and the requested
+// optional sections. The response's Source reports where the data came from.
+func (s *WeatherService) GetWeatherWithOptions(ctx context.Context, lat, lon float64, opts WeatherOptions) (*models.WeatherResponse, error) {
+	return s.GetWeatherFor(ctx, repository.NewCoordinates(lat, lon), opts)
+}
+
+// GetWeatherFor is GetWeatherWithOptions for coordinates the caller has
+// already parsed, which are looked up in the cache without being normalized
+// and keyed again
+func (s *WeatherService) GetWeatherFor(ctx context.Context, c repository.Coordinates, opts WeatherOptions) (*models.WeatherResponse, error) {
+	if opts.At != nil {
+		resp, err := s.getWeatherAt(ctx, c.Latitude, c.Longitude, *opts.At, opts)
+		if errors.Is(err, ErrUpstreamSaturated) && s.metrics != nil {
+			s.metrics.Inc(metrics.RequestsShed)
+		}
+		return resp, classifyFailure(err)
+	}
+
 	// Try to get from cache
-	cachedWeather, err := s.repo.GetFromCache(lat, lon)
-	if err == nil && s.repo.IsCacheFresh(cachedWeather) {
-		return &models.WeatherResponse{
-			Forecast:     cachedWeather.Forecast,
-			Temperature:  s.GetTemperatureCharacterization(cachedWeather.TempC),
-			TemperatureC: cachedWeather.TempC,
-			TemperatureF: cachedWeather.TempF,
-		}, nil
-	}
-
-	// Fetch fresh data from NWS
-	weather, err := s.nwsClient.GetForecast(lat, lon)
+	cachedWeather, lookupErr := s.getCached(ctx, c)
+	if lookupErr == nil && s.repo.IsCacheFresh(cachedWeather) {
+		resp := s.respond(ctx, cachedWeather, opts)
+		resp.FreshUntil = cachedWeather.Timestamp.Add(s.repo.CacheTTL())
+		resp.CacheHit = true
+		setProvenance(resp, cachedWeather.Source, cachedWeather.Timestamp)
+		return resp, nil
+	}
+
+	// Serve recently expired data without waiting on the NWS
+	if lookupErr == nil && time.Since(cachedWeather.Timestamp) < s.maxStale {
+		s.revalidate(ctx, c)
+		resp := s.respond(ctx, cachedWeather, opts)
+		setProvenance(resp, SourceStale, cachedWeather.Timestamp)
+		return resp, nil
+	}
+
+	weather, source, err := s.fetchWeather(ctx, c)
 	if err != nil {
 		// Return stale cache if available
 		if cachedWeather != nil {
-			return &models.WeatherResponse{
-				Forecast:     cachedWeather.Forecast,
-				Temperature:  s.GetTemperatureCharacterization(cachedWeather.TempC),
-				TemperatureC: cachedWeather.TempC,
-				TemperatureF: cachedWeather.TempF,
-			}, nil
+			resp := s.respond(ctx, cachedWeather, opts)
+			setProvenance(resp, SourceStale, cachedWeather.Timestamp)
+			return resp, nil
+		}
+		if errors.Is(err, ErrUpstreamSaturated) && s.metrics != nil {
+			s.metrics.Inc(metrics.RequestsShed)
+		}
+		if cacheErr := cacheFailure(lookupErr); cacheErr != nil {
+			return nil, errors.Join(err, cacheErr)
 		}
 		return nil, err
 	}
 
-	// Save to cache (ignore errors, don't fail the request)
-	_ = s.repo.SaveToCache(weather)
+	resp := s.respond(ctx, weather, opts)
+	resp.FreshUntil = weather.Timestamp.Add(s.repo.CacheTTL())
+	switch source {
+	case repository.SourceRedis, repository.SourceMemory, repository.SourceSQLite, repository.SourcePostgres:
+		resp.CacheHit = true
+	}
+	setProvenance(resp, source, weather.Timestamp)
+	return resp, nil
+}
+
+// SubscribeWeather returns a subscription that receives a coordinate's cache
+// entry each time it is refreshed; WeatherUpdate turns the entries into
+// responses. The subscription must be closed when no longer needed.
+func (s *WeatherService) SubscribeWeather(lat, lon float64) *pubsub.Subscription[models.WeatherCache] {
+	return s.repo.SubscribeCache(lat, lon)
+}
+
+// WeatherUpdate builds the response for an entry received from SubscribeWeather,
+// as GetWeatherWithOptions serves a freshly fetched entry. Only the current
+// period is described, so opts.At is ignored.
+func (s *WeatherService) WeatherUpdate(ctx context.Context, entry models.WeatherCache, opts WeatherOptions) *models.WeatherResponse {
+	resp := s.buildResponse(ctx, &entry, opts)
+	s.setPeriodAdvisories(ctx, resp, &entry, opts)
+	resp.FreshUntil = entry.Timestamp.Add(s.repo.CacheTTL())
+	setProvenance(resp, SourceLive, entry.Timestamp)
+	return resp
+}
+
+// fetchedWeather is the result of a coalesced weather fetch
+type fetchedWeather struct {
+	weather *models.WeatherCache
+	source  string
+}
+
+// backgroundRefreshTimeout bounds a background refresh, independent of the
+// request that triggered it
+const backgroundRefreshTimeout = 30 * time.Second
+
+// revalidate refreshes a coordinate's expired weather in the background. At
+// most one refresh runs per coordinate, and it also coalesces with foreground
+// fetches of the coordinate. The refresh outlives the request that found the
+// entry expired, so it runs under a context carrying the request's trace but
+// not its cancellation.
+func (s *WeatherService) revalidate(ctx context.Context, c repository.Coordinates) {
+	if _, running := s.refreshing.LoadOrStore(c.Key, struct{}{}); running {
+		return
+	}
+	go func() {
+		defer s.refreshing.Delete(c.Key)
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backgroundRefreshTimeout)
+		defer cancel()
+		if _, _, err := s.fetchWeather(ctx, c); err != nil {
+			log.Printf("Background weather refresh for %s failed: %v", c.Key, err)
+		}
+	}()
+}
+
+// errRefreshStale reports a refresh whose upstream fetch failed, leaving the
+// cached forecast in place
+var errRefreshStale = errors.New("forecast could not be refetched; the cached forecast was kept")
+
+// RefreshWeather fetches and caches a coordinate's weather whether or not its
+// cache entry is fresh. A cached grid forecast is reused only if it was fetched
+// at or after fetchedAfter, so coordinates sharing a grid cell refetch it once.
+func (s *WeatherService) RefreshWeather(ctx context.Context, lat, lon float64, fetchedAfter time.Time) error {
+	refresh := *s
+	refresh.refreshBefore = fetchedAfter
+	_, source, err := refresh.fetchWeather(ctx, repository.NewCoordinates(lat, lon))
+	if err == nil && source == SourceStale {
+		return errRefreshStale
+	}
+	return err
+}
+
+// WeatherKey identifies a coordinate at the precision its weather is cached at
+func WeatherKey(lat, lon float64) string {
+	return repository.NewCoordinates(lat, lon).Key
+}
 
-	return &models.WeatherResponse{
-		Forecast:     weather.Forecast,
-		Temperature:  s.GetTemperatureCharacterization(weather.TempC),
-		TemperatureC: weather.TempC,
-		TemperatureF: weather.TempF,
-	}, nil
+// fetchWeather fetches and caches the weather for a coordinate whose cache
+// entry is missing or expired, recording its temperature change since the
+// entry it replaces. Concurrent calls for the same coordinate share one fetch
+// and one cache write; the shared result must not be modified. Each caller
+// stops waiting when its own ctx is done, while the shared fetch runs until
+// the last of them has (see fetchContexts).
+func (s *WeatherService) fetchWeather(ctx context.Context, c repository.Coordinates) (*models.WeatherCache, string, error) {
+	fetchCtx, leave := s.fetchContexts.join(ctx, c.Key)
+	defer leave()
+	results := s.flights.DoChan(c.Key, func() (interface{}, error) {
+		weather, source, err := s.providerForecast(fetchCtx, c.Latitude, c.Longitude)
+		if err != nil {
+			return nil, classifyFailure(err)
+		}
+		s.setTemperatureChange(fetchCtx, weather)
+		// Save to cache (ignore errors, don't fail the request)
+		_ = s.repo.SaveToCache(fetchCtx, weather)
+		return fetchedWeather{weather: weather, source: source}, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, "", classifyFailure(ctx.Err())
+	case result := <-results:
+		if result.Err != nil {
+			return nil, "", result.Err
+		}
+		fetched := result.Val.(fetchedWeather)
+		return fetched.weather, fetched.source, nil
+	}
+}
+
+// setTemperatureChange sets a fetched entry's change in temperature since
+// the coordinate's previously cached entry. Entries cached for nearby
+// coordinates don't count, and a refetch of the same forecast, such as a
+// stale grid forecast, keeps the change already recorded for it.
+func (s *WeatherService) setTemperatureChange(ctx context.Context, weather *models.WeatherCache) {
+	previous, err := s.repo.GetFromCacheAt(ctx, repository.NewCoordinates(weather.Latitude, weather.Longitude))
+	if err != nil || previous.DistanceKm > 0 {
+		return
+	}
+	switch {
+	case previous.Timestamp.Before(weather.Timestamp):
+		change := math.Round((weather.TempC-previous.TempC)*10) / 10
+		if change == 0 {
+			change = 0 // avoid "-0"
+		}
+		weather.TemperatureChangeC = &change
+	case previous.Timestamp.Equal(weather.Timestamp):
+		weather.TemperatureChangeC = previous.TemperatureChangeC
+	}
+}
+
+// setProvenance records where a response's data came from and when it was
+// fetched, in UTC and in the response's time zone
+func setProvenance(resp *models.WeatherResponse, source string, fetchedAt time.Time) {
+	resp.Source = source
+	if !fetchedAt.IsZero() {
+		cachedAt := fetchedAt.UTC()
+		resp.CachedAt = &cachedAt
+		resp.CachedAtLocal = localTime(cachedAt, loadZone(resp.TimeZone))
+	}
+}
+
+// getGridForecast returns the forecast for the NWS grid cell containing a
+// coordinate, reusing a fresh cached forecast for the cell when one exists.
+// If the upstream fetch fails, a stale forecast for the cell is returned instead.
+// The string reports the forecast's provenance: the cache tier of a fresh cached
+// forecast, SourceLive, or SourceStale.
+func (s *WeatherService) getGridForecast(ctx context.Context, nws *NWSAPIClient, lat, lon float64) (*models.WeatherCache, string, error) {
+	point, err := s.resolveGridPointWith(ctx, nws, lat, lon)
+	if err != nil {
+		return nil, "", err
+	}
+
+	forecast, err := s.repo.GetGridForecast(ctx, point.GridID, point.GridX, point.GridY)
+	var source string
+	if err == nil && s.repo.IsCacheFresh(forecast) && !forecast.Timestamp.Before(s.refreshBefore) {
+		source = forecast.Source
+	} else {
+		var fresh *models.WeatherCache
+		fetch := func() (err error) {
+			fresh, err = nws.GetGridForecast(ctx, point.ForecastURL)
+			return err
+		}
+		fetchErr := s.upstream(fetch)
+		if errors.Is(fetchErr, ErrForecastNotFound) {
+			// The cached mapping points at a retired forecast URL; resolve the
+			// coordinate again and retry once if that moved it
+			if moved, err := s.reresolveGridPoint(ctx, nws, lat, lon); err == nil && moved.ForecastURL != point.ForecastURL {
+				point = moved
+				fetchErr = s.upstream(fetch)
+			}
+		}
+		if fetchErr != nil {
+			if forecast == nil {
+				return nil, "", fetchErr
+			}
+			source = SourceStale
+		} else {
+			forecast = fresh
+			source = SourceLive
+			s.saveGridForecast(ctx, point, forecast)
+		}
+	}
+
+	weather := *forecast
+	weather.Latitude = lat
+	weather.Longitude = lon
+	weather.City = point.City
+	weather.State = point.State
+	weather.TimeZone = point.TimeZone
+	weather.GridID, weather.GridX, weather.GridY = point.GridID, point.GridX, point.GridY
+	return &weather, source, nil
+}
+
+// resolveGridPoint maps a coordinate to its NWS grid cell, caching the mapping
+// under the coordinate normalized to the precision the NWS resolves points at
+func (s *WeatherService) resolveGridPoint(ctx context.Context, lat, lon float64) (*models.GridPoint, error) {
+	if err := s.requireNWS(); err != nil {
+		return nil, err
+	}
+	return s.resolveGridPointWith(ctx, s.nwsClient, lat, lon)
+}
+
+// resolveGridPointWith is resolveGridPoint using the given NWS client
+func (s *WeatherService) resolveGridPointWith(ctx context.Context, nws *NWSAPIClient, lat, lon float64) (*models.GridPoint, error) {
+	if err := s.checkCoverage(lat, lon); err != nil {
+		return nil, err
+	}
+	lat, lon = normalizePointCoordinate(lat), normalizePointCoordinate(lon)
+
+	cached, err := s.repo.GetGridPoint(ctx, lat, lon)
+	if err == nil && s.repo.IsGridPointFresh(cached) {
+		return cached, nil
+	}
+	if err := s.checkUncovered(ctx, lat, lon); err != nil {
+		return nil, err
+	}
+
+	point, err := s.fetchGridPoint(ctx, nws, lat, lon)
+	if err != nil {
+		// Grid assignments rarely change, so an expired mapping beats failing
+		if cached != nil {
+			return cached, nil
+		}
+		return nil, err
+	}
+	return point, nil
+}
+
+// fetchGridPoint asks the NWS points endpoint for a normalized coordinate's
+// grid mapping through the upstream limiter, recording a definitive
+// out-of-coverage answer and caching the mapping with its points document.
+// Callers check coverage and uncovered entries first, as
+// resolveGridPointWith does.
+func (s *WeatherService) fetchGridPoint(ctx context.Context, nws *NWSAPIClient, lat, lon float64) (*models.GridPoint, error) {
+	var point *models.GridPoint
+	err := s.upstream(func() (err error) {
+		point, err = nws.GetGridPoint(ctx, lat, lon)
+		return err
+	})
+	if err != nil {
+		s.recordCoverage(ctx, lat, lon, err)
+		return nil, err
+	}
+
+	s.saveGridPoint(ctx, point)
+	return point, nil
+}
+
+// reresolveGridPoint drops a coordinate's cached grid mapping and fetches it
+// again from the NWS points endpoint
+func (s *WeatherService) reresolveGridPoint(ctx context.Context, nws *NWSAPIClient, lat, lon float64) (*models.GridPoint, error) {
+	lat, lon = normalizePointCoordinate(lat), normalizePointCoordinate(lon)
+	_ = s.repo.DeleteGridPoint(ctx, lat, lon)
+
+	var point *models.GridPoint
+	err := s.upstream(func() (err error) {
+		point, err = nws.GetGridPoint(ctx, lat, lon)
+		return err
+	})
+	if err != nil {
+		s.recordCoverage(ctx, lat, lon, err)
+		return nil, err
+	}
+
+	log.Printf("Re-resolved grid point %.4f,%.4f after its forecast URL returned 404", lat, lon)
+	s.saveGridPoint(ctx, point)
+	return point, nil
+}
+
+// normalizePointCoordinate rounds a coordinate to the four decimal places (~11m)
+// the NWS points endpoint resolves at
+func normalizePointCoordinate(v float64) float64 {
+	return math.Round(v*1e4) / 1e4
+}
+
+// respond builds the response for a weather entry, first looking up the UV
+// index when it is included
+func (s *WeatherService) respond(ctx context.Context, weather *models.WeatherCache, opts WeatherOptions) *models.WeatherResponse {
+	if opts.IncludeUV {
+		weather = s.withUVIndex(ctx, weather)
+	}
+	resp := s.buildResponse(ctx, weather, opts)
+	s.setPeriodAdvisories(ctx, resp, weather, opts)
+	return resp
+}
+
+// setPeriodAdvisories sets a current forecast's advisories, computed over the
+// cached periods ahead, when they are included
+func (s *WeatherService) setPeriodAdvisories(ctx context.Context, resp *models.WeatherResponse, weather *models.WeatherCache, opts WeatherOptions) {
+	if opts.IncludeAdvisories {
+		resp.Advisories = ComputeAdvisories(s.advisorySamples(ctx, weather, time.Now()), s.advisories)
+	}
+}
+
+// buildResponse converts cached weather data into the API response shape
+func (s *WeatherService) buildResponse(ctx context.Context, weather *models.WeatherCache, opts WeatherOptions) *models.WeatherResponse {
+	resp := &models.WeatherResponse{
+		Forecast:    weather.Forecast,
+		PeriodName:  weather.PeriodName,
+		Icon:        ResizeIcon(weather.Icon, opts.IconSize),
+		Temperature: s.GetTemperatureCharacterization(weather.TempC),
+		Location:    formatLocation(weather.City, weather.State),
+		TimeZone:    zoneName(responseZone(weather.TimeZone, opts.TimeZone)),
+		Provider:    weather.Provider,
+	}
+	if resp.Provider == "" {
+		resp.Provider = ProviderNWS
+	}
+	resp.IsDaytime = weather.IsDaytime
+	if resp.IsDaytime == nil {
+		daytime := isDaytimeAt(weather.Timestamp, weather.Longitude)
+		resp.IsDaytime = &daytime
+	}
+	if weather.DistanceKm > 0 {
+		resp.CachedPoint = &models.CachedPoint{
+			Latitude:   weather.Latitude,
+			Longitude:  weather.Longitude,
+			DistanceKm: math.Round(weather.DistanceKm*100) / 100,
+		}
+	}
+	if units.IncludesMetric(opts.Units) {
+		tempC := weather.TempC
+		resp.TemperatureC = &tempC
+		resp.WindSpeedKmh = weather.WindKmh
+		resp.DewpointC = weather.DewpointC
+	}
+	if units.IncludesImperial(opts.Units) {
+		tempF := weather.TempF
+		resp.TemperatureF = &tempF
+		resp.WindSpeedMph = weather.WindMph
+		resp.DewpointF = weather.DewpointF
+	}
+	if opts.WindUnit != "" {
+		resp.WindSpeedKmh, resp.WindSpeedMph = nil, nil
+		resp.WindSpeed = convertSpeedRange(weather.WindKmh, units.KMH, opts.WindUnit)
+		if resp.WindSpeed != nil {
+			resp.WindUnit = opts.WindUnit
+		}
+	}
+	resp.TemperatureTrend = weather.TemperatureTrend
+	resp.TemperatureChangeC = weather.TemperatureChangeC
+	resp.WindDirection = weather.WindDirection
+	resp.PrecipitationProbability = weather.PrecipitationProbability
+	resp.HumidityPercent = weather.RelativeHumidity
+
+	if opts.IncludeDetailed {
+		resp.DetailedForecast = weather.DetailedForecast
+	}
+	if opts.IncludeUV && weather.UVIndex != nil {
+		resp.UVIndex = weather.UVIndex
+		resp.UVCategory = UVCategory(*weather.UVIndex)
+	}
+
+	return resp
+}
+
+// isDaytimeAt estimates whether it is daytime at a longitude, by local solar
+// time between 06:00 and 18:00, for forecasts that don't say: those from
+// providers without day and night periods and entries cached before it was
+// recorded
+func isDaytimeAt(t time.Time, lon float64) bool {
+	utc := t.UTC()
+	hour := float64(utc.Hour()) + float64(utc.Minute())/60 + lon/15
+	hour = math.Mod(hour+24, 24)
+	return hour >= 6 && hour < 18
+}
+
+// formatLocation labels a coordinate "City, ST", or just the city when the
+// state is unknown
+func formatLocation(city, state string) string {
+	if city == "" || state == "" {
+		return city
+	}
+	return city + ", " + state
 }
@@ -1,7 +1,24 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"weather-api-go/internal/metrics"
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+	"weather-api-go/internal/units"
 )
 
 func TestGetTemperatureCharacterization(t *testing.T) {
@@ -30,3 +47,866 @@ func TestGetTemperatureCharacterization(t *testing.T) {
 		})
 	}
 }
+
+func TestGetTemperatureCharacterizationCustomThresholds(t *testing.T) {
+	service := NewWeatherService(nil, nil, WithTemperatureThresholds(TemperatureThresholds{HotC: 25, ColdC: 0}))
+
+	if got := service.TemperatureThresholds(); got != (TemperatureThresholds{HotC: 25, ColdC: 0}) {
+		t.Errorf("TemperatureThresholds() = %+v; want {HotC:25 ColdC:0}", got)
+	}
+
+	tests := []struct {
+		tempC    float64
+		expected string
+	}{
+		{30.0, "hot"},
+		{25.0, "hot"},
+		{24.9, "moderate"},
+		{10.0, "moderate"},
+		{0.1, "moderate"},
+		{0.0, "cold"},
+		{-5.0, "cold"},
+	}
+	for _, tt := range tests {
+		if got := service.GetTemperatureCharacterization(tt.tempC); got != tt.expected {
+			t.Errorf("GetTemperatureCharacterization(%.1f) = %s; want %s", tt.tempC, got, tt.expected)
+		}
+	}
+}
+
+func TestTemperatureThresholdsValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds TemperatureThresholds
+		wantErr    bool
+	}{
+		{"defaults", DefaultTemperatureThresholds(), false},
+		{"custom", TemperatureThresholds{HotC: 35, ColdC: -5}, false},
+		{"equal", TemperatureThresholds{HotC: 20, ColdC: 20}, true},
+		{"inverted", TemperatureThresholds{HotC: 10, ColdC: 30}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.thresholds.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v; wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// fakeGridNWS serves points that map coordinates south of 41N to grid cell
+// OKX/33,35 and the rest to OKX/40,40, counting upstream requests by path
+func fakeGridNWS(t *testing.T, forecastStatus int) (*httptest.Server, map[string]*int32) {
+	t.Helper()
+	hits := map[string]*int32{"points": new(int32), "OKX/33,35": new(int32), "OKX/40,40": new(int32)}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/points/"):
+			atomic.AddInt32(hits["points"], 1)
+			var lat, lon float64
+			fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/points/"), "%f,%f", &lat, &lon)
+			x, y := 33, 35
+			if lat >= 41 {
+				x, y = 40, 40
+			}
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": %d, "gridY": %d, "forecast": "%s/gridpoints/OKX/%d,%d/forecast",
+				"relativeLocation": {"properties": {"city": "Newark", "state": "NJ"}}}}`,
+				x, y, server.URL, x, y)
+		case strings.HasPrefix(r.URL.Path, "/gridpoints/"):
+			cell := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/gridpoints/"), "/forecast")
+			atomic.AddInt32(hits[cell], 1)
+			if forecastStatus != http.StatusOK {
+				w.WriteHeader(forecastStatus)
+				return
+			}
+			fmt.Fprint(w, `{"properties": {"periods": [
+				{"name": "Tonight", "isDaytime": false, "shortForecast": "Partly Cloudy", "temperature": 72, "temperatureUnit": "F", "temperatureTrend": "rising"}
+			]}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, hits
+}
+
+func TestGetWeatherSharesGridCellForecast(t *testing.T) {
+	server, hits := fakeGridNWS(t, http.StatusOK)
+	service := NewWeatherService(newTestRepo(t), newTestNWSClient(server))
+
+	// Two points across a grid cell, farther apart than the nearby cache radius
+	first, err := service.GetWeather(context.Background(), 40.7128, -74.0060)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := service.GetWeather(context.Background(), 40.7340, -74.0200)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := atomic.LoadInt32(hits["OKX/33,35"]); got != 1 {
+		t.Errorf("forecast fetched %d times for a shared grid cell; want 1", got)
+	}
+	if got := atomic.LoadInt32(hits["points"]); got != 2 {
+		t.Errorf("points resolved %d times; want 2 (one per coordinate)", got)
+	}
+	if first.Forecast != second.Forecast || *first.TemperatureC != *second.TemperatureC {
+		t.Errorf("nearby coordinates got different forecasts: %+v vs %+v", first, second)
+	}
+	if !second.FreshUntil.Equal(first.FreshUntil) {
+		t.Errorf("reused grid forecast FreshUntil = %v; want the cell's %v", second.FreshUntil, first.FreshUntil)
+	}
+
+	// A coordinate in another cell does its own upstream fetch
+	if _, err := service.GetWeather(context.Background(), 41.5, -74.0); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(hits["OKX/40,40"]); got != 1 {
+		t.Errorf("forecast fetched %d times for a distinct grid cell; want 1", got)
+	}
+	if got := atomic.LoadInt32(hits["OKX/33,35"]); got != 1 {
+		t.Errorf("distinct grid cell refetched the first cell (%d fetches)", got)
+	}
+}
+
+func TestGetWeatherSource(t *testing.T) {
+	server, _ := fakeGridNWS(t, http.StatusOK)
+	service := NewWeatherService(newTestRepo(t), newTestNWSClient(server))
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     string
+		hit      bool
+	}{
+		{"cold", 40.7128, -74.0060, SourceLive, false},
+		{"warm", 40.7128, -74.0060, repository.SourceSQLite, true},
+		{"warm nearby", 40.7200, -74.0060, repository.SourceSQLite, true},
+		{"warm grid cell", 40.7340, -74.0200, repository.SourceSQLite, true},
+	}
+	for _, tt := range tests {
+		resp, err := service.GetWeather(context.Background(), tt.lat, tt.lon)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if resp.Source != tt.want || resp.CacheHit != tt.hit {
+			t.Errorf("%s: Source = %q, CacheHit = %v; want %q, %v", tt.name, resp.Source, resp.CacheHit, tt.want, tt.hit)
+		}
+		if resp.CachedAt == nil || time.Since(*resp.CachedAt) > time.Minute {
+			t.Errorf("%s: CachedAt = %v; want the recent fetch time", tt.name, resp.CachedAt)
+		}
+	}
+}
+
+func TestGetWeatherServesNearbyCache(t *testing.T) {
+	server, hits := fakeGridNWS(t, http.StatusOK)
+	service := NewWeatherService(newTestRepo(t), newTestNWSClient(server))
+
+	own, err := service.GetWeather(context.Background(), 40.7128, -74.0060)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if own.CachedPoint != nil {
+		t.Errorf("live response CachedPoint = %+v; want none", own.CachedPoint)
+	}
+
+	// 0.78 km north is answered from the entry above without resolving its point
+	nearby, err := service.GetWeather(context.Background(), 40.7200, -74.0060)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(hits["points"]); got != 1 {
+		t.Errorf("points resolved %d times; want 1", got)
+	}
+	want := &models.CachedPoint{Latitude: 40.713, Longitude: -74.006, DistanceKm: 0.78}
+	if !nearby.CacheHit || nearby.Forecast != own.Forecast || !reflect.DeepEqual(nearby.CachedPoint, want) {
+		t.Errorf("nearby response = %+v, CachedPoint %+v; want a cache hit from %+v", nearby, nearby.CachedPoint, want)
+	}
+	if cached, ok := service.CachedWeather(context.Background(), 40.7200, -74.0060); !ok || cached.Source != "nearby:"+repository.SourceSQLite {
+		t.Errorf("CachedWeather = %+v, %v; want a nearby:sqlite hit", cached, ok)
+	}
+}
+
+func TestWeatherUpdate(t *testing.T) {
+	server, _ := fakeGridNWS(t, http.StatusOK)
+	service := NewWeatherService(newTestRepo(t), newTestNWSClient(server))
+	sub := service.SubscribeWeather(40.7128, -74.0060)
+	defer sub.Close()
+
+	opts := WeatherOptions{IncludeAdvisories: true, Units: units.Metric}
+	fetched, err := service.GetWeatherWithOptions(context.Background(), 40.7128, -74.0060, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case entry := <-sub.Updates():
+		// A pushed update reads the same as the response that caused it
+		if got := service.WeatherUpdate(context.Background(), entry, opts); !reflect.DeepEqual(got, fetched) {
+			t.Errorf("WeatherUpdate = %+v; want %+v", got, fetched)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no update after fetching the coordinate")
+	}
+
+	// Cache hits don't write, so they send nothing
+	if _, err := service.GetWeather(context.Background(), 40.7128, -74.0060); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case entry := <-sub.Updates():
+		t.Errorf("cache hit sent update %+v", entry)
+	default:
+	}
+}
+
+func TestGetWeatherCoalescesConcurrentMisses(t *testing.T) {
+	var points, forecasts int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A slow upstream keeps every request in flight together
+		time.Sleep(50 * time.Millisecond)
+		if strings.HasPrefix(r.URL.Path, "/points/") {
+			atomic.AddInt32(&points, 1)
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35, "forecast": "%s/gridpoints/OKX/33,35/forecast"}}`, server.URL)
+			return
+		}
+		atomic.AddInt32(&forecasts, 1)
+		fmt.Fprint(w, `{"properties": {"periods": [
+			{"shortForecast": "Partly Cloudy", "temperature": 72, "temperatureUnit": "F"}
+		]}}`)
+	}))
+	defer server.Close()
+
+	db, err := repository.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer db.Close()
+	service := NewWeatherService(repository.NewWeatherRepository(db, nil), newTestNWSClient(server))
+
+	const requests = 50
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			resp, err := service.GetWeather(context.Background(), 40.7128, -74.0060)
+			if err != nil {
+				t.Errorf("GetWeather: %v", err)
+				return
+			}
+			if resp.Forecast != "Partly Cloudy" {
+				t.Errorf("Forecast = %q; want the shared fetch's", resp.Forecast)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if p, f := atomic.LoadInt32(&points), atomic.LoadInt32(&forecasts); p != 1 || f != 1 {
+		t.Errorf("upstream called %d points + %d forecast times for %d concurrent requests; want 1 + 1", p, f, requests)
+	}
+	var rows int
+	if err := db.QueryRow("SELECT COUNT(*) FROM weather_cache").Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 1 {
+		t.Errorf("weather_cache has %d rows; want the shared result written once", rows)
+	}
+}
+
+func TestGetWeatherStaleWhileRevalidate(t *testing.T) {
+	release := make(chan struct{})
+	var points, forecasts int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/points/") {
+			atomic.AddInt32(&points, 1)
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35, "forecast": "%s/gridpoints/OKX/33,35/forecast"}}`, server.URL)
+			return
+		}
+		// Hold forecasts until released, like a slow NWS
+		<-release
+		atomic.AddInt32(&forecasts, 1)
+		fmt.Fprint(w, `{"properties": {"periods": [
+			{"shortForecast": "Partly Cloudy", "temperature": 72, "temperatureUnit": "F"}
+		]}}`)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	repo := newTestRepo(t)
+	service := NewWeatherService(repo, newTestNWSClient(server), WithStaleWhileRevalidate(6*time.Hour))
+
+	expired := &models.WeatherCache{Latitude: 40.7128, Longitude: -74.0060, Forecast: "Rain", TempC: 10, TempF: 50, Timestamp: time.Now().Add(-2 * time.Hour)}
+	if err := repo.SaveToCache(context.Background(), expired); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every request is answered from the expired entry without waiting on the NWS
+	for i := 0; i < 10; i++ {
+		resp, err := service.GetWeather(context.Background(), 40.7128, -74.0060)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Forecast != "Rain" || resp.Source != SourceStale || resp.CacheHit {
+			t.Fatalf("request %d: Forecast = %q from %q (hit %v); want the expired entry marked stale", i+1, resp.Forecast, resp.Source, resp.CacheHit)
+		}
+		if resp.CachedAt == nil || !resp.CachedAt.Equal(expired.Timestamp) {
+			t.Errorf("request %d: CachedAt = %v; want %v", i+1, resp.CachedAt, expired.Timestamp)
+		}
+	}
+
+	// The single background refresh completes once the NWS responds
+	release <- struct{}{}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		cached, err := repo.GetFromCache(context.Background(), 40.7128, -74.0060)
+		if err == nil && repo.IsCacheFresh(cached) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("background refresh did not update the cache")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if p, f := atomic.LoadInt32(&points), atomic.LoadInt32(&forecasts); p != 1 || f != 1 {
+		t.Errorf("background refresh made %d points + %d forecast calls; want 1 + 1", p, f)
+	}
+
+	resp, err := service.GetWeather(context.Background(), 40.7128, -74.0060)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Forecast != "Partly Cloudy" || !resp.CacheHit {
+		t.Errorf("after refresh: Forecast = %q (hit %v); want the refreshed entry", resp.Forecast, resp.CacheHit)
+	}
+}
+
+func TestGetWeatherLocation(t *testing.T) {
+	server, _ := fakeGridNWS(t, http.StatusOK)
+	service := NewWeatherService(newTestRepo(t), newTestNWSClient(server))
+
+	for _, label := range []string{"fetched", "cached"} {
+		resp, err := service.GetWeather(context.Background(), 40.7357, -74.1724)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Location != "Newark, NJ" {
+			t.Errorf("%s: Location = %q; want Newark, NJ", label, resp.Location)
+		}
+	}
+
+	if got := formatLocation("Newark", ""); got != "Newark" {
+		t.Errorf("formatLocation without a state = %q; want the city", got)
+	}
+}
+
+func TestGetWeatherPeriod(t *testing.T) {
+	server, _ := fakeGridNWS(t, http.StatusOK)
+	repo := newTestRepo(t)
+	service := NewWeatherService(repo, newTestNWSClient(server))
+
+	// The period passes through the coordinate and grid cell caches untouched,
+	// whatever the time of day
+	for _, tt := range []struct {
+		label    string
+		lat, lon float64
+	}{
+		{"fetched", 40.7128, -74.0060},
+		{"cached", 40.7128, -74.0060},
+		{"cached grid cell", 40.7340, -74.0200},
+	} {
+		resp, err := service.GetWeather(context.Background(), tt.lat, tt.lon)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.PeriodName != "Tonight" || resp.IsDaytime == nil || *resp.IsDaytime {
+			t.Errorf("%s: period = %q, daytime %v; want Tonight at night", tt.label, resp.PeriodName, resp.IsDaytime)
+		}
+	}
+
+	// An entry cached before the period was recorded is placed by its timestamp:
+	// 17:00 UTC is about midday at 75W
+	legacy := &models.WeatherCache{
+		Latitude: 39.95, Longitude: -75.165, Forecast: "Sunny", Timestamp: time.Now().UTC().Truncate(24 * time.Hour).Add(17 * time.Hour),
+	}
+	if legacy.Timestamp.After(time.Now()) {
+		legacy.Timestamp = legacy.Timestamp.Add(-24 * time.Hour)
+	}
+	if err := repo.SaveToCache(context.Background(), legacy); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := repo.GetFromCache(context.Background(), legacy.Latitude, legacy.Longitude)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := service.buildResponse(context.Background(), cached, WeatherOptions{})
+	if resp.PeriodName != "" || resp.IsDaytime == nil || !*resp.IsDaytime {
+		t.Errorf("legacy entry: period = %q, daytime %v; want no name, in daytime", resp.PeriodName, resp.IsDaytime)
+	}
+}
+
+func TestGetWeatherTemperatureChange(t *testing.T) {
+	server, _ := fakeGridNWS(t, http.StatusOK)
+	repo := newTestRepo(t)
+	service := NewWeatherService(repo, newTestNWSClient(server))
+
+	// A coordinate's first forecast has nothing to compare against; the NWS
+	// trend passes through the caches
+	for _, label := range []string{"fetched", "cached"} {
+		resp, err := service.GetWeather(context.Background(), 40.7128, -74.0060)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.TemperatureChangeC != nil || resp.TemperatureTrend != "rising" {
+			t.Errorf("%s: change %v, trend %q; want no change, rising", label, resp.TemperatureChangeC, resp.TemperatureTrend)
+		}
+	}
+
+	// A refresh compares against the entry it replaces: 72°F is 22.2°C
+	previous := &models.WeatherCache{
+		Latitude: 40.7357, Longitude: -74.1724, Forecast: "Sunny", TempC: 24, TempF: 75.2, Timestamp: time.Now().Add(-2 * time.Hour),
+	}
+	if err := repo.SaveToCache(context.Background(), previous); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.RefreshWeather(context.Background(), previous.Latitude, previous.Longitude, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := service.GetWeather(context.Background(), previous.Latitude, previous.Longitude)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.TemperatureChangeC == nil || *resp.TemperatureChangeC != -1.8 {
+		t.Errorf("after a refresh: change %v; want -1.8", resp.TemperatureChangeC)
+	}
+
+	// Refreshing from the same cached grid forecast keeps the change
+	if err := service.RefreshWeather(context.Background(), previous.Latitude, previous.Longitude, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := repo.GetFromCache(context.Background(), previous.Latitude, previous.Longitude)
+	if err != nil || cached.TemperatureChangeC == nil || *cached.TemperatureChangeC != -1.8 {
+		t.Errorf("refetched grid forecast: cached entry %+v, %v; want the change kept", cached, err)
+	}
+}
+
+func TestSetTemperatureChange(t *testing.T) {
+	repo := newTestRepo(t)
+	service := NewWeatherService(repo, nil)
+	now := time.Now()
+	previous := &models.WeatherCache{Latitude: 40.7128, Longitude: -74.006, Forecast: "Sunny", TempC: 22.24, Timestamp: now.Add(-time.Hour)}
+	if err := repo.SaveToCache(context.Background(), previous); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tempC float64
+		want  float64
+	}{
+		{25, 2.8},
+		{20, -2.2},
+		{22.24, 0},
+		// -0.04 rounds to -0, which must read as unchanged
+		{22.2, 0},
+	}
+	for _, tt := range tests {
+		weather := &models.WeatherCache{Latitude: previous.Latitude, Longitude: previous.Longitude, TempC: tt.tempC, Timestamp: now}
+		service.setTemperatureChange(context.Background(), weather)
+		if weather.TemperatureChangeC == nil {
+			t.Errorf("%v°C after %v°C: no change; want %v", tt.tempC, previous.TempC, tt.want)
+			continue
+		}
+		if got := *weather.TemperatureChangeC; got != tt.want || math.Signbit(got) != math.Signbit(tt.want) {
+			t.Errorf("%v°C after %v°C: change %v; want %v", tt.tempC, previous.TempC, got, tt.want)
+		}
+	}
+}
+
+func TestIsDaytimeAt(t *testing.T) {
+	noon := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		t    time.Time
+		lon  float64
+		want bool
+	}{
+		{noon, 0, true},
+		{noon, 90, false},    // 18:00 solar time
+		{noon, -90, true},    // 06:00
+		{noon, -97.5, false}, // 05:30
+		{noon, 180, false},   // midnight
+		{noon.Add(-12 * time.Hour), -179.9, true},
+	}
+	for _, tt := range tests {
+		if got := isDaytimeAt(tt.t, tt.lon); got != tt.want {
+			t.Errorf("isDaytimeAt(%v, %v) = %v; want %v", tt.t, tt.lon, got, tt.want)
+		}
+	}
+}
+
+func TestGetWeatherNormalizesGridPointLookup(t *testing.T) {
+	server, hits := fakeGridNWS(t, http.StatusOK)
+	repo := newTestRepo(t)
+	service := NewWeatherService(repo, newTestNWSClient(server))
+
+	// Both round to 40.7128,-74.006 at the precision NWS resolves points at
+	for _, coord := range [][2]float64{{40.71281, -74.00601}, {40.712804, -74.006004}} {
+		if _, err := service.GetWeather(context.Background(), coord[0], coord[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := atomic.LoadInt32(hits["points"]); got != 1 {
+		t.Errorf("points resolved %d times; want 1 for the same normalized coordinate", got)
+	}
+	if _, err := repo.GetGridPoint(context.Background(), 40.7128, -74.006); err != nil {
+		t.Errorf("grid mapping not stored under the normalized coordinate: %v", err)
+	}
+}
+
+func TestGetWeatherFallsBackToStaleGridForecast(t *testing.T) {
+	server, _ := fakeGridNWS(t, http.StatusServiceUnavailable)
+	repo := newTestRepo(t)
+	service := NewWeatherService(repo, newTestNWSClient(server))
+
+	stale := &models.WeatherCache{Forecast: "Rain", TempC: 10, TempF: 50, Timestamp: time.Now().Add(-3 * time.Hour)}
+	if err := repo.SaveGridForecast(context.Background(), "OKX", 33, 35, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := service.GetWeather(context.Background(), 40.7128, -74.0060)
+	if err != nil {
+		t.Fatalf("GetWeather with stale grid forecast: %v", err)
+	}
+	if resp.Forecast != "Rain" {
+		t.Errorf("Forecast = %q; want stale grid forecast", resp.Forecast)
+	}
+	if resp.FreshUntil.After(time.Now()) {
+		t.Errorf("stale forecast reported fresh until %v", resp.FreshUntil)
+	}
+	if resp.Source != SourceStale || resp.CachedAt == nil || !resp.CachedAt.Equal(stale.Timestamp) {
+		t.Errorf("Source = %q, CachedAt = %v; want stale from %v", resp.Source, resp.CachedAt, stale.Timestamp)
+	}
+}
+
+func TestGetWeatherServesStaleWhenRateLimited(t *testing.T) {
+	server, hits := fakeGridNWS(t, http.StatusOK)
+	repo := newTestRepo(t)
+	// The burst covers the points lookup; the forecast fetch would have to wait
+	client := NewNWSAPIClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()),
+		WithRateLimit(RateLimitConfig{RequestsPerSecond: 0.1, Burst: 1, MaxWait: time.Millisecond}))
+	service := NewWeatherService(repo, client)
+
+	stale := &models.WeatherCache{Forecast: "Rain", TempC: 10, TempF: 50, Timestamp: time.Now().Add(-3 * time.Hour)}
+	if err := repo.SaveGridForecast(context.Background(), "OKX", 33, 35, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := service.GetWeather(context.Background(), 40.7128, -74.0060)
+	if err != nil {
+		t.Fatalf("GetWeather while rate-limited with a stale forecast: %v", err)
+	}
+	if resp.Forecast != "Rain" || resp.Source != SourceStale {
+		t.Errorf("Forecast = %q from %q; want the stale grid forecast", resp.Forecast, resp.Source)
+	}
+	if got := atomic.LoadInt32(hits["OKX/33,35"]); got != 0 {
+		t.Errorf("forecast fetched %d times despite the rate limit", got)
+	}
+
+	// Without cached data the request is shed
+	_, err = service.GetWeather(context.Background(), 41.5, -74.0)
+	var shed *ShedError
+	if !errors.As(err, &shed) {
+		t.Errorf("uncached rate-limited GetWeather error = %v; want *ShedError", err)
+	}
+}
+
+func TestGetWeatherRejectsOutOfCoverageWithoutUpstreamCall(t *testing.T) {
+	server, hits := fakeGridNWS(t, http.StatusOK)
+	recorder := metrics.NewRecorder(metrics.DefaultWindow)
+	service := NewWeatherService(newTestRepo(t), newTestNWSClient(server), WithMetrics(recorder))
+
+	// Mid-Atlantic, Toronto, and Mexico City
+	for _, coord := range [][2]float64{{35, -60}, {43.6532, -79.3832}, {19.4326, -99.1332}} {
+		if _, err := service.GetWeather(context.Background(), coord[0], coord[1]); !errors.Is(err, ErrOutsideCoverage) {
+			t.Errorf("GetWeather(%v) error = %v; want ErrOutsideCoverage", coord, err)
+		}
+	}
+	if _, err := service.GetRawPoints(context.Background(), 35, -60); !errors.Is(err, ErrOutsideCoverage) {
+		t.Errorf("GetRawPoints error = %v; want ErrOutsideCoverage", err)
+	}
+	if got := atomic.LoadInt32(hits["points"]); got != 0 {
+		t.Errorf("out-of-coverage coordinates made %d points requests; want 0", got)
+	}
+	if got := recorder.Counters()[metrics.RequestsOutOfCoverage]; got != 4 {
+		t.Errorf("%s = %d; want 4", metrics.RequestsOutOfCoverage, got)
+	}
+
+	// With the pre-check disabled the coordinate goes upstream as before
+	unchecked := NewWeatherService(newTestRepo(t), newTestNWSClient(server), WithCoverageCheck(false))
+	if _, err := unchecked.GetWeather(context.Background(), 35, -60); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(hits["points"]); got != 1 {
+		t.Errorf("points requests with the pre-check disabled = %d; want 1", got)
+	}
+}
+
+func TestGetWeatherReresolvesRetiredForecastURL(t *testing.T) {
+	var points, retired, current int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/points/40.7128,-74.006":
+			// The first lookup maps the coordinate to a cell the NWS later retires
+			cell := "OKX/33,35"
+			if atomic.AddInt32(&points, 1) > 1 {
+				cell = "OKX/34,36"
+			}
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "forecast": "%s/gridpoints/%s/forecast"}}`, server.URL, cell)
+		case "/gridpoints/OKX/33,35/forecast":
+			atomic.AddInt32(&retired, 1)
+			http.NotFound(w, r)
+		case "/gridpoints/OKX/34,36/forecast":
+			atomic.AddInt32(&current, 1)
+			fmt.Fprint(w, `{"properties": {"periods": [
+				{"shortForecast": "Sunny", "temperature": 72, "temperatureUnit": "F"}
+			]}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	repo := newTestRepo(t)
+	service := NewWeatherService(repo, newTestNWSClient(server))
+
+	resp, err := service.GetWeather(context.Background(), 40.7128, -74.0060)
+	if err != nil {
+		t.Fatalf("GetWeather: %v", err)
+	}
+	if resp.Forecast != "Sunny" {
+		t.Errorf("Forecast = %q; want the re-resolved cell's forecast", resp.Forecast)
+	}
+	if points != 2 || retired != 1 || current != 1 {
+		t.Errorf("points, retired, current fetches = %d, %d, %d; want 2, 1, 1", points, retired, current)
+	}
+
+	point, err := repo.GetGridPoint(context.Background(), 40.7128, -74.006)
+	if err != nil {
+		t.Fatalf("GetGridPoint: %v", err)
+	}
+	if want := server.URL + "/gridpoints/OKX/34,36/forecast"; point.ForecastURL != want {
+		t.Errorf("cached ForecastURL = %q; want %q", point.ForecastURL, want)
+	}
+}
+
+func TestGetWeatherServesStaleOnDeadline(t *testing.T) {
+	aborted := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		aborted <- struct{}{}
+	}))
+	defer server.Close()
+
+	repo := newTestRepo(t)
+	service := NewWeatherService(repo, newTestNWSClient(server))
+	stale := &models.WeatherCache{Latitude: 40.7128, Longitude: -74.006, Forecast: "Fog", TempC: 8, TempF: 46.4, Timestamp: time.Now().Add(-DefaultMaxStale - time.Hour)}
+	if err := repo.SaveToCache(context.Background(), stale); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		lat, lon  float64
+		wantStale bool
+	}{
+		{"stale entry", 40.7128, -74.006, true},
+		{"no entry", 39.9526, -75.1652, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			resp, err := service.GetWeather(ctx, tt.lat, tt.lon)
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("GetWeather returned after %v; want it to stop at the deadline", elapsed)
+			}
+			select {
+			case <-aborted:
+			case <-time.After(time.Second):
+				t.Error("upstream request was not aborted")
+			}
+
+			if tt.wantStale {
+				if err != nil || resp.Forecast != "Fog" || resp.Source != SourceStale {
+					t.Errorf("GetWeather = %+v, %v; want the stale entry", resp, err)
+				}
+				return
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("GetWeather error = %v; want context.DeadlineExceeded", err)
+			}
+		})
+	}
+}
+
+func TestGetWeatherOutlivesRequestContexts(t *testing.T) {
+	arrived := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/points/") {
+			fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35, "forecast": "%s/gridpoints/OKX/33,35/forecast"}}`, server.URL)
+			return
+		}
+		arrived <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		fmt.Fprint(w, `{"properties": {"periods": [
+			{"shortForecast": "Partly Cloudy", "temperature": 72, "temperatureUnit": "F"}
+		]}}`)
+	}))
+	defer server.Close()
+
+	waitFresh := func(t *testing.T, repo *repository.WeatherRepository) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			cached, err := repo.GetFromCache(context.Background(), 40.7128, -74.0060)
+			if err == nil && repo.IsCacheFresh(cached) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("the fetch did not update the cache")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	t.Run("request that started a shared fetch gives up", func(t *testing.T) {
+		repo := newTestRepo(t)
+		service := NewWeatherService(repo, newTestNWSClient(server))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		leader := make(chan error, 1)
+		go func() {
+			_, err := service.GetWeather(ctx, 40.7128, -74.0060)
+			leader <- err
+		}()
+		<-arrived
+
+		follower := make(chan *models.WeatherResponse, 1)
+		go func() {
+			resp, err := service.GetWeather(context.Background(), 40.7128, -74.0060)
+			if err != nil {
+				t.Errorf("follower: %v", err)
+			}
+			follower <- resp
+		}()
+		// Wait for the follower to join the fetch before the leader leaves it
+		for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(time.Millisecond) {
+			service.fetchContexts.mu.Lock()
+			fetch := service.fetchContexts.fetches[WeatherKey(40.7128, -74.0060)]
+			joined := fetch != nil && fetch.waiters == 2
+			service.fetchContexts.mu.Unlock()
+			if joined {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("follower did not join the fetch")
+			}
+		}
+
+		cancel()
+		if err := <-leader; !errors.Is(err, context.Canceled) {
+			t.Errorf("leader error = %v; want context.Canceled", err)
+		}
+		release <- struct{}{}
+		if resp := <-follower; resp == nil || resp.Forecast != "Partly Cloudy" {
+			t.Errorf("follower = %+v; want the shared fetch's forecast", resp)
+		}
+		waitFresh(t, repo)
+	})
+
+	t.Run("background refresh outlives its request", func(t *testing.T) {
+		repo := newTestRepo(t)
+		service := NewWeatherService(repo, newTestNWSClient(server), WithStaleWhileRevalidate(6*time.Hour))
+		expired := &models.WeatherCache{Latitude: 40.7128, Longitude: -74.0060, Forecast: "Rain", TempC: 10, TempF: 50, Timestamp: time.Now().Add(-2 * time.Hour)}
+		if err := repo.SaveToCache(context.Background(), expired); err != nil {
+			t.Fatal(err)
+		}
+
+		// The request's context ends with it, as in the handlers
+		ctx, cancel := context.WithCancel(context.Background())
+		resp, err := service.GetWeather(ctx, 40.7128, -74.0060)
+		cancel()
+		if err != nil || resp.Source != SourceStale {
+			t.Fatalf("GetWeather = %+v, %v; want the expired entry", resp, err)
+		}
+		<-arrived
+		release <- struct{}{}
+		waitFresh(t, repo)
+	})
+}
+
+// stallingProvider is a fakeProvider whose first fetch keeps running after
+// its context is cancelled, until finish is closed
+type stallingProvider struct {
+	fakeProvider
+	started chan struct{}
+	finish  chan struct{}
+}
+
+func (p *stallingProvider) GetForecast(ctx context.Context, lat, lon float64) (*models.WeatherCache, error) {
+	if atomic.AddInt32(&p.calls, 1) == 1 {
+		close(p.started)
+		<-ctx.Done()
+		<-p.finish
+		return nil, ctx.Err()
+	}
+	return &models.WeatherCache{Forecast: p.forecast, TempC: 20, TempF: 68, Timestamp: time.Now()}, nil
+}
+
+func TestGetWeatherAfterLastWaiterLeaves(t *testing.T) {
+	provider := &stallingProvider{
+		fakeProvider: fakeProvider{forecast: "Drizzle", minLat: -90, maxLat: 90},
+		started:      make(chan struct{}),
+		finish:       make(chan struct{}),
+	}
+	defer close(provider.finish)
+	service := NewWeatherService(newTestRepo(t), provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := service.GetWeather(ctx, 40.7128, -74.0060)
+		leader <- err
+	}()
+	<-provider.started
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Fatalf("leader error = %v; want context.Canceled", err)
+	}
+
+	// The abandoned fetch is still winding down; a new caller mustn't join it
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	resp, err := service.GetWeather(ctx, 40.7128, -74.0060)
+	if err != nil || resp.Forecast != "Drizzle" {
+		t.Fatalf("GetWeather = %+v, %v; want a fresh fetch's forecast", resp, err)
+	}
+	if calls := atomic.LoadInt32(&provider.calls); calls != 2 {
+		t.Errorf("provider calls = %d; want 2", calls)
+	}
+}
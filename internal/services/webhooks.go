@@ -0,0 +1,448 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+const (
+	// DefaultWebhookPollInterval is how often subscribed points are checked
+	// for new alerts and due deliveries are sent
+	DefaultWebhookPollInterval = time.Minute
+	// DefaultWebhookTimeout bounds a single POST to a callback
+	DefaultWebhookTimeout = 10 * time.Second
+	// DefaultMinSeverity is the least severe alert delivered when a
+	// subscription doesn't say
+	DefaultMinSeverity = "Severe"
+	// MaxWebhookDeliveries caps the deliveries listed for a subscription
+	MaxWebhookDeliveries = 100
+
+	// WebhookSignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the
+	// body, keyed by the subscription's secret
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	// WebhookDeliveryHeader carries the delivery's ID, the same on every retry
+	WebhookDeliveryHeader = "X-Webhook-Delivery"
+
+	// webhookDeliveryBatch caps the deliveries sent in one pass
+	webhookDeliveryBatch = 100
+)
+
+// ErrInvalidCallbackURL is returned when a callback URL isn't an absolute http(s) URL
+var ErrInvalidCallbackURL = errors.New("callback URL must be an absolute http or https URL")
+
+// ErrCallbackNotAllowed is returned when a callback host doesn't resolve, or
+// resolves to an address deliveries may not be sent to
+var ErrCallbackNotAllowed = errors.New("callback URL must resolve to a public address")
+
+// ErrInvalidSeverity is returned when a minimum severity isn't an NWS alert severity
+var ErrInvalidSeverity = errors.New("unknown alert severity")
+
+// alertSeverityRank orders the NWS alert severities; Unknown and anything
+// else ranks below Minor
+var alertSeverityRank = map[string]int{
+	"Minor":    1,
+	"Moderate": 2,
+	"Severe":   3,
+	"Extreme":  4,
+}
+
+// WebhookConfig controls delivery of alerts to webhook subscriptions
+type WebhookConfig struct {
+	// Retry bounds how failed deliveries are retried. MaxAttempts is the most
+	// times a delivery is tried before it is marked dead; MaxElapsed is unused.
+	Retry RetryConfig
+	// Timeout bounds a single POST to a callback
+	Timeout time.Duration
+	// AllowPrivateCallbacks permits callbacks on private, loopback, and
+	// link-local addresses, for local development. Otherwise they are refused
+	// when subscribed and again when a delivery connects, so a callback can't
+	// be pointed at services inside the network.
+	AllowPrivateCallbacks bool
+	// Resolver looks callback hosts up when they are subscribed; nil uses
+	// net.DefaultResolver
+	Resolver HostResolver
+}
+
+// HostResolver looks up the addresses of a host name, as *net.Resolver does
+type HostResolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// DefaultWebhookConfig returns the default webhook delivery settings: five
+// attempts over roughly half an hour
+func DefaultWebhookConfig() WebhookConfig {
+	return WebhookConfig{
+		Retry: RetryConfig{
+			MaxAttempts: 5,
+			BaseDelay:   time.Minute,
+			MaxDelay:    15 * time.Minute,
+		},
+		Timeout: DefaultWebhookTimeout,
+	}
+}
+
+// Webhooks manages webhook subscriptions and periodically delivers new
+// alerts at their points to their callbacks
+type Webhooks struct {
+	repo    *repository.WeatherRepository
+	weather *WeatherService
+	client  *http.Client
+	cfg     WebhookConfig
+	now     func() time.Time
+}
+
+// NewWebhooks creates the webhook job, looking alerts up through weather
+func NewWebhooks(repo *repository.WeatherRepository, weather *WeatherService, cfg WebhookConfig) *Webhooks {
+	defaults := DefaultWebhookConfig()
+	if cfg.Retry.MaxAttempts < 1 {
+		cfg.Retry = defaults.Retry
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = net.DefaultResolver
+	}
+	return &Webhooks{
+		repo:    repo,
+		weather: weather,
+		client:  &http.Client{Timeout: cfg.Timeout, Transport: callbackTransport(cfg.AllowPrivateCallbacks)},
+		cfg:     cfg,
+		now:     time.Now,
+	}
+}
+
+// callbackTransport returns the transport deliveries are sent with. Unless
+// private callbacks are allowed, it refuses to connect to an address
+// callbackAddrAllowed rejects, checked on the address actually dialed so a
+// host that resolves differently after it was subscribed, or a redirect,
+// can't reach one. Deliveries don't go through HTTP_PROXY, whose address
+// would be the one checked.
+func callbackTransport(allowPrivate bool) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	if allowPrivate {
+		return transport
+	}
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !callbackAddrAllowed(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrCallbackNotAllowed, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// nonPublicPrefixes are the ranges, beyond loopback, link-local, multicast,
+// and RFC 1918/4193 private addresses, that callbacks may not resolve to
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// callbackAddrAllowed reports whether deliveries may be sent to an address:
+// a global unicast address that isn't private or otherwise reserved
+func callbackAddrAllowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkCallbackHost returns ErrCallbackNotAllowed unless every address a
+// callback host resolves to may be sent deliveries
+func (w *Webhooks) checkCallbackHost(host string) error {
+	if w.cfg.AllowPrivateCallbacks {
+		return nil
+	}
+	addrs := []netip.Addr{}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = append(addrs, addr)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), w.cfg.Timeout)
+		defer cancel()
+		if addrs, err = w.cfg.Resolver.LookupNetIP(ctx, "ip", host); err != nil {
+			return fmt.Errorf("%w: %w", ErrCallbackNotAllowed, err)
+		}
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("%w: %s has no addresses", ErrCallbackNotAllowed, host)
+	}
+	for _, addr := range addrs {
+		if !callbackAddrAllowed(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrCallbackNotAllowed, host, addr)
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a callback for new alerts at a coordinate at least as
+// severe as minSeverity, which is matched case-insensitively and defaults to
+// DefaultMinSeverity. The subscription belongs to owner, the ID of the API
+// key that created it or empty when keys aren't required. The returned
+// subscription includes its signing secret.
+func (w *Webhooks) Subscribe(ctx context.Context, owner, callbackURL string, lat, lon float64, minSeverity string) (*models.WebhookSubscription, error) {
+	u, err := url.Parse(callbackURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidCallbackURL
+	}
+	if err := w.checkCallbackHost(u.Hostname()); err != nil {
+		return nil, err
+	}
+	severity, err := canonicalSeverity(minSeverity)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	sub := models.WebhookSubscription{
+		ID:          id,
+		CallbackURL: u.String(),
+		Latitude:    lat,
+		Longitude:   lon,
+		MinSeverity: severity,
+		CreatedAt:   w.now().UTC(),
+		Secret:      secret,
+		Owner:       owner,
+	}
+	if err := w.repo.SaveWebhookSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// canonicalSeverity returns the NWS spelling of a severity, or
+// DefaultMinSeverity when it is empty
+func canonicalSeverity(severity string) (string, error) {
+	if severity == "" {
+		return DefaultMinSeverity, nil
+	}
+	for name := range alertSeverityRank {
+		if strings.EqualFold(name, severity) {
+			return name, nil
+		}
+	}
+	return "", ErrInvalidSeverity
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ListSubscriptions returns an owner's webhook subscriptions without their secrets
+func (w *Webhooks) ListSubscriptions(ctx context.Context, owner string) (*models.WebhookSubscriptionsResponse, error) {
+	subs, err := w.repo.ListOwnedWebhookSubscriptions(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	resp := &models.WebhookSubscriptionsResponse{Subscriptions: make([]models.WebhookSubscription, len(subs))}
+	for i, sub := range subs {
+		sub.Secret = ""
+		resp.Subscriptions[i] = sub
+	}
+	return resp, nil
+}
+
+// Unsubscribe deletes an owner's webhook subscription and its delivery log,
+// returning repository.ErrSubscriptionNotFound when the owner has none with
+// the ID
+func (w *Webhooks) Unsubscribe(ctx context.Context, owner, id string) error {
+	return w.repo.DeleteWebhookSubscription(ctx, owner, id)
+}
+
+// Deliveries returns the most recent deliveries of an owner's subscription,
+// returning repository.ErrSubscriptionNotFound when the owner has none with
+// the ID
+func (w *Webhooks) Deliveries(ctx context.Context, owner, id string) (*models.WebhookDeliveriesResponse, error) {
+	sub, err := w.repo.GetWebhookSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Owner != owner {
+		return nil, repository.ErrSubscriptionNotFound
+	}
+	deliveries, err := w.repo.ListWebhookDeliveries(ctx, id, MaxWebhookDeliveries)
+	if err != nil {
+		return nil, err
+	}
+	return &models.WebhookDeliveriesResponse{Deliveries: deliveries}, nil
+}
+
+// RunOnce checks every subscription's point for new alerts, then sends the
+// deliveries that are due
+func (w *Webhooks) RunOnce(ctx context.Context) error {
+	if err := w.Poll(ctx); err != nil {
+		return err
+	}
+	return w.DeliverDue(ctx)
+}
+
+// Poll queues a delivery for each active alert at a subscription's point that
+// is severe enough and hasn't been queued for it before. A point whose alerts
+// can't be looked up is skipped until the next poll.
+func (w *Webhooks) Poll(ctx context.Context) error {
+	subs, err := w.repo.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		alerts, err := w.weather.GetPointAlerts(ctx, sub.Latitude, sub.Longitude)
+		if err != nil {
+			log.Printf("Webhook %s: alerts lookup failed: %v", sub.ID, err)
+			continue
+		}
+		for _, alert := range alerts.Alerts {
+			if alertSeverityRank[alert.Severity] < alertSeverityRank[sub.MinSeverity] {
+				continue
+			}
+			// Deliveries stay small; the shape is on /alerts?geometry=true
+			alert.Geometry = nil
+			payload, err := json.Marshal(models.WebhookPayload{
+				SubscriptionID: sub.ID,
+				Latitude:       sub.Latitude,
+				Longitude:      sub.Longitude,
+				Alert:          alert,
+			})
+			if err != nil {
+				return err
+			}
+			if _, err := w.repo.EnqueueWebhookDelivery(ctx, sub.ID, alert.ID, string(payload), w.now()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DeliverDue sends each pending delivery whose next attempt is due. A
+// delivery the callback doesn't accept with a 2xx response is retried with
+// backoff until it runs out of attempts, when it is marked dead.
+func (w *Webhooks) DeliverDue(ctx context.Context) error {
+	due, err := w.repo.DueWebhookDeliveries(ctx, w.now(), webhookDeliveryBatch)
+	if err != nil {
+		return err
+	}
+	for _, d := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		delivery := d.WebhookDelivery
+		delivery.Attempts++
+		now := w.now()
+		if err := w.post(ctx, d); err != nil {
+			delivery.LastError = err.Error()
+			if delivery.Attempts >= w.cfg.Retry.MaxAttempts {
+				delivery.Status = models.DeliveryDead
+				delivery.NextAttemptAt = nil
+				log.Printf("Webhook delivery %d to %s failed %d times; giving up: %v", d.ID, d.CallbackURL, delivery.Attempts, err)
+			} else {
+				next := now.Add(w.cfg.Retry.backoff(delivery.Attempts))
+				delivery.NextAttemptAt = &next
+			}
+		} else {
+			delivery.Status = models.DeliveryDelivered
+			delivery.LastError = ""
+			delivery.NextAttemptAt = nil
+			delivery.DeliveredAt = &now
+		}
+		if err := w.repo.UpdateWebhookDelivery(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// post sends a delivery's payload to its callback, signed with the
+// subscription's secret
+func (w *Webhooks) post(ctx context.Context, d repository.DueWebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.CallbackURL, strings.NewReader(d.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(d.Secret, []byte(d.Payload)))
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(d.ID, 10))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignWebhookPayload returns the signature header value for a payload:
+// "sha256=" and the hex HMAC-SHA256 of the payload keyed by secret
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run polls and delivers immediately and then every interval until ctx is cancelled
+func (w *Webhooks) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Webhook delivery failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
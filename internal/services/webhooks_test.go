@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/repository"
+)
+
+// callbackRequest is one POST a webhook callback received
+type callbackRequest struct {
+	signature, delivery string
+	body                []byte
+}
+
+// fakeResolver resolves the host names it lists and no others
+type fakeResolver map[string][]netip.Addr
+
+func (r fakeResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	if addrs, ok := r[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// testResolver resolves example.com publicly and a few names privately
+var testResolver = fakeResolver{
+	"example.com":          {netip.MustParseAddr("93.184.215.14"), netip.MustParseAddr("2606:2800:21f:cb07:6820:80da:af6b:8b2c")},
+	"localhost":            {netip.MustParseAddr("127.0.0.1"), netip.MustParseAddr("::1")},
+	"intranet.example.com": {netip.MustParseAddr("10.0.0.5")},
+	"split.example.com":    {netip.MustParseAddr("93.184.215.14"), netip.MustParseAddr("192.168.1.20")},
+}
+
+// newWebhookTest returns a webhook job whose alerts come from
+// pointAlertsFixture, and a callback server answering with status. The
+// server listens on loopback, so cfg must allow private callbacks for
+// deliveries to reach it.
+func newWebhookTest(t *testing.T, status int, cfg WebhookConfig) (*Webhooks, string, func() []callbackRequest) {
+	t.Helper()
+	expires := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	expired := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	nws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, pointAlertsFixture, expires, expired)
+	}))
+	t.Cleanup(nws.Close)
+
+	var mu sync.Mutex
+	var received []callbackRequest
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, callbackRequest{
+			signature: r.Header.Get(WebhookSignatureHeader),
+			delivery:  r.Header.Get(WebhookDeliveryHeader),
+			body:      body,
+		})
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(callback.Close)
+
+	repo := newTestRepo(t)
+	webhooks := NewWebhooks(repo, NewWeatherService(repo, newTestNWSClient(nws)), cfg)
+	return webhooks, callback.URL, func() []callbackRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]callbackRequest(nil), received...)
+	}
+}
+
+func TestWebhooksSubscribe(t *testing.T) {
+	webhooks := NewWebhooks(newTestRepo(t), nil, WebhookConfig{Resolver: testResolver})
+
+	tests := []struct {
+		url, severity string
+		want          string
+		wantErr       error
+	}{
+		{"https://example.com/hook", "", DefaultMinSeverity, nil},
+		{"http://example.com/hook", "moderate", "Moderate", nil},
+		{"ftp://example.com/hook", "", "", ErrInvalidCallbackURL},
+		{"/hook", "", "", ErrInvalidCallbackURL},
+		{"https://example.com/hook", "Catastrophic", "", ErrInvalidSeverity},
+		{"https://localhost/hook", "", "", ErrCallbackNotAllowed},
+		{"http://127.0.0.1:8080/hook", "", "", ErrCallbackNotAllowed},
+		{"http://[::1]/hook", "", "", ErrCallbackNotAllowed},
+		{"http://169.254.169.254/latest/meta-data/", "", "", ErrCallbackNotAllowed},
+		{"http://[::ffff:10.1.2.3]/hook", "", "", ErrCallbackNotAllowed},
+		{"https://intranet.example.com/hook", "", "", ErrCallbackNotAllowed},
+		{"https://split.example.com/hook", "", "", ErrCallbackNotAllowed},
+		{"https://unknown.example.com/hook", "", "", ErrCallbackNotAllowed},
+	}
+	for _, tt := range tests {
+		sub, err := webhooks.Subscribe(context.Background(), "mobile-app", tt.url, 40.7128, -74.006, tt.severity)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Subscribe(%q, %q) error = %v; want %v", tt.url, tt.severity, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (sub.MinSeverity != tt.want || sub.Secret == "" || sub.ID == "") {
+			t.Errorf("Subscribe(%q, %q) = %+v; want severity %s with an ID and secret", tt.url, tt.severity, sub, tt.want)
+		}
+	}
+
+	list, err := webhooks.ListSubscriptions(context.Background(), "mobile-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Subscriptions) != 2 {
+		t.Fatalf("listed %d subscriptions; want 2", len(list.Subscriptions))
+	}
+	for _, sub := range list.Subscriptions {
+		if sub.Secret != "" {
+			t.Errorf("listed subscription %s has its secret", sub.ID)
+		}
+	}
+
+	// Other callers can't see or change them
+	if other, err := webhooks.ListSubscriptions(context.Background(), "dashboard"); err != nil || len(other.Subscriptions) != 0 {
+		t.Errorf("another owner listed %+v, %v; want none", other, err)
+	}
+	id := list.Subscriptions[0].ID
+	if _, err := webhooks.Deliveries(context.Background(), "dashboard", id); !errors.Is(err, repository.ErrSubscriptionNotFound) {
+		t.Errorf("Deliveries by another owner error = %v; want ErrSubscriptionNotFound", err)
+	}
+	if err := webhooks.Unsubscribe(context.Background(), "dashboard", id); !errors.Is(err, repository.ErrSubscriptionNotFound) {
+		t.Errorf("Unsubscribe by another owner error = %v; want ErrSubscriptionNotFound", err)
+	}
+
+	if err := webhooks.Unsubscribe(context.Background(), "mobile-app", id); err != nil {
+		t.Fatal(err)
+	}
+	if err := webhooks.Unsubscribe(context.Background(), "mobile-app", id); !errors.Is(err, repository.ErrSubscriptionNotFound) {
+		t.Errorf("second Unsubscribe error = %v; want ErrSubscriptionNotFound", err)
+	}
+}
+
+func TestWebhooksRefusePrivateCallbacksOnDelivery(t *testing.T) {
+	webhooks, callbackURL, received := newWebhookTest(t, http.StatusNoContent, WebhookConfig{})
+	if _, err := webhooks.Subscribe(context.Background(), "", callbackURL, 40.7128, -74.006, "Moderate"); !errors.Is(err, ErrCallbackNotAllowed) {
+		t.Fatalf("Subscribe(%q) error = %v; want ErrCallbackNotAllowed", callbackURL, err)
+	}
+
+	// A host that resolved publicly when subscribed can resolve to a private
+	// address by the time a delivery is sent
+	err := webhooks.repo.SaveWebhookSubscription(context.Background(), models.WebhookSubscription{
+		ID: "rebound", CallbackURL: callbackURL, Latitude: 40.7128, Longitude: -74.006,
+		MinSeverity: "Extreme", Secret: "s3cret", CreatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := webhooks.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(received()); n != 0 {
+		t.Errorf("callback received %d requests; want none", n)
+	}
+	deliveries, err := webhooks.Deliveries(context.Background(), "", "rebound")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries.Deliveries) != 1 || !strings.Contains(deliveries.Deliveries[0].LastError, ErrCallbackNotAllowed.Error()) {
+		t.Errorf("deliveries = %+v; want one refused with ErrCallbackNotAllowed", deliveries.Deliveries)
+	}
+}
+
+func TestWebhooksDeliverNewAlerts(t *testing.T) {
+	webhooks, callbackURL, received := newWebhookTest(t, http.StatusNoContent, WebhookConfig{AllowPrivateCallbacks: true})
+	sub, err := webhooks.Subscribe(context.Background(), "", callbackURL, 40.7128, -74.006, "Moderate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second pass finds the same alerts, which were already delivered
+	for i := 0; i < 2; i++ {
+		if err := webhooks.RunOnce(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := received()
+	if len(got) != 2 {
+		t.Fatalf("callback received %d requests; want one per active alert at least Moderate", len(got))
+	}
+	events := map[string]bool{}
+	for _, req := range got {
+		if want := SignWebhookPayload(sub.Secret, req.body); req.signature != want {
+			t.Errorf("signature = %q; want %q", req.signature, want)
+		}
+		var payload models.WebhookPayload
+		if err := json.Unmarshal(req.body, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.SubscriptionID != sub.ID || req.delivery == "" {
+			t.Errorf("payload for %s with delivery %q; want subscription %s", payload.SubscriptionID, req.delivery, sub.ID)
+		}
+		events[payload.Alert.Event] = true
+	}
+	if !events["Tornado Warning"] || !events["Heat Advisory"] {
+		t.Errorf("delivered %v; want the tornado warning and heat advisory", events)
+	}
+
+	deliveries, err := webhooks.Deliveries(context.Background(), "", sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range deliveries.Deliveries {
+		if d.Status != models.DeliveryDelivered || d.Attempts != 1 || d.DeliveredAt == nil {
+			t.Errorf("delivery = %+v; want delivered on the first attempt", d)
+		}
+	}
+}
+
+func TestWebhooksRetryThenDeadLetter(t *testing.T) {
+	cfg := WebhookConfig{Retry: RetryConfig{MaxAttempts: 2, BaseDelay: time.Minute, MaxDelay: time.Minute}, AllowPrivateCallbacks: true}
+	webhooks, callbackURL, received := newWebhookTest(t, http.StatusServiceUnavailable, cfg)
+	now := time.Now()
+	webhooks.now = func() time.Time { return now }
+	sub, err := webhooks.Subscribe(context.Background(), "", callbackURL, 40.7128, -74.006, "Extreme")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := webhooks.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, err := webhooks.Deliveries(context.Background(), "", sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries.Deliveries) != 1 {
+		t.Fatalf("got %d deliveries; want the tornado warning only", len(deliveries.Deliveries))
+	}
+	d := deliveries.Deliveries[0]
+	if d.Status != models.DeliveryPending || d.Attempts != 1 || d.LastError != "callback returned status 503" || d.NextAttemptAt == nil {
+		t.Fatalf("after one failure: %+v; want pending with a retry scheduled", d)
+	}
+
+	// The retry isn't due until its backoff has passed
+	if err := webhooks.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(received()); n != 1 {
+		t.Errorf("callback received %d requests before the backoff passed; want 1", n)
+	}
+
+	now = now.Add(time.Minute)
+	if err := webhooks.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, err = webhooks.Deliveries(context.Background(), "", sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := deliveries.Deliveries[0]; d.Status != models.DeliveryDead || d.Attempts != 2 || d.NextAttemptAt != nil {
+		t.Errorf("after the last attempt: %+v; want dead", d)
+	}
+	if n := len(received()); n != 2 {
+		t.Errorf("callback received %d requests; want 2", n)
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/units"
+)
+
+// ConvertWindSpeed rewrites an NWS wind speed such as "5 to 10 mph" in
+// another unit, converting both ends of a range. Empty units and speeds that
+// don't parse are returned unchanged.
+func ConvertWindSpeed(speed, unit string) string {
+	if unit == "" {
+		return speed
+	}
+	r, err := units.ParseWindSpeed(speed)
+	if err != nil {
+		return speed
+	}
+	return r.Convert(unit).String()
+}
+
+// convertSpeedRange converts a cached wind speed range between units, nil
+// when no wind was forecast
+func convertSpeedRange(r *models.SpeedRange, from, to string) *models.SpeedRange {
+	if r == nil {
+		return nil
+	}
+	return &models.SpeedRange{Min: units.ConvertWind(r.Min, from, to), Max: units.ConvertWind(r.Max, from, to)}
+}
+
+// ConvertPeriodWinds returns forecast periods with their wind speeds in unit,
+// leaving periods unchanged for an empty unit
+func ConvertPeriodWinds(periods []models.ForecastPeriod, unit string) []models.ForecastPeriod {
+	if unit == "" {
+		return periods
+	}
+	converted := make([]models.ForecastPeriod, len(periods))
+	for i, p := range periods {
+		p.WindSpeed = ConvertWindSpeed(p.WindSpeed, unit)
+		converted[i] = p
+	}
+	return converted
+}
+
+// ConvertHourlyWinds returns an hourly forecast with its wind speeds in unit,
+// leaving it unchanged for an empty unit
+func ConvertHourlyWinds(forecast *models.HourlyForecastResponse, unit string) *models.HourlyForecastResponse {
+	if unit == "" {
+		return forecast
+	}
+	converted := *forecast
+	converted.Hours = make([]models.HourlyForecast, len(forecast.Hours))
+	for i, h := range forecast.Hours {
+		h.WindSpeed = ConvertWindSpeed(h.WindSpeed, unit)
+		converted.Hours[i] = h
+	}
+	return &converted
+}
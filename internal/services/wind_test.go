@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"weather-api-go/internal/models"
+	"weather-api-go/internal/units"
+)
+
+func TestConvertWindSpeed(t *testing.T) {
+	tests := []struct {
+		speed string
+		unit  string
+		want  string
+	}{
+		{"5 to 10 mph", "", "5 to 10 mph"},
+		{"5 to 10 mph", units.KMH, "8 to 16.1 km/h"},
+		{"10 mph", units.MS, "4.5 m/s"},
+		{"15 km/h", units.MPH, "9.3 mph"},
+		{"20 mph", units.KN, "17.4 kn"},
+		{"calm", units.KMH, "calm"},
+	}
+
+	for _, tt := range tests {
+		if got := ConvertWindSpeed(tt.speed, tt.unit); got != tt.want {
+			t.Errorf("ConvertWindSpeed(%q, %q) = %q; want %q", tt.speed, tt.unit, got, tt.want)
+		}
+	}
+}
+
+func TestConvertPeriodAndHourlyWinds(t *testing.T) {
+	periods := []models.ForecastPeriod{{Name: "Tonight", WindSpeed: "10 mph"}}
+	converted := ConvertPeriodWinds(periods, units.KN)
+	if converted[0].WindSpeed != "8.7 kn" {
+		t.Errorf("period wind = %q; want 8.7 kn", converted[0].WindSpeed)
+	}
+	if periods[0].WindSpeed != "10 mph" {
+		t.Errorf("ConvertPeriodWinds modified its input: %q", periods[0].WindSpeed)
+	}
+
+	hourly := &models.HourlyForecastResponse{Hours: []models.HourlyForecast{{WindSpeed: "5 to 10 mph"}}}
+	got := ConvertHourlyWinds(hourly, units.KMH)
+	if got.Hours[0].WindSpeed != "8 to 16.1 km/h" {
+		t.Errorf("hourly wind = %q; want 8 to 16.1 km/h", got.Hours[0].WindSpeed)
+	}
+	if hourly.Hours[0].WindSpeed != "5 to 10 mph" {
+		t.Errorf("ConvertHourlyWinds modified its input: %q", hourly.Hours[0].WindSpeed)
+	}
+	if ConvertHourlyWinds(hourly, "") != hourly {
+		t.Error("ConvertHourlyWinds with no unit should return its input")
+	}
+}
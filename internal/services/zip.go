@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"weather-api-go/internal/models"
+)
+
+// ZIPResolver resolves US ZIP codes to the places they cover
+type ZIPResolver interface {
+	// ResolveZIP returns the place a five-digit ZIP code covers, at its
+	// centroid, with upstream requests bound by ctx. An unknown ZIP code is
+	// nil, not an error.
+	ResolveZIP(ctx context.Context, zip string) (*models.Place, error)
+}
+
+// ParseZIP validates a ZIP or ZIP+4 code, returning its five-digit ZIP code
+func ParseZIP(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	zip, plus4, hasPlus4 := strings.Cut(raw, "-")
+	if !allDigits(zip, 5) || (hasPlus4 && !allDigits(plus4, 4)) {
+		return "", false
+	}
+	return zip, true
+}
+
+// allDigits reports whether s is n ASCII digits
+func allDigits(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// DefaultZippopotamURL is the public Zippopotam.us API
+const DefaultZippopotamURL = "https://api.zippopotam.us"
+
+// ZippopotamConfig configures a ZippopotamClient
+type ZippopotamConfig struct {
+	// BaseURL is the Zippopotam.us host; empty uses DefaultZippopotamURL
+	BaseURL string
+	// HTTPClient replaces the default client, such as for tests
+	HTTPClient *http.Client
+}
+
+// ZippopotamClient resolves ZIP codes with the Zippopotam.us API
+type ZippopotamClient struct {
+	cfg ZippopotamConfig
+}
+
+// NewZippopotamClient creates a Zippopotam.us client
+func NewZippopotamClient(cfg ZippopotamConfig) *ZippopotamClient {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultZippopotamURL
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: DefaultUpstreamTimeout}
+	}
+	return &ZippopotamClient{cfg: cfg}
+}
+
+// zippopotamResponse is a Zippopotam.us postal code document
+type zippopotamResponse struct {
+	PostCode string `json:"post code"`
+	Places   []struct {
+		PlaceName         string `json:"place name"`
+		StateAbbreviation string `json:"state abbreviation"`
+		Latitude          string `json:"latitude"`
+		Longitude         string `json:"longitude"`
+	} `json:"places"`
+}
+
+// ResolveZIP looks up a ZIP code. A ZIP code listing several places is named
+// after the first and located at the mean of their coordinates.
+func (z *ZippopotamClient) ResolveZIP(ctx context.Context, zip string) (*models.Place, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, z.cfg.BaseURL+"/us/"+url.PathEscape(zip), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := z.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ZIP code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ZIP code lookup returned status: %d", resp.StatusCode)
+	}
+
+	var doc zippopotamResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, MaxDocumentBytes)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode ZIP code: %w", err)
+	}
+
+	var place *models.Place
+	var latSum, lonSum float64
+	var n int
+	for _, p := range doc.Places {
+		lat, latErr := strconv.ParseFloat(p.Latitude, 64)
+		lon, lonErr := strconv.ParseFloat(p.Longitude, 64)
+		if latErr != nil || lonErr != nil {
+			continue
+		}
+		if place == nil {
+			place = &models.Place{Name: fmt.Sprintf("%s, %s %s", p.PlaceName, p.StateAbbreviation, zip)}
+		}
+		latSum, lonSum, n = latSum+lat, lonSum+lon, n+1
+	}
+	if place == nil {
+		return nil, nil
+	}
+	place.Latitude, place.Longitude = latSum/float64(n), lonSum/float64(n)
+	return place, nil
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"weather-api-go/internal/models"
+)
+
+func TestParseZIP(t *testing.T) {
+	tests := []struct {
+		raw    string
+		want   string
+		wantOK bool
+	}{
+		{"10001", "10001", true},
+		{" 10001 ", "10001", true},
+		{"10001-1234", "10001", true},
+		{"00501", "00501", true},
+		{"1000", "", false},
+		{"100011", "", false},
+		{"1000a", "", false},
+		{"10001-123", "", false},
+		{"10001-", "", false},
+		{"10001 1234", "", false},
+		{"１０００１", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseZIP(tt.raw)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseZIP(%q) = %q, %v; want %q, %v", tt.raw, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestZippopotamClient(t *testing.T) {
+	tests := []struct {
+		zip     string
+		status  int
+		fixture string
+		want    *models.Place
+		wantErr bool
+	}{
+		{"10001", http.StatusOK, "zippopotam_10001.json", &models.Place{Name: "New York City, NY 10001", Latitude: 40.7484, Longitude: -73.9967}, false},
+		// Several places are located at their mean
+		{"05401", http.StatusOK, "zippopotam_multiple.json", &models.Place{Name: "Burlington, VT 05401", Latitude: 44.47, Longitude: -73.19}, false},
+		{"00000", http.StatusNotFound, "", nil, false},
+		{"10001", http.StatusInternalServerError, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		var body []byte
+		if tt.fixture != "" {
+			var err error
+			if body, err = os.ReadFile(filepath.Join("testdata", tt.fixture)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var gotPath string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotPath = r.URL.Path
+			w.WriteHeader(tt.status)
+			w.Write(body)
+		}))
+
+		client := NewZippopotamClient(ZippopotamConfig{BaseURL: server.URL + "/", HTTPClient: server.Client()})
+		place, err := client.ResolveZIP(context.Background(), tt.zip)
+		server.Close()
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("ResolveZIP(%s) error = %v; wantErr %v", tt.zip, err, tt.wantErr)
+		}
+		if gotPath != "/us/"+tt.zip {
+			t.Errorf("ResolveZIP(%s) requested %s; want /us/%s", tt.zip, gotPath, tt.zip)
+		}
+		if (place == nil) != (tt.want == nil) || (place != nil && (place.Name != tt.want.Name ||
+			math.Abs(place.Latitude-tt.want.Latitude) > 1e-9 || math.Abs(place.Longitude-tt.want.Longitude) > 1e-9)) {
+			t.Errorf("ResolveZIP(%s) = %+v; want %+v", tt.zip, place, tt.want)
+		}
+	}
+}
+
+// fakeZIPResolver returns canned places per ZIP code, counting lookups
+type fakeZIPResolver struct {
+	places map[string]models.Place
+	err    error
+	calls  int
+}
+
+func (z *fakeZIPResolver) ResolveZIP(ctx context.Context, zip string) (*models.Place, error) {
+	z.calls++
+	if z.err != nil {
+		return nil, z.err
+	}
+	place, ok := z.places[zip]
+	if !ok {
+		return nil, nil
+	}
+	return &place, nil
+}
+
+func TestResolveZIP(t *testing.T) {
+	chelsea := models.Place{Name: "New York City, NY 10001", Latitude: 40.7484, Longitude: -73.9967}
+	resolver := &fakeZIPResolver{places: map[string]models.Place{"10001": chelsea}}
+	repo := newTestRepo(t)
+	service := NewWeatherService(repo, nil, WithZIPResolver(resolver))
+
+	if place, err := service.ResolveZIP(context.Background(), "10001-2345"); err != nil || *place != chelsea {
+		t.Errorf("ResolveZIP(10001-2345) = %+v, %v; want %+v", place, err, chelsea)
+	}
+	if _, err := service.ResolveZIP(context.Background(), "99999"); !errors.Is(err, ErrZIPNotFound) {
+		t.Errorf("unknown ZIP err = %v; want ErrZIPNotFound", err)
+	}
+	if _, err := service.ResolveZIP(context.Background(), "NY 10001"); !errors.Is(err, ErrInvalidZIP) {
+		t.Errorf("malformed ZIP err = %v; want ErrInvalidZIP", err)
+	}
+
+	// Cached per five-digit ZIP code, including misses
+	calls := resolver.calls
+	for _, zip := range []string{"10001", "99999"} {
+		service.ResolveZIP(context.Background(), zip)
+	}
+	if resolver.calls != calls {
+		t.Errorf("repeat lookups made %d resolver calls; want none", resolver.calls-calls)
+	}
+
+	// A stale result is used when the resolver fails
+	stale := &models.GeocodeCache{Query: "zip:97201", Places: []models.Place{{Name: "Portland, OR 97201", Latitude: 45.5, Longitude: -122.7}}, Timestamp: time.Now().Add(-60 * 24 * time.Hour)}
+	if err := repo.SaveGeocode(context.Background(), stale); err != nil {
+		t.Fatal(err)
+	}
+	resolver.err = errors.New("resolver down")
+	if place, err := service.ResolveZIP(context.Background(), "97201"); err != nil || place.Name != "Portland, OR 97201" {
+		t.Errorf("stale lookup = %+v, %v; want the cached place", place, err)
+	}
+	if _, err := service.ResolveZIP(context.Background(), "60601"); !errors.Is(err, resolver.err) {
+		t.Errorf("uncached lookup err = %v; want the resolver error", err)
+	}
+
+	if _, err := NewWeatherService(repo, nil).ResolveZIP(context.Background(), "10001"); !errors.Is(err, ErrGeocodingDisabled) {
+		t.Errorf("disabled err = %v; want ErrGeocodingDisabled", err)
+	}
+}
@@ -0,0 +1,143 @@
+// Package tracing wires the service into OpenTelemetry. Spans are exported over
+// OTLP/HTTP when an OTLP endpoint is configured through the standard OTEL_*
+// environment variables; otherwise every span is a no-op.
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName is the service.name reported when OTEL_SERVICE_NAME is unset
+const ServiceName = "weather-api-go"
+
+// Span attributes shared by the repository and NWS client spans
+const (
+	// LatitudeKey and LongitudeKey locate the coordinate a span looked up
+	LatitudeKey  = attribute.Key("weather.latitude")
+	LongitudeKey = attribute.Key("weather.longitude")
+	// CacheTierKey names the cache tier a span read or wrote (redis or sqlite)
+	CacheTierKey = attribute.Key("cache.tier")
+	// CacheHitKey reports whether a cache read found an entry
+	CacheHitKey = attribute.Key("cache.hit")
+)
+
+// Coordinate returns the span attributes for a coordinate
+func Coordinate(lat, lon float64) []attribute.KeyValue {
+	return []attribute.KeyValue{LatitudeKey.Float64(lat), LongitudeKey.Float64(lon)}
+}
+
+// Enabled reports whether an OTLP endpoint is configured and the SDK isn't disabled
+func Enabled() bool {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return false
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Setup installs an OTLP/HTTP exporting tracer provider and the W3C trace
+// context propagator when tracing is Enabled. The exporter, sampler, and
+// resource read the standard OTEL_* variables. The returned function flushes
+// and stops the exporter; it does nothing when tracing is disabled.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	if !Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Attributes from OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the default name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// End records err on span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// requestCarrier reads propagated trace headers from a Fiber request
+type requestCarrier struct {
+	c *fiber.Ctx
+}
+
+func (rc requestCarrier) Get(key string) string {
+	return rc.c.Get(key)
+}
+
+func (rc requestCarrier) Set(key, value string) {
+	rc.c.Request().Header.Set(key, value)
+}
+
+func (rc requestCarrier) Keys() []string {
+	var keys []string
+	rc.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+// Middleware starts a server span for each request, continuing any trace
+// propagated by the caller, and makes it the request's user context so spans
+// started further down join it. The span is named after the matched route.
+func Middleware() fiber.Handler {
+	tracer := otel.Tracer(ServiceName)
+	return func(c *fiber.Ctx) error {
+		parent := otel.GetTextMapPropagator().Extract(c.UserContext(), requestCarrier{c})
+		ctx, span := tracer.Start(parent, c.Method()+" "+c.Path(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPRequestMethodKey.String(c.Method()), semconv.URLPath(c.Path())),
+		)
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		// Errors returned here are turned into responses by the app's error handler later
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if fe, ok := err.(*fiber.Error); ok {
+				status = fe.Code
+			}
+			span.RecordError(err)
+		}
+		route := c.Route().Path
+		span.SetName(c.Method() + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		return err
+	}
+}
@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		traces   string
+		disabled string
+		want     bool
+	}{
+		{"no endpoint", "", "", "", false},
+		{"endpoint", "http://collector:4318", "", "", true},
+		{"traces endpoint", "", "http://collector:4318/v1/traces", "", true},
+		{"SDK disabled", "http://collector:4318", "", "true", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", tt.endpoint)
+			t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", tt.traces)
+			t.Setenv("OTEL_SDK_DISABLED", tt.disabled)
+			if got := Enabled(); got != tt.want {
+				t.Errorf("Enabled() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetupWithoutEndpointIsNoop(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+
+	shutdown, err := Setup(context.Background())
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	_, span := otel.Tracer(ServiceName).Start(context.Background(), "test")
+	defer span.End()
+	if span.IsRecording() || span.SpanContext().IsValid() {
+		t.Error("span is recording without an endpoint; want a no-op span")
+	}
+}
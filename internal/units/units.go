@@ -0,0 +1,174 @@
+package units
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Wind speed units accepted by the API
+const (
+	MPH = "mph"
+	KMH = "kmh"
+	MS  = "ms"
+	KN  = "kn"
+)
+
+// Unit systems a response can be shaped for
+const (
+	Metric   = "metric"
+	Imperial = "imperial"
+	Both     = "both"
+)
+
+// Systems lists the accepted unit systems in display order
+var Systems = []string{Metric, Imperial, Both}
+
+// ParseSystem validates a unit system name. Empty selects Both.
+func ParseSystem(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", Both:
+		return Both, nil
+	case Metric:
+		return Metric, nil
+	case Imperial:
+		return Imperial, nil
+	}
+	return "", fmt.Errorf("unknown unit system %q (accepted: %s)", s, strings.Join(Systems, ", "))
+}
+
+// IncludesMetric reports whether a unit system shows metric values
+func IncludesMetric(system string) bool {
+	return system != Imperial
+}
+
+// IncludesImperial reports whether a unit system shows imperial values
+func IncludesImperial(system string) bool {
+	return system != Metric
+}
+
+// WindUnits lists the accepted wind speed units in display order
+var WindUnits = []string{MPH, KMH, MS, KN}
+
+// Meters per second in one unit of each wind speed
+var metersPerSecond = map[string]float64{
+	MPH: 0.44704,
+	KMH: 1000.0 / 3600.0,
+	MS:  1,
+	KN:  1852.0 / 3600.0,
+}
+
+// CelsiusToFahrenheit converts a temperature from Celsius to Fahrenheit
+func CelsiusToFahrenheit(c float64) float64 {
+	return c*9/5 + 32
+}
+
+// FahrenheitToCelsius converts a temperature from Fahrenheit to Celsius
+func FahrenheitToCelsius(f float64) float64 {
+	return (f - 32) * 5 / 9
+}
+
+// KelvinToCelsius converts a temperature from Kelvin to Celsius
+func KelvinToCelsius(k float64) float64 {
+	return k - 273.15
+}
+
+// ParseWindUnit validates a wind unit name, accepting common aliases
+func ParseWindUnit(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "mph":
+		return MPH, nil
+	case "kmh", "km/h", "kph":
+		return KMH, nil
+	case "ms", "m/s", "mps":
+		return MS, nil
+	case "kn", "kt", "kts", "knots":
+		return KN, nil
+	}
+	return "", fmt.Errorf("unknown wind unit %q (accepted: %s)", s, strings.Join(WindUnits, ", "))
+}
+
+// ConvertWind converts a wind speed between units. Both units must be valid.
+func ConvertWind(v float64, from, to string) float64 {
+	if from == to {
+		return v
+	}
+	return v * metersPerSecond[from] / metersPerSecond[to]
+}
+
+// WindRange is a wind speed range as reported by the NWS, e.g. "5 to 10 mph".
+// Single-value speeds have Min equal to Max.
+type WindRange struct {
+	Min  float64
+	Max  float64
+	Unit string
+}
+
+// Convert returns the range expressed in another unit, converting both endpoints
+func (r WindRange) Convert(to string) WindRange {
+	return WindRange{
+		Min:  ConvertWind(r.Min, r.Unit, to),
+		Max:  ConvertWind(r.Max, r.Unit, to),
+		Unit: to,
+	}
+}
+
+// windLabels are the unit names String writes, as the NWS and common usage
+// spell them
+var windLabels = map[string]string{MPH: "mph", KMH: "km/h", MS: "m/s", KN: "kn"}
+
+// String formats the range as the NWS words speeds, such as "5 to 10 mph",
+// with each endpoint rounded to a tenth
+func (r WindRange) String() string {
+	format := func(v float64) string {
+		return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64)
+	}
+	if format(r.Min) == format(r.Max) {
+		return format(r.Max) + " " + windLabels[r.Unit]
+	}
+	return format(r.Min) + " to " + format(r.Max) + " " + windLabels[r.Unit]
+}
+
+// ParseWindSpeed parses NWS wind speed strings such as "10 mph", "5 to 10 mph",
+// or "15 km/h". The unit defaults to mph when omitted.
+func ParseWindSpeed(s string) (WindRange, error) {
+	fields := strings.Fields(strings.TrimSpace(s))
+	if len(fields) == 0 {
+		return WindRange{}, fmt.Errorf("empty wind speed")
+	}
+
+	unit := MPH
+	if _, err := strconv.ParseFloat(fields[len(fields)-1], 64); err != nil {
+		u, err := ParseWindUnit(fields[len(fields)-1])
+		if err != nil {
+			return WindRange{}, fmt.Errorf("invalid wind speed %q: %w", s, err)
+		}
+		unit = u
+		fields = fields[:len(fields)-1]
+	}
+
+	var minStr, maxStr string
+	switch {
+	case len(fields) == 1:
+		minStr, maxStr = fields[0], fields[0]
+	case len(fields) == 3 && strings.EqualFold(fields[1], "to"):
+		minStr, maxStr = fields[0], fields[2]
+	default:
+		return WindRange{}, fmt.Errorf("invalid wind speed %q", s)
+	}
+
+	min, err := strconv.ParseFloat(minStr, 64)
+	if err != nil {
+		return WindRange{}, fmt.Errorf("invalid wind speed %q: %w", s, err)
+	}
+	max, err := strconv.ParseFloat(maxStr, 64)
+	if err != nil {
+		return WindRange{}, fmt.Errorf("invalid wind speed %q: %w", s, err)
+	}
+	if math.IsNaN(min) || math.IsNaN(max) || math.IsInf(max, 0) || min < 0 || max < min {
+		return WindRange{}, fmt.Errorf("invalid wind speed range %q", s)
+	}
+
+	return WindRange{Min: min, Max: max, Unit: unit}, nil
+}
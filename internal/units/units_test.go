@@ -0,0 +1,187 @@
+package units
+
+import (
+	"math"
+	"testing"
+)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 0.01
+}
+
+func TestConvertWind(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    float64
+		from, to string
+		expected float64
+	}{
+		{"mph to kmh", 10, MPH, KMH, 16.09},
+		{"kmh to mph", 100, KMH, MPH, 62.14},
+		{"mph to ms", 10, MPH, MS, 4.47},
+		{"ms to kn", 10, MS, KN, 19.44},
+		{"kn to kmh", 10, KN, KMH, 18.52},
+		{"same unit", 7, KN, KN, 7},
+		{"zero", 0, MPH, KMH, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ConvertWind(tt.value, tt.from, tt.to)
+			if !approxEqual(result, tt.expected) {
+				t.Errorf("ConvertWind(%v, %s, %s) = %.4f; want %.2f", tt.value, tt.from, tt.to, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTemperatureConversion(t *testing.T) {
+	tests := []struct {
+		c, f, k float64
+	}{
+		{0, 32, 273.15},
+		{100, 212, 373.15},
+		{-40, -40, 233.15},
+		{22.5, 72.5, 295.65},
+	}
+
+	for _, tt := range tests {
+		if got := KelvinToCelsius(tt.k); !approxEqual(got, tt.c) {
+			t.Errorf("KelvinToCelsius(%v) = %v; want %v", tt.k, got, tt.c)
+		}
+		if got := CelsiusToFahrenheit(tt.c); !approxEqual(got, tt.f) {
+			t.Errorf("CelsiusToFahrenheit(%v) = %v; want %v", tt.c, got, tt.f)
+		}
+		if got := FahrenheitToCelsius(tt.f); !approxEqual(got, tt.c) {
+			t.Errorf("FahrenheitToCelsius(%v) = %v; want %v", tt.f, got, tt.c)
+		}
+	}
+}
+
+func TestParseWindUnit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"mph", MPH, false},
+		{"MPH", MPH, false},
+		{"kmh", KMH, false},
+		{"km/h", KMH, false},
+		{"ms", MS, false},
+		{"m/s", MS, false},
+		{"kn", KN, false},
+		{"knots", KN, false},
+		{"furlongs", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseWindUnit(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWindUnit(%q) error = %v; wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("ParseWindUnit(%q) = %q; want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseSystem(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"", Both, false},
+		{"both", Both, false},
+		{"metric", Metric, false},
+		{" Imperial ", Imperial, false},
+		{"si", "", true},
+	}
+
+	for _, tt := range tests {
+		result, err := ParseSystem(tt.input)
+		if (err != nil) != tt.wantErr || result != tt.expected {
+			t.Errorf("ParseSystem(%q) = %q, %v; want %q (error %v)", tt.input, result, err, tt.expected, tt.wantErr)
+		}
+	}
+}
+
+func TestParseWindSpeed(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected WindRange
+		wantErr  bool
+	}{
+		{"10 mph", WindRange{10, 10, MPH}, false},
+		{"5 to 10 mph", WindRange{5, 10, MPH}, false},
+		{"15 km/h", WindRange{15, 15, KMH}, false},
+		{"10 to 20 km/h", WindRange{10, 20, KMH}, false},
+		{"0 mph", WindRange{0, 0, MPH}, false},
+		{"12", WindRange{12, 12, MPH}, false},
+		{" 5 TO 10 MPH ", WindRange{5, 10, MPH}, false},
+		{"2.5 to 4 m/s", WindRange{2.5, 4, MS}, false},
+		{"", WindRange{}, true},
+		{"breezy", WindRange{}, true},
+		{"mph", WindRange{}, true},
+		{"5 to mph", WindRange{}, true},
+		{"10 to 5 mph", WindRange{}, true},
+		{"5 or 10 mph", WindRange{}, true},
+		{"-5 mph", WindRange{}, true},
+		{"NaN mph", WindRange{}, true},
+		{"5 to NaN mph", WindRange{}, true},
+		{"5 to Inf mph", WindRange{}, true},
+		{"10 mph gusting to 25", WindRange{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseWindSpeed(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWindSpeed(%q) error = %v; wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("ParseWindSpeed(%q) = %+v; want %+v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWindRangeConvertBothEndpoints(t *testing.T) {
+	r, err := ParseWindSpeed("5 to 10 mph")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kmh := r.Convert(KMH)
+	if kmh.Unit != KMH || !approxEqual(kmh.Min, 8.05) || !approxEqual(kmh.Max, 16.09) {
+		t.Errorf("Convert(kmh) = %+v; want {8.05 16.09 kmh}", kmh)
+	}
+
+	back := kmh.Convert(MPH)
+	if !approxEqual(back.Min, r.Min) || !approxEqual(back.Max, r.Max) {
+		t.Errorf("round trip = %+v; want %+v", back, r)
+	}
+}
+
+func TestWindRangeString(t *testing.T) {
+	tests := []struct {
+		r        WindRange
+		expected string
+	}{
+		{WindRange{5, 10, MPH}, "5 to 10 mph"},
+		{WindRange{10, 10, MPH}, "10 mph"},
+		{WindRange{8.04672, 16.09344, KMH}, "8 to 16.1 km/h"},
+		{WindRange{2.5, 2.54, MS}, "2.5 m/s"},
+		{WindRange{4.3, 8.7, KN}, "4.3 to 8.7 kn"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.r.String(); got != tt.expected {
+			t.Errorf("%+v.String() = %q; want %q", tt.r, got, tt.expected)
+		}
+	}
+}
@@ -16,76 +16,542 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	// Embeds the time zone database for ?tz= and NWS zones, since the Alpine
+	// runtime image has none
+	_ "time/tzdata"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/redis/go-redis/v9"
 
+	"weather-api-go/internal/certreload"
+	"weather-api-go/internal/config"
+	"weather-api-go/internal/geoip"
 	"weather-api-go/internal/handlers"
+	"weather-api-go/internal/jsoncase"
+	"weather-api-go/internal/jsoncodec"
+	"weather-api-go/internal/metrics"
+	"weather-api-go/internal/middleware"
 	"weather-api-go/internal/repository"
 	"weather-api-go/internal/services"
+	"weather-api-go/internal/tracing"
 )
 
 var ctx = context.Background()
 
-func initRedis() *redis.Client {
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		redisURL = "localhost:6379"
-	}
-
-	client := redis.NewClient(&redis.Options{
-		Addr:     redisURL,
-		Password: "",
-		DB:       0,
-	})
+// initRedis connects to Redis, returning nil to run on SQLite alone when it
+// can't be reached unless cfg.Required is set
+func initRedis(cfg config.Redis) *redis.Client {
+	client := redis.NewClient(cfg.Options)
 
 	if _, err := client.Ping(ctx).Result(); err != nil {
-		log.Printf("Redis connection failed: %v, falling back to SQLite only", err)
+		client.Close()
+		if cfg.Required {
+			log.Fatalf("Redis connection to %s failed: %v (REDIS_REQUIRED is set)", cfg.Options.Addr, err)
+		}
+		log.Printf("Redis connection to %s failed: %v, falling back to SQLite only", cfg.Options.Addr, err)
 		return nil
 	}
 
-	log.Println("Connected to Redis")
+	log.Printf("Connected to Redis at %s", cfg.Options.Addr)
 	return client
 }
 
+// envFloat reads a float from the environment, falling back to def when unset
+func envFloat(key string, def float64) float64 {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: %v", key, raw, err)
+	}
+	return v
+}
+
+// envInt reads an integer from the environment, falling back to def when unset
+func envInt(key string, def int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		log.Fatalf("Invalid %s %q: must be a non-negative integer", key, raw)
+	}
+	return v
+}
+
+// envDuration reads a duration (e.g. 30s) from the environment, falling back to def when unset
+func envDuration(key string, def time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	v, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: %v", key, raw, err)
+	}
+	return v
+}
+
+// envPositiveDuration is envDuration for settings where zero or a negative
+// duration makes no sense, such as TTLs
+func envPositiveDuration(key string, def time.Duration) time.Duration {
+	v := envDuration(key, def)
+	if v <= 0 {
+		log.Fatalf("Invalid %s %q: must be a positive duration", key, os.Getenv(key))
+	}
+	return v
+}
+
+// envDate reads a date (e.g. 2027-04-16) from the environment, falling back to def when unset
+func envDate(key string, def time.Time) time.Time {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	v, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: must be a date like 2027-04-16", key, raw)
+	}
+	return v
+}
+
+// envList reads a comma-separated list from the environment
+func envList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func loadAdvisoryThresholds() services.AdvisoryThresholds {
+	t := services.DefaultAdvisoryThresholds()
+	t.FrostTempC = envFloat("FROST_TEMP_C", t.FrostTempC)
+	t.HardFreezeC = envFloat("HARD_FREEZE_C", t.HardFreezeC)
+	t.FrostMaxWindKmh = envFloat("FROST_MAX_WIND_KMH", t.FrostMaxWindKmh)
+	t.HeatIndexC = envFloat("HEAT_INDEX_C", t.HeatIndexC)
+	t.HeatIndexDangerC = envFloat("HEAT_INDEX_DANGER_C", t.HeatIndexDangerC)
+	if err := t.Validate(); err != nil {
+		log.Fatalf("Invalid advisory thresholds: %v", err)
+	}
+	return t
+}
+
+func loadTemperatureThresholds() services.TemperatureThresholds {
+	t := services.DefaultTemperatureThresholds()
+	t.HotC = envFloat("TEMP_HOT_C", t.HotC)
+	t.ColdC = envFloat("TEMP_COLD_C", t.ColdC)
+	if err := t.Validate(); err != nil {
+		log.Fatalf("Invalid temperature thresholds: %v", err)
+	}
+	return t
+}
+
+// loadSheddingConfig reads the upstream concurrency and load-shedding limits.
+// Shedding is disabled unless UPSTREAM_MAX_IN_FLIGHT is set.
+func loadSheddingConfig() services.LoadSheddingConfig {
+	return services.LoadSheddingConfig{
+		MaxInFlight:   envInt("UPSTREAM_MAX_IN_FLIGHT", 0),
+		MaxQueueDepth: envInt("UPSTREAM_MAX_QUEUE", 32),
+		MaxQueueWait:  envDuration("UPSTREAM_MAX_WAIT", 2*time.Second),
+		RetryAfter:    envDuration("SHED_RETRY_AFTER", services.DefaultRetryAfter),
+	}
+}
+
 func main() {
-	app := fiber.New()
+	listen, err := config.LoadListen(os.Getenv)
+	if err != nil {
+		log.Fatalf("Invalid listen address: %v", err)
+	}
+	tlsConfig, err := config.LoadTLS(os.Getenv)
+	if err != nil {
+		log.Fatalf("Invalid TLS configuration: %v", err)
+	}
+	providers, err := config.LoadProviders(os.Getenv)
+	if err != nil {
+		log.Fatalf("Invalid forecast provider configuration: %v", err)
+	}
+	compression, err := config.LoadCompression(os.Getenv)
+	if err != nil {
+		log.Fatalf("Invalid compression configuration: %v", err)
+	}
+	redisConfig, err := config.LoadRedis(os.Getenv)
+	if err != nil {
+		log.Fatalf("Invalid Redis configuration: %v", err)
+	}
+	warmLocations, err := config.LoadWarmLocations(os.Getenv)
+	if err != nil {
+		log.Fatalf("Invalid cache warming configuration: %v", err)
+	}
+	codec, err := jsoncodec.Lookup(os.Getenv("JSON_CODEC"))
+	if err != nil {
+		log.Fatalf("Invalid JSON_CODEC: %v", err)
+	}
+	jsonCase, err := jsoncase.Parse(os.Getenv("JSON_CASE"))
+	if err != nil {
+		log.Fatalf("Invalid JSON_CASE: %v", err)
+	}
+
+	proxy, err := config.LoadProxy(os.Getenv)
+	if err != nil {
+		log.Fatalf("Invalid proxy configuration: %v", err)
+	}
+
+	app := fiber.New(fiber.Config{
+		// Forwarded headers (X-Forwarded-Proto/Host) are only honored from these proxies
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          envList("TRUSTED_PROXIES"),
+		JSONEncoder:             jsoncase.Encoder(codec.Marshal, jsonCase),
+		JSONDecoder:             codec.Unmarshal,
+	})
 
 	app.Use(recover.New())
 	app.Use(logger.New())
 	app.Use(cors.New())
+	// Compression wraps everything below, so the response cache and validator
+	// see uncompressed bodies
+	app.Use(middleware.Compress(middleware.CompressConfig{Level: compression.Level, MinSize: compression.MinSize}))
+	// Property naming follows ?case=camel|snake, defaulting to JSON_CASE
+	app.Use(jsoncase.Middleware(jsonCase))
+
+	// Development-mode response validation against the OpenAPI spec
+	if os.Getenv("VALIDATE_RESPONSES") == "true" {
+		spec, err := handlers.OpenAPISpecJSON()
+		if err != nil {
+			log.Fatalf("Failed to render OpenAPI spec: %v", err)
+		}
+		validator, err := middleware.ValidateResponses(middleware.ValidationConfig{
+			Spec:           spec,
+			FailOnMismatch: os.Getenv("VALIDATE_RESPONSES_MODE") == "fail",
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize response validation: %v", err)
+		}
+		app.Use(validator)
+		log.Println("OpenAPI response validation enabled")
+	}
 
 	// Initialize database
-	db, err := repository.InitDB("./weather_cache.db")
+	database := config.LoadDatabase(os.Getenv)
+	db, err := repository.InitDB(database.SQLitePath)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer db.Close()
 
+	// Forecasts go to Postgres instead when DATABASE_URL points at one, so
+	// replicas share them
+	var forecastStore *repository.PostgresStore
+	if database.PostgresURL != "" {
+		connectCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		forecastStore, err = repository.OpenPostgres(connectCtx, database.PostgresURL)
+		cancel()
+		if err != nil {
+			log.Fatalf("Failed to open the Postgres forecast store: %v", err)
+		}
+		defer forecastStore.Close()
+		log.Println("Keeping forecasts in Postgres")
+	}
+
 	// Initialize Redis
-	rdb := initRedis()
+	rdb := initRedis(redisConfig)
 	if rdb != nil {
 		defer rdb.Close()
 	}
 
+	// OpenTelemetry tracing, exported when OTEL_EXPORTER_OTLP_ENDPOINT is set
+	shutdownTracing, err := tracing.Setup(ctx)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		_ = shutdownTracing(flushCtx)
+	}()
+	if tracing.Enabled() {
+		log.Println("OpenTelemetry tracing enabled")
+	}
+
+	recorder := metrics.NewRecorder(metrics.DefaultWindow)
+
+	nearbyRadius := envFloat("NEARBY_CACHE_RADIUS_KM", repository.DefaultNearbyCacheRadiusKm)
+	if !(nearbyRadius >= 0) {
+		log.Fatalf("Invalid NEARBY_CACHE_RADIUS_KM %q: must be a non-negative number of kilometers", os.Getenv("NEARBY_CACHE_RADIUS_KM"))
+	}
+
 	// Initialize layered architecture
-	weatherRepo := repository.NewWeatherRepository(db, rdb)
-	nwsClient := services.NewNWSAPIClient()
-	weatherService := services.NewWeatherService(weatherRepo, nwsClient)
-	weatherHandler := handlers.NewWeatherHandler(weatherService)
+	repoOpts := []repository.WeatherRepositoryOption{
+		repository.WithCacheTTL(envPositiveDuration("CACHE_TTL", repository.DefaultWeatherCacheTTL)),
+		repository.WithMemoryCache(envInt("MEMORY_CACHE_SIZE", repository.DefaultMemoryCacheSize), envDuration("MEMORY_CACHE_TTL", 0)),
+		repository.WithNearbyCacheRadius(nearbyRadius),
+	}
+	if forecastStore != nil {
+		repoOpts = append(repoOpts, repository.WithForecastStore(forecastStore))
+	}
+	weatherRepo := repository.NewWeatherRepository(db, rdb, repoOpts...)
+
+	// Background jobs: request log writer, the daily stats rollup, and cache
+	// maintenance. They stop when the server shuts down on SIGINT or SIGTERM.
+	jobsCtx, stopJobs := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stopJobs()
+	requestLog := metrics.NewRequestLog(weatherRepo, metrics.DefaultRequestLogBuffer)
+	go requestLog.Run(jobsCtx, 5*time.Second)
+	statsService := services.NewStatsService(weatherRepo,
+		envDuration("REQUEST_LOG_RETENTION", services.DefaultRequestLogRetention))
+	go statsService.Run(jobsCtx)
+	maintenance := services.NewMaintenance(weatherRepo, services.MaintenanceConfig{
+		HistoryRawRetention: envDuration("HISTORY_RAW_RETENTION", repository.DefaultHistoryRawRetention),
+		CacheRetention:      envDuration("CACHE_RETENTION", services.DefaultCacheRetention),
+		MaxDatabaseBytes:    int64(envInt("DB_MAX_SIZE_MB", 0)) << 20,
+		PruneLowWater:       envFloat("DB_PRUNE_LOW_WATER", services.DefaultPruneLowWater),
+	})
+	go maintenance.Run(jobsCtx, envDuration("MAINTENANCE_INTERVAL", services.DefaultMaintenanceInterval))
+
+	retry := services.DefaultRetryConfig()
+	retry.MaxAttempts = envInt("NWS_MAX_ATTEMPTS", retry.MaxAttempts)
+	retry.MaxElapsed = envDuration("NWS_RETRY_BUDGET", retry.MaxElapsed)
+	transport := services.DefaultTransportConfig()
+	transport.Timeout = envPositiveDuration("NWS_TIMEOUT", transport.Timeout)
+	transport.MaxIdleConnsPerHost = envInt("NWS_MAX_IDLE_CONNS_PER_HOST", transport.MaxIdleConnsPerHost)
+	transport.IdleConnTimeout = envPositiveDuration("NWS_IDLE_CONN_TIMEOUT", transport.IdleConnTimeout)
+	transport.DialTimeout = envPositiveDuration("NWS_DIAL_TIMEOUT", transport.DialTimeout)
+	transport.TLSHandshakeTimeout = envPositiveDuration("NWS_TLS_HANDSHAKE_TIMEOUT", transport.TLSHandshakeTimeout)
+	nwsClient := services.NewNWSAPIClient(
+		services.WithUserAgent(os.Getenv("NWS_USER_AGENT")),
+		services.WithTransport(transport),
+		services.WithRetry(retry),
+		services.WithRateLimit(services.RateLimitConfig{
+			RequestsPerSecond: envFloat("NWS_RATE_LIMIT", 0),
+			Burst:             envInt("NWS_RATE_BURST", 5),
+			MaxWait:           envDuration("NWS_RATE_MAX_WAIT", services.DefaultRateLimitWait),
+		}),
+		services.WithCircuitBreaker(services.CircuitBreakerConfig{
+			FailureThreshold: envInt("NWS_BREAKER_THRESHOLD", 5),
+			OpenDuration:     envDuration("NWS_BREAKER_OPEN_DURATION", services.DefaultCircuitOpenDuration),
+			HalfOpenProbes:   envInt("NWS_BREAKER_PROBES", services.DefaultCircuitHalfOpenProbes),
+			Recorder:         recorder,
+		}),
+	)
+	log.Printf("NWS client: %s", nwsClient.Transport())
+	geocoder := services.NewNominatimGeocoder(services.NominatimConfig{
+		BaseURL:     os.Getenv("GEOCODER_URL"),
+		UserAgent:   os.Getenv("GEOCODER_USER_AGENT"),
+		MinInterval: envDuration("GEOCODER_MIN_INTERVAL", services.DefaultNominatimInterval),
+	})
+	// Callers naming no location are located by IP when a City database is given
+	var ipLocator services.IPLocator
+	if path := os.Getenv("GEOIP_DB"); path != "" {
+		db, err := geoip.Open(path)
+		if err != nil {
+			log.Fatalf("Failed to open GeoIP database: %v", err)
+		}
+		ipLocator = db
+		log.Printf("Requests without a location are located by IP address using %s", path)
+	}
+	// Air quality needs an AirNow API key; without one /airquality answers 501
+	var airQuality services.AirQualityProvider
+	if key := os.Getenv("AIRNOW_API_KEY"); key != "" {
+		airQuality = services.NewAirNowClient(services.AirNowConfig{APIKey: key, BaseURL: os.Getenv("AIRNOW_URL")})
+	}
+	// The NWS-specific endpoints use the NWS whichever provider serves forecasts
+	var provider services.WeatherProvider = nwsClient
+	switch providers.Primary {
+	case config.ProviderOpenMeteo:
+		provider = services.NewOpenMeteoClient(services.OpenMeteoConfig{BaseURL: providers.OpenMeteoURL})
+	case config.ProviderOWM:
+		provider = services.NewOWMClient(services.OWMConfig{APIKey: providers.OWMAPIKey, BaseURL: providers.OWMURL, Retry: retry})
+	}
+	log.Printf("Forecasts are served by %s", provider.Name())
+	var fallback services.WeatherProvider
+	if providers.Fallback == config.ProviderOpenMeteo && providers.Primary == config.ProviderNWS {
+		fallback = services.NewOpenMeteoClient(services.OpenMeteoConfig{BaseURL: providers.OpenMeteoURL})
+		log.Printf("Coordinates outside NWS coverage fall back to Open-Meteo")
+	}
+	weatherService := services.NewWeatherService(weatherRepo, provider,
+		services.WithNWSFeatures(nwsClient),
+		services.WithFallbackProvider(fallback),
+		services.WithAdvisoryThresholds(loadAdvisoryThresholds()),
+		services.WithTemperatureThresholds(loadTemperatureThresholds()),
+		services.WithLoadShedding(loadSheddingConfig()),
+		services.WithMetrics(recorder),
+		services.WithCoverageCheck(os.Getenv("NWS_COVERAGE_CHECK") != "false"),
+		services.WithStaleWhileRevalidate(envDuration("STALE_WHILE_REVALIDATE", services.DefaultMaxStale)),
+		services.WithBatchConcurrency(envInt("BATCH_CONCURRENCY", services.DefaultBatchConcurrency)),
+		services.WithGeocoder(geocoder),
+		services.WithZIPResolver(services.NewZippopotamClient(services.ZippopotamConfig{BaseURL: os.Getenv("ZIP_LOOKUP_URL")})),
+		services.WithUVForecaster(services.NewEPAUVClient(services.EPAUVConfig{BaseURL: os.Getenv("UV_FORECAST_URL")})),
+		services.WithIPLocator(ipLocator),
+		services.WithAirQualityProvider(airQuality),
+	)
+	// The cache warmer refreshes the busiest locations before they expire,
+	// through the same NWS client and its rate limit as requests
+	warmer := services.NewCacheWarmer(weatherRepo, weatherService, services.WarmerConfig{
+		Locations: warmLocations,
+		Lead:      envPositiveDuration("WARM_LEAD", services.DefaultWarmLead),
+		Stagger:   envDuration("WARM_STAGGER", services.DefaultWarmStagger),
+	})
+	go warmer.Run(jobsCtx, envPositiveDuration("WARM_INTERVAL", services.DefaultWarmInterval))
+	// Webhook subscriptions are polled for new alerts in the background too
+	webhookConfig := services.DefaultWebhookConfig()
+	webhookConfig.Retry.MaxAttempts = envInt("WEBHOOK_MAX_ATTEMPTS", webhookConfig.Retry.MaxAttempts)
+	webhookConfig.Timeout = envPositiveDuration("WEBHOOK_TIMEOUT", webhookConfig.Timeout)
+	webhookConfig.AllowPrivateCallbacks = os.Getenv("WEBHOOK_ALLOW_PRIVATE_CALLBACKS") == "true"
+	webhooks := services.NewWebhooks(weatherRepo, weatherService, webhookConfig)
+	go webhooks.Run(jobsCtx, envPositiveDuration("WEBHOOK_POLL_INTERVAL", services.DefaultWebhookPollInterval))
+	weatherHandler := handlers.NewWeatherHandler(weatherService,
+		handlers.WithDatabaseUsage(maintenance.DatabaseUsage),
+		handlers.WithMaxBatchSize(envInt("BATCH_MAX_SIZE", handlers.DefaultMaxBatchSize)),
+		handlers.WithRequestTimeout(envPositiveDuration("REQUEST_TIMEOUT", handlers.DefaultRequestTimeout)),
+		handlers.WithQueryLimits(envInt("GRAPHQL_MAX_DEPTH", handlers.DefaultMaxQueryDepth), envInt("GRAPHQL_MAX_COMPLEXITY", handlers.DefaultMaxQueryComplexity)),
+		handlers.WithProxy(proxy),
+	)
+	metricsHandler := handlers.NewMetricsHandler(recorder)
+	statsHandler := handlers.NewStatsHandler(statsService)
+	cacheHandler := handlers.NewCacheHandler(maintenance, handlers.WithCacheWarmer(warmer))
+	subscriptionHandler := handlers.NewSubscriptionHandler(webhooks, handlers.WithSubscriptionOwner(middleware.APIKeyID))
 
-	// API Routes
-	api := app.Group("/api")
-	api.Get("/weather", weatherHandler.GetWeather)
-	api.Get("/health", weatherHandler.GetHealth)
+	// Optional HTTP response cache for the public weather-family routes
+	cached := func(c *fiber.Ctx) error { return c.Next() }
+	if os.Getenv("RESPONSE_CACHE") == "true" {
+		cfg := middleware.DefaultResponseCacheConfig()
+		cfg.MaxTTL = envDuration("RESPONSE_CACHE_MAX_TTL", cfg.MaxTTL)
+		cached = middleware.ResponseCache(cfg)
+		log.Printf("HTTP response cache enabled (max TTL %s)", cfg.MaxTTL)
+	}
 
-	// Futuristic API Documentation
-	app.Get("/docs", handlers.ServeAPIDocs)
+	// Optional API key authentication, from API_KEYS and the api_keys table
+	apiKeys := middleware.ParseAPIKeys(envList("API_KEYS"))
+	for i := range apiKeys {
+		apiKeys[i].DailyQuota = envInt("API_KEY_DAILY_QUOTA", 0)
+	}
+	storedKeys, err := weatherRepo.ListAPIKeys(ctx)
+	if err != nil {
+		log.Fatalf("Failed to load API keys: %v", err)
+	}
+	apiKeys = append(apiKeys, storedKeys...)
+	if len(apiKeys) > 0 {
+		log.Printf("API key authentication enabled for %d keys", len(apiKeys))
+	}
+	if quota := envInt("API_KEY_DAILY_QUOTA", 0); quota > 0 && len(envList("API_KEYS")) > 0 {
+		log.Printf("Daily quota of %d requests for keys from API_KEYS", quota)
+	}
+	if limit := envInt("CLIENT_RATE_LIMIT", 0); limit > 0 {
+		log.Printf("Per-client rate limit enabled (%d requests/minute)", limit)
+	}
+
+	// API Routes, under /api/v1 and the deprecated unversioned /api paths
+	api := app.Group(handlers.APIBasePath)
+	healthPaths := map[string]bool{
+		handlers.APIBasePath + "/" + handlers.APIVersion + "/health": true,
+		handlers.APIBasePath + "/health":                             true,
+	}
+	// Checking usage neither spends it nor is refused once it runs out
+	usagePaths := map[string]bool{
+		handlers.APIBasePath + "/" + handlers.APIVersion + "/me/usage": true,
+		handlers.APIBasePath + "/me/usage":                             true,
+	}
+	if tracing.Enabled() {
+		api.Use(tracing.Middleware())
+	}
+	api.Use(recorder.Middleware())
+	api.Use(requestLog.Middleware())
+	api.Use(middleware.RequireAPIKey(middleware.APIKeyConfig{
+		Keys: apiKeys,
+		Next: func(c *fiber.Ctx) bool { return healthPaths[c.Path()] },
+	}))
+	api.Use(middleware.RateLimit(middleware.RateLimitConfig{
+		RequestsPerMinute: envInt("CLIENT_RATE_LIMIT", 0),
+		Burst:             envInt("CLIENT_RATE_BURST", 0),
+		Redis:             rdb,
+		Next:              func(c *fiber.Ctx) bool { return healthPaths[c.Path()] },
+		Free:              func(c *fiber.Ctx) bool { return usagePaths[c.Path()] },
+	}))
+	quota := middleware.NewQuota(middleware.QuotaConfig{
+		Keys:  apiKeys,
+		Store: weatherRepo,
+		Free:  func(c *fiber.Ctx) bool { return usagePaths[c.Path()] },
+	})
+	api.Use(quota.Middleware())
+	routes := handlers.NewRoutes(api, handlers.APIVersion,
+		handlers.WithUnversionedAliases(handlers.UnversionedDeprecated, envDate("UNVERSIONED_API_SUNSET", handlers.DefaultUnversionedSunset)))
+	routes.Get("/weather", cached, weatherHandler.GetWeather)
+	routes.Get("/weather/:lat/:lon", cached, weatherHandler.GetWeather)
+	routes.Post("/weather", weatherHandler.PostWeather)
+	routes.Post("/weather/batch", weatherHandler.GetWeatherBatch)
+	routes.Post("/graphql", weatherHandler.GraphQL)
+	routes.Get("/ws", weatherHandler.WeatherUpdates)
+	routes.Get("/weather/cached", weatherHandler.GetCachedWeather)
+	routes.Get("/weather/history", weatherHandler.GetWeatherHistory)
+	routes.Get("/weather/stream", weatherHandler.GetWeatherStream)
+	routes.Get("/weather/hourly", cached, weatherHandler.GetHourlyForecast)
+	routes.Get("/forecast", cached, weatherHandler.GetForecast)
+	routes.Get("/forecast/:lat/:lon", cached, weatherHandler.GetForecast)
+	routes.Get("/alerts", cached, weatherHandler.GetAlerts)
+	routes.Get("/astronomy", weatherHandler.GetAstronomy)
+	routes.Get("/airquality", cached, weatherHandler.GetAirQuality)
+	routes.Get("/metadata", cached, weatherHandler.GetMetadata)
+	routes.Post("/subscriptions", subscriptionHandler.CreateSubscription)
+	routes.Get("/subscriptions", subscriptionHandler.ListSubscriptions)
+	routes.Delete("/subscriptions/:id", subscriptionHandler.DeleteSubscription)
+	routes.Get("/subscriptions/:id/deliveries", subscriptionHandler.GetDeliveries)
+	routes.Get("/stations/:stationId/observations", cached, weatherHandler.GetStationObservations)
+	routes.Get("/observations", cached, weatherHandler.GetCurrentConditions)
+	routes.Get("/health", weatherHandler.GetHealth)
+	routes.Get("/metrics", metricsHandler.GetMetrics)
+	routes.Get("/stats/daily", statsHandler.GetDailyStats)
+	routes.Get("/cache/stats", cacheHandler.GetCacheStats)
+	routes.Get("/me/usage", quota.GetUsage)
+
+	// Raw NWS documents, for the admin or any API key holder
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	if adminToken != "" || len(apiKeys) > 0 {
+		adminOrKey := middleware.RequireAdminTokenOrAPIKey(adminToken)
+		routes.Get("/raw/points", adminOrKey, weatherHandler.GetRawPoints)
+		routes.Get("/raw/forecast", adminOrKey, weatherHandler.GetRawForecast)
+	}
+
+	// Admin-only endpoints, enabled by setting ADMIN_TOKEN
+	if adminToken != "" {
+		admin := middleware.RequireAdminToken(adminToken)
+		routes.Delete("/admin/cache", admin, weatherHandler.InvalidateCache)
+		routes.Delete("/admin/cache/all", admin, weatherHandler.InvalidateAllCache)
+		routes.Get("/admin/cache/locations", admin, weatherHandler.ListCachedLocations)
+		routes.Get("/admin/warm-locations", admin, cacheHandler.ListWarmLocations)
+		routes.Post("/admin/warm-locations", admin, cacheHandler.AddWarmLocation)
+		routes.Delete("/admin/warm-locations", admin, cacheHandler.RemoveWarmLocation)
+		// Subscriptions made before API keys were required belong to no key
+		routes.Get("/admin/subscriptions/unowned", admin, subscriptionHandler.ListUnownedSubscriptions)
+		routes.Delete("/admin/subscriptions/unowned/:id", admin, subscriptionHandler.DeleteUnownedSubscription)
+		routes.Get("/admin/subscriptions/unowned/:id/deliveries", admin, subscriptionHandler.GetUnownedDeliveries)
+	}
+
+	// Futuristic API Documentation, of the routes registered above
+	docsHandler := handlers.NewDocsHandler(os.Getenv("PUBLIC_BASE_URL"),
+		handlers.WithRoutes(routes),
+		handlers.WithHealthCheck(weatherHandler.Health),
+		handlers.WithMetrics(recorder),
+	)
+	app.Get("/docs", docsHandler.ServeAPIDocs)
+	app.Get("/openapi.json", docsHandler.ServeOpenAPIJSON)
+	app.Get("/openapi.yaml", docsHandler.ServeOpenAPIYAML)
+	app.Get("/swagger/doc.json", docsHandler.ServeOpenAPIJSON)
+	app.Get("/schemas/:model.json", handlers.ServeModelSchema)
 
 	// Serve frontend static files
 	app.Static("/", "./dist/frontend")
@@ -98,10 +564,39 @@ func main() {
 		return c.SendFile("./dist/frontend/index.html")
 	})
 
-	log.Println("Starting weather service on port 3000...")
-	log.Println("Frontend available at: http://localhost:3000")
+	// Bind before logging so the URLs carry the real port, even for ADDR=:0
+	ln, err := net.Listen("tcp", listen.Addr())
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", listen.Addr(), err)
+	}
+	scheme := "http"
+	if tlsConfig.Enabled() {
+		certs, err := certreload.New(tlsConfig.CertFile, tlsConfig.KeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load TLS certificate: %v", err)
+		}
+		if tlsConfig.AutoReload {
+			hup := make(chan os.Signal, 1)
+			signal.Notify(hup, syscall.SIGHUP)
+			go certs.ReloadOn(jobsCtx, hup)
+			go certs.Watch(jobsCtx, certreload.DefaultWatchInterval)
+			log.Println("TLS certificate reloads on change or SIGHUP")
+		}
+		ln = certs.Listener(ln)
+		scheme = "https"
+	}
+	baseURL := listen.URL(scheme, ln.Addr().(*net.TCPAddr).Port)
+	log.Printf("Starting weather service on %s...", ln.Addr())
+	log.Printf("Frontend available at: %s", baseURL)
+	log.Printf("API documentation at: %s/docs", baseURL)
+
+	go func() {
+		<-jobsCtx.Done()
+		log.Println("Shutting down...")
+		_ = app.Shutdown()
+	}()
 
-	if err := app.Listen(":3000"); err != nil {
+	if err := app.Listener(ln); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }